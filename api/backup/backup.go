@@ -11,6 +11,7 @@ import (
 	"github.com/portainer/portainer/api/archive"
 	"github.com/portainer/portainer/api/crypto"
 	"github.com/portainer/portainer/api/http/offlinegate"
+	"github.com/portainer/portainer/api/internal/metrics"
 )
 
 const rwxr__r__ os.FileMode = 0744
@@ -18,7 +19,25 @@ const rwxr__r__ os.FileMode = 0744
 var filesToBackup = []string{"compose", "config.json", "custom_templates", "edge_jobs", "edge_stacks", "extensions", "portainer.key", "portainer.pub", "tls"}
 
 // Creates a tar.gz system archive and encrypts it if password is not empty. Returns a path to the archive file.
-func CreateBackupArchive(password string, gate *offlinegate.OfflineGate, datastore portainer.DataStore, filestorePath string) (string, error) {
+// A backup.failed event is sent when the archive cannot be created, whether the backup was requested or scheduled.
+func CreateBackupArchive(password string, gate *offlinegate.OfflineGate, datastore portainer.DataStore, filestorePath string, notificationService portainer.NotificationService) (string, error) {
+	archivePath, err := createBackupArchive(password, gate, datastore, filestorePath)
+	if err != nil {
+		metrics.BackupJobs.Inc("failure")
+		notificationService.Notify(portainer.NotificationEvent{
+			Type:    portainer.NotificationEventBackupFailed,
+			Time:    time.Now().Unix(),
+			Message: "Failed to create backup",
+			Details: map[string]string{"error": err.Error()},
+		})
+		return "", err
+	}
+
+	metrics.BackupJobs.Inc("success")
+	return archivePath, nil
+}
+
+func createBackupArchive(password string, gate *offlinegate.OfflineGate, datastore portainer.DataStore, filestorePath string) (string, error) {
 	unlock := gate.Lock()
 	defer unlock()
 
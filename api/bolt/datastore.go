@@ -10,22 +10,29 @@ import (
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/bolt/customtemplate"
 	"github.com/portainer/portainer/api/bolt/dockerhub"
+	"github.com/portainer/portainer/api/bolt/edgeenrollmenttoken"
 	"github.com/portainer/portainer/api/bolt/edgegroup"
 	"github.com/portainer/portainer/api/bolt/edgejob"
+	"github.com/portainer/portainer/api/bolt/edgejobresult"
 	"github.com/portainer/portainer/api/bolt/edgestack"
 	"github.com/portainer/portainer/api/bolt/endpoint"
 	"github.com/portainer/portainer/api/bolt/endpointgroup"
 	"github.com/portainer/portainer/api/bolt/endpointrelation"
 	"github.com/portainer/portainer/api/bolt/errors"
 	"github.com/portainer/portainer/api/bolt/extension"
+	"github.com/portainer/portainer/api/bolt/imagevulnerability"
 	"github.com/portainer/portainer/api/bolt/internal"
 	"github.com/portainer/portainer/api/bolt/migrator"
+	"github.com/portainer/portainer/api/bolt/notificationchannel"
+	"github.com/portainer/portainer/api/bolt/notificationdelivery"
 	"github.com/portainer/portainer/api/bolt/registry"
 	"github.com/portainer/portainer/api/bolt/resourcecontrol"
 	"github.com/portainer/portainer/api/bolt/role"
 	"github.com/portainer/portainer/api/bolt/schedule"
 	"github.com/portainer/portainer/api/bolt/settings"
+	"github.com/portainer/portainer/api/bolt/snapshothistory"
 	"github.com/portainer/portainer/api/bolt/stack"
+	"github.com/portainer/portainer/api/bolt/stackrevision"
 	"github.com/portainer/portainer/api/bolt/tag"
 	"github.com/portainer/portainer/api/bolt/team"
 	"github.com/portainer/portainer/api/bolt/teammembership"
@@ -43,32 +50,39 @@ const (
 // Store defines the implementation of portainer.DataStore using
 // BoltDB as the storage system.
 type Store struct {
-	path                    string
-	connection              *internal.DbConnection
-	isNew                   bool
-	fileService             portainer.FileService
-	CustomTemplateService   *customtemplate.Service
-	DockerHubService        *dockerhub.Service
-	EdgeGroupService        *edgegroup.Service
-	EdgeJobService          *edgejob.Service
-	EdgeStackService        *edgestack.Service
-	EndpointGroupService    *endpointgroup.Service
-	EndpointService         *endpoint.Service
-	EndpointRelationService *endpointrelation.Service
-	ExtensionService        *extension.Service
-	RegistryService         *registry.Service
-	ResourceControlService  *resourcecontrol.Service
-	RoleService             *role.Service
-	ScheduleService         *schedule.Service
-	SettingsService         *settings.Service
-	StackService            *stack.Service
-	TagService              *tag.Service
-	TeamMembershipService   *teammembership.Service
-	TeamService             *team.Service
-	TunnelServerService     *tunnelserver.Service
-	UserService             *user.Service
-	VersionService          *version.Service
-	WebhookService          *webhook.Service
+	path                        string
+	connection                  *internal.DbConnection
+	isNew                       bool
+	fileService                 portainer.FileService
+	CustomTemplateService       *customtemplate.Service
+	DockerHubService            *dockerhub.Service
+	EdgeEnrollmentTokenService  *edgeenrollmenttoken.Service
+	EdgeGroupService            *edgegroup.Service
+	EdgeJobService              *edgejob.Service
+	EdgeJobResultService        *edgejobresult.Service
+	EdgeStackService            *edgestack.Service
+	EndpointGroupService        *endpointgroup.Service
+	EndpointService             *endpoint.Service
+	EndpointRelationService     *endpointrelation.Service
+	ExtensionService            *extension.Service
+	ImageVulnerabilityService   *imagevulnerability.Service
+	NotificationChannelService  *notificationchannel.Service
+	NotificationDeliveryService *notificationdelivery.Service
+	RegistryService             *registry.Service
+	ResourceControlService      *resourcecontrol.Service
+	RoleService                 *role.Service
+	ScheduleService             *schedule.Service
+	SettingsService             *settings.Service
+	SnapshotHistoryService      *snapshothistory.Service
+	StackService                *stack.Service
+	StackRevisionService        *stackrevision.Service
+	TagService                  *tag.Service
+	TeamMembershipService       *teammembership.Service
+	TeamService                 *team.Service
+	TunnelServerService         *tunnelserver.Service
+	UserService                 *user.Service
+	VersionService              *version.Service
+	WebhookService              *webhook.Service
 }
 
 func (store *Store) edition() portainer.SoftwareEdition {
@@ -164,6 +178,7 @@ func (store *Store) MigrateData(force bool) error {
 			ScheduleService:         store.ScheduleService,
 			SettingsService:         store.SettingsService,
 			StackService:            store.StackService,
+			StackRevisionService:    store.StackRevisionService,
 			TagService:              store.TagService,
 			TeamMembershipService:   store.TeamMembershipService,
 			UserService:             store.UserService,
@@ -0,0 +1,352 @@
+package edgeenrollmenttoken
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/edgegroup"
+	"github.com/portainer/portainer/api/bolt/endpoint"
+	"github.com/portainer/portainer/api/bolt/endpointrelation"
+	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/bolt/internal"
+	"github.com/portainer/portainer/api/bolt/tag"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "edge_enrollment_tokens"
+	// HashBucketName represents the name of the bucket indexing the tokens by the hash of their secret value.
+	HashBucketName = "edge_enrollment_token_hashes"
+)
+
+// Service represents a service for managing Edge enrollment token data.
+// The secret value of a token is not stored, only its SHA-256 hash.
+type Service struct {
+	connection *internal.DbConnection
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection *internal.DbConnection) (*Service, error) {
+	err := internal.CreateBucket(connection, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = internal.CreateBucket(connection, HashBucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+// EdgeEnrollmentTokens returns an array containing all the Edge enrollment tokens.
+func (service *Service) EdgeEnrollmentTokens() ([]portainer.EdgeEnrollmentToken, error) {
+	var tokens = make([]portainer.EdgeEnrollmentToken, 0)
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var token portainer.EdgeEnrollmentToken
+			err := internal.UnmarshalObject(v, &token)
+			if err != nil {
+				return err
+			}
+			tokens = append(tokens, token)
+		}
+
+		return nil
+	})
+
+	return tokens, err
+}
+
+// EdgeEnrollmentToken returns an Edge enrollment token by ID.
+func (service *Service) EdgeEnrollmentToken(ID portainer.EdgeEnrollmentTokenID) (*portainer.EdgeEnrollmentToken, error) {
+	var token portainer.EdgeEnrollmentToken
+	identifier := internal.Itob(int(ID))
+
+	err := internal.GetObject(service.connection, BucketName, identifier, &token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func tokenHash(value string) string {
+	hash := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(hash[:])
+}
+
+// tokenByValue returns the Edge enrollment token whose secret value is the specified value
+func tokenByValue(tx *bolt.Tx, value string) (*portainer.EdgeEnrollmentToken, error) {
+	hash := tokenHash(value)
+
+	identifier := tx.Bucket([]byte(HashBucketName)).Get([]byte(hash))
+	if identifier == nil {
+		return nil, errors.ErrObjectNotFound
+	}
+
+	data := tx.Bucket([]byte(BucketName)).Get(identifier)
+	if data == nil {
+		return nil, errors.ErrObjectNotFound
+	}
+
+	var token portainer.EdgeEnrollmentToken
+	err := internal.UnmarshalObject(data, &token)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(token.TokenHash), []byte(hash)) != 1 {
+		return nil, errors.ErrObjectNotFound
+	}
+
+	return &token, nil
+}
+
+// EdgeEnrollmentTokenByToken returns an Edge enrollment token by its secret value.
+func (service *Service) EdgeEnrollmentTokenByToken(value string) (*portainer.EdgeEnrollmentToken, error) {
+	var token *portainer.EdgeEnrollmentToken
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		var err error
+		token, err = tokenByValue(tx, value)
+		return err
+	})
+
+	return token, err
+}
+
+// putToken saves the Edge enrollment token without its secret value
+func putToken(tx *bolt.Tx, token *portainer.EdgeEnrollmentToken) error {
+	stored := *token
+	stored.Token = ""
+
+	data, err := internal.MarshalObject(&stored)
+	if err != nil {
+		return err
+	}
+
+	return tx.Bucket([]byte(BucketName)).Put(internal.Itob(int(token.ID)), data)
+}
+
+// CreateEdgeEnrollmentToken assigns an ID to a new Edge enrollment token and saves it with the hash of its secret value.
+func (service *Service) CreateEdgeEnrollmentToken(token *portainer.EdgeEnrollmentToken) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		id, _ := bucket.NextSequence()
+		token.ID = portainer.EdgeEnrollmentTokenID(id)
+		token.TokenHash = tokenHash(token.Token)
+
+		err := tx.Bucket([]byte(HashBucketName)).Put([]byte(token.TokenHash), internal.Itob(int(token.ID)))
+		if err != nil {
+			return err
+		}
+
+		return putToken(tx, token)
+	})
+}
+
+// UpdateEdgeEnrollmentToken updates an Edge enrollment token.
+func (service *Service) UpdateEdgeEnrollmentToken(ID portainer.EdgeEnrollmentTokenID, token *portainer.EdgeEnrollmentToken) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		token.ID = ID
+		return putToken(tx, token)
+	})
+}
+
+// DeleteEdgeEnrollmentToken deletes an Edge enrollment token and its hash.
+func (service *Service) DeleteEdgeEnrollmentToken(ID portainer.EdgeEnrollmentTokenID) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+		identifier := internal.Itob(int(ID))
+
+		data := bucket.Get(identifier)
+		if data == nil {
+			return errors.ErrObjectNotFound
+		}
+
+		var token portainer.EdgeEnrollmentToken
+		err := internal.UnmarshalObject(data, &token)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Bucket([]byte(HashBucketName)).Delete([]byte(token.TokenHash))
+		if err != nil {
+			return err
+		}
+
+		return bucket.Delete(identifier)
+	})
+}
+
+// RegisterEndpoint creates an endpoint with the Edge enrollment token whose secret value is the specified value.
+// The endpoint, its empty relation, its membership of the tags of the endpoint and of the static Edge groups of the token
+// and the incremented usage count of the token are saved in a single transaction.
+// The register function validates the token and returns the endpoint to create with the assigned identifier.
+// It returns errors.ErrObjectNotFound when no token matches the value and errors.ErrEdgeIDAlreadyRegistered
+// when an endpoint is already registered with the Edge identifier of the endpoint.
+func (service *Service) RegisterEndpoint(value string, register func(token *portainer.EdgeEnrollmentToken, endpointID portainer.EndpointID) (*portainer.Endpoint, error)) (*portainer.EdgeEnrollmentToken, *portainer.Endpoint, error) {
+	var token *portainer.EdgeEnrollmentToken
+	var newEndpoint *portainer.Endpoint
+
+	err := service.connection.Update(func(tx *bolt.Tx) error {
+		var err error
+		token, err = tokenByValue(tx, value)
+		if err != nil {
+			return err
+		}
+
+		endpointBucket := tx.Bucket([]byte(endpoint.BucketName))
+
+		id, err := endpointBucket.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		newEndpoint, err = register(token, portainer.EndpointID(id))
+		if err != nil {
+			return err
+		}
+
+		cursor := endpointBucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var existing portainer.Endpoint
+			err := internal.UnmarshalObject(v, &existing)
+			if err != nil {
+				return err
+			}
+
+			if existing.EdgeID == newEndpoint.EdgeID {
+				return errors.ErrEdgeIDAlreadyRegistered
+			}
+		}
+
+		data, err := internal.MarshalObject(newEndpoint)
+		if err != nil {
+			return err
+		}
+
+		err = endpointBucket.Put(internal.Itob(int(newEndpoint.ID)), data)
+		if err != nil {
+			return err
+		}
+
+		relation := &portainer.EndpointRelation{
+			EndpointID: newEndpoint.ID,
+			EdgeStacks: map[portainer.EdgeStackID]bool{},
+		}
+
+		data, err = internal.MarshalObject(relation)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Bucket([]byte(endpointrelation.BucketName)).Put(internal.Itob(int(relation.EndpointID)), data)
+		if err != nil {
+			return err
+		}
+
+		err = addEndpointToTags(tx, newEndpoint)
+		if err != nil {
+			return err
+		}
+
+		err = addEndpointToEdgeGroups(tx, newEndpoint.ID, token.EdgeGroups)
+		if err != nil {
+			return err
+		}
+
+		token.UsageCount++
+
+		return putToken(tx, token)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return token, newEndpoint, nil
+}
+
+// addEndpointToTags adds the endpoint to its tags, the tags that no longer exist are ignored
+func addEndpointToTags(tx *bolt.Tx, newEndpoint *portainer.Endpoint) error {
+	bucket := tx.Bucket([]byte(tag.BucketName))
+
+	for _, tagID := range newEndpoint.TagIDs {
+		value := bucket.Get(internal.Itob(int(tagID)))
+		if value == nil {
+			continue
+		}
+
+		var endpointTag portainer.Tag
+		err := internal.UnmarshalObject(value, &endpointTag)
+		if err != nil {
+			return err
+		}
+
+		if endpointTag.Endpoints == nil {
+			endpointTag.Endpoints = make(map[portainer.EndpointID]bool)
+		}
+		endpointTag.Endpoints[newEndpoint.ID] = true
+
+		data, err := internal.MarshalObject(&endpointTag)
+		if err != nil {
+			return err
+		}
+
+		err = bucket.Put(internal.Itob(int(tagID)), data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addEndpointToEdgeGroups adds the endpoint to the static Edge groups, the dynamic Edge groups and the Edge groups
+// that no longer exist are ignored
+func addEndpointToEdgeGroups(tx *bolt.Tx, endpointID portainer.EndpointID, edgeGroupIDs []portainer.EdgeGroupID) error {
+	bucket := tx.Bucket([]byte(edgegroup.BucketName))
+
+	for _, edgeGroupID := range edgeGroupIDs {
+		value := bucket.Get(internal.Itob(int(edgeGroupID)))
+		if value == nil {
+			continue
+		}
+
+		var edgeGroup portainer.EdgeGroup
+		err := internal.UnmarshalObject(value, &edgeGroup)
+		if err != nil {
+			return err
+		}
+
+		if edgeGroup.Dynamic {
+			continue
+		}
+		edgeGroup.Endpoints = append(edgeGroup.Endpoints, endpointID)
+
+		data, err := internal.MarshalObject(&edgeGroup)
+		if err != nil {
+			return err
+		}
+
+		err = bucket.Put(internal.Itob(int(edgeGroupID)), data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package edgejobresult
+
+import (
+	"bytes"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/edgejob"
+	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "edge_job_results"
+)
+
+// Service represents a service for managing Edge job result data.
+// The results are keyed by the identifier of their Edge job followed by their own identifier.
+type Service struct {
+	connection *internal.DbConnection
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection *internal.DbConnection) (*Service, error) {
+	err := internal.CreateBucket(connection, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+func resultKey(edgeJobID portainer.EdgeJobID, ID portainer.EdgeJobResultID) []byte {
+	return append(internal.Itob(int(edgeJobID)), internal.Itob(int(ID))...)
+}
+
+// EdgeJobResults returns the results of an Edge job, ordered from the oldest to the most recent one.
+func (service *Service) EdgeJobResults(edgeJobID portainer.EdgeJobID) ([]portainer.EdgeJobResult, error) {
+	var results = make([]portainer.EdgeJobResult, 0)
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		prefix := internal.Itob(int(edgeJobID))
+		cursor := bucket.Cursor()
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			var result portainer.EdgeJobResult
+			err := internal.UnmarshalObject(v, &result)
+			if err != nil {
+				return err
+			}
+
+			results = append(results, result)
+		}
+
+		return nil
+	})
+
+	return results, err
+}
+
+// RecordEdgeJobResult assigns an ID to a new Edge job result and saves it along with its Edge job
+// in a single transaction. The Edge job is updated by the update function before being saved,
+// the result is not saved when the update function returns an error.
+// It returns the updated Edge job.
+func (service *Service) RecordEdgeJobResult(result *portainer.EdgeJobResult, update func(edgeJob *portainer.EdgeJob) error) (*portainer.EdgeJob, error) {
+	var edgeJob portainer.EdgeJob
+
+	err := service.connection.Update(func(tx *bolt.Tx) error {
+		edgeJobBucket := tx.Bucket([]byte(edgejob.BucketName))
+
+		edgeJobKey := internal.Itob(int(result.EdgeJobID))
+		value := edgeJobBucket.Get(edgeJobKey)
+		if value == nil {
+			return errors.ErrObjectNotFound
+		}
+
+		err := internal.UnmarshalObject(value, &edgeJob)
+		if err != nil {
+			return err
+		}
+
+		err = update(&edgeJob)
+		if err != nil {
+			return err
+		}
+
+		data, err := internal.MarshalObject(&edgeJob)
+		if err != nil {
+			return err
+		}
+
+		err = edgeJobBucket.Put(edgeJobKey, data)
+		if err != nil {
+			return err
+		}
+
+		bucket := tx.Bucket([]byte(BucketName))
+
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		result.ID = portainer.EdgeJobResultID(id)
+
+		data, err = internal.MarshalObject(result)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(resultKey(result.EdgeJobID, result.ID), data)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &edgeJob, nil
+}
+
+// DeleteEdgeJobResultsBefore deletes the results of an Edge job whose run is lower than the specified run.
+func (service *Service) DeleteEdgeJobResultsBefore(edgeJobID portainer.EdgeJobID, run int) error {
+	return service.deleteEdgeJobResults(edgeJobID, func(result *portainer.EdgeJobResult) bool {
+		return result.Run < run
+	})
+}
+
+// DeleteEdgeJobResults deletes all the results of an Edge job.
+func (service *Service) DeleteEdgeJobResults(edgeJobID portainer.EdgeJobID) error {
+	return service.deleteEdgeJobResults(edgeJobID, nil)
+}
+
+func (service *Service) deleteEdgeJobResults(edgeJobID portainer.EdgeJobID, match func(result *portainer.EdgeJobResult) bool) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		prefix := internal.Itob(int(edgeJobID))
+		keys := make([][]byte, 0)
+		cursor := bucket.Cursor()
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			if match != nil {
+				var result portainer.EdgeJobResult
+				err := internal.UnmarshalObject(v, &result)
+				if err != nil {
+					return err
+				}
+
+				if !match(&result) {
+					continue
+				}
+			}
+
+			keys = append(keys, k)
+		}
+
+		for _, k := range keys {
+			err := bucket.Delete(k)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -3,6 +3,7 @@ package edgestack
 import (
 	"github.com/boltdb/bolt"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/errors"
 	"github.com/portainer/portainer/api/bolt/internal"
 )
 
@@ -89,6 +90,36 @@ func (service *Service) UpdateEdgeStack(ID portainer.EdgeStackID, edgeStack *por
 	return internal.UpdateObject(service.connection, BucketName, identifier, edgeStack)
 }
 
+// UpdateEdgeStackFunc reads an Edge stack, applies the update function and saves it in a single transaction,
+// so that the changes made to the Edge stack in the meantime are not overwritten.
+// It returns errors.ErrObjectNotFound when the Edge stack does not exist.
+func (service *Service) UpdateEdgeStackFunc(ID portainer.EdgeStackID, updateFunc func(edgeStack *portainer.EdgeStack)) error {
+	identifier := internal.Itob(int(ID))
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		value := bucket.Get(identifier)
+		if value == nil {
+			return errors.ErrObjectNotFound
+		}
+
+		var edgeStack portainer.EdgeStack
+		err := internal.UnmarshalObject(value, &edgeStack)
+		if err != nil {
+			return err
+		}
+
+		updateFunc(&edgeStack)
+
+		data, err := internal.MarshalObject(&edgeStack)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(identifier, data)
+	})
+}
+
 // DeleteEdgeStack deletes an Edge stack.
 func (service *Service) DeleteEdgeStack(ID portainer.EdgeStackID) error {
 	identifier := internal.Itob(int(ID))
@@ -3,6 +3,8 @@ package errors
 import "errors"
 
 var (
-	ErrObjectNotFound = errors.New("Object not found inside the database")
-	ErrWrongDBEdition = errors.New("The Portainer database is set for Portainer Business Edition, please follow the instructions in our documentation to downgrade it: https://documentation.portainer.io/v2.0-be/downgrade/be-to-ce/")
+	ErrObjectNotFound          = errors.New("Object not found inside the database")
+	ErrStackAlreadyExists      = errors.New("A stack with the same name already exists on the endpoint")
+	ErrEdgeIDAlreadyRegistered = errors.New("An endpoint is already registered with this Edge identifier")
+	ErrWrongDBEdition          = errors.New("The Portainer database is set for Portainer Business Edition, please follow the instructions in our documentation to downgrade it: https://documentation.portainer.io/v2.0-be/downgrade/be-to-ce/")
 )
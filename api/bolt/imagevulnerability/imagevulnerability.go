@@ -0,0 +1,76 @@
+package imagevulnerability
+
+import (
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "image_vulnerabilities"
+)
+
+// Service represents a service for managing the vulnerability reports of the images.
+// Reports are keyed by image digest.
+type Service struct {
+	connection *internal.DbConnection
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection *internal.DbConnection) (*Service, error) {
+	err := internal.CreateBucket(connection, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+// ImageVulnerabilityReports returns all the image vulnerability reports.
+func (service *Service) ImageVulnerabilityReports() ([]portainer.ImageVulnerabilityReport, error) {
+	var reports = make([]portainer.ImageVulnerabilityReport, 0)
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var report portainer.ImageVulnerabilityReport
+			err := internal.UnmarshalObject(v, &report)
+			if err != nil {
+				return err
+			}
+			reports = append(reports, report)
+		}
+
+		return nil
+	})
+
+	return reports, err
+}
+
+// ImageVulnerabilityReport returns the vulnerability report of an image digest.
+func (service *Service) ImageVulnerabilityReport(digest string) (*portainer.ImageVulnerabilityReport, error) {
+	var report portainer.ImageVulnerabilityReport
+
+	err := internal.GetObject(service.connection, BucketName, []byte(digest), &report)
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// UpdateImageVulnerabilityReport creates or replaces the vulnerability report of an image digest.
+func (service *Service) UpdateImageVulnerabilityReport(digest string, report *portainer.ImageVulnerabilityReport) error {
+	return internal.UpdateObject(service.connection, BucketName, []byte(digest), report)
+}
+
+// DeleteImageVulnerabilityReport deletes the vulnerability report of an image digest.
+func (service *Service) DeleteImageVulnerabilityReport(digest string) error {
+	return internal.DeleteObject(service.connection, BucketName, []byte(digest))
+}
@@ -2,15 +2,33 @@ package internal
 
 import (
 	"encoding/binary"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/metrics"
 )
 
 type DbConnection struct {
 	*bolt.DB
 }
 
+// View executes a function within a read-only transaction and records the duration of the transaction.
+func (connection *DbConnection) View(fn func(*bolt.Tx) error) error {
+	defer observeTransaction("view", time.Now())
+	return connection.DB.View(fn)
+}
+
+// Update executes a function within a read-write transaction and records the duration of the transaction.
+func (connection *DbConnection) Update(fn func(*bolt.Tx) error) error {
+	defer observeTransaction("update", time.Now())
+	return connection.DB.Update(fn)
+}
+
+func observeTransaction(transactionType string, start time.Time) {
+	metrics.DatabaseTransactionDuration.Observe(time.Since(start).Seconds(), transactionType)
+}
+
 // Itob returns an 8-byte big endian representation of v.
 // This function is typically used for encoding integer IDs to byte slices
 // so that they can be used as BoltDB keys.
@@ -0,0 +1,50 @@
+package migrator
+
+import (
+	"path"
+
+	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/logging"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+func (m *Migrator) migrateDBVersionTo31() error {
+	return m.createBaselineStackRevisions()
+}
+
+// createBaselineStackRevisions records the currently deployed state of the stacks created
+// before stack revisions were introduced, so that they can be rolled back to it
+func (m *Migrator) createBaselineStackRevisions() error {
+	stacks, err := m.stackService.Stacks()
+	if err != nil {
+		return err
+	}
+
+	for _, stack := range stacks {
+		_, err := m.stackRevisionService.LatestStackRevision(stack.ID)
+		if err == nil {
+			continue
+		} else if err != errors.ErrObjectNotFound {
+			return err
+		}
+
+		stackFileContent, err := m.fileService.GetFileContent(path.Join(stack.ProjectPath, stack.EntryPoint))
+		if err != nil {
+			logging.Component("migrator").WithField(logging.FieldStackID, stack.ID).WithError(err).Warn("unable to read the stack file, no baseline revision recorded")
+			continue
+		}
+
+		revision := stackutils.NewStackRevision(&stack, stackFileContent, 0)
+		revision.DeploymentDate = stack.UpdateDate
+		if revision.DeploymentDate == 0 {
+			revision.DeploymentDate = stack.CreationDate
+		}
+
+		err = m.stackRevisionService.CreateStackRevision(revision)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/portainer/portainer/api/bolt/schedule"
 	"github.com/portainer/portainer/api/bolt/settings"
 	"github.com/portainer/portainer/api/bolt/stack"
+	"github.com/portainer/portainer/api/bolt/stackrevision"
 	"github.com/portainer/portainer/api/bolt/tag"
 	"github.com/portainer/portainer/api/bolt/teammembership"
 	"github.com/portainer/portainer/api/bolt/user"
@@ -35,6 +36,7 @@ type (
 		scheduleService         *schedule.Service
 		settingsService         *settings.Service
 		stackService            *stack.Service
+		stackRevisionService    *stackrevision.Service
 		tagService              *tag.Service
 		teamMembershipService   *teammembership.Service
 		userService             *user.Service
@@ -57,6 +59,7 @@ type (
 		ScheduleService         *schedule.Service
 		SettingsService         *settings.Service
 		StackService            *stack.Service
+		StackRevisionService    *stackrevision.Service
 		TagService              *tag.Service
 		TeamMembershipService   *teammembership.Service
 		UserService             *user.Service
@@ -83,6 +86,7 @@ func NewMigrator(parameters *Parameters) *Migrator {
 		tagService:              parameters.TagService,
 		teamMembershipService:   parameters.TeamMembershipService,
 		stackService:            parameters.StackService,
+		stackRevisionService:    parameters.StackRevisionService,
 		userService:             parameters.UserService,
 		versionService:          parameters.VersionService,
 		fileService:             parameters.FileService,
@@ -366,5 +370,12 @@ func (m *Migrator) Migrate() error {
 		}
 	}
 
+	if m.currentDBVersion < 31 {
+		err := m.migrateDBVersionTo31()
+		if err != nil {
+			return err
+		}
+	}
+
 	return m.versionService.StoreDBVersion(portainer.DBVersion)
 }
@@ -0,0 +1,95 @@
+package notificationchannel
+
+import (
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "notification_channels"
+)
+
+// Service represents a service for managing notification channel data.
+type Service struct {
+	connection *internal.DbConnection
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection *internal.DbConnection) (*Service, error) {
+	err := internal.CreateBucket(connection, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+// NotificationChannels returns an array containing all the notification channels.
+func (service *Service) NotificationChannels() ([]portainer.NotificationChannel, error) {
+	var channels = make([]portainer.NotificationChannel, 0)
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var channel portainer.NotificationChannel
+			err := internal.UnmarshalObject(v, &channel)
+			if err != nil {
+				return err
+			}
+			channels = append(channels, channel)
+		}
+
+		return nil
+	})
+
+	return channels, err
+}
+
+// NotificationChannel returns a notification channel by ID.
+func (service *Service) NotificationChannel(ID portainer.NotificationChannelID) (*portainer.NotificationChannel, error) {
+	var channel portainer.NotificationChannel
+	identifier := internal.Itob(int(ID))
+
+	err := internal.GetObject(service.connection, BucketName, identifier, &channel)
+	if err != nil {
+		return nil, err
+	}
+
+	return &channel, nil
+}
+
+// CreateNotificationChannel assigns an ID to a new notification channel and saves it.
+func (service *Service) CreateNotificationChannel(channel *portainer.NotificationChannel) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		id, _ := bucket.NextSequence()
+		channel.ID = portainer.NotificationChannelID(id)
+
+		data, err := internal.MarshalObject(channel)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(internal.Itob(int(channel.ID)), data)
+	})
+}
+
+// UpdateNotificationChannel updates a notification channel.
+func (service *Service) UpdateNotificationChannel(ID portainer.NotificationChannelID, channel *portainer.NotificationChannel) error {
+	identifier := internal.Itob(int(ID))
+	return internal.UpdateObject(service.connection, BucketName, identifier, channel)
+}
+
+// DeleteNotificationChannel deletes a notification channel.
+func (service *Service) DeleteNotificationChannel(ID portainer.NotificationChannelID) error {
+	identifier := internal.Itob(int(ID))
+	return internal.DeleteObject(service.connection, BucketName, identifier)
+}
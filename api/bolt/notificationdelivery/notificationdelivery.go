@@ -0,0 +1,102 @@
+package notificationdelivery
+
+import (
+	"bytes"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "notification_deliveries"
+)
+
+// Service represents a service for managing the notification delivery log.
+type Service struct {
+	connection *internal.DbConnection
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection *internal.DbConnection) (*Service, error) {
+	err := internal.CreateBucket(connection, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+// NotificationDeliveries returns all the notification deliveries, ordered from the oldest to the most recent one.
+func (service *Service) NotificationDeliveries() ([]portainer.NotificationDelivery, error) {
+	var deliveries = make([]portainer.NotificationDelivery, 0)
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var delivery portainer.NotificationDelivery
+			err := internal.UnmarshalObject(v, &delivery)
+			if err != nil {
+				return err
+			}
+			deliveries = append(deliveries, delivery)
+		}
+
+		return nil
+	})
+
+	return deliveries, err
+}
+
+// CreateNotificationDelivery assigns an ID to a new notification delivery and saves it.
+func (service *Service) CreateNotificationDelivery(delivery *portainer.NotificationDelivery) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		id, _ := bucket.NextSequence()
+		delivery.ID = portainer.NotificationDeliveryID(id)
+
+		data, err := internal.MarshalObject(delivery)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(internal.Itob(int(delivery.ID)), data)
+	})
+}
+
+// UpdateNotificationDelivery updates a notification delivery.
+func (service *Service) UpdateNotificationDelivery(ID portainer.NotificationDeliveryID, delivery *portainer.NotificationDelivery) error {
+	identifier := internal.Itob(int(ID))
+	return internal.UpdateObject(service.connection, BucketName, identifier, delivery)
+}
+
+// DeleteNotificationDeliveriesUpTo deletes the notification deliveries whose identifier is lower than or equal to the specified one.
+func (service *Service) DeleteNotificationDeliveriesUpTo(ID portainer.NotificationDeliveryID) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		last := internal.Itob(int(ID))
+		keys := make([][]byte, 0)
+
+		cursor := bucket.Cursor()
+		for k, _ := cursor.First(); k != nil && bytes.Compare(k, last) <= 0; k, _ = cursor.Next() {
+			keys = append(keys, k)
+		}
+
+		for _, k := range keys {
+			err := bucket.Delete(k)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
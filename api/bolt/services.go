@@ -4,19 +4,26 @@ import (
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/bolt/customtemplate"
 	"github.com/portainer/portainer/api/bolt/dockerhub"
+	"github.com/portainer/portainer/api/bolt/edgeenrollmenttoken"
 	"github.com/portainer/portainer/api/bolt/edgegroup"
 	"github.com/portainer/portainer/api/bolt/edgejob"
+	"github.com/portainer/portainer/api/bolt/edgejobresult"
 	"github.com/portainer/portainer/api/bolt/edgestack"
 	"github.com/portainer/portainer/api/bolt/endpoint"
 	"github.com/portainer/portainer/api/bolt/endpointgroup"
 	"github.com/portainer/portainer/api/bolt/endpointrelation"
 	"github.com/portainer/portainer/api/bolt/extension"
+	"github.com/portainer/portainer/api/bolt/imagevulnerability"
+	"github.com/portainer/portainer/api/bolt/notificationchannel"
+	"github.com/portainer/portainer/api/bolt/notificationdelivery"
 	"github.com/portainer/portainer/api/bolt/registry"
 	"github.com/portainer/portainer/api/bolt/resourcecontrol"
 	"github.com/portainer/portainer/api/bolt/role"
 	"github.com/portainer/portainer/api/bolt/schedule"
 	"github.com/portainer/portainer/api/bolt/settings"
+	"github.com/portainer/portainer/api/bolt/snapshothistory"
 	"github.com/portainer/portainer/api/bolt/stack"
+	"github.com/portainer/portainer/api/bolt/stackrevision"
 	"github.com/portainer/portainer/api/bolt/tag"
 	"github.com/portainer/portainer/api/bolt/team"
 	"github.com/portainer/portainer/api/bolt/teammembership"
@@ -51,6 +58,12 @@ func (store *Store) initServices() error {
 	}
 	store.EdgeStackService = edgeStackService
 
+	edgeEnrollmentTokenService, err := edgeenrollmenttoken.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.EdgeEnrollmentTokenService = edgeEnrollmentTokenService
+
 	edgeGroupService, err := edgegroup.NewService(store.connection)
 	if err != nil {
 		return err
@@ -63,6 +76,12 @@ func (store *Store) initServices() error {
 	}
 	store.EdgeJobService = edgeJobService
 
+	edgeJobResultService, err := edgejobresult.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.EdgeJobResultService = edgeJobResultService
+
 	endpointgroupService, err := endpointgroup.NewService(store.connection)
 	if err != nil {
 		return err
@@ -87,6 +106,24 @@ func (store *Store) initServices() error {
 	}
 	store.ExtensionService = extensionService
 
+	imageVulnerabilityService, err := imagevulnerability.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.ImageVulnerabilityService = imageVulnerabilityService
+
+	notificationChannelService, err := notificationchannel.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.NotificationChannelService = notificationChannelService
+
+	notificationDeliveryService, err := notificationdelivery.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.NotificationDeliveryService = notificationDeliveryService
+
 	registryService, err := registry.NewService(store.connection)
 	if err != nil {
 		return err
@@ -105,12 +142,24 @@ func (store *Store) initServices() error {
 	}
 	store.SettingsService = settingsService
 
+	snapshotHistoryService, err := snapshothistory.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.SnapshotHistoryService = snapshotHistoryService
+
 	stackService, err := stack.NewService(store.connection)
 	if err != nil {
 		return err
 	}
 	store.StackService = stackService
 
+	stackRevisionService, err := stackrevision.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.StackRevisionService = stackRevisionService
+
 	tagService, err := tag.NewService(store.connection)
 	if err != nil {
 		return err
@@ -172,6 +221,11 @@ func (store *Store) DockerHub() portainer.DockerHubService {
 	return store.DockerHubService
 }
 
+// EdgeEnrollmentToken gives access to the EdgeEnrollmentToken data management layer
+func (store *Store) EdgeEnrollmentToken() portainer.EdgeEnrollmentTokenService {
+	return store.EdgeEnrollmentTokenService
+}
+
 // EdgeGroup gives access to the EdgeGroup data management layer
 func (store *Store) EdgeGroup() portainer.EdgeGroupService {
 	return store.EdgeGroupService
@@ -182,6 +236,11 @@ func (store *Store) EdgeJob() portainer.EdgeJobService {
 	return store.EdgeJobService
 }
 
+// EdgeJobResult gives access to the EdgeJobResult data management layer
+func (store *Store) EdgeJobResult() portainer.EdgeJobResultService {
+	return store.EdgeJobResultService
+}
+
 // EdgeStack gives access to the EdgeStack data management layer
 func (store *Store) EdgeStack() portainer.EdgeStackService {
 	return store.EdgeStackService
@@ -202,6 +261,21 @@ func (store *Store) EndpointRelation() portainer.EndpointRelationService {
 	return store.EndpointRelationService
 }
 
+// ImageVulnerability gives access to the ImageVulnerability data management layer
+func (store *Store) ImageVulnerability() portainer.ImageVulnerabilityService {
+	return store.ImageVulnerabilityService
+}
+
+// NotificationChannel gives access to the NotificationChannel data management layer
+func (store *Store) NotificationChannel() portainer.NotificationChannelService {
+	return store.NotificationChannelService
+}
+
+// NotificationDelivery gives access to the NotificationDelivery data management layer
+func (store *Store) NotificationDelivery() portainer.NotificationDeliveryService {
+	return store.NotificationDeliveryService
+}
+
 // Registry gives access to the Registry data management layer
 func (store *Store) Registry() portainer.RegistryService {
 	return store.RegistryService
@@ -222,11 +296,21 @@ func (store *Store) Settings() portainer.SettingsService {
 	return store.SettingsService
 }
 
+// SnapshotHistory gives access to the SnapshotHistory data management layer
+func (store *Store) SnapshotHistory() portainer.SnapshotHistoryService {
+	return store.SnapshotHistoryService
+}
+
 // Stack gives access to the Stack data management layer
 func (store *Store) Stack() portainer.StackService {
 	return store.StackService
 }
 
+// StackRevision gives access to the StackRevision data management layer
+func (store *Store) StackRevision() portainer.StackRevisionService {
+	return store.StackRevisionService
+}
+
 // Tag gives access to the Tag data management layer
 func (store *Store) Tag() portainer.TagService {
 	return store.TagService
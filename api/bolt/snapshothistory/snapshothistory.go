@@ -0,0 +1,129 @@
+package snapshothistory
+
+import (
+	"bytes"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "snapshot_history"
+)
+
+// Service represents a service for managing the snapshot history of the endpoints.
+// Points are keyed by endpoint identifier then time so that the history of an endpoint
+// can be read and pruned with a single cursor range.
+type Service struct {
+	connection *internal.DbConnection
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection *internal.DbConnection) (*Service, error) {
+	err := internal.CreateBucket(connection, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+func pointKey(endpointID portainer.EndpointID, time int64) []byte {
+	return append(internal.Itob(int(endpointID)), internal.Itob(int(time))...)
+}
+
+// SnapshotHistory returns the points of an endpoint with a time between from (inclusive) and to (exclusive),
+// ordered from the oldest to the most recent one.
+func (service *Service) SnapshotHistory(endpointID portainer.EndpointID, from, to int64) ([]portainer.SnapshotHistoryPoint, error) {
+	var points = make([]portainer.SnapshotHistoryPoint, 0)
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		end := pointKey(endpointID, to)
+		cursor := bucket.Cursor()
+		for k, v := cursor.Seek(pointKey(endpointID, from)); k != nil && bytes.Compare(k, end) < 0; k, v = cursor.Next() {
+			var point portainer.SnapshotHistoryPoint
+			err := internal.UnmarshalObject(v, &point)
+			if err != nil {
+				return err
+			}
+			points = append(points, point)
+		}
+
+		return nil
+	})
+
+	return points, err
+}
+
+// CreateSnapshotHistoryPoint saves a point, replacing any point of the same endpoint recorded at the same time.
+func (service *Service) CreateSnapshotHistoryPoint(point *portainer.SnapshotHistoryPoint) error {
+	return internal.UpdateObject(service.connection, BucketName, pointKey(point.EndpointID, point.Time), point)
+}
+
+// ReplaceSnapshotHistory replaces the points of an endpoint with a time between from (inclusive) and to (exclusive)
+// with the specified points inside a single transaction.
+func (service *Service) ReplaceSnapshotHistory(endpointID portainer.EndpointID, from, to int64, points []portainer.SnapshotHistoryPoint) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		err := deleteRange(bucket, pointKey(endpointID, from), pointKey(endpointID, to))
+		if err != nil {
+			return err
+		}
+
+		for _, point := range points {
+			data, err := internal.MarshalObject(point)
+			if err != nil {
+				return err
+			}
+
+			err = bucket.Put(pointKey(endpointID, point.Time), data)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// DeleteSnapshotHistory deletes the points of an endpoint older than the specified time.
+func (service *Service) DeleteSnapshotHistory(endpointID portainer.EndpointID, before int64) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+		return deleteRange(bucket, pointKey(endpointID, 0), pointKey(endpointID, before))
+	})
+}
+
+// DeleteEndpointSnapshotHistory deletes all the points of an endpoint.
+func (service *Service) DeleteEndpointSnapshotHistory(endpointID portainer.EndpointID) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+		return deleteRange(bucket, pointKey(endpointID, 0), pointKey(endpointID+1, 0))
+	})
+}
+
+func deleteRange(bucket *bolt.Bucket, start, end []byte) error {
+	keys := make([][]byte, 0)
+
+	cursor := bucket.Cursor()
+	for k, _ := cursor.Seek(start); k != nil && bytes.Compare(k, end) < 0; k, _ = cursor.Next() {
+		keys = append(keys, k)
+	}
+
+	for _, k := range keys {
+		err := bucket.Delete(k)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package stack
 
 import (
+	"strings"
+
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/bolt/errors"
 	"github.com/portainer/portainer/api/bolt/internal"
@@ -122,6 +124,43 @@ func (service *Service) CreateStack(stack *portainer.Stack) error {
 	})
 }
 
+// CreateStackWithUniqueName assigns the next identifier to the stack and creates it in a single transaction.
+// It returns errors.ErrStackAlreadyExists when another stack with the same name, compared case insensitively,
+// exists on the same endpoint. The name is not checked for stacks without name.
+func (service *Service) CreateStackWithUniqueName(stack *portainer.Stack) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		if stack.Name != "" {
+			cursor := bucket.Cursor()
+			for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+				var existing portainer.Stack
+				err := internal.UnmarshalObject(v, &existing)
+				if err != nil {
+					return err
+				}
+
+				if existing.EndpointID == stack.EndpointID && strings.EqualFold(existing.Name, stack.Name) {
+					return errors.ErrStackAlreadyExists
+				}
+			}
+		}
+
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		stack.ID = portainer.StackID(id)
+
+		data, err := internal.MarshalObject(stack)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(internal.Itob(int(stack.ID)), data)
+	})
+}
+
 // UpdateStack updates a stack.
 func (service *Service) UpdateStack(ID portainer.StackID, stack *portainer.Stack) error {
 	identifier := internal.Itob(int(ID))
@@ -0,0 +1,165 @@
+package stackrevision
+
+import (
+	"bytes"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "stack_revisions"
+)
+
+// Service represents a service for managing stack revision data.
+// Revisions are keyed by stack identifier followed by version, so that the revisions
+// of a stack are stored next to each other, ordered by version.
+type Service struct {
+	connection *internal.DbConnection
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection *internal.DbConnection) (*Service, error) {
+	err := internal.CreateBucket(connection, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+func stackPrefix(stackID portainer.StackID) []byte {
+	return internal.Itob(int(stackID))
+}
+
+func revisionKey(stackID portainer.StackID, version int) []byte {
+	return append(stackPrefix(stackID), internal.Itob(version)...)
+}
+
+// StackRevision returns the revision of a stack with the specified version.
+func (service *Service) StackRevision(stackID portainer.StackID, version int) (*portainer.StackRevision, error) {
+	var revision portainer.StackRevision
+
+	err := internal.GetObject(service.connection, BucketName, revisionKey(stackID, version), &revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+// LatestStackRevision returns the most recent revision of a stack.
+func (service *Service) LatestStackRevision(stackID portainer.StackID) (*portainer.StackRevision, error) {
+	var revision *portainer.StackRevision
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		var err error
+		revision, err = latestStackRevision(tx.Bucket([]byte(BucketName)), stackID)
+		return err
+	})
+
+	return revision, err
+}
+
+// StackRevisions returns the revisions of a stack, ordered from the oldest to the most recent one.
+func (service *Service) StackRevisions(stackID portainer.StackID) ([]portainer.StackRevision, error) {
+	var revisions = make([]portainer.StackRevision, 0)
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		prefix := stackPrefix(stackID)
+
+		cursor := tx.Bucket([]byte(BucketName)).Cursor()
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			var revision portainer.StackRevision
+			err := internal.UnmarshalObject(v, &revision)
+			if err != nil {
+				return err
+			}
+
+			revisions = append(revisions, revision)
+		}
+
+		return nil
+	})
+
+	return revisions, err
+}
+
+// CreateStackRevision assigns an ID and the next version of the stack to a new stack revision and saves it.
+func (service *Service) CreateStackRevision(revision *portainer.StackRevision) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		latest, err := latestStackRevision(bucket, revision.StackID)
+		if err == errors.ErrObjectNotFound {
+			revision.Version = 1
+		} else if err != nil {
+			return err
+		} else {
+			revision.Version = latest.Version + 1
+		}
+
+		id, _ := bucket.NextSequence()
+		revision.ID = portainer.StackRevisionID(id)
+
+		data, err := internal.MarshalObject(revision)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(revisionKey(revision.StackID, revision.Version), data)
+	})
+}
+
+// DeleteStackRevisions deletes all the revisions of a stack.
+func (service *Service) DeleteStackRevisions(stackID portainer.StackID) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		prefix := stackPrefix(stackID)
+
+		keys := make([][]byte, 0)
+		cursor := tx.Bucket([]byte(BucketName)).Cursor()
+		for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+			keys = append(keys, append([]byte{}, k...))
+		}
+
+		bucket := tx.Bucket([]byte(BucketName))
+		for _, k := range keys {
+			err := bucket.Delete(k)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func latestStackRevision(bucket *bolt.Bucket, stackID portainer.StackID) (*portainer.StackRevision, error) {
+	prefix := stackPrefix(stackID)
+
+	cursor := bucket.Cursor()
+	k, v := cursor.Seek(stackPrefix(stackID + 1))
+	if k == nil {
+		k, v = cursor.Last()
+	} else {
+		k, v = cursor.Prev()
+	}
+
+	if k == nil || !bytes.HasPrefix(k, prefix) {
+		return nil, errors.ErrObjectNotFound
+	}
+
+	var revision portainer.StackRevision
+	err := internal.UnmarshalObject(v, &revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
@@ -3,7 +3,6 @@ package chisel
 import (
 	"context"
 	"fmt"
-	"log"
 	"strconv"
 	"time"
 
@@ -12,6 +11,9 @@ import (
 	cmap "github.com/orcaman/concurrent-map"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/internal/logging"
+	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -20,6 +22,8 @@ const (
 	activeTimeout         = 4*time.Minute + 30*time.Second
 )
 
+var logger = logging.Component("chisel")
+
 // Service represents a service to manage the state of multiple reverse tunnels.
 // It is used to start a reverse tunnel server and to manage the connection status of each tunnel
 // connected to the tunnel server.
@@ -27,9 +31,11 @@ type Service struct {
 	serverFingerprint string
 	serverPort        string
 	tunnelDetailsMap  cmap.ConcurrentMap
+	tunnelStatsMap    cmap.ConcurrentMap
 	dataStore         portainer.DataStore
 	snapshotService   portainer.SnapshotService
 	chiselServer      *chserver.Server
+	sessions          *sessionTracker
 	shutdownCtx       context.Context
 }
 
@@ -37,6 +43,7 @@ type Service struct {
 func NewService(dataStore portainer.DataStore, shutdownCtx context.Context) *Service {
 	return &Service{
 		tunnelDetailsMap: cmap.New(),
+		tunnelStatsMap:   cmap.New(),
 		dataStore:        dataStore,
 		shutdownCtx:      shutdownCtx,
 	}
@@ -63,6 +70,12 @@ func (service *Service) StartTunnelServer(addr, port string, snapshotService por
 		return err
 	}
 
+	sessions, err := newSessionTracker(chiselServer)
+	if err != nil {
+		logger.WithError(err).Warn("unable to track the tunnel sessions, closed tunnels stay open until the Edge agents disconnect")
+	}
+	service.sessions = sessions
+
 	service.serverFingerprint = chiselServer.GetFingerprint()
 	service.serverPort = port
 
@@ -114,7 +127,7 @@ func (service *Service) retrievePrivateKeySeed() (string, error) {
 }
 
 func (service *Service) startTunnelVerificationLoop() {
-	log.Printf("[DEBUG] [chisel, monitoring] [check_interval_seconds: %f] [message: starting tunnel management process]", tunnelCleanupInterval.Seconds())
+	logger.WithField("check_interval_seconds", tunnelCleanupInterval.Seconds()).Debug("starting tunnel management process")
 	ticker := time.NewTicker(tunnelCleanupInterval)
 
 	for {
@@ -122,9 +135,9 @@ func (service *Service) startTunnelVerificationLoop() {
 		case <-ticker.C:
 			service.checkTunnels()
 		case <-service.shutdownCtx.Done():
-			log.Println("[DEBUG] Shutting down tunnel service")
+			logger.Debug("shutting down tunnel service")
 			if err := service.StopTunnelServer(); err != nil {
-				log.Printf("Stopped tunnel service: %s", err)
+				logger.WithError(err).Error("unable to stop tunnel service")
 			}
 			ticker.Stop()
 			return
@@ -136,48 +149,58 @@ func (service *Service) checkTunnels() {
 	for item := range service.tunnelDetailsMap.IterBuffered() {
 		tunnel := item.Val.(*portainer.TunnelDetails)
 
+		service.recordTunnelStatus(item.Key, tunnel.Status, "")
+
 		if tunnel.LastActivity.IsZero() || tunnel.Status == portainer.EdgeAgentIdle {
 			continue
 		}
 
 		elapsed := time.Since(tunnel.LastActivity)
-		log.Printf("[DEBUG] [chisel,monitoring] [endpoint_id: %s] [status: %s] [status_time_seconds: %f] [message: endpoint tunnel monitoring]", item.Key, tunnel.Status, elapsed.Seconds())
+		tunnelLogger := logger.WithFields(logrus.Fields{
+			logging.FieldEndpointID: item.Key,
+			"status":                tunnel.Status,
+			"status_time_seconds":   elapsed.Seconds(),
+		})
+		tunnelLogger.Debug("endpoint tunnel monitoring")
 
 		if tunnel.Status == portainer.EdgeAgentManagementRequired && elapsed.Seconds() < requiredTimeout.Seconds() {
 			continue
 		} else if tunnel.Status == portainer.EdgeAgentManagementRequired && elapsed.Seconds() > requiredTimeout.Seconds() {
-			log.Printf("[DEBUG] [chisel,monitoring] [endpoint_id: %s] [status: %s] [status_time_seconds: %f] [timeout_seconds: %f] [message: REQUIRED state timeout exceeded]", item.Key, tunnel.Status, elapsed.Seconds(), requiredTimeout.Seconds())
+			tunnelLogger.WithField("timeout_seconds", requiredTimeout.Seconds()).Debug("REQUIRED state timeout exceeded")
 		}
 
 		if tunnel.Status == portainer.EdgeAgentActive && elapsed.Seconds() < activeTimeout.Seconds() {
 			continue
 		} else if tunnel.Status == portainer.EdgeAgentActive && elapsed.Seconds() > activeTimeout.Seconds() {
-			log.Printf("[DEBUG] [chisel,monitoring] [endpoint_id: %s] [status: %s] [status_time_seconds: %f] [timeout_seconds: %f] [message: ACTIVE state timeout exceeded]", item.Key, tunnel.Status, elapsed.Seconds(), activeTimeout.Seconds())
+			tunnelLogger.WithField("timeout_seconds", activeTimeout.Seconds()).Debug("ACTIVE state timeout exceeded")
 
 			endpointID, err := strconv.Atoi(item.Key)
 			if err != nil {
-				log.Printf("[ERROR] [chisel,snapshot,conversion] Invalid endpoint identifier (id: %s): %s", item.Key, err)
+				tunnelLogger.WithError(err).Error("invalid endpoint identifier")
 			}
 
 			err = service.snapshotEnvironment(portainer.EndpointID(endpointID), tunnel.Port)
 			if err != nil {
-				log.Printf("[ERROR] [snapshot] Unable to snapshot Edge endpoint (id: %s): %s", item.Key, err)
+				tunnelLogger.WithError(err).Error("unable to snapshot Edge endpoint")
 			}
 		}
 
-		if len(tunnel.Jobs) > 0 {
-			endpointID, err := strconv.Atoi(item.Key)
-			if err != nil {
-				log.Printf("[ERROR] [chisel,conversion] Invalid endpoint identifier (id: %s): %s", item.Key, err)
-				continue
-			}
+		reason := tunnelActiveTimeout
+		if tunnel.Status == portainer.EdgeAgentManagementRequired {
+			reason = tunnelRequiredTimeout
+		}
 
-			service.SetTunnelStatusToIdle(portainer.EndpointID(endpointID))
-		} else {
-			service.tunnelDetailsMap.Remove(item.Key)
+		endpointID, err := strconv.Atoi(item.Key)
+		if err != nil {
+			tunnelLogger.WithError(err).Error("invalid endpoint identifier")
+			continue
 		}
 
+		service.SetTunnelStatusToIdle(portainer.EndpointID(endpointID))
+		service.recordTunnelStatus(item.Key, portainer.EdgeAgentIdle, reason)
 	}
+
+	service.sessions.closeRevokedSessions()
 }
 
 func (service *Service) snapshotEnvironment(endpointID portainer.EndpointID, tunnelPort int) error {
@@ -195,5 +218,12 @@ func (service *Service) snapshotEnvironment(endpointID portainer.EndpointID, tun
 	}
 
 	endpoint.URL = endpointURL
-	return service.dataStore.Endpoint().UpdateEndpoint(endpoint.ID, endpoint)
+	err = service.dataStore.Endpoint().UpdateEndpoint(endpoint.ID, endpoint)
+	if err != nil {
+		return err
+	}
+
+	// the Docker version and operating system of the snapshot can change the Edge groups of the endpoint
+	_, err = edge.UpdateEndpointRelation(service.dataStore, endpoint)
+	return err
 }
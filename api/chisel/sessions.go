@@ -0,0 +1,147 @@
+package chisel
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+	"unsafe"
+
+	chserver "github.com/jpillora/chisel/server"
+	"golang.org/x/crypto/ssh"
+)
+
+// sessionHandshakeTimeout is the time after which a connection to the tunnel server which did not authenticate is forgotten
+const sessionHandshakeTimeout = time.Minute
+
+var errUnsupportedTunnelServer = errors.New("unsupported tunnel server version")
+
+type (
+	// sessionTracker keeps the connections of the Edge agents to the tunnel server, so that the session of an agent
+	// can be closed once its credentials are revoked. Chisel only verifies the credentials when an agent connects
+	// and does not expose its sessions: the tracker hooks the connection states of the HTTP server and the SSH
+	// password authentication of the Chisel server.
+	sessionTracker struct {
+		mu           sync.Mutex
+		pending      map[string]pendingConn
+		sessions     map[string]*tunnelSession
+		authenticate func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error)
+	}
+
+	pendingConn struct {
+		conn  net.Conn
+		since time.Time
+	}
+
+	tunnelSession struct {
+		conn     net.Conn
+		metadata ssh.ConnMetadata
+		password []byte
+	}
+)
+
+// newSessionTracker hooks a session tracker into a Chisel server. It must be called before the server is started.
+func newSessionTracker(server *chserver.Server) (*sessionTracker, error) {
+	value := reflect.ValueOf(server).Elem()
+
+	sshConfigField := value.FieldByName("sshConfig")
+	if !sshConfigField.IsValid() || sshConfigField.Type() != reflect.TypeOf(&ssh.ServerConfig{}) || sshConfigField.IsNil() {
+		return nil, errUnsupportedTunnelServer
+	}
+
+	httpServerField := value.FieldByName("httpServer")
+	if !httpServerField.IsValid() || httpServerField.Kind() != reflect.Ptr || httpServerField.IsNil() {
+		return nil, errUnsupportedTunnelServer
+	}
+
+	httpServerField = httpServerField.Elem().FieldByName("Server")
+	if !httpServerField.IsValid() || httpServerField.Type() != reflect.TypeOf(&http.Server{}) || httpServerField.IsNil() {
+		return nil, errUnsupportedTunnelServer
+	}
+
+	sshConfig := (*ssh.ServerConfig)(unsafe.Pointer(sshConfigField.Pointer()))
+	httpServer := (*http.Server)(unsafe.Pointer(httpServerField.Pointer()))
+
+	if sshConfig.PasswordCallback == nil {
+		return nil, errUnsupportedTunnelServer
+	}
+
+	tracker := &sessionTracker{
+		pending:      make(map[string]pendingConn),
+		sessions:     make(map[string]*tunnelSession),
+		authenticate: sshConfig.PasswordCallback,
+	}
+
+	sshConfig.PasswordCallback = tracker.passwordCallback
+	httpServer.ConnState = tracker.connState
+
+	return tracker, nil
+}
+
+func (tracker *sessionTracker) connState(conn net.Conn, state http.ConnState) {
+	key := conn.RemoteAddr().String()
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	switch state {
+	case http.StateNew:
+		tracker.pending[key] = pendingConn{conn: conn, since: time.Now()}
+	case http.StateClosed:
+		delete(tracker.pending, key)
+		delete(tracker.sessions, key)
+	}
+}
+
+func (tracker *sessionTracker) passwordCallback(metadata ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	permissions, err := tracker.authenticate(metadata, password)
+	if err != nil {
+		return nil, err
+	}
+
+	key := metadata.RemoteAddr().String()
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	if pending, ok := tracker.pending[key]; ok {
+		delete(tracker.pending, key)
+		tracker.sessions[key] = &tunnelSession{
+			conn:     pending.conn,
+			metadata: metadata,
+			password: append([]byte(nil), password...),
+		}
+	}
+
+	return permissions, nil
+}
+
+// closeRevokedSessions closes the sessions of the Edge agents whose credentials are no longer valid
+// and forgets the connections which did not authenticate in time
+func (tracker *sessionTracker) closeRevokedSessions() {
+	if tracker == nil {
+		return
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	for key, session := range tracker.sessions {
+		if _, err := tracker.authenticate(session.metadata, session.password); err == nil {
+			continue
+		}
+
+		if err := session.conn.Close(); err != nil {
+			logger.WithError(err).WithField("remote_address", key).Debug("unable to close tunnel session")
+		}
+		delete(tracker.sessions, key)
+	}
+
+	for key, pending := range tracker.pending {
+		if time.Since(pending.since) > sessionHandshakeTimeout {
+			delete(tracker.pending, key)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package chisel
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	tunnelClosedByAdministrator = "Tunnel closed by an administrator"
+	tunnelRequiredTimeout       = "Edge agent did not open the tunnel before the REQUIRED state timeout"
+	tunnelActiveTimeout         = "No activity on the tunnel before the ACTIVE state timeout"
+)
+
+// tunnelStatusReasons are the reasons recorded for the status changes observed on a tunnel
+var tunnelStatusReasons = map[string]string{
+	portainer.EdgeAgentIdle:               "Tunnel is not required",
+	portainer.EdgeAgentManagementRequired: "Portainer requested the tunnel to manage the endpoint",
+	portainer.EdgeAgentActive:             "Edge agent opened the tunnel",
+}
+
+// tunnelStats represents the status history and the traffic of a tunnel
+type tunnelStats struct {
+	mu           sync.Mutex
+	status       string
+	statusReason string
+	statusDate   time.Time
+	activeSince  time.Time
+
+	bytesIn         int64
+	bytesOut        int64
+	openConnections int64
+}
+
+func (stats *tunnelStats) setStatus(status, reason string, now time.Time) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	if status == stats.status && reason == "" {
+		return
+	}
+
+	if reason == "" {
+		reason = tunnelStatusReasons[status]
+	}
+
+	if status == portainer.EdgeAgentActive && stats.status != portainer.EdgeAgentActive {
+		stats.activeSince = now
+	} else if status != portainer.EdgeAgentActive {
+		stats.activeSince = time.Time{}
+	}
+
+	stats.status = status
+	stats.statusReason = reason
+	stats.statusDate = now
+}
+
+func (stats *tunnelStats) statistics(endpointID portainer.EndpointID, port int, now time.Time) portainer.TunnelStatistics {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	statistics := portainer.TunnelStatistics{
+		EndpointID:      endpointID,
+		Status:          stats.status,
+		Port:            port,
+		BytesIn:         atomic.LoadInt64(&stats.bytesIn),
+		BytesOut:        atomic.LoadInt64(&stats.bytesOut),
+		OpenConnections: atomic.LoadInt64(&stats.openConnections),
+		StatusReason:    stats.statusReason,
+		StatusDate:      stats.statusDate.Unix(),
+	}
+
+	if !stats.activeSince.IsZero() {
+		statistics.Uptime = int64(now.Sub(stats.activeSince).Seconds())
+	}
+
+	return statistics
+}
+
+// trackedBody counts the bytes of a request body sent to a tunnel
+type trackedBody struct {
+	io.ReadCloser
+	stats *tunnelStats
+}
+
+func (body *trackedBody) Read(b []byte) (int, error) {
+	n, err := body.ReadCloser.Read(b)
+	atomic.AddInt64(&body.stats.bytesOut, int64(n))
+	return n, err
+}
+
+// trackedResponseWriter counts the bytes of a response received from a tunnel
+type trackedResponseWriter struct {
+	http.ResponseWriter
+	stats *tunnelStats
+}
+
+func (writer *trackedResponseWriter) Write(b []byte) (int, error) {
+	n, err := writer.ResponseWriter.Write(b)
+	atomic.AddInt64(&writer.stats.bytesIn, int64(n))
+	return n, err
+}
+
+func (writer *trackedResponseWriter) Flush() {
+	if flusher, ok := writer.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (writer *trackedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := writer.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response writer does not support hijacking")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &trackedConn{Conn: conn, stats: writer.stats}, rw, nil
+}
+
+// trackedConn counts the bytes going through a connection hijacked from a request proxied to a tunnel,
+// the bytes read from the client are sent to the tunnel
+type trackedConn struct {
+	net.Conn
+	stats *tunnelStats
+}
+
+func (conn *trackedConn) Read(b []byte) (int, error) {
+	n, err := conn.Conn.Read(b)
+	atomic.AddInt64(&conn.stats.bytesOut, int64(n))
+	return n, err
+}
+
+func (conn *trackedConn) Write(b []byte) (int, error) {
+	n, err := conn.Conn.Write(b)
+	atomic.AddInt64(&conn.stats.bytesIn, int64(n))
+	return n, err
+}
+
+// stats returns the statistics of the tunnel of an endpoint, creating them when the tunnel is not tracked yet
+func (service *Service) stats(key string) *tunnelStats {
+	service.tunnelStatsMap.SetIfAbsent(key, &tunnelStats{})
+	item, _ := service.tunnelStatsMap.Get(key)
+	return item.(*tunnelStats)
+}
+
+// recordTunnelStatus records the current status of a tunnel, with the reason of the change when it is known
+func (service *Service) recordTunnelStatus(key string, status, reason string) {
+	service.stats(key).setStatus(status, reason, time.Now())
+}
+
+// TrackTunnelTraffic returns a handler counting the bytes of the requests proxied to the tunnel of an endpoint.
+// A request is counted as an open connection while it is served, the idle connections kept by the proxy
+// transports are not counted.
+func (service *Service) TrackTunnelTraffic(endpointID portainer.EndpointID, next http.Handler) http.Handler {
+	key := strconv.Itoa(int(endpointID))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats := service.stats(key)
+
+		atomic.AddInt64(&stats.openConnections, 1)
+		defer atomic.AddInt64(&stats.openConnections, -1)
+
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &trackedBody{ReadCloser: r.Body, stats: stats}
+		}
+
+		next.ServeHTTP(&trackedResponseWriter{ResponseWriter: w, stats: stats}, r)
+	})
+}
+
+// TunnelStatistics returns the state and the traffic of the tunnels, ordered by endpoint
+func (service *Service) TunnelStatistics() []portainer.TunnelStatistics {
+	now := time.Now()
+	statistics := make([]portainer.TunnelStatistics, 0)
+
+	for item := range service.tunnelDetailsMap.IterBuffered() {
+		tunnel := item.Val.(*portainer.TunnelDetails)
+
+		endpointID, err := strconv.Atoi(item.Key)
+		if err != nil {
+			continue
+		}
+
+		stats := service.stats(item.Key)
+		stats.setStatus(tunnel.Status, "", now)
+		statistics = append(statistics, stats.statistics(portainer.EndpointID(endpointID), tunnel.Port, now))
+	}
+
+	sort.Slice(statistics, func(i, j int) bool {
+		return statistics[i].EndpointID < statistics[j].EndpointID
+	})
+
+	return statistics
+}
+
+// CloseTunnel closes the tunnel of an endpoint by revoking its credentials and closing the session of the Edge agent.
+// The tunnel is opened again the next time Portainer needs to reach the endpoint.
+func (service *Service) CloseTunnel(endpointID portainer.EndpointID) error {
+	key := strconv.Itoa(int(endpointID))
+
+	if _, ok := service.tunnelDetailsMap.Get(key); !ok {
+		return nil
+	}
+
+	service.SetTunnelStatusToIdle(endpointID)
+	service.recordTunnelStatus(key, portainer.EdgeAgentIdle, tunnelClosedByAdministrator)
+	service.sessions.closeRevokedSessions()
+
+	return nil
+}
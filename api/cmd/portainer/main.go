@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"log"
 	"os"
 	"strings"
 
@@ -22,33 +21,89 @@ import (
 	kubeproxy "github.com/portainer/portainer/api/http/proxy/factory/kubernetes"
 	"github.com/portainer/portainer/api/internal/authorization"
 	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/internal/logging"
+	"github.com/portainer/portainer/api/internal/notification"
+	"github.com/portainer/portainer/api/internal/requestid"
 	"github.com/portainer/portainer/api/internal/snapshot"
+	"github.com/portainer/portainer/api/internal/tracing"
+	"github.com/portainer/portainer/api/internal/vulnerability"
 	"github.com/portainer/portainer/api/jwt"
 	"github.com/portainer/portainer/api/kubernetes"
 	kubecli "github.com/portainer/portainer/api/kubernetes/cli"
 	"github.com/portainer/portainer/api/ldap"
 	"github.com/portainer/portainer/api/libcompose"
 	"github.com/portainer/portainer/api/oauth"
+	"github.com/sirupsen/logrus"
 )
 
+var logger = logging.Component("main")
+
 func initCLI() *portainer.CLIFlags {
 	var cliService portainer.CLIService = &cli.Service{}
 	flags, err := cliService.ParseFlags(portainer.APIVersion)
 	if err != nil {
-		log.Fatalf("failed parsing flags: %v", err)
+		logger.Fatalf("failed parsing flags: %v", err)
 	}
 
 	err = cliService.ValidateFlags(flags)
 	if err != nil {
-		log.Fatalf("failed validating flags:%v", err)
+		logger.Fatalf("failed validating flags:%v", err)
 	}
 	return flags
 }
 
+func initLogger(flags *portainer.CLIFlags) {
+	level := logging.DefaultLevel
+	if flags.LogLevel != nil && *flags.LogLevel != "" {
+		level = *flags.LogLevel
+	}
+
+	format := logging.DefaultFormat
+	if flags.LogFormat != nil && *flags.LogFormat != "" {
+		format = *flags.LogFormat
+	}
+
+	err := logging.Configure(level, format)
+	if err != nil {
+		logger.Fatalf("failed configuring logger: %v", err)
+	}
+
+	logging.AddHook(requestid.LogHook{})
+}
+
+// initRequestTracer returns the tracer exporting the API requests to an OpenTelemetry collector,
+// or nil when no collector is configured
+func initRequestTracer(shutdownCtx context.Context) requestid.Tracer {
+	tracer := tracing.NewTracerFromEnvironment(shutdownCtx)
+	if tracer == nil {
+		return nil
+	}
+
+	tracer.Start()
+	return tracer
+}
+
+// initLogLevel applies the log level defined in the settings, the level of the flags is kept when none is defined
+func initLogLevel(dataStore portainer.DataStore) {
+	settings, err := dataStore.Settings().Settings()
+	if err != nil {
+		logger.Fatalf("failed fetching settings: %v", err)
+	}
+
+	if settings.LogLevel == "" {
+		return
+	}
+
+	err = logging.SetLevel(settings.LogLevel)
+	if err != nil {
+		logger.WithError(err).Warn("invalid log level in settings, keeping the log level of the flags")
+	}
+}
+
 func initFileService(dataStorePath string) portainer.FileService {
 	fileService, err := filesystem.NewService(dataStorePath, "")
 	if err != nil {
-		log.Fatalf("failed creating file service: %v", err)
+		logger.Fatalf("failed creating file service: %v", err)
 	}
 	return fileService
 }
@@ -56,27 +111,32 @@ func initFileService(dataStorePath string) portainer.FileService {
 func initDataStore(dataStorePath string, fileService portainer.FileService) portainer.DataStore {
 	store, err := bolt.NewStore(dataStorePath, fileService)
 	if err != nil {
-		log.Fatalf("failed creating data store: %v", err)
+		logger.Fatalf("failed creating data store: %v", err)
 	}
 
 	err = store.Open()
 	if err != nil {
-		log.Fatalf("failed opening store: %v", err)
+		logger.Fatalf("failed opening store: %v", err)
 	}
 
 	err = store.Init()
 	if err != nil {
-		log.Fatalf("failed initializing data store: %v", err)
+		logger.Fatalf("failed initializing data store: %v", err)
 	}
 
 	err = store.MigrateData(false)
 	if err != nil {
-		log.Fatalf("failed migration: %v", err)
+		logger.Fatalf("failed migration: %v", err)
 	}
 	return store
 }
 
 func initComposeStackManager(assetsPath string, dataStorePath string, reverseTunnelService portainer.ReverseTunnelService, proxyManager *proxy.Manager) portainer.ComposeStackManager {
+	composePluginWrapper := exec.NewComposePluginWrapper(assetsPath, dataStorePath, proxyManager)
+	if composePluginWrapper != nil {
+		return composePluginWrapper
+	}
+
 	composeWrapper := exec.NewComposeWrapper(assetsPath, dataStorePath, proxyManager)
 	if composeWrapper != nil {
 		return composeWrapper
@@ -138,11 +198,11 @@ func initKubernetesClientFactory(signatureService portainer.DigitalSignatureServ
 	return kubecli.NewClientFactory(signatureService, reverseTunnelService, instanceID)
 }
 
-func initSnapshotService(snapshotInterval string, dataStore portainer.DataStore, dockerClientFactory *docker.ClientFactory, kubernetesClientFactory *kubecli.ClientFactory, shutdownCtx context.Context) (portainer.SnapshotService, error) {
+func initSnapshotService(snapshotInterval string, dataStore portainer.DataStore, dockerClientFactory *docker.ClientFactory, kubernetesClientFactory *kubecli.ClientFactory, notificationService portainer.NotificationService, shutdownCtx context.Context) (portainer.SnapshotService, error) {
 	dockerSnapshotter := docker.NewSnapshotter(dockerClientFactory)
 	kubernetesSnapshotter := kubernetes.NewSnapshotter(kubernetesClientFactory)
 
-	snapshotService, err := snapshot.NewService(snapshotInterval, dataStore, dockerSnapshotter, kubernetesSnapshotter, shutdownCtx)
+	snapshotService, err := snapshot.NewService(snapshotInterval, dataStore, dockerSnapshotter, kubernetesSnapshotter, notificationService, shutdownCtx)
 	if err != nil {
 		return nil, err
 	}
@@ -196,10 +256,18 @@ func generateAndStoreKeyPair(fileService portainer.FileService, signatureService
 	return fileService.StoreKeyPair(private, public, privateHeader, publicHeader)
 }
 
+func initSecretService(fileService portainer.FileService) (portainer.SecretService, error) {
+	private, _, err := fileService.LoadKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	return crypto.NewSecretService(private)
+}
+
 func initKeyPair(fileService portainer.FileService, signatureService portainer.DigitalSignatureService) error {
 	existingKeyPair, err := fileService.KeyPairFilesExist()
 	if err != nil {
-		log.Fatalf("failed checking for existing key pair: %v", err)
+		logger.Fatalf("failed checking for existing key pair: %v", err)
 	}
 
 	if existingKeyPair {
@@ -270,7 +338,7 @@ func createTLSSecuredEndpoint(flags *portainer.CLIFlags, dataStore portainer.Dat
 
 	err := snapshotService.SnapshotEndpoint(endpoint)
 	if err != nil {
-		log.Printf("http error: endpoint snapshot error (endpoint=%s, URL=%s) (err=%s)\n", endpoint.Name, endpoint.URL, err)
+		logger.WithFields(logrus.Fields{logging.FieldEndpointID: endpoint.ID, "endpoint": endpoint.Name, "url": endpoint.URL}).WithError(err).Error("endpoint snapshot error")
 	}
 
 	return dataStore.Endpoint().CreateEndpoint(endpoint)
@@ -316,7 +384,7 @@ func createUnsecuredEndpoint(endpointURL string, dataStore portainer.DataStore,
 
 	err := snapshotService.SnapshotEndpoint(endpoint)
 	if err != nil {
-		log.Printf("http error: endpoint snapshot error (endpoint=%s, URL=%s) (err=%s)\n", endpoint.Name, endpoint.URL, err)
+		logger.WithFields(logrus.Fields{logging.FieldEndpointID: endpoint.ID, "endpoint": endpoint.Name, "url": endpoint.URL}).WithError(err).Error("endpoint snapshot error")
 	}
 
 	return dataStore.Endpoint().CreateEndpoint(endpoint)
@@ -333,7 +401,7 @@ func initEndpoint(flags *portainer.CLIFlags, dataStore portainer.DataStore, snap
 	}
 
 	if len(endpoints) > 0 {
-		log.Println("Instance already has defined endpoints. Skipping the endpoint defined via CLI.")
+		logger.Info("Instance already has defined endpoints. Skipping the endpoint defined via CLI.")
 		return nil
 	}
 
@@ -351,12 +419,14 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 	dataStore := initDataStore(*flags.Data, fileService)
 
 	if err := dataStore.CheckCurrentEdition(); err != nil {
-		log.Fatal(err)
+		logger.Fatal(err)
 	}
 
+	initLogLevel(dataStore)
+
 	jwtService, err := initJWTService(dataStore)
 	if err != nil {
-		log.Fatalf("failed initializing JWT service: %v", err)
+		logger.Fatalf("failed initializing JWT service: %v", err)
 	}
 
 	ldapService := initLDAPService()
@@ -371,31 +441,42 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 
 	err = initKeyPair(fileService, digitalSignatureService)
 	if err != nil {
-		log.Fatalf("failed initializing key pai: %v", err)
+		logger.Fatalf("failed initializing key pai: %v", err)
+	}
+
+	secretService, err := initSecretService(fileService)
+	if err != nil {
+		logger.Fatalf("failed initializing secret service: %v", err)
 	}
 
 	reverseTunnelService := chisel.NewService(dataStore, shutdownCtx)
 
+	notificationService := notification.NewService(dataStore, shutdownCtx)
+	notificationService.Start()
+
 	instanceID, err := dataStore.Version().InstanceID()
 	if err != nil {
-		log.Fatalf("failed getting instance id: %v", err)
+		logger.Fatalf("failed getting instance id: %v", err)
 	}
 
 	dockerClientFactory := initDockerClientFactory(digitalSignatureService, reverseTunnelService)
 	kubernetesClientFactory := initKubernetesClientFactory(digitalSignatureService, reverseTunnelService, instanceID)
 
-	snapshotService, err := initSnapshotService(*flags.SnapshotInterval, dataStore, dockerClientFactory, kubernetesClientFactory, shutdownCtx)
+	snapshotService, err := initSnapshotService(*flags.SnapshotInterval, dataStore, dockerClientFactory, kubernetesClientFactory, notificationService, shutdownCtx)
 	if err != nil {
-		log.Fatalf("failed initializing snapshot service: %v", err)
+		logger.Fatalf("failed initializing snapshot service: %v", err)
 	}
 	snapshotService.Start()
 
+	vulnerabilityService := vulnerability.NewService(dataStore, *flags.Assets, shutdownCtx)
+	vulnerabilityService.Start()
+
 	authorizationService := authorization.NewService(dataStore)
 	authorizationService.K8sClientFactory = kubernetesClientFactory
 
 	swarmStackManager, err := initSwarmStackManager(*flags.Assets, *flags.Data, digitalSignatureService, fileService, reverseTunnelService)
 	if err != nil {
-		log.Fatalf("failed initializing swarm stack manager: %v", err)
+		logger.Fatalf("failed initializing swarm stack manager: %v", err)
 	}
 	kubernetesTokenCacheManager := kubeproxy.NewTokenCacheManager()
 	proxyManager := proxy.NewManager(dataStore, digitalSignatureService, reverseTunnelService, dockerClientFactory, kubernetesClientFactory, kubernetesTokenCacheManager)
@@ -407,31 +488,31 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 	if dataStore.IsNew() {
 		err = updateSettingsFromFlags(dataStore, flags)
 		if err != nil {
-			log.Fatalf("failed updating settings from flags: %v", err)
+			logger.Fatalf("failed updating settings from flags: %v", err)
 		}
 	}
 
 	err = edge.LoadEdgeJobs(dataStore, reverseTunnelService)
 	if err != nil {
-		log.Fatalf("failed loading edge jobs from database: %v", err)
+		logger.Fatalf("failed loading edge jobs from database: %v", err)
 	}
 
 	applicationStatus := initStatus(flags)
 
 	err = initEndpoint(flags, dataStore, snapshotService)
 	if err != nil {
-		log.Fatalf("failed initializing endpoint: %v", err)
+		logger.Fatalf("failed initializing endpoint: %v", err)
 	}
 
 	adminPasswordHash := ""
 	if *flags.AdminPasswordFile != "" {
 		content, err := fileService.GetFileContent(*flags.AdminPasswordFile)
 		if err != nil {
-			log.Fatalf("failed getting admin password file: %v", err)
+			logger.Fatalf("failed getting admin password file: %v", err)
 		}
 		adminPasswordHash, err = cryptoService.Hash(strings.TrimSuffix(string(content), "\n"))
 		if err != nil {
-			log.Fatalf("failed hashing admin password: %v", err)
+			logger.Fatalf("failed hashing admin password: %v", err)
 		}
 	} else if *flags.AdminPassword != "" {
 		adminPasswordHash = *flags.AdminPassword
@@ -440,11 +521,11 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 	if adminPasswordHash != "" {
 		users, err := dataStore.User().UsersByRole(portainer.AdministratorRole)
 		if err != nil {
-			log.Fatalf("failed getting admin user: %v", err)
+			logger.Fatalf("failed getting admin user: %v", err)
 		}
 
 		if len(users) == 0 {
-			log.Println("Created admin user with the given password.")
+			logger.Info("Created admin user with the given password.")
 			user := &portainer.User{
 				Username: "admin",
 				Role:     portainer.AdministratorRole,
@@ -452,16 +533,16 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 			}
 			err := dataStore.User().CreateUser(user)
 			if err != nil {
-				log.Fatalf("failed creating admin user: %v", err)
+				logger.Fatalf("failed creating admin user: %v", err)
 			}
 		} else {
-			log.Println("Instance already has an administrator user defined. Skipping admin password related flags.")
+			logger.Info("Instance already has an administrator user defined. Skipping admin password related flags.")
 		}
 	}
 
 	err = reverseTunnelService.StartTunnelServer(*flags.TunnelAddr, *flags.TunnelPort, snapshotService)
 	if err != nil {
-		log.Fatalf("failed starting license service: %s", err)
+		logger.Fatalf("failed starting license service: %s", err)
 	}
 
 	return &http.Server{
@@ -478,17 +559,21 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 		JWTService:                  jwtService,
 		FileService:                 fileService,
 		LDAPService:                 ldapService,
+		NotificationService:         notificationService,
 		OAuthService:                oauthService,
 		GitService:                  gitService,
 		ProxyManager:                proxyManager,
 		KubernetesTokenCacheManager: kubernetesTokenCacheManager,
 		SignatureService:            digitalSignatureService,
+		SecretService:               secretService,
 		SnapshotService:             snapshotService,
+		VulnerabilityService:        vulnerabilityService,
 		SSL:                         *flags.SSL,
 		SSLCert:                     *flags.SSLCert,
 		SSLKey:                      *flags.SSLKey,
 		DockerClientFactory:         dockerClientFactory,
 		KubernetesClientFactory:     kubernetesClientFactory,
+		RequestTracer:               initRequestTracer(shutdownCtx),
 		ShutdownCtx:                 shutdownCtx,
 		ShutdownTrigger:             shutdownTrigger,
 	}
@@ -497,10 +582,12 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 func main() {
 	flags := initCLI()
 
+	initLogger(flags)
+
 	for {
 		server := buildServer(flags)
-		log.Printf("Starting Portainer %s on %s\n", portainer.APIVersion, *flags.Addr)
+		logger.WithField("version", portainer.APIVersion).WithField("address", *flags.Addr).Info("starting Portainer")
 		err := server.Start()
-		log.Printf("Http server exited: %s\n", err)
+		logger.WithError(err).Info("HTTP server exited")
 	}
 }
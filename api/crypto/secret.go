@@ -0,0 +1,67 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+var errInvalidSecret = errors.New("invalid encrypted secret")
+
+// SecretService encrypts the secrets stored in the database with AES-256-GCM
+type SecretService struct {
+	aead cipher.AEAD
+}
+
+// NewSecretService returns a SecretService using a key derived from the specified seed,
+// usually the private key of the instance
+func NewSecretService(seed []byte) (*SecretService, error) {
+	key := sha256.Sum256(seed)
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretService{aead: aead}, nil
+}
+
+// Encrypt returns the base64 encoded nonce and ciphertext of the value
+func (service *SecretService) Encrypt(value string) (string, error) {
+	nonce := make([]byte, service.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := service.aead.Seal(nonce, nonce, []byte(value), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the value encrypted by Encrypt
+func (service *SecretService) Decrypt(value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", errInvalidSecret
+	}
+
+	nonceSize := service.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", errInvalidSecret
+	}
+
+	plaintext, err := service.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", errInvalidSecret
+	}
+
+	return string(plaintext), nil
+}
@@ -0,0 +1,28 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SecretService_EncryptDecrypt(t *testing.T) {
+	service, err := NewSecretService([]byte("private key"))
+	assert.NoError(t, err)
+
+	encrypted, err := service.Encrypt("password")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "password", encrypted)
+
+	decrypted, err := service.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "password", decrypted)
+
+	other, err := NewSecretService([]byte("other key"))
+	assert.NoError(t, err)
+	_, err = other.Decrypt(encrypted)
+	assert.Error(t, err)
+
+	_, err = service.Decrypt("not encrypted")
+	assert.Error(t, err)
+}
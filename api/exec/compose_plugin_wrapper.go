@@ -0,0 +1,142 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/proxy"
+)
+
+var composeProjectNameInvalidCharsRe = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// ComposePluginWrapper is a wrapper for the docker compose (v2) CLI plugin.
+// It supports the compose specification, including files without a version
+// as well as profiles, include and depends_on conditions.
+type ComposePluginWrapper struct {
+	binaryPath   string
+	dataPath     string
+	proxyManager *proxy.Manager
+}
+
+// NewComposePluginWrapper returns a docker compose plugin wrapper if the docker binary
+// and the compose plugin are present, otherwise nil
+func NewComposePluginWrapper(binaryPath, dataPath string, proxyManager *proxy.Manager) *ComposePluginWrapper {
+	program := programPath(binaryPath, "docker")
+	if !IsBinaryPresent(program) {
+		return nil
+	}
+
+	cmd := exec.Command(program, "compose", "version", "--short")
+	cmd.Env = append(os.Environ(), fmt.Sprintf("DOCKER_CONFIG=%s", dataPath))
+	if err := cmd.Run(); err != nil {
+		return nil
+	}
+
+	return &ComposePluginWrapper{
+		binaryPath:   binaryPath,
+		dataPath:     dataPath,
+		proxyManager: proxyManager,
+	}
+}
+
+// ComposeSyntaxMaxVersion returns the maximum supported version of the versioned docker compose syntax
+func (w *ComposePluginWrapper) ComposeSyntaxMaxVersion() string {
+	return portainer.ComposeSyntaxMaxVersion
+}
+
+// ComposeSpecSupported returns true, the compose plugin implements the compose specification
+// where the version of the stack file is optional
+func (w *ComposePluginWrapper) ComposeSpecSupported() bool {
+	return true
+}
+
+// NormalizeStackName returns a new stack name that is a valid compose project name.
+// Compose v2 only accepts lowercase alphanumeric characters, dashes and underscores.
+// It is applied to the project name of every stack, including the stacks created before
+// the compose plugin was available.
+func (w *ComposePluginWrapper) NormalizeStackName(name string) string {
+	name = strings.ToLower(name)
+	name = composeProjectNameInvalidCharsRe.ReplaceAllString(name, "")
+	return strings.TrimLeft(name, "_-")
+}
+
+// Up builds, (re)creates and starts containers in the background. Wraps `docker compose up -d --remove-orphans` command
+func (w *ComposePluginWrapper) Up(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	_, err := w.command([]string{"up", "-d", "--remove-orphans"}, stack, endpoint, nil)
+	return err
+}
+
+// UpWithOutput behaves like Up and copies the docker compose output to the specified writer while the command runs
+func (w *ComposePluginWrapper) UpWithOutput(stack *portainer.Stack, endpoint *portainer.Endpoint, output io.Writer) error {
+	_, err := w.command([]string{"up", "-d", "--remove-orphans"}, stack, endpoint, output)
+	return err
+}
+
+// Down stops and removes containers, networks, images, and volumes. Wraps `docker compose down --remove-orphans` command
+func (w *ComposePluginWrapper) Down(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	_, err := w.command([]string{"down", "--remove-orphans"}, stack, endpoint, nil)
+	return err
+}
+
+func (w *ComposePluginWrapper) command(command []string, stack *portainer.Stack, endpoint *portainer.Endpoint, output io.Writer) ([]byte, error) {
+	if endpoint == nil {
+		return nil, errors.New("cannot call a compose command on an empty endpoint")
+	}
+
+	program := programPath(w.binaryPath, "docker")
+
+	// global docker CLI options must be set before the compose sub-command
+	args := make([]string, 0)
+
+	if !(endpoint.URL == "" || strings.HasPrefix(endpoint.URL, "unix://") || strings.HasPrefix(endpoint.URL, "npipe://")) {
+		proxy, err := w.proxyManager.CreateComposeProxyServer(endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		defer proxy.Close()
+
+		args = append(args, "-H", fmt.Sprintf("http://127.0.0.1:%d", proxy.Port))
+	}
+
+	args = append(args, "compose")
+
+	options := setComposeFile(stack)
+	options = addProjectNameOption(options, w.NormalizeStackName(stackName(stack)))
+	options, err := addEnvFileOption(options, stack)
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, options...)
+	args = append(args, command...)
+
+	cmd := exec.Command(program, args...)
+	cmd.Env = os.Environ()
+	cmd.Env = append(cmd.Env, fmt.Sprintf("DOCKER_CONFIG=%s", w.dataPath))
+	cmd.Env = append(cmd.Env, composeProfilesEnv(stack)...)
+
+	return runCommand(cmd, output)
+}
+
+// composeProfilesEnv forwards the COMPOSE_PROFILES stack environment variable to the compose process
+// so that services assigned to the listed profiles are deployed.
+func composeProfilesEnv(stack *portainer.Stack) []string {
+	if stack == nil {
+		return nil
+	}
+
+	for _, v := range stack.Env {
+		if v.Name == "COMPOSE_PROFILES" {
+			return []string{fmt.Sprintf("COMPOSE_PROFILES=%s", v.Value)}
+		}
+	}
+
+	return nil
+}
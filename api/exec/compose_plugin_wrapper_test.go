@@ -0,0 +1,38 @@
+package exec
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ComposePluginWrapper_NormalizeStackName(t *testing.T) {
+	w := &ComposePluginWrapper{}
+
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "mystack", expected: "mystack"},
+		{input: "My.Stack", expected: "mystack"},
+		{input: "my_stack-1", expected: "my_stack-1"},
+		{input: "-_stack", expected: "stack"},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, w.NormalizeStackName(tt.input))
+		})
+	}
+}
+
+func Test_composeProfilesEnv(t *testing.T) {
+	assert.Nil(t, composeProfilesEnv(nil))
+
+	stack := &portainer.Stack{Env: []portainer.Pair{{Name: "FOO", Value: "bar"}}}
+	assert.Nil(t, composeProfilesEnv(stack))
+
+	stack.Env = append(stack.Env, portainer.Pair{Name: "COMPOSE_PROFILES", Value: "debug,metrics"})
+	assert.Equal(t, []string{"COMPOSE_PROFILES=debug,metrics"}, composeProfilesEnv(stack))
+}
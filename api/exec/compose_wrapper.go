@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path"
@@ -38,6 +39,11 @@ func (w *ComposeWrapper) ComposeSyntaxMaxVersion() string {
 	return portainer.ComposeSyntaxMaxVersion
 }
 
+// ComposeSpecSupported returns false, docker-compose requires a versioned stack file
+func (w *ComposeWrapper) ComposeSpecSupported() bool {
+	return false
+}
+
 // NormalizeStackName returns a new stack name with unsupported characters replaced
 func (w *ComposeWrapper) NormalizeStackName(name string) string {
 	return name
@@ -45,17 +51,23 @@ func (w *ComposeWrapper) NormalizeStackName(name string) string {
 
 // Up builds, (re)creates and starts containers in the background. Wraps `docker-compose up -d` command
 func (w *ComposeWrapper) Up(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
-	_, err := w.command([]string{"up", "-d"}, stack, endpoint)
+	_, err := w.command([]string{"up", "-d"}, stack, endpoint, nil)
+	return err
+}
+
+// UpWithOutput behaves like Up and copies the docker-compose output to the specified writer while the command runs
+func (w *ComposeWrapper) UpWithOutput(stack *portainer.Stack, endpoint *portainer.Endpoint, output io.Writer) error {
+	_, err := w.command([]string{"up", "-d"}, stack, endpoint, output)
 	return err
 }
 
 // Down stops and removes containers, networks, images, and volumes. Wraps `docker-compose down --remove-orphans` command
 func (w *ComposeWrapper) Down(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
-	_, err := w.command([]string{"down", "--remove-orphans"}, stack, endpoint)
+	_, err := w.command([]string{"down", "--remove-orphans"}, stack, endpoint, nil)
 	return err
 }
 
-func (w *ComposeWrapper) command(command []string, stack *portainer.Stack, endpoint *portainer.Endpoint) ([]byte, error) {
+func (w *ComposeWrapper) command(command []string, stack *portainer.Stack, endpoint *portainer.Endpoint, output io.Writer) ([]byte, error) {
 	if endpoint == nil {
 		return nil, errors.New("cannot call a compose command on an empty endpoint")
 	}
@@ -64,7 +76,7 @@ func (w *ComposeWrapper) command(command []string, stack *portainer.Stack, endpo
 
 	options := setComposeFile(stack)
 
-	options = addProjectNameOption(options, stack)
+	options = addProjectNameOption(options, stackName(stack))
 	options, err := addEnvFileOption(options, stack)
 	if err != nil {
 		return nil, err
@@ -84,18 +96,31 @@ func (w *ComposeWrapper) command(command []string, stack *portainer.Stack, endpo
 
 	args := append(options, command...)
 
-	var stderr bytes.Buffer
 	cmd := exec.Command(program, args...)
 	cmd.Env = os.Environ()
 	cmd.Env = append(cmd.Env, fmt.Sprintf("DOCKER_CONFIG=%s", w.dataPath))
+
+	return runCommand(cmd, output)
+}
+
+// runCommand runs the command and returns its standard output. The standard error is returned as an error
+// when the command fails. When output is not nil, both streams are also copied to it while the command runs.
+func runCommand(cmd *exec.Cmd, output io.Writer) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	out, err := cmd.Output()
+	if output != nil {
+		cmd.Stdout = io.MultiWriter(&stdout, output)
+		cmd.Stderr = io.MultiWriter(&stderr, output)
+	}
+
+	err := cmd.Run()
 	if err != nil {
-		return out, errors.New(stderr.String())
+		return stdout.Bytes(), errors.New(stderr.String())
 	}
 
-	return out, nil
+	return stdout.Bytes(), nil
 }
 
 func setComposeFile(stack *portainer.Stack) []string {
@@ -110,15 +135,22 @@ func setComposeFile(stack *portainer.Stack) []string {
 	return options
 }
 
-func addProjectNameOption(options []string, stack *portainer.Stack) []string {
-	if stack == nil || stack.Name == "" {
+func addProjectNameOption(options []string, projectName string) []string {
+	if projectName == "" {
 		return options
 	}
 
-	options = append(options, "-p", stack.Name)
+	options = append(options, "-p", projectName)
 	return options
 }
 
+func stackName(stack *portainer.Stack) string {
+	if stack == nil {
+		return ""
+	}
+	return stack.Name
+}
+
 func addEnvFileOption(options []string, stack *portainer.Stack) ([]string, error) {
 	if stack == nil || stack.Env == nil || len(stack.Env) == 0 {
 		return options, nil
@@ -73,3 +73,55 @@ func containerExists(contaierName string) bool {
 
 	return strings.Contains(string(out), contaierName)
 }
+
+const composeSpecFile = `services:
+  busybox:
+    image: "alpine:latest"
+    container_name: "compose_plugin_wrapper_test"
+  debug:
+    image: "alpine:latest"
+    container_name: "compose_plugin_wrapper_debug_test"
+    profiles: ["debug"]`
+const composedPluginContainerName = "compose_plugin_wrapper_test"
+const composedPluginProfileContainerName = "compose_plugin_wrapper_debug_test"
+
+func Test_PluginUpAndDown(t *testing.T) {
+	dir := t.TempDir()
+	composeFileName := "compose_plugin_wrapper_test.yml"
+	f, _ := os.Create(filepath.Join(dir, composeFileName))
+	f.WriteString(composeSpecFile)
+
+	stack := &portainer.Stack{
+		ProjectPath: dir,
+		EntryPoint:  composeFileName,
+		Name:        "project-name",
+	}
+	endpoint := &portainer.Endpoint{}
+
+	w := NewComposePluginWrapper("", "", nil)
+	if w == nil {
+		t.Skip("docker compose plugin is not available")
+	}
+
+	err := w.Up(stack, endpoint)
+	if err != nil {
+		t.Fatalf("Error calling docker compose up: %s", err)
+	}
+
+	if containerExists(composedPluginContainerName) == false {
+		t.Fatal("container should exist")
+	}
+
+	if containerExists(composedPluginProfileContainerName) {
+		t.Fatal("container assigned to an inactive profile should not exist")
+	}
+
+	err = w.Down(stack, endpoint)
+	if err != nil {
+		t.Fatalf("Error calling docker compose down: %s", err)
+	}
+
+	if containerExists(composedPluginContainerName) {
+		t.Fatal("container should be removed")
+	}
+}
@@ -16,6 +16,7 @@ import (
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/crypto"
+	"github.com/portainer/portainer/api/internal/requestid"
 )
 
 // KubernetesDeployer represents a service to deploy resources inside a Kubernetes environment.
@@ -38,7 +39,8 @@ func NewKubernetesDeployer(datastore portainer.DataStore, reverseTunnelService p
 
 // Deploy will deploy a Kubernetes manifest inside a specific namespace in a Kubernetes endpoint.
 // Otherwise it will use kubectl to deploy the manifest.
-func (deployer *KubernetesDeployer) Deploy(endpoint *portainer.Endpoint, stackConfig string, namespace string) (string, error) {
+// The request identifier, if any, is forwarded to the agent.
+func (deployer *KubernetesDeployer) Deploy(endpoint *portainer.Endpoint, stackConfig string, namespace string, requestID string) (string, error) {
 	if endpoint.Type == portainer.KubernetesLocalEnvironment {
 		token, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/token")
 		if err != nil {
@@ -141,6 +143,9 @@ func (deployer *KubernetesDeployer) Deploy(endpoint *portainer.Endpoint, stackCo
 
 	req.Header.Set(portainer.PortainerAgentPublicKeyHeader, deployer.signatureService.EncodedPublicKey())
 	req.Header.Set(portainer.PortainerAgentSignatureHeader, signature)
+	if requestID != "" {
+		req.Header.Set(requestid.Header, requestID)
+	}
 
 	resp, err := httpCli.Do(req)
 	if err != nil {
@@ -14,6 +14,8 @@ import (
 	"io"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
 )
 
 const (
@@ -534,3 +536,39 @@ func MoveDirectory(originalPath, newPath string) error {
 
 	return os.Rename(originalPath, newPath)
 }
+
+// ErrPathOutsideRoot is returned when a path resolves outside of its root directory
+var ErrPathOutsideRoot = errors.New("The path resolves outside of its root directory")
+
+// JoinPaths joins a relative path coming from a user, such as the path of a file inside a git repository,
+// to a root directory. An error is returned when the path, or the target of the symbolic links it contains,
+// resolves outside of the root directory.
+func JoinPaths(root, relativePath string) (string, error) {
+	joinedPath := filepath.Join(root, filepath.Clean(relativePath))
+	if !isWithin(root, joinedPath) {
+		return "", ErrPathOutsideRoot
+	}
+
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+
+	resolvedPath, err := filepath.EvalSymlinks(joinedPath)
+	if os.IsNotExist(err) {
+		return joinedPath, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	if !isWithin(resolvedRoot, resolvedPath) {
+		return "", ErrPathOutsideRoot
+	}
+
+	return joinedPath, nil
+}
+
+func isWithin(root, target string) bool {
+	relativePath, err := filepath.Rel(root, target)
+	return err == nil && relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,43 @@
+package filesystem
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_JoinPaths(t *testing.T) {
+	tmpDir := tempDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	root := path.Join(tmpDir, "repository")
+	err := os.MkdirAll(path.Join(root, "deploy"), 0700)
+	assert.NoError(t, err)
+
+	err = os.Symlink("/etc/passwd", path.Join(root, "deploy", "escape.yml"))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		relativePath string
+		expected     string
+		err          error
+	}{
+		{name: "file in a subfolder", relativePath: "deploy/docker-compose.yml", expected: path.Join(root, "deploy", "docker-compose.yml")},
+		{name: "absolute path is relative to the root", relativePath: "/deploy/docker-compose.yml", expected: path.Join(root, "deploy", "docker-compose.yml")},
+		{name: "parent folder inside the root", relativePath: "deploy/../docker-compose.yml", expected: path.Join(root, "docker-compose.yml")},
+		{name: "parent folder outside the root", relativePath: "../docker-compose.yml", err: ErrPathOutsideRoot},
+		{name: "sibling folder with the root as prefix", relativePath: "../repository-old/docker-compose.yml", err: ErrPathOutsideRoot},
+		{name: "symbolic link outside the root", relativePath: "deploy/escape.yml", err: ErrPathOutsideRoot},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			joinedPath, err := JoinPaths(root, tt.relativePath)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.expected, joinedPath)
+		})
+	}
+}
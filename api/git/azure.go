@@ -2,6 +2,7 @@ package git
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/portainer/portainer/api/archive"
@@ -57,6 +58,85 @@ func (a *azureDownloader) download(ctx context.Context, destination string, opti
 	return nil
 }
 
+func (a *azureDownloader) latestCommitID(ctx context.Context, options cloneOptions) (string, error) {
+	if options.referenceName != "" && getVersionType(options.referenceName) == "commit" {
+		return options.referenceName, nil
+	}
+
+	config, err := parseUrl(options.repositoryUrl)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to parse url")
+	}
+
+	referenceName := options.referenceName
+	if referenceName == "" {
+		var repository struct {
+			DefaultBranch string `json:"defaultBranch"`
+		}
+
+		err = a.getJSON(ctx, a.buildRepositoryUrl(config, ""), options, config, &repository)
+		if err != nil {
+			return "", errors.WithMessage(err, "failed to retrieve the repository default branch")
+		}
+		referenceName = repository.DefaultBranch
+	}
+
+	var refs struct {
+		Value []struct {
+			Name     string `json:"name"`
+			ObjectID string `json:"objectId"`
+		} `json:"value"`
+	}
+
+	refsUrl := a.buildRepositoryUrl(config, "/refs") + "&filter=" + url.QueryEscape(strings.TrimPrefix(referenceName, "refs/"))
+	err = a.getJSON(ctx, refsUrl, options, config, &refs)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to retrieve the repository refs")
+	}
+
+	for _, ref := range refs.Value {
+		if ref.Name == referenceName {
+			return ref.ObjectID, nil
+		}
+	}
+
+	return "", errors.Errorf("could not find the reference %s in the repository", referenceName)
+}
+
+func (a *azureDownloader) buildRepositoryUrl(config *azureOptions, path string) string {
+	return fmt.Sprintf("%s/%s/%s/_apis/git/repositories/%s%s?api-version=6.0",
+		a.baseUrl,
+		url.PathEscape(config.organisation),
+		url.PathEscape(config.project),
+		url.PathEscape(config.repository),
+		path)
+}
+
+func (a *azureDownloader) getJSON(ctx context.Context, rawUrl string, options cloneOptions, config *azureOptions, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawUrl, nil)
+	if err != nil {
+		return errors.WithMessage(err, "failed to create a new HTTP request")
+	}
+
+	if options.username != "" || options.password != "" {
+		req.SetBasicAuth(options.username, options.password)
+	} else if config.username != "" || config.password != "" {
+		req.SetBasicAuth(config.username, config.password)
+	}
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return errors.WithMessage(err, "failed to make an HTTP request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get %s with a status \"%v\"", rawUrl, res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(target)
+}
+
 func (a *azureDownloader) downloadZipFromAzureDevOps(ctx context.Context, options cloneOptions) (string, error) {
 	config, err := parseUrl(options.repositoryUrl)
 	if err != nil {
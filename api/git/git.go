@@ -11,9 +11,11 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/transport/client"
 	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
 )
 
 type cloneOptions struct {
@@ -21,11 +23,13 @@ type cloneOptions struct {
 	username      string
 	password      string
 	referenceName string
+	commitID      string
 	depth         int
 }
 
 type downloader interface {
 	download(ctx context.Context, dst string, opt cloneOptions) error
+	latestCommitID(ctx context.Context, opt cloneOptions) (string, error)
 }
 
 type gitClient struct {
@@ -49,12 +53,28 @@ func (c gitClient) download(ctx context.Context, dst string, opt cloneOptions) e
 		gitOptions.ReferenceName = plumbing.ReferenceName(opt.referenceName)
 	}
 
-	_, err := git.PlainCloneContext(ctx, dst, false, &gitOptions)
+	if opt.commitID != "" {
+		gitOptions.NoCheckout = true
+	}
+
+	repository, err := git.PlainCloneContext(ctx, dst, false, &gitOptions)
 
 	if err != nil {
 		return errors.Wrap(err, "failed to clone git repository")
 	}
 
+	if opt.commitID != "" {
+		worktree, err := repository.Worktree()
+		if err != nil {
+			return errors.Wrap(err, "failed to open the git worktree")
+		}
+
+		err = worktree.Checkout(&git.CheckoutOptions{Hash: plumbing.NewHash(opt.commitID), Force: true})
+		if err != nil {
+			return errors.Wrapf(err, "failed to checkout commit %s", opt.commitID)
+		}
+	}
+
 	if !c.preserveGitDirectory {
 		os.RemoveAll(filepath.Join(dst, ".git"))
 	}
@@ -62,6 +82,55 @@ func (c gitClient) download(ctx context.Context, dst string, opt cloneOptions) e
 	return nil
 }
 
+func (c gitClient) latestCommitID(ctx context.Context, opt cloneOptions) (string, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{opt.repositoryUrl},
+	})
+
+	listOptions := &git.ListOptions{}
+	if opt.password != "" || opt.username != "" {
+		listOptions.Auth = &githttp.BasicAuth{
+			Username: opt.username,
+			Password: opt.password,
+		}
+	}
+
+	refs, err := remote.List(listOptions)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list repository refs")
+	}
+
+	referenceName := opt.referenceName
+	if referenceName == "" {
+		referenceName = string(plumbing.HEAD)
+	}
+
+	for _, ref := range refs {
+		if ref.Name().String() != referenceName {
+			continue
+		}
+
+		if ref.Type() == plumbing.SymbolicReference {
+			return c.resolveReference(refs, ref.Target().String())
+		}
+
+		return ref.Hash().String(), nil
+	}
+
+	return "", errors.Errorf("could not find the reference %s in the repository", referenceName)
+}
+
+func (c gitClient) resolveReference(refs []*plumbing.Reference, referenceName string) (string, error) {
+	for _, ref := range refs {
+		if ref.Name().String() == referenceName && ref.Type() == plumbing.HashReference {
+			return ref.Hash().String(), nil
+		}
+	}
+
+	return "", errors.Errorf("could not find the reference %s in the repository", referenceName)
+}
+
 // Service represents a service for managing Git.
 type Service struct {
 	httpsCli *http.Client
@@ -101,6 +170,42 @@ func (service *Service) CloneRepository(destination, repositoryURL, referenceNam
 	return service.cloneRepository(destination, options)
 }
 
+// CloneRepositoryAtCommit clones a git repository using the specified URL in the specified
+// destination folder and checks out the specified commit of the reference.
+func (service *Service) CloneRepositoryAtCommit(destination, repositoryURL, referenceName, commitID, username, password string) error {
+	options := cloneOptions{
+		repositoryUrl: repositoryURL,
+		username:      username,
+		password:      password,
+		referenceName: referenceName,
+		commitID:      commitID,
+	}
+
+	if isAzureUrl(options.repositoryUrl) {
+		// Azure DevOps resolves a commit identifier as a version descriptor of the download
+		options.referenceName = commitID
+	}
+
+	return service.cloneRepository(destination, options)
+}
+
+// LatestCommitID returns the identifier of the latest commit of the specified reference
+// of a remote repository, without cloning it.
+func (service *Service) LatestCommitID(repositoryURL, referenceName, username, password string) (string, error) {
+	options := cloneOptions{
+		repositoryUrl: repositoryURL,
+		username:      username,
+		password:      password,
+		referenceName: referenceName,
+	}
+
+	if isAzureUrl(options.repositoryUrl) {
+		return service.azure.latestCommitID(context.TODO(), options)
+	}
+
+	return service.git.latestCommitID(context.TODO(), options)
+}
+
 func (service *Service) cloneRepository(destination string, options cloneOptions) error {
 	if isAzureUrl(options.repositoryUrl) {
 		return service.azure.download(context.TODO(), destination, options)
@@ -108,6 +108,64 @@ func Test_cloneRepository(t *testing.T) {
 	assert.Equal(t, 3, getCommitHistoryLength(t, err, dir), "cloned repo has incorrect depth")
 }
 
+func Test_latestCommitID(t *testing.T) {
+	service := Service{git: gitClient{}}
+
+	repo, err := git.PlainOpen(bareRepoDir)
+	if err != nil {
+		t.Fatalf("can't open a git repo at %s with error %v", bareRepoDir, err)
+	}
+	ref, err := repo.Reference("refs/heads/main", true)
+	if err != nil {
+		t.Fatalf("can't resolve the main branch with error %v", err)
+	}
+
+	id, err := service.LatestCommitID(bareRepoDir, "refs/heads/main", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, ref.Hash().String(), id)
+
+	_, err = service.LatestCommitID(bareRepoDir, "refs/heads/unknown", "", "")
+	assert.Error(t, err)
+}
+
+func Test_CloneRepositoryAtCommit(t *testing.T) {
+	service := Service{git: gitClient{preserveGitDirectory: true}}
+
+	repo, err := git.PlainOpen(bareRepoDir)
+	if err != nil {
+		t.Fatalf("can't open a git repo at %s with error %v", bareRepoDir, err)
+	}
+	ref, err := repo.Reference("refs/heads/main", true)
+	if err != nil {
+		t.Fatalf("can't resolve the main branch with error %v", err)
+	}
+	head, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		t.Fatalf("can't load the head commit with error %v", err)
+	}
+	parent, err := head.Parent(0)
+	if err != nil {
+		t.Fatalf("can't load the parent commit with error %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "commit")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir")
+	}
+	defer os.RemoveAll(dir)
+
+	err = service.CloneRepositoryAtCommit(dir, bareRepoDir, "refs/heads/main", parent.Hash.String(), "", "")
+	assert.NoError(t, err)
+
+	cloned, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatalf("can't open a git repo at %s with error %v", dir, err)
+	}
+	clonedHead, err := cloned.Head()
+	assert.NoError(t, err)
+	assert.Equal(t, parent.Hash.String(), clonedHead.Hash().String())
+}
+
 func getCommitHistoryLength(t *testing.T, err error, dir string) int {
 	repo, err := git.PlainOpen(dir)
 	if err != nil {
@@ -137,6 +195,11 @@ func (t *testDownloader) download(_ context.Context, _ string, _ cloneOptions) e
 	return nil
 }
 
+func (t *testDownloader) latestCommitID(_ context.Context, _ cloneOptions) (string, error) {
+	t.called = true
+	return "", nil
+}
+
 func Test_cloneRepository_azure(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -4,4 +4,12 @@ type RepoConfig struct {
 	URL            string
 	ReferenceName  string
 	ConfigFilePath string
+	ConfigHash     string
+	// Credentials used to access the repository, nil for a public repository
+	Authentication *GitAuthentication
+}
+
+type GitAuthentication struct {
+	Username string
+	Password string
 }
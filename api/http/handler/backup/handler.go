@@ -15,24 +15,26 @@ import (
 // Handler is an http handler responsible for backup and restore portainer state
 type Handler struct {
 	*mux.Router
-	bouncer         *security.RequestBouncer
-	dataStore       portainer.DataStore
-	gate            *offlinegate.OfflineGate
-	filestorePath   string
-	shutdownTrigger context.CancelFunc
-	adminMonitor    *adminmonitor.Monitor
+	bouncer             *security.RequestBouncer
+	dataStore           portainer.DataStore
+	gate                *offlinegate.OfflineGate
+	filestorePath       string
+	shutdownTrigger     context.CancelFunc
+	adminMonitor        *adminmonitor.Monitor
+	notificationService portainer.NotificationService
 }
 
 // NewHandler creates an new instance of backup handler
-func NewHandler(bouncer *security.RequestBouncer, dataStore portainer.DataStore, gate *offlinegate.OfflineGate, filestorePath string, shutdownTrigger context.CancelFunc, adminMonitor *adminmonitor.Monitor) *Handler {
+func NewHandler(bouncer *security.RequestBouncer, dataStore portainer.DataStore, gate *offlinegate.OfflineGate, filestorePath string, shutdownTrigger context.CancelFunc, adminMonitor *adminmonitor.Monitor, notificationService portainer.NotificationService) *Handler {
 	h := &Handler{
-		Router:          mux.NewRouter(),
-		bouncer:         bouncer,
-		dataStore:       dataStore,
-		gate:            gate,
-		filestorePath:   filestorePath,
-		shutdownTrigger: shutdownTrigger,
-		adminMonitor:    adminMonitor,
+		Router:              mux.NewRouter(),
+		bouncer:             bouncer,
+		dataStore:           dataStore,
+		gate:                gate,
+		filestorePath:       filestorePath,
+		shutdownTrigger:     shutdownTrigger,
+		adminMonitor:        adminMonitor,
+		notificationService: notificationService,
 	}
 
 	h.Handle("/backup", bouncer.RestrictedAccess(adminAccess(httperror.LoggerHandler(h.backup)))).Methods(http.MethodPost)
@@ -0,0 +1,171 @@
+package edgeenrollment
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/edge"
+)
+
+type edgeRegisterPayload struct {
+	// Edge enrollment token
+	Token string `example:"2c0f7e2e-2f2d-4b8a-9a3f-6d1b3a3c9f5e"`
+	// Identifier of the Edge agent
+	EdgeID string `example:"7e2b0143-c511-43c3-844c-a7a91ab0bedc"`
+	// Name of the endpoint, defaults to the Edge identifier
+	Name string `example:"factory-01"`
+	// Platform of the Edge agent. Value must be one of: 1 (Docker) or 2 (Kubernetes)
+	Platform portainer.AgentPlatform `example:"1" enums:"1,2"`
+}
+
+func (payload *edgeRegisterPayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Token) {
+		return errors.New("Invalid enrollment token")
+	}
+	if govalidator.IsNull(payload.EdgeID) {
+		return errors.New("Invalid Edge identifier")
+	}
+	if payload.Platform != portainer.AgentPlatformDocker && payload.Platform != portainer.AgentPlatformKubernetes {
+		return errors.New("Invalid agent platform. Must be one of: 1 (Docker) or 2 (Kubernetes)")
+	}
+	if len(payload.EdgeID) > edgeRegisterMaxLength || !isPrintable(payload.EdgeID) {
+		return errors.New("Invalid Edge identifier. Must be at most 128 printable characters")
+	}
+
+	payload.Name = strings.TrimSpace(payload.Name)
+	if govalidator.IsNull(payload.Name) {
+		payload.Name = payload.EdgeID
+	}
+	if len(payload.Name) > edgeRegisterMaxLength || !isPrintable(payload.Name) {
+		return errors.New("Invalid endpoint name. Must be at most 128 printable characters")
+	}
+	return nil
+}
+
+// edgeRegisterMaxLength is the maximum length of the Edge identifier and the name sent by an Edge agent
+const edgeRegisterMaxLength = 128
+
+func isPrintable(value string) bool {
+	for _, r := range value {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+type edgeRegisterResponse struct {
+	// Identifier of the registered endpoint
+	ID portainer.EndpointID `json:"Id" example:"1"`
+	// The key the Edge agent uses to connect to Portainer
+	EdgeKey string `json:"EdgeKey"`
+}
+
+// @id EdgeRegister
+// @summary Register an Edge agent with an enrollment token
+// @description Create an Edge endpoint for the agent presenting a valid enrollment token. The endpoint inherits
+// @description the endpoint group, tags and Edge groups of the token and waits for the approval of an administrator
+// @description before any Edge stack or job is sent to it.
+// @description **Access policy**: public
+// @tags edge
+// @accept json
+// @produce json
+// @param body body edgeRegisterPayload true "Registration data"
+// @success 200 {object} edgeRegisterResponse
+// @failure 400
+// @failure 403 "Invalid, expired or exhausted enrollment token"
+// @failure 409 "An endpoint is already registered with this Edge identifier"
+// @failure 500
+// @router /edge/register [post]
+func (handler *Handler) edgeRegister(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload edgeRegisterPayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	// the endpoint is added to its tags and to the static edge groups of the token in the registration transaction.
+	// Its relation starts empty, the endpoint only belongs to the edge groups and receives their edge stacks once approved.
+	var tokenErr error
+	_, endpoint, err := handler.DataStore.EdgeEnrollmentToken().RegisterEndpoint(payload.Token, func(token *portainer.EdgeEnrollmentToken, endpointID portainer.EndpointID) (*portainer.Endpoint, error) {
+		tokenErr = edge.ValidateEnrollmentToken(token, time.Now())
+		if tokenErr != nil {
+			return nil, tokenErr
+		}
+
+		host, err := portainerHost(token.PortainerURL)
+		if err != nil {
+			return nil, err
+		}
+
+		endpoint := newEnrolledEndpoint(token, endpointID, &payload)
+		endpoint.URL = host
+		endpoint.EdgeKey = handler.ReverseTunnelService.GenerateEdgeKey(token.PortainerURL, host, int(endpointID))
+
+		return endpoint, nil
+	})
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusForbidden, "Invalid enrollment token", errors.New("Invalid enrollment token")}
+	} else if tokenErr != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Invalid enrollment token", tokenErr}
+	} else if err == bolterrors.ErrEdgeIDAlreadyRegistered {
+		return &httperror.HandlerError{http.StatusConflict, "An endpoint is already registered with this Edge identifier", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the endpoint inside the database", err}
+	}
+
+	return response.JSON(w, edgeRegisterResponse{ID: endpoint.ID, EdgeKey: endpoint.EdgeKey})
+}
+
+// newEnrolledEndpoint returns a pending Edge endpoint inheriting the endpoint group and the tags of the enrollment token
+func newEnrolledEndpoint(token *portainer.EdgeEnrollmentToken, endpointID portainer.EndpointID, payload *edgeRegisterPayload) *portainer.Endpoint {
+	endpointType := portainer.EdgeAgentOnDockerEnvironment
+	if payload.Platform == portainer.AgentPlatformKubernetes {
+		endpointType = portainer.EdgeAgentOnKubernetesEnvironment
+	}
+
+	groupID := token.GroupID
+	if groupID == 0 {
+		groupID = portainer.EndpointGroupID(1)
+	}
+
+	return &portainer.Endpoint{
+		ID:      endpointID,
+		Name:    payload.Name,
+		Type:    endpointType,
+		GroupID: groupID,
+		TLSConfig: portainer.TLSConfiguration{
+			TLS: false,
+		},
+		AuthorizedUsers:    []portainer.UserID{},
+		AuthorizedTeams:    []portainer.TeamID{},
+		UserAccessPolicies: portainer.UserAccessPolicies{},
+		TeamAccessPolicies: portainer.TeamAccessPolicies{},
+		Extensions:         []portainer.EndpointExtension{},
+		TagIDs:             append([]portainer.TagID{}, token.TagIDs...),
+		Status:             portainer.EndpointStatusUp,
+		Snapshots:          []portainer.DockerSnapshot{},
+		EdgeID:             payload.EdgeID,
+		Kubernetes:         portainer.KubernetesDefault(),
+		SecuritySettings: portainer.EndpointSecuritySettings{
+			AllowSysctlSettingForRegularUsers:         true,
+			AllowBindMountsForRegularUsers:            true,
+			AllowPrivilegedModeForRegularUsers:        true,
+			AllowHostNamespaceForRegularUsers:         true,
+			AllowContainerCapabilitiesForRegularUsers: true,
+			AllowDeviceMappingForRegularUsers:         true,
+			AllowStackManagementForRegularUsers:       true,
+		},
+		EdgePending:           true,
+		EdgeEnrollmentTokenID: token.ID,
+	}
+}
@@ -0,0 +1,156 @@
+package edgeenrollment
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/gofrs/uuid"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+type edgeEnrollmentTokenCreatePayload struct {
+	// Name of the token
+	Name string `example:"factory-golden-image"`
+	// URL of the Portainer instance the Edge agents connect to
+	PortainerURL string `example:"https://portainer.mydomain.tld"`
+	// The date in unix time after which the token is rejected, 0 when the token does not expire
+	ExpiryDate int64 `example:"1587399600"`
+	// Maximum number of endpoints registered with the token, 0 when unlimited
+	UsageLimit int `example:"100"`
+	// Endpoint group of the registered endpoints, defaults to 1 (unassigned)
+	GroupID portainer.EndpointGroupID `example:"1"`
+	// List of tags associated to the registered endpoints
+	TagIDs []portainer.TagID
+	// List of static Edge groups the registered endpoints are added to
+	EdgeGroups []portainer.EdgeGroupID
+}
+
+func (payload *edgeEnrollmentTokenCreatePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Name) {
+		return errors.New("Invalid token name")
+	}
+	if _, err := portainerHost(payload.PortainerURL); err != nil {
+		return err
+	}
+	if payload.ExpiryDate < 0 {
+		return errors.New("Invalid expiry date. Must be a unix time or 0")
+	}
+	if payload.UsageLimit < 0 {
+		return errors.New("Invalid usage limit. Must be a positive number or 0")
+	}
+	if payload.GroupID == 0 {
+		payload.GroupID = 1
+	}
+	if payload.TagIDs == nil {
+		payload.TagIDs = []portainer.TagID{}
+	}
+	if payload.EdgeGroups == nil {
+		payload.EdgeGroups = []portainer.EdgeGroupID{}
+	}
+	return nil
+}
+
+// portainerHost returns the host of the Portainer URL encoded in the Edge keys
+func portainerHost(portainerURL string) (string, error) {
+	parsedURL, err := url.Parse(portainerURL)
+	if err != nil || govalidator.IsNull(parsedURL.Host) {
+		return "", errors.New("Invalid Portainer URL")
+	}
+
+	host, _, err := net.SplitHostPort(parsedURL.Host)
+	if err != nil {
+		host = parsedURL.Host
+	}
+
+	if host == "localhost" {
+		return "", errors.New("Invalid Portainer URL. Cannot use localhost")
+	}
+
+	return host, nil
+}
+
+// @id EdgeEnrollmentTokenCreate
+// @summary Create an Edge enrollment token
+// @description Create a reusable token allowing Edge agents to register themselves as endpoints waiting for approval.
+// @description The secret value of the token is only returned by this request.
+// @description **Access policy**: administrator
+// @tags edge
+// @security jwt
+// @accept json
+// @produce json
+// @param body body edgeEnrollmentTokenCreatePayload true "Edge enrollment token data"
+// @success 200 {object} portainer.EdgeEnrollmentToken
+// @failure 400
+// @failure 500
+// @router /edge_enrollment_tokens [post]
+func (handler *Handler) edgeEnrollmentTokenCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload edgeEnrollmentTokenCreatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	_, err = handler.DataStore.EndpointGroup().EndpointGroup(payload.GroupID)
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusBadRequest, "Unable to find an endpoint group with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint group with the specified identifier inside the database", err}
+	}
+
+	for _, tagID := range payload.TagIDs {
+		_, err = handler.DataStore.Tag().Tag(tagID)
+		if err == bolterrors.ErrObjectNotFound {
+			return &httperror.HandlerError{http.StatusBadRequest, "Unable to find a tag with the specified identifier inside the database", err}
+		} else if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a tag with the specified identifier inside the database", err}
+		}
+	}
+
+	for _, edgeGroupID := range payload.EdgeGroups {
+		edgeGroup, err := handler.DataStore.EdgeGroup().EdgeGroup(edgeGroupID)
+		if err == bolterrors.ErrObjectNotFound {
+			return &httperror.HandlerError{http.StatusBadRequest, "Unable to find an edge group with the specified identifier inside the database", err}
+		} else if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an edge group with the specified identifier inside the database", err}
+		}
+
+		if edgeGroup.Dynamic {
+			return &httperror.HandlerError{http.StatusBadRequest, "Endpoints cannot be added to a dynamic edge group", errors.New("Invalid edge group. Must be a static edge group")}
+		}
+	}
+
+	value, err := uuid.NewV4()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Error creating unique token", err}
+	}
+
+	secret := value.String()
+	token := &portainer.EdgeEnrollmentToken{
+		Name:         payload.Name,
+		Token:        secret,
+		TokenHint:    secret[len(secret)-4:],
+		PortainerURL: payload.PortainerURL,
+		ExpiryDate:   payload.ExpiryDate,
+		UsageLimit:   payload.UsageLimit,
+		GroupID:      payload.GroupID,
+		TagIDs:       payload.TagIDs,
+		EdgeGroups:   payload.EdgeGroups,
+		Created:      time.Now().Unix(),
+	}
+
+	err = handler.DataStore.EdgeEnrollmentToken().CreateEdgeEnrollmentToken(token)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the Edge enrollment token inside the database", err}
+	}
+
+	token.TokenHash = ""
+	return response.JSON(w, token)
+}
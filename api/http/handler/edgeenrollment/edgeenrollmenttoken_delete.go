@@ -0,0 +1,44 @@
+package edgeenrollment
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+// @id EdgeEnrollmentTokenDelete
+// @summary Delete an Edge enrollment token
+// @description The endpoints already registered with the token are kept.
+// @description **Access policy**: administrator
+// @tags edge
+// @security jwt
+// @param id path int true "Edge enrollment token identifier"
+// @success 204
+// @failure 400
+// @failure 404
+// @failure 500
+// @router /edge_enrollment_tokens/{id} [delete]
+func (handler *Handler) edgeEnrollmentTokenDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	tokenID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid Edge enrollment token identifier route variable", err}
+	}
+
+	_, err = handler.DataStore.EdgeEnrollmentToken().EdgeEnrollmentToken(portainer.EdgeEnrollmentTokenID(tokenID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an Edge enrollment token with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an Edge enrollment token with the specified identifier inside the database", err}
+	}
+
+	err = handler.DataStore.EdgeEnrollmentToken().DeleteEdgeEnrollmentToken(portainer.EdgeEnrollmentTokenID(tokenID))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the Edge enrollment token from the database", err}
+	}
+
+	return response.Empty(w)
+}
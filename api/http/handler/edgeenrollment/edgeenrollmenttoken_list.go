@@ -0,0 +1,31 @@
+package edgeenrollment
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+)
+
+// @id EdgeEnrollmentTokenList
+// @summary List Edge enrollment tokens
+// @description The secret values of the tokens are not returned, only their last characters.
+// @description **Access policy**: administrator
+// @tags edge
+// @security jwt
+// @produce json
+// @success 200 {array} portainer.EdgeEnrollmentToken
+// @failure 500
+// @router /edge_enrollment_tokens [get]
+func (handler *Handler) edgeEnrollmentTokenList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	tokens, err := handler.DataStore.EdgeEnrollmentToken().EdgeEnrollmentTokens()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve Edge enrollment tokens from the database", err}
+	}
+
+	for idx := range tokens {
+		tokens[idx].TokenHash = ""
+	}
+
+	return response.JSON(w, tokens)
+}
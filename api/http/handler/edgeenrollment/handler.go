@@ -0,0 +1,33 @@
+package edgeenrollment
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+// Handler is the HTTP handler used to handle Edge enrollment token operations and the registration of Edge agents.
+type Handler struct {
+	*mux.Router
+	DataStore            portainer.DataStore
+	ReverseTunnelService portainer.ReverseTunnelService
+}
+
+// NewHandler creates a handler to manage Edge enrollment token operations.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+	h.Handle("/edge_enrollment_tokens",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.edgeEnrollmentTokenList))).Methods(http.MethodGet)
+	h.Handle("/edge_enrollment_tokens",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.edgeEnrollmentTokenCreate))).Methods(http.MethodPost)
+	h.Handle("/edge_enrollment_tokens/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.edgeEnrollmentTokenDelete))).Methods(http.MethodDelete)
+	h.Handle("/edge/register",
+		bouncer.PublicAccess(httperror.LoggerHandler(h.edgeRegister))).Methods(http.MethodPost)
+	return h
+}
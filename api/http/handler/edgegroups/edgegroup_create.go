@@ -9,6 +9,7 @@ import (
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/edge"
 )
 
 type edgeGroupCreatePayload struct {
@@ -17,14 +18,24 @@ type edgeGroupCreatePayload struct {
 	TagIDs       []portainer.TagID
 	Endpoints    []portainer.EndpointID
 	PartialMatch bool
+	Selector     string
 }
 
 func (payload *edgeGroupCreatePayload) Validate(r *http.Request) error {
 	if govalidator.IsNull(payload.Name) {
 		return errors.New("Invalid Edge group name")
 	}
-	if payload.Dynamic && (payload.TagIDs == nil || len(payload.TagIDs) == 0) {
-		return errors.New("TagIDs is mandatory for a dynamic Edge group")
+	if payload.Dynamic && payload.Selector == "" && (payload.TagIDs == nil || len(payload.TagIDs) == 0) {
+		return errors.New("TagIDs or Selector is mandatory for a dynamic Edge group")
+	}
+	if payload.Dynamic && payload.Selector != "" {
+		_, err := edge.ParseEdgeGroupSelector(payload.Selector)
+		if err != nil {
+			return err
+		}
+	}
+	if !payload.Dynamic && payload.Selector != "" {
+		return errors.New("Selector is only supported by dynamic Edge groups")
 	}
 	if !payload.Dynamic && (payload.Endpoints == nil || len(payload.Endpoints) == 0) {
 		return errors.New("Endpoints is mandatory for a static Edge group")
@@ -72,6 +83,10 @@ func (handler *Handler) edgeGroupCreate(w http.ResponseWriter, r *http.Request)
 
 	if edgeGroup.Dynamic {
 		edgeGroup.TagIDs = payload.TagIDs
+		edgeGroup.Selector = payload.Selector
+		if edgeGroup.TagIDs == nil {
+			edgeGroup.TagIDs = []portainer.TagID{}
+		}
 	} else {
 		endpointIDs := []portainer.EndpointID{}
 		for _, endpointID := range payload.Endpoints {
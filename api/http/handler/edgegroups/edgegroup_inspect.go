@@ -8,6 +8,7 @@ import (
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/edge"
 )
 
 // @id EdgeGroupInspect
@@ -36,7 +37,7 @@ func (handler *Handler) edgeGroupInspect(w http.ResponseWriter, r *http.Request)
 	}
 
 	if edgeGroup.Dynamic {
-		endpoints, err := handler.getEndpointsByTags(edgeGroup.TagIDs, edgeGroup.PartialMatch)
+		endpoints, err := handler.getDynamicEdgeGroupEndpoints(edgeGroup)
 		if err != nil {
 			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoints and endpoint groups for Edge group", err}
 		}
@@ -46,3 +47,23 @@ func (handler *Handler) edgeGroupInspect(w http.ResponseWriter, r *http.Request)
 
 	return response.JSON(w, edgeGroup)
 }
+
+// getDynamicEdgeGroupEndpoints returns the endpoints of a dynamic Edge group, matched on their attributes
+// when the Edge group has a selector and on their tags otherwise
+func (handler *Handler) getDynamicEdgeGroupEndpoints(edgeGroup *portainer.EdgeGroup) ([]portainer.EndpointID, error) {
+	if edgeGroup.Selector == "" {
+		return handler.getEndpointsByTags(edgeGroup.TagIDs, edgeGroup.PartialMatch)
+	}
+
+	endpoints, err := handler.DataStore.Endpoint().Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	membership, err := edge.LoadEdgeGroupMembership(handler.DataStore)
+	if err != nil {
+		return nil, err
+	}
+
+	return membership.EdgeGroupEndpoints(edgeGroup, endpoints), nil
+}
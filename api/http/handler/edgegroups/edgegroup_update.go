@@ -19,14 +19,24 @@ type edgeGroupUpdatePayload struct {
 	TagIDs       []portainer.TagID
 	Endpoints    []portainer.EndpointID
 	PartialMatch *bool
+	Selector     string
 }
 
 func (payload *edgeGroupUpdatePayload) Validate(r *http.Request) error {
 	if govalidator.IsNull(payload.Name) {
 		return errors.New("Invalid Edge group name")
 	}
-	if payload.Dynamic && (payload.TagIDs == nil || len(payload.TagIDs) == 0) {
-		return errors.New("TagIDs is mandatory for a dynamic Edge group")
+	if payload.Dynamic && payload.Selector == "" && (payload.TagIDs == nil || len(payload.TagIDs) == 0) {
+		return errors.New("TagIDs or Selector is mandatory for a dynamic Edge group")
+	}
+	if payload.Dynamic && payload.Selector != "" {
+		_, err := edge.ParseEdgeGroupSelector(payload.Selector)
+		if err != nil {
+			return err
+		}
+	}
+	if !payload.Dynamic && payload.Selector != "" {
+		return errors.New("Selector is only supported by dynamic Edge groups")
 	}
 	if !payload.Dynamic && (payload.Endpoints == nil || len(payload.Endpoints) == 0) {
 		return errors.New("Endpoints is mandatory for a static Edge group")
@@ -84,16 +94,20 @@ func (handler *Handler) edgeGroupUpdate(w http.ResponseWriter, r *http.Request)
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoints from database", err}
 	}
 
-	endpointGroups, err := handler.DataStore.EndpointGroup().EndpointGroups()
+	membership, err := edge.LoadEdgeGroupMembership(handler.DataStore)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoint groups from database", err}
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoint groups and tags from database", err}
 	}
 
-	oldRelatedEndpoints := edge.EdgeGroupRelatedEndpoints(edgeGroup, endpoints, endpointGroups)
+	oldRelatedEndpoints := membership.EdgeGroupEndpoints(edgeGroup, endpoints)
 
 	edgeGroup.Dynamic = payload.Dynamic
 	if edgeGroup.Dynamic {
 		edgeGroup.TagIDs = payload.TagIDs
+		edgeGroup.Selector = payload.Selector
+		if edgeGroup.TagIDs == nil {
+			edgeGroup.TagIDs = []portainer.TagID{}
+		}
 	} else {
 		endpointIDs := []portainer.EndpointID{}
 		for _, endpointID := range payload.Endpoints {
@@ -107,6 +121,7 @@ func (handler *Handler) edgeGroupUpdate(w http.ResponseWriter, r *http.Request)
 			}
 		}
 		edgeGroup.Endpoints = endpointIDs
+		edgeGroup.Selector = ""
 	}
 
 	if payload.PartialMatch != nil {
@@ -118,7 +133,7 @@ func (handler *Handler) edgeGroupUpdate(w http.ResponseWriter, r *http.Request)
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist Edge group changes inside the database", err}
 	}
 
-	newRelatedEndpoints := edge.EdgeGroupRelatedEndpoints(edgeGroup, endpoints, endpointGroups)
+	newRelatedEndpoints := membership.EdgeGroupEndpoints(edgeGroup, endpoints)
 	endpointsToUpdate := append(newRelatedEndpoints, oldRelatedEndpoints...)
 
 	for _, endpointID := range endpointsToUpdate {
@@ -132,39 +147,11 @@ func (handler *Handler) edgeGroupUpdate(w http.ResponseWriter, r *http.Request)
 }
 
 func (handler *Handler) updateEndpoint(endpointID portainer.EndpointID) error {
-	relation, err := handler.DataStore.EndpointRelation().EndpointRelation(endpointID)
-	if err != nil {
-		return err
-	}
-
 	endpoint, err := handler.DataStore.Endpoint().Endpoint(endpointID)
 	if err != nil {
 		return err
 	}
 
-	endpointGroup, err := handler.DataStore.EndpointGroup().EndpointGroup(endpoint.GroupID)
-	if err != nil {
-		return err
-	}
-
-	edgeGroups, err := handler.DataStore.EdgeGroup().EdgeGroups()
-	if err != nil {
-		return err
-	}
-
-	edgeStacks, err := handler.DataStore.EdgeStack().EdgeStacks()
-	if err != nil {
-		return err
-	}
-
-	edgeStackSet := map[portainer.EdgeStackID]bool{}
-
-	endpointEdgeStacks := edge.EndpointRelatedEdgeStacks(endpoint, endpointGroup, edgeGroups, edgeStacks)
-	for _, edgeStackID := range endpointEdgeStacks {
-		edgeStackSet[edgeStackID] = true
-	}
-
-	relation.EdgeStacks = edgeStackSet
-
-	return handler.DataStore.EndpointRelation().UpdateEndpointRelation(endpoint.ID, relation)
+	_, err = edge.UpdateEndpointRelation(handler.DataStore, endpoint)
+	return err
 }
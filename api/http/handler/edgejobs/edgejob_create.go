@@ -50,6 +50,12 @@ type edgeJobCreateFromFileContentPayload struct {
 	Recurring      bool
 	Endpoints      []portainer.EndpointID
 	FileContent    string
+	// Number of times a failed run is retried on an endpoint
+	RetryCount int `example:"3"`
+	// Number of minutes to wait before retrying a failed run
+	RetryInterval int `example:"5"`
+	// Number of runs whose results are kept for each endpoint
+	ResultRetention int `example:"10"`
 }
 
 func (payload *edgeJobCreateFromFileContentPayload) Validate(r *http.Request) error {
@@ -73,7 +79,7 @@ func (payload *edgeJobCreateFromFileContentPayload) Validate(r *http.Request) er
 		return errors.New("Invalid script file content")
 	}
 
-	return nil
+	return validateRetryPolicy(payload.RetryCount, payload.RetryInterval, payload.ResultRetention)
 }
 
 func (handler *Handler) createEdgeJobFromFileContent(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
@@ -94,11 +100,14 @@ func (handler *Handler) createEdgeJobFromFileContent(w http.ResponseWriter, r *h
 }
 
 type edgeJobCreateFromFilePayload struct {
-	Name           string
-	CronExpression string
-	Recurring      bool
-	Endpoints      []portainer.EndpointID
-	File           []byte
+	Name            string
+	CronExpression  string
+	Recurring       bool
+	Endpoints       []portainer.EndpointID
+	File            []byte
+	RetryCount      int
+	RetryInterval   int
+	ResultRetention int
 }
 
 func (payload *edgeJobCreateFromFilePayload) Validate(r *http.Request) error {
@@ -131,6 +140,44 @@ func (payload *edgeJobCreateFromFilePayload) Validate(r *http.Request) error {
 	}
 	payload.File = file
 
+	payload.RetryCount, err = retrieveOptionalNumericMultiPartFormValue(r, "RetryCount")
+	if err != nil {
+		return errors.New("Invalid retry count")
+	}
+
+	payload.RetryInterval, err = retrieveOptionalNumericMultiPartFormValue(r, "RetryInterval")
+	if err != nil {
+		return errors.New("Invalid retry interval")
+	}
+
+	payload.ResultRetention, err = retrieveOptionalNumericMultiPartFormValue(r, "ResultRetention")
+	if err != nil {
+		return errors.New("Invalid result retention")
+	}
+
+	return validateRetryPolicy(payload.RetryCount, payload.RetryInterval, payload.ResultRetention)
+}
+
+// retrieveOptionalNumericMultiPartFormValue returns the value of some form data as an integer, 0 when the value is not found
+func retrieveOptionalNumericMultiPartFormValue(r *http.Request, name string) (int, error) {
+	value, err := request.RetrieveMultiPartFormValue(r, name, true)
+	if err != nil || value == "" {
+		return 0, err
+	}
+
+	return strconv.Atoi(value)
+}
+
+func validateRetryPolicy(retryCount, retryInterval, resultRetention int) error {
+	if retryCount < 0 {
+		return errors.New("Invalid retry count")
+	}
+	if retryInterval < 0 {
+		return errors.New("Invalid retry interval")
+	}
+	if resultRetention < 0 {
+		return errors.New("Invalid result retention")
+	}
 	return nil
 }
 
@@ -157,13 +204,16 @@ func (handler *Handler) createEdgeJobObjectFromFilePayload(payload *edgeJobCreat
 	endpoints := convertEndpointsToMetaObject(payload.Endpoints)
 
 	edgeJob := &portainer.EdgeJob{
-		ID:             edgeJobIdentifier,
-		Name:           payload.Name,
-		CronExpression: payload.CronExpression,
-		Recurring:      payload.Recurring,
-		Created:        time.Now().Unix(),
-		Endpoints:      endpoints,
-		Version:        1,
+		ID:              edgeJobIdentifier,
+		Name:            payload.Name,
+		CronExpression:  payload.CronExpression,
+		Recurring:       payload.Recurring,
+		Created:         time.Now().Unix(),
+		Endpoints:       endpoints,
+		Version:         1,
+		RetryCount:      payload.RetryCount,
+		RetryInterval:   payload.RetryInterval,
+		ResultRetention: payload.ResultRetention,
 	}
 
 	return edgeJob
@@ -175,13 +225,16 @@ func (handler *Handler) createEdgeJobObjectFromFileContentPayload(payload *edgeJ
 	endpoints := convertEndpointsToMetaObject(payload.Endpoints)
 
 	edgeJob := &portainer.EdgeJob{
-		ID:             edgeJobIdentifier,
-		Name:           payload.Name,
-		CronExpression: payload.CronExpression,
-		Recurring:      payload.Recurring,
-		Created:        time.Now().Unix(),
-		Endpoints:      endpoints,
-		Version:        1,
+		ID:              edgeJobIdentifier,
+		Name:            payload.Name,
+		CronExpression:  payload.CronExpression,
+		Recurring:       payload.Recurring,
+		Created:         time.Now().Unix(),
+		Endpoints:       endpoints,
+		Version:         1,
+		RetryCount:      payload.RetryCount,
+		RetryInterval:   payload.RetryInterval,
+		ResultRetention: payload.ResultRetention,
 	}
 
 	return edgeJob
@@ -0,0 +1,78 @@
+package edgejobs
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/edge"
+)
+
+type edgeJobResultsResponse struct {
+	// Outcome of each run across the endpoints of the EdgeJob, from the oldest to the most recent run
+	Runs []edge.EdgeJobRunSummary
+	// Results reported by the endpoints, from the oldest to the most recent one
+	Results []portainer.EdgeJobResult
+}
+
+// @id EdgeJobResultsList
+// @summary Fetch the results of the runs of an EdgeJob
+// @description
+// @tags edge_jobs
+// @security jwt
+// @produce json
+// @param id path string true "EdgeJob Id"
+// @param endpointId query int false "Only return the results reported by this endpoint"
+// @success 200 {object} edgeJobResultsResponse
+// @failure 500
+// @failure 400
+// @failure 404
+// @failure 503 Edge compute features are disabled
+// @router /edge_jobs/{id}/results [get]
+func (handler *Handler) edgeJobResultsList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	edgeJobID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid Edge job identifier route variable", err}
+	}
+
+	endpointID, err := request.RetrieveNumericQueryParameter(r, "endpointId", true)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: endpointId", err}
+	}
+
+	edgeJob, err := handler.DataStore.EdgeJob().EdgeJob(portainer.EdgeJobID(edgeJobID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an Edge job with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an Edge job with the specified identifier inside the database", err}
+	}
+
+	results, err := handler.DataStore.EdgeJobResult().EdgeJobResults(edgeJob.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the Edge job results from the database", err}
+	}
+
+	if endpointID != 0 {
+		filteredResults := make([]portainer.EdgeJobResult, 0)
+		for _, result := range results {
+			if result.EndpointID == portainer.EndpointID(endpointID) {
+				filteredResults = append(filteredResults, result)
+			}
+		}
+		results = filteredResults
+
+		meta, ok := edgeJob.Endpoints[portainer.EndpointID(endpointID)]
+		edgeJob.Endpoints = map[portainer.EndpointID]portainer.EdgeJobEndpointMeta{}
+		if ok {
+			edgeJob.Endpoints[portainer.EndpointID(endpointID)] = meta
+		}
+	}
+
+	return response.JSON(w, &edgeJobResultsResponse{
+		Runs:    edge.SummarizeEdgeJobRuns(edgeJob, results),
+		Results: results,
+	})
+}
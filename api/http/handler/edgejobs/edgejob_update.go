@@ -19,12 +19,27 @@ type edgeJobUpdatePayload struct {
 	Recurring      *bool
 	Endpoints      []portainer.EndpointID
 	FileContent    *string
+	// Number of times a failed run is retried on an endpoint
+	RetryCount *int `example:"3"`
+	// Number of minutes to wait before retrying a failed run
+	RetryInterval *int `example:"5"`
+	// Number of runs whose results are kept for each endpoint
+	ResultRetention *int `example:"10"`
 }
 
 func (payload *edgeJobUpdatePayload) Validate(r *http.Request) error {
 	if payload.Name != nil && !govalidator.Matches(*payload.Name, `^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`) {
 		return errors.New("Invalid Edge job name format. Allowed characters are: [a-zA-Z0-9_.-]")
 	}
+	if payload.RetryCount != nil && *payload.RetryCount < 0 {
+		return errors.New("Invalid retry count")
+	}
+	if payload.RetryInterval != nil && *payload.RetryInterval < 0 {
+		return errors.New("Invalid retry interval")
+	}
+	if payload.ResultRetention != nil && *payload.ResultRetention < 0 {
+		return errors.New("Invalid result retention")
+	}
 	return nil
 }
 
@@ -122,6 +137,18 @@ func (handler *Handler) updateEdgeSchedule(edgeJob *portainer.EdgeJob, payload *
 		updateVersion = true
 	}
 
+	if payload.RetryCount != nil {
+		edgeJob.RetryCount = *payload.RetryCount
+	}
+
+	if payload.RetryInterval != nil {
+		edgeJob.RetryInterval = *payload.RetryInterval
+	}
+
+	if payload.ResultRetention != nil {
+		edgeJob.ResultRetention = *payload.ResultRetention
+	}
+
 	if updateVersion {
 		edgeJob.Version++
 	}
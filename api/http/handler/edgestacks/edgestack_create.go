@@ -8,12 +8,15 @@ import (
 	"time"
 
 	"github.com/asaskevich/govalidator"
+	"github.com/gofrs/uuid"
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/filesystem"
+	gittypes "github.com/portainer/portainer/api/git/types"
 	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/internal/edgestackgit"
 )
 
 // @id EdgeStackCreate
@@ -47,9 +50,9 @@ func (handler *Handler) edgeStackCreate(w http.ResponseWriter, r *http.Request)
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoints from database", err}
 	}
 
-	endpointGroups, err := handler.DataStore.EndpointGroup().EndpointGroups()
+	membership, err := edge.LoadEdgeGroupMembership(handler.DataStore)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoint groups from database", err}
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoint groups and tags from database", err}
 	}
 
 	edgeGroups, err := handler.DataStore.EdgeGroup().EdgeGroups()
@@ -57,7 +60,7 @@ func (handler *Handler) edgeStackCreate(w http.ResponseWriter, r *http.Request)
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve edge groups from database", err}
 	}
 
-	relatedEndpoints, err := edge.EdgeStackRelatedEndpoints(edgeStack.EdgeGroups, endpoints, endpointGroups, edgeGroups)
+	relatedEndpoints, err := membership.EdgeStackEndpoints(edgeStack.EdgeGroups, endpoints, edgeGroups)
 
 	for _, endpointID := range relatedEndpoints {
 		relation, err := handler.DataStore.EndpointRelation().EndpointRelation(endpointID)
@@ -73,6 +76,7 @@ func (handler *Handler) edgeStackCreate(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	hideGitCredentials(edgeStack)
 	return response.JSON(w, edgeStack)
 }
 
@@ -95,6 +99,8 @@ type swarmStackFromFileContentPayload struct {
 	StackFileContent string `example:"version: 3\n services:\n web:\n image:nginx" validate:"required"`
 	// List of identifiers of EdgeGroups
 	EdgeGroups []portainer.EdgeGroupID `example:"1"`
+	// Variables of the stack file, resolved for each endpoint
+	Variables []portainer.EdgeStackVariable
 }
 
 func (payload *swarmStackFromFileContentPayload) Validate(r *http.Request) error {
@@ -131,6 +137,12 @@ func (handler *Handler) createSwarmStackFromFileContent(r *http.Request) (*porta
 		EdgeGroups:   payload.EdgeGroups,
 		Status:       make(map[portainer.EndpointID]portainer.EdgeStackStatus),
 		Version:      1,
+		Variables:    payload.Variables,
+	}
+
+	err = edge.ValidateEdgeStackEndpointVariables(handler.DataStore, stack)
+	if err != nil {
+		return nil, err
 	}
 
 	stackFolder := strconv.Itoa(int(stack.ID))
@@ -165,6 +177,12 @@ type swarmStackFromGitRepositoryPayload struct {
 	ComposeFilePathInRepository string `example:"docker-compose.yml" default:"docker-compose.yml"`
 	// List of identifiers of EdgeGroups
 	EdgeGroups []portainer.EdgeGroupID `example:"1"`
+	// Variables of the stack file, resolved for each endpoint
+	Variables []portainer.EdgeStackVariable
+	// Interval between two checks of the Git repository for new commits, empty to disable polling
+	AutoUpdateInterval string `example:"5m"`
+	// Create a webhook updating the Edge stack when a new commit is pushed to the Git repository
+	AutoUpdateWebhook bool `example:"true"`
 }
 
 func (payload *swarmStackFromGitRepositoryPayload) Validate(r *http.Request) error {
@@ -183,6 +201,11 @@ func (payload *swarmStackFromGitRepositoryPayload) Validate(r *http.Request) err
 	if payload.EdgeGroups == nil || len(payload.EdgeGroups) == 0 {
 		return errors.New("Edge Groups are mandatory for an Edge stack")
 	}
+	if payload.AutoUpdateInterval != "" {
+		if _, err := edgestackgit.ParseInterval(payload.AutoUpdateInterval); err != nil {
+			return errors.New("Invalid auto update interval. " + err.Error())
+		}
+	}
 	return nil
 }
 
@@ -207,6 +230,12 @@ func (handler *Handler) createSwarmStackFromGitRepository(r *http.Request) (*por
 		EdgeGroups:   payload.EdgeGroups,
 		Status:       make(map[portainer.EndpointID]portainer.EdgeStackStatus),
 		Version:      1,
+		Variables:    payload.Variables,
+	}
+
+	err = edge.ValidateEdgeStackEndpointVariables(handler.DataStore, stack)
+	if err != nil {
+		return nil, err
 	}
 
 	projectPath := handler.FileService.GetEdgeStackProjectPath(strconv.Itoa(int(stack.ID)))
@@ -219,11 +248,53 @@ func (handler *Handler) createSwarmStackFromGitRepository(r *http.Request) (*por
 		repositoryPassword = ""
 	}
 
-	err = handler.GitService.CloneRepository(projectPath, payload.RepositoryURL, payload.RepositoryReferenceName, repositoryUsername, repositoryPassword)
+	// the repository is cloned at the resolved commit, so that the recorded commit is the one deployed
+	// even when the reference is updated in the meantime
+	commitID, err := handler.GitService.LatestCommitID(payload.RepositoryURL, payload.RepositoryReferenceName, repositoryUsername, repositoryPassword)
+	if err != nil || commitID == "" {
+		logger.WithError(err).Warn("unable to retrieve the latest git commit")
+		commitID = ""
+		err = handler.GitService.CloneRepository(projectPath, payload.RepositoryURL, payload.RepositoryReferenceName, repositoryUsername, repositoryPassword)
+	} else {
+		err = handler.GitService.CloneRepositoryAtCommit(projectPath, payload.RepositoryURL, payload.RepositoryReferenceName, commitID, repositoryUsername, repositoryPassword)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	stack.GitConfig = &gittypes.RepoConfig{
+		URL:            payload.RepositoryURL,
+		ReferenceName:  payload.RepositoryReferenceName,
+		ConfigFilePath: payload.ComposeFilePathInRepository,
+		ConfigHash:     commitID,
+	}
+
+	if payload.RepositoryAuthentication {
+		encryptedPassword, err := handler.SecretService.Encrypt(payload.RepositoryPassword)
+		if err != nil {
+			return nil, err
+		}
+
+		stack.GitConfig.Authentication = &gittypes.GitAuthentication{
+			Username: payload.RepositoryUsername,
+			Password: encryptedPassword,
+		}
+	}
+
+	if payload.AutoUpdateInterval != "" || payload.AutoUpdateWebhook {
+		stack.AutoUpdate = &portainer.EdgeStackAutoUpdate{
+			Interval: payload.AutoUpdateInterval,
+		}
+
+		if payload.AutoUpdateWebhook {
+			token, err := uuid.NewV4()
+			if err != nil {
+				return nil, err
+			}
+			stack.AutoUpdate.Webhook = token.String()
+		}
+	}
+
 	err = handler.DataStore.EdgeStack().CreateEdgeStack(stack)
 	if err != nil {
 		return nil, err
@@ -236,6 +307,7 @@ type swarmStackFromFileUploadPayload struct {
 	Name             string
 	StackFileContent []byte
 	EdgeGroups       []portainer.EdgeGroupID
+	Variables        []portainer.EdgeStackVariable
 }
 
 func (payload *swarmStackFromFileUploadPayload) Validate(r *http.Request) error {
@@ -257,6 +329,13 @@ func (payload *swarmStackFromFileUploadPayload) Validate(r *http.Request) error
 		return errors.New("Edge Groups are mandatory for an Edge stack")
 	}
 	payload.EdgeGroups = edgeGroups
+
+	var variables []portainer.EdgeStackVariable
+	err = request.RetrieveMultiPartFormJSONValue(r, "Variables", &variables, true)
+	if err != nil {
+		return errors.New("Invalid variables")
+	}
+	payload.Variables = variables
 	return nil
 }
 
@@ -281,6 +360,12 @@ func (handler *Handler) createSwarmStackFromFileUpload(r *http.Request) (*portai
 		EdgeGroups:   payload.EdgeGroups,
 		Status:       make(map[portainer.EndpointID]portainer.EdgeStackStatus),
 		Version:      1,
+		Variables:    payload.Variables,
+	}
+
+	err = edge.ValidateEdgeStackEndpointVariables(handler.DataStore, stack)
+	if err != nil {
+		return nil, err
 	}
 
 	stackFolder := strconv.Itoa(int(stack.ID))
@@ -36,5 +36,6 @@ func (handler *Handler) edgeStackInspect(w http.ResponseWriter, r *http.Request)
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an edge stack with the specified identifier inside the database", err}
 	}
 
+	hideGitCredentials(edgeStack)
 	return response.JSON(w, edgeStack)
 }
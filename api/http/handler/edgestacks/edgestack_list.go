@@ -25,5 +25,9 @@ func (handler *Handler) edgeStackList(w http.ResponseWriter, r *http.Request) *h
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve edge stacks from the database", err}
 	}
 
+	for idx := range edgeStacks {
+		hideGitCredentials(&edgeStacks[idx])
+	}
+
 	return response.JSON(w, edgeStacks)
 }
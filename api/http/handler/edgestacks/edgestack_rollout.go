@@ -0,0 +1,200 @@
+package edgestacks
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/edge"
+)
+
+type edgeStackRolloutPayload struct {
+	// Rollout strategy. 1 to start with a percentage of the endpoints, 2 to start with a named subset of the endpoints
+	Strategy portainer.EdgeStackRolloutStrategy `example:"1" enums:"1,2"`
+	// Percentage of the endpoints deployed in the first batch. Required when Strategy is 1
+	CanaryPercentage int `example:"10"`
+	// Endpoints deployed in the first batch. Required when Strategy is 2
+	CanaryEndpoints []portainer.EndpointID
+	// Number of endpoints deployed in each following batch, 0 to deploy all the remaining endpoints at once
+	BatchSize int `example:"5"`
+	// Percentage of the deployed endpoints reporting an error above which the rollout is stopped
+	ErrorThreshold int `example:"20"`
+	// Time in seconds the endpoints of a batch have to report their status before counting as failed, 0 for one hour
+	BatchTimeout int `example:"3600"`
+}
+
+func (payload *edgeStackRolloutPayload) Validate(r *http.Request) error {
+	switch payload.Strategy {
+	case portainer.EdgeStackRolloutCanary:
+		if payload.CanaryPercentage < 1 || payload.CanaryPercentage > 100 {
+			return errors.New("Invalid canary percentage. Must be between 1 and 100")
+		}
+	case portainer.EdgeStackRolloutSubset:
+		if len(payload.CanaryEndpoints) == 0 {
+			return errors.New("Canary endpoints are mandatory for a subset rollout")
+		}
+	default:
+		return errors.New("Invalid rollout strategy. Must be one of: 1 (canary) or 2 (subset)")
+	}
+
+	if payload.BatchSize < 0 {
+		return errors.New("Invalid batch size")
+	}
+	if payload.ErrorThreshold < 0 || payload.ErrorThreshold > 100 {
+		return errors.New("Invalid error threshold. Must be between 0 and 100")
+	}
+	if payload.BatchTimeout < 0 {
+		return errors.New("Invalid batch timeout")
+	}
+	return nil
+}
+
+type edgeStackRolloutResumePayload struct {
+	// Optional new error threshold, used to continue a rollout stopped because of its error threshold
+	ErrorThreshold *int `example:"50"`
+}
+
+func (payload *edgeStackRolloutResumePayload) Validate(r *http.Request) error {
+	if payload.ErrorThreshold != nil && (*payload.ErrorThreshold < 0 || *payload.ErrorThreshold > 100) {
+		return errors.New("Invalid error threshold. Must be between 0 and 100")
+	}
+	return nil
+}
+
+// @id EdgeStackRolloutPause
+// @summary Pause the rollout of an EdgeStack
+// @description No new batch of endpoints is deployed until the rollout is resumed
+// @tags edge_stacks
+// @security jwt
+// @produce json
+// @param id path string true "EdgeStack Id"
+// @success 200 {object} portainer.EdgeStack
+// @failure 500
+// @failure 400
+// @failure 404
+// @failure 409 The rollout is not running
+// @failure 503 Edge compute features are disabled
+// @router /edge_stacks/{id}/rollout/pause [post]
+func (handler *Handler) edgeStackRolloutPause(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return handler.updateEdgeStackRollout(w, r, func(edgeStack *portainer.EdgeStack, relatedEndpoints []portainer.EndpointID) *httperror.HandlerError {
+		if edgeStack.Rollout.Status != portainer.EdgeStackRolloutRunning {
+			return &httperror.HandlerError{http.StatusConflict, "Only a running rollout can be paused", errors.New("Rollout is not running")}
+		}
+
+		edgeStack.Rollout.Status = portainer.EdgeStackRolloutPaused
+		return nil
+	})
+}
+
+// @id EdgeStackRolloutResume
+// @summary Resume the rollout of an EdgeStack
+// @description Resume a paused rollout or a rollout stopped because of its error threshold.
+// @description The endpoints that did not report their status yet are given a new batch timeout
+// @tags edge_stacks
+// @security jwt
+// @accept json
+// @produce json
+// @param id path string true "EdgeStack Id"
+// @param body body edgeStackRolloutResumePayload false "Rollout options"
+// @success 200 {object} portainer.EdgeStack
+// @failure 500
+// @failure 400
+// @failure 404
+// @failure 409 The rollout is not paused nor stopped
+// @failure 503 Edge compute features are disabled
+// @router /edge_stacks/{id}/rollout/resume [post]
+func (handler *Handler) edgeStackRolloutResume(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload edgeStackRolloutResumePayload
+	if r.ContentLength != 0 {
+		err := request.DecodeAndValidateJSONPayload(r, &payload)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+		}
+	}
+
+	return handler.updateEdgeStackRollout(w, r, func(edgeStack *portainer.EdgeStack, relatedEndpoints []portainer.EndpointID) *httperror.HandlerError {
+		if edgeStack.Rollout.Status != portainer.EdgeStackRolloutPaused && edgeStack.Rollout.Status != portainer.EdgeStackRolloutStopped {
+			return &httperror.HandlerError{http.StatusConflict, "Only a paused or stopped rollout can be resumed", errors.New("Rollout is not paused nor stopped")}
+		}
+
+		if payload.ErrorThreshold != nil {
+			edgeStack.Rollout.ErrorThreshold = *payload.ErrorThreshold
+		}
+
+		edge.ResumeEdgeStackRollout(edgeStack, relatedEndpoints, time.Now())
+		return nil
+	})
+}
+
+// @id EdgeStackRolloutAbort
+// @summary Abort the rollout of an EdgeStack
+// @description All the endpoints are sent back to the version deployed before the rollout
+// @tags edge_stacks
+// @security jwt
+// @produce json
+// @param id path string true "EdgeStack Id"
+// @success 200 {object} portainer.EdgeStack
+// @failure 500
+// @failure 400
+// @failure 404
+// @failure 409 The rollout is already completed or aborted
+// @failure 503 Edge compute features are disabled
+// @router /edge_stacks/{id}/rollout/abort [post]
+func (handler *Handler) edgeStackRolloutAbort(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return handler.updateEdgeStackRollout(w, r, func(edgeStack *portainer.EdgeStack, relatedEndpoints []portainer.EndpointID) *httperror.HandlerError {
+		if edgeStack.Rollout.Status == portainer.EdgeStackRolloutCompleted || edgeStack.Rollout.Status == portainer.EdgeStackRolloutAborted {
+			return &httperror.HandlerError{http.StatusConflict, "The rollout is already completed or aborted", errors.New("Rollout is completed or aborted")}
+		}
+
+		edgeStack.Rollout.Status = portainer.EdgeStackRolloutAborted
+		return nil
+	})
+}
+
+// updateEdgeStackRollout applies the update function to the rollout of the edge stack and persists the edge stack
+func (handler *Handler) updateEdgeStackRollout(w http.ResponseWriter, r *http.Request, update func(edgeStack *portainer.EdgeStack, relatedEndpoints []portainer.EndpointID) *httperror.HandlerError) *httperror.HandlerError {
+	edgeStackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid edge stack identifier route variable", err}
+	}
+
+	edgeStack, err := handler.DataStore.EdgeStack().EdgeStack(portainer.EdgeStackID(edgeStackID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an edge stack with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an edge stack with the specified identifier inside the database", err}
+	}
+
+	if edgeStack.Rollout == nil || edgeStack.Rollout.Version != edgeStack.Version {
+		return &httperror.HandlerError{http.StatusConflict, "No rollout is associated to the current version of the edge stack", errors.New("Edge stack has no rollout")}
+	}
+
+	relatedEndpoints, err := handler.edgeStackRelatedEndpoints(edgeStack.EdgeGroups)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve edge stack related endpoints from database", err}
+	}
+
+	httpErr := update(edgeStack, relatedEndpoints)
+	if httpErr != nil {
+		return httpErr
+	}
+	edgeStack.Rollout.UpdateDate = time.Now().Unix()
+
+	err = handler.DataStore.EdgeStack().UpdateEdgeStack(edgeStack.ID, edgeStack)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
+	}
+
+	hideGitCredentials(edgeStack)
+	return response.JSON(w, edgeStack)
+}
+
+// edgeStackRelatedEndpoints returns the endpoints targeted by the edge groups
+func (handler *Handler) edgeStackRelatedEndpoints(edgeGroupIDs []portainer.EdgeGroupID) ([]portainer.EndpointID, error) {
+	return edge.LoadEdgeStackEndpoints(handler.DataStore, edgeGroupIDs)
+}
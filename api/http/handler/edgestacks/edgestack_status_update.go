@@ -2,7 +2,9 @@ package edgestacks
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/asaskevich/govalidator"
 	httperror "github.com/portainer/libhttp/error"
@@ -10,12 +12,18 @@ import (
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/internal/logging"
+	"github.com/portainer/portainer/api/internal/notification"
 )
 
 type updateStatusPayload struct {
 	Error      string
 	Status     *portainer.EdgeStackStatusType
 	EndpointID *portainer.EndpointID
+	// Version of the edge stack the status refers to. The status is ignored when it is not the version the endpoint
+	// must deploy, the status reported without version is not taken into account by the rollouts
+	Version *int `example:"3"`
 }
 
 func (payload *updateStatusPayload) Validate(r *http.Request) error {
@@ -75,17 +83,78 @@ func (handler *Handler) edgeStackStatusUpdate(w http.ResponseWriter, r *http.Req
 		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
 	}
 
-	stack.Status[*payload.EndpointID] = portainer.EdgeStackStatus{
-		Type:       *payload.Status,
-		Error:      payload.Error,
-		EndpointID: *payload.EndpointID,
+	var relatedEndpoints []portainer.EndpointID
+	if stack.Rollout != nil && stack.Rollout.Status == portainer.EdgeStackRolloutRunning {
+		relatedEndpoints, err = handler.edgeStackRelatedEndpoints(stack.EdgeGroups)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve edge stack related endpoints from database", err}
+		}
 	}
 
-	err = handler.DataStore.EdgeStack().UpdateEdgeStack(stack.ID, stack)
+	recorded := false
+	err = handler.DataStore.EdgeStack().UpdateEdgeStackFunc(stack.ID, func(edgeStack *portainer.EdgeStack) {
+		stack = edgeStack
+		recorded = recordEdgeStackStatus(edgeStack, &payload, relatedEndpoints)
+	})
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
 	}
 
+	if !recorded {
+		logger.WithField(logging.FieldEndpointID, endpoint.ID).WithField("edge_stack_id", stack.ID).WithField("version", *payload.Version).
+			Debug("ignoring the status reported for a version the endpoint is not expected to deploy")
+	}
+
+	if recorded && *payload.Status == portainer.StatusError {
+		version := edge.EdgeStackEndpointVersion(stack, endpoint.ID)
+		if payload.Version != nil {
+			version = *payload.Version
+		}
+
+		event := notification.NewEndpointEvent(portainer.NotificationEventEdgeStackDeployFailed, endpoint, fmt.Sprintf("Edge stack %s failed to deploy on endpoint %s", stack.Name, endpoint.Name))
+		event.Details = map[string]string{
+			"edgeStack": stack.Name,
+			"version":   fmt.Sprint(version),
+			"error":     payload.Error,
+		}
+		handler.NotificationService.Notify(event)
+	}
+
+	hideGitCredentials(stack)
 	return response.JSON(w, stack)
+}
+
+// recordEdgeStackStatus records the status reported by the endpoint and returns false when the status is ignored.
+// The status of an explicit version is only recorded when it is the version the endpoint must deploy, so that a late
+// report for a previous version does not overwrite the status of the current one. Only the status of an explicit
+// version counts for the rollout, the status reported without version is kept for display.
+func recordEdgeStackStatus(edgeStack *portainer.EdgeStack, payload *updateStatusPayload, relatedEndpoints []portainer.EndpointID) bool {
+	endpointID := *payload.EndpointID
+
+	if payload.Version != nil && *payload.Version != edge.EdgeStackEndpointVersion(edgeStack, endpointID) {
+		return false
+	}
+
+	if edgeStack.Status == nil {
+		edgeStack.Status = make(map[portainer.EndpointID]portainer.EdgeStackStatus)
+	}
+
+	edgeStack.Status[endpointID] = portainer.EdgeStackStatus{
+		Type:       *payload.Status,
+		Error:      payload.Error,
+		EndpointID: endpointID,
+	}
+
+	if payload.Version == nil {
+		return true
+	}
+
+	edge.RecordEdgeStackEndpointStatus(edgeStack, endpointID, *payload.Status, *payload.Version)
+
+	// the related endpoints are only loaded when the rollout was running before the transaction
+	if relatedEndpoints != nil && edgeStack.Rollout != nil && edgeStack.Rollout.Status == portainer.EdgeStackRolloutRunning {
+		edge.UpdateEdgeStackRollout(edgeStack, relatedEndpoints, time.Now())
+	}
 
+	return true
 }
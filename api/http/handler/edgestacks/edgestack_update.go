@@ -3,6 +3,7 @@ package edgestacks
 import (
 	"errors"
 	"net/http"
+	"reflect"
 	"strconv"
 
 	"github.com/asaskevich/govalidator"
@@ -19,6 +20,12 @@ type updateEdgeStackPayload struct {
 	Version          *int
 	Prune            *bool
 	EdgeGroups       []portainer.EdgeGroupID
+	// Send the endpoints failing to deploy a new version back to the last version they deployed successfully
+	AutoRollback *bool `example:"true"`
+	// Variables of the stack file, resolved for each endpoint. Unchanged when omitted
+	Variables []portainer.EdgeStackVariable
+	// Staged rollout of the new version. When omitted, the new version is deployed to every endpoint at once
+	Rollout *edgeStackRolloutPayload
 }
 
 func (payload *updateEdgeStackPayload) Validate(r *http.Request) error {
@@ -28,12 +35,25 @@ func (payload *updateEdgeStackPayload) Validate(r *http.Request) error {
 	if payload.EdgeGroups != nil && len(payload.EdgeGroups) == 0 {
 		return errors.New("Edge Groups are mandatory for an Edge stack")
 	}
+	if payload.Variables != nil {
+		err := edge.ValidateEdgeStackVariables(payload.Variables)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.Rollout != nil {
+		return payload.Rollout.Validate(r)
+	}
 	return nil
 }
 
 // @id EdgeStackUpdate
 // @summary Update an EdgeStack
-// @description
+// @description When the version is updated with a rollout, the new version is deployed to the endpoints in batches
+// @description and the endpoints not reached by the rollout keep running the previous version.
+// @description The files of the last previous versions are kept so that failing endpoints can be rolled back.
+// @description The update is rejected when a required variable has no value for one of the endpoints of the edge stack.
+// @description Changing the values of the variables creates a new version of the edge stack.
 // @tags edge_stacks
 // @security jwt
 // @accept json
@@ -64,15 +84,28 @@ func (handler *Handler) edgeStackUpdate(w http.ResponseWriter, r *http.Request)
 		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
 	}
 
+	updatedStack := *stack
+	if payload.EdgeGroups != nil {
+		updatedStack.EdgeGroups = payload.EdgeGroups
+	}
+	if payload.Variables != nil {
+		updatedStack.Variables = payload.Variables
+	}
+
+	err = edge.ValidateEdgeStackEndpointVariables(handler.DataStore, &updatedStack)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Unable to resolve the variables of the edge stack for every endpoint", err}
+	}
+
 	if payload.EdgeGroups != nil {
 		endpoints, err := handler.DataStore.Endpoint().Endpoints()
 		if err != nil {
 			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoints from database", err}
 		}
 
-		endpointGroups, err := handler.DataStore.EndpointGroup().EndpointGroups()
+		membership, err := edge.LoadEdgeGroupMembership(handler.DataStore)
 		if err != nil {
-			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoint groups from database", err}
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoint groups and tags from database", err}
 		}
 
 		edgeGroups, err := handler.DataStore.EdgeGroup().EdgeGroups()
@@ -80,12 +113,12 @@ func (handler *Handler) edgeStackUpdate(w http.ResponseWriter, r *http.Request)
 			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve edge groups from database", err}
 		}
 
-		oldRelated, err := edge.EdgeStackRelatedEndpoints(stack.EdgeGroups, endpoints, endpointGroups, edgeGroups)
+		oldRelated, err := membership.EdgeStackEndpoints(stack.EdgeGroups, endpoints, edgeGroups)
 		if err != nil {
 			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve edge stack related endpoints from database", err}
 		}
 
-		newRelated, err := edge.EdgeStackRelatedEndpoints(payload.EdgeGroups, endpoints, endpointGroups, edgeGroups)
+		newRelated, err := membership.EdgeStackEndpoints(payload.EdgeGroups, endpoints, edgeGroups)
 		if err != nil {
 			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve edge stack related endpoints from database", err}
 		}
@@ -143,15 +176,69 @@ func (handler *Handler) edgeStackUpdate(w http.ResponseWriter, r *http.Request)
 		stack.Prune = *payload.Prune
 	}
 
+	if payload.AutoRollback != nil {
+		stack.AutoRollback = *payload.AutoRollback
+	}
+
+	newVersion := stack.Version
+	if payload.Version != nil {
+		newVersion = *payload.Version
+	}
+
+	// the endpoints only redeploy the edge stack with the new values of its variables on a new version
+	if payload.Variables != nil && !reflect.DeepEqual(payload.Variables, stack.Variables) {
+		stack.Variables = payload.Variables
+		if newVersion == stack.Version {
+			newVersion++
+		}
+	}
+
+	versionUpdated := newVersion != stack.Version
+
+	// the endpoints not reached by a rollout yet keep running the version deployed before it
+	previousVersion := stack.Version
+	if stack.Rollout != nil && stack.Rollout.Version == stack.Version && stack.Rollout.Status != portainer.EdgeStackRolloutCompleted {
+		previousVersion = stack.Rollout.PreviousVersion
+	}
+
 	stackFolder := strconv.Itoa(int(stack.ID))
+	if versionUpdated {
+		settings, err := handler.DataStore.Settings().Settings()
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve settings from the database", err}
+		}
+
+		err = edge.StoreEdgeStackPreviousVersion(handler.FileService, stack, edge.MaxPreviousVersions(settings))
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the previous Compose file on disk", err}
+		}
+	}
+
 	_, err = handler.FileService.StoreEdgeStackFileFromBytes(stackFolder, stack.EntryPoint, []byte(payload.StackFileContent))
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist updated Compose file on disk", err}
 	}
 
-	if payload.Version != nil && *payload.Version != stack.Version {
-		stack.Version = *payload.Version
+	if versionUpdated {
+		stack.Version = newVersion
 		stack.Status = map[portainer.EndpointID]portainer.EdgeStackStatus{}
+		stack.Rollout = nil
+
+		if payload.Rollout != nil {
+			relatedEndpoints, err := handler.edgeStackRelatedEndpoints(stack.EdgeGroups)
+			if err != nil {
+				return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve edge stack related endpoints from database", err}
+			}
+
+			edge.StartEdgeStackRollout(stack, &portainer.EdgeStackRollout{
+				Strategy:         payload.Rollout.Strategy,
+				CanaryPercentage: payload.Rollout.CanaryPercentage,
+				CanaryEndpoints:  payload.Rollout.CanaryEndpoints,
+				BatchSize:        payload.Rollout.BatchSize,
+				ErrorThreshold:   payload.Rollout.ErrorThreshold,
+				BatchTimeout:     payload.Rollout.BatchTimeout,
+			}, previousVersion, relatedEndpoints)
+		}
 	}
 
 	err = handler.DataStore.EdgeStack().UpdateEdgeStack(stack.ID, stack)
@@ -159,6 +246,7 @@ func (handler *Handler) edgeStackUpdate(w http.ResponseWriter, r *http.Request)
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
 	}
 
+	hideGitCredentials(stack)
 	return response.JSON(w, stack)
 }
 
@@ -0,0 +1,74 @@
+package edgestacks
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+)
+
+type edgeStackWebhookResponse struct {
+	// Whether a new version of the edge stack was created
+	Updated bool `example:"true"`
+	// Version of the edge stack
+	Version int `example:"3"`
+}
+
+// @id EdgeStackWebhookInvoke
+// @summary Update a git EdgeStack from its repository
+// @description Checks the Git repository of the EdgeStack and creates a new version of the EdgeStack
+// @description when the commit of the followed reference changed. Meant to be called by the push webhook of the Git provider.
+// @tags edge_stacks
+// @produce json
+// @param token path string true "EdgeStack webhook token"
+// @success 200 {object} edgeStackWebhookResponse
+// @failure 500
+// @failure 404
+// @failure 503 Edge compute features are disabled
+// @router /edge_stacks/webhooks/{token} [post]
+func (handler *Handler) edgeStackWebhookInvoke(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	token, err := request.RetrieveRouteVariableValue(r, "token")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid webhook token route variable", err}
+	}
+
+	edgeStacks, err := handler.DataStore.EdgeStack().EdgeStacks()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve edge stacks from the database", err}
+	}
+
+	var edgeStack *portainer.EdgeStack
+	for idx := range edgeStacks {
+		autoUpdate := edgeStacks[idx].AutoUpdate
+		if edgeStacks[idx].GitConfig != nil && autoUpdate != nil && autoUpdate.Webhook != "" && autoUpdate.Webhook == token {
+			edgeStack = &edgeStacks[idx]
+			break
+		}
+	}
+
+	if edgeStack == nil {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an edge stack with this webhook token", errors.New("Edge stack not found")}
+	}
+
+	updated, err := handler.EdgeStackGitService.UpdateEdgeStack(edgeStack.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to update the edge stack from its git repository", err}
+	}
+
+	edgeStack, err = handler.DataStore.EdgeStack().EdgeStack(edgeStack.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an edge stack with the specified identifier inside the database", err}
+	}
+
+	return response.JSON(w, &edgeStackWebhookResponse{Updated: updated, Version: edgeStack.Version})
+}
+
+// hideGitCredentials removes the password of the git repository from the edge stack returned to the client
+func hideGitCredentials(edgeStack *portainer.EdgeStack) {
+	if edgeStack.GitConfig != nil && edgeStack.GitConfig.Authentication != nil {
+		edgeStack.GitConfig.Authentication.Password = ""
+	}
+}
@@ -0,0 +1,57 @@
+package edgestacks
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/edgestackgit"
+	"github.com/portainer/portainer/api/internal/logging"
+)
+
+var logger = logging.Component("edge_stacks")
+
+// Handler is the HTTP handler used to handle edge stack operations.
+type Handler struct {
+	*mux.Router
+	requestBouncer      *security.RequestBouncer
+	DataStore           portainer.DataStore
+	EdgeStackGitService *edgestackgit.Service
+	FileService         portainer.FileService
+	GitService          portainer.GitService
+	NotificationService portainer.NotificationService
+	SecretService       portainer.SecretService
+}
+
+// NewHandler creates a handler to manage edge stack operations.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router:         mux.NewRouter(),
+		requestBouncer: bouncer,
+	}
+	h.Handle("/edge_stacks",
+		bouncer.AdminAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackCreate)))).Methods(http.MethodPost)
+	h.Handle("/edge_stacks",
+		bouncer.AdminAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackList)))).Methods(http.MethodGet)
+	h.Handle("/edge_stacks/{id}",
+		bouncer.AdminAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackInspect)))).Methods(http.MethodGet)
+	h.Handle("/edge_stacks/{id}",
+		bouncer.AdminAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackUpdate)))).Methods(http.MethodPut)
+	h.Handle("/edge_stacks/{id}",
+		bouncer.AdminAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackDelete)))).Methods(http.MethodDelete)
+	h.Handle("/edge_stacks/{id}/file",
+		bouncer.AdminAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackFile)))).Methods(http.MethodGet)
+	h.Handle("/edge_stacks/{id}/status",
+		bouncer.PublicAccess(httperror.LoggerHandler(h.edgeStackStatusUpdate))).Methods(http.MethodPut)
+	h.Handle("/edge_stacks/webhooks/{token}",
+		bouncer.PublicAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackWebhookInvoke)))).Methods(http.MethodPost)
+	h.Handle("/edge_stacks/{id}/rollout/pause",
+		bouncer.AdminAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackRolloutPause)))).Methods(http.MethodPost)
+	h.Handle("/edge_stacks/{id}/rollout/resume",
+		bouncer.AdminAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackRolloutResume)))).Methods(http.MethodPost)
+	h.Handle("/edge_stacks/{id}/rollout/abort",
+		bouncer.AdminAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackRolloutAbort)))).Methods(http.MethodPost)
+	return h
+}
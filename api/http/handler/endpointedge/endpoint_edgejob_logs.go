@@ -28,6 +28,7 @@ func (payload *logsPayload) Validate(r *http.Request) error {
 // @param id path string true "Endpoint Id"
 // @param jobID path string true "Job Id"
 // @success 200
+// @failure 403 "The endpoint is waiting for approval"
 // @failure 500
 // @failure 400
 // @router /endpoints/{id}/edge/jobs/{jobID}/logs [post]
@@ -49,6 +50,10 @@ func (handler *Handler) endpointEdgeJobsLogs(w http.ResponseWriter, r *http.Requ
 		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
 	}
 
+	if endpoint.EdgePending {
+		return &httperror.HandlerError{http.StatusForbidden, "The endpoint is waiting for approval", errEndpointPending}
+	}
+
 	edgeJobID, err := request.RetrieveNumericRouteVariableValue(r, "jobID")
 	if err != nil {
 		return &httperror.HandlerError{http.StatusBadRequest, "Invalid edge job identifier route variable", err}
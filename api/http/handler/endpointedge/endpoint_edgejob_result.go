@@ -0,0 +1,136 @@
+package endpointedge
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/edge"
+)
+
+var errEdgeJobNotAssociated = errors.New("Edge job not associated to the endpoint")
+
+type resultPayload struct {
+	// Exit code of the script
+	ExitCode *int `example:"0" validate:"required"`
+	// Duration of the run in milliseconds
+	Duration int64 `example:"1500"`
+	// Output of the script, only its end is kept when too long
+	Output string `example:"hello"`
+	// The date in unix time the run was scheduled at by the cron expression of the job, or the retry date
+	// of the run for a retry. The results reporting the same date belong to the same run of the job.
+	ScheduledDate int64 `example:"1587399600"`
+}
+
+func (payload *resultPayload) Validate(r *http.Request) error {
+	if payload.ExitCode == nil {
+		return errors.New("Invalid exit code")
+	}
+	if payload.Duration < 0 {
+		return errors.New("Invalid duration")
+	}
+	if payload.ScheduledDate < 0 {
+		return errors.New("Invalid scheduled date")
+	}
+	return nil
+}
+
+// endpointEdgeJobResult
+// @summary Report the result of an EdgeJob run
+// @description Failed runs are retried according to the retry policy of the EdgeJob
+// @tags edge, endpoints
+// @accept json
+// @produce json
+// @param id path string true "Endpoint Id"
+// @param jobID path string true "Job Id"
+// @param body body resultPayload true "Run result"
+// @success 200 {object} portainer.EdgeJobResult
+// @failure 403 "The endpoint is waiting for approval"
+// @failure 500
+// @failure 400
+// @failure 404
+// @router /endpoints/{id}/edge/jobs/{jobID}/results [post]
+func (handler *Handler) endpointEdgeJobResult(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.AuthorizedEdgeEndpointOperation(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	if endpoint.EdgePending {
+		return &httperror.HandlerError{http.StatusForbidden, "The endpoint is waiting for approval", errEndpointPending}
+	}
+
+	edgeJobID, err := request.RetrieveNumericRouteVariableValue(r, "jobID")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid edge job identifier route variable", err}
+	}
+
+	var payload resultPayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	now := time.Now()
+	result := &portainer.EdgeJobResult{
+		EdgeJobID:     portainer.EdgeJobID(edgeJobID),
+		EndpointID:    endpoint.ID,
+		ExitCode:      *payload.ExitCode,
+		Duration:      payload.Duration,
+		Output:        edge.TruncateEdgeJobOutput(payload.Output),
+		ScheduledDate: payload.ScheduledDate,
+		Date:          now.Unix(),
+	}
+
+	scheduleChanged := false
+	edgeJob, err := handler.DataStore.EdgeJobResult().RecordEdgeJobResult(result, func(edgeJob *portainer.EdgeJob) error {
+		if _, ok := edgeJob.Endpoints[endpoint.ID]; !ok {
+			return errEdgeJobNotAssociated
+		}
+
+		scheduleChanged = edge.RecordEdgeJobResult(edgeJob, result, now)
+		if scheduleChanged {
+			// a new version makes the agents reload the schedule, with or without the retry
+			edgeJob.Version++
+		}
+
+		return nil
+	})
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an edge job with the specified identifier inside the database", err}
+	} else if err == errEdgeJobNotAssociated {
+		return &httperror.HandlerError{http.StatusNotFound, "The edge job is not associated to the endpoint", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the edge job result inside the database", err}
+	}
+
+	if scheduleChanged {
+		for endpointID := range edgeJob.Endpoints {
+			handler.ReverseTunnelService.AddEdgeJob(endpointID, edgeJob)
+		}
+	}
+
+	err = handler.DataStore.EdgeJobResult().DeleteEdgeJobResultsBefore(edgeJob.ID, edge.FirstRetainedEdgeJobRun(edgeJob))
+	if err != nil {
+		logger.WithError(err).Warn("unable to remove the expired edge job results")
+	}
+
+	return response.JSON(w, result)
+}
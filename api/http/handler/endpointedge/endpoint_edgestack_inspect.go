@@ -1,14 +1,16 @@
 package endpointedge
 
 import (
+	"fmt"
 	"net/http"
-	"path"
+	"strings"
 
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/edge"
 )
 
 type configResponse struct {
@@ -18,16 +20,18 @@ type configResponse struct {
 }
 
 // @summary Inspect an Edge Stack for an Endpoint
-// @description
+// @description The variables of the Edge Stack are replaced by their value for the endpoint in the stack file content
 // @tags edge, endpoints, edge_stacks
 // @accept json
 // @produce json
 // @param id path string true "Endpoint Id"
 // @param stackID path string true "EdgeStack Id"
 // @success 200 {object} configResponse
+// @failure 403 "The endpoint is waiting for approval"
 // @failure 500
 // @failure 400
 // @failure 404
+// @failure 409 A required variable has no value for the endpoint
 // @router /endpoints/{id}/edge/stacks/{stackId} [get]
 func (handler *Handler) endpointEdgeStackInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
 	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
@@ -47,6 +51,10 @@ func (handler *Handler) endpointEdgeStackInspect(w http.ResponseWriter, r *http.
 		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
 	}
 
+	if endpoint.EdgePending {
+		return &httperror.HandlerError{http.StatusForbidden, "The endpoint is waiting for approval", errEndpointPending}
+	}
+
 	edgeStackID, err := request.RetrieveNumericRouteVariableValue(r, "stackId")
 	if err != nil {
 		return &httperror.HandlerError{http.StatusBadRequest, "Invalid edge stack identifier route variable", err}
@@ -59,14 +67,30 @@ func (handler *Handler) endpointEdgeStackInspect(w http.ResponseWriter, r *http.
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an edge stack with the specified identifier inside the database", err}
 	}
 
-	stackFileContent, err := handler.FileService.GetFileContent(path.Join(edgeStack.ProjectPath, edgeStack.EntryPoint))
+	version := edge.EdgeStackEndpointVersion(edgeStack, endpoint.ID)
+
+	stackFileContent, err := handler.FileService.GetFileContent(edge.EdgeStackFilePath(edgeStack, version))
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve Compose file from disk", err}
 	}
 
+	content := string(stackFileContent)
+	if len(edgeStack.Variables) > 0 {
+		values, missing, err := edge.ResolveEndpointEdgeStackVariables(handler.DataStore, edgeStack, endpoint)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to resolve the variables of the edge stack", err}
+		}
+
+		if len(missing) > 0 {
+			return &httperror.HandlerError{http.StatusConflict, "Required variables of the edge stack have no value for this endpoint", fmt.Errorf("Missing values for variables: %s", strings.Join(missing, ", "))}
+		}
+
+		content = edge.SubstituteEdgeStackVariables(content, values)
+	}
+
 	return response.JSON(w, configResponse{
 		Prune:            edgeStack.Prune,
-		StackFileContent: string(stackFileContent),
+		StackFileContent: content,
 		Name:             edgeStack.Name,
 	})
 }
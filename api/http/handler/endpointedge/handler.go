@@ -0,0 +1,42 @@
+package endpointedge
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/logging"
+)
+
+var logger = logging.Component("endpoint_edge")
+
+// errEndpointPending is returned when an Edge endpoint waiting for approval requests its stacks or jobs
+var errEndpointPending = errors.New("Endpoint is waiting for approval")
+
+// Handler is the HTTP handler used to handle edge endpoint operations.
+type Handler struct {
+	*mux.Router
+	requestBouncer       *security.RequestBouncer
+	DataStore            portainer.DataStore
+	FileService          portainer.FileService
+	ReverseTunnelService portainer.ReverseTunnelService
+}
+
+// NewHandler creates a handler to manage endpoint operations.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router:         mux.NewRouter(),
+		requestBouncer: bouncer,
+	}
+
+	h.Handle("/{id}/edge/stacks/{stackId}",
+		bouncer.PublicAccess(httperror.LoggerHandler(h.endpointEdgeStackInspect))).Methods(http.MethodGet)
+	h.Handle("/{id}/edge/jobs/{jobID}/logs",
+		bouncer.PublicAccess(httperror.LoggerHandler(h.endpointEdgeJobsLogs))).Methods(http.MethodPost)
+	h.Handle("/{id}/edge/jobs/{jobID}/results",
+		bouncer.PublicAccess(httperror.LoggerHandler(h.endpointEdgeJobResult))).Methods(http.MethodPost)
+	return h
+}
@@ -56,6 +56,11 @@ func (handler *Handler) endpointDelete(w http.ResponseWriter, r *http.Request) *
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove endpoint relation from the database", err}
 	}
 
+	err = handler.DataStore.SnapshotHistory().DeleteEndpointSnapshotHistory(endpoint.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove endpoint snapshot history from the database", err}
+	}
+
 	for _, tagID := range endpoint.TagIDs {
 		tag, err := handler.DataStore.Tag().Tag(tagID)
 		if err != nil {
@@ -94,8 +99,11 @@ func (handler *Handler) endpointDelete(w http.ResponseWriter, r *http.Request) *
 
 	for idx := range edgeStacks {
 		edgeStack := &edgeStacks[idx]
-		if _, ok := edgeStack.Status[endpoint.ID]; ok {
+		_, hasStatus := edgeStack.Status[endpoint.ID]
+		_, hasDeployment := edgeStack.EndpointDeployments[endpoint.ID]
+		if hasStatus || hasDeployment {
 			delete(edgeStack.Status, endpoint.ID)
+			delete(edgeStack.EndpointDeployments, endpoint.ID)
 			err = handler.DataStore.EdgeStack().UpdateEdgeStack(edgeStack.ID, edgeStack)
 			if err != nil {
 				return &httperror.HandlerError{http.StatusInternalServerError, "Unable to update edge stack", err}
@@ -0,0 +1,86 @@
+package endpoints
+
+import (
+	"net/http"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/snapshot"
+)
+
+type endpointHealthResponse struct {
+	// The status of the endpoint (1 - up, 2 - down)
+	Status portainer.EndpointStatus `example:"1"`
+	// Error returned by the latest failed contact with the endpoint
+	LastError string `example:"Cannot connect to the Docker daemon"`
+	// Unix timestamp of the latest failed contact with the endpoint
+	LastErrorDate int64 `example:"1587399600"`
+	// Unix timestamp of the latest successful contact with the endpoint
+	LastSuccessDate int64 `example:"1587399600"`
+	// Number of failed contacts since the latest successful one
+	ConsecutiveFailures int `example:"0"`
+	// Number of consecutive failed contacts after which the endpoint is marked as down
+	FailureThreshold int `example:"3"`
+	// Whether the status of the endpoint changed too often during the last hour
+	Flapping bool `example:"false"`
+	// Latest status changes of the endpoint, ordered from the oldest to the most recent one
+	StatusHistory []portainer.EndpointStatusChange
+}
+
+// @id EndpointHealthInspect
+// @summary Inspect the health of an endpoint
+// @description Retrieve the outcome of the latest contacts with an endpoint, its status changes and whether it is flapping.
+// @description **Access policy**: restricted
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @success 200 {object} endpointHealthResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/health [get]
+func (handler *Handler) endpointHealthInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == errors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve settings from the database", err}
+	}
+
+	statusHistory := endpoint.Health.StatusHistory
+	if statusHistory == nil {
+		statusHistory = []portainer.EndpointStatusChange{}
+	}
+
+	return response.JSON(w, &endpointHealthResponse{
+		Status:              endpoint.Status,
+		LastError:           endpoint.Health.LastError,
+		LastErrorDate:       endpoint.Health.LastErrorDate,
+		LastSuccessDate:     endpoint.Health.LastSuccessDate,
+		ConsecutiveFailures: endpoint.Health.ConsecutiveFailures,
+		FailureThreshold:    snapshot.FailureThreshold(settings),
+		Flapping:            snapshot.IsFlapping(&endpoint.Health, time.Now()),
+		StatusHistory:       statusHistory,
+	})
+}
@@ -43,6 +43,7 @@ func (handler *Handler) endpointInspect(w http.ResponseWriter, r *http.Request)
 
 	hideFields(endpoint)
 	endpoint.ComposeSyntaxMaxVersion = handler.ComposeStackManager.ComposeSyntaxMaxVersion()
+	endpoint.ComposeSpecSupported = handler.ComposeStackManager.ComposeSpecSupported()
 
 	return response.JSON(w, endpoint)
 }
@@ -0,0 +1,77 @@
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+// @id EndpointSnapshotHistory
+// @summary Retrieve the snapshot history of an endpoint
+// @description Retrieve the key figures of the snapshots of an endpoint over a period of time, ordered from the oldest to the most recent one.
+// @description Points older than the downsampling age are averaged over the downsampling interval.
+// @description **Access policy**: restricted
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param from query int false "Unix timestamp of the start of the period (inclusive), defaults to 24 hours before the end of the period"
+// @param to query int false "Unix timestamp of the end of the period (exclusive), defaults to now"
+// @success 200 {array} portainer.SnapshotHistoryPoint "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/snapshots [get]
+func (handler *Handler) endpointSnapshotHistory(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	to, err := request.RetrieveNumericQueryParameter(r, "to", true)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: to", err}
+	}
+	if to == 0 {
+		// the end of the period is exclusive, include the snapshots taken during the current second
+		to = int(time.Now().Unix()) + 1
+	}
+
+	from, err := request.RetrieveNumericQueryParameter(r, "from", true)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: from", err}
+	}
+	if from == 0 {
+		from = to - int((24 * time.Hour).Seconds())
+	}
+
+	if from < 0 || from >= to {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameters: from must be positive and before to", errors.New("Invalid period")}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	points, err := handler.DataStore.SnapshotHistory().SnapshotHistory(endpoint.ID, int64(from), int64(to))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the snapshot history from the database", err}
+	}
+
+	return response.JSON(w, points)
+}
@@ -1,12 +1,12 @@
 package endpoints
 
 import (
-	"log"
 	"net/http"
+	"time"
 
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/response"
-	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/logging"
 	"github.com/portainer/portainer/api/internal/snapshot"
 )
 
@@ -25,6 +25,13 @@ func (handler *Handler) endpointSnapshots(w http.ResponseWriter, r *http.Request
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoints from the database", err}
 	}
 
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve settings from the database", err}
+	}
+
+	failureThreshold := snapshot.FailureThreshold(settings)
+
 	for _, endpoint := range endpoints {
 		if !snapshot.SupportDirectSnapshot(&endpoint) {
 			continue
@@ -32,17 +39,18 @@ func (handler *Handler) endpointSnapshots(w http.ResponseWriter, r *http.Request
 
 		snapshotError := handler.SnapshotService.SnapshotEndpoint(&endpoint)
 
+		endpointLogger := logger.WithContext(r.Context()).WithField(logging.FieldEndpointID, endpoint.ID)
+
 		latestEndpointReference, err := handler.DataStore.Endpoint().Endpoint(endpoint.ID)
 		if latestEndpointReference == nil {
-			log.Printf("background schedule error (endpoint snapshot). Endpoint not found inside the database anymore (endpoint=%s, URL=%s) (err=%s)\n", endpoint.Name, endpoint.URL, err)
+			endpointLogger.WithError(err).Warn("endpoint not found inside the database anymore, skipping its snapshot")
 			continue
 		}
 
-		endpoint.Status = portainer.EndpointStatusUp
 		if snapshotError != nil {
-			log.Printf("background schedule error (endpoint snapshot). Unable to create snapshot (endpoint=%s, URL=%s) (err=%s)\n", endpoint.Name, endpoint.URL, snapshotError)
-			endpoint.Status = portainer.EndpointStatusDown
+			endpointLogger.WithError(snapshotError).Warn("unable to create the endpoint snapshot")
 		}
+		snapshot.UpdateEndpointHealth(latestEndpointReference, snapshotError, failureThreshold, time.Now())
 
 		latestEndpointReference.Snapshots = endpoint.Snapshots
 		latestEndpointReference.Kubernetes.Snapshots = endpoint.Kubernetes.Snapshots
@@ -12,6 +12,8 @@ import (
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/internal/snapshot"
 )
 
 type stackStatusResponse struct {
@@ -19,6 +21,8 @@ type stackStatusResponse struct {
 	ID portainer.EdgeStackID `example:"1"`
 	// Version of this stack
 	Version int `example:"3"`
+	// Version the endpoint was sent back to after failing to deploy the latest version, 0 when not rolled back
+	RollbackTo int `example:"2"`
 }
 
 type edgeJobResponse struct {
@@ -32,6 +36,8 @@ type edgeJobResponse struct {
 	Script string `json:"Script" example:"echo hello"`
 	// Version of this EdgeJob
 	Version int `json:"Version" example:"2"`
+	// The date in unix time when the failed run is retried once, independently of the schedule. 0 when no retry is scheduled
+	RetryDate int64 `json:"RetryDate" example:"1587399600"`
 }
 
 type endpointStatusInspectResponse struct {
@@ -49,19 +55,47 @@ type endpointStatusInspectResponse struct {
 	Stacks []stackStatusResponse `json:"stacks"`
 }
 
+type endpointStatusPayload struct {
+	// Agent version and host metrics of the Edge endpoint
+	Heartbeat portainer.EdgeHeartbeat
+	// Optional snapshot of an Edge agent on Docker
+	DockerSnapshot *portainer.DockerSnapshot
+	// Optional snapshot of an Edge agent on Kubernetes
+	KubernetesSnapshot *portainer.KubernetesSnapshot
+}
+
+func (payload *endpointStatusPayload) Validate(r *http.Request) error {
+	heartbeat := payload.Heartbeat
+	if heartbeat.Uptime < 0 || heartbeat.RunningContainerCount < 0 || heartbeat.StoppedContainerCount < 0 {
+		return errors.New("Invalid heartbeat. Uptime and container counts must be positive numbers")
+	}
+	if heartbeat.DiskUsed < 0 || heartbeat.DiskTotal < 0 || (heartbeat.DiskTotal > 0 && heartbeat.DiskUsed > heartbeat.DiskTotal) {
+		return errors.New("Invalid heartbeat disk usage")
+	}
+	if heartbeat.MemoryUsed < 0 || heartbeat.MemoryTotal < 0 || (heartbeat.MemoryTotal > 0 && heartbeat.MemoryUsed > heartbeat.MemoryTotal) {
+		return errors.New("Invalid heartbeat memory usage")
+	}
+	return nil
+}
+
 // @id EndpointStatusInspect
 // @summary Get endpoint status
-// @description Endpoint for edge agent to check status of environment
+// @description Endpoint for edge agent to check status of environment.
+// @description When using POST, the agent reports its version, the metrics of its host and optionally a small snapshot,
+// @description stored on the endpoint without opening a reverse tunnel.
 // @description **Access policy**: restricted only to Edge endpoints
 // @tags endpoints
 // @security jwt
+// @accept json
 // @param id path int true "Endpoint identifier"
+// @param body body endpointStatusPayload false "Heartbeat of the Edge agent, only with POST"
 // @success 200 {object} endpointStatusInspectResponse "Success"
 // @failure 400 "Invalid request"
 // @failure 403 "Permission denied to access endpoint"
 // @failure 404 "Endpoint not found"
 // @failure 500 "Server error"
 // @router /endpoints/{id}/status [get]
+// @router /endpoints/{id}/status [post]
 func (handler *Handler) endpointStatusInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
 	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
 	if err != nil {
@@ -80,6 +114,11 @@ func (handler *Handler) endpointStatusInspect(w http.ResponseWriter, r *http.Req
 		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
 	}
 
+	// the relation of the endpoint is only updated when the attributes evaluated by the selectors change
+	now := time.Now()
+	previousAttributes := edge.NewSelectorEndpoint(endpoint, nil, nil, now)
+
+	platformDetected := false
 	if endpoint.EdgeID == "" {
 		edgeIdentifier := r.Header.Get(portainer.PortainerAgentEdgeIDHeader)
 		endpoint.EdgeID = edgeIdentifier
@@ -101,9 +140,24 @@ func (handler *Handler) endpointStatusInspect(w http.ResponseWriter, r *http.Req
 		} else if agentPlatform == portainer.AgentPlatformKubernetes {
 			endpoint.Type = portainer.EdgeAgentOnKubernetesEnvironment
 		}
+		platformDetected = true
+	}
+
+	snapshotUpdated := false
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, portainer.EdgeHeartbeatMaxSize)
+
+		var payload endpointStatusPayload
+		err = request.DecodeAndValidateJSONPayload(r, &payload)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+		}
+
+		snapshotUpdated = edge.ApplyEdgeHeartbeat(endpoint, payload.Heartbeat, payload.DockerSnapshot, payload.KubernetesSnapshot, now)
 	}
+	attributesChanged := !previousAttributes.EqualAttributes(edge.NewSelectorEndpoint(endpoint, nil, nil, now))
 
-	endpoint.LastCheckInDate = time.Now().Unix()
+	endpoint.LastCheckInDate = now.Unix()
 
 	err = handler.DataStore.Endpoint().UpdateEndpoint(endpoint.ID, endpoint)
 	if err != nil {
@@ -115,13 +169,30 @@ func (handler *Handler) endpointStatusInspect(w http.ResponseWriter, r *http.Req
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve settings from the database", err}
 	}
 
-	tunnel := handler.ReverseTunnelService.GetTunnelDetails(endpoint.ID)
+	if snapshotUpdated {
+		err = handler.recordSnapshotHistory(endpoint, settings, now)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist snapshot history inside the database", err}
+		}
+	}
 
 	checkinInterval := settings.EdgeAgentCheckinInterval
 	if endpoint.EdgeCheckinInterval != 0 {
 		checkinInterval = endpoint.EdgeCheckinInterval
 	}
 
+	// an endpoint waiting for approval keeps checking in but is not managed until it is approved
+	if endpoint.EdgePending {
+		return response.JSON(w, endpointStatusInspectResponse{
+			Status:          portainer.EdgeAgentIdle,
+			Schedules:       []edgeJobResponse{},
+			CheckinInterval: checkinInterval,
+			Stacks:          []stackStatusResponse{},
+		})
+	}
+
+	tunnel := handler.ReverseTunnelService.GetTunnelDetails(endpoint.ID)
+
 	schedules := []edgeJobResponse{}
 	for _, job := range tunnel.Jobs {
 		schedule := edgeJobResponse{
@@ -129,6 +200,7 @@ func (handler *Handler) endpointStatusInspect(w http.ResponseWriter, r *http.Req
 			CronExpression: job.CronExpression,
 			CollectLogs:    job.Endpoints[endpoint.ID].CollectLogs,
 			Version:        job.Version,
+			RetryDate:      job.Endpoints[endpoint.ID].RetryDate,
 		}
 
 		file, err := handler.FileService.GetFileContent(job.ScriptPath)
@@ -154,6 +226,15 @@ func (handler *Handler) endpointStatusInspect(w http.ResponseWriter, r *http.Req
 		handler.ReverseTunnelService.SetTunnelStatusToActive(endpoint.ID)
 	}
 
+	// the platform and the snapshot of the endpoint can change the edge groups it belongs to.
+	// The changes of its check-in age are evaluated by the edge fleet monitor
+	if platformDetected || attributesChanged {
+		_, err = edge.UpdateEndpointRelation(handler.DataStore, endpoint)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist endpoint relation changes inside the database", err}
+		}
+	}
+
 	relation, err := handler.DataStore.EndpointRelation().EndpointRelation(endpoint.ID)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve relation object from the database", err}
@@ -167,8 +248,9 @@ func (handler *Handler) endpointStatusInspect(w http.ResponseWriter, r *http.Req
 		}
 
 		stackStatus := stackStatusResponse{
-			ID:      stack.ID,
-			Version: stack.Version,
+			ID:         stack.ID,
+			Version:    edge.EdgeStackEndpointVersion(stack, endpoint.ID),
+			RollbackTo: edge.EdgeStackEndpointRollback(stack, endpoint.ID),
 		}
 
 		edgeStacksStatus = append(edgeStacksStatus, stackStatus)
@@ -178,3 +260,26 @@ func (handler *Handler) endpointStatusInspect(w http.ResponseWriter, r *http.Req
 
 	return response.JSON(w, statusResponse)
 }
+
+// recordSnapshotHistory adds the snapshot reported by the Edge agent to the snapshot history of the endpoint,
+// at most once per snapshot interval as the agent reports its snapshot on each check-in
+func (handler *Handler) recordSnapshotHistory(endpoint *portainer.Endpoint, settings *portainer.Settings, now time.Time) error {
+	interval := int64(snapshot.HistoryInterval(endpoint, settings).Seconds())
+	if interval > 0 {
+		points, err := handler.DataStore.SnapshotHistory().SnapshotHistory(endpoint.ID, now.Unix()-interval+1, now.Unix()+1)
+		if err != nil {
+			return err
+		}
+
+		if len(points) > 0 {
+			return nil
+		}
+	}
+
+	point := snapshot.NewHistoryPoint(endpoint)
+	if point == nil {
+		return nil
+	}
+
+	return handler.DataStore.SnapshotHistory().CreateSnapshotHistoryPoint(point)
+}
@@ -6,12 +6,15 @@ import (
 	"github.com/portainer/portainer/api/http/proxy"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/logging"
 
 	"net/http"
 
 	"github.com/gorilla/mux"
 )
 
+var logger = logging.Component("endpoints")
+
 func hideFields(endpoint *portainer.Endpoint) {
 	endpoint.AzureCredentials = portainer.AzureCredentials{}
 	if len(endpoint.Snapshots) > 0 {
@@ -61,7 +64,13 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointExtensionRemove))).Methods(http.MethodDelete)
 	h.Handle("/endpoints/{id}/snapshot",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.endpointSnapshot))).Methods(http.MethodPost)
+	h.Handle("/endpoints/{id}/health",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointHealthInspect))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/snapshots",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointSnapshotHistory))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/approve",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.endpointApprove))).Methods(http.MethodPost)
 	h.Handle("/endpoints/{id}/status",
-		bouncer.PublicAccess(httperror.LoggerHandler(h.endpointStatusInspect))).Methods(http.MethodGet)
+		bouncer.PublicAccess(httperror.LoggerHandler(h.endpointStatusInspect))).Methods(http.MethodGet, http.MethodPost)
 	return h
 }
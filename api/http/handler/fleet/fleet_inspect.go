@@ -0,0 +1,138 @@
+package fleet
+
+import (
+	"net/http"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/internal/edgefleet"
+)
+
+// @id EdgeFleetInspect
+// @summary Report the health of the Edge endpoints
+// @description Classify the Edge endpoints as healthy, late or offline from their check-ins
+// @description and report the deployment state of the Edge stacks and the pending Edge jobs.
+// @description **Access policy**: administrator
+// @tags edge
+// @security jwt
+// @produce json
+// @param edgeGroupId query int false "Only report the endpoints of this Edge group"
+// @param tagId query int false "Only report the endpoints associated to this tag, directly or through their endpoint group"
+// @success 200 {object} edgefleet.Report
+// @failure 400
+// @failure 404
+// @failure 500
+// @failure 503 Edge compute features are disabled
+// @router /edge/fleet [get]
+func (handler *Handler) fleetInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	edgeGroupID, err := request.RetrieveNumericQueryParameter(r, "edgeGroupId", true)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: edgeGroupId", err}
+	}
+
+	tagID, err := request.RetrieveNumericQueryParameter(r, "tagId", true)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: tagId", err}
+	}
+
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve settings from the database", err}
+	}
+
+	endpoints, err := handler.DataStore.Endpoint().Endpoints()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoints from the database", err}
+	}
+
+	endpointGroups, err := handler.DataStore.EndpointGroup().EndpointGroups()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoint groups from the database", err}
+	}
+
+	tags, err := handler.DataStore.Tag().Tags()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve tags from the database", err}
+	}
+
+	edgeGroups, err := handler.DataStore.EdgeGroup().EdgeGroups()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve edge groups from the database", err}
+	}
+
+	edgeStacks, err := handler.DataStore.EdgeStack().EdgeStacks()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve edge stacks from the database", err}
+	}
+
+	edgeJobs, err := handler.DataStore.EdgeJob().EdgeJobs()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve edge jobs from the database", err}
+	}
+
+	now := time.Now()
+	membership := edge.NewEdgeGroupMembership(endpointGroups, tags, now)
+
+	if edgeGroupID != 0 {
+		edgeGroup, err := handler.DataStore.EdgeGroup().EdgeGroup(portainer.EdgeGroupID(edgeGroupID))
+		if err == bolterrors.ErrObjectNotFound {
+			return &httperror.HandlerError{http.StatusNotFound, "Unable to find an edge group with the specified identifier inside the database", err}
+		} else if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an edge group with the specified identifier inside the database", err}
+		}
+
+		endpoints = filterEndpoints(endpoints, func(endpoint *portainer.Endpoint) bool {
+			return membership.Contains(edgeGroup, endpoint)
+		})
+	}
+
+	if tagID != 0 {
+		endpoints = filterEndpoints(endpoints, func(endpoint *portainer.Endpoint) bool {
+			return hasTag(endpoint, endpointGroups, portainer.TagID(tagID))
+		})
+	}
+
+	edgeStackEndpoints := make(map[portainer.EdgeStackID][]portainer.EndpointID)
+	for _, edgeStack := range edgeStacks {
+		relatedEndpoints, err := membership.EdgeStackEndpoints(edgeStack.EdgeGroups, endpoints, edgeGroups)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the endpoints of an edge stack", err}
+		}
+		edgeStackEndpoints[edgeStack.ID] = relatedEndpoints
+	}
+
+	return response.JSON(w, edgefleet.NewReport(endpoints, edgeStacks, edgeStackEndpoints, edgeJobs, settings, now))
+}
+
+func filterEndpoints(endpoints []portainer.Endpoint, keep func(endpoint *portainer.Endpoint) bool) []portainer.Endpoint {
+	filteredEndpoints := make([]portainer.Endpoint, 0)
+	for idx := range endpoints {
+		if keep(&endpoints[idx]) {
+			filteredEndpoints = append(filteredEndpoints, endpoints[idx])
+		}
+	}
+	return filteredEndpoints
+}
+
+// hasTag returns true when the tag is associated to the endpoint or to its endpoint group
+func hasTag(endpoint *portainer.Endpoint, endpointGroups []portainer.EndpointGroup, tagID portainer.TagID) bool {
+	tagIDs := endpoint.TagIDs
+	for _, endpointGroup := range endpointGroups {
+		if endpointGroup.ID == endpoint.GroupID {
+			tagIDs = append(append([]portainer.TagID{}, tagIDs...), endpointGroup.TagIDs...)
+			break
+		}
+	}
+
+	for _, id := range tagIDs {
+		if id == tagID {
+			return true
+		}
+	}
+	return false
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/portainer/portainer/api/http/handler/backup"
 	"github.com/portainer/portainer/api/http/handler/customtemplates"
 	"github.com/portainer/portainer/api/http/handler/dockerhub"
+	"github.com/portainer/portainer/api/http/handler/edgeenrollment"
 	"github.com/portainer/portainer/api/http/handler/edgegroups"
 	"github.com/portainer/portainer/api/http/handler/edgejobs"
 	"github.com/portainer/portainer/api/http/handler/edgestacks"
@@ -17,7 +18,13 @@ import (
 	"github.com/portainer/portainer/api/http/handler/endpointproxy"
 	"github.com/portainer/portainer/api/http/handler/endpoints"
 	"github.com/portainer/portainer/api/http/handler/file"
+	"github.com/portainer/portainer/api/http/handler/fleet"
+	"github.com/portainer/portainer/api/http/handler/images"
+	"github.com/portainer/portainer/api/http/handler/jobs"
+	"github.com/portainer/portainer/api/http/handler/logs"
+	"github.com/portainer/portainer/api/http/handler/metrics"
 	"github.com/portainer/portainer/api/http/handler/motd"
+	"github.com/portainer/portainer/api/http/handler/notifications"
 	"github.com/portainer/portainer/api/http/handler/registries"
 	"github.com/portainer/portainer/api/http/handler/resourcecontrols"
 	"github.com/portainer/portainer/api/http/handler/roles"
@@ -28,6 +35,7 @@ import (
 	"github.com/portainer/portainer/api/http/handler/teammemberships"
 	"github.com/portainer/portainer/api/http/handler/teams"
 	"github.com/portainer/portainer/api/http/handler/templates"
+	"github.com/portainer/portainer/api/http/handler/tunnels"
 	"github.com/portainer/portainer/api/http/handler/upload"
 	"github.com/portainer/portainer/api/http/handler/users"
 	"github.com/portainer/portainer/api/http/handler/webhooks"
@@ -40,6 +48,7 @@ type Handler struct {
 	BackupHandler          *backup.Handler
 	CustomTemplatesHandler *customtemplates.Handler
 	DockerHubHandler       *dockerhub.Handler
+	EdgeEnrollmentHandler  *edgeenrollment.Handler
 	EdgeGroupsHandler      *edgegroups.Handler
 	EdgeJobsHandler        *edgejobs.Handler
 	EdgeStacksHandler      *edgestacks.Handler
@@ -49,7 +58,13 @@ type Handler struct {
 	EndpointHandler        *endpoints.Handler
 	EndpointProxyHandler   *endpointproxy.Handler
 	FileHandler            *file.Handler
+	FleetHandler           *fleet.Handler
+	ImageHandler           *images.Handler
+	JobHandler             *jobs.Handler
+	LogHandler             *logs.Handler
+	MetricsHandler         *metrics.Handler
 	MOTDHandler            *motd.Handler
+	NotificationHandler    *notifications.Handler
 	RegistryHandler        *registries.Handler
 	ResourceControlHandler *resourcecontrols.Handler
 	RoleHandler            *roles.Handler
@@ -60,6 +75,7 @@ type Handler struct {
 	TeamMembershipHandler  *teammemberships.Handler
 	TeamHandler            *teams.Handler
 	TemplatesHandler       *templates.Handler
+	TunnelsHandler         *tunnels.Handler
 	UploadHandler          *upload.Handler
 	UserHandler            *users.Handler
 	WebSocketHandler       *websocket.Handler
@@ -104,6 +120,12 @@ type Handler struct {
 // @tag.description Manage Docker environments
 // @tag.name endpoint_groups
 // @tag.description Manage endpoint groups
+// @tag.name images
+// @tag.description Browse the vulnerabilities of the images found on the endpoints
+// @tag.name jobs
+// @tag.description Follow stack deployment jobs
+// @tag.name logs
+// @tag.description Search the logs of containers and services
 // @tag.name motd
 // @tag.description Fetch the message of the day
 // @tag.name registries
@@ -160,6 +182,14 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.StripPrefix("/api", h.EdgeStacksHandler).ServeHTTP(w, r)
 	case strings.HasPrefix(r.URL.Path, "/api/edge_templates"):
 		http.StripPrefix("/api", h.EdgeTemplatesHandler).ServeHTTP(w, r)
+	case strings.HasPrefix(r.URL.Path, "/api/edge_enrollment_tokens"):
+		http.StripPrefix("/api", h.EdgeEnrollmentHandler).ServeHTTP(w, r)
+	case strings.HasPrefix(r.URL.Path, "/api/edge/fleet"):
+		http.StripPrefix("/api", h.FleetHandler).ServeHTTP(w, r)
+	case strings.HasPrefix(r.URL.Path, "/api/edge/register"):
+		http.StripPrefix("/api", h.EdgeEnrollmentHandler).ServeHTTP(w, r)
+	case strings.HasPrefix(r.URL.Path, "/api/edge/tunnels"):
+		http.StripPrefix("/api", h.TunnelsHandler).ServeHTTP(w, r)
 	case strings.HasPrefix(r.URL.Path, "/api/endpoint_groups"):
 		http.StripPrefix("/api", h.EndpointGroupHandler).ServeHTTP(w, r)
 	case strings.HasPrefix(r.URL.Path, "/api/endpoints"):
@@ -177,8 +207,18 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		default:
 			http.StripPrefix("/api", h.EndpointHandler).ServeHTTP(w, r)
 		}
+	case strings.HasPrefix(r.URL.Path, "/api/images"):
+		http.StripPrefix("/api", h.ImageHandler).ServeHTTP(w, r)
+	case strings.HasPrefix(r.URL.Path, "/api/jobs"):
+		http.StripPrefix("/api", h.JobHandler).ServeHTTP(w, r)
+	case strings.HasPrefix(r.URL.Path, "/api/logs"):
+		http.StripPrefix("/api", h.LogHandler).ServeHTTP(w, r)
+	case strings.HasPrefix(r.URL.Path, "/api/metrics"):
+		http.StripPrefix("/api", h.MetricsHandler).ServeHTTP(w, r)
 	case strings.HasPrefix(r.URL.Path, "/api/motd"):
 		http.StripPrefix("/api", h.MOTDHandler).ServeHTTP(w, r)
+	case strings.HasPrefix(r.URL.Path, "/api/notifications"):
+		http.StripPrefix("/api", h.NotificationHandler).ServeHTTP(w, r)
 	case strings.HasPrefix(r.URL.Path, "/api/registries"):
 		http.StripPrefix("/api", h.RegistryHandler).ServeHTTP(w, r)
 	case strings.HasPrefix(r.URL.Path, "/api/resource_controls"):
@@ -0,0 +1,67 @@
+package images
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+// Handler is the HTTP handler used to handle image vulnerability operations.
+type Handler struct {
+	*mux.Router
+	DataStore portainer.DataStore
+}
+
+// NewHandler creates a handler to manage image vulnerability operations.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+	h.Handle("/images/vulnerabilities",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.imageVulnerabilityList))).Methods(http.MethodGet)
+	h.Handle("/images/vulnerabilities/{digest}",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.imageVulnerabilityInspect))).Methods(http.MethodGet)
+	return h
+}
+
+// authorizedEndpointIDs returns the identifiers of the endpoints the user can access
+func (handler *Handler) authorizedEndpointIDs(r *http.Request) (map[portainer.EndpointID]bool, *httperror.HandlerError) {
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	endpointGroups, err := handler.DataStore.EndpointGroup().EndpointGroups()
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoint groups from the database", err}
+	}
+
+	endpoints, err := handler.DataStore.Endpoint().Endpoints()
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoints from the database", err}
+	}
+
+	endpointIDs := make(map[portainer.EndpointID]bool)
+	for _, endpoint := range security.FilterEndpoints(endpoints, endpointGroups, securityContext) {
+		endpointIDs[endpoint.ID] = true
+	}
+
+	return endpointIDs, nil
+}
+
+// filterReportEndpoints removes the endpoints the user cannot access from the report.
+// It returns false when the image was not found on any of the endpoints the user can access.
+func filterReportEndpoints(report *portainer.ImageVulnerabilityReport, authorizedEndpointIDs map[portainer.EndpointID]bool) bool {
+	endpointIDs := make([]portainer.EndpointID, 0, len(report.EndpointIDs))
+	for _, endpointID := range report.EndpointIDs {
+		if authorizedEndpointIDs[endpointID] {
+			endpointIDs = append(endpointIDs, endpointID)
+		}
+	}
+
+	report.EndpointIDs = endpointIDs
+	return len(endpointIDs) > 0
+}
@@ -0,0 +1,48 @@
+package images
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+// @id ImageVulnerabilityInspect
+// @summary Inspect an image vulnerability report
+// @description Retrieve the vulnerability report of an image found on an endpoint the user can access.
+// @description **Access policy**: restricted
+// @tags images
+// @security jwt
+// @produce json
+// @param digest path string true "Image digest, e.g. sha256:3c5c..."
+// @success 200 {object} portainer.ImageVulnerabilityReport "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Image vulnerability report not found"
+// @failure 500 "Server error"
+// @router /images/vulnerabilities/{digest} [get]
+func (handler *Handler) imageVulnerabilityInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	digest, err := request.RetrieveRouteVariableValue(r, "digest")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid image digest route variable", err}
+	}
+
+	authorizedEndpointIDs, handlerErr := handler.authorizedEndpointIDs(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	report, err := handler.DataStore.ImageVulnerability().ImageVulnerabilityReport(digest)
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an image vulnerability report with the specified digest", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an image vulnerability report with the specified digest", err}
+	}
+
+	if !filterReportEndpoints(report, authorizedEndpointIDs) {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an image vulnerability report with the specified digest", bolterrors.ErrObjectNotFound}
+	}
+
+	return response.JSON(w, report)
+}
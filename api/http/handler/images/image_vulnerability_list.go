@@ -0,0 +1,139 @@
+package images
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/vulnerability"
+)
+
+type imageVulnerabilityListFilters struct {
+	endpointID      portainer.EndpointID
+	severity        portainer.VulnerabilitySeverity
+	image           string
+	vulnerabilityID string
+}
+
+// @id ImageVulnerabilityList
+// @summary List image vulnerability reports
+// @description List the vulnerability reports of the images found on the endpoints the user can access.
+// @description When a minimum severity is specified, only the vulnerabilities of that severity or higher are returned.
+// @description **Access policy**: restricted
+// @tags images
+// @security jwt
+// @produce json
+// @param endpointId query int false "Only return the images found on this endpoint"
+// @param severity query string false "Minimum severity of the returned vulnerabilities" Enums(UNKNOWN, LOW, MEDIUM, HIGH, CRITICAL)
+// @param image query string false "Only return the images whose tags or references contain this value"
+// @param vulnerabilityId query string false "Only return the images affected by this vulnerability, e.g. CVE-2021-3449"
+// @success 200 {array} portainer.ImageVulnerabilityReport "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /images/vulnerabilities [get]
+func (handler *Handler) imageVulnerabilityList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var filters imageVulnerabilityListFilters
+
+	endpointID, _ := request.RetrieveNumericQueryParameter(r, "endpointId", true)
+	filters.endpointID = portainer.EndpointID(endpointID)
+
+	severity, _ := request.RetrieveQueryParameter(r, "severity", true)
+	if severity != "" {
+		minimum, err := vulnerability.ParseSeverity(severity)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: severity", err}
+		}
+		filters.severity = minimum
+	}
+
+	filters.image, _ = request.RetrieveQueryParameter(r, "image", true)
+	filters.vulnerabilityID, _ = request.RetrieveQueryParameter(r, "vulnerabilityId", true)
+
+	authorizedEndpointIDs, handlerErr := handler.authorizedEndpointIDs(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	reports, err := handler.DataStore.ImageVulnerability().ImageVulnerabilityReports()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve image vulnerability reports from the database", err}
+	}
+
+	filteredReports := make([]portainer.ImageVulnerabilityReport, 0, len(reports))
+	for _, report := range reports {
+		if !filterReportEndpoints(&report, authorizedEndpointIDs) {
+			continue
+		}
+
+		if filterReport(&report, &filters) {
+			filteredReports = append(filteredReports, report)
+		}
+	}
+
+	sort.Slice(filteredReports, func(i, j int) bool {
+		return filteredReports[i].Digest < filteredReports[j].Digest
+	})
+
+	return response.JSON(w, filteredReports)
+}
+
+// filterReport returns false when the report does not match the filters. The vulnerabilities
+// of the report are restricted to the ones matching the severity and vulnerability filters.
+func filterReport(report *portainer.ImageVulnerabilityReport, filters *imageVulnerabilityListFilters) bool {
+	if filters.endpointID != 0 && !containsEndpoint(report.EndpointIDs, filters.endpointID) {
+		return false
+	}
+
+	if filters.image != "" && !matchesImage(report, filters.image) {
+		return false
+	}
+
+	if filters.severity == "" && filters.vulnerabilityID == "" {
+		return true
+	}
+
+	vulnerabilities := make([]portainer.ImageVulnerability, 0)
+	for _, vuln := range report.Vulnerabilities {
+		if filters.severity != "" && !vulnerability.AtLeast(vuln.Severity, filters.severity) {
+			continue
+		}
+
+		if filters.vulnerabilityID != "" && !strings.EqualFold(vuln.ID, filters.vulnerabilityID) {
+			continue
+		}
+
+		vulnerabilities = append(vulnerabilities, vuln)
+	}
+
+	report.Vulnerabilities = vulnerabilities
+	return len(vulnerabilities) > 0
+}
+
+func containsEndpoint(endpointIDs []portainer.EndpointID, endpointID portainer.EndpointID) bool {
+	for _, id := range endpointIDs {
+		if id == endpointID {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesImage(report *portainer.ImageVulnerabilityReport, image string) bool {
+	for _, tag := range report.Tags {
+		if strings.Contains(tag, image) {
+			return true
+		}
+	}
+
+	for _, reference := range report.References {
+		if strings.Contains(reference, image) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	portainer "github.com/portainer/portainer/api"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/deploymentjob"
+	"github.com/portainer/portainer/api/internal/logging"
+)
+
+var logger = logging.Component("jobs")
+
+// Handler is the HTTP handler used to follow the stack deployment jobs.
+type Handler struct {
+	*mux.Router
+	DeploymentJobService *deploymentjob.Service
+	connectionUpgrader   websocket.Upgrader
+}
+
+// NewHandler creates a handler to follow the stack deployment jobs.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router:             mux.NewRouter(),
+		connectionUpgrader: websocket.Upgrader{},
+	}
+	h.Handle("/jobs/{id}",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.jobInspect))).Methods(http.MethodGet)
+	h.Handle("/jobs/{id}/stream",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.jobStream))).Methods(http.MethodGet)
+	return h
+}
+
+// retrieveJob returns the job associated to the id route variable when the user is an administrator
+// or the user who started the job
+func (handler *Handler) retrieveJob(r *http.Request) (*portainer.DeploymentJob, *httperror.HandlerError) {
+	jobID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid job identifier route variable", err}
+	}
+
+	job, err := handler.DeploymentJobService.Job(portainer.DeploymentJobID(jobID))
+	if err == deploymentjob.ErrJobNotFound {
+		return nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find a job with the specified identifier", err}
+	} else if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a job with the specified identifier", err}
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve user details from authentication token", err}
+	}
+
+	if tokenData.Role != portainer.AdministratorRole && tokenData.Username != job.CreatedBy {
+		return nil, &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", httperrors.ErrResourceAccessDenied}
+	}
+
+	return job, nil
+}
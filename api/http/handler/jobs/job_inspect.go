@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+)
+
+// @id JobInspect
+// @summary Inspect a stack deployment job
+// @description Retrieve the status and the output of a stack deployment job.
+// @description **Access policy**: restricted, only the administrators and the user who started the job can inspect it
+// @tags jobs
+// @security jwt
+// @produce json
+// @param id path int true "Job identifier"
+// @success 200 {object} portainer.DeploymentJob "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Job not found"
+// @failure 500 "Server error"
+// @router /jobs/{id} [get]
+func (handler *Handler) jobInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	job, handlerErr := handler.retrieveJob(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	return response.JSON(w, job)
+}
@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/deploymentjob"
+)
+
+const streamWriteTimeout = 10 * time.Second
+
+type jobStreamMessage struct {
+	// Output lines produced since the previous message
+	Output []string
+	// Status of the job
+	Status portainer.DeploymentJobStatus
+	// Error message when the deployment failed
+	Error string `json:",omitempty"`
+}
+
+// @id JobStream
+// @summary Follow a stack deployment job
+// @description Upgrade the request to the websocket protocol and stream the output of a stack deployment job.
+// @description Each message contains the output lines produced since the previous message and the job status.
+// @description The connection is closed once the job is completed.
+// @description Authentication and access is controlled via the mandatory token query parameter.
+// @description **Access policy**: restricted, only the administrators and the user who started the job can follow it
+// @tags jobs
+// @security jwt
+// @param id path int true "Job identifier"
+// @param token query string true "JWT token used for authentication"
+// @success 200
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Job not found"
+// @failure 500 "Server error"
+// @router /jobs/{id}/stream [get]
+func (handler *Handler) jobStream(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	job, handlerErr := handler.retrieveJob(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	websocketConn, err := handler.connectionUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to upgrade the connection to the websocket protocol", err}
+	}
+	defer websocketConn.Close()
+
+	err = handler.streamJob(websocketConn, job.ID)
+	if err != nil {
+		logger.WithContext(r.Context()).WithField("job_id", job.ID).WithError(err).Debug("job stream interrupted")
+	}
+
+	return nil
+}
+
+func (handler *Handler) streamJob(websocketConn *websocket.Conn, jobID portainer.DeploymentJobID) error {
+	// the client is not expected to send messages, reading is only used to detect the connection closure
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := websocketConn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	offset := 0
+	for {
+		lines, status, changed, err := handler.DeploymentJobService.Follow(jobID, offset)
+		if err != nil {
+			return err
+		}
+		offset += len(lines)
+
+		message := jobStreamMessage{
+			Output: lines,
+			Status: status,
+		}
+
+		completed := deploymentjob.IsCompleted(status)
+		if completed {
+			job, err := handler.DeploymentJobService.Job(jobID)
+			if err != nil {
+				return err
+			}
+			message.Error = job.Error
+		}
+
+		websocketConn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		err = websocketConn.WriteJSON(message)
+		if err != nil {
+			return err
+		}
+
+		if completed {
+			closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			return websocketConn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(streamWriteTimeout))
+		}
+
+		select {
+		case <-changed:
+		case <-closed:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package logs
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/logging"
+)
+
+var logger = logging.Component("logs")
+
+// Handler is the HTTP handler used to search the logs of containers and services.
+type Handler struct {
+	*mux.Router
+	DataStore           portainer.DataStore
+	DockerClientFactory *docker.ClientFactory
+}
+
+// NewHandler creates a handler to search the logs of containers and services.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+	h.Handle("/logs/search",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.logSearch))).Methods(http.MethodPost)
+	return h
+}
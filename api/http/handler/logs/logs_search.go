@@ -0,0 +1,632 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/endpointutils"
+	"github.com/portainer/portainer/api/internal/labelfilter"
+	"github.com/portainer/portainer/api/internal/logging"
+	"github.com/portainer/portainer/api/internal/logsearch"
+)
+
+const (
+	defaultSearchLimit = 1000
+	maxSearchLimit     = 10000
+
+	// maxConcurrentEndpoints is the number of endpoints whose resources are listed concurrently
+	maxConcurrentEndpoints = 10
+	// maxConcurrentStreams is the number of log streams read concurrently
+	maxConcurrentStreams = 20
+)
+
+type logSearchPayload struct {
+	// Identifiers of the endpoints to search. All the Docker endpoints the user can access,
+	// except Edge endpoints, are searched when empty. Edge endpoints cannot be searched
+	EndpointIDs []portainer.EndpointID `example:"1,3"`
+	// Type of the resources to search (container or service), defaults to container
+	ResourceType string `example:"container" enums:"container,service"`
+	// Only search the resources deployed with this Compose or Swarm stack
+	StackName string `example:"web"`
+	// Regular expression the log lines must match
+	Pattern string `example:"(?i)error"`
+	// Unix timestamp, lines written before this time are excluded
+	Since int64 `example:"1614592800"`
+	// Unix timestamp, lines written after this time are excluded
+	Until int64 `example:"1614596400"`
+	// Maximum number of lines returned, defaults to 1000
+	Limit int `example:"1000"`
+
+	pattern *regexp.Regexp
+}
+
+func (payload *logSearchPayload) Validate(r *http.Request) error {
+	if payload.ResourceType == "" {
+		payload.ResourceType = logsearch.ResourceTypeContainer
+	}
+	if payload.ResourceType != logsearch.ResourceTypeContainer && payload.ResourceType != logsearch.ResourceTypeService {
+		return errors.New("Invalid resource type. Valid values are: container or service")
+	}
+
+	if payload.Pattern != "" {
+		pattern, err := regexp.Compile(payload.Pattern)
+		if err != nil {
+			return errors.New("Invalid pattern: " + err.Error())
+		}
+		payload.pattern = pattern
+	}
+
+	if payload.Since < 0 || payload.Until < 0 {
+		return errors.New("Invalid time range")
+	}
+	if payload.Until != 0 && payload.Until < payload.Since {
+		return errors.New("Invalid time range. Until must be greater than or equal to since")
+	}
+
+	if payload.Limit == 0 {
+		payload.Limit = defaultSearchLimit
+	}
+	if payload.Limit < 0 || payload.Limit > maxSearchLimit {
+		return errors.New("Invalid limit. Value must be between 1 and " + strconv.Itoa(maxSearchLimit))
+	}
+
+	return nil
+}
+
+func (payload *logSearchPayload) query() *logsearch.Query {
+	query := &logsearch.Query{Pattern: payload.pattern}
+	if payload.Since != 0 {
+		query.Since = time.Unix(payload.Since, 0)
+	}
+	if payload.Until != 0 {
+		query.Until = time.Unix(payload.Until, 0)
+	}
+	return query
+}
+
+func (payload *logSearchPayload) logsOptions() types.ContainerLogsOptions {
+	options := types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Timestamps: true,
+	}
+	if payload.Since != 0 {
+		options.Since = strconv.FormatInt(payload.Since, 10)
+	}
+	if payload.Until != 0 {
+		options.Until = strconv.FormatInt(payload.Until, 10)
+	}
+	return options
+}
+
+// logSource is a container or a service whose logs are part of the search.
+// The TTY of a container is detected from its logs, as it is not part of the container list.
+type logSource struct {
+	client *client.Client
+	entry  logsearch.Entry
+	tty    *bool
+}
+
+// @id LogSearch
+// @summary Search the logs of containers or services
+// @description Search the logs of the containers or the services of one or more endpoints.
+// @description The lines are filtered by time range, stack and regular expression, the earliest ones up to the limit
+// @description are streamed in timestamp order as newline delimited JSON objects while the logs are read.
+// @description Only the logs of the resources the user can access, based on resource controls, are searched.
+// @description Edge endpoints are not searched. The containers of the Swarm endpoints connected through an agent
+// @description are searched on every node, the containers of the other Swarm endpoints only on the node Portainer
+// @description is connected to: search the services to include the logs of all the nodes.
+// @description Endpoints and nodes that cannot be reached are skipped.
+// @description **Access policy**: restricted
+// @tags logs
+// @security jwt
+// @accept json
+// @produce json
+// @param body body logSearchPayload true "Search criteria"
+// @success 200 {array} logsearch.Entry "Log lines, as newline delimited JSON"
+// @failure 400
+// @failure 403
+// @failure 404
+// @failure 500
+// @router /logs/search [post]
+func (handler *Handler) logSearch(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload logSearchPayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	endpoints, handlerErr := handler.searchedEndpoints(payload.EndpointIDs, securityContext)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	resourceControls, err := handler.DataStore.ResourceControl().ResourceControls()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve resource controls from the database", err}
+	}
+
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve settings from the database", err}
+	}
+
+	access := &logsearch.AccessContext{
+		IsAdmin:          securityContext.IsAdmin,
+		UserID:           securityContext.UserID,
+		ResourceControls: resourceControls,
+	}
+	for _, membership := range securityContext.UserMemberships {
+		access.TeamIDs = append(access.TeamIDs, membership.TeamID)
+	}
+
+	ctx := r.Context()
+
+	sources, clients := handler.collectLogSources(ctx, endpoints, &payload, access, settings)
+	defer func() {
+		for _, dockerClient := range clients {
+			dockerClient.Close()
+		}
+	}()
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+
+	err = searchLogs(ctx, sources, payload.logsOptions(), payload.query(), payload.Limit, func(entry logsearch.Entry) error {
+		err := encoder.Encode(entry)
+		if err != nil {
+			return err
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		logger.WithContext(ctx).WithError(err).Warn("unable to write log search results")
+	}
+
+	return nil
+}
+
+// searchedEndpoints returns the Docker endpoints to search. When no endpoint is specified,
+// all the Docker endpoints the user can access are returned, except Edge endpoints whose tunnel
+// would have to be opened for the search.
+func (handler *Handler) searchedEndpoints(endpointIDs []portainer.EndpointID, securityContext *security.RestrictedRequestContext) ([]portainer.Endpoint, *httperror.HandlerError) {
+	endpointGroups, err := handler.DataStore.EndpointGroup().EndpointGroups()
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoint groups from the database", err}
+	}
+
+	endpoints, err := handler.DataStore.Endpoint().Endpoints()
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoints from the database", err}
+	}
+
+	authorizedEndpoints := security.FilterEndpoints(endpoints, endpointGroups, securityContext)
+
+	if len(endpointIDs) == 0 {
+		searchedEndpoints := make([]portainer.Endpoint, 0)
+		for _, endpoint := range authorizedEndpoints {
+			if endpointutils.IsDockerEndpoint(&endpoint) && endpoint.Type != portainer.EdgeAgentOnDockerEnvironment {
+				searchedEndpoints = append(searchedEndpoints, endpoint)
+			}
+		}
+		return searchedEndpoints, nil
+	}
+
+	searchedEndpoints := make([]portainer.Endpoint, 0, len(endpointIDs))
+	for _, endpointID := range endpointIDs {
+		endpoint, err := handler.DataStore.Endpoint().Endpoint(endpointID)
+		if err == bolterrors.ErrObjectNotFound {
+			return nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+		} else if err != nil {
+			return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+		}
+
+		if !containsEndpoint(authorizedEndpoints, endpointID) {
+			return nil, &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", httperrors.ErrEndpointAccessDenied}
+		}
+
+		if !endpointutils.IsDockerEndpoint(endpoint) {
+			return nil, &httperror.HandlerError{http.StatusBadRequest, "Logs can only be searched on Docker endpoints", errors.New("Invalid endpoint type")}
+		}
+
+		if endpoint.Type == portainer.EdgeAgentOnDockerEnvironment {
+			return nil, &httperror.HandlerError{http.StatusBadRequest, "Logs cannot be searched on Edge endpoints", errors.New("Invalid endpoint type")}
+		}
+
+		searchedEndpoints = append(searchedEndpoints, *endpoint)
+	}
+
+	return searchedEndpoints, nil
+}
+
+func containsEndpoint(endpoints []portainer.Endpoint, endpointID portainer.EndpointID) bool {
+	for _, endpoint := range endpoints {
+		if endpoint.ID == endpointID {
+			return true
+		}
+	}
+	return false
+}
+
+// collectLogSources lists the resources of the endpoints, a bounded number of endpoints at a time, and returns
+// the ones the user can access along with the Docker clients that must be closed once the search is done.
+// Endpoints that cannot be reached are skipped.
+func (handler *Handler) collectLogSources(ctx context.Context, endpoints []portainer.Endpoint, payload *logSearchPayload, access *logsearch.AccessContext, settings *portainer.Settings) ([]*logSource, []*client.Client) {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		sources []*logSource
+		clients []*client.Client
+	)
+
+	workers := make(chan struct{}, maxConcurrentEndpoints)
+
+listing:
+	for idx := range endpoints {
+		select {
+		case workers <- struct{}{}:
+		case <-ctx.Done():
+			break listing
+		}
+
+		wg.Add(1)
+
+		go func(endpoint *portainer.Endpoint) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+
+			endpointSources, endpointClients := handler.endpointLogSources(ctx, endpoint, payload, access, settings)
+
+			mu.Lock()
+			defer mu.Unlock()
+			sources = append(sources, endpointSources...)
+			clients = append(clients, endpointClients...)
+		}(&endpoints[idx])
+	}
+
+	wg.Wait()
+
+	return sources, clients
+}
+
+// endpointLogSources lists the resources of an endpoint. The containers of a Swarm endpoint connected
+// through an agent are listed on each node, with a client targeting the node.
+func (handler *Handler) endpointLogSources(ctx context.Context, endpoint *portainer.Endpoint, payload *logSearchPayload, access *logsearch.AccessContext, settings *portainer.Settings) ([]*logSource, []*client.Client) {
+	endpointLogger := logger.WithContext(ctx).WithField(logging.FieldEndpointID, endpoint.ID)
+
+	dockerClient, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		endpointLogger.WithError(err).Warn("unable to create Docker client, skipping endpoint")
+		return nil, nil
+	}
+
+	if payload.ResourceType == logsearch.ResourceTypeService {
+		sources, err := serviceLogSources(ctx, dockerClient, endpoint, payload, access, settings)
+		if err != nil {
+			endpointLogger.WithError(err).Warn("unable to list services, skipping endpoint")
+			dockerClient.Close()
+			return nil, nil
+		}
+		return sources, []*client.Client{dockerClient}
+	}
+
+	if !isSwarmEndpoint(endpoint) || endpoint.Type != portainer.AgentOnDockerEnvironment {
+		sources, err := containerLogSources(ctx, dockerClient, endpoint, payload, access, settings)
+		if err != nil {
+			endpointLogger.WithError(err).Warn("unable to list containers, skipping endpoint")
+			dockerClient.Close()
+			return nil, nil
+		}
+		return sources, []*client.Client{dockerClient}
+	}
+
+	nodes, err := dockerClient.NodeList(ctx, types.NodeListOptions{})
+	dockerClient.Close()
+	if err != nil {
+		endpointLogger.WithError(err).Warn("unable to list Swarm nodes, skipping endpoint")
+		return nil, nil
+	}
+
+	var sources []*logSource
+	var clients []*client.Client
+	for _, node := range nodes {
+		nodeName := node.Description.Hostname
+		nodeLogger := endpointLogger.WithField("node", nodeName)
+
+		nodeClient, err := handler.DockerClientFactory.CreateClient(endpoint, nodeName)
+		if err != nil {
+			nodeLogger.WithError(err).Warn("unable to create Docker client, skipping node")
+			continue
+		}
+
+		nodeSources, err := containerLogSources(ctx, nodeClient, endpoint, payload, access, settings)
+		if err != nil {
+			nodeLogger.WithError(err).Warn("unable to list containers, skipping node")
+			nodeClient.Close()
+			continue
+		}
+
+		sources = append(sources, nodeSources...)
+		clients = append(clients, nodeClient)
+	}
+
+	return sources, clients
+}
+
+func isSwarmEndpoint(endpoint *portainer.Endpoint) bool {
+	return len(endpoint.Snapshots) > 0 && endpoint.Snapshots[len(endpoint.Snapshots)-1].Swarm
+}
+
+func containerLogSources(ctx context.Context, dockerClient *client.Client, endpoint *portainer.Endpoint, payload *logSearchPayload, access *logsearch.AccessContext, settings *portainer.Settings) ([]*logSource, error) {
+	labelFilters := labelfilter.CompileAll(settings, endpoint.GroupID, portainer.ContainerResourceControl)
+
+	containers, err := dockerClient.ContainerList(ctx, types.ContainerListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]*logSource, 0)
+	for _, container := range containers {
+		if !matchesResource(endpoint, container.ID, portainer.ContainerResourceControl, container.Labels, payload, access, labelFilters) {
+			continue
+		}
+
+		name := container.ID
+		if len(container.Names) > 0 {
+			name = strings.TrimPrefix(container.Names[0], "/")
+		}
+
+		sources = append(sources, &logSource{
+			client: dockerClient,
+			entry: logsearch.Entry{
+				EndpointID:   endpoint.ID,
+				ResourceType: logsearch.ResourceTypeContainer,
+				ResourceID:   container.ID,
+				ResourceName: name,
+			},
+		})
+	}
+
+	return sources, nil
+}
+
+func serviceLogSources(ctx context.Context, dockerClient *client.Client, endpoint *portainer.Endpoint, payload *logSearchPayload, access *logsearch.AccessContext, settings *portainer.Settings) ([]*logSource, error) {
+	if !isSwarmEndpoint(endpoint) {
+		return nil, nil
+	}
+
+	labelFilters := labelfilter.CompileAll(settings, endpoint.GroupID, portainer.ServiceResourceControl)
+
+	services, err := dockerClient.ServiceList(ctx, types.ServiceListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]*logSource, 0)
+	for _, service := range services {
+		if !matchesResource(endpoint, service.ID, portainer.ServiceResourceControl, service.Spec.Labels, payload, access, labelFilters) {
+			continue
+		}
+
+		containerSpec := service.Spec.TaskTemplate.ContainerSpec
+		tty := containerSpec != nil && containerSpec.TTY
+
+		sources = append(sources, &logSource{
+			client: dockerClient,
+			entry: logsearch.Entry{
+				EndpointID:   endpoint.ID,
+				ResourceType: logsearch.ResourceTypeService,
+				ResourceID:   service.ID,
+				ResourceName: service.Spec.Name,
+			},
+			tty: &tty,
+		})
+	}
+
+	return sources, nil
+}
+
+// matchesResource returns true when the resource is part of the searched stack,
+// is not hidden by a label filter and can be accessed by the user
+func matchesResource(endpoint *portainer.Endpoint, resourceID string, resourceType portainer.ResourceControlType, labels map[string]string, payload *logSearchPayload, access *logsearch.AccessContext, labelFilters []*labelfilter.Filter) bool {
+	if payload.StackName != "" && logsearch.StackName(labels) != payload.StackName {
+		return false
+	}
+
+	if len(labelFilters) > 0 {
+		labelsObject := make(map[string]interface{}, len(labels))
+		for name, value := range labels {
+			labelsObject[name] = value
+		}
+
+		if labelfilter.MatchesAny(labelFilters, labelsObject) {
+			return false
+		}
+	}
+
+	return access.CanAccess(endpoint.ID, resourceID, resourceType, labels)
+}
+
+// searchLogs reads the logs of the sources and passes the earliest entries matching the query, up to the limit,
+// to emit in timestamp order. The logs are merged while they are read: an entry is passed as soon as every source
+// has read a later entry or is exhausted. At most maxConcurrentStreams log streams are open at a time, so the logs
+// of the sources exceeding this number are read first, keeping only the earliest entries up to the limit in memory,
+// and are then merged with the streams of the other sources.
+func searchLogs(ctx context.Context, sources []*logSource, options types.ContainerLogsOptions, query *logsearch.Query, limit int, emit func(entry logsearch.Entry) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	streamed := sources
+	inputs := make([]<-chan logsearch.Entry, 0, maxConcurrentStreams+1)
+
+	if len(sources) > maxConcurrentStreams {
+		streamed = sources[:maxConcurrentStreams]
+
+		collected := collectLogs(ctx, sources[maxConcurrentStreams:], options, query, limit)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		entries := make(chan logsearch.Entry, len(collected))
+		for _, entry := range collected {
+			entries <- entry
+		}
+		close(entries)
+		inputs = append(inputs, entries)
+	}
+
+	var wg sync.WaitGroup
+	for _, source := range streamed {
+		entries := make(chan logsearch.Entry, 64)
+		inputs = append(inputs, entries)
+
+		wg.Add(1)
+		go func(source *logSource) {
+			defer func() {
+				close(entries)
+				wg.Done()
+			}()
+
+			err := source.read(ctx, options, query, entries)
+			if err != nil && ctx.Err() == nil {
+				source.logReadError(ctx, err)
+			}
+		}(source)
+	}
+
+	err := logsearch.Merge(ctx, inputs, limit, emit)
+
+	// the streams still open once the limit is reached are closed
+	cancel()
+	wg.Wait()
+
+	return err
+}
+
+// collectLogs reads the logs of the sources, a bounded number of sources at a time, and returns
+// the earliest entries matching the query, up to the limit, in timestamp order
+func collectLogs(ctx context.Context, sources []*logSource, options types.ContainerLogsOptions, query *logsearch.Query, limit int) []logsearch.Entry {
+	collector := logsearch.NewCollector(limit)
+
+	queue := make(chan *logSource)
+	var wg sync.WaitGroup
+
+	for i := 0; i < maxConcurrentStreams && i < len(sources); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for source := range queue {
+				source.collect(ctx, options, query, collector)
+			}
+		}()
+	}
+
+queueing:
+	for _, source := range sources {
+		select {
+		case queue <- source:
+		case <-ctx.Done():
+			break queueing
+		}
+	}
+	close(queue)
+
+	wg.Wait()
+
+	return collector.Entries()
+}
+
+// collect adds the entries of the source to the collector. The log stream is closed once the collector
+// drops an entry, as the following entries of the source would be dropped as well.
+func (source *logSource) collect(ctx context.Context, options types.ContainerLogsOptions, query *logsearch.Query, collector *logsearch.Collector) {
+	sourceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	entries := make(chan logsearch.Entry, 64)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(entries)
+		errs <- source.read(sourceCtx, options, query, entries)
+	}()
+
+	full := false
+	for entry := range entries {
+		if !full && !collector.Add(entry) {
+			full = true
+			cancel()
+		}
+	}
+
+	err := <-errs
+	if err != nil && sourceCtx.Err() == nil {
+		source.logReadError(ctx, err)
+	}
+}
+
+func (source *logSource) logReadError(ctx context.Context, err error) {
+	logger.WithContext(ctx).WithField(logging.FieldEndpointID, source.entry.EndpointID).
+		WithField("resource_id", source.entry.ResourceID).
+		WithError(err).Warn("unable to read logs")
+}
+
+func (source *logSource) read(ctx context.Context, options types.ContainerLogsOptions, query *logsearch.Query, entries chan<- logsearch.Entry) error {
+	var reader io.ReadCloser
+	var err error
+
+	if source.entry.ResourceType == logsearch.ResourceTypeService {
+		reader, err = source.client.ServiceLogs(ctx, source.entry.ResourceID, options)
+	} else {
+		reader, err = source.client.ContainerLogs(ctx, source.entry.ResourceID, options)
+	}
+	if client.IsErrNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	logs := bufio.NewReader(reader)
+
+	tty := !logsearch.IsMultiplexed(logs)
+	if source.tty != nil {
+		tty = *source.tty
+	}
+
+	return logsearch.Read(ctx, logs, tty, source.entry, query, entries)
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/logging"
+)
+
+var logger = logging.Component("metrics")
+
+// Handler is the HTTP handler used to expose the Prometheus metrics of the Portainer server.
+type Handler struct {
+	*mux.Router
+	DataStore            portainer.DataStore
+	ReverseTunnelService portainer.ReverseTunnelService
+}
+
+// NewHandler creates a handler to expose the Prometheus metrics.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+	h.Handle("/metrics",
+		bouncer.PublicAccess(httperror.LoggerHandler(h.metricsInspect))).Methods(http.MethodGet)
+	return h
+}
@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	metricsregistry "github.com/portainer/portainer/api/internal/metrics"
+)
+
+// @id MetricsInspect
+// @summary Retrieve the Prometheus metrics of the Portainer server
+// @description Metrics are exposed in the Prometheus text format when a metrics token is set in the settings.
+// @description **Access policy**: bearer metrics token
+// @tags status
+// @produce plain
+// @success 200 "Metrics in the Prometheus text exposition format"
+// @failure 401 "Missing or invalid metrics token"
+// @failure 403 "Metrics are disabled"
+// @failure 500 "Server error"
+// @router /metrics [get]
+func (handler *Handler) metricsInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve settings from the database", err}
+	}
+
+	if settings.MetricsToken == "" {
+		return &httperror.HandlerError{http.StatusForbidden, "Metrics are disabled", errors.New("Metrics are disabled")}
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(settings.MetricsToken)) != 1 {
+		return &httperror.HandlerError{http.StatusUnauthorized, "Invalid metrics token", errors.New("Invalid metrics token")}
+	}
+
+	state, err := handler.collectState()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to collect the state of the Portainer server", err}
+	}
+
+	// the metrics are rendered before being written, so that an error can still be returned as the response
+	var body bytes.Buffer
+	err = metricsregistry.Default.Write(&body)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to render the metrics", err}
+	}
+
+	err = state.Write(&body)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to render the metrics", err}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, err = body.WriteTo(w)
+	if err != nil {
+		logger.WithContext(r.Context()).WithError(err).Debug("unable to write the metrics")
+	}
+
+	return nil
+}
+
+// collectState returns the gauges describing the endpoints, stacks, Edge stacks and tunnels at the time of the scrape
+func (handler *Handler) collectState() (*metricsregistry.Registry, error) {
+	state := metricsregistry.NewRegistry()
+
+	endpoints, err := handler.DataStore.Endpoint().Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	endpointGauge := state.NewGauge("portainer_endpoints", "Number of endpoints by status.", "status")
+	endpointCounts := map[string]int{"up": 0, "down": 0}
+	for _, endpoint := range endpoints {
+		if endpoint.Status == portainer.EndpointStatusDown {
+			endpointCounts["down"]++
+		} else {
+			endpointCounts["up"]++
+		}
+	}
+	setGauge(endpointGauge, endpointCounts)
+
+	stacks, err := handler.DataStore.Stack().Stacks()
+	if err != nil {
+		return nil, err
+	}
+
+	stackGauge := state.NewGauge("portainer_stacks", "Number of stacks by status.", "status")
+	stackCounts := map[string]int{"active": 0, "inactive": 0}
+	for _, stack := range stacks {
+		if stack.Status == portainer.StackStatusInactive {
+			stackCounts["inactive"]++
+		} else {
+			stackCounts["active"]++
+		}
+	}
+	setGauge(stackGauge, stackCounts)
+
+	edgeStacks, err := handler.DataStore.EdgeStack().EdgeStacks()
+	if err != nil {
+		return nil, err
+	}
+
+	state.NewGauge("portainer_edge_stacks", "Number of Edge stacks.").Set(float64(len(edgeStacks)))
+
+	deploymentGauge := state.NewGauge("portainer_edge_stack_deployments", "Number of Edge stack deployments reported by the Edge endpoints by status.", "status")
+	deploymentCounts := map[string]int{"ok": 0, "error": 0, "acknowledged": 0}
+	for _, edgeStack := range edgeStacks {
+		for _, status := range edgeStack.Status {
+			switch status.Type {
+			case portainer.StatusOk:
+				deploymentCounts["ok"]++
+			case portainer.StatusError:
+				deploymentCounts["error"]++
+			case portainer.StatusAcknowledged:
+				deploymentCounts["acknowledged"]++
+			}
+		}
+	}
+	setGauge(deploymentGauge, deploymentCounts)
+
+	tunnelGauge := state.NewGauge("portainer_tunnels", "Number of reverse tunnels of the Edge endpoints by status.", "status")
+	tunnelCounts := map[string]int{
+		portainer.EdgeAgentIdle:               0,
+		portainer.EdgeAgentManagementRequired: 0,
+		portainer.EdgeAgentActive:             0,
+	}
+	for _, tunnel := range handler.ReverseTunnelService.TunnelStatistics() {
+		tunnelCounts[tunnel.Status]++
+	}
+	setGauge(tunnelGauge, tunnelCounts)
+
+	return state, nil
+}
+
+func setGauge(gauge *metricsregistry.Gauge, counts map[string]int) {
+	for label, count := range counts {
+		gauge.Set(float64(count), label)
+	}
+}
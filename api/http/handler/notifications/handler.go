@@ -0,0 +1,48 @@
+package notifications
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+// Handler is the HTTP handler used to handle notification channel operations.
+type Handler struct {
+	*mux.Router
+	DataStore           portainer.DataStore
+	NotificationService portainer.NotificationService
+}
+
+// NewHandler creates a handler to manage notification channel operations.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+	h.Handle("/notifications/channels",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.notificationChannelList))).Methods(http.MethodGet)
+	h.Handle("/notifications/channels",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.notificationChannelCreate))).Methods(http.MethodPost)
+	h.Handle("/notifications/channels/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.notificationChannelInspect))).Methods(http.MethodGet)
+	h.Handle("/notifications/channels/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.notificationChannelUpdate))).Methods(http.MethodPut)
+	h.Handle("/notifications/channels/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.notificationChannelDelete))).Methods(http.MethodDelete)
+	h.Handle("/notifications/channels/{id}/test",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.notificationChannelTestSend))).Methods(http.MethodPost)
+	h.Handle("/notifications/deliveries",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.notificationDeliveryList))).Methods(http.MethodGet)
+	return h
+}
+
+// hidePassword removes the SMTP password from a channel before it is sent to the client
+func hidePassword(channel *portainer.NotificationChannel) {
+	if channel.Email != nil {
+		email := *channel.Email
+		email.Password = ""
+		channel.Email = &email
+	}
+}
@@ -0,0 +1,125 @@
+package notifications
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/notification"
+)
+
+type notificationChannelCreatePayload struct {
+	// Notification channel name
+	Name string `validate:"required" example:"ops-team"`
+	// Notification channel type (1 - HTTP webhook, 2 - email, 3 - Slack/Teams compatible incoming webhook)
+	Type portainer.NotificationChannelType `validate:"required" example:"1" enums:"1,2,3"`
+	// Whether notifications are sent to this channel
+	Enabled bool `example:"true"`
+	// URL of the webhook, required for webhook and Slack/Teams compatible channels
+	URL string `example:"https://hooks.mydomain.tld/portainer"`
+	// Go template used to render the body of the webhook, the event is sent as JSON when empty
+	BodyTemplate string `example:"{\"text\": \"{{ .Message }}\"}"`
+	// SMTP settings, required for email channels
+	Email *portainer.NotificationEmailSettings
+	// Types of the events sent to this channel, all the events are sent when empty
+	EventTypes []portainer.NotificationEventType
+	// Endpoint groups the events are sent for, the events of all the endpoint groups are sent when empty
+	EndpointGroupIDs []portainer.EndpointGroupID
+}
+
+func (payload *notificationChannelCreatePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Name) {
+		return errors.New("Invalid notification channel name")
+	}
+	return validateChannelSettings(payload.Type, payload.URL, payload.BodyTemplate, payload.Email)
+}
+
+// validateChannelSettings ensures that a channel holds the settings required by its type
+func validateChannelSettings(channelType portainer.NotificationChannelType, url, bodyTemplate string, email *portainer.NotificationEmailSettings) error {
+	switch channelType {
+	case portainer.NotificationChannelWebhook, portainer.NotificationChannelSlack:
+		if !govalidator.IsURL(url) {
+			return errors.New("Invalid webhook URL")
+		}
+	case portainer.NotificationChannelEmail:
+		if email == nil || govalidator.IsNull(email.Host) {
+			return errors.New("Invalid SMTP host")
+		}
+		if email.Port <= 0 || email.Port > 65535 {
+			return errors.New("Invalid SMTP port")
+		}
+		if !govalidator.IsEmail(email.From) {
+			return errors.New("Invalid sender address")
+		}
+		if len(email.To) == 0 {
+			return errors.New("Invalid recipient addresses")
+		}
+		for _, to := range email.To {
+			if !govalidator.IsEmail(to) {
+				return errors.New("Invalid recipient address: " + to)
+			}
+		}
+	default:
+		return errors.New("Invalid notification channel type. Valid values are: 1 (webhook), 2 (email) or 3 (Slack/Teams)")
+	}
+
+	if channelType == portainer.NotificationChannelWebhook {
+		err := notification.ValidateBodyTemplate(bodyTemplate)
+		if err != nil {
+			return errors.New("Invalid body template: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// @id NotificationChannelCreate
+// @summary Create a notification channel
+// @description **Access policy**: administrator
+// @tags notifications
+// @security jwt
+// @accept json
+// @produce json
+// @param body body notificationChannelCreatePayload true "Notification channel details"
+// @success 200 {object} portainer.NotificationChannel
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /notifications/channels [post]
+func (handler *Handler) notificationChannelCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload notificationChannelCreatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	channel := &portainer.NotificationChannel{
+		Name:             payload.Name,
+		Type:             payload.Type,
+		Enabled:          payload.Enabled,
+		EventTypes:       payload.EventTypes,
+		EndpointGroupIDs: payload.EndpointGroupIDs,
+		Created:          time.Now().Unix(),
+	}
+
+	if payload.Type == portainer.NotificationChannelEmail {
+		channel.Email = payload.Email
+	} else {
+		channel.URL = payload.URL
+		if payload.Type == portainer.NotificationChannelWebhook {
+			channel.BodyTemplate = payload.BodyTemplate
+		}
+	}
+
+	err = handler.DataStore.NotificationChannel().CreateNotificationChannel(channel)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the notification channel inside the database", err}
+	}
+
+	hidePassword(channel)
+	return response.JSON(w, channel)
+}
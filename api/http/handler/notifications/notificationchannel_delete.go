@@ -0,0 +1,44 @@
+package notifications
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+// @id NotificationChannelDelete
+// @summary Delete a notification channel
+// @description The deliveries of the channel are kept in the delivery log.
+// @description **Access policy**: administrator
+// @tags notifications
+// @security jwt
+// @param id path int true "Notification channel identifier"
+// @success 204
+// @failure 400
+// @failure 404
+// @failure 500
+// @router /notifications/channels/{id} [delete]
+func (handler *Handler) notificationChannelDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	channelID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid notification channel identifier route variable", err}
+	}
+
+	_, err = handler.DataStore.NotificationChannel().NotificationChannel(portainer.NotificationChannelID(channelID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a notification channel with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a notification channel with the specified identifier inside the database", err}
+	}
+
+	err = handler.DataStore.NotificationChannel().DeleteNotificationChannel(portainer.NotificationChannelID(channelID))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the notification channel from the database", err}
+	}
+
+	return response.Empty(w)
+}
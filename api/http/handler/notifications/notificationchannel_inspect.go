@@ -0,0 +1,40 @@
+package notifications
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+// @id NotificationChannelInspect
+// @summary Inspect a notification channel
+// @description **Access policy**: administrator
+// @tags notifications
+// @security jwt
+// @produce json
+// @param id path int true "Notification channel identifier"
+// @success 200 {object} portainer.NotificationChannel
+// @failure 400
+// @failure 404
+// @failure 500
+// @router /notifications/channels/{id} [get]
+func (handler *Handler) notificationChannelInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	channelID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid notification channel identifier route variable", err}
+	}
+
+	channel, err := handler.DataStore.NotificationChannel().NotificationChannel(portainer.NotificationChannelID(channelID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a notification channel with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a notification channel with the specified identifier inside the database", err}
+	}
+
+	hidePassword(channel)
+	return response.JSON(w, channel)
+}
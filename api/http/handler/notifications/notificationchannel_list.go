@@ -0,0 +1,30 @@
+package notifications
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+)
+
+// @id NotificationChannelList
+// @summary List notification channels
+// @description **Access policy**: administrator
+// @tags notifications
+// @security jwt
+// @produce json
+// @success 200 {array} portainer.NotificationChannel
+// @failure 500
+// @router /notifications/channels [get]
+func (handler *Handler) notificationChannelList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	channels, err := handler.DataStore.NotificationChannel().NotificationChannels()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve notification channels from the database", err}
+	}
+
+	for idx := range channels {
+		hidePassword(&channels[idx])
+	}
+
+	return response.JSON(w, channels)
+}
@@ -0,0 +1,46 @@
+package notifications
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+// @id NotificationChannelTest
+// @summary Send a test notification
+// @description Send a test event to a notification channel, whether it is enabled or not.
+// @description The test notification is not retried nor recorded in the delivery log.
+// @description **Access policy**: administrator
+// @tags notifications
+// @security jwt
+// @param id path int true "Notification channel identifier"
+// @success 204
+// @failure 400
+// @failure 404
+// @failure 502 "Unable to send the notification"
+// @failure 500
+// @router /notifications/channels/{id}/test [post]
+func (handler *Handler) notificationChannelTestSend(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	channelID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid notification channel identifier route variable", err}
+	}
+
+	channel, err := handler.DataStore.NotificationChannel().NotificationChannel(portainer.NotificationChannelID(channelID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a notification channel with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a notification channel with the specified identifier inside the database", err}
+	}
+
+	err = handler.NotificationService.Test(channel)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadGateway, "Unable to send the test notification", err}
+	}
+
+	return response.Empty(w)
+}
@@ -0,0 +1,116 @@
+package notifications
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+type notificationChannelUpdatePayload struct {
+	// Notification channel name
+	Name *string `example:"ops-team"`
+	// Whether notifications are sent to this channel
+	Enabled *bool `example:"true"`
+	// URL of the webhook, for webhook and Slack/Teams compatible channels
+	URL *string `example:"https://hooks.mydomain.tld/portainer"`
+	// Go template used to render the body of the webhook, the event is sent as JSON when empty
+	BodyTemplate *string `example:"{\"text\": \"{{ .Message }}\"}"`
+	// SMTP settings, for email channels. The current password is kept when the password is empty
+	Email *portainer.NotificationEmailSettings
+	// Types of the events sent to this channel, all the events are sent when empty
+	EventTypes []portainer.NotificationEventType
+	// Endpoint groups the events are sent for, the events of all the endpoint groups are sent when empty
+	EndpointGroupIDs []portainer.EndpointGroupID
+}
+
+func (payload *notificationChannelUpdatePayload) Validate(r *http.Request) error {
+	return nil
+}
+
+// @id NotificationChannelUpdate
+// @summary Update a notification channel
+// @description The type of a notification channel cannot be changed.
+// @description **Access policy**: administrator
+// @tags notifications
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Notification channel identifier"
+// @param body body notificationChannelUpdatePayload true "Notification channel details"
+// @success 200 {object} portainer.NotificationChannel
+// @failure 400 "Invalid request"
+// @failure 404 "Notification channel not found"
+// @failure 500 "Server error"
+// @router /notifications/channels/{id} [put]
+func (handler *Handler) notificationChannelUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	channelID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid notification channel identifier route variable", err}
+	}
+
+	var payload notificationChannelUpdatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	channel, err := handler.DataStore.NotificationChannel().NotificationChannel(portainer.NotificationChannelID(channelID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a notification channel with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a notification channel with the specified identifier inside the database", err}
+	}
+
+	if payload.Name != nil && *payload.Name != "" {
+		channel.Name = *payload.Name
+	}
+
+	if payload.Enabled != nil {
+		channel.Enabled = *payload.Enabled
+	}
+
+	if payload.EventTypes != nil {
+		channel.EventTypes = payload.EventTypes
+	}
+
+	if payload.EndpointGroupIDs != nil {
+		channel.EndpointGroupIDs = payload.EndpointGroupIDs
+	}
+
+	switch channel.Type {
+	case portainer.NotificationChannelEmail:
+		if payload.Email != nil {
+			email := *payload.Email
+			if email.Password == "" && channel.Email != nil {
+				email.Password = channel.Email.Password
+			}
+			channel.Email = &email
+		}
+	case portainer.NotificationChannelWebhook:
+		if payload.BodyTemplate != nil {
+			channel.BodyTemplate = *payload.BodyTemplate
+		}
+		fallthrough
+	default:
+		if payload.URL != nil {
+			channel.URL = *payload.URL
+		}
+	}
+
+	err = validateChannelSettings(channel.Type, channel.URL, channel.BodyTemplate, channel.Email)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid notification channel settings", err}
+	}
+
+	err = handler.DataStore.NotificationChannel().UpdateNotificationChannel(channel.ID, channel)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the notification channel changes inside the database", err}
+	}
+
+	hidePassword(channel)
+	return response.JSON(w, channel)
+}
@@ -0,0 +1,44 @@
+package notifications
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+)
+
+// @id NotificationDeliveryList
+// @summary List notification deliveries
+// @description List the most recent notification deliveries, newest first.
+// @description **Access policy**: administrator
+// @tags notifications
+// @security jwt
+// @produce json
+// @param channelId query int false "Only return the deliveries of this notification channel"
+// @success 200 {array} portainer.NotificationDelivery
+// @failure 400
+// @failure 500
+// @router /notifications/deliveries [get]
+func (handler *Handler) notificationDeliveryList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	channelID, err := request.RetrieveNumericQueryParameter(r, "channelId", true)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: channelId", err}
+	}
+
+	deliveries, err := handler.DataStore.NotificationDelivery().NotificationDeliveries()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve notification deliveries from the database", err}
+	}
+
+	filteredDeliveries := make([]portainer.NotificationDelivery, 0, len(deliveries))
+	for idx := len(deliveries) - 1; idx >= 0; idx-- {
+		if channelID != 0 && deliveries[idx].ChannelID != portainer.NotificationChannelID(channelID) {
+			continue
+		}
+		filteredDeliveries = append(filteredDeliveries, deliveries[idx])
+	}
+
+	return response.JSON(w, filteredDeliveries)
+}
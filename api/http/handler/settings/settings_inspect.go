@@ -5,11 +5,12 @@ import (
 
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/response"
+	"github.com/portainer/portainer/api/http/security"
 )
 
 // @id SettingsInspect
 // @summary Retrieve Portainer settings
-// @description Retrieve Portainer settings.
+// @description Retrieve Portainer settings. The metrics token is only returned to administrators.
 // @description **Access policy**: administrator
 // @tags settings
 // @security jwt
@@ -18,11 +19,19 @@ import (
 // @failure 500 "Server error"
 // @router /settings [get]
 func (handler *Handler) settingsInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
 	settings, err := handler.DataStore.Settings().Settings()
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the settings from the database", err}
 	}
 
 	hideFields(settings)
+	if !securityContext.IsAdmin {
+		settings.MetricsToken = ""
+	}
 	return response.JSON(w, settings)
 }
@@ -2,7 +2,9 @@ package settings
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/asaskevich/govalidator"
@@ -11,6 +13,10 @@ import (
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/filesystem"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/labelfilter"
+	"github.com/portainer/portainer/api/internal/logging"
+	"github.com/portainer/portainer/api/internal/vulnerability"
 )
 
 type settingsUpdatePayload struct {
@@ -18,16 +24,50 @@ type settingsUpdatePayload struct {
 	LogoURL *string `example:"https://mycompany.mydomain.tld/logo.png"`
 	// A list of label name & value that will be used to hide containers when querying containers
 	BlackListedLabels []portainer.Pair
+	// A list of label rules that will be used to hide containers, services, volumes and networks
+	LabelFilters []portainer.LabelFilter
 	// Active authentication method for the Portainer instance. Valid values are: 1 for internal, 2 for LDAP, or 3 for oauth
 	AuthenticationMethod *int                     `example:"1"`
 	LDAPSettings         *portainer.LDAPSettings  `example:""`
 	OAuthSettings        *portainer.OAuthSettings `example:""`
 	// The interval in which endpoint snapshots are created
 	SnapshotInterval *string `example:"5m"`
+	// The number of consecutive failed snapshots after which an endpoint is marked as down, 0 to use the default value
+	EndpointFailureThreshold *int `example:"3"`
+	// The number of endpoint snapshots created concurrently, 0 to use the default value
+	SnapshotConcurrency *int `example:"10"`
+	// The duration after which the snapshot of an endpoint is abandoned, empty to use the default value
+	SnapshotTimeout *string `example:"30s"`
+	// How long the snapshot history of an endpoint is kept, empty to use the default value
+	SnapshotHistoryRetention *string `example:"720h"`
+	// The age after which the snapshot history is downsampled, empty to use the default value
+	SnapshotHistoryDownsampleAfter *string `example:"24h"`
+	// The interval the snapshot history is averaged over once downsampled, empty to use the default value
+	SnapshotHistoryDownsampleInterval *string `example:"1h"`
 	// URL to the templates that will be displayed in the UI when navigating to App Templates
 	TemplatesURL *string `example:"https://raw.githubusercontent.com/portainer/templates/master/templates.json"`
 	// The default check in interval for edge agent (in seconds)
 	EdgeAgentCheckinInterval *int `example:"5"`
+	// The number of check-ins an edge agent can miss before an alert is raised, 0 to disable the alert
+	EdgeAgentCheckinAlertThreshold *int `example:"3"`
+	// The number of previous versions kept for each edge stack, 0 to use the default value
+	EdgeStackMaxPreviousVersions *int `example:"5"`
+	// Bearer token required to scrape the Prometheus metrics, empty to disable the metrics
+	MetricsToken *string `example:"c5f4d3a8b1e94f2c9a7d6e5b4c3a2f1e"`
+	// Level of the logs, applied immediately. Valid values are: DEBUG, INFO, WARN or ERROR
+	LogLevel *string `example:"INFO" enums:"DEBUG,INFO,WARN,ERROR"`
+	// Scanner used to scan the endpoint images for vulnerabilities, empty to disable the scans
+	VulnerabilityScanner *string `example:"trivy" enums:"trivy,grype"`
+	// URL of the scanner server, empty to scan the images with the local vulnerability database
+	VulnerabilityScannerServerURL *string `example:"http://trivy:4954"`
+	// Interval between two scans of the same image, empty to use the default value
+	VulnerabilityScanInterval *string `example:"24h"`
+	// Maximum duration of the scan of an image, empty to use the default value
+	VulnerabilityScanTimeout *string `example:"10m"`
+	// Minimum severity of the image vulnerabilities preventing stack deployments, empty to disable the gate
+	VulnerabilityGateSeverity *string `example:"CRITICAL" enums:"UNKNOWN,LOW,MEDIUM,HIGH,CRITICAL"`
+	// Whether the vulnerability gate rejects the stacks using an image that could not be scanned
+	VulnerabilityGateRejectUnscanned *bool `example:"true"`
 	// Whether edge compute features are enabled
 	EnableEdgeComputeFeatures *bool `example:"true"`
 	// The duration of a user session
@@ -46,16 +86,82 @@ func (payload *settingsUpdatePayload) Validate(r *http.Request) error {
 	if payload.TemplatesURL != nil && *payload.TemplatesURL != "" && !govalidator.IsURL(*payload.TemplatesURL) {
 		return errors.New("Invalid external templates URL. Must correspond to a valid URL format")
 	}
+	if payload.EdgeAgentCheckinAlertThreshold != nil && *payload.EdgeAgentCheckinAlertThreshold < 0 {
+		return errors.New("Invalid edge agent check-in alert threshold. Must be a positive number or 0 to disable the alert")
+	}
+	if payload.EdgeStackMaxPreviousVersions != nil && *payload.EdgeStackMaxPreviousVersions < 0 {
+		return errors.New("Invalid edge stack max previous versions. Must be a positive number or 0 to use the default value")
+	}
+	if payload.MetricsToken != nil && *payload.MetricsToken != "" && len(*payload.MetricsToken) < 16 {
+		return errors.New("Invalid metrics token. Must be at least 16 characters long or empty to disable the metrics")
+	}
+	if payload.LogLevel != nil {
+		_, err := logging.ParseLevel(*payload.LogLevel)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.VulnerabilityScanner != nil && *payload.VulnerabilityScanner != "" && *payload.VulnerabilityScanner != vulnerability.ScannerTrivy && *payload.VulnerabilityScanner != vulnerability.ScannerGrype {
+		return errors.New("Invalid vulnerability scanner. Valid values are: trivy, grype or empty to disable the scans")
+	}
+	if payload.VulnerabilityScannerServerURL != nil && *payload.VulnerabilityScannerServerURL != "" && !govalidator.IsURL(*payload.VulnerabilityScannerServerURL) {
+		return errors.New("Invalid vulnerability scanner server URL. Must correspond to a valid URL format")
+	}
+	if !isValidPositiveDuration(payload.VulnerabilityScanInterval) {
+		return errors.New("Invalid vulnerability scan interval. Must be a positive duration or empty to use the default value")
+	}
+	if !isValidPositiveDuration(payload.VulnerabilityScanTimeout) {
+		return errors.New("Invalid vulnerability scan timeout. Must be a positive duration or empty to use the default value")
+	}
+	if payload.VulnerabilityGateSeverity != nil && *payload.VulnerabilityGateSeverity != "" {
+		_, err := vulnerability.ParseSeverity(*payload.VulnerabilityGateSeverity)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.EndpointFailureThreshold != nil && *payload.EndpointFailureThreshold < 0 {
+		return errors.New("Invalid endpoint failure threshold. Must be a positive number or 0 to use the default value")
+	}
+	if payload.SnapshotConcurrency != nil && *payload.SnapshotConcurrency < 0 {
+		return errors.New("Invalid snapshot concurrency. Must be a positive number or 0 to use the default value")
+	}
+	if !isValidPositiveDuration(payload.SnapshotTimeout) {
+		return errors.New("Invalid snapshot timeout. Must be a positive duration or empty to use the default value")
+	}
+	if !isValidPositiveDuration(payload.SnapshotHistoryRetention) {
+		return errors.New("Invalid snapshot history retention. Must be a positive duration or empty to use the default value")
+	}
+	if !isValidPositiveDuration(payload.SnapshotHistoryDownsampleAfter) {
+		return errors.New("Invalid snapshot history downsampling age. Must be a positive duration or empty to use the default value")
+	}
+	if !isValidPositiveDuration(payload.SnapshotHistoryDownsampleInterval) {
+		return errors.New("Invalid snapshot history downsampling interval. Must be a positive duration or empty to use the default value")
+	}
 	if payload.UserSessionTimeout != nil {
 		_, err := time.ParseDuration(*payload.UserSessionTimeout)
 		if err != nil {
 			return errors.New("Invalid user session timeout")
 		}
 	}
+	for _, rule := range payload.LabelFilters {
+		_, err := labelfilter.Compile(rule)
+		if err != nil {
+			return fmt.Errorf("Invalid label filter: %w", err)
+		}
+	}
 
 	return nil
 }
 
+func isValidPositiveDuration(value *string) bool {
+	if value == nil || *value == "" {
+		return true
+	}
+
+	duration, err := time.ParseDuration(*value)
+	return err == nil && duration > 0
+}
+
 // @id SettingsUpdate
 // @summary Update Portainer settings
 // @description Update Portainer settings.
@@ -97,6 +203,10 @@ func (handler *Handler) settingsUpdate(w http.ResponseWriter, r *http.Request) *
 		settings.BlackListedLabels = payload.BlackListedLabels
 	}
 
+	if payload.LabelFilters != nil {
+		settings.LabelFilters = payload.LabelFilters
+	}
+
 	if payload.LDAPSettings != nil {
 		ldapReaderDN := settings.LDAPSettings.ReaderDN
 		ldapPassword := settings.LDAPSettings.Password
@@ -131,10 +241,74 @@ func (handler *Handler) settingsUpdate(w http.ResponseWriter, r *http.Request) *
 		}
 	}
 
+	if payload.EndpointFailureThreshold != nil {
+		settings.EndpointFailureThreshold = *payload.EndpointFailureThreshold
+	}
+
+	if payload.SnapshotConcurrency != nil {
+		settings.SnapshotConcurrency = *payload.SnapshotConcurrency
+	}
+
+	if payload.SnapshotTimeout != nil {
+		settings.SnapshotTimeout = *payload.SnapshotTimeout
+	}
+
+	if payload.SnapshotHistoryRetention != nil {
+		settings.SnapshotHistoryRetention = *payload.SnapshotHistoryRetention
+	}
+
+	if payload.SnapshotHistoryDownsampleAfter != nil {
+		settings.SnapshotHistoryDownsampleAfter = *payload.SnapshotHistoryDownsampleAfter
+	}
+
+	if payload.SnapshotHistoryDownsampleInterval != nil {
+		settings.SnapshotHistoryDownsampleInterval = *payload.SnapshotHistoryDownsampleInterval
+	}
+
 	if payload.EdgeAgentCheckinInterval != nil {
 		settings.EdgeAgentCheckinInterval = *payload.EdgeAgentCheckinInterval
 	}
 
+	if payload.EdgeAgentCheckinAlertThreshold != nil {
+		settings.EdgeAgentCheckinAlertThreshold = *payload.EdgeAgentCheckinAlertThreshold
+	}
+
+	if payload.EdgeStackMaxPreviousVersions != nil {
+		settings.EdgeStackMaxPreviousVersions = *payload.EdgeStackMaxPreviousVersions
+	}
+
+	if payload.MetricsToken != nil {
+		settings.MetricsToken = *payload.MetricsToken
+	}
+
+	if payload.LogLevel != nil {
+		settings.LogLevel = strings.ToUpper(*payload.LogLevel)
+	}
+
+	if payload.VulnerabilityScanner != nil {
+		settings.VulnerabilityScanner = *payload.VulnerabilityScanner
+	}
+
+	if payload.VulnerabilityScannerServerURL != nil {
+		settings.VulnerabilityScannerServerURL = *payload.VulnerabilityScannerServerURL
+	}
+
+	if payload.VulnerabilityScanInterval != nil {
+		settings.VulnerabilityScanInterval = *payload.VulnerabilityScanInterval
+	}
+
+	if payload.VulnerabilityScanTimeout != nil {
+		settings.VulnerabilityScanTimeout = *payload.VulnerabilityScanTimeout
+	}
+
+	if payload.VulnerabilityGateSeverity != nil {
+		settings.VulnerabilityGateSeverity = portainer.VulnerabilitySeverity(strings.ToUpper(*payload.VulnerabilityGateSeverity))
+	}
+
+	if payload.VulnerabilityGateRejectUnscanned != nil {
+		settings.VulnerabilityGateRejectUnscanned = *payload.VulnerabilityGateRejectUnscanned
+	}
+
 	if payload.UserSessionTimeout != nil {
 		settings.UserSessionTimeout = *payload.UserSessionTimeout
 
@@ -157,6 +331,25 @@ func (handler *Handler) settingsUpdate(w http.ResponseWriter, r *http.Request) *
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist settings changes inside the database", err}
 	}
 
+	// the log level is only applied once saved, so that it matches the level restored on restart
+	if payload.LogLevel != nil {
+		err = logging.SetLevel(settings.LogLevel)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to apply the log level", err}
+		}
+	}
+
+	event := portainer.NotificationEvent{
+		Type:    portainer.NotificationEventSettingsUpdated,
+		Time:    time.Now().Unix(),
+		Message: "Portainer settings were updated",
+	}
+	if tokenData, err := security.RetrieveTokenData(r); err == nil {
+		event.Message = "Portainer settings were updated by " + tokenData.Username
+		event.Details = map[string]string{"user": tokenData.Username}
+	}
+	handler.NotificationService.Notify(event)
+
 	return response.JSON(w, settings)
 }
 
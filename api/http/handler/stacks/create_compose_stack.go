@@ -3,6 +3,7 @@ package stacks
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"path"
 	"strconv"
@@ -54,47 +55,45 @@ func (handler *Handler) createComposeStackFromFileContent(w http.ResponseWriter,
 		return &httperror.HandlerError{http.StatusConflict, errorMessage, errors.New(errorMessage)}
 	}
 
-	stackID := handler.DataStore.Stack().GetNextIdentifier()
+	if isDryRun(r) {
+		return handler.stackDryRun(w, r, endpoint, portainer.DockerComposeStack, payload.Name, []byte(payload.StackFileContent), payload.Env)
+	}
+
 	stack := &portainer.Stack{
-		ID:           portainer.StackID(stackID),
 		Name:         payload.Name,
 		Type:         portainer.DockerComposeStack,
 		EndpointID:   endpoint.ID,
 		EntryPoint:   filesystem.ComposeFileDefaultName,
 		Env:          payload.Env,
-		Status:       portainer.StackStatusActive,
 		CreationDate: time.Now().Unix(),
 	}
 
-	stackFolder := strconv.Itoa(int(stack.ID))
-	projectPath, err := handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, []byte(payload.StackFileContent))
-	if err != nil {
-		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist Compose file on disk", Err: err}
-	}
-	stack.ProjectPath = projectPath
-
-	doCleanUp := true
-	defer handler.cleanUp(stack, &doCleanUp)
-
 	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
 	if configErr != nil {
 		return configErr
 	}
 
-	err = handler.deployComposeStack(config)
-	if err != nil {
-		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: err.Error(), Err: err}
+	stack.CreatedBy = config.user.Username
+
+	httpErr := handler.reserveStack(stack, userID)
+	if httpErr != nil {
+		return httpErr
 	}
 
-	stack.CreatedBy = config.user.Username
+	doCleanUp := true
+	defer handler.cleanUp(stack, &doCleanUp)
 
-	err = handler.DataStore.Stack().CreateStack(stack)
+	stackFolder := strconv.Itoa(int(stack.ID))
+	projectPath, err := handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, []byte(payload.StackFileContent))
 	if err != nil {
-		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist the stack inside the database", Err: err}
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist Compose file on disk", Err: err}
 	}
+	stack.ProjectPath = projectPath
 
 	doCleanUp = false
-	return handler.decorateStackResponse(w, stack, userID)
+	return handler.createStackJob(w, r, stack, func(output io.Writer) error {
+		return handler.deployComposeStack(config, output)
+	})
 }
 
 type composeStackFromGitRepositoryPayload struct {
@@ -154,48 +153,44 @@ func (handler *Handler) createComposeStackFromGitRepository(w http.ResponseWrite
 		return &httperror.HandlerError{http.StatusConflict, errorMessage, errors.New(errorMessage)}
 	}
 
-	stackID := handler.DataStore.Stack().GetNextIdentifier()
+	if isDryRun(r) {
+		stackFileContent, err := handler.retrieveGitStackFile(payload.RepositoryURL, payload.RepositoryReferenceName, payload.ComposeFilePathInRepository, payload.RepositoryAuthentication, payload.RepositoryUsername, payload.RepositoryPassword)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the stack file from the git repository", err}
+		}
+
+		return handler.stackDryRun(w, r, endpoint, portainer.DockerComposeStack, payload.Name, stackFileContent, payload.Env)
+	}
+
 	stack := &portainer.Stack{
-		ID:           portainer.StackID(stackID),
 		Name:         payload.Name,
 		Type:         portainer.DockerComposeStack,
 		EndpointID:   endpoint.ID,
 		EntryPoint:   payload.ComposeFilePathInRepository,
 		Env:          payload.Env,
-		Status:       portainer.StackStatusActive,
 		CreationDate: time.Now().Unix(),
 	}
 
-	projectPath := handler.FileService.GetStackProjectPath(strconv.Itoa(int(stack.ID)))
-	stack.ProjectPath = projectPath
-
-	doCleanUp := true
-	defer handler.cleanUp(stack, &doCleanUp)
-
-	err = handler.cloneAndSaveConfig(stack, projectPath, payload.RepositoryURL, payload.RepositoryReferenceName, payload.ComposeFilePathInRepository, payload.RepositoryAuthentication, payload.RepositoryUsername, payload.RepositoryPassword)
-	if err != nil {
-		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to clone git repository", Err: err}
-	}
-
 	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
 	if configErr != nil {
 		return configErr
 	}
 
-	err = handler.deployComposeStack(config)
-	if err != nil {
-		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: err.Error(), Err: err}
-	}
-
 	stack.CreatedBy = config.user.Username
 
-	err = handler.DataStore.Stack().CreateStack(stack)
-	if err != nil {
-		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist the stack inside the database", Err: err}
+	httpErr := handler.reserveStack(stack, userID)
+	if httpErr != nil {
+		return httpErr
 	}
 
-	doCleanUp = false
-	return handler.decorateStackResponse(w, stack, userID)
+	return handler.createStackJob(w, r, stack, func(output io.Writer) error {
+		err := handler.cloneAndSaveConfig(stack, stack.ProjectPath, payload.RepositoryURL, payload.RepositoryReferenceName, payload.ComposeFilePathInRepository, payload.RepositoryAuthentication, payload.RepositoryUsername, payload.RepositoryPassword)
+		if err != nil {
+			return err
+		}
+
+		return handler.deployComposeStack(config, output)
+	})
 }
 
 type composeStackFromFileUploadPayload struct {
@@ -244,47 +239,45 @@ func (handler *Handler) createComposeStackFromFileUpload(w http.ResponseWriter,
 		return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: errorMessage, Err: errors.New(errorMessage)}
 	}
 
-	stackID := handler.DataStore.Stack().GetNextIdentifier()
+	if isDryRun(r) {
+		return handler.stackDryRun(w, r, endpoint, portainer.DockerComposeStack, payload.Name, payload.StackFileContent, payload.Env)
+	}
+
 	stack := &portainer.Stack{
-		ID:           portainer.StackID(stackID),
 		Name:         payload.Name,
 		Type:         portainer.DockerComposeStack,
 		EndpointID:   endpoint.ID,
 		EntryPoint:   filesystem.ComposeFileDefaultName,
 		Env:          payload.Env,
-		Status:       portainer.StackStatusActive,
 		CreationDate: time.Now().Unix(),
 	}
 
-	stackFolder := strconv.Itoa(int(stack.ID))
-	projectPath, err := handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, payload.StackFileContent)
-	if err != nil {
-		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist Compose file on disk", Err: err}
-	}
-	stack.ProjectPath = projectPath
-
-	doCleanUp := true
-	defer handler.cleanUp(stack, &doCleanUp)
-
 	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
 	if configErr != nil {
 		return configErr
 	}
 
-	err = handler.deployComposeStack(config)
-	if err != nil {
-		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: err.Error(), Err: err}
+	stack.CreatedBy = config.user.Username
+
+	httpErr := handler.reserveStack(stack, userID)
+	if httpErr != nil {
+		return httpErr
 	}
 
-	stack.CreatedBy = config.user.Username
+	doCleanUp := true
+	defer handler.cleanUp(stack, &doCleanUp)
 
-	err = handler.DataStore.Stack().CreateStack(stack)
+	stackFolder := strconv.Itoa(int(stack.ID))
+	projectPath, err := handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, payload.StackFileContent)
 	if err != nil {
-		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist the stack inside the database", Err: err}
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist Compose file on disk", Err: err}
 	}
+	stack.ProjectPath = projectPath
 
 	doCleanUp = false
-	return handler.decorateStackResponse(w, stack, userID)
+	return handler.createStackJob(w, r, stack, func(output io.Writer) error {
+		return handler.deployComposeStack(config, output)
+	})
 }
 
 type composeStackDeploymentConfig struct {
@@ -335,7 +328,7 @@ func (handler *Handler) createComposeDeployConfig(r *http.Request, stack *portai
 // to login/logout, which will generate the required data in the config.json file and then
 // clean it. Hence the use of the mutex.
 // We should contribute to libcompose to support authentication without using the config.json file.
-func (handler *Handler) deployComposeStack(config *composeStackDeploymentConfig) error {
+func (handler *Handler) deployComposeStack(config *composeStackDeploymentConfig, output io.Writer) error {
 	isAdminOrEndpointAdmin, err := handler.userIsAdminOrEndpointAdmin(config.user, config.endpoint.ID)
 	if err != nil {
 		return err
@@ -357,18 +350,28 @@ func (handler *Handler) deployComposeStack(config *composeStackDeploymentConfig)
 			return err
 		}
 
-		err = handler.isValidStackFile(stackContent, securitySettings)
+		err = handler.isValidStackFile(stackContent, config.stack.Env, securitySettings)
 		if err != nil {
 			return err
 		}
 	}
 
+	// the deployment job outlives the request, the scans are only cancelled when Portainer shuts down
+	err = handler.checkVulnerabilityGate(handler.ShutdownCtx, config.stack)
+	if err != nil {
+		return err
+	}
+
 	handler.stackCreationMutex.Lock()
 	defer handler.stackCreationMutex.Unlock()
 
 	handler.SwarmStackManager.Login(config.dockerhub, config.registries, config.endpoint)
 
-	err = handler.ComposeStackManager.Up(config.stack, config.endpoint)
+	if manager, ok := handler.ComposeStackManager.(composeOutputStackManager); ok && output != nil {
+		err = manager.UpWithOutput(config.stack, config.endpoint, output)
+	} else {
+		err = handler.ComposeStackManager.Up(config.stack, config.endpoint)
+	}
 	if err != nil {
 		return err
 	}
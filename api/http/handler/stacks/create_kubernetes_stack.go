@@ -2,6 +2,8 @@ package stacks
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"path/filepath"
@@ -12,9 +14,9 @@ import (
 
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/request"
-	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/filesystem"
+	"github.com/portainer/portainer/api/internal/requestid"
 )
 
 const defaultReferenceName = "refs/heads/master"
@@ -69,67 +71,59 @@ type createKubernetesStackResponse struct {
 	Output string `json:"Output"`
 }
 
-func (handler *Handler) createKubernetesStackFromFileContent(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint) *httperror.HandlerError {
+func (handler *Handler) createKubernetesStackFromFileContent(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint, userID portainer.UserID) *httperror.HandlerError {
 	var payload kubernetesStringDeploymentPayload
 	if err := request.DecodeAndValidateJSONPayload(r, &payload); err != nil {
 		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid request payload", Err: err}
 	}
 
-	stackID := handler.DataStore.Stack().GetNextIdentifier()
 	stack := &portainer.Stack{
-		ID:           portainer.StackID(stackID),
 		Type:         portainer.KubernetesStack,
 		EndpointID:   endpoint.ID,
 		EntryPoint:   filesystem.ManifestFileDefaultName,
-		Status:       portainer.StackStatusActive,
 		CreationDate: time.Now().Unix(),
 	}
 
-	stackFolder := strconv.Itoa(int(stack.ID))
-	projectPath, err := handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, []byte(payload.StackFileContent))
-	if err != nil {
-		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist Kubernetes manifest file on disk", Err: err}
+	httpErr := handler.reserveStack(stack, userID)
+	if httpErr != nil {
+		return httpErr
 	}
-	stack.ProjectPath = projectPath
 
 	doCleanUp := true
 	defer handler.cleanUp(stack, &doCleanUp)
 
-	output, err := handler.deployKubernetesStack(endpoint, payload.StackFileContent, payload.ComposeFormat, payload.Namespace)
-	if err != nil {
-		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to deploy Kubernetes stack", Err: err}
-	}
-
-	err = handler.DataStore.Stack().CreateStack(stack)
+	stackFolder := strconv.Itoa(int(stack.ID))
+	projectPath, err := handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, []byte(payload.StackFileContent))
 	if err != nil {
-		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist the Kubernetes stack inside the database", Err: err}
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist Kubernetes manifest file on disk", Err: err}
 	}
+	stack.ProjectPath = projectPath
 
-	resp := &createKubernetesStackResponse{
-		Output: output,
-	}
+	resp := &createKubernetesStackResponse{}
 
-	return response.JSON(w, resp)
+	doCleanUp = false
+	return handler.runDeploymentJob(w, r, portainer.DeploymentJobCreate, stack, resp, func(output io.Writer) error {
+		return handler.createKubernetesStackJob(stack, endpoint, payload.StackFileContent, payload.ComposeFormat, payload.Namespace, requestid.FromRequest(r), resp, output)
+	})
 }
 
-func (handler *Handler) createKubernetesStackFromGitRepository(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint) *httperror.HandlerError {
+func (handler *Handler) createKubernetesStackFromGitRepository(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint, userID portainer.UserID) *httperror.HandlerError {
 	var payload kubernetesGitDeploymentPayload
 	if err := request.DecodeAndValidateJSONPayload(r, &payload); err != nil {
 		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid request payload", Err: err}
 	}
 
-	stackID := handler.DataStore.Stack().GetNextIdentifier()
 	stack := &portainer.Stack{
-		ID:           portainer.StackID(stackID),
 		Type:         portainer.KubernetesStack,
 		EndpointID:   endpoint.ID,
 		EntryPoint:   payload.FilePathInRepository,
-		Status:       portainer.StackStatusActive,
 		CreationDate: time.Now().Unix(),
 	}
 
-	projectPath := handler.FileService.GetStackProjectPath(strconv.Itoa(int(stack.ID)))
-	stack.ProjectPath = projectPath
+	httpErr := handler.reserveStack(stack, userID)
+	if httpErr != nil {
+		return httpErr
+	}
 
 	doCleanUp := true
 	defer handler.cleanUp(stack, &doCleanUp)
@@ -139,23 +133,42 @@ func (handler *Handler) createKubernetesStackFromGitRepository(w http.ResponseWr
 		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Failed to process manifest from Git repository", Err: err}
 	}
 
-	output, err := handler.deployKubernetesStack(endpoint, stackFileContent, payload.ComposeFormat, payload.Namespace)
+	resp := &createKubernetesStackResponse{}
+
+	doCleanUp = false
+	return handler.runDeploymentJob(w, r, portainer.DeploymentJobCreate, stack, resp, func(output io.Writer) error {
+		return handler.createKubernetesStackJob(stack, endpoint, stackFileContent, payload.ComposeFormat, payload.Namespace, requestid.FromRequest(r), resp, output)
+	})
+}
+
+// createKubernetesStackJob deploys the manifest, records the kubectl output and activates the stack once deployed.
+// The stack and its files are removed when the deployment fails.
+func (handler *Handler) createKubernetesStackJob(stack *portainer.Stack, endpoint *portainer.Endpoint, stackConfig string, composeFormat bool, namespace, requestID string, resp *createKubernetesStackResponse, output io.Writer) error {
+	doCleanUp := true
+	defer handler.cleanUp(stack, &doCleanUp)
+
+	deployOutput, err := handler.deployKubernetesStack(endpoint, stackConfig, composeFormat, namespace, requestID)
 	if err != nil {
-		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to deploy Kubernetes stack", Err: err}
+		return fmt.Errorf("Unable to deploy Kubernetes stack: %w", err)
 	}
+	resp.Output = deployOutput
 
-	err = handler.DataStore.Stack().CreateStack(stack)
+	_, err = io.WriteString(output, deployOutput)
 	if err != nil {
-		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist the stack inside the database", Err: err}
+		return err
 	}
 
-	resp := &createKubernetesStackResponse{
-		Output: output,
+	stack.Status = portainer.StackStatusActive
+	err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
+	if err != nil {
+		return fmt.Errorf("Unable to persist the Kubernetes stack inside the database: %w", err)
 	}
-	return response.JSON(w, resp)
+
+	doCleanUp = false
+	return nil
 }
 
-func (handler *Handler) deployKubernetesStack(endpoint *portainer.Endpoint, stackConfig string, composeFormat bool, namespace string) (string, error) {
+func (handler *Handler) deployKubernetesStack(endpoint *portainer.Endpoint, stackConfig string, composeFormat bool, namespace, requestID string) (string, error) {
 	handler.stackCreationMutex.Lock()
 	defer handler.stackCreationMutex.Unlock()
 
@@ -167,7 +180,7 @@ func (handler *Handler) deployKubernetesStack(endpoint *portainer.Endpoint, stac
 		stackConfig = string(convertedConfig)
 	}
 
-	return handler.KubernetesDeployer.Deploy(endpoint, stackConfig, namespace)
+	return handler.KubernetesDeployer.Deploy(endpoint, stackConfig, namespace, requestID)
 
 }
 
@@ -3,6 +3,7 @@ package stacks
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"path"
 	"strconv"
@@ -56,48 +57,46 @@ func (handler *Handler) createSwarmStackFromFileContent(w http.ResponseWriter, r
 		return &httperror.HandlerError{http.StatusConflict, errorMessage, errors.New(errorMessage)}
 	}
 
-	stackID := handler.DataStore.Stack().GetNextIdentifier()
+	if isDryRun(r) {
+		return handler.stackDryRun(w, r, endpoint, portainer.DockerSwarmStack, payload.Name, []byte(payload.StackFileContent), payload.Env)
+	}
+
 	stack := &portainer.Stack{
-		ID:           portainer.StackID(stackID),
 		Name:         payload.Name,
 		Type:         portainer.DockerSwarmStack,
 		SwarmID:      payload.SwarmID,
 		EndpointID:   endpoint.ID,
 		EntryPoint:   filesystem.ComposeFileDefaultName,
 		Env:          payload.Env,
-		Status:       portainer.StackStatusActive,
 		CreationDate: time.Now().Unix(),
 	}
 
-	stackFolder := strconv.Itoa(int(stack.ID))
-	projectPath, err := handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, []byte(payload.StackFileContent))
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist Compose file on disk", err}
-	}
-	stack.ProjectPath = projectPath
-
-	doCleanUp := true
-	defer handler.cleanUp(stack, &doCleanUp)
-
 	config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, false)
 	if configErr != nil {
 		return configErr
 	}
 
-	err = handler.deploySwarmStack(config)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+	stack.CreatedBy = config.user.Username
+
+	httpErr := handler.reserveStack(stack, userID)
+	if httpErr != nil {
+		return httpErr
 	}
 
-	stack.CreatedBy = config.user.Username
+	doCleanUp := true
+	defer handler.cleanUp(stack, &doCleanUp)
 
-	err = handler.DataStore.Stack().CreateStack(stack)
+	stackFolder := strconv.Itoa(int(stack.ID))
+	projectPath, err := handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, []byte(payload.StackFileContent))
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist Compose file on disk", err}
 	}
+	stack.ProjectPath = projectPath
 
 	doCleanUp = false
-	return handler.decorateStackResponse(w, stack, userID)
+	return handler.createStackJob(w, r, stack, func(output io.Writer) error {
+		return handler.deploySwarmStack(config, output)
+	})
 }
 
 type swarmStackFromGitRepositoryPayload struct {
@@ -157,49 +156,45 @@ func (handler *Handler) createSwarmStackFromGitRepository(w http.ResponseWriter,
 		return &httperror.HandlerError{http.StatusConflict, errorMessage, errors.New(errorMessage)}
 	}
 
-	stackID := handler.DataStore.Stack().GetNextIdentifier()
+	if isDryRun(r) {
+		stackFileContent, err := handler.retrieveGitStackFile(payload.RepositoryURL, payload.RepositoryReferenceName, payload.ComposeFilePathInRepository, payload.RepositoryAuthentication, payload.RepositoryUsername, payload.RepositoryPassword)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the stack file from the git repository", err}
+		}
+
+		return handler.stackDryRun(w, r, endpoint, portainer.DockerSwarmStack, payload.Name, stackFileContent, payload.Env)
+	}
+
 	stack := &portainer.Stack{
-		ID:           portainer.StackID(stackID),
 		Name:         payload.Name,
 		Type:         portainer.DockerSwarmStack,
 		SwarmID:      payload.SwarmID,
 		EndpointID:   endpoint.ID,
 		EntryPoint:   payload.ComposeFilePathInRepository,
 		Env:          payload.Env,
-		Status:       portainer.StackStatusActive,
 		CreationDate: time.Now().Unix(),
 	}
 
-	projectPath := handler.FileService.GetStackProjectPath(strconv.Itoa(int(stack.ID)))
-	stack.ProjectPath = projectPath
-
-	doCleanUp := true
-	defer handler.cleanUp(stack, &doCleanUp)
-
-	err = handler.cloneAndSaveConfig(stack, projectPath, payload.RepositoryURL, payload.RepositoryReferenceName, payload.ComposeFilePathInRepository, payload.RepositoryAuthentication, payload.RepositoryUsername, payload.RepositoryPassword)
-	if err != nil {
-		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to clone git repository", Err: err}
-	}
-
 	config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, false)
 	if configErr != nil {
 		return configErr
 	}
 
-	err = handler.deploySwarmStack(config)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
-	}
-
 	stack.CreatedBy = config.user.Username
 
-	err = handler.DataStore.Stack().CreateStack(stack)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
+	httpErr := handler.reserveStack(stack, userID)
+	if httpErr != nil {
+		return httpErr
 	}
 
-	doCleanUp = false
-	return handler.decorateStackResponse(w, stack, userID)
+	return handler.createStackJob(w, r, stack, func(output io.Writer) error {
+		err := handler.cloneAndSaveConfig(stack, stack.ProjectPath, payload.RepositoryURL, payload.RepositoryReferenceName, payload.ComposeFilePathInRepository, payload.RepositoryAuthentication, payload.RepositoryUsername, payload.RepositoryPassword)
+		if err != nil {
+			return err
+		}
+
+		return handler.deploySwarmStack(config, output)
+	})
 }
 
 type swarmStackFromFileUploadPayload struct {
@@ -253,48 +248,46 @@ func (handler *Handler) createSwarmStackFromFileUpload(w http.ResponseWriter, r
 		return &httperror.HandlerError{http.StatusConflict, errorMessage, errors.New(errorMessage)}
 	}
 
-	stackID := handler.DataStore.Stack().GetNextIdentifier()
+	if isDryRun(r) {
+		return handler.stackDryRun(w, r, endpoint, portainer.DockerSwarmStack, payload.Name, payload.StackFileContent, payload.Env)
+	}
+
 	stack := &portainer.Stack{
-		ID:           portainer.StackID(stackID),
 		Name:         payload.Name,
 		Type:         portainer.DockerSwarmStack,
 		SwarmID:      payload.SwarmID,
 		EndpointID:   endpoint.ID,
 		EntryPoint:   filesystem.ComposeFileDefaultName,
 		Env:          payload.Env,
-		Status:       portainer.StackStatusActive,
 		CreationDate: time.Now().Unix(),
 	}
 
-	stackFolder := strconv.Itoa(int(stack.ID))
-	projectPath, err := handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, []byte(payload.StackFileContent))
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist Compose file on disk", err}
-	}
-	stack.ProjectPath = projectPath
-
-	doCleanUp := true
-	defer handler.cleanUp(stack, &doCleanUp)
-
 	config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, false)
 	if configErr != nil {
 		return configErr
 	}
 
-	err = handler.deploySwarmStack(config)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+	stack.CreatedBy = config.user.Username
+
+	httpErr := handler.reserveStack(stack, userID)
+	if httpErr != nil {
+		return httpErr
 	}
 
-	stack.CreatedBy = config.user.Username
+	doCleanUp := true
+	defer handler.cleanUp(stack, &doCleanUp)
 
-	err = handler.DataStore.Stack().CreateStack(stack)
+	stackFolder := strconv.Itoa(int(stack.ID))
+	projectPath, err := handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, []byte(payload.StackFileContent))
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist Compose file on disk", err}
 	}
+	stack.ProjectPath = projectPath
 
 	doCleanUp = false
-	return handler.decorateStackResponse(w, stack, userID)
+	return handler.createStackJob(w, r, stack, func(output io.Writer) error {
+		return handler.deploySwarmStack(config, output)
+	})
 }
 
 type swarmStackDeploymentConfig struct {
@@ -342,7 +335,7 @@ func (handler *Handler) createSwarmDeployConfig(r *http.Request, stack *portaine
 	return config, nil
 }
 
-func (handler *Handler) deploySwarmStack(config *swarmStackDeploymentConfig) error {
+func (handler *Handler) deploySwarmStack(config *swarmStackDeploymentConfig, output io.Writer) error {
 	isAdminOrEndpointAdmin, err := handler.userIsAdminOrEndpointAdmin(config.user, config.endpoint.ID)
 	if err != nil {
 		return err
@@ -358,18 +351,28 @@ func (handler *Handler) deploySwarmStack(config *swarmStackDeploymentConfig) err
 			return err
 		}
 
-		err = handler.isValidStackFile(stackContent, settings)
+		err = handler.isValidStackFile(stackContent, config.stack.Env, settings)
 		if err != nil {
 			return err
 		}
 	}
 
+	// the deployment job outlives the request, the scans are only cancelled when Portainer shuts down
+	err = handler.checkVulnerabilityGate(handler.ShutdownCtx, config.stack)
+	if err != nil {
+		return err
+	}
+
 	handler.stackCreationMutex.Lock()
 	defer handler.stackCreationMutex.Unlock()
 
 	handler.SwarmStackManager.Login(config.dockerhub, config.registries, config.endpoint)
 
-	err = handler.SwarmStackManager.Deploy(config.stack, config.prune, config.endpoint)
+	if manager, ok := handler.SwarmStackManager.(swarmOutputStackManager); ok && output != nil {
+		err = manager.DeployWithOutput(config.stack, config.prune, config.endpoint, output)
+	} else {
+		err = handler.SwarmStackManager.Deploy(config.stack, config.prune, config.endpoint)
+	}
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,144 @@
+package stacks
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+// composeOutputStackManager is implemented by the Compose stack managers able to stream the deployment output
+type composeOutputStackManager interface {
+	UpWithOutput(stack *portainer.Stack, endpoint *portainer.Endpoint, output io.Writer) error
+}
+
+// swarmOutputStackManager is implemented by the Swarm stack managers able to stream the deployment output
+type swarmOutputStackManager interface {
+	DeployWithOutput(stack *portainer.Stack, prune bool, endpoint *portainer.Endpoint, output io.Writer) error
+}
+
+// runDeploymentJob executes the deploy function inside a deployment job.
+// The job is returned right away and its progress can be followed via the jobs API. When the async query
+// parameter is set to false, the request waits for the job to complete and responds with the result instead.
+func (handler *Handler) runDeploymentJob(w http.ResponseWriter, r *http.Request, jobType portainer.DeploymentJobType, stack *portainer.Stack, result interface{}, deploy func(output io.Writer) error) *httperror.HandlerError {
+	asyncParam, _ := request.RetrieveQueryParameter(r, "async", true)
+	async := asyncParam != "false"
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve user details from authentication token", err}
+	}
+
+	job := handler.DeploymentJobService.Run(jobType, stack.ID, stack.EndpointID, tokenData.Username, deploy)
+	if async {
+		return response.JSON(w, job)
+	}
+
+	err = handler.DeploymentJobService.Wait(job.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+	}
+
+	return response.JSON(w, result)
+}
+
+// reserveStack persists a new stack with the deploying status before its deployment starts. The identifier
+// is assigned and the name is checked for uniqueness in the same transaction, so that concurrent creations
+// cannot share an identifier, a project path or a name. The stack is visible while it is deployed.
+func (handler *Handler) reserveStack(stack *portainer.Stack, userID portainer.UserID) *httperror.HandlerError {
+	stack.Status = portainer.StackStatusDeploying
+
+	err := handler.DataStore.Stack().CreateStackWithUniqueName(stack)
+	if err == bolterrors.ErrStackAlreadyExists {
+		errorMessage := fmt.Sprintf("A stack with the name '%s' already exists", stack.Name)
+		return &httperror.HandlerError{http.StatusConflict, errorMessage, err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
+	}
+
+	stack.ProjectPath = handler.FileService.GetStackProjectPath(strconv.Itoa(int(stack.ID)))
+
+	if stack.Type == portainer.KubernetesStack {
+		return nil
+	}
+
+	err = handler.createStackResourceControl(stack, userID)
+	if err != nil {
+		deleteErr := handler.DataStore.Stack().DeleteStack(stack.ID)
+		if deleteErr != nil {
+			stackLogger(stack).WithError(deleteErr).Warn("unable to remove the stack")
+		}
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack resource control inside the database", err}
+	}
+
+	return nil
+}
+
+// createStackJob deploys a new stack, reserved with reserveStack, inside a deployment job. The stack becomes
+// active once the deployment succeeds. It is removed along with its files and resource control when it fails.
+func (handler *Handler) createStackJob(w http.ResponseWriter, r *http.Request, stack *portainer.Stack, deploy func(output io.Writer) error) *httperror.HandlerError {
+	return handler.runDeploymentJob(w, r, portainer.DeploymentJobCreate, stack, stack, func(output io.Writer) error {
+		doCleanUp := true
+		defer handler.cleanUp(stack, &doCleanUp)
+
+		err := deploy(output)
+		if err != nil {
+			return err
+		}
+
+		stack.Status = portainer.StackStatusActive
+		err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
+		if err != nil {
+			return fmt.Errorf("Unable to persist the stack inside the database: %w", err)
+		}
+		doCleanUp = false
+
+		err = handler.createStackRevision(stack, 0)
+		if err != nil {
+			stackLogger(stack).WithContext(r.Context()).WithError(err).Warn("unable to record the stack revision")
+		}
+
+		return nil
+	})
+}
+
+// stackDeploymentConflict returns a conflict error when a deployment job of the stack is still running
+func (handler *Handler) stackDeploymentConflict(stack *portainer.Stack) *httperror.HandlerError {
+	if !handler.DeploymentJobService.IsRunning(stack.ID) {
+		return nil
+	}
+
+	return &httperror.HandlerError{http.StatusConflict, "The stack is being deployed", errors.New("The stack is being deployed")}
+}
+
+// updateStackJob redeploys an existing stack inside a deployment job and persists the stack changes
+// once the deployment succeeds. A new stack revision is recorded for each successful deployment,
+// rollbackOf is the version of the revision being redeployed or 0 for a regular deployment.
+func (handler *Handler) updateStackJob(w http.ResponseWriter, r *http.Request, jobType portainer.DeploymentJobType, stack *portainer.Stack, rollbackOf int, deploy func(output io.Writer) error) *httperror.HandlerError {
+	return handler.runDeploymentJob(w, r, jobType, stack, stack, func(output io.Writer) error {
+		err := deploy(output)
+		if err != nil {
+			return err
+		}
+
+		err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
+		if err != nil {
+			return fmt.Errorf("Unable to persist the stack changes inside the database: %w", err)
+		}
+
+		err = handler.createStackRevision(stack, rollbackOf)
+		if err != nil {
+			stackLogger(stack).WithContext(r.Context()).WithError(err).Warn("unable to record the stack revision")
+		}
+
+		return nil
+	})
+}
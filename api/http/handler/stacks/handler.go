@@ -14,6 +14,9 @@ import (
 	"github.com/portainer/portainer/api/docker"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/deploymentjob"
+	"github.com/portainer/portainer/api/internal/logging"
+	"github.com/sirupsen/logrus"
 )
 
 var (
@@ -21,19 +24,29 @@ var (
 	errStackNotExternal   = errors.New("Not an external stack")
 )
 
+var logger = logging.Component("stacks")
+
+// stackLogger returns a logger whose entries are tagged with the stack identifier
+func stackLogger(stack *portainer.Stack) *logrus.Entry {
+	return logger.WithField(logging.FieldStackID, stack.ID)
+}
+
 // Handler is the HTTP handler used to handle stack operations.
 type Handler struct {
 	stackCreationMutex *sync.Mutex
 	stackDeletionMutex *sync.Mutex
 	requestBouncer     *security.RequestBouncer
 	*mux.Router
-	DataStore           portainer.DataStore
-	DockerClientFactory *docker.ClientFactory
-	FileService         portainer.FileService
-	GitService          portainer.GitService
-	SwarmStackManager   portainer.SwarmStackManager
-	ComposeStackManager portainer.ComposeStackManager
-	KubernetesDeployer  portainer.KubernetesDeployer
+	DataStore            portainer.DataStore
+	DeploymentJobService *deploymentjob.Service
+	DockerClientFactory  *docker.ClientFactory
+	FileService          portainer.FileService
+	GitService           portainer.GitService
+	SwarmStackManager    portainer.SwarmStackManager
+	ComposeStackManager  portainer.ComposeStackManager
+	KubernetesDeployer   portainer.KubernetesDeployer
+	VulnerabilityService portainer.VulnerabilityService
+	ShutdownCtx          context.Context
 }
 
 // NewHandler creates a handler to manage stack operations.
@@ -48,6 +61,8 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackCreate))).Methods(http.MethodPost)
 	h.Handle("/stacks",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackList))).Methods(http.MethodGet)
+	h.Handle("/stacks/validate",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackValidate))).Methods(http.MethodPost)
 	h.Handle("/stacks/{id}",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackInspect))).Methods(http.MethodGet)
 	h.Handle("/stacks/{id}",
@@ -60,6 +75,10 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackUpdateGit))).Methods(http.MethodPut)
 	h.Handle("/stacks/{id}/file",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackFile))).Methods(http.MethodGet)
+	h.Handle("/stacks/{id}/revisions",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackRevisionList))).Methods(http.MethodGet)
+	h.Handle("/stacks/{id}/rollback/{rev}",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackRollback))).Methods(http.MethodPost)
 	h.Handle("/stacks/{id}/migrate",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackMigrate))).Methods(http.MethodPost)
 	h.Handle("/stacks/{id}/start",
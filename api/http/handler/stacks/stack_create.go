@@ -1,16 +1,17 @@
 package stacks
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"path"
+	"strings"
 
 	"github.com/docker/cli/cli/compose/loader"
 	"github.com/docker/cli/cli/compose/types"
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/request"
-	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	bolterrors "github.com/portainer/portainer/api/bolt/errors"
 	gittypes "github.com/portainer/portainer/api/git/types"
@@ -18,6 +19,8 @@ import (
 	"github.com/portainer/portainer/api/internal/authorization"
 	"github.com/portainer/portainer/api/internal/endpointutils"
 	"github.com/portainer/portainer/api/internal/stackutils"
+	"github.com/portainer/portainer/api/internal/stackvalidation"
+	"github.com/portainer/portainer/api/internal/vulnerability"
 )
 
 func (handler *Handler) cleanUp(stack *portainer.Stack, doCleanUp *bool) error {
@@ -27,7 +30,19 @@ func (handler *Handler) cleanUp(stack *portainer.Stack, doCleanUp *bool) error {
 
 	err := handler.FileService.RemoveDirectory(stack.ProjectPath)
 	if err != nil {
-		log.Printf("http error: Unable to cleanup stack creation (err=%s)\n", err)
+		stackLogger(stack).WithError(err).Warn("unable to cleanup stack creation")
+	}
+
+	if stack.ResourceControl != nil {
+		err = handler.DataStore.ResourceControl().DeleteResourceControl(stack.ResourceControl.ID)
+		if err != nil {
+			stackLogger(stack).WithError(err).Warn("unable to remove the stack resource control")
+		}
+	}
+
+	err = handler.DataStore.Stack().DeleteStack(stack.ID)
+	if err != nil {
+		stackLogger(stack).WithError(err).Warn("unable to remove the stack")
 	}
 	return nil
 }
@@ -43,6 +58,8 @@ func (handler *Handler) cleanUp(stack *portainer.Stack, doCleanUp *bool) error {
 // @param type query int true "Stack deployment type. Possible values: 1 (Swarm stack) or 2 (Compose stack)." Enums(1,2)
 // @param method query string true "Stack deployment method. Possible values: file, string or repository." Enums(string, file, repository)
 // @param endpointId query int true "Identifier of the endpoint that will be used to deploy the stack"
+// @param async query bool false "Return the deployment job right away, set to false to wait for the deployment to complete and respond with the result" default(true)
+// @param dryRun query bool false "Validate the stack file and return the validation report instead of deploying the stack"
 // @param body_swarm_string body swarmStackFromFileContentPayload false "Required when using method=string and type=1"
 // @param body_swarm_repository body swarmStackFromGitRepositoryPayload false "Required when using method=repository and type=1"
 // @param body_compose_string body composeStackFromFileContentPayload false "Required when using method=string and type=2"
@@ -112,7 +129,7 @@ func (handler *Handler) stackCreate(w http.ResponseWriter, r *http.Request) *htt
 	case portainer.DockerComposeStack:
 		return handler.createComposeStack(w, r, method, endpoint, tokenData.ID)
 	case portainer.KubernetesStack:
-		return handler.createKubernetesStack(w, r, method, endpoint)
+		return handler.createKubernetesStack(w, r, method, endpoint, tokenData.ID)
 	}
 
 	return &httperror.HandlerError{http.StatusBadRequest, "Invalid value for query parameter: type. Value must be one of: 1 (Swarm stack) or 2 (Compose stack)", errors.New(request.ErrInvalidQueryParameter)}
@@ -145,79 +162,142 @@ func (handler *Handler) createSwarmStack(w http.ResponseWriter, r *http.Request,
 	return &httperror.HandlerError{http.StatusBadRequest, "Invalid value for query parameter: method. Value must be one of: string, repository or file", errors.New(request.ErrInvalidQueryParameter)}
 }
 
-func (handler *Handler) createKubernetesStack(w http.ResponseWriter, r *http.Request, method string, endpoint *portainer.Endpoint) *httperror.HandlerError {
+func (handler *Handler) createKubernetesStack(w http.ResponseWriter, r *http.Request, method string, endpoint *portainer.Endpoint, userID portainer.UserID) *httperror.HandlerError {
 	switch method {
 	case "string":
-		return handler.createKubernetesStackFromFileContent(w, r, endpoint)
+		return handler.createKubernetesStackFromFileContent(w, r, endpoint, userID)
 	case "repository":
-		return handler.createKubernetesStackFromGitRepository(w, r, endpoint)
+		return handler.createKubernetesStackFromGitRepository(w, r, endpoint, userID)
 	}
 	return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid value for query parameter: method. Value must be one of: string or repository", Err: errors.New(request.ErrInvalidQueryParameter)}
 }
 
-func (handler *Handler) isValidStackFile(stackFileContent []byte, securitySettings *portainer.EndpointSecuritySettings) error {
-	composeConfigYAML, err := loader.ParseYAML(stackFileContent)
+func (handler *Handler) isValidStackFile(stackFileContent []byte, env []portainer.Pair, securitySettings *portainer.EndpointSecuritySettings) error {
+	composeConfig, err := loadComposeConfig(stackFileContent, env)
 	if err != nil {
 		return err
 	}
 
-	composeConfigFile := types.ConfigFile{
-		Config: composeConfigYAML,
+	violations := stackvalidation.SecurityViolations(composeConfig, securitySettings)
+	if len(violations) > 0 {
+		return violations[0]
 	}
 
-	composeConfigDetails := types.ConfigDetails{
-		ConfigFiles: []types.ConfigFile{composeConfigFile},
-		Environment: map[string]string{},
+	return nil
+}
+
+// checkVulnerabilityGate rejects the stack file when one of its images has known vulnerabilities
+// of the severity set by the vulnerability gate or higher. The images which were not scanned yet are
+// scanned first, the ones that cannot be scanned are rejected when the gate is set to reject them.
+// The scans are cancelled with the context or once the scan timeout of the settings is exceeded.
+func (handler *Handler) checkVulnerabilityGate(ctx context.Context, stack *portainer.Stack) error {
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return err
 	}
 
-	composeConfig, err := loader.Load(composeConfigDetails, func(options *loader.Options) {
-		options.SkipValidation = true
-		options.SkipInterpolation = true
-	})
+	if settings.VulnerabilityGateSeverity == "" {
+		return nil
+	}
+
+	stackContent, err := handler.FileService.GetFileContent(path.Join(stack.ProjectPath, stack.EntryPoint))
 	if err != nil {
 		return err
 	}
 
-	for key := range composeConfig.Services {
-		service := composeConfig.Services[key]
-		if !securitySettings.AllowBindMountsForRegularUsers {
-			for _, volume := range service.Volumes {
-				if volume.Type == "bind" {
-					return errors.New("bind-mount disabled for non administrator users")
-				}
-			}
-		}
+	composeConfig, err := loadComposeConfig(stackContent, stack.Env)
+	if err != nil {
+		return err
+	}
 
-		if !securitySettings.AllowPrivilegedModeForRegularUsers && service.Privileged == true {
-			return errors.New("privileged mode disabled for non administrator users")
-		}
+	reports, err := handler.DataStore.ImageVulnerability().ImageVulnerabilityReports()
+	if err != nil {
+		return err
+	}
 
-		if !securitySettings.AllowHostNamespaceForRegularUsers && service.Pid == "host" {
-			return errors.New("pid host disabled for non administrator users")
-		}
+	for _, image := range stackvalidation.UnscannedImages(composeConfig, reports) {
+		vulnerabilities, err := handler.scanImage(ctx, image)
+		if err != nil {
+			if settings.VulnerabilityGateRejectUnscanned {
+				return fmt.Errorf("image %s could not be scanned for vulnerabilities: %w", image, err)
+			}
 
-		if !securitySettings.AllowDeviceMappingForRegularUsers && service.Devices != nil && len(service.Devices) > 0 {
-			return errors.New("device mapping disabled for non administrator users")
+			stackLogger(stack).WithField("image", image).WithError(err).Warn("unable to scan image for vulnerabilities, the image is deployed unchecked")
+			continue
 		}
 
-		if !securitySettings.AllowSysctlSettingForRegularUsers && service.Sysctls != nil && len(service.Sysctls) > 0 {
-			return errors.New("sysctl setting disabled for non administrator users")
-		}
+		reports = append(reports, imageReport(image, vulnerabilities))
+	}
 
-		if !securitySettings.AllowContainerCapabilitiesForRegularUsers && (len(service.CapAdd) > 0 || len(service.CapDrop) > 0) {
-			return errors.New("container capabilities disabled for non administrator users")
-		}
+	violations := stackvalidation.VulnerabilityViolations(composeConfig, reports, settings.VulnerabilityGateSeverity)
+	if len(violations) > 0 {
+		return violations[0]
 	}
 
 	return nil
 }
 
-func (handler *Handler) decorateStackResponse(w http.ResponseWriter, stack *portainer.Stack, userID portainer.UserID) *httperror.HandlerError {
+func (handler *Handler) scanImage(ctx context.Context, image string) ([]portainer.ImageVulnerability, error) {
+	if handler.VulnerabilityService == nil {
+		return nil, errors.New("Vulnerability scanning is not available")
+	}
+
+	return handler.VulnerabilityService.ScanImage(ctx, image)
+}
+
+// imageReport returns the report of an image scanned before its deployment, matching the image reference of the stack service
+func imageReport(image string, vulnerabilities []portainer.ImageVulnerability) portainer.ImageVulnerabilityReport {
+	report := portainer.ImageVulnerabilityReport{
+		Tags:            []string{image},
+		Summary:         vulnerability.Summarize(vulnerabilities),
+		Vulnerabilities: vulnerabilities,
+	}
+
+	if idx := strings.LastIndex(image, "@"); idx != -1 {
+		report.Digest = image[idx+1:]
+		report.Tags = []string{}
+	}
+
+	return report
+}
+
+// loadComposeConfig loads the stack file with its variables resolved from the environment of the stack,
+// unset variables resolving to a blank string as they do on deployment
+func loadComposeConfig(stackFileContent []byte, env []portainer.Pair) (*types.Config, error) {
+	composeConfigYAML, err := loader.ParseYAML(stackFileContent)
+	if err != nil {
+		return nil, err
+	}
+
+	composeConfigFile := types.ConfigFile{
+		Config: composeConfigYAML,
+	}
+
+	environment := make(map[string]string)
+	for _, pair := range env {
+		environment[pair.Name] = pair.Value
+	}
+
+	composeConfigDetails := types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{composeConfigFile},
+		Environment: environment,
+	}
+
+	return loader.Load(composeConfigDetails, func(options *loader.Options) {
+		options.SkipValidation = true
+		options.Interpolate.LookupValue = func(key string) (string, bool) {
+			value, ok := environment[key]
+			return value, ok
+		}
+	})
+}
+
+func (handler *Handler) createStackResourceControl(stack *portainer.Stack, userID portainer.UserID) error {
 	var resourceControl *portainer.ResourceControl
 
 	isAdmin, err := handler.userIsAdmin(userID)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to load user information from the database", err}
+		return fmt.Errorf("Unable to load user information from the database: %w", err)
 	}
 
 	if isAdmin {
@@ -228,11 +308,11 @@ func (handler *Handler) decorateStackResponse(w http.ResponseWriter, stack *port
 
 	err = handler.DataStore.ResourceControl().CreateResourceControl(resourceControl)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist resource control inside the database", err}
+		return fmt.Errorf("Unable to persist resource control inside the database: %w", err)
 	}
 
 	stack.ResourceControl = resourceControl
-	return response.JSON(w, stack)
+	return nil
 }
 
 func (handler *Handler) cloneAndSaveConfig(stack *portainer.Stack, projectPath, repositoryURL, refName, configFilePath string, auth bool, username, password string) error {
@@ -241,7 +321,7 @@ func (handler *Handler) cloneAndSaveConfig(stack *portainer.Stack, projectPath,
 		password = ""
 	}
 
-	err := handler.GitService.CloneRepository(projectPath, repositoryURL, refName, username, password)
+	commitID, err := handler.cloneRepositoryAtLatestCommit(stack, projectPath, repositoryURL, refName, username, password)
 	if err != nil {
 		return fmt.Errorf("unable to clone git repository: %w", err)
 	}
@@ -250,6 +330,20 @@ func (handler *Handler) cloneAndSaveConfig(stack *portainer.Stack, projectPath,
 		URL:            repositoryURL,
 		ReferenceName:  refName,
 		ConfigFilePath: configFilePath,
+		ConfigHash:     commitID,
 	}
 	return nil
 }
+
+// cloneRepositoryAtLatestCommit resolves the latest commit of the reference and clones the repository at this commit,
+// so that the returned commit is the one deployed even when the reference is updated in the meantime.
+// The reference is cloned with an empty commit when its latest commit cannot be resolved.
+func (handler *Handler) cloneRepositoryAtLatestCommit(stack *portainer.Stack, destination, repositoryURL, refName, username, password string) (string, error) {
+	commitID, err := handler.GitService.LatestCommitID(repositoryURL, refName, username, password)
+	if err != nil || commitID == "" {
+		stackLogger(stack).WithError(err).Warn("unable to retrieve the latest git commit")
+		return "", handler.GitService.CloneRepository(destination, repositoryURL, refName, username, password)
+	}
+
+	return commitID, handler.GitService.CloneRepositoryAtCommit(destination, repositoryURL, refName, commitID, username, password)
+}
@@ -3,11 +3,11 @@ package stacks
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/request"
-	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	bolterrors "github.com/portainer/portainer/api/bolt/errors"
 	httperrors "github.com/portainer/portainer/api/http/errors"
@@ -40,6 +40,7 @@ func (payload *stackMigratePayload) Validate(r *http.Request) error {
 // @produce json
 // @param id path int true "Stack identifier"
 // @param endpointId query int false "Stacks created before version 1.18.0 might not have an associated endpoint identifier. Use this optional parameter to set the endpoint identifier used by the stack."
+// @param async query bool false "Return the deployment job right away, set to false to wait for the deployment to complete and respond with the result" default(true)
 // @param body body stackMigratePayload true "Stack migration details"
 // @success 200 {object} portainer.Stack "Success"
 // @failure 400 "Invalid request"
@@ -66,6 +67,10 @@ func (handler *Handler) stackMigrate(w http.ResponseWriter, r *http.Request) *ht
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack with the specified identifier inside the database", err}
 	}
 
+	if httpErr := handler.stackDeploymentConflict(stack); httpErr != nil {
+		return httpErr
+	}
+
 	endpoint, err := handler.DataStore.Endpoint().Endpoint(stack.EndpointID)
 	if err == bolterrors.ErrObjectNotFound {
 		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
@@ -134,56 +139,58 @@ func (handler *Handler) stackMigrate(w http.ResponseWriter, r *http.Request) *ht
 		return &httperror.HandlerError{http.StatusConflict, errorMessage, errors.New(errorMessage)}
 	}
 
-	migrationError := handler.migrateStack(r, stack, targetEndpoint)
+	deploy, migrationError := handler.migrateStack(r, stack, targetEndpoint)
 	if migrationError != nil {
 		return migrationError
 	}
 
-	stack.Name = oldName
-	err = handler.deleteStack(stack, endpoint)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
-	}
+	return handler.runDeploymentJob(w, r, portainer.DeploymentJobMigrate, stack, stack, func(output io.Writer) error {
+		err := deploy(output)
+		if err != nil {
+			return err
+		}
 
-	err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
-	}
+		stack.Name = oldName
+		err = handler.deleteStack(stack, endpoint)
+		if err != nil {
+			return err
+		}
 
-	return response.JSON(w, stack)
+		err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
+		if err != nil {
+			return fmt.Errorf("Unable to persist the stack changes inside the database: %w", err)
+		}
+
+		return nil
+	})
 }
 
-func (handler *Handler) migrateStack(r *http.Request, stack *portainer.Stack, next *portainer.Endpoint) *httperror.HandlerError {
+// migrateStack returns the function used to deploy the stack inside the target endpoint
+func (handler *Handler) migrateStack(r *http.Request, stack *portainer.Stack, next *portainer.Endpoint) (func(output io.Writer) error, *httperror.HandlerError) {
 	if stack.Type == portainer.DockerSwarmStack {
 		return handler.migrateSwarmStack(r, stack, next)
 	}
 	return handler.migrateComposeStack(r, stack, next)
 }
 
-func (handler *Handler) migrateComposeStack(r *http.Request, stack *portainer.Stack, next *portainer.Endpoint) *httperror.HandlerError {
+func (handler *Handler) migrateComposeStack(r *http.Request, stack *portainer.Stack, next *portainer.Endpoint) (func(output io.Writer) error, *httperror.HandlerError) {
 	config, configErr := handler.createComposeDeployConfig(r, stack, next)
 	if configErr != nil {
-		return configErr
+		return nil, configErr
 	}
 
-	err := handler.deployComposeStack(config)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
-	}
-
-	return nil
+	return func(output io.Writer) error {
+		return handler.deployComposeStack(config, output)
+	}, nil
 }
 
-func (handler *Handler) migrateSwarmStack(r *http.Request, stack *portainer.Stack, next *portainer.Endpoint) *httperror.HandlerError {
+func (handler *Handler) migrateSwarmStack(r *http.Request, stack *portainer.Stack, next *portainer.Endpoint) (func(output io.Writer) error, *httperror.HandlerError) {
 	config, configErr := handler.createSwarmDeployConfig(r, stack, next, true)
 	if configErr != nil {
-		return configErr
+		return nil, configErr
 	}
 
-	err := handler.deploySwarmStack(config)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
-	}
-
-	return nil
+	return func(output io.Writer) error {
+		return handler.deploySwarmStack(config, output)
+	}, nil
 }
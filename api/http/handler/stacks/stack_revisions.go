@@ -0,0 +1,122 @@
+package stacks
+
+import (
+	"net/http"
+	"path"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+type stackRevisionResponse struct {
+	portainer.StackRevision
+	// Unified diff of the stack file against the previous revision
+	Diff string `json:"Diff" example:"@@ -1,3 +1,3 @@\n services:\n   web:\n-    image: nginx:1.19\n+    image: nginx:1.20\n"`
+}
+
+// @id StackRevisionList
+// @summary List the revisions of a stack
+// @description List the deployed revisions of a stack, from the oldest to the most recent one.
+// @description Each revision includes the diff of its stack file against the previous revision.
+// @description **Access policy**: restricted
+// @tags stacks
+// @security jwt
+// @produce json
+// @param id path int true "Stack identifier"
+// @success 200 {array} stackRevisionResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Stack not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/revisions [get]
+func (handler *Handler) stackRevisionList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid stack identifier route variable", err}
+	}
+
+	stack, err := handler.DataStore.Stack().Stack(portainer.StackID(stackID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a stack with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack with the specified identifier inside the database", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(stack.EndpointID)
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find the endpoint associated to the stack inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find the endpoint associated to the stack inside the database", err}
+	}
+
+	httpErr := handler.checkStackAccess(r, stack, endpoint)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	revisions, err := handler.DataStore.StackRevision().StackRevisions(stack.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the stack revisions from the database", err}
+	}
+
+	previousContent := ""
+	result := make([]stackRevisionResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		result = append(result, stackRevisionResponse{
+			StackRevision: revision,
+			Diff:          stackutils.Diff(previousContent, revision.StackFileContent),
+		})
+		previousContent = revision.StackFileContent
+	}
+
+	return response.JSON(w, result)
+}
+
+// checkStackAccess verifies that the user can access the endpoint of the stack and the stack itself
+func (handler *Handler) checkStackAccess(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint) *httperror.HandlerError {
+	err := handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	resourceControl, err := handler.DataStore.ResourceControl().ResourceControlByResourceIDAndType(stackutils.ResourceControlID(stack.EndpointID, stack.Name), portainer.StackResourceControl)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve a resource control associated to the stack", err}
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	access, err := handler.userCanAccessStack(securityContext, endpoint.ID, resourceControl)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to verify user authorizations to validate stack access", err}
+	}
+	if !access {
+		return &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", httperrors.ErrResourceAccessDenied}
+	}
+
+	return nil
+}
+
+// createStackRevision records the stack file, environment and git commit currently deployed for the stack.
+// rollbackOf is the version of the revision being redeployed, 0 for a regular deployment.
+func (handler *Handler) createStackRevision(stack *portainer.Stack, rollbackOf int) error {
+	stackFileContent, err := handler.FileService.GetFileContent(path.Join(stack.ProjectPath, stack.EntryPoint))
+	if err != nil {
+		return err
+	}
+
+	revision := stackutils.NewStackRevision(stack, stackFileContent, rollbackOf)
+	revision.DeploymentDate = time.Now().Unix()
+
+	return handler.DataStore.StackRevision().CreateStackRevision(revision)
+}
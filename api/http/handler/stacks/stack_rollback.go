@@ -0,0 +1,183 @@
+package stacks
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/filesystem"
+	gittypes "github.com/portainer/portainer/api/git/types"
+)
+
+// @id StackRollback
+// @summary Rollback a stack to a previous revision
+// @description Redeploy the stack file and environment variables recorded in a previous revision of the stack.
+// @description The revision files are prepared next to the stack files, the repository of a git stack is cloned at the recorded commit.
+// @description The stack files and settings are only replaced once the deployment succeeds. The rollback is recorded as a new revision.
+// @description **Access policy**: restricted
+// @tags stacks
+// @security jwt
+// @produce json
+// @param id path int true "Stack identifier"
+// @param rev path int true "Version of the revision to redeploy"
+// @param async query bool false "Return the deployment job right away, set to false to wait for the deployment to complete and respond with the result" default(true)
+// @success 200 {object} portainer.Stack "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Stack or revision not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/rollback/{rev} [post]
+func (handler *Handler) stackRollback(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid stack identifier route variable", err}
+	}
+
+	version, err := request.RetrieveNumericRouteVariableValue(r, "rev")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid stack revision route variable", err}
+	}
+
+	stack, err := handler.DataStore.Stack().Stack(portainer.StackID(stackID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a stack with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack with the specified identifier inside the database", err}
+	}
+
+	if httpErr := handler.stackDeploymentConflict(stack); httpErr != nil {
+		return httpErr
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(stack.EndpointID)
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find the endpoint associated to the stack inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find the endpoint associated to the stack inside the database", err}
+	}
+
+	httpErr := handler.checkStackAccess(r, stack, endpoint)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	revision, err := handler.DataStore.StackRevision().StackRevision(stack.ID, version)
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a stack revision with the specified version inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack revision with the specified version inside the database", err}
+	}
+
+	stagingPath, err := handler.stageStackRevision(stack, revision)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to prepare the stack revision files on disk", err}
+	}
+
+	return handler.updateStackJob(w, r, portainer.DeploymentJobRollback, stack, revision.Version, func(output io.Writer) error {
+		backupProjectPath := fmt.Sprintf("%s-old", stack.ProjectPath)
+		err := filesystem.MoveDirectory(stack.ProjectPath, backupProjectPath)
+		if err != nil {
+			handler.removeStackDirectory(stack, stagingPath)
+			return fmt.Errorf("Unable to move the stack directory: %w", err)
+		}
+
+		err = filesystem.MoveDirectory(stagingPath, stack.ProjectPath)
+		if err != nil {
+			handler.removeStackDirectory(stack, stagingPath)
+			handler.restoreStackDirectory(stack, backupProjectPath)
+			return fmt.Errorf("Unable to move the stack revision directory: %w", err)
+		}
+
+		previousStack := *stack
+		var previousGitConfig gittypes.RepoConfig
+		if stack.GitConfig != nil {
+			previousGitConfig = *stack.GitConfig
+			stack.GitConfig.ReferenceName = revision.GitReferenceName
+			stack.GitConfig.ConfigHash = revision.GitCommitID
+		}
+		stack.Env = revision.Env
+
+		err = handler.deployStackWithOutput(r, stack, endpoint, output)
+		if err != nil {
+			// the stack keeps running its previous configuration, restore its files and settings
+			*stack = previousStack
+			if stack.GitConfig != nil {
+				*stack.GitConfig = previousGitConfig
+			}
+			handler.removeStackDirectory(stack, stack.ProjectPath)
+			handler.restoreStackDirectory(stack, backupProjectPath)
+			return err
+		}
+
+		handler.removeStackDirectory(stack, backupProjectPath)
+		return nil
+	})
+}
+
+// stageStackRevision prepares the files of a stack revision in a directory next to the project path of the stack.
+// The repository of a git stack is cloned at the commit recorded in the revision.
+func (handler *Handler) stageStackRevision(stack *portainer.Stack, revision *portainer.StackRevision) (string, error) {
+	stagingIdentifier := fmt.Sprintf("%d-rollback", stack.ID)
+	stagingPath := handler.FileService.GetStackProjectPath(stagingIdentifier)
+
+	err := handler.FileService.RemoveDirectory(stagingPath)
+	if err != nil {
+		return "", err
+	}
+
+	if stack.GitConfig == nil {
+		return handler.FileService.StoreStackFileFromBytes(stagingIdentifier, stack.EntryPoint, []byte(revision.StackFileContent))
+	}
+
+	username, password := "", ""
+	if stack.GitConfig.Authentication != nil {
+		username = stack.GitConfig.Authentication.Username
+		password = stack.GitConfig.Authentication.Password
+	}
+
+	if revision.GitCommitID == "" {
+		// the commit was not recorded, clone the reference and restore the recorded stack file
+		err = handler.GitService.CloneRepository(stagingPath, stack.GitConfig.URL, revision.GitReferenceName, username, password)
+		if err != nil {
+			return "", err
+		}
+
+		_, err = handler.FileService.StoreStackFileFromBytes(stagingIdentifier, stack.EntryPoint, []byte(revision.StackFileContent))
+		return stagingPath, err
+	}
+
+	err = handler.GitService.CloneRepositoryAtCommit(stagingPath, stack.GitConfig.URL, revision.GitReferenceName, revision.GitCommitID, username, password)
+	if err != nil {
+		return "", err
+	}
+
+	return stagingPath, nil
+}
+
+// deployStackWithOutput redeploys the stack with its current configuration
+func (handler *Handler) deployStackWithOutput(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint, output io.Writer) error {
+	deploy, httpErr := handler.deployStack(r, stack, endpoint)
+	if httpErr != nil {
+		return fmt.Errorf("%s: %w", httpErr.Message, httpErr.Err)
+	}
+
+	return deploy(output)
+}
+
+func (handler *Handler) restoreStackDirectory(stack *portainer.Stack, backupProjectPath string) {
+	err := filesystem.MoveDirectory(backupProjectPath, stack.ProjectPath)
+	if err != nil {
+		stackLogger(stack).WithError(err).Warn("failed restoring backup folder")
+	}
+}
+
+func (handler *Handler) removeStackDirectory(stack *portainer.Stack, directoryPath string) {
+	err := handler.FileService.RemoveDirectory(directoryPath)
+	if err != nil {
+		stackLogger(stack).WithError(err).Warn("unable to remove stack directory")
+	}
+}
@@ -2,6 +2,7 @@ package stacks
 
 import (
 	"errors"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
@@ -9,7 +10,6 @@ import (
 	"github.com/asaskevich/govalidator"
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/request"
-	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	bolterrors "github.com/portainer/portainer/api/bolt/errors"
 	httperrors "github.com/portainer/portainer/api/http/errors"
@@ -57,6 +57,8 @@ func (payload *updateSwarmStackPayload) Validate(r *http.Request) error {
 // @produce json
 // @param id path int true "Stack identifier"
 // @param endpointId query int false "Stacks created before version 1.18.0 might not have an associated endpoint identifier. Use this optional parameter to set the endpoint identifier used by the stack."
+// @param async query bool false "Return the deployment job right away, set to false to wait for the deployment to complete and respond with the result" default(true)
+// @param dryRun query bool false "Validate the stack file and return the validation report instead of updating the stack"
 // @param body body updateSwarmStackPayload true "Stack details"
 // @success 200 {object} portainer.Stack "Success"
 // @failure 400 "Invalid request"
@@ -77,6 +79,10 @@ func (handler *Handler) stackUpdate(w http.ResponseWriter, r *http.Request) *htt
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack with the specified identifier inside the database", err}
 	}
 
+	if httpErr := handler.stackDeploymentConflict(stack); httpErr != nil {
+		return httpErr
+	}
+
 	// TODO: this is a work-around for stacks created with Portainer version >= 1.17.1
 	// The EndpointID property is not available for these stacks, this API endpoint
 	// can use the optional EndpointID query parameter to associate a valid endpoint identifier to the stack.
@@ -118,31 +124,37 @@ func (handler *Handler) stackUpdate(w http.ResponseWriter, r *http.Request) *htt
 		return &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", httperrors.ErrResourceAccessDenied}
 	}
 
-	updateError := handler.updateAndDeployStack(r, stack, endpoint)
-	if updateError != nil {
-		return updateError
+	if isDryRun(r) {
+		var payload updateSwarmStackPayload
+		err = request.DecodeAndValidateJSONPayload(r, &payload)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+		}
+
+		return handler.stackDryRun(w, r, endpoint, stack.Type, stack.Name, []byte(payload.StackFileContent), payload.Env)
 	}
 
-	err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
+	deploy, updateError := handler.updateStack(r, stack, endpoint)
+	if updateError != nil {
+		return updateError
 	}
 
-	return response.JSON(w, stack)
+	return handler.updateStackJob(w, r, portainer.DeploymentJobUpdate, stack, 0, deploy)
 }
 
-func (handler *Handler) updateAndDeployStack(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint) *httperror.HandlerError {
+// updateStack stores the updated stack file and returns the function used to redeploy the stack
+func (handler *Handler) updateStack(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint) (func(output io.Writer) error, *httperror.HandlerError) {
 	if stack.Type == portainer.DockerSwarmStack {
 		return handler.updateSwarmStack(r, stack, endpoint)
 	}
 	return handler.updateComposeStack(r, stack, endpoint)
 }
 
-func (handler *Handler) updateComposeStack(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint) *httperror.HandlerError {
+func (handler *Handler) updateComposeStack(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint) (func(output io.Writer) error, *httperror.HandlerError) {
 	var payload updateComposeStackPayload
 	err := request.DecodeAndValidateJSONPayload(r, &payload)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+		return nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
 	}
 
 	stack.Env = payload.Env
@@ -150,30 +162,27 @@ func (handler *Handler) updateComposeStack(r *http.Request, stack *portainer.Sta
 	stackFolder := strconv.Itoa(int(stack.ID))
 	_, err = handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, []byte(payload.StackFileContent))
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist updated Compose file on disk", err}
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist updated Compose file on disk", err}
 	}
 
 	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
 	if configErr != nil {
-		return configErr
+		return nil, configErr
 	}
 
 	stack.UpdateDate = time.Now().Unix()
 	stack.UpdatedBy = config.user.Username
 
-	err = handler.deployComposeStack(config)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
-	}
-
-	return nil
+	return func(output io.Writer) error {
+		return handler.deployComposeStack(config, output)
+	}, nil
 }
 
-func (handler *Handler) updateSwarmStack(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint) *httperror.HandlerError {
+func (handler *Handler) updateSwarmStack(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint) (func(output io.Writer) error, *httperror.HandlerError) {
 	var payload updateSwarmStackPayload
 	err := request.DecodeAndValidateJSONPayload(r, &payload)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+		return nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
 	}
 
 	stack.Env = payload.Env
@@ -181,22 +190,19 @@ func (handler *Handler) updateSwarmStack(r *http.Request, stack *portainer.Stack
 	stackFolder := strconv.Itoa(int(stack.ID))
 	_, err = handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, []byte(payload.StackFileContent))
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist updated Compose file on disk", err}
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist updated Compose file on disk", err}
 	}
 
 	config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, payload.Prune)
 	if configErr != nil {
-		return configErr
+		return nil, configErr
 	}
 
 	stack.UpdateDate = time.Now().Unix()
 	stack.UpdatedBy = config.user.Username
 	stack.Status = portainer.StackStatusActive
 
-	err = handler.deploySwarmStack(config)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
-	}
-
-	return nil
+	return func(output io.Writer) error {
+		return handler.deploySwarmStack(config, output)
+	}, nil
 }
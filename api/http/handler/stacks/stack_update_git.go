@@ -3,14 +3,13 @@ package stacks
 import (
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
 	"time"
 
 	"github.com/asaskevich/govalidator"
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/request"
-	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	bolterrors "github.com/portainer/portainer/api/bolt/errors"
 	"github.com/portainer/portainer/api/filesystem"
@@ -33,7 +32,7 @@ func (payload *updateStackGitPayload) Validate(r *http.Request) error {
 	return nil
 }
 
-// PUT request on /api/stacks/:id/git?endpointId=<endpointId>
+// PUT request on /api/stacks/:id/git?endpointId=<endpointId>&async=<async>
 func (handler *Handler) stackUpdateGit(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
 	stackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
 	if err != nil {
@@ -47,6 +46,10 @@ func (handler *Handler) stackUpdateGit(w http.ResponseWriter, r *http.Request) *
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack with the specified identifier inside the database", err}
 	}
 
+	if httpErr := handler.stackDeploymentConflict(stack); httpErr != nil {
+		return httpErr
+	}
+
 	if stack.GitConfig == nil {
 		return &httperror.HandlerError{http.StatusBadRequest, "Stack is not created from git", err}
 	}
@@ -100,12 +103,6 @@ func (handler *Handler) stackUpdateGit(w http.ResponseWriter, r *http.Request) *
 
 	stack.GitConfig.ReferenceName = payload.RepositoryReferenceName
 
-	backupProjectPath := fmt.Sprintf("%s-old", stack.ProjectPath)
-	err = filesystem.MoveDirectory(stack.ProjectPath, backupProjectPath)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to move git repository directory", err}
-	}
-
 	repositoryUsername := payload.RepositoryUsername
 	repositoryPassword := payload.RepositoryPassword
 	if !payload.RepositoryAuthentication {
@@ -113,68 +110,63 @@ func (handler *Handler) stackUpdateGit(w http.ResponseWriter, r *http.Request) *
 		repositoryPassword = ""
 	}
 
-	err = handler.GitService.CloneRepository(stack.ProjectPath, stack.GitConfig.URL, payload.RepositoryReferenceName, repositoryUsername, repositoryPassword)
-	if err != nil {
-		restoreError := filesystem.MoveDirectory(backupProjectPath, stack.ProjectPath)
-		if restoreError != nil {
-			log.Printf("[WARN] [http,stacks,git] [error: %s] [message: failed restoring backup folder]", restoreError)
-		}
-
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to clone git repository", err}
-	}
-
-	defer func() {
-		err = handler.FileService.RemoveDirectory(backupProjectPath)
+	return handler.updateStackJob(w, r, portainer.DeploymentJobGitRedeploy, stack, 0, func(output io.Writer) error {
+		backupProjectPath := fmt.Sprintf("%s-old", stack.ProjectPath)
+		err := filesystem.MoveDirectory(stack.ProjectPath, backupProjectPath)
 		if err != nil {
-			log.Printf("[WARN] [http,stacks,git] [error: %s] [message: unable to remove git repository directory]", err)
+			return fmt.Errorf("Unable to move git repository directory: %w", err)
 		}
-	}()
 
-	httpErr := handler.deployStack(r, stack, endpoint)
-	if httpErr != nil {
-		return httpErr
-	}
-
-	err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
-	}
+		commitID, err := handler.cloneRepositoryAtLatestCommit(stack, stack.ProjectPath, stack.GitConfig.URL, payload.RepositoryReferenceName, repositoryUsername, repositoryPassword)
+		if err != nil {
+			restoreError := filesystem.MoveDirectory(backupProjectPath, stack.ProjectPath)
+			if restoreError != nil {
+				stackLogger(stack).WithError(restoreError).Warn("failed restoring backup folder")
+			}
 
-	return response.JSON(w, stack)
+			return fmt.Errorf("Unable to clone git repository: %w", err)
+		}
+		stack.GitConfig.ConfigHash = commitID
+
+		defer func() {
+			err = handler.FileService.RemoveDirectory(backupProjectPath)
+			if err != nil {
+				stackLogger(stack).WithError(err).Warn("unable to remove git repository directory")
+			}
+		}()
+
+		// the deployment configuration is only built once the repository is cloned
+		return handler.deployStackWithOutput(r, stack, endpoint, output)
+	})
 }
 
-func (handler *Handler) deployStack(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint) *httperror.HandlerError {
+// deployStack returns the function used to redeploy the stack with its current configuration
+func (handler *Handler) deployStack(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint) (func(output io.Writer) error, *httperror.HandlerError) {
 	if stack.Type == portainer.DockerSwarmStack {
 		config, httpErr := handler.createSwarmDeployConfig(r, stack, endpoint, false)
 		if httpErr != nil {
-			return httpErr
-		}
-
-		err := handler.deploySwarmStack(config)
-		if err != nil {
-			return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+			return nil, httpErr
 		}
 
 		stack.UpdateDate = time.Now().Unix()
 		stack.UpdatedBy = config.user.Username
 		stack.Status = portainer.StackStatusActive
 
-		return nil
+		return func(output io.Writer) error {
+			return handler.deploySwarmStack(config, output)
+		}, nil
 	}
 
 	config, httpErr := handler.createComposeDeployConfig(r, stack, endpoint)
 	if httpErr != nil {
-		return httpErr
-	}
-
-	err := handler.deployComposeStack(config)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return nil, httpErr
 	}
 
 	stack.UpdateDate = time.Now().Unix()
 	stack.UpdatedBy = config.user.Username
 	stack.Status = portainer.StackStatusActive
 
-	return nil
+	return func(output io.Writer) error {
+		return handler.deployComposeStack(config, output)
+	}, nil
 }
@@ -0,0 +1,278 @@
+package stacks
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/filesystem"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/stackvalidation"
+)
+
+type stackValidatePayload struct {
+	// Name of the stack, used to compare the stack file with the services already deployed
+	Name string `example:"myStack"`
+	// Content of the Stack file
+	StackFileContent string `example:"version: 3\n services:\n web:\n image:nginx" validate:"required"`
+	// A list of environment variables used to resolve the variables of the Stack file
+	Env []portainer.Pair `example:""`
+}
+
+func (payload *stackValidatePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.StackFileContent) {
+		return errors.New("Invalid stack file content")
+	}
+	return nil
+}
+
+// @id StackValidate
+// @summary Validate a stack file
+// @description Validate a stack file against an endpoint without deploying it.
+// @description The stack file is parsed against the compose syntax supported by the endpoint, its variables are resolved
+// @description from the environment variables and the referenced images, networks, secrets and the endpoint security settings are checked.
+// @description When a name is specified, the report includes the services that would be created, changed or removed.
+// @description **Access policy**: restricted
+// @tags stacks
+// @security jwt
+// @accept json
+// @produce json
+// @param type query int true "Stack deployment type. Possible values: 1 (Swarm stack) or 2 (Compose stack)." Enums(1,2)
+// @param endpointId query int true "Identifier of the endpoint that will be used to deploy the stack"
+// @param body body stackValidatePayload true "Stack details"
+// @success 200 {object} stackvalidation.Report "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint not found"
+// @failure 500 "Server error"
+// @router /stacks/validate [post]
+func (handler *Handler) stackValidate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stackType, err := request.RetrieveNumericQueryParameter(r, "type", false)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: type", err}
+	}
+
+	if portainer.StackType(stackType) != portainer.DockerSwarmStack && portainer.StackType(stackType) != portainer.DockerComposeStack {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid value for query parameter: type. Value must be one of: 1 (Swarm stack) or 2 (Compose stack)", errors.New(request.ErrInvalidQueryParameter)}
+	}
+
+	endpointID, err := request.RetrieveNumericQueryParameter(r, "endpointId", false)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: endpointId", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	var payload stackValidatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	if payload.Name != "" && portainer.StackType(stackType) == portainer.DockerComposeStack {
+		payload.Name = handler.ComposeStackManager.NormalizeStackName(payload.Name)
+	}
+
+	return handler.stackDryRun(w, r, endpoint, portainer.StackType(stackType), payload.Name, []byte(payload.StackFileContent), payload.Env)
+}
+
+// isDryRun returns true when the dryRun query parameter is set, in which case the stack file is validated but not deployed
+func isDryRun(r *http.Request) bool {
+	dryRun, _ := request.RetrieveBooleanQueryParameter(r, "dryRun", true)
+	return dryRun
+}
+
+// stackDryRun responds with the validation report of the stack file instead of deploying it
+func (handler *Handler) stackDryRun(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint, stackType portainer.StackType, stackName string, stackFileContent []byte, env []portainer.Pair) *httperror.HandlerError {
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	user, err := handler.DataStore.User().User(securityContext.UserID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to load user information from the database", err}
+	}
+
+	isAdminOrEndpointAdmin, err := handler.userIsAdminOrEndpointAdmin(user, endpoint.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to verify user authorizations", err}
+	}
+
+	options := stackvalidation.Options{
+		MaxVersion:  handler.ComposeStackManager.ComposeSyntaxMaxVersion(),
+		ComposeSpec: handler.ComposeStackManager.ComposeSpecSupported(),
+		Swarm:       stackType == portainer.DockerSwarmStack,
+	}
+
+	if !isAdminOrEndpointAdmin {
+		options.SecuritySettings = &endpoint.SecuritySettings
+	}
+
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve settings from the database", err}
+	}
+
+	if settings.VulnerabilityGateSeverity != "" {
+		options.VulnerabilityGateSeverity = settings.VulnerabilityGateSeverity
+
+		options.VulnerabilityReports, err = handler.DataStore.ImageVulnerability().ImageVulnerabilityReports()
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve image vulnerability reports from the database", err}
+		}
+	}
+
+	options.Endpoint, err = handler.retrieveEndpointState(endpoint, stackName, options.Swarm)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the resources available on the endpoint", err}
+	}
+
+	return response.JSON(w, stackvalidation.Validate(stackFileContent, env, options))
+}
+
+// retrieveEndpointState lists the resources available on the endpoint and the services already running for the stack
+func (handler *Handler) retrieveEndpointState(endpoint *portainer.Endpoint, stackName string, swarm bool) (*stackvalidation.EndpointState, error) {
+	dockerClient, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return nil, err
+	}
+	defer dockerClient.Close()
+
+	state := &stackvalidation.EndpointState{
+		Images:   make(map[string]bool),
+		Networks: make(map[string]bool),
+		Services: make(map[string]string),
+	}
+
+	images, err := dockerClient.ImageList(context.Background(), types.ImageListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range images {
+		for _, tag := range image.RepoTags {
+			state.Images[tag] = true
+		}
+	}
+
+	networks, err := dockerClient.NetworkList(context.Background(), types.NetworkListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, network := range networks {
+		state.Networks[network.Name] = true
+	}
+
+	if !swarm {
+		if stackName == "" {
+			return state, nil
+		}
+
+		containers, err := dockerClient.ContainerList(context.Background(), types.ContainerListOptions{
+			All:     true,
+			Filters: filters.NewArgs(filters.Arg("label", "com.docker.compose.project="+stackName)),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, container := range containers {
+			if serviceName, ok := container.Labels["com.docker.compose.service"]; ok {
+				state.Services[serviceName] = container.Image
+			}
+		}
+
+		return state, nil
+	}
+
+	state.Secrets = make(map[string]bool)
+	secrets, err := dockerClient.SecretList(context.Background(), types.SecretListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, secret := range secrets {
+		state.Secrets[secret.Spec.Name] = true
+	}
+
+	state.Configs = make(map[string]bool)
+	configs, err := dockerClient.ConfigList(context.Background(), types.ConfigListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, config := range configs {
+		state.Configs[config.Spec.Name] = true
+	}
+
+	if stackName == "" {
+		return state, nil
+	}
+
+	services, err := dockerClient.ServiceList(context.Background(), types.ServiceListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", "com.docker.stack.namespace="+stackName)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, service := range services {
+		if service.Spec.TaskTemplate.ContainerSpec == nil {
+			continue
+		}
+
+		serviceName := strings.TrimPrefix(service.Spec.Name, stackName+"_")
+		state.Services[serviceName] = service.Spec.TaskTemplate.ContainerSpec.Image
+	}
+
+	return state, nil
+}
+
+// retrieveGitStackFile clones the git repository in a temporary folder and returns the content of the stack file
+func (handler *Handler) retrieveGitStackFile(repositoryURL, referenceName, stackFilePath string, auth bool, username, password string) ([]byte, error) {
+	if !auth {
+		username = ""
+		password = ""
+	}
+
+	tmpDir, err := ioutil.TempDir("", "stack-validate")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = handler.GitService.CloneRepository(tmpDir, repositoryURL, referenceName, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	stackFilePath, err = filesystem.JoinPaths(tmpDir, stackFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(stackFilePath)
+}
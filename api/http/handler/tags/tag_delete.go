@@ -87,9 +87,14 @@ func (handler *Handler) tagDelete(w http.ResponseWriter, r *http.Request) *httpe
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve edge stacks from the database", err}
 	}
 
+	membership, err := edge.LoadEdgeGroupMembership(handler.DataStore)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoint groups and tags from the database", err}
+	}
+
 	for _, endpoint := range endpoints {
 		if (tag.Endpoints[endpoint.ID] || tag.EndpointGroups[endpoint.GroupID]) && (endpoint.Type == portainer.EdgeAgentOnDockerEnvironment || endpoint.Type == portainer.EdgeAgentOnKubernetesEnvironment) {
-			err = handler.updateEndpointRelations(endpoint, edgeGroups, edgeStacks)
+			_, err = membership.UpdateEndpointRelation(handler.DataStore, &endpoint, edgeGroups, edgeStacks)
 			if err != nil {
 				return &httperror.HandlerError{http.StatusInternalServerError, "Unable to update endpoint relations in the database", err}
 			}
@@ -116,27 +121,6 @@ func (handler *Handler) tagDelete(w http.ResponseWriter, r *http.Request) *httpe
 	return response.Empty(w)
 }
 
-func (handler *Handler) updateEndpointRelations(endpoint portainer.Endpoint, edgeGroups []portainer.EdgeGroup, edgeStacks []portainer.EdgeStack) error {
-	endpointRelation, err := handler.DataStore.EndpointRelation().EndpointRelation(endpoint.ID)
-	if err != nil {
-		return err
-	}
-
-	endpointGroup, err := handler.DataStore.EndpointGroup().EndpointGroup(endpoint.GroupID)
-	if err != nil {
-		return err
-	}
-
-	endpointStacks := edge.EndpointRelatedEdgeStacks(&endpoint, endpointGroup, edgeGroups, edgeStacks)
-	stacksSet := map[portainer.EdgeStackID]bool{}
-	for _, edgeStackID := range endpointStacks {
-		stacksSet[edgeStackID] = true
-	}
-	endpointRelation.EdgeStacks = stacksSet
-
-	return handler.DataStore.EndpointRelation().UpdateEndpointRelation(endpoint.ID, endpointRelation)
-}
-
 func findTagIndex(tags []portainer.TagID, searchTagID portainer.TagID) int {
 	for idx, tagID := range tags {
 		if searchTagID == tagID {
@@ -0,0 +1,28 @@
+package tunnels
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+// Handler is the HTTP handler used to manage the reverse tunnels of the Edge endpoints.
+type Handler struct {
+	*mux.Router
+	ReverseTunnelService portainer.ReverseTunnelService
+}
+
+// NewHandler creates a handler to manage the reverse tunnels of the Edge endpoints.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+	h.Handle("/edge/tunnels",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.tunnelList))).Methods(http.MethodGet)
+	h.Handle("/edge/tunnels/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.tunnelClose))).Methods(http.MethodDelete)
+	return h
+}
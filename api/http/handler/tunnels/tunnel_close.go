@@ -0,0 +1,49 @@
+package tunnels
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+)
+
+// @id EdgeTunnelClose
+// @summary Close the reverse tunnel of an Edge endpoint
+// @description Revoke the credentials of the tunnel and close the session of the Edge agent.
+// @description **Access policy**: administrator
+// @tags edge
+// @security jwt
+// @param id path int true "Endpoint identifier"
+// @success 204
+// @failure 400
+// @failure 404 "No tunnel is open for this endpoint"
+// @failure 500
+// @router /edge/tunnels/{id} [delete]
+func (handler *Handler) tunnelClose(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	found := false
+	for _, tunnel := range handler.ReverseTunnelService.TunnelStatistics() {
+		if tunnel.EndpointID == portainer.EndpointID(endpointID) {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return &httperror.HandlerError{http.StatusNotFound, "No tunnel is open for this endpoint", errors.New("No tunnel is open for this endpoint")}
+	}
+
+	err = handler.ReverseTunnelService.CloseTunnel(portainer.EndpointID(endpointID))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to close the tunnel", err}
+	}
+
+	return response.Empty(w)
+}
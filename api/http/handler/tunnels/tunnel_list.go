@@ -0,0 +1,40 @@
+package tunnels
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+)
+
+type tunnelListResponse struct {
+	// State and traffic of the tunnels
+	Tunnels []portainer.TunnelStatistics
+	// Number of tunnels for each status
+	Counts map[string]int `example:"ACTIVE:2,IDLE:10,REQUIRED:1"`
+}
+
+// @id EdgeTunnelList
+// @summary List the reverse tunnels of the Edge endpoints
+// @description List the tunnels known by the tunnel server with their status, uptime, traffic and proxied requests in flight.
+// @description **Access policy**: administrator
+// @tags edge
+// @security jwt
+// @produce json
+// @success 200 {object} tunnelListResponse
+// @router /edge/tunnels [get]
+func (handler *Handler) tunnelList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	tunnels := handler.ReverseTunnelService.TunnelStatistics()
+
+	counts := map[string]int{
+		portainer.EdgeAgentIdle:               0,
+		portainer.EdgeAgentManagementRequired: 0,
+		portainer.EdgeAgentActive:             0,
+	}
+	for _, tunnel := range tunnels {
+		counts[tunnel.Status]++
+	}
+
+	return response.JSON(w, tunnelListResponse{Tunnels: tunnels, Counts: counts})
+}
@@ -1,7 +1,6 @@
 package azure
 
 import (
-	"log"
 	"net/http"
 
 	portainer "github.com/portainer/portainer/api"
@@ -65,7 +64,7 @@ func (transport *Transport) createPrivateResourceControl(
 
 	err := transport.dataStore.ResourceControl().CreateResourceControl(resourceControl)
 	if err != nil {
-		log.Printf("[ERROR] [http,proxy,azure,transport] [message: unable to persist resource control] [resource: %s] [err: %s]", resourceIdentifier, err)
+		transport.logger().WithField("resource", resourceIdentifier).WithError(err).Error("unable to persist resource control")
 		return nil, err
 	}
 
@@ -100,7 +99,7 @@ func (transport *Transport) decorateContainerGroup(containerGroup map[string]int
 			containerGroup = decorateObject(containerGroup, resourceControl)
 		}
 	} else {
-		log.Printf("[WARN] [http,proxy,azure,decorate] [message: unable to find resource id property in container group]")
+		transport.logger().Warn("unable to find resource id property in container group")
 	}
 
 	return containerGroup
@@ -137,7 +136,7 @@ func (transport *Transport) removeResourceControl(containerGroup map[string]inte
 			return err
 		}
 	} else {
-		log.Printf("[WARN] [http,proxy,azure] [message: missign ID in container group]")
+		transport.logger().Warn("missing ID in container group")
 	}
 
 	return nil
@@ -8,8 +8,12 @@ import (
 	"path"
 	"github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/http/client"
+	"github.com/portainer/portainer/api/internal/logging"
+	"github.com/sirupsen/logrus"
 )
 
+var logger = logging.Component("proxy,azure")
+
 type (
 	azureAPIToken struct {
 		value          string
@@ -33,6 +37,11 @@ type (
 	}
 )
 
+// logger returns a logger whose entries are tagged with the endpoint of the transport
+func (transport *Transport) logger() *logrus.Entry {
+	return logger.WithField(logging.FieldEndpointID, transport.endpoint.ID)
+}
+
 // NewTransport returns a pointer to a new instance of Transport that implements the HTTP Transport
 // interface for proxying requests to the Azure API.
 func NewTransport(credentials *portainer.AzureCredentials, dataStore portainer.DataStore, endpoint *portainer.Endpoint) *Transport {
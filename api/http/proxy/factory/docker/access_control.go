@@ -1,7 +1,6 @@
 package docker
 
 import (
-	"log"
 	"net/http"
 	"strings"
 
@@ -9,6 +8,8 @@ import (
 
 	"github.com/portainer/portainer/api/http/proxy/factory/responseutils"
 	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/labelfilter"
+	"github.com/sirupsen/logrus"
 
 	portainer "github.com/portainer/portainer/api"
 )
@@ -79,7 +80,7 @@ func (transport *Transport) newResourceControlFromPortainerLabels(labelsObject m
 		for _, name := range teamNames {
 			team, err := transport.dataStore.Team().TeamByName(name)
 			if err != nil {
-				log.Printf("[WARN] [http,proxy,docker] [message: unknown team name in access control label, ignoring access control rule for this team] [name: %s] [resource_id: %s]", name, resourceID)
+				transport.logger().WithFields(logrus.Fields{"name": name, "resource_id": resourceID}).Warn("unknown team name in access control label, ignoring access control rule for this team")
 				continue
 			}
 
@@ -89,7 +90,7 @@ func (transport *Transport) newResourceControlFromPortainerLabels(labelsObject m
 		for _, name := range userNames {
 			user, err := transport.dataStore.User().UserByUsername(name)
 			if err != nil {
-				log.Printf("[WARN] [http,proxy,docker] [message: unknown user name in access control label, ignoring access control rule for this user] [name: %s] [resource_id: %s]", name, resourceID)
+				transport.logger().WithFields(logrus.Fields{"name": name, "resource_id": resourceID}).Warn("unknown user name in access control label, ignoring access control rule for this user")
 				continue
 			}
 
@@ -114,7 +115,7 @@ func (transport *Transport) createPrivateResourceControl(resourceIdentifier stri
 
 	err := transport.dataStore.ResourceControl().CreateResourceControl(resourceControl)
 	if err != nil {
-		log.Printf("[ERROR] [http,proxy,docker,transport] [message: unable to persist resource control] [resource: %s] [err: %s]", resourceIdentifier, err)
+		transport.logger().WithField("resource", resourceIdentifier).WithError(err).Error("unable to persist resource control")
 		return nil, err
 	}
 
@@ -154,7 +155,7 @@ func (transport *Transport) getInheritedResourceControlFromServiceOrStack(resour
 
 func (transport *Transport) applyAccessControlOnResource(parameters *resourceOperationParameters, responseObject map[string]interface{}, response *http.Response, executor *operationExecutor) error {
 	if responseObject[parameters.resourceIdentifierAttribute] == nil {
-		log.Printf("[WARN] [message: unable to find resource identifier property in resource object] [identifier_attribute: %s]", parameters.resourceIdentifierAttribute)
+		transport.logger().WithField("identifier_attribute", parameters.resourceIdentifierAttribute).Warn("unable to find resource identifier property in resource object")
 		return nil
 	}
 
@@ -201,7 +202,7 @@ func (transport *Transport) decorateResourceList(parameters *resourceOperationPa
 		resourceObject := resource.(map[string]interface{})
 
 		if resourceObject[parameters.resourceIdentifierAttribute] == nil {
-			log.Printf("[WARN] [http,proxy,docker,decorate] [message: unable to find resource identifier property in resource list element] [identifier_attribute: %s]", parameters.resourceIdentifierAttribute)
+			transport.logger().WithField("identifier_attribute", parameters.resourceIdentifierAttribute).Warn("unable to find resource identifier property in resource list element")
 			continue
 		}
 
@@ -238,7 +239,7 @@ func (transport *Transport) filterResourceList(parameters *resourceOperationPara
 	for _, resource := range resourceData {
 		resourceObject := resource.(map[string]interface{})
 		if resourceObject[parameters.resourceIdentifierAttribute] == nil {
-			log.Printf("[WARN] [http,proxy,docker,filter] [message: unable to find resource identifier property in resource list element] [identifier_attribute: %s]", parameters.resourceIdentifierAttribute)
+			transport.logger().WithField("identifier_attribute", parameters.resourceIdentifierAttribute).Warn("unable to find resource identifier property in resource list element")
 			continue
 		}
 
@@ -275,6 +276,29 @@ func (transport *Transport) filterResourceList(parameters *resourceOperationPara
 	return filteredResourceData, nil
 }
 
+// filterResourcesWithLabelFilters loops through a list of resources and removes the resources
+// with at least one label matching one of the label filters.
+func filterResourcesWithLabelFilters(resourceData []interface{}, labelsObjectSelector resourceLabelsObjectSelector, labelFilters []*labelfilter.Filter) []interface{} {
+	if len(labelFilters) == 0 {
+		return resourceData
+	}
+
+	filteredResourceData := make([]interface{}, 0)
+
+	for _, resource := range resourceData {
+		resourceObject := resource.(map[string]interface{})
+
+		resourceLabelsObject := labelsObjectSelector(resourceObject)
+		if resourceLabelsObject != nil && labelfilter.MatchesAny(labelFilters, resourceLabelsObject) {
+			continue
+		}
+
+		filteredResourceData = append(filteredResourceData, resourceObject)
+	}
+
+	return filteredResourceData
+}
+
 func (transport *Transport) findResourceControl(resourceIdentifier string, resourceType portainer.ResourceControlType, resourceLabelsObject map[string]interface{}, resourceControls []portainer.ResourceControl) (*portainer.ResourceControl, error) {
 	resourceControl := authorization.GetResourceControlByResourceIDAndType(resourceIdentifier, resourceType, resourceControls)
 	if resourceControl != nil {
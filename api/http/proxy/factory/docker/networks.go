@@ -54,6 +54,8 @@ func (transport *Transport) networkListOperation(response *http.Response, execut
 		return err
 	}
 
+	responseArray = filterResourcesWithLabelFilters(responseArray, selectorNetworkLabels, executor.labelFilters)
+
 	return responseutils.RewriteResponse(response, responseArray, http.StatusOK)
 }
 
@@ -55,6 +55,8 @@ func (transport *Transport) serviceListOperation(response *http.Response, execut
 		return err
 	}
 
+	responseArray = filterResourcesWithLabelFilters(responseArray, selectorServiceLabels, executor.labelFilters)
+
 	return responseutils.RewriteResponse(response, responseArray, http.StatusOK)
 }
 
@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"errors"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"path"
 	"regexp"
@@ -18,10 +17,15 @@ import (
 	"github.com/portainer/portainer/api/http/proxy/factory/responseutils"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/labelfilter"
+	"github.com/portainer/portainer/api/internal/logging"
+	"github.com/sirupsen/logrus"
 )
 
 var apiVersionRe = regexp.MustCompile(`(/v[0-9]\.[0-9]*)?`)
 
+var logger = logging.Component("proxy,docker")
+
 type (
 	// Transport is a custom transport for Docker API reverse proxy. It allows
 	// interception of requests and rewriting of responses.
@@ -53,12 +57,17 @@ type (
 
 	operationExecutor struct {
 		operationContext *restrictedDockerOperationContext
-		labelBlackList   []portainer.Pair
+		labelFilters     []*labelfilter.Filter
 	}
 	restrictedOperationRequest func(*http.Response, *operationExecutor) error
 	operationRequest           func(*http.Request) error
 )
 
+// logger returns a logger whose entries are tagged with the endpoint of the transport
+func (transport *Transport) logger() *logrus.Entry {
+	return logger.WithField(logging.FieldEndpointID, transport.endpoint.ID)
+}
+
 // NewTransport returns a pointer to a new Transport instance.
 func NewTransport(parameters *TransportParameters, httpTransport *http.Transport) (*Transport, error) {
 	dockerClient, err := parameters.DockerClientFactory.CreateClient(parameters.Endpoint, "")
@@ -216,7 +225,7 @@ func (transport *Transport) proxyContainerRequest(request *http.Request) (*http.
 		return transport.administratorOperation(request)
 
 	case "/containers/json":
-		return transport.rewriteOperationWithLabelFiltering(request, transport.containerListOperation)
+		return transport.rewriteOperationWithLabelFiltering(request, transport.containerListOperation, portainer.ContainerResourceControl)
 
 	default:
 		// This section assumes /containers/**
@@ -249,7 +258,7 @@ func (transport *Transport) proxyServiceRequest(request *http.Request) (*http.Re
 		return transport.decorateServiceCreationOperation(request)
 
 	case "/services":
-		return transport.rewriteOperation(request, transport.serviceListOperation)
+		return transport.rewriteOperationWithLabelFiltering(request, transport.serviceListOperation, portainer.ServiceResourceControl)
 
 	default:
 		// This section assumes /services/**
@@ -282,7 +291,7 @@ func (transport *Transport) proxyVolumeRequest(request *http.Request) (*http.Res
 		return transport.administratorOperation(request)
 
 	case "/volumes":
-		return transport.rewriteOperation(request, transport.volumeListOperation)
+		return transport.rewriteOperationWithLabelFiltering(request, transport.volumeListOperation, portainer.VolumeResourceControl)
 
 	default:
 		// assume /volumes/{name}
@@ -296,7 +305,7 @@ func (transport *Transport) proxyNetworkRequest(request *http.Request) (*http.Re
 		return transport.decorateGenericResourceCreationOperation(request, networkObjectIdentifier, portainer.NetworkResourceControl)
 
 	case "/networks":
-		return transport.rewriteOperation(request, transport.networkListOperation)
+		return transport.rewriteOperationWithLabelFiltering(request, transport.networkListOperation, portainer.NetworkResourceControl)
 
 	default:
 		// assume /networks/{id}
@@ -474,9 +483,9 @@ func (transport *Transport) restrictedResourceOperation(request *http.Request, r
 }
 
 // rewriteOperationWithLabelFiltering will create a new operation context with data that will be used
-// to decorate the original request's response as well as retrieve all the black listed labels
-// to filter the resources.
-func (transport *Transport) rewriteOperationWithLabelFiltering(request *http.Request, operation restrictedOperationRequest) (*http.Response, error) {
+// to decorate the original request's response as well as compile all the label filters applying
+// to the endpoint group and resource type to filter the resources.
+func (transport *Transport) rewriteOperationWithLabelFiltering(request *http.Request, operation restrictedOperationRequest, resourceType portainer.ResourceControlType) (*http.Response, error) {
 	operationContext, err := transport.createOperationContext(request)
 	if err != nil {
 		return nil, err
@@ -489,7 +498,7 @@ func (transport *Transport) rewriteOperationWithLabelFiltering(request *http.Req
 
 	executor := &operationExecutor{
 		operationContext: operationContext,
-		labelBlackList:   settings.BlackListedLabels,
+		labelFilters:     labelfilter.CompileAll(settings, transport.endpoint.GroupID, resourceType),
 	}
 
 	return transport.executeRequestAndRewriteResponse(request, operation, executor)
@@ -536,7 +545,7 @@ func (transport *Transport) decorateGenericResourceCreationResponse(response *ht
 	}
 
 	if responseObject[resourceIdentifierAttribute] == nil {
-		log.Printf("[ERROR] [proxy,docker]")
+		transport.logger().WithField("identifier_attribute", resourceIdentifierAttribute).Error("missing identifier in Docker resource creation response")
 		return errors.New("missing identifier in Docker resource creation response")
 	}
 
@@ -64,6 +64,8 @@ func (transport *Transport) volumeListOperation(response *http.Response, executo
 		if err != nil {
 			return err
 		}
+
+		volumeData = filterResourcesWithLabelFilters(volumeData, selectorVolumeLabels, executor.labelFilters)
 		// Overwrite the original volume list
 		responseObject["Volumes"] = volumeData
 	}
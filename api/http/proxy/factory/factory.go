@@ -7,6 +7,7 @@ import (
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/http/proxy/factory/kubernetes"
+	"github.com/portainer/portainer/api/internal/metrics"
 
 	"github.com/portainer/portainer/api/kubernetes/cli"
 
@@ -51,8 +52,35 @@ func (factory *ProxyFactory) NewLegacyExtensionProxy(extensionAPIURL string) (ht
 	return proxy, nil
 }
 
-// NewEndpointProxy returns a new reverse proxy (filesystem based or HTTP) to an endpoint API server
+// NewEndpointProxy returns a new reverse proxy (filesystem based or HTTP) to an endpoint API server.
+// The proxied requests are counted in the metrics and the traffic of the proxies to Edge endpoints
+// is accounted on their reverse tunnel.
 func (factory *ProxyFactory) NewEndpointProxy(endpoint *portainer.Endpoint) (http.Handler, error) {
+	proxy, err := factory.newEndpointProxy(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint.Type == portainer.EdgeAgentOnDockerEnvironment || endpoint.Type == portainer.EdgeAgentOnKubernetesEnvironment {
+		proxy = factory.reverseTunnelService.TrackTunnelTraffic(endpoint.ID, proxy)
+	}
+
+	return metrics.InstrumentProxy(int(endpoint.ID), proxyType(endpoint), proxy), nil
+}
+
+// proxyType returns the type of the proxy of an endpoint reported in the metrics
+func proxyType(endpoint *portainer.Endpoint) string {
+	switch endpoint.Type {
+	case portainer.AzureEnvironment:
+		return "azure"
+	case portainer.EdgeAgentOnKubernetesEnvironment, portainer.AgentOnKubernetesEnvironment, portainer.KubernetesLocalEnvironment:
+		return "kubernetes"
+	}
+
+	return "docker"
+}
+
+func (factory *ProxyFactory) newEndpointProxy(endpoint *portainer.Endpoint) (http.Handler, error) {
 	switch endpoint.Type {
 	case portainer.AzureEnvironment:
 		return newAzureProxy(endpoint, factory.dataStore)
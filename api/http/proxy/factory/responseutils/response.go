@@ -6,11 +6,14 @@ import (
 	"encoding/json"
 	"errors"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"strconv"
+
+	"github.com/portainer/portainer/api/internal/logging"
 )
 
+var logger = logging.Component("proxy,response")
+
 // GetResponseAsJSONObject returns the response content as a generic JSON object
 func GetResponseAsJSONObject(response *http.Response) (map[string]interface{}, error) {
 	responseData, err := getResponseBodyAsGenericJSON(response)
@@ -36,10 +39,10 @@ func GetResponseAsJSONArray(response *http.Response) ([]interface{}, error) {
 		if responseObject["message"] != nil {
 			return nil, errors.New(responseObject["message"].(string))
 		}
-		log.Printf("[ERROR] [http,proxy,response] [message: invalid response format, expecting JSON array] [response: %+v]", responseObject)
+		logger.WithField("response", responseObject).Error("invalid response format, expecting JSON array")
 		return nil, errors.New("unable to parse response: expected JSON array, got JSON object")
 	default:
-		log.Printf("[ERROR] [http,proxy,response] [message: invalid response format, expecting JSON array] [response: %+v]", responseObject)
+		logger.WithField("response", responseObject).Error("invalid response format, expecting JSON array")
 		return nil, errors.New("unable to parse response: expected JSON array")
 	}
 }
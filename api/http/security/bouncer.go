@@ -2,8 +2,10 @@ package security
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	httperror "github.com/portainer/libhttp/error"
 	portainer "github.com/portainer/portainer/api"
@@ -14,8 +16,9 @@ import (
 type (
 	// RequestBouncer represents an entity that manages API request accesses
 	RequestBouncer struct {
-		dataStore  portainer.DataStore
-		jwtService portainer.JWTService
+		dataStore           portainer.DataStore
+		jwtService          portainer.JWTService
+		notificationService portainer.NotificationService
 	}
 
 	// RestrictedRequestContext is a data structure containing information
@@ -29,10 +32,11 @@ type (
 )
 
 // NewRequestBouncer initializes a new RequestBouncer
-func NewRequestBouncer(dataStore portainer.DataStore, jwtService portainer.JWTService) *RequestBouncer {
+func NewRequestBouncer(dataStore portainer.DataStore, jwtService portainer.JWTService, notificationService portainer.NotificationService) *RequestBouncer {
 	return &RequestBouncer{
-		dataStore:  dataStore,
-		jwtService: jwtService,
+		dataStore:           dataStore,
+		jwtService:          jwtService,
+		notificationService: notificationService,
 	}
 }
 
@@ -49,7 +53,9 @@ func (bouncer *RequestBouncer) PublicAccess(h http.Handler) http.Handler {
 // The request context will be enhanced with a RestrictedRequestContext object
 // that might be used later to inside the API operation for extra authorization validation
 // and resource filtering.
+// The changes made through these endpoints are audited with an audit.admin_action event.
 func (bouncer *RequestBouncer) AdminAccess(h http.Handler) http.Handler {
+	h = bouncer.mwAuditAdminAction(h)
 	h = bouncer.mwUpgradeToRestrictedRequest(h)
 	h = bouncer.mwCheckPortainerAuthorizations(h, true)
 	h = bouncer.mwAuthenticatedUser(h)
@@ -319,3 +325,60 @@ func (bouncer *RequestBouncer) EdgeComputeOperation(next http.Handler) http.Hand
 		next.ServeHTTP(w, r)
 	})
 }
+
+// mwAuditAdminAction sends an audit.admin_action event for each change successfully made through an administrator endpoint
+func (bouncer *RequestBouncer) mwAuditAdminAction(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if bouncer.notificationService == nil || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status >= http.StatusBadRequest {
+			return
+		}
+
+		event := portainer.NotificationEvent{
+			Type:    portainer.NotificationEventAdminAction,
+			Time:    time.Now().Unix(),
+			Message: fmt.Sprintf("Administrator action %s %s", r.Method, r.URL.Path),
+			Details: map[string]string{"method": r.Method, "path": r.URL.Path},
+		}
+
+		if tokenData, err := RetrieveTokenData(r); err == nil {
+			event.Message = fmt.Sprintf("Administrator action %s %s by %s", r.Method, r.URL.Path, tokenData.Username)
+			event.Details["user"] = tokenData.Username
+		}
+
+		bouncer.notificationService.Notify(event)
+	})
+}
+
+// statusRecorder records the status code of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	if !recorder.wroteHeader {
+		recorder.status = status
+		recorder.wroteHeader = true
+	}
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Write(b []byte) (int, error) {
+	recorder.wroteHeader = true
+	return recorder.ResponseWriter.Write(b)
+}
+
+func (recorder *statusRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
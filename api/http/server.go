@@ -2,8 +2,6 @@ package http
 
 import (
 	"context"
-	"fmt"
-	"log"
 	"net/http"
 	"path/filepath"
 	"time"
@@ -17,6 +15,7 @@ import (
 	"github.com/portainer/portainer/api/http/handler/backup"
 	"github.com/portainer/portainer/api/http/handler/customtemplates"
 	"github.com/portainer/portainer/api/http/handler/dockerhub"
+	"github.com/portainer/portainer/api/http/handler/edgeenrollment"
 	"github.com/portainer/portainer/api/http/handler/edgegroups"
 	"github.com/portainer/portainer/api/http/handler/edgejobs"
 	"github.com/portainer/portainer/api/http/handler/edgestacks"
@@ -26,7 +25,13 @@ import (
 	"github.com/portainer/portainer/api/http/handler/endpointproxy"
 	"github.com/portainer/portainer/api/http/handler/endpoints"
 	"github.com/portainer/portainer/api/http/handler/file"
+	"github.com/portainer/portainer/api/http/handler/fleet"
+	"github.com/portainer/portainer/api/http/handler/images"
+	"github.com/portainer/portainer/api/http/handler/jobs"
+	"github.com/portainer/portainer/api/http/handler/logs"
+	"github.com/portainer/portainer/api/http/handler/metrics"
 	"github.com/portainer/portainer/api/http/handler/motd"
+	"github.com/portainer/portainer/api/http/handler/notifications"
 	"github.com/portainer/portainer/api/http/handler/registries"
 	"github.com/portainer/portainer/api/http/handler/resourcecontrols"
 	"github.com/portainer/portainer/api/http/handler/roles"
@@ -37,6 +42,7 @@ import (
 	"github.com/portainer/portainer/api/http/handler/teammemberships"
 	"github.com/portainer/portainer/api/http/handler/teams"
 	"github.com/portainer/portainer/api/http/handler/templates"
+	"github.com/portainer/portainer/api/http/handler/tunnels"
 	"github.com/portainer/portainer/api/http/handler/upload"
 	"github.com/portainer/portainer/api/http/handler/users"
 	"github.com/portainer/portainer/api/http/handler/webhooks"
@@ -46,9 +52,18 @@ import (
 	"github.com/portainer/portainer/api/http/proxy/factory/kubernetes"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/deploymentjob"
+	"github.com/portainer/portainer/api/internal/edgefleet"
+	"github.com/portainer/portainer/api/internal/edgerollout"
+	"github.com/portainer/portainer/api/internal/edgestackgit"
+	"github.com/portainer/portainer/api/internal/logging"
+	metricsregistry "github.com/portainer/portainer/api/internal/metrics"
+	"github.com/portainer/portainer/api/internal/requestid"
 	"github.com/portainer/portainer/api/kubernetes/cli"
 )
 
+var logger = logging.Component("http")
+
 // Server implements the portainer.Server interface
 type Server struct {
 	AuthorizationService 		*authorization.Service
@@ -59,15 +74,19 @@ type Server struct {
 	ComposeStackManager         portainer.ComposeStackManager
 	CryptoService               portainer.CryptoService
 	SignatureService            portainer.DigitalSignatureService
+	SecretService               portainer.SecretService
 	SnapshotService             portainer.SnapshotService
+	VulnerabilityService        portainer.VulnerabilityService
 	FileService                 portainer.FileService
 	DataStore                   portainer.DataStore
 	GitService                  portainer.GitService
 	JWTService                  portainer.JWTService
 	LDAPService                 portainer.LDAPService
+	NotificationService         portainer.NotificationService
 	OAuthService                portainer.OAuthService
 	SwarmStackManager           portainer.SwarmStackManager
 	ProxyManager                *proxy.Manager
+	RequestTracer               requestid.Tracer
 	KubernetesTokenCacheManager *kubernetes.TokenCacheManager
 	Handler                     *handler.Handler
 	SSL                         bool
@@ -84,7 +103,7 @@ type Server struct {
 func (server *Server) Start() error {
 	kubernetesTokenCacheManager := server.KubernetesTokenCacheManager
 
-	requestBouncer := security.NewRequestBouncer(server.DataStore, server.JWTService)
+	requestBouncer := security.NewRequestBouncer(server.DataStore, server.JWTService, server.NotificationService)
 
 	rateLimiter := security.NewRateLimiter(10, 1*time.Second, 1*time.Hour)
 	offlineGate := offlinegate.NewOfflineGate()
@@ -101,7 +120,7 @@ func (server *Server) Start() error {
 	adminMonitor := adminmonitor.New(5*time.Minute, server.DataStore, server.ShutdownCtx)
 	adminMonitor.Start()
 
-	var backupHandler = backup.NewHandler(requestBouncer, server.DataStore, offlineGate, server.FileService.GetDatastorePath(), server.ShutdownTrigger, adminMonitor)
+	var backupHandler = backup.NewHandler(requestBouncer, server.DataStore, offlineGate, server.FileService.GetDatastorePath(), server.ShutdownTrigger, adminMonitor, server.NotificationService)
 
 	var roleHandler = roles.NewHandler(requestBouncer)
 	roleHandler.DataStore = server.DataStore
@@ -117,15 +136,28 @@ func (server *Server) Start() error {
 	var edgeGroupsHandler = edgegroups.NewHandler(requestBouncer)
 	edgeGroupsHandler.DataStore = server.DataStore
 
+	var edgeEnrollmentHandler = edgeenrollment.NewHandler(requestBouncer)
+	edgeEnrollmentHandler.DataStore = server.DataStore
+	edgeEnrollmentHandler.ReverseTunnelService = server.ReverseTunnelService
+
 	var edgeJobsHandler = edgejobs.NewHandler(requestBouncer)
 	edgeJobsHandler.DataStore = server.DataStore
 	edgeJobsHandler.FileService = server.FileService
 	edgeJobsHandler.ReverseTunnelService = server.ReverseTunnelService
 
+	edgeStackGitService := edgestackgit.NewService(server.DataStore, server.FileService, server.GitService, server.SecretService, server.ShutdownCtx)
+	edgeStackGitService.Start()
+
 	var edgeStacksHandler = edgestacks.NewHandler(requestBouncer)
 	edgeStacksHandler.DataStore = server.DataStore
+	edgeStacksHandler.EdgeStackGitService = edgeStackGitService
 	edgeStacksHandler.FileService = server.FileService
 	edgeStacksHandler.GitService = server.GitService
+	edgeStacksHandler.NotificationService = server.NotificationService
+	edgeStacksHandler.SecretService = server.SecretService
+
+	edgeRolloutMonitor := edgerollout.NewMonitor(server.DataStore, server.ShutdownCtx)
+	edgeRolloutMonitor.Start()
 
 	var edgeTemplatesHandler = edgetemplates.NewHandler(requestBouncer)
 	edgeTemplatesHandler.DataStore = server.DataStore
@@ -144,6 +176,12 @@ func (server *Server) Start() error {
 	endpointEdgeHandler.FileService = server.FileService
 	endpointEdgeHandler.ReverseTunnelService = server.ReverseTunnelService
 
+	edgeFleetMonitor := edgefleet.NewMonitor(server.DataStore, server.NotificationService, server.ShutdownCtx)
+	edgeFleetMonitor.Start()
+
+	var fleetHandler = fleet.NewHandler(requestBouncer)
+	fleetHandler.DataStore = server.DataStore
+
 	var endpointGroupHandler = endpointgroups.NewHandler(requestBouncer)
 	endpointGroupHandler.AuthorizationService = server.AuthorizationService
 	endpointGroupHandler.DataStore = server.DataStore
@@ -155,8 +193,23 @@ func (server *Server) Start() error {
 
 	var fileHandler = file.NewHandler(filepath.Join(server.AssetsPath, "public"))
 
+	var imageHandler = images.NewHandler(requestBouncer)
+	imageHandler.DataStore = server.DataStore
+
+	var logHandler = logs.NewHandler(requestBouncer)
+	logHandler.DataStore = server.DataStore
+	logHandler.DockerClientFactory = server.DockerClientFactory
+
+	var metricsHandler = metrics.NewHandler(requestBouncer)
+	metricsHandler.DataStore = server.DataStore
+	metricsHandler.ReverseTunnelService = server.ReverseTunnelService
+
 	var motdHandler = motd.NewHandler(requestBouncer)
 
+	var notificationHandler = notifications.NewHandler(requestBouncer)
+	notificationHandler.DataStore = server.DataStore
+	notificationHandler.NotificationService = server.NotificationService
+
 	var registryHandler = registries.NewHandler(requestBouncer)
 	registryHandler.DataStore = server.DataStore
 	registryHandler.FileService = server.FileService
@@ -170,16 +223,25 @@ func (server *Server) Start() error {
 	settingsHandler.FileService = server.FileService
 	settingsHandler.JWTService = server.JWTService
 	settingsHandler.LDAPService = server.LDAPService
+	settingsHandler.NotificationService = server.NotificationService
 	settingsHandler.SnapshotService = server.SnapshotService
 
+	deploymentJobService := deploymentjob.NewService(24 * time.Hour)
+
+	var jobHandler = jobs.NewHandler(requestBouncer)
+	jobHandler.DeploymentJobService = deploymentJobService
+
 	var stackHandler = stacks.NewHandler(requestBouncer)
 	stackHandler.DataStore = server.DataStore
+	stackHandler.DeploymentJobService = deploymentJobService
 	stackHandler.DockerClientFactory = server.DockerClientFactory
 	stackHandler.FileService = server.FileService
 	stackHandler.SwarmStackManager = server.SwarmStackManager
 	stackHandler.ComposeStackManager = server.ComposeStackManager
 	stackHandler.KubernetesDeployer = server.KubernetesDeployer
 	stackHandler.GitService = server.GitService
+	stackHandler.VulnerabilityService = server.VulnerabilityService
+	stackHandler.ShutdownCtx = server.ShutdownCtx
 
 	var tagHandler = tags.NewHandler(requestBouncer)
 	tagHandler.DataStore = server.DataStore
@@ -197,6 +259,9 @@ func (server *Server) Start() error {
 	templatesHandler.FileService = server.FileService
 	templatesHandler.GitService = server.GitService
 
+	var tunnelsHandler = tunnels.NewHandler(requestBouncer)
+	tunnelsHandler.ReverseTunnelService = server.ReverseTunnelService
+
 	var uploadHandler = upload.NewHandler(requestBouncer)
 	uploadHandler.FileService = server.FileService
 
@@ -221,6 +286,7 @@ func (server *Server) Start() error {
 		CustomTemplatesHandler: customTemplatesHandler,
 		DockerHubHandler:       dockerHubHandler,
 		EdgeGroupsHandler:      edgeGroupsHandler,
+		EdgeEnrollmentHandler:  edgeEnrollmentHandler,
 		EdgeJobsHandler:        edgeJobsHandler,
 		EdgeStacksHandler:      edgeStacksHandler,
 		EdgeTemplatesHandler:   edgeTemplatesHandler,
@@ -229,7 +295,13 @@ func (server *Server) Start() error {
 		EndpointEdgeHandler:    endpointEdgeHandler,
 		EndpointProxyHandler:   endpointProxyHandler,
 		FileHandler:            fileHandler,
+		FleetHandler:           fleetHandler,
+		ImageHandler:           imageHandler,
+		JobHandler:             jobHandler,
+		LogHandler:             logHandler,
+		MetricsHandler:         metricsHandler,
 		MOTDHandler:            motdHandler,
+		NotificationHandler:    notificationHandler,
 		RegistryHandler:        registryHandler,
 		ResourceControlHandler: resourceControlHandler,
 		SettingsHandler:        settingsHandler,
@@ -239,6 +311,7 @@ func (server *Server) Start() error {
 		TeamHandler:            teamHandler,
 		TeamMembershipHandler:  teamMembershipHandler,
 		TemplatesHandler:       templatesHandler,
+		TunnelsHandler:         tunnelsHandler,
 		UploadHandler:          uploadHandler,
 		UserHandler:            userHandler,
 		WebSocketHandler:       websocketHandler,
@@ -249,6 +322,8 @@ func (server *Server) Start() error {
 		Addr:    server.BindAddress,
 		Handler: server.Handler,
 	}
+	httpServer.Handler = requestid.Middleware(httpServer.Handler, server.RequestTracer)
+	httpServer.Handler = metricsregistry.InstrumentHandler(httpServer.Handler)
 	httpServer.Handler = offlineGate.WaitingMiddleware(time.Minute, httpServer.Handler)
 
 	if server.SSL {
@@ -264,12 +339,12 @@ func (server *Server) Start() error {
 func (server *Server) shutdown(httpServer *http.Server) {
 	<-server.ShutdownCtx.Done()
 
-	log.Println("[DEBUG] Shutting down http server")
+	logger.Debug("shutting down http server")
 	shutdownTimeout, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	err := httpServer.Shutdown(shutdownTimeout)
 	if err != nil {
-		fmt.Printf("Failed shutdown http server: %s \n", err)
+		logger.WithError(err).Error("failed to shut down http server")
 	}
 }
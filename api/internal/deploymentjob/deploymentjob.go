@@ -0,0 +1,231 @@
+package deploymentjob
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// ErrJobNotFound is returned when a deployment job does not exist or was already purged
+var ErrJobNotFound = errors.New("Deployment job not found")
+
+// maxOutputLines is the maximum number of output lines kept for a single job
+const maxOutputLines = 10000
+
+const truncatedOutputLine = "[output truncated]"
+
+// Service keeps track of the stack deployment jobs and of their output.
+// Jobs are kept in memory and purged once completed for longer than the retention period.
+type Service struct {
+	mu        sync.Mutex
+	lastID    portainer.DeploymentJobID
+	jobs      map[portainer.DeploymentJobID]*job
+	retention time.Duration
+}
+
+type job struct {
+	data    portainer.DeploymentJob
+	err     error
+	partial []byte
+	changed chan struct{}
+	done    chan struct{}
+}
+
+// NewService initializes a new deployment job service
+func NewService(retention time.Duration) *Service {
+	return &Service{
+		jobs:      make(map[portainer.DeploymentJobID]*job),
+		retention: retention,
+	}
+}
+
+// Run registers a new job and executes the deploy function in the background.
+// Everything written by the deploy function to the output writer is recorded line by line.
+func (service *Service) Run(jobType portainer.DeploymentJobType, stackID portainer.StackID, endpointID portainer.EndpointID, createdBy string, deploy func(output io.Writer) error) *portainer.DeploymentJob {
+	service.mu.Lock()
+	service.purge()
+
+	service.lastID++
+	j := &job{
+		data: portainer.DeploymentJob{
+			ID:           service.lastID,
+			Type:         jobType,
+			StackID:      stackID,
+			EndpointID:   endpointID,
+			Status:       portainer.DeploymentJobPending,
+			Output:       make([]string, 0),
+			CreatedBy:    createdBy,
+			CreationDate: time.Now().Unix(),
+		},
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	service.jobs[j.data.ID] = j
+	snapshot := j.snapshot()
+	service.mu.Unlock()
+
+	go func() {
+		service.update(j, func() {
+			j.data.Status = portainer.DeploymentJobRunning
+		})
+
+		err := deploy(&jobWriter{service: service, job: j})
+
+		service.update(j, func() {
+			j.flush()
+			j.err = err
+			j.data.EndDate = time.Now().Unix()
+			j.data.Status = portainer.DeploymentJobSuccess
+			if err != nil {
+				j.data.Status = portainer.DeploymentJobError
+				j.data.Error = err.Error()
+			}
+		})
+
+		close(j.done)
+	}()
+
+	return snapshot
+}
+
+// Job returns a copy of the job associated to the specified identifier
+func (service *Service) Job(ID portainer.DeploymentJobID) (*portainer.DeploymentJob, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	j, ok := service.jobs[ID]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	return j.snapshot(), nil
+}
+
+// Wait blocks until the job is completed and returns the error returned by its deploy function
+func (service *Service) Wait(ID portainer.DeploymentJobID) error {
+	service.mu.Lock()
+	j, ok := service.jobs[ID]
+	service.mu.Unlock()
+
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	<-j.done
+	return j.err
+}
+
+// Follow returns the output lines of the job starting at the specified offset, the current job status
+// and a channel that is closed as soon as the job output or status changes.
+func (service *Service) Follow(ID portainer.DeploymentJobID, offset int) ([]string, portainer.DeploymentJobStatus, <-chan struct{}, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	j, ok := service.jobs[ID]
+	if !ok {
+		return nil, 0, nil, ErrJobNotFound
+	}
+
+	lines := make([]string, 0)
+	if offset < len(j.data.Output) {
+		lines = append(lines, j.data.Output[offset:]...)
+	}
+
+	return lines, j.data.Status, j.changed, nil
+}
+
+// IsRunning returns true when a job of the stack is not completed yet
+func (service *Service) IsRunning(stackID portainer.StackID) bool {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	for _, j := range service.jobs {
+		if j.data.StackID == stackID && !IsCompleted(j.data.Status) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsCompleted returns true if the status is a final job status
+func IsCompleted(status portainer.DeploymentJobStatus) bool {
+	return status == portainer.DeploymentJobSuccess || status == portainer.DeploymentJobError
+}
+
+func (service *Service) update(j *job, fn func()) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	fn()
+
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// purge must be called with the service lock held
+func (service *Service) purge() {
+	threshold := time.Now().Add(-service.retention).Unix()
+
+	for id, j := range service.jobs {
+		if IsCompleted(j.data.Status) && j.data.EndDate < threshold {
+			delete(service.jobs, id)
+		}
+	}
+}
+
+func (j *job) snapshot() *portainer.DeploymentJob {
+	data := j.data
+	data.Output = append(make([]string, 0, len(j.data.Output)), j.data.Output...)
+	return &data
+}
+
+func (j *job) appendLine(line []byte) {
+	if len(j.data.Output) > maxOutputLines {
+		return
+	}
+
+	if len(j.data.Output) == maxOutputLines {
+		j.data.Output = append(j.data.Output, truncatedOutputLine)
+		return
+	}
+
+	j.data.Output = append(j.data.Output, string(bytes.TrimRight(line, "\r")))
+}
+
+func (j *job) flush() {
+	if len(j.partial) > 0 {
+		j.appendLine(j.partial)
+		j.partial = nil
+	}
+}
+
+type jobWriter struct {
+	service *Service
+	job     *job
+}
+
+// Write records every complete line of p, incomplete lines are buffered until the next write
+func (w *jobWriter) Write(p []byte) (int, error) {
+	w.service.update(w.job, func() {
+		data := append(w.job.partial, p...)
+
+		for {
+			index := bytes.IndexByte(data, '\n')
+			if index < 0 {
+				break
+			}
+
+			w.job.appendLine(data[:index])
+			data = data[index+1:]
+		}
+
+		w.job.partial = append([]byte(nil), data...)
+	})
+
+	return len(p), nil
+}
@@ -0,0 +1,116 @@
+package deploymentjob
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Run_RecordsOutputLineByLine(t *testing.T) {
+	service := NewService(time.Hour)
+
+	job := service.Run(portainer.DeploymentJobCreate, 1, 2, "admin", func(output io.Writer) error {
+		fmt.Fprint(output, "Creating network ")
+		fmt.Fprint(output, "default\r\nCreating web ... ")
+		fmt.Fprint(output, "done")
+		return nil
+	})
+	assert.Equal(t, portainer.StackID(1), job.StackID)
+	assert.Equal(t, portainer.EndpointID(2), job.EndpointID)
+
+	err := service.Wait(job.ID)
+	assert.NoError(t, err)
+
+	completed, err := service.Job(job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, portainer.DeploymentJobSuccess, completed.Status)
+	assert.Equal(t, []string{"Creating network default", "Creating web ... done"}, completed.Output)
+	assert.NotEqual(t, int64(0), completed.EndDate)
+}
+
+func Test_Run_RecordsError(t *testing.T) {
+	service := NewService(time.Hour)
+
+	job := service.Run(portainer.DeploymentJobUpdate, 1, 1, "admin", func(output io.Writer) error {
+		return errors.New("network not found")
+	})
+
+	err := service.Wait(job.ID)
+	assert.Error(t, err)
+
+	completed, err := service.Job(job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, portainer.DeploymentJobError, completed.Status)
+	assert.Equal(t, "network not found", completed.Error)
+}
+
+func Test_Follow_ReturnsLinesFromOffset(t *testing.T) {
+	service := NewService(time.Hour)
+
+	release := make(chan struct{})
+	job := service.Run(portainer.DeploymentJobCreate, 1, 1, "admin", func(output io.Writer) error {
+		fmt.Fprintln(output, "first")
+		<-release
+		fmt.Fprintln(output, "second")
+		return nil
+	})
+
+	var lines []string
+	for len(lines) == 0 {
+		var changed <-chan struct{}
+		var err error
+		lines, _, changed, err = service.Follow(job.ID, 0)
+		assert.NoError(t, err)
+		if len(lines) == 0 {
+			<-changed
+		}
+	}
+	assert.Equal(t, []string{"first"}, lines)
+
+	close(release)
+	service.Wait(job.ID)
+
+	lines, status, _, err := service.Follow(job.ID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"second"}, lines)
+	assert.True(t, IsCompleted(status))
+}
+
+func Test_Run_PurgesExpiredJobs(t *testing.T) {
+	service := NewService(-time.Second)
+
+	job := service.Run(portainer.DeploymentJobCreate, 1, 1, "admin", func(output io.Writer) error {
+		return nil
+	})
+	service.Wait(job.ID)
+
+	service.Run(portainer.DeploymentJobCreate, 2, 1, "admin", func(output io.Writer) error {
+		return nil
+	})
+
+	_, err := service.Job(job.ID)
+	assert.Equal(t, ErrJobNotFound, err)
+}
+
+func Test_IsRunning(t *testing.T) {
+	service := NewService(time.Hour)
+
+	release := make(chan struct{})
+	job := service.Run(portainer.DeploymentJobCreate, 1, 1, "admin", func(output io.Writer) error {
+		<-release
+		return nil
+	})
+
+	assert.True(t, service.IsRunning(1))
+	assert.False(t, service.IsRunning(2))
+
+	close(release)
+	err := service.Wait(job.ID)
+	assert.NoError(t, err)
+	assert.False(t, service.IsRunning(1))
+}
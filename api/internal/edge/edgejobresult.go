@@ -0,0 +1,173 @@
+package edge
+
+import (
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+const truncatedOutputPrefix = "[output truncated]\n"
+
+// EdgeJobRunSummary represents the outcome of a run of an Edge job across its endpoints
+type EdgeJobRunSummary struct {
+	// Number of the run
+	Run int `example:"3"`
+	// Number of endpoints on which the run succeeded
+	Succeeded int `example:"8"`
+	// Number of endpoints on which the run failed after all its attempts
+	Failed int `example:"1"`
+	// Number of endpoints that did not report the run yet or on which a retry is scheduled
+	Pending int `example:"1"`
+}
+
+// RecordEdgeJobResult associates the result reported by an endpoint to its run and attempt.
+// Runs are numbered at the job level and keyed on the date the run was scheduled at, so the endpoints running
+// the same schedule share a run number whatever the order their results arrive in. A retry reports the retry date
+// of its run. The results reported without scheduled date start the next run of the job unless the endpoint did not
+// report the current run yet. A late result of a previous run of the endpoint does not change its schedule.
+// A failed run is retried once after the retry interval of the job until its retry count is reached.
+// It returns true when the schedule of the job changed for the endpoint, a retry being scheduled or completed.
+func RecordEdgeJobResult(edgeJob *portainer.EdgeJob, result *portainer.EdgeJobResult, now time.Time) bool {
+	meta := edgeJob.Endpoints[result.EndpointID]
+
+	retried := meta.RetryDate != 0
+
+	var run int
+	switch {
+	case retried && meta.Run != 0 && (result.ScheduledDate == 0 || result.ScheduledDate == meta.RetryDate):
+		run = meta.Run
+	case result.ScheduledDate != 0:
+		run = edgeJobScheduledRun(edgeJob, result.ScheduledDate)
+	default:
+		if edgeJob.Run == 0 || meta.Run >= edgeJob.Run {
+			edgeJob.Run++
+		}
+		run = edgeJob.Run
+	}
+
+	if run < meta.Run {
+		result.Run = run
+		result.Attempt = 1
+		return false
+	}
+
+	if run != meta.Run {
+		meta.Run = run
+		meta.Attempt = 0
+	}
+	meta.Attempt++
+	meta.RetryDate = 0
+
+	if result.ExitCode != 0 && meta.Attempt <= edgeJob.RetryCount {
+		retryInterval := edgeJob.RetryInterval
+		if retryInterval < 1 {
+			retryInterval = 1
+		}
+		meta.RetryDate = now.Add(time.Duration(retryInterval) * time.Minute).Unix()
+	}
+
+	result.Run = meta.Run
+	result.Attempt = meta.Attempt
+	edgeJob.Endpoints[result.EndpointID] = meta
+
+	return retried || meta.RetryDate != 0
+}
+
+// edgeJobScheduledRun returns the run of the Edge job started at the scheduled date.
+// The first result reported for a date starts the next run of the job.
+func edgeJobScheduledRun(edgeJob *portainer.EdgeJob, scheduledDate int64) int {
+	if run, ok := edgeJob.ScheduledRuns[scheduledDate]; ok {
+		return run
+	}
+
+	if edgeJob.ScheduledRuns == nil {
+		edgeJob.ScheduledRuns = make(map[int64]int)
+	}
+
+	edgeJob.Run++
+	edgeJob.ScheduledRuns[scheduledDate] = edgeJob.Run
+
+	// the dates of the runs whose results are no longer kept are forgotten
+	firstRun := FirstRetainedEdgeJobRun(edgeJob)
+	for date, run := range edgeJob.ScheduledRuns {
+		if run < firstRun {
+			delete(edgeJob.ScheduledRuns, date)
+		}
+	}
+
+	return edgeJob.Run
+}
+
+// TruncateEdgeJobOutput keeps the last EdgeJobResultMaxOutputSize bytes of the output of an Edge job run
+func TruncateEdgeJobOutput(output string) string {
+	if len(output) <= portainer.EdgeJobResultMaxOutputSize {
+		return output
+	}
+
+	output = output[len(output)-portainer.EdgeJobResultMaxOutputSize:]
+	for len(output) > 0 && !utf8.RuneStart(output[0]) {
+		output = output[1:]
+	}
+
+	return truncatedOutputPrefix + output
+}
+
+// SummarizeEdgeJobRuns returns the outcome of each run of the Edge job found in the results,
+// ordered from the oldest to the most recent run
+func SummarizeEdgeJobRuns(edgeJob *portainer.EdgeJob, results []portainer.EdgeJobResult) []EdgeJobRunSummary {
+	type runEndpoint struct {
+		run        int
+		endpointID portainer.EndpointID
+	}
+
+	lastResults := make(map[runEndpoint]portainer.EdgeJobResult)
+	runs := make(map[int]bool)
+	for _, result := range results {
+		key := runEndpoint{run: result.Run, endpointID: result.EndpointID}
+		if last, ok := lastResults[key]; !ok || result.Attempt > last.Attempt {
+			lastResults[key] = result
+		}
+		runs[result.Run] = true
+	}
+
+	summaries := make([]EdgeJobRunSummary, 0, len(runs))
+	for run := range runs {
+		summary := EdgeJobRunSummary{Run: run}
+
+		for endpointID, meta := range edgeJob.Endpoints {
+			result, ok := lastResults[runEndpoint{run: run, endpointID: endpointID}]
+			switch {
+			case !ok && meta.Run < run:
+				summary.Pending++
+			case !ok:
+				// result removed by the retention policy
+			case result.ExitCode == 0:
+				summary.Succeeded++
+			case meta.Run == run && meta.RetryDate != 0:
+				summary.Pending++
+			default:
+				summary.Failed++
+			}
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Run < summaries[j].Run
+	})
+
+	return summaries
+}
+
+// FirstRetainedEdgeJobRun returns the oldest run of the Edge job whose results are kept by its retention
+func FirstRetainedEdgeJobRun(edgeJob *portainer.EdgeJob) int {
+	retention := edgeJob.ResultRetention
+	if retention <= 0 {
+		retention = portainer.EdgeJobDefaultResultRetention
+	}
+
+	return edgeJob.Run - retention + 1
+}
@@ -0,0 +1,140 @@
+package edge
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func recordResult(edgeJob *portainer.EdgeJob, endpointID portainer.EndpointID, exitCode int, now time.Time) (*portainer.EdgeJobResult, bool) {
+	result := &portainer.EdgeJobResult{EdgeJobID: edgeJob.ID, EndpointID: endpointID, ExitCode: exitCode}
+	changed := RecordEdgeJobResult(edgeJob, result, now)
+	return result, changed
+}
+
+func Test_RecordEdgeJobResult_RetriesFailedRuns(t *testing.T) {
+	now := time.Date(2021, 3, 4, 10, 30, 0, 0, time.UTC)
+	edgeJob := &portainer.EdgeJob{
+		ID:            1,
+		RetryCount:    2,
+		RetryInterval: 5,
+		Endpoints:     map[portainer.EndpointID]portainer.EdgeJobEndpointMeta{1: {}},
+	}
+
+	result, changed := recordResult(edgeJob, 1, 1, now)
+	assert.True(t, changed)
+	assert.Equal(t, 1, result.Run)
+	assert.Equal(t, 1, result.Attempt)
+	assert.Equal(t, now.Add(5*time.Minute).Unix(), edgeJob.Endpoints[1].RetryDate)
+
+	result, _ = recordResult(edgeJob, 1, 1, now)
+	assert.Equal(t, 1, result.Run)
+	assert.Equal(t, 2, result.Attempt)
+
+	result, changed = recordResult(edgeJob, 1, 1, now)
+	assert.True(t, changed, "the last retry is completed")
+	assert.Equal(t, 3, result.Attempt)
+	assert.Equal(t, int64(0), edgeJob.Endpoints[1].RetryDate, "no retry after the retry count is reached")
+
+	result, changed = recordResult(edgeJob, 1, 0, now)
+	assert.False(t, changed)
+	assert.Equal(t, 2, result.Run)
+	assert.Equal(t, 1, result.Attempt)
+}
+
+func Test_SummarizeEdgeJobRuns(t *testing.T) {
+	edgeJob := &portainer.EdgeJob{
+		Endpoints: map[portainer.EndpointID]portainer.EdgeJobEndpointMeta{
+			1: {Run: 2},
+			2: {Run: 2, RetryDate: 1587399600},
+			3: {Run: 1},
+		},
+	}
+	results := []portainer.EdgeJobResult{
+		{EndpointID: 1, Run: 1, Attempt: 1, ExitCode: 0},
+		{EndpointID: 2, Run: 1, Attempt: 1, ExitCode: 1},
+		{EndpointID: 2, Run: 1, Attempt: 2, ExitCode: 0},
+		{EndpointID: 3, Run: 1, Attempt: 1, ExitCode: 2},
+		{EndpointID: 1, Run: 2, Attempt: 1, ExitCode: 0},
+		{EndpointID: 2, Run: 2, Attempt: 1, ExitCode: 1},
+	}
+
+	assert.Equal(t, []EdgeJobRunSummary{
+		{Run: 1, Succeeded: 2, Failed: 1},
+		{Run: 2, Succeeded: 1, Pending: 2},
+	}, SummarizeEdgeJobRuns(edgeJob, results))
+}
+
+func Test_RecordEdgeJobResult_SharesRunsAcrossEndpoints(t *testing.T) {
+	now := time.Date(2021, 3, 4, 10, 30, 0, 0, time.UTC)
+	edgeJob := &portainer.EdgeJob{
+		ID:        1,
+		Endpoints: map[portainer.EndpointID]portainer.EdgeJobEndpointMeta{1: {}, 2: {}, 3: {}},
+	}
+
+	result, _ := recordResult(edgeJob, 1, 0, now)
+	assert.Equal(t, 1, result.Run)
+	result, _ = recordResult(edgeJob, 2, 0, now)
+	assert.Equal(t, 1, result.Run, "the endpoints reporting the same run share its number")
+
+	result, _ = recordResult(edgeJob, 1, 0, now)
+	assert.Equal(t, 2, result.Run)
+	result, _ = recordResult(edgeJob, 2, 0, now)
+	assert.Equal(t, 2, result.Run)
+	result, _ = recordResult(edgeJob, 3, 0, now)
+	assert.Equal(t, 2, result.Run, "an endpoint that missed a run joins the current run")
+	assert.Equal(t, 2, edgeJob.Run)
+}
+
+func Test_FirstRetainedEdgeJobRun(t *testing.T) {
+	assert.Equal(t, 2, FirstRetainedEdgeJobRun(&portainer.EdgeJob{ResultRetention: 2, Run: 3}))
+	assert.Equal(t, 1-portainer.EdgeJobDefaultResultRetention+1, FirstRetainedEdgeJobRun(&portainer.EdgeJob{Run: 1}))
+}
+
+func Test_TruncateEdgeJobOutput(t *testing.T) {
+	output := strings.Repeat("é", portainer.EdgeJobResultMaxOutputSize)
+
+	truncated := TruncateEdgeJobOutput(output)
+
+	assert.True(t, strings.HasPrefix(truncated, truncatedOutputPrefix))
+	assert.Equal(t, portainer.EdgeJobResultMaxOutputSize/2, len([]rune(strings.TrimPrefix(truncated, truncatedOutputPrefix))))
+	assert.Equal(t, "short output", TruncateEdgeJobOutput("short output"))
+}
+
+func Test_RecordEdgeJobResult_KeysRunsOnScheduledDate(t *testing.T) {
+	now := time.Date(2021, 3, 4, 10, 30, 0, 0, time.UTC)
+	firstDate := now.Add(-2 * time.Minute).Unix()
+	secondDate := now.Add(-time.Minute).Unix()
+	edgeJob := &portainer.EdgeJob{
+		ID:            1,
+		RetryCount:    1,
+		RetryInterval: 5,
+		Endpoints:     map[portainer.EndpointID]portainer.EdgeJobEndpointMeta{1: {}, 2: {}, 3: {}},
+	}
+
+	record := func(endpointID portainer.EndpointID, exitCode int, scheduledDate int64) *portainer.EdgeJobResult {
+		result := &portainer.EdgeJobResult{EdgeJobID: edgeJob.ID, EndpointID: endpointID, ExitCode: exitCode, ScheduledDate: scheduledDate}
+		RecordEdgeJobResult(edgeJob, result, now)
+		return result
+	}
+
+	assert.Equal(t, 1, record(1, 0, firstDate).Run)
+	assert.Equal(t, 1, record(2, 1, firstDate).Run)
+	assert.Equal(t, 2, record(1, 0, secondDate).Run)
+
+	result := record(3, 0, firstDate)
+	assert.Equal(t, 1, result.Run, "a late result joins the run of its scheduled date")
+	assert.Equal(t, 1, result.Attempt)
+
+	result = record(2, 0, edgeJob.Endpoints[2].RetryDate)
+	assert.Equal(t, 1, result.Run, "a retry reports the retry date of its run")
+	assert.Equal(t, 2, result.Attempt)
+
+	result = record(1, 0, firstDate)
+	assert.Equal(t, 1, result.Run, "a late result of a previous run keeps the endpoint schedule")
+	assert.Equal(t, 2, edgeJob.Endpoints[1].Run)
+	assert.Equal(t, 2, edgeJob.Run)
+}
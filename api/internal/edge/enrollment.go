@@ -0,0 +1,28 @@
+package edge
+
+import (
+	"errors"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+var (
+	// ErrEnrollmentTokenExpired is returned when an Edge agent registers with an expired enrollment token
+	ErrEnrollmentTokenExpired = errors.New("Enrollment token is expired")
+	// ErrEnrollmentTokenExhausted is returned when an Edge agent registers with an enrollment token that reached its usage limit
+	ErrEnrollmentTokenExhausted = errors.New("Enrollment token reached its usage limit")
+)
+
+// ValidateEnrollmentToken verifies that an Edge agent can register a new endpoint with the enrollment token at the specified time
+func ValidateEnrollmentToken(token *portainer.EdgeEnrollmentToken, now time.Time) error {
+	if token.ExpiryDate != 0 && now.Unix() >= token.ExpiryDate {
+		return ErrEnrollmentTokenExpired
+	}
+
+	if token.UsageLimit != 0 && token.UsageCount >= token.UsageLimit {
+		return ErrEnrollmentTokenExhausted
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package edge
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ValidateEnrollmentToken(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	tests := []struct {
+		token    portainer.EdgeEnrollmentToken
+		expected error
+	}{
+		{token: portainer.EdgeEnrollmentToken{}, expected: nil},
+		{token: portainer.EdgeEnrollmentToken{ExpiryDate: now.Unix() + 1, UsageLimit: 2, UsageCount: 1}, expected: nil},
+		{token: portainer.EdgeEnrollmentToken{ExpiryDate: now.Unix()}, expected: ErrEnrollmentTokenExpired},
+		{token: portainer.EdgeEnrollmentToken{UsageLimit: 2, UsageCount: 2}, expected: ErrEnrollmentTokenExhausted},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, ValidateEnrollmentToken(&test.token, now))
+	}
+}
@@ -0,0 +1,30 @@
+package edge
+
+import (
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// ApplyEdgeHeartbeat stores the heartbeat reported by an Edge agent on the endpoint, along with the optional snapshot
+// matching the platform of the endpoint. It returns true when the snapshot of the endpoint was replaced.
+func ApplyEdgeHeartbeat(endpoint *portainer.Endpoint, heartbeat portainer.EdgeHeartbeat, dockerSnapshot *portainer.DockerSnapshot, kubernetesSnapshot *portainer.KubernetesSnapshot, now time.Time) bool {
+	heartbeat.Time = now.Unix()
+	endpoint.EdgeHeartbeat = &heartbeat
+	endpoint.Status = portainer.EndpointStatusUp
+
+	switch {
+	case dockerSnapshot != nil && endpoint.Type == portainer.EdgeAgentOnDockerEnvironment:
+		snapshot := *dockerSnapshot
+		snapshot.Time = now.Unix()
+		endpoint.Snapshots = []portainer.DockerSnapshot{snapshot}
+		return true
+	case kubernetesSnapshot != nil && endpoint.Type == portainer.EdgeAgentOnKubernetesEnvironment:
+		snapshot := *kubernetesSnapshot
+		snapshot.Time = now.Unix()
+		endpoint.Kubernetes.Snapshots = []portainer.KubernetesSnapshot{snapshot}
+		return true
+	}
+
+	return false
+}
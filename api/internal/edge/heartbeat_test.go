@@ -0,0 +1,35 @@
+package edge
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ApplyEdgeHeartbeat(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	heartbeat := portainer.EdgeHeartbeat{AgentVersion: "2.4.0", Uptime: 3600, RunningContainerCount: 3, DiskUsed: 10, DiskTotal: 20}
+	dockerSnapshot := &portainer.DockerSnapshot{DockerVersion: "20.10.7", RunningContainerCount: 3}
+	kubernetesSnapshot := &portainer.KubernetesSnapshot{KubernetesVersion: "1.21", NodeCount: 1}
+
+	endpoint := &portainer.Endpoint{Type: portainer.EdgeAgentOnDockerEnvironment, Status: portainer.EndpointStatusDown}
+	assert.True(t, ApplyEdgeHeartbeat(endpoint, heartbeat, dockerSnapshot, kubernetesSnapshot, now))
+	assert.Equal(t, &portainer.EdgeHeartbeat{AgentVersion: "2.4.0", Uptime: 3600, RunningContainerCount: 3, DiskUsed: 10, DiskTotal: 20, Time: now.Unix()}, endpoint.EdgeHeartbeat)
+	assert.Equal(t, portainer.EndpointStatusUp, endpoint.Status)
+	assert.Equal(t, []portainer.DockerSnapshot{{Time: now.Unix(), DockerVersion: "20.10.7", RunningContainerCount: 3}}, endpoint.Snapshots)
+	assert.Empty(t, endpoint.Kubernetes.Snapshots)
+	assert.Equal(t, int64(0), dockerSnapshot.Time)
+
+	endpoint = &portainer.Endpoint{Type: portainer.EdgeAgentOnKubernetesEnvironment}
+	assert.True(t, ApplyEdgeHeartbeat(endpoint, heartbeat, dockerSnapshot, kubernetesSnapshot, now))
+	assert.Empty(t, endpoint.Snapshots)
+	assert.Equal(t, []portainer.KubernetesSnapshot{{Time: now.Unix(), KubernetesVersion: "1.21", NodeCount: 1}}, endpoint.Kubernetes.Snapshots)
+
+	snapshots := []portainer.DockerSnapshot{{DockerVersion: "19.03"}}
+	endpoint = &portainer.Endpoint{Type: portainer.EdgeAgentOnDockerEnvironment, Snapshots: snapshots}
+	assert.False(t, ApplyEdgeHeartbeat(endpoint, heartbeat, nil, kubernetesSnapshot, now))
+	assert.Equal(t, snapshots, endpoint.Snapshots)
+	assert.Equal(t, "2.4.0", endpoint.EdgeHeartbeat.AgentVersion)
+}
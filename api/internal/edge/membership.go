@@ -0,0 +1,318 @@
+package edge
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/logging"
+)
+
+var logger = logging.Component("edge")
+
+// EdgeGroupMembership evaluates which Edge endpoints belong to the Edge groups, whether the groups
+// are static, dynamic based on tags or dynamic based on a selector
+type EdgeGroupMembership struct {
+	endpointGroups map[portainer.EndpointGroupID]*portainer.EndpointGroup
+	tagNames       map[portainer.TagID]string
+	now            time.Time
+}
+
+// selectorCacheMaxSize is the number of parsed selectors kept in memory, the cache is cleared when it is exceeded
+const selectorCacheMaxSize = 256
+
+type cachedSelector struct {
+	selector *EdgeGroupSelector
+	err      error
+}
+
+var (
+	selectorCacheMu sync.Mutex
+	selectorCache   = make(map[string]cachedSelector)
+)
+
+// NewEdgeGroupMembership returns the membership of the Edge groups evaluated at the specified time
+func NewEdgeGroupMembership(endpointGroups []portainer.EndpointGroup, tags []portainer.Tag, now time.Time) *EdgeGroupMembership {
+	membership := &EdgeGroupMembership{
+		endpointGroups: make(map[portainer.EndpointGroupID]*portainer.EndpointGroup),
+		tagNames:       make(map[portainer.TagID]string),
+		now:            now,
+	}
+
+	for idx := range endpointGroups {
+		membership.endpointGroups[endpointGroups[idx].ID] = &endpointGroups[idx]
+	}
+
+	for _, tag := range tags {
+		membership.tagNames[tag.ID] = tag.Name
+	}
+
+	return membership
+}
+
+// LoadEdgeGroupMembership returns the membership of the Edge groups evaluated now with the endpoint groups and tags of the database
+func LoadEdgeGroupMembership(dataStore portainer.DataStore) (*EdgeGroupMembership, error) {
+	endpointGroups, err := dataStore.EndpointGroup().EndpointGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := dataStore.Tag().Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEdgeGroupMembership(endpointGroups, tags, time.Now()), nil
+}
+
+// Contains returns true when the endpoint belongs to the Edge group.
+// An endpoint waiting for approval does not belong to any Edge group.
+func (membership *EdgeGroupMembership) Contains(edgeGroup *portainer.EdgeGroup, endpoint *portainer.Endpoint) bool {
+	if endpoint.EdgePending {
+		return false
+	}
+
+	if !edgeGroup.Dynamic {
+		for _, endpointID := range edgeGroup.Endpoints {
+			if endpointID == endpoint.ID {
+				return true
+			}
+		}
+		return false
+	}
+
+	if endpoint.Type != portainer.EdgeAgentOnDockerEnvironment && endpoint.Type != portainer.EdgeAgentOnKubernetesEnvironment {
+		return false
+	}
+
+	endpointGroup := membership.endpointGroups[endpoint.GroupID]
+
+	if edgeGroup.Selector != "" {
+		selector := membership.selector(edgeGroup)
+		return selector != nil && selector.Match(NewSelectorEndpoint(endpoint, endpointGroup, membership.tagNames, membership.now))
+	}
+
+	endpointTags := make(map[portainer.TagID]bool)
+	for _, tagID := range endpoint.TagIDs {
+		endpointTags[tagID] = true
+	}
+	if endpointGroup != nil {
+		for _, tagID := range endpointGroup.TagIDs {
+			endpointTags[tagID] = true
+		}
+	}
+
+	for _, tagID := range edgeGroup.TagIDs {
+		if endpointTags[tagID] && edgeGroup.PartialMatch {
+			return true
+		}
+		if !endpointTags[tagID] && !edgeGroup.PartialMatch {
+			return false
+		}
+	}
+
+	return !edgeGroup.PartialMatch && len(edgeGroup.TagIDs) > 0
+}
+
+// selector returns the parsed selector of the Edge group, nil when the selector is invalid
+func (membership *EdgeGroupMembership) selector(edgeGroup *portainer.EdgeGroup) *EdgeGroupSelector {
+	selector, _ := parseEdgeGroupSelector(edgeGroup)
+	return selector
+}
+
+// parseEdgeGroupSelector parses the selector of the Edge group once and returns the cached result afterwards.
+// The Edge groups are saved with a valid selector, an invalid selector is only logged the first time it is parsed.
+func parseEdgeGroupSelector(edgeGroup *portainer.EdgeGroup) (*EdgeGroupSelector, error) {
+	selectorCacheMu.Lock()
+	defer selectorCacheMu.Unlock()
+
+	cached, ok := selectorCache[edgeGroup.Selector]
+	if ok {
+		return cached.selector, cached.err
+	}
+
+	selector, err := ParseEdgeGroupSelector(edgeGroup.Selector)
+	if err != nil {
+		logger.WithField("edge_group_id", edgeGroup.ID).WithError(err).Warn("invalid selector, the edge group matches no endpoint")
+	}
+
+	if len(selectorCache) >= selectorCacheMaxSize {
+		selectorCache = make(map[string]cachedSelector)
+	}
+	selectorCache[edgeGroup.Selector] = cachedSelector{selector: selector, err: err}
+
+	return selector, err
+}
+
+// EdgeGroupEndpoints returns the endpoints that belong to the Edge group
+func (membership *EdgeGroupMembership) EdgeGroupEndpoints(edgeGroup *portainer.EdgeGroup, endpoints []portainer.Endpoint) []portainer.EndpointID {
+	endpointIDs := []portainer.EndpointID{}
+
+	if !edgeGroup.Dynamic {
+		pending := make(map[portainer.EndpointID]bool)
+		for _, endpoint := range endpoints {
+			if endpoint.EdgePending {
+				pending[endpoint.ID] = true
+			}
+		}
+
+		for _, endpointID := range edgeGroup.Endpoints {
+			if !pending[endpointID] {
+				endpointIDs = append(endpointIDs, endpointID)
+			}
+		}
+		return endpointIDs
+	}
+
+	for idx := range endpoints {
+		if membership.Contains(edgeGroup, &endpoints[idx]) {
+			endpointIDs = append(endpointIDs, endpoints[idx].ID)
+		}
+	}
+
+	return endpointIDs
+}
+
+// EdgeStackEndpoints returns the endpoints that belong to any of the Edge groups of an Edge stack
+func (membership *EdgeGroupMembership) EdgeStackEndpoints(edgeGroupIDs []portainer.EdgeGroupID, endpoints []portainer.Endpoint, edgeGroups []portainer.EdgeGroup) ([]portainer.EndpointID, error) {
+	edgeStackEndpoints := []portainer.EndpointID{}
+	seen := make(map[portainer.EndpointID]bool)
+
+	for _, edgeGroupID := range edgeGroupIDs {
+		var edgeGroup *portainer.EdgeGroup
+		for idx := range edgeGroups {
+			if edgeGroups[idx].ID == edgeGroupID {
+				edgeGroup = &edgeGroups[idx]
+				break
+			}
+		}
+
+		if edgeGroup == nil {
+			return nil, errors.New("Edge group was not found")
+		}
+
+		for _, endpointID := range membership.EdgeGroupEndpoints(edgeGroup, endpoints) {
+			if !seen[endpointID] {
+				seen[endpointID] = true
+				edgeStackEndpoints = append(edgeStackEndpoints, endpointID)
+			}
+		}
+	}
+
+	return edgeStackEndpoints, nil
+}
+
+// LoadEdgeStackEndpoints returns the endpoints of the database that belong to any of the Edge groups of an Edge stack
+func LoadEdgeStackEndpoints(dataStore portainer.DataStore, edgeGroupIDs []portainer.EdgeGroupID) ([]portainer.EndpointID, error) {
+	endpoints, err := dataStore.Endpoint().Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	membership, err := LoadEdgeGroupMembership(dataStore)
+	if err != nil {
+		return nil, err
+	}
+
+	edgeGroups, err := dataStore.EdgeGroup().EdgeGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	return membership.EdgeStackEndpoints(edgeGroupIDs, endpoints, edgeGroups)
+}
+
+// EndpointEdgeStacks returns the Edge stacks deployed to the endpoint through its Edge groups
+func (membership *EdgeGroupMembership) EndpointEdgeStacks(endpoint *portainer.Endpoint, edgeGroups []portainer.EdgeGroup, edgeStacks []portainer.EdgeStack) []portainer.EdgeStackID {
+	endpointEdgeGroups := make(map[portainer.EdgeGroupID]bool)
+	for idx := range edgeGroups {
+		if membership.Contains(&edgeGroups[idx], endpoint) {
+			endpointEdgeGroups[edgeGroups[idx].ID] = true
+		}
+	}
+
+	edgeStackIDs := []portainer.EdgeStackID{}
+	for _, edgeStack := range edgeStacks {
+		for _, edgeGroupID := range edgeStack.EdgeGroups {
+			if endpointEdgeGroups[edgeGroupID] {
+				edgeStackIDs = append(edgeStackIDs, edgeStack.ID)
+				break
+			}
+		}
+	}
+
+	return edgeStackIDs
+}
+
+// EdgeGroupsUseCheckInAge returns true when the membership of one of the Edge groups depends on the last check-in of the endpoints
+func EdgeGroupsUseCheckInAge(edgeGroups []portainer.EdgeGroup) bool {
+	for _, edgeGroup := range edgeGroups {
+		if !edgeGroup.Dynamic || edgeGroup.Selector == "" {
+			continue
+		}
+
+		selector, err := parseEdgeGroupSelector(&edgeGroup)
+		if err == nil && selector.UsesCheckInAge() {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateEndpointRelation recomputes the Edge stacks related to an Edge endpoint after its attributes changed
+// and persists the relation when it differs. It returns true when the relation was updated.
+func UpdateEndpointRelation(dataStore portainer.DataStore, endpoint *portainer.Endpoint) (bool, error) {
+	if endpoint.Type != portainer.EdgeAgentOnDockerEnvironment && endpoint.Type != portainer.EdgeAgentOnKubernetesEnvironment {
+		return false, nil
+	}
+
+	membership, err := LoadEdgeGroupMembership(dataStore)
+	if err != nil {
+		return false, err
+	}
+
+	edgeGroups, err := dataStore.EdgeGroup().EdgeGroups()
+	if err != nil {
+		return false, err
+	}
+
+	edgeStacks, err := dataStore.EdgeStack().EdgeStacks()
+	if err != nil {
+		return false, err
+	}
+
+	return membership.UpdateEndpointRelation(dataStore, endpoint, edgeGroups, edgeStacks)
+}
+
+// UpdateEndpointRelation recomputes the Edge stacks related to an Edge endpoint with the specified Edge groups and stacks
+// and persists the relation when it differs. It returns true when the relation was updated.
+func (membership *EdgeGroupMembership) UpdateEndpointRelation(dataStore portainer.DataStore, endpoint *portainer.Endpoint, edgeGroups []portainer.EdgeGroup, edgeStacks []portainer.EdgeStack) (bool, error) {
+	relation, err := dataStore.EndpointRelation().EndpointRelation(endpoint.ID)
+	if err != nil {
+		return false, err
+	}
+
+	edgeStackSet := map[portainer.EdgeStackID]bool{}
+	for _, edgeStackID := range membership.EndpointEdgeStacks(endpoint, edgeGroups, edgeStacks) {
+		edgeStackSet[edgeStackID] = true
+	}
+
+	if len(edgeStackSet) == len(relation.EdgeStacks) {
+		unchanged := true
+		for edgeStackID := range edgeStackSet {
+			if !relation.EdgeStacks[edgeStackID] {
+				unchanged = false
+				break
+			}
+		}
+
+		if unchanged {
+			return false, nil
+		}
+	}
+
+	relation.EdgeStacks = edgeStackSet
+
+	return true, dataStore.EndpointRelation().UpdateEndpointRelation(endpoint.ID, relation)
+}
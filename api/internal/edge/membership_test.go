@@ -0,0 +1,98 @@
+package edge
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EdgeGroupMembership(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	endpoints := []portainer.Endpoint{
+		{ID: 1, Name: "factory-01", Type: portainer.EdgeAgentOnDockerEnvironment, GroupID: 1, TagIDs: []portainer.TagID{1}, LastCheckInDate: now.Unix()},
+		{ID: 2, Name: "factory-02", Type: portainer.EdgeAgentOnKubernetesEnvironment, GroupID: 2, TagIDs: []portainer.TagID{2}},
+		{ID: 3, Name: "local", Type: portainer.DockerEnvironment, GroupID: 1, TagIDs: []portainer.TagID{1}},
+	}
+	endpointGroups := []portainer.EndpointGroup{{ID: 1, Name: "Unassigned"}, {ID: 2, Name: "Factory B", TagIDs: []portainer.TagID{1}}}
+	tags := []portainer.Tag{{ID: 1, Name: "production"}, {ID: 2, Name: "gpu"}}
+	edgeGroups := []portainer.EdgeGroup{
+		{ID: 1, Dynamic: true, TagIDs: []portainer.TagID{1, 2}},
+		{ID: 2, Dynamic: true, TagIDs: []portainer.TagID{1, 2}, PartialMatch: true},
+		{ID: 3, Dynamic: true, Selector: "tag == production and checkin_age < 1m"},
+		{ID: 4, Endpoints: []portainer.EndpointID{3}},
+		{ID: 5, Dynamic: true, Selector: "tag =="},
+	}
+	edgeStacks := []portainer.EdgeStack{
+		{ID: 1, EdgeGroups: []portainer.EdgeGroupID{1}},
+		{ID: 2, EdgeGroups: []portainer.EdgeGroupID{3, 4}},
+	}
+
+	membership := NewEdgeGroupMembership(endpointGroups, tags, now)
+
+	tests := []struct {
+		edgeGroup portainer.EdgeGroup
+		expected  []portainer.EndpointID
+	}{
+		{edgeGroup: edgeGroups[0], expected: []portainer.EndpointID{2}},
+		{edgeGroup: edgeGroups[1], expected: []portainer.EndpointID{1, 2}},
+		{edgeGroup: edgeGroups[2], expected: []portainer.EndpointID{1}},
+		{edgeGroup: edgeGroups[3], expected: []portainer.EndpointID{3}},
+		{edgeGroup: edgeGroups[4], expected: []portainer.EndpointID{}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, membership.EdgeGroupEndpoints(&test.edgeGroup, endpoints), "edge group %d", test.edgeGroup.ID)
+	}
+
+	edgeStackEndpoints, err := membership.EdgeStackEndpoints([]portainer.EdgeGroupID{3, 4, 1}, endpoints, edgeGroups)
+	assert.NoError(t, err)
+	assert.Equal(t, []portainer.EndpointID{1, 3, 2}, edgeStackEndpoints)
+
+	_, err = membership.EdgeStackEndpoints([]portainer.EdgeGroupID{9}, endpoints, edgeGroups)
+	assert.Error(t, err)
+
+	assert.Equal(t, []portainer.EdgeStackID{2}, membership.EndpointEdgeStacks(&endpoints[0], edgeGroups, edgeStacks))
+	assert.Equal(t, []portainer.EdgeStackID{1}, membership.EndpointEdgeStacks(&endpoints[1], edgeGroups, edgeStacks))
+	assert.True(t, EdgeGroupsUseCheckInAge(edgeGroups))
+	assert.False(t, EdgeGroupsUseCheckInAge(edgeGroups[:2]))
+}
+
+func Test_EdgeGroupMembership_PendingEndpoint(t *testing.T) {
+	endpoints := []portainer.Endpoint{
+		{ID: 1, Type: portainer.EdgeAgentOnDockerEnvironment, TagIDs: []portainer.TagID{1}},
+		{ID: 2, Type: portainer.EdgeAgentOnDockerEnvironment, TagIDs: []portainer.TagID{1}, EdgePending: true},
+	}
+	edgeGroups := []portainer.EdgeGroup{
+		{ID: 1, Dynamic: true, TagIDs: []portainer.TagID{1}},
+		{ID: 2, Endpoints: []portainer.EndpointID{1, 2}},
+	}
+	edgeStacks := []portainer.EdgeStack{{ID: 1, EdgeGroups: []portainer.EdgeGroupID{1, 2}}}
+
+	membership := NewEdgeGroupMembership(nil, nil, time.Now())
+
+	assert.Equal(t, []portainer.EndpointID{1}, membership.EdgeGroupEndpoints(&edgeGroups[0], endpoints))
+	assert.Equal(t, []portainer.EndpointID{1}, membership.EdgeGroupEndpoints(&edgeGroups[1], endpoints))
+	assert.Equal(t, []portainer.EdgeStackID{}, membership.EndpointEdgeStacks(&endpoints[1], edgeGroups, edgeStacks))
+
+	endpoints[1].EdgePending = false
+	assert.Equal(t, []portainer.EdgeStackID{1}, membership.EndpointEdgeStacks(&endpoints[1], edgeGroups, edgeStacks))
+}
+
+func Test_parseEdgeGroupSelector_CachesSelectors(t *testing.T) {
+	edgeGroup := &portainer.EdgeGroup{ID: 1, Dynamic: true, Selector: "checkin_age > 1h"}
+
+	selector, err := parseEdgeGroupSelector(edgeGroup)
+	assert.NoError(t, err)
+
+	cached, err := parseEdgeGroupSelector(edgeGroup)
+	assert.NoError(t, err)
+	assert.True(t, selector == cached, "the selector is only parsed once")
+
+	edgeGroup.Selector = "checkin_age > soon"
+	_, err = parseEdgeGroupSelector(edgeGroup)
+	assert.Error(t, err)
+	_, err = parseEdgeGroupSelector(edgeGroup)
+	assert.Error(t, err, "an invalid selector stays invalid")
+}
@@ -0,0 +1,216 @@
+package edge
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// DefaultRolloutBatchTimeout is the time the endpoints of a rollout batch have to report their status
+// when the rollout does not specify a batch timeout
+const DefaultRolloutBatchTimeout = time.Hour
+
+// EdgeStackVersionFolder returns the folder, relative to the edge stack project path,
+// where the files of a previous version of an edge stack are kept
+func EdgeStackVersionFolder(version int) string {
+	return path.Join("versions", strconv.Itoa(version))
+}
+
+// EdgeStackFilePath returns the path to the edge stack file of the specified version
+func EdgeStackFilePath(edgeStack *portainer.EdgeStack, version int) string {
+	if version == edgeStack.Version {
+		return path.Join(edgeStack.ProjectPath, edgeStack.EntryPoint)
+	}
+
+	return path.Join(edgeStack.ProjectPath, EdgeStackVersionFolder(version), edgeStack.EntryPoint)
+}
+
+// EdgeStackEndpointVersion returns the version of the edge stack that must be deployed on the endpoint.
+// It is the rollback version when the endpoint was sent back to a previous version.
+func EdgeStackEndpointVersion(edgeStack *portainer.EdgeStack, endpointID portainer.EndpointID) int {
+	targetVersion := edgeStackTargetVersion(edgeStack, endpointID)
+
+	rollbackVersion := edgeStackRollbackVersion(edgeStack, endpointID, targetVersion)
+	if rollbackVersion != 0 {
+		return rollbackVersion
+	}
+
+	return targetVersion
+}
+
+// EdgeStackEndpointRollback returns the version the endpoint was sent back to after failing to deploy
+// the version targeted for it, 0 when the endpoint is not rolled back
+func EdgeStackEndpointRollback(edgeStack *portainer.EdgeStack, endpointID portainer.EndpointID) int {
+	return edgeStackRollbackVersion(edgeStack, endpointID, edgeStackTargetVersion(edgeStack, endpointID))
+}
+
+// edgeStackTargetVersion returns the version of the edge stack targeted for the endpoint by the current rollout
+func edgeStackTargetVersion(edgeStack *portainer.EdgeStack, endpointID portainer.EndpointID) int {
+	rollout := edgeStack.Rollout
+	if rollout == nil || rollout.Version != edgeStack.Version || rollout.Status == portainer.EdgeStackRolloutCompleted {
+		return edgeStack.Version
+	}
+
+	if rollout.Status == portainer.EdgeStackRolloutAborted {
+		return rollout.PreviousVersion
+	}
+
+	for _, id := range rollout.DeployedEndpoints {
+		if id == endpointID {
+			return rollout.Version
+		}
+	}
+
+	return rollout.PreviousVersion
+}
+
+// StartEdgeStackRollout starts the rollout of the current edge stack version and deploys its first batch.
+// The endpoints not part of the first batch keep running previousVersion.
+func StartEdgeStackRollout(edgeStack *portainer.EdgeStack, rollout *portainer.EdgeStackRollout, previousVersion int, relatedEndpoints []portainer.EndpointID) {
+	now := time.Now().Unix()
+
+	rollout.Status = portainer.EdgeStackRolloutRunning
+	rollout.Version = edgeStack.Version
+	rollout.PreviousVersion = previousVersion
+	rollout.DeployedEndpoints = make([]portainer.EndpointID, 0)
+	rollout.Batches = 1
+	rollout.Error = ""
+	rollout.StartDate = now
+	rollout.BatchStartDate = now
+	rollout.UpdateDate = now
+
+	related := endpointSet(relatedEndpoints)
+
+	switch rollout.Strategy {
+	case portainer.EdgeStackRolloutSubset:
+		for _, endpointID := range rollout.CanaryEndpoints {
+			if related[endpointID] {
+				rollout.DeployedEndpoints = append(rollout.DeployedEndpoints, endpointID)
+			}
+		}
+	default:
+		count := (len(relatedEndpoints)*rollout.CanaryPercentage + 99) / 100
+		if count == 0 && len(relatedEndpoints) > 0 {
+			count = 1
+		}
+		rollout.DeployedEndpoints = append(rollout.DeployedEndpoints, sortedEndpoints(relatedEndpoints)[:count]...)
+	}
+
+	edgeStack.Rollout = rollout
+	UpdateEdgeStackRollout(edgeStack, relatedEndpoints, time.Unix(now, 0))
+}
+
+// UpdateEdgeStackRollout evaluates the status reported by the endpoints of the running rollout at the specified time.
+// Only the status reported for the version of the rollout is taken into account.
+// The endpoints that did not report their status before the batch timeout count as failed.
+// The rollout is stopped when the ratio of endpoints reporting an error exceeds the error threshold,
+// otherwise the next batch is deployed once every endpoint of the previous batches reported its status.
+// It returns true when the rollout was updated.
+func UpdateEdgeStackRollout(edgeStack *portainer.EdgeStack, relatedEndpoints []portainer.EndpointID, now time.Time) bool {
+	rollout := edgeStack.Rollout
+	if rollout == nil || rollout.Status != portainer.EdgeStackRolloutRunning || rollout.Version != edgeStack.Version {
+		return false
+	}
+
+	related := endpointSet(relatedEndpoints)
+	expired := !now.Before(time.Unix(rollout.BatchStartDate, 0).Add(rolloutBatchTimeout(rollout)))
+
+	deployed := 0
+	failed := 0
+	unreported := 0
+	pending := 0
+	for _, endpointID := range rollout.DeployedEndpoints {
+		if !related[endpointID] {
+			continue
+		}
+		deployed++
+
+		deployment, ok := edgeStack.EndpointDeployments[endpointID]
+		reported := ok && deployment.Version == rollout.Version && deployment.Status != portainer.StatusAcknowledged
+		switch {
+		case edgeStackRollbackVersion(edgeStack, endpointID, rollout.Version) != 0:
+			failed++
+		case !reported && expired:
+			failed++
+			unreported++
+		case !reported:
+			pending++
+		case deployment.Status == portainer.StatusError:
+			failed++
+		}
+	}
+
+	if failed > 0 && failed*100 > rollout.ErrorThreshold*deployed {
+		rollout.Status = portainer.EdgeStackRolloutStopped
+		rollout.Error = fmt.Sprintf("%d of %d endpoints failed to deploy version %d, above the %d%% error threshold", failed, deployed, rollout.Version, rollout.ErrorThreshold)
+		if unreported > 0 {
+			rollout.Error += fmt.Sprintf(", %d endpoints did not report their status within %s", unreported, rolloutBatchTimeout(rollout))
+		}
+		rollout.UpdateDate = now.Unix()
+		return true
+	}
+
+	if pending > 0 {
+		return false
+	}
+
+	remaining := make([]portainer.EndpointID, 0)
+	deployedSet := endpointSet(rollout.DeployedEndpoints)
+	for _, endpointID := range sortedEndpoints(relatedEndpoints) {
+		if !deployedSet[endpointID] {
+			remaining = append(remaining, endpointID)
+		}
+	}
+
+	if len(remaining) == 0 {
+		rollout.Status = portainer.EdgeStackRolloutCompleted
+		rollout.UpdateDate = now.Unix()
+		return true
+	}
+
+	if rollout.BatchSize > 0 && rollout.BatchSize < len(remaining) {
+		remaining = remaining[:rollout.BatchSize]
+	}
+
+	rollout.DeployedEndpoints = append(rollout.DeployedEndpoints, remaining...)
+	rollout.Batches++
+	rollout.BatchStartDate = now.Unix()
+	rollout.UpdateDate = now.Unix()
+	return true
+}
+
+// ResumeEdgeStackRollout resumes a paused or stopped rollout at the specified time. The endpoints
+// that did not report their status yet are given a new batch timeout.
+func ResumeEdgeStackRollout(edgeStack *portainer.EdgeStack, relatedEndpoints []portainer.EndpointID, now time.Time) {
+	edgeStack.Rollout.Status = portainer.EdgeStackRolloutRunning
+	edgeStack.Rollout.Error = ""
+	edgeStack.Rollout.BatchStartDate = now.Unix()
+	UpdateEdgeStackRollout(edgeStack, relatedEndpoints, now)
+}
+
+func rolloutBatchTimeout(rollout *portainer.EdgeStackRollout) time.Duration {
+	if rollout.BatchTimeout <= 0 {
+		return DefaultRolloutBatchTimeout
+	}
+	return time.Duration(rollout.BatchTimeout) * time.Second
+}
+
+func endpointSet(endpointIDs []portainer.EndpointID) map[portainer.EndpointID]bool {
+	set := make(map[portainer.EndpointID]bool)
+	for _, endpointID := range endpointIDs {
+		set[endpointID] = true
+	}
+	return set
+}
+
+func sortedEndpoints(endpointIDs []portainer.EndpointID) []portainer.EndpointID {
+	sorted := append([]portainer.EndpointID{}, endpointIDs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
@@ -0,0 +1,153 @@
+package edge
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRolloutEdgeStack() *portainer.EdgeStack {
+	return &portainer.EdgeStack{
+		ID:      1,
+		Version: 3,
+		Status:  map[portainer.EndpointID]portainer.EdgeStackStatus{},
+	}
+}
+
+func reportStatus(edgeStack *portainer.EdgeStack, status portainer.EdgeStackStatusType, endpointIDs ...portainer.EndpointID) {
+	for _, endpointID := range endpointIDs {
+		edgeStack.Status[endpointID] = portainer.EdgeStackStatus{Type: status, EndpointID: endpointID}
+		RecordEdgeStackEndpointStatus(edgeStack, endpointID, status, EdgeStackEndpointVersion(edgeStack, endpointID))
+	}
+}
+
+func Test_StartEdgeStackRollout_CanaryPercentage(t *testing.T) {
+	edgeStack := newRolloutEdgeStack()
+	related := []portainer.EndpointID{5, 1, 4, 2, 3}
+
+	StartEdgeStackRollout(edgeStack, &portainer.EdgeStackRollout{Strategy: portainer.EdgeStackRolloutCanary, CanaryPercentage: 30, BatchSize: 2}, 2, related)
+
+	assert.Equal(t, portainer.EdgeStackRolloutRunning, edgeStack.Rollout.Status)
+	assert.Equal(t, []portainer.EndpointID{1, 2}, edgeStack.Rollout.DeployedEndpoints)
+	assert.Equal(t, 3, EdgeStackEndpointVersion(edgeStack, 1))
+	assert.Equal(t, 2, EdgeStackEndpointVersion(edgeStack, 3))
+}
+
+func Test_UpdateEdgeStackRollout_DeploysBatchesUntilCompleted(t *testing.T) {
+	edgeStack := newRolloutEdgeStack()
+	related := []portainer.EndpointID{1, 2, 3, 4, 5}
+
+	StartEdgeStackRollout(edgeStack, &portainer.EdgeStackRollout{Strategy: portainer.EdgeStackRolloutSubset, CanaryEndpoints: []portainer.EndpointID{4, 9}, BatchSize: 2}, 2, related)
+	assert.Equal(t, []portainer.EndpointID{4}, edgeStack.Rollout.DeployedEndpoints)
+
+	reportStatus(edgeStack, portainer.StatusAcknowledged, 4)
+	assert.False(t, UpdateEdgeStackRollout(edgeStack, related, time.Now()), "acknowledged endpoints are still pending")
+
+	reportStatus(edgeStack, portainer.StatusOk, 4)
+	assert.True(t, UpdateEdgeStackRollout(edgeStack, related, time.Now()))
+	assert.Equal(t, []portainer.EndpointID{4, 1, 2}, edgeStack.Rollout.DeployedEndpoints)
+
+	reportStatus(edgeStack, portainer.StatusOk, 1, 2)
+	UpdateEdgeStackRollout(edgeStack, related, time.Now())
+	assert.Equal(t, []portainer.EndpointID{4, 1, 2, 3, 5}, edgeStack.Rollout.DeployedEndpoints)
+	assert.Equal(t, 3, edgeStack.Rollout.Batches)
+
+	reportStatus(edgeStack, portainer.StatusOk, 3, 5)
+	UpdateEdgeStackRollout(edgeStack, related, time.Now())
+	assert.Equal(t, portainer.EdgeStackRolloutCompleted, edgeStack.Rollout.Status)
+	assert.Equal(t, 3, EdgeStackEndpointVersion(edgeStack, 5))
+}
+
+func Test_UpdateEdgeStackRollout_IgnoresPreviousVersionStatus(t *testing.T) {
+	edgeStack := newRolloutEdgeStack()
+	related := []portainer.EndpointID{1, 2}
+
+	StartEdgeStackRollout(edgeStack, &portainer.EdgeStackRollout{Strategy: portainer.EdgeStackRolloutCanary, CanaryPercentage: 50}, 2, related)
+	RecordEdgeStackEndpointStatus(edgeStack, 1, portainer.StatusOk, 2)
+
+	assert.False(t, UpdateEdgeStackRollout(edgeStack, related, time.Now()), "the status of the previous version does not count for the canary batch")
+	assert.Equal(t, []portainer.EndpointID{1}, edgeStack.Rollout.DeployedEndpoints)
+}
+
+func Test_UpdateEdgeStackRollout_StopsAboveErrorThreshold(t *testing.T) {
+	tests := []struct {
+		name           string
+		errorThreshold int
+		expectedStatus portainer.EdgeStackRolloutStatus
+	}{
+		{name: "error rate above the threshold", errorThreshold: 20, expectedStatus: portainer.EdgeStackRolloutStopped},
+		{name: "error rate below the threshold", errorThreshold: 50, expectedStatus: portainer.EdgeStackRolloutRunning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edgeStack := newRolloutEdgeStack()
+			related := []portainer.EndpointID{1, 2, 3, 4, 5, 6}
+
+			StartEdgeStackRollout(edgeStack, &portainer.EdgeStackRollout{Strategy: portainer.EdgeStackRolloutCanary, CanaryPercentage: 50, ErrorThreshold: tt.errorThreshold}, 2, related)
+			reportStatus(edgeStack, portainer.StatusOk, 1, 2)
+			reportStatus(edgeStack, portainer.StatusError, 3)
+
+			UpdateEdgeStackRollout(edgeStack, related, time.Now())
+			assert.Equal(t, tt.expectedStatus, edgeStack.Rollout.Status)
+		})
+	}
+}
+
+func Test_EdgeStackEndpointVersion_AbortedRollout(t *testing.T) {
+	edgeStack := newRolloutEdgeStack()
+	related := []portainer.EndpointID{1, 2}
+
+	StartEdgeStackRollout(edgeStack, &portainer.EdgeStackRollout{Strategy: portainer.EdgeStackRolloutCanary, CanaryPercentage: 50}, 2, related)
+	assert.Equal(t, 3, EdgeStackEndpointVersion(edgeStack, 1))
+
+	edgeStack.Rollout.Status = portainer.EdgeStackRolloutAborted
+	assert.Equal(t, 2, EdgeStackEndpointVersion(edgeStack, 1))
+	assert.Equal(t, "/data/edge_stacks/1/versions/2/docker-compose.yml", EdgeStackFilePath(&portainer.EdgeStack{ProjectPath: "/data/edge_stacks/1", EntryPoint: "docker-compose.yml", Version: 3}, 2))
+}
+
+func Test_UpdateEdgeStackRollout_BatchTimeout(t *testing.T) {
+	tests := []struct {
+		name             string
+		errorThreshold   int
+		expectedStatus   portainer.EdgeStackRolloutStatus
+		expectedEndpoint []portainer.EndpointID
+	}{
+		{name: "unreported endpoints above the threshold", errorThreshold: 20, expectedStatus: portainer.EdgeStackRolloutStopped, expectedEndpoint: []portainer.EndpointID{1, 2}},
+		{name: "unreported endpoints below the threshold", errorThreshold: 50, expectedStatus: portainer.EdgeStackRolloutRunning, expectedEndpoint: []portainer.EndpointID{1, 2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edgeStack := newRolloutEdgeStack()
+			related := []portainer.EndpointID{1, 2, 3, 4}
+
+			StartEdgeStackRollout(edgeStack, &portainer.EdgeStackRollout{Strategy: portainer.EdgeStackRolloutCanary, CanaryPercentage: 50, ErrorThreshold: tt.errorThreshold, BatchTimeout: 60}, 2, related)
+			reportStatus(edgeStack, portainer.StatusOk, 1)
+
+			batchStart := time.Unix(edgeStack.Rollout.BatchStartDate, 0)
+			assert.False(t, UpdateEdgeStackRollout(edgeStack, related, batchStart.Add(59*time.Second)), "endpoint 2 is pending until the batch timeout")
+
+			assert.True(t, UpdateEdgeStackRollout(edgeStack, related, batchStart.Add(60*time.Second)))
+			assert.Equal(t, tt.expectedStatus, edgeStack.Rollout.Status)
+			assert.Equal(t, tt.expectedEndpoint, edgeStack.Rollout.DeployedEndpoints)
+		})
+	}
+}
+
+func Test_ResumeEdgeStackRollout_RestartsBatchTimeout(t *testing.T) {
+	edgeStack := newRolloutEdgeStack()
+	related := []portainer.EndpointID{1, 2}
+
+	StartEdgeStackRollout(edgeStack, &portainer.EdgeStackRollout{Strategy: portainer.EdgeStackRolloutCanary, CanaryPercentage: 50, BatchTimeout: 60}, 2, related)
+	batchStart := time.Unix(edgeStack.Rollout.BatchStartDate, 0)
+
+	UpdateEdgeStackRollout(edgeStack, related, batchStart.Add(time.Hour))
+	assert.Equal(t, portainer.EdgeStackRolloutStopped, edgeStack.Rollout.Status)
+
+	ResumeEdgeStackRollout(edgeStack, related, batchStart.Add(2*time.Hour))
+	assert.Equal(t, portainer.EdgeStackRolloutRunning, edgeStack.Rollout.Status)
+	assert.Equal(t, []portainer.EndpointID{1}, edgeStack.Rollout.DeployedEndpoints)
+}
@@ -0,0 +1,585 @@
+package edge
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// Attributes of an endpoint that can be used in the selector of a dynamic Edge group
+const (
+	selectorAttributeTag           = "tag"
+	selectorAttributeGroup         = "group"
+	selectorAttributeName          = "name"
+	selectorAttributePlatform      = "platform"
+	selectorAttributeDockerVersion = "docker_version"
+	selectorAttributeOS            = "os"
+	selectorAttributeOSName        = "os_name"
+	selectorAttributeCheckInAge    = "checkin_age"
+)
+
+var (
+	selectorMatchOperators   = []string{"==", "!=", "~", "!~"}
+	selectorOrderOperators   = []string{"<", "<=", ">", ">="}
+	selectorOperatorsByField = map[string][]string{
+		selectorAttributeTag:           selectorMatchOperators,
+		selectorAttributeGroup:         selectorMatchOperators,
+		selectorAttributeName:          selectorMatchOperators,
+		selectorAttributePlatform:      {"==", "!="},
+		selectorAttributeDockerVersion: append(append([]string{}, selectorMatchOperators...), selectorOrderOperators...),
+		selectorAttributeOS:            selectorMatchOperators,
+		selectorAttributeOSName:        selectorMatchOperators,
+		selectorAttributeCheckInAge:    selectorOrderOperators,
+	}
+	selectorNegatedOperators = map[string]string{"!=": "==", "!~": "~"}
+	selectorPlatforms        = map[string]portainer.AgentPlatform{
+		"docker":     portainer.AgentPlatformDocker,
+		"kubernetes": portainer.AgentPlatformKubernetes,
+	}
+)
+
+// SelectorEndpoint represents the attributes of an endpoint evaluated by the selector of a dynamic Edge group
+type SelectorEndpoint struct {
+	Name      string
+	GroupName string
+	// Names of the tags of the endpoint and of its endpoint group
+	Tags     []string
+	Platform portainer.AgentPlatform
+	// Docker version and operating system found in the latest snapshot of the endpoint
+	DockerVersion   string
+	OSType          string
+	OperatingSystem string
+	// Time elapsed since the last check-in of the endpoint, negative when it never checked in
+	CheckInAge time.Duration
+}
+
+// EdgeGroupSelector is a parsed selector expression of a dynamic Edge group.
+//
+// A selector compares the attributes of an endpoint to values and combines the comparisons
+// with and, or, not and parentheses, e.g.:
+//
+//	tag == production and (platform == docker or os_name ~ "Ubuntu*") and not checkin_age > 1h
+//
+// tag, group, name, os and os_name support == and != as well as ~ and !~ to match a glob pattern.
+// platform is docker or kubernetes, docker_version can also be ordered with <, <=, > and >=
+// and checkin_age is compared to a duration. A comparison on an attribute the endpoint does not report,
+// like the Docker version of an endpoint without snapshot, is false.
+type EdgeGroupSelector struct {
+	expression selectorNode
+}
+
+type selectorNode interface {
+	match(endpoint *SelectorEndpoint) bool
+}
+
+type (
+	selectorAnd struct {
+		left, right selectorNode
+	}
+
+	selectorOr struct {
+		left, right selectorNode
+	}
+
+	selectorNot struct {
+		node selectorNode
+	}
+
+	selectorComparison struct {
+		attribute string
+		operator  string
+		value     string
+		duration  time.Duration
+	}
+)
+
+func (node *selectorAnd) match(endpoint *SelectorEndpoint) bool {
+	return node.left.match(endpoint) && node.right.match(endpoint)
+}
+
+func (node *selectorOr) match(endpoint *SelectorEndpoint) bool {
+	return node.left.match(endpoint) || node.right.match(endpoint)
+}
+
+func (node *selectorNot) match(endpoint *SelectorEndpoint) bool {
+	return !node.node.match(endpoint)
+}
+
+func (node *selectorComparison) match(endpoint *SelectorEndpoint) bool {
+	switch node.attribute {
+	case selectorAttributeTag:
+		// a negated comparison selects the endpoints without any matching tag
+		operator, negated := node.operator, false
+		if positive, ok := selectorNegatedOperators[operator]; ok {
+			operator, negated = positive, true
+		}
+
+		matched := false
+		for _, tag := range endpoint.Tags {
+			if matchSelectorString(tag, operator, node.value) {
+				matched = true
+				break
+			}
+		}
+		return matched != negated
+	case selectorAttributeGroup:
+		return matchSelectorString(endpoint.GroupName, node.operator, node.value)
+	case selectorAttributeName:
+		return matchSelectorString(endpoint.Name, node.operator, node.value)
+	case selectorAttributePlatform:
+		if endpoint.Platform == 0 {
+			return false
+		}
+		return (endpoint.Platform == selectorPlatforms[strings.ToLower(node.value)]) == (node.operator == "==")
+	case selectorAttributeDockerVersion:
+		if endpoint.DockerVersion == "" {
+			return false
+		}
+		if strings.Contains(node.operator, "<") || strings.Contains(node.operator, ">") {
+			return compareSelectorOrder(compareSelectorVersions(endpoint.DockerVersion, node.value), node.operator)
+		}
+		return matchSelectorString(endpoint.DockerVersion, node.operator, node.value)
+	case selectorAttributeOS:
+		if endpoint.OSType == "" {
+			return false
+		}
+		return matchSelectorString(strings.ToLower(endpoint.OSType), node.operator, strings.ToLower(node.value))
+	case selectorAttributeOSName:
+		if endpoint.OperatingSystem == "" {
+			return false
+		}
+		return matchSelectorString(endpoint.OperatingSystem, node.operator, node.value)
+	case selectorAttributeCheckInAge:
+		if endpoint.CheckInAge < 0 {
+			// an endpoint that never checked in is older than any duration
+			return node.operator == ">" || node.operator == ">="
+		}
+		comparison := 0
+		if endpoint.CheckInAge < node.duration {
+			comparison = -1
+		} else if endpoint.CheckInAge > node.duration {
+			comparison = 1
+		}
+		return compareSelectorOrder(comparison, node.operator)
+	}
+
+	return false
+}
+
+// ParseEdgeGroupSelector parses the selector expression of a dynamic Edge group
+func ParseEdgeGroupSelector(expression string) (*EdgeGroupSelector, error) {
+	tokens, err := tokenizeSelector(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &selectorParser{tokens: tokens}
+	node, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if token := parser.peek(); token.kind != selectorTokenEnd {
+		return nil, fmt.Errorf("Invalid selector: unexpected %q at position %d", token.value, token.position)
+	}
+
+	return &EdgeGroupSelector{expression: node}, nil
+}
+
+// Match returns true when the endpoint is selected
+func (selector *EdgeGroupSelector) Match(endpoint *SelectorEndpoint) bool {
+	return selector.expression.match(endpoint)
+}
+
+// NewSelectorEndpoint returns the attributes of an endpoint evaluated by the selector of a dynamic Edge group.
+// The endpoint group can be nil and tagNames maps the tag identifiers to their names.
+func NewSelectorEndpoint(endpoint *portainer.Endpoint, endpointGroup *portainer.EndpointGroup, tagNames map[portainer.TagID]string, now time.Time) *SelectorEndpoint {
+	selectorEndpoint := &SelectorEndpoint{
+		Name:       endpoint.Name,
+		Tags:       make([]string, 0),
+		Platform:   endpointPlatform(endpoint),
+		CheckInAge: -1,
+	}
+
+	tagIDs := endpoint.TagIDs
+	if endpointGroup != nil {
+		selectorEndpoint.GroupName = endpointGroup.Name
+		tagIDs = append(append([]portainer.TagID{}, tagIDs...), endpointGroup.TagIDs...)
+	}
+
+	for _, tagID := range tagIDs {
+		if name, ok := tagNames[tagID]; ok {
+			selectorEndpoint.Tags = append(selectorEndpoint.Tags, name)
+		}
+	}
+
+	if len(endpoint.Snapshots) > 0 {
+		snapshot := endpoint.Snapshots[len(endpoint.Snapshots)-1]
+		selectorEndpoint.DockerVersion = snapshot.DockerVersion
+		selectorEndpoint.OSType, selectorEndpoint.OperatingSystem = snapshotOperatingSystem(&snapshot)
+	}
+
+	if endpoint.LastCheckInDate != 0 {
+		selectorEndpoint.CheckInAge = now.Sub(time.Unix(endpoint.LastCheckInDate, 0))
+		if selectorEndpoint.CheckInAge < 0 {
+			selectorEndpoint.CheckInAge = 0
+		}
+	}
+
+	return selectorEndpoint
+}
+
+// EqualAttributes returns true when both endpoints have the same attributes, their check-in age excepted,
+// so that the Edge groups they belong to only differ when the selectors depend on the check-in age
+func (selectorEndpoint *SelectorEndpoint) EqualAttributes(other *SelectorEndpoint) bool {
+	if selectorEndpoint.Name != other.Name || selectorEndpoint.GroupName != other.GroupName || selectorEndpoint.Platform != other.Platform ||
+		selectorEndpoint.DockerVersion != other.DockerVersion || selectorEndpoint.OSType != other.OSType ||
+		selectorEndpoint.OperatingSystem != other.OperatingSystem || len(selectorEndpoint.Tags) != len(other.Tags) {
+		return false
+	}
+
+	for idx := range selectorEndpoint.Tags {
+		if selectorEndpoint.Tags[idx] != other.Tags[idx] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// UsesCheckInAge returns true when the selector depends on the last check-in of the endpoints
+func (selector *EdgeGroupSelector) UsesCheckInAge() bool {
+	return selectorUsesAttribute(selector.expression, selectorAttributeCheckInAge)
+}
+
+func selectorUsesAttribute(node selectorNode, attribute string) bool {
+	switch node := node.(type) {
+	case *selectorAnd:
+		return selectorUsesAttribute(node.left, attribute) || selectorUsesAttribute(node.right, attribute)
+	case *selectorOr:
+		return selectorUsesAttribute(node.left, attribute) || selectorUsesAttribute(node.right, attribute)
+	case *selectorNot:
+		return selectorUsesAttribute(node.node, attribute)
+	case *selectorComparison:
+		return node.attribute == attribute
+	}
+	return false
+}
+
+func endpointPlatform(endpoint *portainer.Endpoint) portainer.AgentPlatform {
+	switch endpoint.Type {
+	case portainer.DockerEnvironment, portainer.AgentOnDockerEnvironment, portainer.EdgeAgentOnDockerEnvironment:
+		return portainer.AgentPlatformDocker
+	case portainer.KubernetesLocalEnvironment, portainer.AgentOnKubernetesEnvironment, portainer.EdgeAgentOnKubernetesEnvironment:
+		return portainer.AgentPlatformKubernetes
+	}
+	return 0
+}
+
+// snapshotOperatingSystem returns the operating system reported by the Docker info of the snapshot,
+// which is either a Docker info structure or its JSON representation once loaded from the database
+func snapshotOperatingSystem(snapshot *portainer.DockerSnapshot) (string, string) {
+	if snapshot.SnapshotRaw.Info == nil {
+		return "", ""
+	}
+
+	data, err := json.Marshal(snapshot.SnapshotRaw.Info)
+	if err != nil {
+		return "", ""
+	}
+
+	var info struct {
+		OSType          string
+		OperatingSystem string
+	}
+	err = json.Unmarshal(data, &info)
+	if err != nil {
+		return "", ""
+	}
+
+	return info.OSType, info.OperatingSystem
+}
+
+func matchSelectorString(value, operator, expected string) bool {
+	switch operator {
+	case "==":
+		return value == expected
+	case "!=":
+		return value != expected
+	case "~":
+		matched, _ := path.Match(expected, value)
+		return matched
+	case "!~":
+		matched, _ := path.Match(expected, value)
+		return !matched
+	}
+	return false
+}
+
+func compareSelectorOrder(comparison int, operator string) bool {
+	switch operator {
+	case "<":
+		return comparison < 0
+	case "<=":
+		return comparison <= 0
+	case ">":
+		return comparison > 0
+	case ">=":
+		return comparison >= 0
+	}
+	return false
+}
+
+// compareSelectorVersions compares the numeric parts of two versions, ignoring any suffix like in 19.03.15-ce
+func compareSelectorVersions(version, other string) int {
+	parts, otherParts := selectorVersionParts(version), selectorVersionParts(other)
+	for idx := 0; idx < len(parts) || idx < len(otherParts); idx++ {
+		part, otherPart := 0, 0
+		if idx < len(parts) {
+			part = parts[idx]
+		}
+		if idx < len(otherParts) {
+			otherPart = otherParts[idx]
+		}
+
+		if part < otherPart {
+			return -1
+		} else if part > otherPart {
+			return 1
+		}
+	}
+	return 0
+}
+
+func selectorVersionParts(version string) []int {
+	parts := make([]int, 0)
+	for _, part := range strings.Split(strings.TrimPrefix(version, "v"), ".") {
+		digits := len(part) - len(strings.TrimLeft(part, "0123456789"))
+		if digits == 0 {
+			break
+		}
+
+		number, err := strconv.Atoi(part[:digits])
+		if err != nil {
+			break
+		}
+		parts = append(parts, number)
+
+		if digits < len(part) {
+			break
+		}
+	}
+	return parts
+}
+
+type selectorTokenKind int
+
+const (
+	selectorTokenEnd selectorTokenKind = iota
+	selectorTokenWord
+	selectorTokenString
+	selectorTokenOperator
+	selectorTokenOpenParenthesis
+	selectorTokenCloseParenthesis
+)
+
+type selectorToken struct {
+	kind     selectorTokenKind
+	value    string
+	position int
+}
+
+const selectorOperatorCharacters = "=!<>~"
+
+func tokenizeSelector(expression string) ([]selectorToken, error) {
+	tokens := make([]selectorToken, 0)
+
+	for idx := 0; idx < len(expression); {
+		character := expression[idx]
+
+		switch {
+		case character == ' ' || character == '\t' || character == '\n' || character == '\r':
+			idx++
+		case character == '(':
+			tokens = append(tokens, selectorToken{kind: selectorTokenOpenParenthesis, value: "(", position: idx})
+			idx++
+		case character == ')':
+			tokens = append(tokens, selectorToken{kind: selectorTokenCloseParenthesis, value: ")", position: idx})
+			idx++
+		case character == '"' || character == '\'':
+			end := strings.IndexByte(expression[idx+1:], character)
+			if end == -1 {
+				return nil, fmt.Errorf("Invalid selector: unterminated string at position %d", idx)
+			}
+			tokens = append(tokens, selectorToken{kind: selectorTokenString, value: expression[idx+1 : idx+1+end], position: idx})
+			idx += end + 2
+		case strings.IndexByte(selectorOperatorCharacters, character) != -1:
+			end := idx
+			for end < len(expression) && strings.IndexByte(selectorOperatorCharacters, expression[end]) != -1 {
+				end++
+			}
+			tokens = append(tokens, selectorToken{kind: selectorTokenOperator, value: expression[idx:end], position: idx})
+			idx = end
+		default:
+			end := idx
+			for end < len(expression) && strings.IndexByte(" \t\n\r()\"'"+selectorOperatorCharacters, expression[end]) == -1 {
+				end++
+			}
+			tokens = append(tokens, selectorToken{kind: selectorTokenWord, value: expression[idx:end], position: idx})
+			idx = end
+		}
+	}
+
+	return append(tokens, selectorToken{kind: selectorTokenEnd, position: len(expression)}), nil
+}
+
+type selectorParser struct {
+	tokens   []selectorToken
+	position int
+}
+
+func (parser *selectorParser) peek() selectorToken {
+	return parser.tokens[parser.position]
+}
+
+func (parser *selectorParser) next() selectorToken {
+	token := parser.tokens[parser.position]
+	if token.kind != selectorTokenEnd {
+		parser.position++
+	}
+	return token
+}
+
+func (parser *selectorParser) peekKeyword(keyword string) bool {
+	token := parser.peek()
+	return token.kind == selectorTokenWord && strings.EqualFold(token.value, keyword)
+}
+
+func (parser *selectorParser) parseOr() (selectorNode, error) {
+	left, err := parser.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.peekKeyword("or") {
+		parser.next()
+		right, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &selectorOr{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (parser *selectorParser) parseAnd() (selectorNode, error) {
+	left, err := parser.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.peekKeyword("and") {
+		parser.next()
+		right, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &selectorAnd{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (parser *selectorParser) parseUnary() (selectorNode, error) {
+	if parser.peekKeyword("not") {
+		parser.next()
+		node, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &selectorNot{node: node}, nil
+	}
+
+	if parser.peek().kind == selectorTokenOpenParenthesis {
+		parser.next()
+		node, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		token := parser.next()
+		if token.kind != selectorTokenCloseParenthesis {
+			return nil, fmt.Errorf("Invalid selector: missing closing parenthesis at position %d", token.position)
+		}
+		return node, nil
+	}
+
+	return parser.parseComparison()
+}
+
+func (parser *selectorParser) parseComparison() (selectorNode, error) {
+	token := parser.next()
+	if token.kind != selectorTokenWord {
+		if token.kind == selectorTokenEnd {
+			return nil, fmt.Errorf("Invalid selector: missing attribute at position %d", token.position)
+		}
+		return nil, fmt.Errorf("Invalid selector: unexpected %q at position %d", token.value, token.position)
+	}
+
+	attribute := strings.ToLower(token.value)
+	operators, ok := selectorOperatorsByField[attribute]
+	if !ok {
+		return nil, fmt.Errorf("Invalid selector: unknown attribute %q at position %d", token.value, token.position)
+	}
+
+	token = parser.next()
+	if token.kind != selectorTokenOperator || !containsSelectorOperator(operators, token.value) {
+		return nil, fmt.Errorf("Invalid selector: attribute %s expects one of the operators %s at position %d", attribute, strings.Join(operators, " "), token.position)
+	}
+	operator := token.value
+
+	token = parser.next()
+	if token.kind != selectorTokenWord && token.kind != selectorTokenString {
+		return nil, fmt.Errorf("Invalid selector: missing value for attribute %s at position %d", attribute, token.position)
+	}
+
+	comparison := &selectorComparison{attribute: attribute, operator: operator, value: token.value}
+
+	switch {
+	case attribute == selectorAttributePlatform:
+		if _, ok := selectorPlatforms[strings.ToLower(comparison.value)]; !ok {
+			return nil, fmt.Errorf("Invalid selector: platform must be docker or kubernetes at position %d", token.position)
+		}
+	case attribute == selectorAttributeCheckInAge:
+		duration, err := time.ParseDuration(comparison.value)
+		if err != nil || duration < 0 {
+			return nil, fmt.Errorf("Invalid selector: invalid duration %q at position %d", comparison.value, token.position)
+		}
+		comparison.duration = duration
+	case attribute == selectorAttributeDockerVersion && containsSelectorOperator(selectorOrderOperators, operator):
+		if len(selectorVersionParts(comparison.value)) == 0 {
+			return nil, fmt.Errorf("Invalid selector: invalid version %q at position %d", comparison.value, token.position)
+		}
+	case operator == "~" || operator == "!~":
+		if _, err := path.Match(comparison.value, ""); err != nil {
+			return nil, fmt.Errorf("Invalid selector: invalid pattern %q at position %d", comparison.value, token.position)
+		}
+	}
+
+	return comparison, nil
+}
+
+func containsSelectorOperator(operators []string, operator string) bool {
+	for _, candidate := range operators {
+		if candidate == operator {
+			return true
+		}
+	}
+	return false
+}
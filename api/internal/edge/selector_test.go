@@ -0,0 +1,160 @@
+package edge
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EdgeGroupSelector_Match(t *testing.T) {
+	endpoint := &SelectorEndpoint{
+		Name:            "factory-01",
+		GroupName:       "Factory A",
+		Tags:            []string{"production", "arm64"},
+		Platform:        portainer.AgentPlatformDocker,
+		DockerVersion:   "20.10.7",
+		OSType:          "linux",
+		OperatingSystem: "Ubuntu 20.04.2 LTS",
+		CheckInAge:      30 * time.Second,
+	}
+
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{expression: "tag == production", expected: true},
+		{expression: "tag != production", expected: false},
+		{expression: "tag == staging", expected: false},
+		{expression: "tag !~ 'stag*'", expected: true},
+		{expression: `group == "Factory A"`, expected: true},
+		{expression: "name ~ factory-*", expected: true},
+		{expression: "name ~ test-*", expected: false},
+		{expression: "platform == docker", expected: true},
+		{expression: "platform == kubernetes", expected: false},
+		{expression: "docker_version >= 20.10", expected: true},
+		{expression: "docker_version < 19.03.15-ce", expected: false},
+		{expression: "docker_version ~ 20.10.*", expected: true},
+		{expression: "os == Linux", expected: true},
+		{expression: `os_name ~ "Ubuntu*"`, expected: true},
+		{expression: "checkin_age < 1m", expected: true},
+		{expression: "checkin_age > 5m", expected: false},
+		{expression: "tag == production and platform == kubernetes", expected: false},
+		{expression: "tag == staging or platform == docker", expected: true},
+		{expression: "not tag == staging", expected: true},
+		{expression: "NOT (tag == staging OR name ~ factory-*) and checkin_age<1h", expected: false},
+		{expression: "tag == staging or platform == docker and checkin_age > 1h", expected: false},
+	}
+
+	for _, test := range tests {
+		selector, err := ParseEdgeGroupSelector(test.expression)
+		if assert.NoError(t, err, test.expression) {
+			assert.Equal(t, test.expected, selector.Match(endpoint), test.expression)
+		}
+	}
+}
+
+func Test_EdgeGroupSelector_MissingAttributes(t *testing.T) {
+	endpoint := &SelectorEndpoint{Name: "new-device", CheckInAge: -1}
+
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{expression: "docker_version >= 1.0", expected: false},
+		{expression: "docker_version != 20.10.7", expected: false},
+		{expression: "os != windows", expected: false},
+		{expression: "platform != docker", expected: false},
+		{expression: "checkin_age > 1h", expected: true},
+		{expression: "checkin_age < 1h", expected: false},
+		{expression: "tag != production", expected: true},
+	}
+
+	for _, test := range tests {
+		selector, err := ParseEdgeGroupSelector(test.expression)
+		if assert.NoError(t, err, test.expression) {
+			assert.Equal(t, test.expected, selector.Match(endpoint), test.expression)
+		}
+	}
+}
+
+func Test_ParseEdgeGroupSelector_Errors(t *testing.T) {
+	expressions := []string{
+		"",
+		"tag",
+		"tag ==",
+		"location == paris",
+		"platform == windows",
+		"platform ~ dock*",
+		"checkin_age == 1h",
+		"checkin_age < soon",
+		"docker_version > latest",
+		"name ~ '[a-'",
+		"tag == a and",
+		"(tag == a",
+		"tag == a)",
+		"tag == 'a",
+		"tag === a",
+	}
+
+	for _, expression := range expressions {
+		_, err := ParseEdgeGroupSelector(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func Test_NewSelectorEndpoint(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	endpoint := &portainer.Endpoint{
+		Name:            "factory-01",
+		Type:            portainer.EdgeAgentOnDockerEnvironment,
+		TagIDs:          []portainer.TagID{1},
+		LastCheckInDate: now.Add(-90 * time.Second).Unix(),
+		Snapshots: []portainer.DockerSnapshot{
+			{
+				DockerVersion: "20.10.7",
+				SnapshotRaw: portainer.DockerSnapshotRaw{
+					Info: map[string]interface{}{"OSType": "linux", "OperatingSystem": "Ubuntu 20.04.2 LTS"},
+				},
+			},
+		},
+	}
+	endpointGroup := &portainer.EndpointGroup{Name: "Factory A", TagIDs: []portainer.TagID{2, 3}}
+	tagNames := map[portainer.TagID]string{1: "production", 2: "arm64"}
+
+	assert.Equal(t, &SelectorEndpoint{
+		Name:            "factory-01",
+		GroupName:       "Factory A",
+		Tags:            []string{"production", "arm64"},
+		Platform:        portainer.AgentPlatformDocker,
+		DockerVersion:   "20.10.7",
+		OSType:          "linux",
+		OperatingSystem: "Ubuntu 20.04.2 LTS",
+		CheckInAge:      90 * time.Second,
+	}, NewSelectorEndpoint(endpoint, endpointGroup, tagNames, now))
+
+	endpoint = &portainer.Endpoint{Name: "new-device", Type: portainer.EdgeAgentOnKubernetesEnvironment}
+	assert.Equal(t, &SelectorEndpoint{
+		Name:       "new-device",
+		Tags:       []string{},
+		Platform:   portainer.AgentPlatformKubernetes,
+		CheckInAge: -1,
+	}, NewSelectorEndpoint(endpoint, nil, tagNames, now))
+}
+
+func Test_SelectorEndpoint_EqualAttributes(t *testing.T) {
+	endpoint := &SelectorEndpoint{Name: "factory-01", Tags: []string{"production"}, Platform: portainer.AgentPlatformDocker, DockerVersion: "20.10.7", CheckInAge: time.Minute}
+
+	same := *endpoint
+	same.CheckInAge = time.Hour
+	assert.True(t, endpoint.EqualAttributes(&same), "the check-in age is ignored")
+
+	upgraded := *endpoint
+	upgraded.DockerVersion = "20.10.8"
+	assert.False(t, endpoint.EqualAttributes(&upgraded))
+
+	tagged := *endpoint
+	tagged.Tags = []string{"staging"}
+	assert.False(t, endpoint.EqualAttributes(&tagged))
+}
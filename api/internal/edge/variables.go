@@ -0,0 +1,250 @@
+package edge
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+var variableNamePattern = regexp.MustCompile(`^[_A-Za-z][_A-Za-z0-9]*$`)
+
+// variablePattern matches the escaped dollar signs and the variables of a stack file,
+// either named ($NAME) or braced with an optional default value or error message (${NAME:-default})
+var variablePattern = regexp.MustCompile(`\$(?:(\$)|\{([_A-Za-z][_A-Za-z0-9]*)(?:(:?[-?])([^}]*))?\}|([_A-Za-z][_A-Za-z0-9]*))`)
+
+// EdgeStackEndpoint describes an endpoint the variables of an edge stack are resolved for
+type EdgeStackEndpoint struct {
+	ID portainer.EndpointID
+	// Tags of the endpoint and of its endpoint group
+	TagIDs []portainer.TagID
+	// Edge groups of the edge stack the endpoint belongs to
+	EdgeGroupIDs []portainer.EdgeGroupID
+}
+
+// ValidateEdgeStackVariables checks the names, scopes and uniqueness of the variables of an edge stack
+func ValidateEdgeStackVariables(variables []portainer.EdgeStackVariable) error {
+	names := make(map[string]bool)
+	for _, variable := range variables {
+		if !variableNamePattern.MatchString(variable.Name) {
+			return fmt.Errorf("Invalid variable name: %s", variable.Name)
+		}
+		if names[variable.Name] {
+			return fmt.Errorf("Variable %s is declared more than once", variable.Name)
+		}
+		names[variable.Name] = true
+
+		for _, value := range variable.Values {
+			switch value.Scope {
+			case portainer.EdgeStackVariableEndpointScope, portainer.EdgeStackVariableTagScope, portainer.EdgeStackVariableEdgeGroupScope:
+			default:
+				return errors.New("Invalid value scope for variable " + variable.Name + ". Must be one of: 1 (endpoint), 2 (tag) or 3 (edge group)")
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveEdgeStackVariables returns the values of the variables of the edge stack for the endpoint.
+// It also returns the names of the required variables without a value for the endpoint.
+func ResolveEdgeStackVariables(edgeStack *portainer.EdgeStack, endpoint EdgeStackEndpoint) (map[string]string, []string) {
+	tags := make(map[int]bool)
+	for _, tagID := range endpoint.TagIDs {
+		tags[int(tagID)] = true
+	}
+
+	edgeGroups := make(map[int]bool)
+	for _, edgeGroupID := range endpoint.EdgeGroupIDs {
+		edgeGroups[int(edgeGroupID)] = true
+	}
+
+	values := make(map[string]string)
+	missing := make([]string, 0)
+	for _, variable := range edgeStack.Variables {
+		value, ok := variableValue(variable, func(v portainer.EdgeStackVariableValue) bool {
+			return v.Scope == portainer.EdgeStackVariableEndpointScope && v.ScopeID == int(endpoint.ID)
+		}, func(v portainer.EdgeStackVariableValue) bool {
+			return v.Scope == portainer.EdgeStackVariableTagScope && tags[v.ScopeID]
+		}, func(v portainer.EdgeStackVariableValue) bool {
+			return v.Scope == portainer.EdgeStackVariableEdgeGroupScope && edgeGroups[v.ScopeID]
+		})
+
+		if !ok {
+			value = variable.DefaultValue
+			if variable.Required && value == "" {
+				missing = append(missing, variable.Name)
+				continue
+			}
+		}
+
+		values[variable.Name] = value
+	}
+
+	sort.Strings(missing)
+	return values, missing
+}
+
+// variableValue returns the first value of the variable matched by the matchers, in the matchers order
+func variableValue(variable portainer.EdgeStackVariable, matchers ...func(portainer.EdgeStackVariableValue) bool) (string, bool) {
+	for _, match := range matchers {
+		for _, value := range variable.Values {
+			if match(value) {
+				return value.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// SubstituteEdgeStackVariables replaces the resolved variables in the stack file content.
+// The variables without a resolved value and the escaped dollar signs are left as is for the agent,
+// the dollar signs of the substituted values are escaped.
+func SubstituteEdgeStackVariables(stackFileContent string, values map[string]string) string {
+	if len(values) == 0 {
+		return stackFileContent
+	}
+
+	return variablePattern.ReplaceAllStringFunc(stackFileContent, func(match string) string {
+		groups := variablePattern.FindStringSubmatch(match)
+		if groups[1] != "" {
+			return match
+		}
+
+		name := groups[2]
+		if name == "" {
+			name = groups[5]
+		}
+
+		value, ok := values[name]
+		if !ok {
+			return match
+		}
+
+		if value == "" && groups[3] == ":-" {
+			return groups[4]
+		}
+
+		return strings.Replace(value, "$", "$$", -1)
+	})
+}
+
+// ValidateEdgeStackEndpointVariables returns an error listing the endpoints of the edge stack without a value for a required variable
+func ValidateEdgeStackEndpointVariables(dataStore portainer.DataStore, edgeStack *portainer.EdgeStack) error {
+	if len(edgeStack.Variables) == 0 {
+		return nil
+	}
+
+	err := ValidateEdgeStackVariables(edgeStack.Variables)
+	if err != nil {
+		return err
+	}
+
+	endpoints, err := dataStore.Endpoint().Endpoints()
+	if err != nil {
+		return err
+	}
+
+	endpointGroups, err := dataStore.EndpointGroup().EndpointGroups()
+	if err != nil {
+		return err
+	}
+
+	edgeGroups, err := dataStore.EdgeGroup().EdgeGroups()
+	if err != nil {
+		return err
+	}
+
+	tags, err := dataStore.Tag().Tags()
+	if err != nil {
+		return err
+	}
+
+	membership := NewEdgeGroupMembership(endpointGroups, tags, time.Now())
+
+	relatedEndpoints, err := membership.EdgeStackEndpoints(edgeStack.EdgeGroups, endpoints, edgeGroups)
+	if err != nil {
+		return err
+	}
+	related := endpointSet(relatedEndpoints)
+
+	unresolved := make([]string, 0)
+	for idx := range endpoints {
+		endpoint := &endpoints[idx]
+		if !related[endpoint.ID] {
+			continue
+		}
+
+		_, missing := ResolveEdgeStackVariables(edgeStack, NewEdgeStackEndpoint(edgeStack, endpoint, endpointGroups, edgeGroups, membership))
+		if len(missing) > 0 {
+			unresolved = append(unresolved, fmt.Sprintf("%s (%s)", endpoint.Name, strings.Join(missing, ", ")))
+		}
+	}
+
+	if len(unresolved) > 0 {
+		return errors.New("Required variables have no value for the endpoints: " + strings.Join(unresolved, ", "))
+	}
+
+	return nil
+}
+
+// ResolveEndpointEdgeStackVariables returns the values of the edge stack variables for the endpoint
+// and the names of the required variables without a value
+func ResolveEndpointEdgeStackVariables(dataStore portainer.DataStore, edgeStack *portainer.EdgeStack, endpoint *portainer.Endpoint) (map[string]string, []string, error) {
+	endpointGroups, err := dataStore.EndpointGroup().EndpointGroups()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	edgeGroups, err := dataStore.EdgeGroup().EdgeGroups()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tags, err := dataStore.Tag().Tags()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	membership := NewEdgeGroupMembership(endpointGroups, tags, time.Now())
+
+	values, missing := ResolveEdgeStackVariables(edgeStack, NewEdgeStackEndpoint(edgeStack, endpoint, endpointGroups, edgeGroups, membership))
+	return values, missing, nil
+}
+
+// NewEdgeStackEndpoint returns the tags of the endpoint and the edge groups of the edge stack the endpoint belongs to
+func NewEdgeStackEndpoint(edgeStack *portainer.EdgeStack, endpoint *portainer.Endpoint, endpointGroups []portainer.EndpointGroup, edgeGroups []portainer.EdgeGroup, membership *EdgeGroupMembership) EdgeStackEndpoint {
+	stackEndpoint := EdgeStackEndpoint{
+		ID:           endpoint.ID,
+		TagIDs:       append([]portainer.TagID{}, endpoint.TagIDs...),
+		EdgeGroupIDs: make([]portainer.EdgeGroupID, 0),
+	}
+
+	for _, endpointGroup := range endpointGroups {
+		if endpointGroup.ID == endpoint.GroupID {
+			stackEndpoint.TagIDs = append(stackEndpoint.TagIDs, endpointGroup.TagIDs...)
+			break
+		}
+	}
+
+	stackEdgeGroups := make(map[portainer.EdgeGroupID]bool)
+	for _, edgeGroupID := range edgeStack.EdgeGroups {
+		stackEdgeGroups[edgeGroupID] = true
+	}
+
+	for idx := range edgeGroups {
+		edgeGroup := &edgeGroups[idx]
+		if !stackEdgeGroups[edgeGroup.ID] {
+			continue
+		}
+
+		if membership.Contains(edgeGroup, endpoint) {
+			stackEndpoint.EdgeGroupIDs = append(stackEndpoint.EdgeGroupIDs, edgeGroup.ID)
+		}
+	}
+
+	return stackEndpoint
+}
@@ -0,0 +1,93 @@
+package edge
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ResolveEdgeStackVariables(t *testing.T) {
+	edgeStack := &portainer.EdgeStack{
+		Variables: []portainer.EdgeStackVariable{
+			{
+				Name:         "SITE_ID",
+				DefaultValue: "unknown",
+				Values: []portainer.EdgeStackVariableValue{
+					{Scope: portainer.EdgeStackVariableEdgeGroupScope, ScopeID: 1, Value: "group-1"},
+					{Scope: portainer.EdgeStackVariableTagScope, ScopeID: 2, Value: "tag-2"},
+					{Scope: portainer.EdgeStackVariableEndpointScope, ScopeID: 3, Value: "endpoint-3"},
+				},
+			},
+			{
+				Name:     "UPSTREAM_URL",
+				Required: true,
+				Values: []portainer.EdgeStackVariableValue{
+					{Scope: portainer.EdgeStackVariableEdgeGroupScope, ScopeID: 1, Value: "http://upstream"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		endpoint        EdgeStackEndpoint
+		expectedValues  map[string]string
+		expectedMissing []string
+	}{
+		{
+			name:            "endpoint value",
+			endpoint:        EdgeStackEndpoint{ID: 3, TagIDs: []portainer.TagID{2}, EdgeGroupIDs: []portainer.EdgeGroupID{1}},
+			expectedValues:  map[string]string{"SITE_ID": "endpoint-3", "UPSTREAM_URL": "http://upstream"},
+			expectedMissing: []string{},
+		},
+		{
+			name:            "tag value over edge group value",
+			endpoint:        EdgeStackEndpoint{ID: 4, TagIDs: []portainer.TagID{2}, EdgeGroupIDs: []portainer.EdgeGroupID{1}},
+			expectedValues:  map[string]string{"SITE_ID": "tag-2", "UPSTREAM_URL": "http://upstream"},
+			expectedMissing: []string{},
+		},
+		{
+			name:            "default value and missing required variable",
+			endpoint:        EdgeStackEndpoint{ID: 5, EdgeGroupIDs: []portainer.EdgeGroupID{2}},
+			expectedValues:  map[string]string{"SITE_ID": "unknown"},
+			expectedMissing: []string{"UPSTREAM_URL"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, missing := ResolveEdgeStackVariables(edgeStack, tt.endpoint)
+			assert.Equal(t, tt.expectedValues, values)
+			assert.Equal(t, tt.expectedMissing, missing)
+		})
+	}
+}
+
+func Test_SubstituteEdgeStackVariables(t *testing.T) {
+	content := "image: app:${TAG}\nenvironment:\n  - SITE=$SITE_ID\n  - HOST=${HOSTNAME}\n  - PRICE=$$5\n  - MODE=${MODE:-standalone}\n"
+	values := map[string]string{"TAG": "1.2", "SITE_ID": "site$1", "MODE": ""}
+
+	expected := "image: app:1.2\nenvironment:\n  - SITE=site$$1\n  - HOST=${HOSTNAME}\n  - PRICE=$$5\n  - MODE=standalone\n"
+	assert.Equal(t, expected, SubstituteEdgeStackVariables(content, values))
+}
+
+func Test_ValidateEdgeStackVariables(t *testing.T) {
+	tests := []struct {
+		name      string
+		variables []portainer.EdgeStackVariable
+		valid     bool
+	}{
+		{name: "valid variables", variables: []portainer.EdgeStackVariable{{Name: "SITE_ID", Values: []portainer.EdgeStackVariableValue{{Scope: portainer.EdgeStackVariableTagScope, ScopeID: 1}}}}, valid: true},
+		{name: "invalid name", variables: []portainer.EdgeStackVariable{{Name: "1SITE"}}, valid: false},
+		{name: "duplicate name", variables: []portainer.EdgeStackVariable{{Name: "SITE"}, {Name: "SITE"}}, valid: false},
+		{name: "invalid scope", variables: []portainer.EdgeStackVariable{{Name: "SITE", Values: []portainer.EdgeStackVariableValue{{Scope: 4}}}}, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEdgeStackVariables(tt.variables)
+			assert.Equal(t, tt.valid, err == nil)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package edge
+
+import (
+	"path"
+	"strconv"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// HasEdgeStackVersion returns true when the files of the specified version of the edge stack are still available
+func HasEdgeStackVersion(edgeStack *portainer.EdgeStack, version int) bool {
+	if version == edgeStack.Version {
+		return true
+	}
+
+	for _, previousVersion := range edgeStack.PreviousVersions {
+		if previousVersion == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MaxPreviousVersions returns the number of previous versions kept for each edge stack
+func MaxPreviousVersions(settings *portainer.Settings) int {
+	if settings.EdgeStackMaxPreviousVersions <= 0 {
+		return portainer.DefaultEdgeStackMaxPreviousVersions
+	}
+	return settings.EdgeStackMaxPreviousVersions
+}
+
+// AddEdgeStackPreviousVersion adds the version to the previous versions kept for the edge stack.
+// Only the last maxPreviousVersions versions are kept, the version the running rollout
+// falls back to is never removed. It returns the versions whose files can be removed.
+func AddEdgeStackPreviousVersion(edgeStack *portainer.EdgeStack, version, maxPreviousVersions int) []int {
+	edgeStack.PreviousVersions = append(edgeStack.PreviousVersions, version)
+
+	protectedVersion := 0
+	if edgeStack.Rollout != nil && edgeStack.Rollout.Status != portainer.EdgeStackRolloutCompleted {
+		protectedVersion = edgeStack.Rollout.PreviousVersion
+	}
+
+	removed := make([]int, 0)
+	kept := make([]int, 0)
+	excess := len(edgeStack.PreviousVersions) - maxPreviousVersions
+	for _, previousVersion := range edgeStack.PreviousVersions {
+		if excess > 0 && previousVersion != protectedVersion {
+			removed = append(removed, previousVersion)
+			excess--
+			continue
+		}
+		kept = append(kept, previousVersion)
+	}
+
+	edgeStack.PreviousVersions = kept
+	return removed
+}
+
+// StoreEdgeStackPreviousVersion keeps the file of the current version of the edge stack in its version folder,
+// before the edge stack is updated to a new version, and removes the files of the versions no longer kept
+func StoreEdgeStackPreviousVersion(fileService portainer.FileService, edgeStack *portainer.EdgeStack, maxPreviousVersions int) error {
+	content, err := fileService.GetFileContent(path.Join(edgeStack.ProjectPath, edgeStack.EntryPoint))
+	if err != nil {
+		return err
+	}
+
+	versionFolder := path.Join(strconv.Itoa(int(edgeStack.ID)), EdgeStackVersionFolder(edgeStack.Version), path.Dir(edgeStack.EntryPoint))
+	_, err = fileService.StoreEdgeStackFileFromBytes(versionFolder, path.Base(edgeStack.EntryPoint), content)
+	if err != nil {
+		return err
+	}
+
+	for _, version := range AddEdgeStackPreviousVersion(edgeStack, edgeStack.Version, maxPreviousVersions) {
+		err = fileService.RemoveDirectory(path.Join(edgeStack.ProjectPath, EdgeStackVersionFolder(version)))
+		if err != nil {
+			logger.WithError(err).WithField("edge_stack_id", edgeStack.ID).WithField("version", version).Warn("unable to remove the files of a previous version of the edge stack")
+		}
+	}
+
+	return nil
+}
+
+// RecordEdgeStackEndpointStatus records the status of the version deployed on the endpoint.
+// When auto rollback is enabled and the endpoint fails to deploy the version targeted for it,
+// the endpoint is sent back to the last version it deployed successfully.
+func RecordEdgeStackEndpointStatus(edgeStack *portainer.EdgeStack, endpointID portainer.EndpointID, status portainer.EdgeStackStatusType, version int) {
+	if edgeStack.EndpointDeployments == nil {
+		edgeStack.EndpointDeployments = make(map[portainer.EndpointID]portainer.EdgeStackEndpointDeployment)
+	}
+
+	deployment := edgeStack.EndpointDeployments[endpointID]
+	deployment.Version = version
+	deployment.Status = status
+	deployment.UpdateDate = time.Now().Unix()
+
+	switch status {
+	case portainer.StatusOk:
+		deployment.LastOkVersion = version
+	case portainer.StatusError:
+		targetVersion := edgeStackTargetVersion(edgeStack, endpointID)
+		if edgeStack.AutoRollback && version == targetVersion && deployment.LastOkVersion != 0 &&
+			deployment.LastOkVersion != version && HasEdgeStackVersion(edgeStack, deployment.LastOkVersion) {
+			deployment.FailedVersion = version
+			deployment.RollbackVersion = deployment.LastOkVersion
+		}
+	}
+
+	edgeStack.EndpointDeployments[endpointID] = deployment
+}
+
+// edgeStackRollbackVersion returns the version the endpoint was sent back to after failing to deploy
+// the target version, 0 when the endpoint is not rolled back for this version
+func edgeStackRollbackVersion(edgeStack *portainer.EdgeStack, endpointID portainer.EndpointID, targetVersion int) int {
+	deployment, ok := edgeStack.EndpointDeployments[endpointID]
+	if !ok || deployment.RollbackVersion == 0 || deployment.FailedVersion != targetVersion {
+		return 0
+	}
+
+	if !HasEdgeStackVersion(edgeStack, deployment.RollbackVersion) {
+		return 0
+	}
+
+	return deployment.RollbackVersion
+}
@@ -0,0 +1,115 @@
+package edge
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AddEdgeStackPreviousVersion(t *testing.T) {
+	tests := []struct {
+		name                string
+		previousVersions    []int
+		rollout             *portainer.EdgeStackRollout
+		version             int
+		maxPreviousVersions int
+		expectedVersions    []int
+		expectedRemoved     []int
+	}{
+		{
+			name:                "below the limit",
+			previousVersions:    []int{1, 2},
+			version:             3,
+			maxPreviousVersions: 5,
+			expectedVersions:    []int{1, 2, 3},
+			expectedRemoved:     []int{},
+		},
+		{
+			name:                "above the limit",
+			previousVersions:    []int{1, 2, 3, 4, 5},
+			version:             6,
+			maxPreviousVersions: 5,
+			expectedVersions:    []int{2, 3, 4, 5, 6},
+			expectedRemoved:     []int{1},
+		},
+		{
+			name:                "keeps the version of a running rollout",
+			previousVersions:    []int{1, 2, 3, 4, 5},
+			rollout:             &portainer.EdgeStackRollout{Status: portainer.EdgeStackRolloutRunning, PreviousVersion: 1},
+			version:             6,
+			maxPreviousVersions: 5,
+			expectedVersions:    []int{1, 3, 4, 5, 6},
+			expectedRemoved:     []int{2},
+		},
+		{
+			name:                "above a custom limit",
+			previousVersions:    []int{1, 2, 3},
+			version:             4,
+			maxPreviousVersions: 2,
+			expectedVersions:    []int{3, 4},
+			expectedRemoved:     []int{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edgeStack := &portainer.EdgeStack{Version: tt.version + 1, PreviousVersions: tt.previousVersions, Rollout: tt.rollout}
+
+			removed := AddEdgeStackPreviousVersion(edgeStack, tt.version, tt.maxPreviousVersions)
+
+			assert.Equal(t, tt.expectedVersions, edgeStack.PreviousVersions)
+			assert.Equal(t, tt.expectedRemoved, removed)
+		})
+	}
+}
+
+func Test_MaxPreviousVersions(t *testing.T) {
+	assert.Equal(t, portainer.DefaultEdgeStackMaxPreviousVersions, MaxPreviousVersions(&portainer.Settings{}))
+	assert.Equal(t, 10, MaxPreviousVersions(&portainer.Settings{EdgeStackMaxPreviousVersions: 10}))
+}
+
+func Test_RecordEdgeStackEndpointStatus_RollsBackFailedEndpoint(t *testing.T) {
+	edgeStack := &portainer.EdgeStack{Version: 2, AutoRollback: true}
+
+	RecordEdgeStackEndpointStatus(edgeStack, 1, portainer.StatusOk, 2)
+	edgeStack.PreviousVersions = []int{2}
+	edgeStack.Version = 3
+
+	RecordEdgeStackEndpointStatus(edgeStack, 1, portainer.StatusError, 3)
+	assert.Equal(t, 2, EdgeStackEndpointVersion(edgeStack, 1))
+	assert.Equal(t, 2, EdgeStackEndpointRollback(edgeStack, 1))
+
+	RecordEdgeStackEndpointStatus(edgeStack, 1, portainer.StatusOk, 2)
+	assert.Equal(t, 2, EdgeStackEndpointVersion(edgeStack, 1), "the endpoint stays on the rollback version")
+
+	edgeStack.PreviousVersions = []int{2, 3}
+	edgeStack.Version = 4
+	assert.Equal(t, 4, EdgeStackEndpointVersion(edgeStack, 1), "a new version is deployed again")
+	assert.Equal(t, 0, EdgeStackEndpointRollback(edgeStack, 1))
+}
+
+func Test_RecordEdgeStackEndpointStatus_WithoutRollback(t *testing.T) {
+	tests := []struct {
+		name             string
+		autoRollback     bool
+		previousVersions []int
+	}{
+		{name: "auto rollback disabled", autoRollback: false, previousVersions: []int{2}},
+		{name: "last successful version removed", autoRollback: true, previousVersions: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edgeStack := &portainer.EdgeStack{Version: 2, AutoRollback: tt.autoRollback}
+
+			RecordEdgeStackEndpointStatus(edgeStack, 1, portainer.StatusOk, 2)
+			edgeStack.PreviousVersions = tt.previousVersions
+			edgeStack.Version = 3
+
+			RecordEdgeStackEndpointStatus(edgeStack, 1, portainer.StatusError, 3)
+			assert.Equal(t, 3, EdgeStackEndpointVersion(edgeStack, 1))
+			assert.Equal(t, 0, EdgeStackEndpointRollback(edgeStack, 1))
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package edgefleet
+
+import (
+	"sort"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// EndpointHealth represents the health of an Edge endpoint based on its check-ins
+type EndpointHealth string
+
+const (
+	// EndpointHealthy represents an Edge endpoint checking in at its interval
+	EndpointHealthy EndpointHealth = "healthy"
+	// EndpointLate represents an Edge endpoint that missed at least LateMissedCheckins check-ins
+	EndpointLate EndpointHealth = "late"
+	// EndpointOffline represents an Edge endpoint that missed at least OfflineMissedCheckins check-ins or never checked in
+	EndpointOffline EndpointHealth = "offline"
+)
+
+const (
+	// LateMissedCheckins is the number of missed check-ins after which an Edge endpoint is late
+	LateMissedCheckins = 1
+	// OfflineMissedCheckins is the number of missed check-ins after which an Edge endpoint is offline
+	OfflineMissedCheckins = 3
+)
+
+type (
+	// Report represents the health of the Edge endpoints and the state of their Edge stacks and jobs
+	Report struct {
+		Summary    Summary
+		Endpoints  []EndpointReport
+		EdgeStacks []EdgeStackReport
+	}
+
+	// Summary represents the number of Edge endpoints for each health
+	Summary struct {
+		Endpoints int `example:"10"`
+		Healthy   int `example:"7"`
+		Late      int `example:"2"`
+		Offline   int `example:"1"`
+		// Number of Edge endpoints that missed more check-ins than the alert threshold of the settings
+		Alerts int `example:"1"`
+		// Number of Edge jobs waiting for a run or a retry across the Edge endpoints
+		PendingEdgeJobs int `example:"3"`
+	}
+
+	// EndpointReport represents the health of an Edge endpoint
+	EndpointReport struct {
+		ID     portainer.EndpointID `json:"Id" example:"1"`
+		Name   string               `example:"factory-01"`
+		Health EndpointHealth       `example:"late"`
+		// Date of the last check-in in unix time, 0 when the endpoint never checked in
+		LastCheckInDate int64 `example:"1587399600"`
+		// Check-in interval of the endpoint in seconds
+		CheckinInterval int `example:"5"`
+		// Number of check-ins missed since the last one
+		MissedCheckins int `example:"2"`
+		// Whether the endpoint missed more check-ins than the alert threshold of the settings
+		Alert bool `example:"false"`
+		// Number of Edge jobs waiting for a run or a retry on the endpoint
+		PendingEdgeJobs int `example:"1"`
+	}
+
+	// EdgeStackReport represents the deployment state of an Edge stack on the Edge endpoints of the report
+	EdgeStackReport struct {
+		ID      portainer.EdgeStackID `json:"Id" example:"1"`
+		Name    string                `example:"monitoring"`
+		Version int                   `example:"3"`
+		// Number of endpoints the Edge stack is deployed to
+		Targeted int `example:"10"`
+		// Number of endpoints that deployed the current version
+		Ok int `example:"7"`
+		// Number of endpoints that failed to deploy the current version
+		Error int `example:"1"`
+		// Number of endpoints that acknowledged the current version but did not deploy it yet
+		Acknowledged int `example:"1"`
+		// Number of endpoints that did not report the current version yet
+		Pending int `example:"1"`
+	}
+)
+
+// CheckinInterval returns the check-in interval of an Edge endpoint in seconds
+func CheckinInterval(endpoint *portainer.Endpoint, settings *portainer.Settings) int {
+	interval := settings.EdgeAgentCheckinInterval
+	if endpoint.EdgeCheckinInterval != 0 {
+		interval = endpoint.EdgeCheckinInterval
+	}
+
+	if interval <= 0 {
+		interval = portainer.DefaultEdgeAgentCheckinIntervalInSeconds
+	}
+
+	return interval
+}
+
+// MissedCheckins returns the number of check-ins an Edge endpoint missed since its last check-in.
+// A check-in is only missed after a whole interval without it, to tolerate agents checking in slightly late.
+// An endpoint that never checked in has missed -1 check-ins.
+func MissedCheckins(endpoint *portainer.Endpoint, interval int, now time.Time) int {
+	if endpoint.LastCheckInDate == 0 {
+		return -1
+	}
+
+	elapsed := now.Unix() - endpoint.LastCheckInDate
+	missed := int(elapsed/int64(interval)) - 1
+	if missed < 0 {
+		return 0
+	}
+
+	return missed
+}
+
+// Health returns the health of an Edge endpoint from the number of check-ins it missed
+func Health(missedCheckins int) EndpointHealth {
+	switch {
+	case missedCheckins < 0 || missedCheckins >= OfflineMissedCheckins:
+		return EndpointOffline
+	case missedCheckins >= LateMissedCheckins:
+		return EndpointLate
+	}
+	return EndpointHealthy
+}
+
+// Alert returns true when the number of missed check-ins reaches the alert threshold of the settings, 0 disabling the alert
+func Alert(missedCheckins int, settings *portainer.Settings) bool {
+	return settings.EdgeAgentCheckinAlertThreshold > 0 && missedCheckins >= settings.EdgeAgentCheckinAlertThreshold
+}
+
+// NewReport returns the health of the Edge endpoints and the deployment state of the Edge stacks targeting them.
+// edgeStackEndpoints associates each Edge stack to the endpoints it is deployed to.
+func NewReport(endpoints []portainer.Endpoint, edgeStacks []portainer.EdgeStack, edgeStackEndpoints map[portainer.EdgeStackID][]portainer.EndpointID, edgeJobs []portainer.EdgeJob, settings *portainer.Settings, now time.Time) *Report {
+	report := &Report{
+		Endpoints:  make([]EndpointReport, 0),
+		EdgeStacks: make([]EdgeStackReport, 0),
+	}
+
+	reported := make(map[portainer.EndpointID]bool)
+	for idx := range endpoints {
+		endpoint := &endpoints[idx]
+		if endpoint.Type != portainer.EdgeAgentOnDockerEnvironment && endpoint.Type != portainer.EdgeAgentOnKubernetesEnvironment {
+			continue
+		}
+		reported[endpoint.ID] = true
+
+		interval := CheckinInterval(endpoint, settings)
+		missed := MissedCheckins(endpoint, interval, now)
+
+		endpointReport := EndpointReport{
+			ID:              endpoint.ID,
+			Name:            endpoint.Name,
+			Health:          Health(missed),
+			LastCheckInDate: endpoint.LastCheckInDate,
+			CheckinInterval: interval,
+			MissedCheckins:  missed,
+			Alert:           Alert(missed, settings),
+			PendingEdgeJobs: pendingEdgeJobs(endpoint.ID, edgeJobs),
+		}
+		if endpointReport.MissedCheckins < 0 {
+			endpointReport.MissedCheckins = 0
+		}
+
+		report.Summary.Endpoints++
+		switch endpointReport.Health {
+		case EndpointHealthy:
+			report.Summary.Healthy++
+		case EndpointLate:
+			report.Summary.Late++
+		case EndpointOffline:
+			report.Summary.Offline++
+		}
+		if endpointReport.Alert {
+			report.Summary.Alerts++
+		}
+		report.Summary.PendingEdgeJobs += endpointReport.PendingEdgeJobs
+
+		report.Endpoints = append(report.Endpoints, endpointReport)
+	}
+
+	for _, edgeStack := range edgeStacks {
+		edgeStackReport := EdgeStackReport{
+			ID:      edgeStack.ID,
+			Name:    edgeStack.Name,
+			Version: edgeStack.Version,
+		}
+
+		for _, endpointID := range edgeStackEndpoints[edgeStack.ID] {
+			if !reported[endpointID] {
+				continue
+			}
+			edgeStackReport.Targeted++
+
+			status, ok := edgeStack.Status[endpointID]
+			switch {
+			case !ok:
+				edgeStackReport.Pending++
+			case status.Type == portainer.StatusOk:
+				edgeStackReport.Ok++
+			case status.Type == portainer.StatusError:
+				edgeStackReport.Error++
+			case status.Type == portainer.StatusAcknowledged:
+				edgeStackReport.Acknowledged++
+			default:
+				edgeStackReport.Pending++
+			}
+		}
+
+		if edgeStackReport.Targeted > 0 {
+			report.EdgeStacks = append(report.EdgeStacks, edgeStackReport)
+		}
+	}
+
+	sort.Slice(report.Endpoints, func(i, j int) bool {
+		return report.Endpoints[i].ID < report.Endpoints[j].ID
+	})
+	sort.Slice(report.EdgeStacks, func(i, j int) bool {
+		return report.EdgeStacks[i].ID < report.EdgeStacks[j].ID
+	})
+
+	return report
+}
+
+// pendingEdgeJobs returns the number of Edge jobs the endpoint did not report a result for yet or that wait for a retry
+func pendingEdgeJobs(endpointID portainer.EndpointID, edgeJobs []portainer.EdgeJob) int {
+	pending := 0
+	for _, edgeJob := range edgeJobs {
+		meta, ok := edgeJob.Endpoints[endpointID]
+		if ok && (meta.Run == 0 || meta.RetryDate != 0) {
+			pending++
+		}
+	}
+	return pending
+}
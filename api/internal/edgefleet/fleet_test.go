@@ -0,0 +1,73 @@
+package edgefleet
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MissedCheckins(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	tests := []struct {
+		lastCheckIn    int64
+		expectedMissed int
+		expectedHealth EndpointHealth
+	}{
+		{lastCheckIn: now.Unix() - 3, expectedMissed: 0, expectedHealth: EndpointHealthy},
+		{lastCheckIn: now.Unix() - 9, expectedMissed: 0, expectedHealth: EndpointHealthy},
+		{lastCheckIn: now.Unix() - 10, expectedMissed: 1, expectedHealth: EndpointLate},
+		{lastCheckIn: now.Unix() - 19, expectedMissed: 2, expectedHealth: EndpointLate},
+		{lastCheckIn: now.Unix() - 20, expectedMissed: 3, expectedHealth: EndpointOffline},
+		{lastCheckIn: 0, expectedMissed: -1, expectedHealth: EndpointOffline},
+	}
+
+	for _, test := range tests {
+		endpoint := &portainer.Endpoint{LastCheckInDate: test.lastCheckIn}
+		missed := MissedCheckins(endpoint, 5, now)
+		assert.Equal(t, test.expectedMissed, missed, "last check-in %d", test.lastCheckIn)
+		assert.Equal(t, test.expectedHealth, Health(missed), "last check-in %d", test.lastCheckIn)
+	}
+}
+
+func Test_NewReport(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	settings := &portainer.Settings{EdgeAgentCheckinInterval: 5, EdgeAgentCheckinAlertThreshold: 5}
+	endpoints := []portainer.Endpoint{
+		{ID: 3, Name: "offline", Type: portainer.EdgeAgentOnDockerEnvironment, LastCheckInDate: now.Unix() - 60},
+		{ID: 1, Name: "healthy", Type: portainer.EdgeAgentOnDockerEnvironment, LastCheckInDate: now.Unix() - 2},
+		{ID: 2, Name: "late", Type: portainer.EdgeAgentOnKubernetesEnvironment, LastCheckInDate: now.Unix() - 60, EdgeCheckinInterval: 30},
+		{ID: 4, Name: "local", Type: portainer.DockerEnvironment},
+	}
+	edgeStacks := []portainer.EdgeStack{
+		{
+			ID:      1,
+			Name:    "monitoring",
+			Version: 2,
+			Status: map[portainer.EndpointID]portainer.EdgeStackStatus{
+				1: {Type: portainer.StatusOk},
+				2: {Type: portainer.StatusError},
+			},
+		},
+		{ID: 2, Name: "unrelated"},
+	}
+	edgeStackEndpoints := map[portainer.EdgeStackID][]portainer.EndpointID{1: {1, 2, 3}, 2: {4}}
+	edgeJobs := []portainer.EdgeJob{
+		{ID: 1, Endpoints: map[portainer.EndpointID]portainer.EdgeJobEndpointMeta{1: {Run: 1}, 3: {}}},
+		{ID: 2, Endpoints: map[portainer.EndpointID]portainer.EdgeJobEndpointMeta{1: {Run: 1, RetryDate: now.Unix()}}},
+	}
+
+	report := NewReport(endpoints, edgeStacks, edgeStackEndpoints, edgeJobs, settings, now)
+
+	assert.Equal(t, Summary{Endpoints: 3, Healthy: 1, Late: 1, Offline: 1, Alerts: 1, PendingEdgeJobs: 2}, report.Summary)
+	assert.Equal(t, []EndpointReport{
+		{ID: 1, Name: "healthy", Health: EndpointHealthy, LastCheckInDate: now.Unix() - 2, CheckinInterval: 5, PendingEdgeJobs: 1},
+		{ID: 2, Name: "late", Health: EndpointLate, LastCheckInDate: now.Unix() - 60, CheckinInterval: 30, MissedCheckins: 1},
+		{ID: 3, Name: "offline", Health: EndpointOffline, LastCheckInDate: now.Unix() - 60, CheckinInterval: 5, MissedCheckins: 11, Alert: true, PendingEdgeJobs: 1},
+	}, report.Endpoints)
+	assert.Equal(t, []EdgeStackReport{
+		{ID: 1, Name: "monitoring", Version: 2, Targeted: 3, Ok: 1, Error: 1, Pending: 1},
+	}, report.EdgeStacks)
+}
@@ -0,0 +1,161 @@
+package edgefleet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/internal/logging"
+	"github.com/portainer/portainer/api/internal/notification"
+)
+
+var logger = logging.Component("edgefleet")
+
+// monitorInterval is the interval between two checks of the check-ins of the Edge endpoints
+const monitorInterval = time.Minute
+
+// Monitor raises an alert when an Edge endpoint misses more check-ins than the alert threshold of the settings
+// and clears it once the endpoint checks in again. It also updates the Edge stacks related to the Edge endpoints
+// when the Edge groups select the endpoints on their check-in age, which changes without the endpoints checking in
+type Monitor struct {
+	dataStore           portainer.DataStore
+	notificationService portainer.NotificationService
+	shutdownCtx         context.Context
+	alerts              map[portainer.EndpointID]bool
+}
+
+// NewMonitor creates a new instance of a monitor
+func NewMonitor(dataStore portainer.DataStore, notificationService portainer.NotificationService, shutdownCtx context.Context) *Monitor {
+	return &Monitor{
+		dataStore:           dataStore,
+		notificationService: notificationService,
+		shutdownCtx:         shutdownCtx,
+		alerts:              make(map[portainer.EndpointID]bool),
+	}
+}
+
+// Start will start a background routine checking the check-ins of the Edge endpoints
+func (monitor *Monitor) Start() {
+	ticker := time.NewTicker(monitorInterval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				err := monitor.checkEndpoints(time.Now())
+				if err != nil {
+					logger.WithError(err).Error("background schedule error (edge check-in monitoring)")
+				}
+			case <-monitor.shutdownCtx.Done():
+				logger.Debug("shutting down edge check-in monitoring")
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+func (monitor *Monitor) checkEndpoints(now time.Time) error {
+	settings, err := monitor.dataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	endpoints, err := monitor.dataStore.Endpoint().Endpoints()
+	if err != nil {
+		return err
+	}
+
+	for _, transition := range monitor.update(endpoints, settings, now) {
+		if transition.alert {
+			logger.WithField(logging.FieldEndpointID, transition.endpoint.ID).WithField("endpoint", transition.endpoint.Name).WithField("missed_checkins", transition.missedCheckins).Warn("edge endpoint missed too many check-ins")
+
+			event := notification.NewEndpointEvent(portainer.NotificationEventEdgeCheckinMissed, transition.endpoint, fmt.Sprintf("Edge endpoint %s missed %d check-ins", transition.endpoint.Name, transition.missedCheckins))
+			event.Details = map[string]string{"missedCheckins": fmt.Sprint(transition.missedCheckins)}
+			monitor.notificationService.Notify(event)
+		} else {
+			logger.WithField(logging.FieldEndpointID, transition.endpoint.ID).WithField("endpoint", transition.endpoint.Name).Info("edge endpoint checked in again")
+
+			monitor.notificationService.Notify(notification.NewEndpointEvent(portainer.NotificationEventEdgeCheckinResumed, transition.endpoint, fmt.Sprintf("Edge endpoint %s checked in again", transition.endpoint.Name)))
+		}
+	}
+
+	return monitor.updateCheckInAgeRelations(endpoints, now)
+}
+
+// updateCheckInAgeRelations recomputes the Edge stacks related to the Edge endpoints when the membership
+// of an Edge group depends on the check-in age of the endpoints
+func (monitor *Monitor) updateCheckInAgeRelations(endpoints []portainer.Endpoint, now time.Time) error {
+	edgeGroups, err := monitor.dataStore.EdgeGroup().EdgeGroups()
+	if err != nil {
+		return err
+	}
+
+	if !edge.EdgeGroupsUseCheckInAge(edgeGroups) {
+		return nil
+	}
+
+	endpointGroups, err := monitor.dataStore.EndpointGroup().EndpointGroups()
+	if err != nil {
+		return err
+	}
+
+	tags, err := monitor.dataStore.Tag().Tags()
+	if err != nil {
+		return err
+	}
+
+	edgeStacks, err := monitor.dataStore.EdgeStack().EdgeStacks()
+	if err != nil {
+		return err
+	}
+
+	membership := edge.NewEdgeGroupMembership(endpointGroups, tags, now)
+	for idx := range endpoints {
+		endpoint := &endpoints[idx]
+		if endpoint.Type != portainer.EdgeAgentOnDockerEnvironment && endpoint.Type != portainer.EdgeAgentOnKubernetesEnvironment {
+			continue
+		}
+
+		_, err := membership.UpdateEndpointRelation(monitor.dataStore, endpoint, edgeGroups, edgeStacks)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type alertTransition struct {
+	endpoint       *portainer.Endpoint
+	missedCheckins int
+	alert          bool
+}
+
+// update records the alerts of the Edge endpoints and returns the endpoints whose alert was raised or cleared
+func (monitor *Monitor) update(endpoints []portainer.Endpoint, settings *portainer.Settings, now time.Time) []alertTransition {
+	transitions := make([]alertTransition, 0)
+	current := make(map[portainer.EndpointID]bool)
+
+	for idx := range endpoints {
+		endpoint := &endpoints[idx]
+		if endpoint.Type != portainer.EdgeAgentOnDockerEnvironment && endpoint.Type != portainer.EdgeAgentOnKubernetesEnvironment {
+			continue
+		}
+
+		missed := MissedCheckins(endpoint, CheckinInterval(endpoint, settings), now)
+		alert := Alert(missed, settings)
+		if alert {
+			current[endpoint.ID] = true
+		}
+
+		if alert != monitor.alerts[endpoint.ID] {
+			transitions = append(transitions, alertTransition{endpoint: endpoint, missedCheckins: missed, alert: alert})
+		}
+	}
+
+	monitor.alerts = current
+
+	return transitions
+}
@@ -0,0 +1,39 @@
+package edgefleet
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Monitor_RaisesAlertsOnce(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	settings := &portainer.Settings{EdgeAgentCheckinInterval: 5, EdgeAgentCheckinAlertThreshold: 3}
+	endpoints := []portainer.Endpoint{
+		{ID: 1, Type: portainer.EdgeAgentOnDockerEnvironment, LastCheckInDate: now.Unix() - 30},
+		{ID: 2, Type: portainer.EdgeAgentOnDockerEnvironment, LastCheckInDate: now.Unix()},
+	}
+
+	monitor := NewMonitor(nil, nil, nil)
+
+	transitions := monitor.update(endpoints, settings, now)
+	if assert.Len(t, transitions, 1) {
+		assert.Equal(t, portainer.EndpointID(1), transitions[0].endpoint.ID)
+		assert.True(t, transitions[0].alert)
+		assert.Equal(t, 5, transitions[0].missedCheckins)
+	}
+
+	assert.Empty(t, monitor.update(endpoints, settings, now), "an alert is only raised once")
+
+	endpoints[0].LastCheckInDate = now.Unix()
+	transitions = monitor.update(endpoints, settings, now)
+	if assert.Len(t, transitions, 1) {
+		assert.False(t, transitions[0].alert, "the alert is cleared after a check-in")
+	}
+
+	settings.EdgeAgentCheckinAlertThreshold = 0
+	endpoints[0].LastCheckInDate = now.Unix() - 3600
+	assert.Empty(t, monitor.update(endpoints, settings, now), "a threshold of 0 disables the alerts")
+}
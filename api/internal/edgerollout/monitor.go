@@ -0,0 +1,85 @@
+package edgerollout
+
+import (
+	"context"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/internal/logging"
+)
+
+// monitorInterval is the interval between two evaluations of the running edge stack rollouts
+const monitorInterval = time.Minute
+
+var logger = logging.Component("edgerollout")
+
+// Monitor evaluates the running edge stack rollouts at a regular interval, so that a batch whose endpoints
+// never report their status is counted as failed once its timeout expires instead of stalling the rollout
+type Monitor struct {
+	dataStore   portainer.DataStore
+	shutdownCtx context.Context
+}
+
+// NewMonitor creates a new instance of a monitor
+func NewMonitor(dataStore portainer.DataStore, shutdownCtx context.Context) *Monitor {
+	return &Monitor{
+		dataStore:   dataStore,
+		shutdownCtx: shutdownCtx,
+	}
+}
+
+// Start will start a background routine evaluating the running edge stack rollouts
+func (monitor *Monitor) Start() {
+	ticker := time.NewTicker(monitorInterval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				err := monitor.checkRollouts(time.Now())
+				if err != nil {
+					logger.WithError(err).Error("background schedule error (edge stack rollouts)")
+				}
+			case <-monitor.shutdownCtx.Done():
+				logger.Debug("shutting down edge stack rollout monitoring")
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+func (monitor *Monitor) checkRollouts(now time.Time) error {
+	edgeStacks, err := monitor.dataStore.EdgeStack().EdgeStacks()
+	if err != nil {
+		return err
+	}
+
+	for idx := range edgeStacks {
+		edgeStack := &edgeStacks[idx]
+
+		rollout := edgeStack.Rollout
+		if rollout == nil || rollout.Status != portainer.EdgeStackRolloutRunning || rollout.Version != edgeStack.Version {
+			continue
+		}
+
+		relatedEndpoints, err := edge.LoadEdgeStackEndpoints(monitor.dataStore, edgeStack.EdgeGroups)
+		if err != nil {
+			return err
+		}
+
+		// the rollout is evaluated on the edge stack read in the transaction, so that the status reported
+		// by the endpoints and the changes made to the edge stack in the meantime are not overwritten
+		err = monitor.dataStore.EdgeStack().UpdateEdgeStackFunc(edgeStack.ID, func(edgeStack *portainer.EdgeStack) {
+			if edge.UpdateEdgeStackRollout(edgeStack, relatedEndpoints, now) && edgeStack.Rollout.Status == portainer.EdgeStackRolloutStopped {
+				logger.WithField("edge_stack_id", edgeStack.ID).Warn(edgeStack.Rollout.Error)
+			}
+		})
+		if err != nil && err != bolterrors.ErrObjectNotFound {
+			return err
+		}
+	}
+
+	return nil
+}
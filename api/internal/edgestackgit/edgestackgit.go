@@ -0,0 +1,290 @@
+package edgestackgit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/filesystem"
+	gittypes "github.com/portainer/portainer/api/git/types"
+	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/internal/logging"
+)
+
+var logger = logging.Component("edgestackgit")
+
+// ErrNotGitEdgeStack is returned when updating an edge stack which is not created from git
+var ErrNotGitEdgeStack = errors.New("Edge stack is not created from git")
+
+// ErrInvalidStackFile is returned when the stack file retrieved from the repository is empty
+var ErrInvalidStackFile = errors.New("Invalid stack file content")
+
+// MinimumInterval is the minimum interval between two checks of the repository of an edge stack
+const MinimumInterval = time.Minute
+
+// Service keeps the git edge stacks up to date with their repository.
+// A new version of the edge stack is created whenever the commit of the followed reference changes.
+type Service struct {
+	mu            sync.Mutex
+	dataStore     portainer.DataStore
+	fileService   portainer.FileService
+	gitService    portainer.GitService
+	secretService portainer.SecretService
+	shutdownCtx   context.Context
+	lastChecks    map[portainer.EdgeStackID]time.Time
+}
+
+// NewService creates a new instance of a service
+func NewService(dataStore portainer.DataStore, fileService portainer.FileService, gitService portainer.GitService, secretService portainer.SecretService, shutdownCtx context.Context) *Service {
+	return &Service{
+		dataStore:     dataStore,
+		fileService:   fileService,
+		gitService:    gitService,
+		secretService: secretService,
+		shutdownCtx:   shutdownCtx,
+		lastChecks:    make(map[portainer.EdgeStackID]time.Time),
+	}
+}
+
+// Start will start a background routine checking the repositories of the git edge stacks at their polling interval
+func (service *Service) Start() {
+	ticker := time.NewTicker(MinimumInterval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				err := service.checkEdgeStacks()
+				if err != nil {
+					logger.WithError(err).Error("background schedule error (git edge stack update)")
+				}
+			case <-service.shutdownCtx.Done():
+				logger.Debug("shutting down git edge stack updates")
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// ParseInterval parses the polling interval of an edge stack
+func ParseInterval(interval string) (time.Duration, error) {
+	duration, err := time.ParseDuration(interval)
+	if err != nil {
+		return 0, err
+	}
+
+	if duration < MinimumInterval {
+		return 0, errors.New("Interval must be at least " + MinimumInterval.String())
+	}
+
+	return duration, nil
+}
+
+func (service *Service) checkEdgeStacks() error {
+	edgeStacks, err := service.dataStore.EdgeStack().EdgeStacks()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, edgeStack := range edgeStacks {
+		if edgeStack.GitConfig == nil || edgeStack.AutoUpdate == nil || edgeStack.AutoUpdate.Interval == "" {
+			continue
+		}
+
+		interval, err := ParseInterval(edgeStack.AutoUpdate.Interval)
+		if err != nil {
+			logger.WithField("edge_stack", edgeStack.Name).WithError(err).Warn("invalid auto update interval")
+			continue
+		}
+
+		if now.Sub(service.lastChecks[edgeStack.ID]) < interval {
+			continue
+		}
+		service.lastChecks[edgeStack.ID] = now
+
+		_, err = service.UpdateEdgeStack(edgeStack.ID)
+		if err != nil {
+			logger.WithField("edge_stack", edgeStack.Name).WithError(err).Warn("unable to update the edge stack from git")
+		}
+	}
+
+	return nil
+}
+
+// UpdateEdgeStack creates a new version of the edge stack when the commit of the followed reference changed.
+// The new version goes through the validation of an edge stack update and is deployed with the rollout
+// settings of the previous version, if any. It returns true when the edge stack was updated.
+func (service *Service) UpdateEdgeStack(edgeStackID portainer.EdgeStackID) (bool, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	edgeStack, err := service.dataStore.EdgeStack().EdgeStack(edgeStackID)
+	if err != nil {
+		return false, err
+	}
+
+	if edgeStack.GitConfig == nil {
+		return false, ErrNotGitEdgeStack
+	}
+
+	username, password, err := service.credentials(edgeStack.GitConfig)
+	if err != nil {
+		return false, err
+	}
+
+	commitID, err := service.gitService.LatestCommitID(edgeStack.GitConfig.URL, edgeStack.GitConfig.ReferenceName, username, password)
+	if err != nil {
+		return false, err
+	}
+
+	if commitID == edgeStack.GitConfig.ConfigHash {
+		return false, nil
+	}
+
+	stackFileContent, err := service.retrieveStackFile(edgeStack.GitConfig, commitID, username, password)
+	if err != nil {
+		return false, err
+	}
+
+	if len(bytes.TrimSpace(stackFileContent)) == 0 {
+		return false, ErrInvalidStackFile
+	}
+
+	err = edge.ValidateEdgeStackEndpointVariables(service.dataStore, edgeStack)
+	if err != nil {
+		return false, err
+	}
+
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return false, err
+	}
+
+	var relatedEndpoints []portainer.EndpointID
+	if edgeStack.Rollout != nil {
+		relatedEndpoints, err = edge.LoadEdgeStackEndpoints(service.dataStore, edgeStack.EdgeGroups)
+		if err != nil {
+			return false, err
+		}
+	}
+	rolloutLoaded := edgeStack.Rollout != nil
+
+	// the new version is created on the edge stack read in the transaction, so that the status reported by the
+	// endpoints and the changes made to the edge stack while the repository was cloned are not overwritten
+	gitConfig := *edgeStack.GitConfig
+	updated := false
+	var updateErr error
+	err = service.dataStore.EdgeStack().UpdateEdgeStackFunc(edgeStackID, func(current *portainer.EdgeStack) {
+		// the update is skipped when the repository or the rollout settings of the edge stack changed in the meantime,
+		// it is retried on the next check
+		if current.GitConfig == nil || current.GitConfig.ConfigHash == commitID || current.GitConfig.URL != gitConfig.URL ||
+			current.GitConfig.ReferenceName != gitConfig.ReferenceName || current.GitConfig.ConfigFilePath != gitConfig.ConfigFilePath ||
+			(current.Rollout != nil) != rolloutLoaded {
+			return
+		}
+
+		previous := *current
+		updateErr = service.createVersion(current, stackFileContent, commitID, edge.MaxPreviousVersions(settings), relatedEndpoints)
+		if updateErr != nil {
+			*current = previous
+			return
+		}
+
+		edgeStack = current
+		updated = true
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if updateErr != nil || !updated {
+		return false, updateErr
+	}
+
+	logger.WithField("edge_stack", edgeStack.Name).WithField("commit", commitID).WithField("version", edgeStack.Version).Info("edge stack updated from git")
+
+	return true, nil
+}
+
+// createVersion stores the stack file of the commit as a new version of the edge stack. The previous version is kept
+// and the new version is deployed with the rollout settings of the previous version, if any.
+func (service *Service) createVersion(edgeStack *portainer.EdgeStack, stackFileContent []byte, commitID string, maxPreviousVersions int, relatedEndpoints []portainer.EndpointID) error {
+	// the endpoints not reached by a rollout yet keep running the version deployed before it
+	previousRollout := edgeStack.Rollout
+	previousVersion := edgeStack.Version
+	if previousRollout != nil && previousRollout.Version == edgeStack.Version && previousRollout.Status != portainer.EdgeStackRolloutCompleted {
+		previousVersion = previousRollout.PreviousVersion
+	}
+
+	err := edge.StoreEdgeStackPreviousVersion(service.fileService, edgeStack, maxPreviousVersions)
+	if err != nil {
+		return err
+	}
+
+	stackFolder := path.Join(strconv.Itoa(int(edgeStack.ID)), path.Dir(edgeStack.EntryPoint))
+	_, err = service.fileService.StoreEdgeStackFileFromBytes(stackFolder, path.Base(edgeStack.EntryPoint), stackFileContent)
+	if err != nil {
+		return err
+	}
+
+	edgeStack.Version++
+	edgeStack.Status = map[portainer.EndpointID]portainer.EdgeStackStatus{}
+	edgeStack.Rollout = nil
+	edgeStack.GitConfig.ConfigHash = commitID
+
+	if previousRollout != nil {
+		edge.StartEdgeStackRollout(edgeStack, &portainer.EdgeStackRollout{
+			Strategy:         previousRollout.Strategy,
+			CanaryPercentage: previousRollout.CanaryPercentage,
+			CanaryEndpoints:  previousRollout.CanaryEndpoints,
+			BatchSize:        previousRollout.BatchSize,
+			ErrorThreshold:   previousRollout.ErrorThreshold,
+			BatchTimeout:     previousRollout.BatchTimeout,
+		}, previousVersion, relatedEndpoints)
+	}
+
+	return nil
+}
+
+// retrieveStackFile clones the repository at the commit in a temporary folder and returns the content of the stack file
+func (service *Service) retrieveStackFile(gitConfig *gittypes.RepoConfig, commitID, username, password string) ([]byte, error) {
+	tmpDir, err := ioutil.TempDir("", "edge-stack-git")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = service.gitService.CloneRepositoryAtCommit(tmpDir, gitConfig.URL, gitConfig.ReferenceName, commitID, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	stackFilePath, err := filesystem.JoinPaths(tmpDir, gitConfig.ConfigFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(stackFilePath)
+}
+
+// credentials returns the username and the decrypted password used to access the repository
+func (service *Service) credentials(gitConfig *gittypes.RepoConfig) (string, string, error) {
+	if gitConfig.Authentication == nil {
+		return "", "", nil
+	}
+
+	password, err := service.secretService.Decrypt(gitConfig.Authentication.Password)
+	if err != nil {
+		return "", "", err
+	}
+
+	return gitConfig.Authentication.Username, password, nil
+}
@@ -0,0 +1,206 @@
+package edgestackgit
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/crypto"
+	"github.com/portainer/portainer/api/filesystem"
+	gittypes "github.com/portainer/portainer/api/git/types"
+	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubGitService struct {
+	commitID         string
+	stackFileContent string
+	username         string
+	password         string
+	clonedCommitID   string
+	// onClone is called when the repository is cloned, to change the data while the edge stack is updated
+	onClone func()
+}
+
+func (service *stubGitService) CloneRepository(destination string, repositoryURL, referenceName string, username, password string) error {
+	service.username, service.password = username, password
+	if service.onClone != nil {
+		service.onClone()
+	}
+
+	err := os.MkdirAll(filepath.Join(destination, "deploy"), 0700)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(destination, "deploy", "docker-compose.yml"), []byte(service.stackFileContent), 0600)
+}
+
+func (service *stubGitService) CloneRepositoryAtCommit(destination string, repositoryURL, referenceName, commitID string, username, password string) error {
+	service.clonedCommitID = commitID
+	return service.CloneRepository(destination, repositoryURL, referenceName, username, password)
+}
+
+func (service *stubGitService) LatestCommitID(repositoryURL, referenceName, username, password string) (string, error) {
+	return service.commitID, nil
+}
+
+func Test_UpdateEdgeStack(t *testing.T) {
+	dir, err := ioutil.TempDir("", "edgestackgit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fileService, err := filesystem.NewService(dir, "")
+	assert.NoError(t, err)
+
+	projectPath, err := fileService.StoreEdgeStackFileFromBytes("1/deploy", "docker-compose.yml", []byte("version 1"))
+	assert.NoError(t, err)
+
+	edgeStack := portainer.EdgeStack{
+		ID:          1,
+		Name:        "edge-stack",
+		ProjectPath: filepath.Dir(projectPath),
+		EntryPoint:  "deploy/docker-compose.yml",
+		Version:     1,
+		Status:      map[portainer.EndpointID]portainer.EdgeStackStatus{1: {Type: portainer.StatusOk, EndpointID: 1}},
+		GitConfig:   &gittypes.RepoConfig{URL: "https://github.com/portainer/edge", ConfigFilePath: "deploy/docker-compose.yml", ConfigHash: "commit-1"},
+	}
+	dataStore := testhelpers.NewDatastore(testhelpers.WithEdgeStacks([]portainer.EdgeStack{edgeStack}), testhelpers.WithSettings(&portainer.Settings{}))
+	gitService := &stubGitService{commitID: "commit-1", stackFileContent: "version 2"}
+
+	service := NewService(dataStore, fileService, gitService, newSecretService(t), context.Background())
+
+	updated, err := service.UpdateEdgeStack(1)
+	assert.NoError(t, err)
+	assert.False(t, updated, "the edge stack is not updated when the commit did not change")
+
+	gitService.commitID = "commit-2"
+	updated, err = service.UpdateEdgeStack(1)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	result, err := dataStore.EdgeStack().EdgeStack(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Version)
+	assert.Equal(t, []int{1}, result.PreviousVersions)
+	assert.Equal(t, "commit-2", result.GitConfig.ConfigHash)
+	assert.Empty(t, result.Status)
+
+	content, err := fileService.GetFileContent(edge.EdgeStackFilePath(result, 2))
+	assert.NoError(t, err)
+	assert.Equal(t, "version 2", string(content))
+
+	content, err = fileService.GetFileContent(edge.EdgeStackFilePath(result, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, "version 1", string(content))
+}
+
+func Test_UpdateEdgeStack_KeepsConcurrentChanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "edgestackgit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fileService, err := filesystem.NewService(dir, "")
+	assert.NoError(t, err)
+
+	projectPath, err := fileService.StoreEdgeStackFileFromBytes("1/deploy", "docker-compose.yml", []byte("version 1"))
+	assert.NoError(t, err)
+
+	edgeStack := portainer.EdgeStack{
+		ID:          1,
+		Name:        "edge-stack",
+		ProjectPath: filepath.Dir(projectPath),
+		EntryPoint:  "deploy/docker-compose.yml",
+		Version:     1,
+		GitConfig:   &gittypes.RepoConfig{URL: "https://github.com/portainer/edge", ConfigFilePath: "deploy/docker-compose.yml", ConfigHash: "commit-1"},
+	}
+	dataStore := testhelpers.NewDatastore(testhelpers.WithEdgeStacks([]portainer.EdgeStack{edgeStack}), testhelpers.WithSettings(&portainer.Settings{}))
+	gitService := &stubGitService{commitID: "commit-2", stackFileContent: "version 2"}
+	gitService.onClone = func() {
+		err := dataStore.EdgeStack().UpdateEdgeStackFunc(1, func(edgeStack *portainer.EdgeStack) {
+			edge.RecordEdgeStackEndpointStatus(edgeStack, 1, portainer.StatusOk, 1)
+			edgeStack.AutoRollback = true
+		})
+		assert.NoError(t, err)
+	}
+
+	service := NewService(dataStore, fileService, gitService, newSecretService(t), context.Background())
+
+	updated, err := service.UpdateEdgeStack(1)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, "commit-2", gitService.clonedCommitID, "the repository is cloned at the resolved commit")
+
+	result, err := dataStore.EdgeStack().EdgeStack(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Version)
+	assert.True(t, result.AutoRollback)
+	assert.Equal(t, 1, result.EndpointDeployments[1].LastOkVersion)
+}
+
+func Test_UpdateEdgeStack_NotFromGit(t *testing.T) {
+	dataStore := testhelpers.NewDatastore(testhelpers.WithEdgeStacks([]portainer.EdgeStack{{ID: 1, Version: 1}}))
+	service := NewService(dataStore, nil, testhelpers.NewGitService(), newSecretService(t), context.Background())
+
+	_, err := service.UpdateEdgeStack(1)
+	assert.Equal(t, ErrNotGitEdgeStack, err)
+}
+
+func Test_UpdateEdgeStack_Validation(t *testing.T) {
+	secretService := newSecretService(t)
+	encryptedPassword, err := secretService.Encrypt("password")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name             string
+		configFilePath   string
+		stackFileContent string
+		variables        []portainer.EdgeStackVariable
+		expectedErr      error
+	}{
+		{name: "stack file outside the repository", configFilePath: "../../etc/passwd", stackFileContent: "version 2", expectedErr: filesystem.ErrPathOutsideRoot},
+		{name: "empty stack file", configFilePath: "deploy/docker-compose.yml", stackFileContent: " \n", expectedErr: ErrInvalidStackFile},
+		{name: "invalid variables", configFilePath: "deploy/docker-compose.yml", stackFileContent: "version 2", variables: []portainer.EdgeStackVariable{{Name: "1SITE"}}, expectedErr: errors.New("Invalid variable name: 1SITE")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			edgeStack := portainer.EdgeStack{
+				ID:         1,
+				EntryPoint: "deploy/docker-compose.yml",
+				Version:    1,
+				Variables:  test.variables,
+				GitConfig: &gittypes.RepoConfig{
+					URL:            "https://github.com/portainer/edge",
+					ConfigFilePath: test.configFilePath,
+					ConfigHash:     "commit-1",
+					Authentication: &gittypes.GitAuthentication{Username: "user", Password: encryptedPassword},
+				},
+			}
+			dataStore := testhelpers.NewDatastore(testhelpers.WithEdgeStacks([]portainer.EdgeStack{edgeStack}), testhelpers.WithSettings(&portainer.Settings{}))
+			gitService := &stubGitService{commitID: "commit-2", stackFileContent: test.stackFileContent}
+
+			service := NewService(dataStore, nil, gitService, secretService, context.Background())
+
+			updated, err := service.UpdateEdgeStack(1)
+			assert.Equal(t, test.expectedErr, err)
+			assert.False(t, updated)
+			assert.Equal(t, "password", gitService.password, "the repository is cloned with the decrypted password")
+
+			result, err := dataStore.EdgeStack().EdgeStack(1)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, result.Version)
+		})
+	}
+}
+
+func newSecretService(t *testing.T) portainer.SecretService {
+	secretService, err := crypto.NewSecretService([]byte("private key"))
+	assert.NoError(t, err)
+	return secretService
+}
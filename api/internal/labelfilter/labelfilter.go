@@ -0,0 +1,212 @@
+package labelfilter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/logging"
+)
+
+var logger = logging.Component("labelfilter")
+
+// Filter is a compiled label filter rule that can be matched against the labels of a Docker resource
+type Filter struct {
+	rule  portainer.LabelFilter
+	name  *regexp.Regexp
+	value *regexp.Regexp
+}
+
+// Compile validates a label filter rule and compiles its name and value patterns.
+// Patterns must match the whole label name or value.
+func Compile(rule portainer.LabelFilter) (*Filter, error) {
+	if rule.Name == "" {
+		return nil, errors.New("label filter name cannot be empty")
+	}
+
+	name, err := compilePattern(rule.Name, rule.MatchType)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &Filter{
+		rule: rule,
+		name: name,
+	}
+
+	if rule.Value != "" {
+		filter.value, err = compilePattern(rule.Value, rule.MatchType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return filter, nil
+}
+
+// CompileAll returns the filters of the settings applying to the specified endpoint group and resource type.
+// The filters are only compiled again when the label filters or black listed labels of the settings change.
+func CompileAll(settings *portainer.Settings, groupID portainer.EndpointGroupID, resourceType portainer.ResourceControlType) []*Filter {
+	filters := make([]*Filter, 0)
+
+	for _, filter := range compileSettings(settings) {
+		if filter.AppliesTo(groupID, resourceType) {
+			filters = append(filters, filter)
+		}
+	}
+
+	return filters
+}
+
+var compiled struct {
+	sync.Mutex
+	rules             []portainer.LabelFilter
+	blackListedLabels []portainer.Pair
+	filters           []*Filter
+}
+
+// compileSettings compiles the label filters and the legacy black listed labels of the settings.
+// Invalid rules are skipped so that a single invalid rule does not break every resource list.
+func compileSettings(settings *portainer.Settings) []*Filter {
+	compiled.Lock()
+	defer compiled.Unlock()
+
+	if compiled.filters != nil &&
+		reflect.DeepEqual(compiled.rules, settings.LabelFilters) &&
+		reflect.DeepEqual(compiled.blackListedLabels, settings.BlackListedLabels) {
+		return compiled.filters
+	}
+
+	filters := make([]*Filter, 0, len(settings.LabelFilters)+len(settings.BlackListedLabels))
+
+	for _, label := range settings.BlackListedLabels {
+		filter, err := compileBlackListedLabel(label)
+		if err != nil {
+			logger.WithField("label", label.Name).WithError(err).Warn("ignoring invalid black listed label")
+			continue
+		}
+		filters = append(filters, filter)
+	}
+
+	for _, rule := range settings.LabelFilters {
+		filter, err := Compile(rule)
+		if err != nil {
+			logger.WithField("label", rule.Name).WithError(err).Warn("ignoring invalid label filter")
+			continue
+		}
+		filters = append(filters, filter)
+	}
+
+	compiled.rules = settings.LabelFilters
+	compiled.blackListedLabels = settings.BlackListedLabels
+	compiled.filters = filters
+
+	return filters
+}
+
+// compileBlackListedLabel converts a legacy black listed label to a rule hiding containers in every endpoint.
+// Unlike label filters, an empty value only matches the labels whose value is empty.
+func compileBlackListedLabel(label portainer.Pair) (*Filter, error) {
+	filter, err := Compile(portainer.LabelFilter{
+		Name:          label.Name,
+		Value:         label.Value,
+		MatchType:     portainer.LabelFilterMatchExact,
+		ResourceTypes: []portainer.ResourceControlType{portainer.ContainerResourceControl},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.value == nil {
+		filter.value = regexp.MustCompile("^$")
+	}
+
+	return filter, nil
+}
+
+// AppliesTo returns true if the rule is scoped to the specified endpoint group and resource type
+func (filter *Filter) AppliesTo(groupID portainer.EndpointGroupID, resourceType portainer.ResourceControlType) bool {
+	if len(filter.rule.EndpointGroupIDs) > 0 {
+		found := false
+		for _, id := range filter.rule.EndpointGroupIDs {
+			if id == groupID {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(filter.rule.ResourceTypes) == 0 {
+		return true
+	}
+
+	for _, t := range filter.rule.ResourceTypes {
+		if t == resourceType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Matches returns true if at least one of the labels matches the rule
+func (filter *Filter) Matches(labels map[string]interface{}) bool {
+	for labelName, labelValue := range labels {
+		if !filter.name.MatchString(labelName) {
+			continue
+		}
+
+		if filter.value == nil {
+			return true
+		}
+
+		value, ok := labelValue.(string)
+		if ok && filter.value.MatchString(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchesAny returns true if the labels match at least one of the filters
+func MatchesAny(filters []*Filter, labels map[string]interface{}) bool {
+	for _, filter := range filters {
+		if filter.Matches(labels) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func compilePattern(pattern string, matchType portainer.LabelFilterMatchType) (*regexp.Regexp, error) {
+	switch matchType {
+	case portainer.LabelFilterMatchExact:
+		return regexp.Compile("^" + regexp.QuoteMeta(pattern) + "$")
+	case portainer.LabelFilterMatchGlob:
+		return regexp.Compile("^" + globToRegexp(pattern) + "$")
+	case portainer.LabelFilterMatchRegex:
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid label filter regular expression %q: %w", pattern, err)
+		}
+		return re, nil
+	}
+
+	return nil, fmt.Errorf("invalid label filter match type: %d", matchType)
+}
+
+func globToRegexp(pattern string) string {
+	quoted := regexp.QuoteMeta(pattern)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	return strings.ReplaceAll(quoted, `\?`, ".")
+}
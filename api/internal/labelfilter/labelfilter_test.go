@@ -0,0 +1,117 @@
+package labelfilter
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Matches(t *testing.T) {
+	labels := map[string]interface{}{
+		"com.docker.compose.project": "system-monitoring-42",
+		"io.portainer.agent":         "true",
+	}
+
+	cases := []struct {
+		description string
+		rule        portainer.LabelFilter
+		expected    bool
+	}{
+		{
+			description: "exact name and value",
+			rule:        portainer.LabelFilter{Name: "io.portainer.agent", Value: "true", MatchType: portainer.LabelFilterMatchExact},
+			expected:    true,
+		},
+		{
+			description: "exact name, any value",
+			rule:        portainer.LabelFilter{Name: "io.portainer.agent", MatchType: portainer.LabelFilterMatchExact},
+			expected:    true,
+		},
+		{
+			description: "exact does not match partial value",
+			rule:        portainer.LabelFilter{Name: "com.docker.compose.project", Value: "system", MatchType: portainer.LabelFilterMatchExact},
+			expected:    false,
+		},
+		{
+			description: "glob value",
+			rule:        portainer.LabelFilter{Name: "com.docker.compose.project", Value: "system-*", MatchType: portainer.LabelFilterMatchGlob},
+			expected:    true,
+		},
+		{
+			description: "glob name",
+			rule:        portainer.LabelFilter{Name: "io.portainer.*", MatchType: portainer.LabelFilterMatchGlob},
+			expected:    true,
+		},
+		{
+			description: "glob dots are literal",
+			rule:        portainer.LabelFilter{Name: "io?portainer*", Value: "t?ue", MatchType: portainer.LabelFilterMatchGlob},
+			expected:    true,
+		},
+		{
+			description: "regex value",
+			rule:        portainer.LabelFilter{Name: "com\\.docker\\..*", Value: "system-[a-z]+-[0-9]+", MatchType: portainer.LabelFilterMatchRegex},
+			expected:    true,
+		},
+		{
+			description: "regex is anchored",
+			rule:        portainer.LabelFilter{Name: "compose", MatchType: portainer.LabelFilterMatchRegex},
+			expected:    false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.description, func(t *testing.T) {
+			filter, err := Compile(tt.rule)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, filter.Matches(labels))
+		})
+	}
+}
+
+func Test_Compile_InvalidRules(t *testing.T) {
+	_, err := Compile(portainer.LabelFilter{Name: "", MatchType: portainer.LabelFilterMatchExact})
+	assert.Error(t, err)
+
+	_, err = Compile(portainer.LabelFilter{Name: "a", MatchType: 0})
+	assert.Error(t, err)
+
+	_, err = Compile(portainer.LabelFilter{Name: "(", MatchType: portainer.LabelFilterMatchRegex})
+	assert.Error(t, err)
+}
+
+func Test_CompileAll_Scope(t *testing.T) {
+	settings := &portainer.Settings{
+		LabelFilters: []portainer.LabelFilter{
+			{Name: "a", MatchType: portainer.LabelFilterMatchExact, EndpointGroupIDs: []portainer.EndpointGroupID{2}},
+			{Name: "b", MatchType: portainer.LabelFilterMatchExact, ResourceTypes: []portainer.ResourceControlType{portainer.VolumeResourceControl}},
+		},
+		BlackListedLabels: []portainer.Pair{{Name: "c", Value: "d"}},
+	}
+
+	filters := CompileAll(settings, 1, portainer.ContainerResourceControl)
+	assert.Len(t, filters, 1, "only the legacy black listed label should apply to containers outside of group 2")
+
+	filters = CompileAll(settings, 2, portainer.VolumeResourceControl)
+	assert.Len(t, filters, 2)
+
+	filters = CompileAll(settings, 1, portainer.NetworkResourceControl)
+	assert.Len(t, filters, 0)
+}
+
+func Test_CompileAll_BlackListedLabels(t *testing.T) {
+	settings := &portainer.Settings{
+		LabelFilters:      []portainer.LabelFilter{{Name: "(", MatchType: portainer.LabelFilterMatchRegex}},
+		BlackListedLabels: []portainer.Pair{{Name: "hidden", Value: ""}, {Name: "", Value: "ignored"}},
+	}
+
+	filters := CompileAll(settings, 1, portainer.ContainerResourceControl)
+	assert.Len(t, filters, 1, "invalid rules should be skipped")
+
+	assert.True(t, MatchesAny(filters, map[string]interface{}{"hidden": ""}))
+	assert.False(t, MatchesAny(filters, map[string]interface{}{"hidden": "true"}), "an empty legacy value should only match empty values")
+
+	settings.BlackListedLabels = []portainer.Pair{{Name: "hidden", Value: "true"}}
+	filters = CompileAll(settings, 1, portainer.ContainerResourceControl)
+	assert.True(t, MatchesAny(filters, map[string]interface{}{"hidden": "true"}), "filters should be compiled again when the settings change")
+}
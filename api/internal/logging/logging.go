@@ -0,0 +1,150 @@
+// Package logging provides the structured and leveled logger used by Portainer.
+// Every entry carries a component field and, when relevant, the endpoint, stack,
+// user and request the entry is related to, so that the logs can be shipped to
+// and queried from a log aggregation pipeline.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// FormatText represents the human readable output format
+	FormatText = "text"
+	// FormatJSON represents the JSON output format, one entry per line
+	FormatJSON = "json"
+	// DefaultLevel represents the level used when none is configured
+	DefaultLevel = "INFO"
+	// DefaultFormat represents the output format used when none is configured
+	DefaultFormat = FormatText
+)
+
+// Field names shared by the entries of all the components
+const (
+	FieldComponent  = "component"
+	FieldEndpointID = "endpoint_id"
+	FieldStackID    = "stack_id"
+	FieldUser       = "user"
+	FieldRequestID  = "request_id"
+)
+
+var logger = logrus.New()
+
+func init() {
+	logger.SetOutput(os.Stderr)
+	logger.SetLevel(logrus.InfoLevel)
+	logger.SetFormatter(newFormatter(DefaultFormat))
+}
+
+// Configure sets the level and the output format of the logger and redirects the output
+// of the standard library logger to it
+func Configure(level, format string) error {
+	err := SetLevel(level)
+	if err != nil {
+		return err
+	}
+
+	if format != FormatText && format != FormatJSON {
+		return fmt.Errorf("Invalid log format: %s. Valid values are: %s or %s", format, FormatText, FormatJSON)
+	}
+	logger.SetFormatter(newFormatter(format))
+
+	log.SetFlags(0)
+	log.SetOutput(stdlibWriter{})
+
+	return nil
+}
+
+// SetLevel changes the level of the logger, entries below this level are discarded
+func SetLevel(level string) error {
+	parsedLevel, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	logger.SetLevel(parsedLevel)
+	return nil
+}
+
+// Level returns the name of the current level of the logger
+func Level() string {
+	switch logger.GetLevel() {
+	case logrus.DebugLevel:
+		return "DEBUG"
+	case logrus.WarnLevel:
+		return "WARN"
+	case logrus.ErrorLevel:
+		return "ERROR"
+	}
+	return "INFO"
+}
+
+// ParseLevel returns the level matching a level name. Valid names are DEBUG, INFO, WARN and ERROR
+func ParseLevel(level string) (logrus.Level, error) {
+	switch strings.ToUpper(level) {
+	case "DEBUG":
+		return logrus.DebugLevel, nil
+	case "INFO":
+		return logrus.InfoLevel, nil
+	case "WARN", "WARNING":
+		return logrus.WarnLevel, nil
+	case "ERROR":
+		return logrus.ErrorLevel, nil
+	}
+
+	return logrus.InfoLevel, fmt.Errorf("Invalid log level: %s. Valid values are: DEBUG, INFO, WARN or ERROR", level)
+}
+
+// AddHook adds a hook fired for each entry, used to add fields to the entries
+func AddHook(hook logrus.Hook) {
+	logger.AddHook(hook)
+}
+
+// Component returns a logger whose entries are tagged with the name of a component
+func Component(name string) *logrus.Entry {
+	return logger.WithField(FieldComponent, name)
+}
+
+func newFormatter(format string) logrus.Formatter {
+	if format == FormatJSON {
+		return &logrus.JSONFormatter{}
+	}
+
+	return &logrus.TextFormatter{
+		DisableColors:          true,
+		FullTimestamp:          true,
+		DisableLevelTruncation: true,
+	}
+}
+
+// legacyLevels maps the level tags of the messages logged through the standard library logger
+var legacyLevels = map[string]logrus.Level{
+	"[DEBUG]": logrus.DebugLevel,
+	"[INFO]":  logrus.InfoLevel,
+	"[WARN]":  logrus.WarnLevel,
+	"[ERROR]": logrus.ErrorLevel,
+}
+
+// stdlibWriter forwards the messages of the standard library logger to the logger.
+// Messages are logged at the level of their leading tag, if any, and at the info level otherwise.
+type stdlibWriter struct{}
+
+func (w stdlibWriter) Write(p []byte) (int, error) {
+	message := strings.TrimSpace(string(p))
+	level := logrus.InfoLevel
+
+	if idx := strings.Index(message, " "); idx > 0 {
+		if tagLevel, ok := legacyLevels[message[:idx]]; ok {
+			level = tagLevel
+			message = message[idx+1:]
+		}
+	}
+
+	logger.Log(level, message)
+	return len(p), nil
+}
@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseLevel(t *testing.T) {
+	tests := []struct {
+		level    string
+		expected logrus.Level
+		valid    bool
+	}{
+		{level: "DEBUG", expected: logrus.DebugLevel, valid: true},
+		{level: "info", expected: logrus.InfoLevel, valid: true},
+		{level: "WARN", expected: logrus.WarnLevel, valid: true},
+		{level: "warning", expected: logrus.WarnLevel, valid: true},
+		{level: "Error", expected: logrus.ErrorLevel, valid: true},
+		{level: "TRACE", valid: false},
+		{level: "", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			level, err := ParseLevel(tt.level)
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, level)
+		})
+	}
+}
+
+func Test_Configure_InvalidFormat(t *testing.T) {
+	assert.Error(t, Configure("INFO", "xml"))
+}
+
+func Test_Component_JSON(t *testing.T) {
+	output := captureOutput(t, "DEBUG", FormatJSON)
+	defer logger.SetOutput(os.Stderr)
+
+	Component("chisel").WithField(FieldEndpointID, 3).Debug("endpoint tunnel monitoring")
+
+	var entry map[string]interface{}
+	err := json.Unmarshal(output.Bytes(), &entry)
+	assert.NoError(t, err)
+	assert.Equal(t, "chisel", entry[FieldComponent])
+	assert.Equal(t, float64(3), entry[FieldEndpointID])
+	assert.Equal(t, "debug", entry["level"])
+	assert.Equal(t, "endpoint tunnel monitoring", entry["msg"])
+}
+
+func Test_SetLevel_DiscardsLowerLevels(t *testing.T) {
+	output := captureOutput(t, "WARN", FormatText)
+	defer logger.SetOutput(os.Stderr)
+
+	Component("snapshot").Info("snapshot created")
+	assert.Empty(t, output.String())
+
+	Component("snapshot").Warn("unable to create snapshot")
+	assert.Contains(t, output.String(), "unable to create snapshot")
+	assert.Equal(t, "WARN", Level())
+}
+
+func Test_stdlibWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{name: "debug tag is discarded at the info level", message: "[DEBUG] [http,proxy] [message: proxying request]", expected: ""},
+		{name: "warn tag is logged at the warn level", message: "[WARN] [http,stacks] [message: unable to record the stack revision]", expected: `level=warning msg="[http,stacks] [message: unable to record the stack revision]"`},
+		{name: "untagged message is logged at the info level", message: "Starting Portainer", expected: `level=info msg="Starting Portainer"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := captureOutput(t, "INFO", FormatText)
+			defer logger.SetOutput(os.Stderr)
+
+			log.Println(tt.message)
+
+			if tt.expected == "" {
+				assert.Empty(t, output.String())
+				return
+			}
+			assert.Contains(t, output.String(), tt.expected)
+		})
+	}
+}
+
+func captureOutput(t *testing.T, level, format string) *bytes.Buffer {
+	err := Configure(level, format)
+	assert.NoError(t, err)
+
+	output := &bytes.Buffer{}
+	logger.SetOutput(output)
+
+	return output
+}
@@ -0,0 +1,413 @@
+package logsearch
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+const (
+	// ResourceTypeContainer identifies the logs of a container
+	ResourceTypeContainer = "container"
+	// ResourceTypeService identifies the logs of a Swarm service
+	ResourceTypeService = "service"
+	// StreamStdout identifies a line written on the standard output
+	StreamStdout = "stdout"
+	// StreamStderr identifies a line written on the standard error
+	StreamStderr = "stderr"
+
+	labelSwarmStackName   = "com.docker.stack.namespace"
+	labelComposeStackName = "com.docker.compose.project"
+	labelSwarmServiceID   = "com.docker.swarm.service.id"
+
+	maxLineSize = 1024 * 1024
+
+	// multiplexedHeaderSize is the size of the header of each frame of a multiplexed stream
+	multiplexedHeaderSize = 8
+)
+
+// ErrInvalidTimestamp is returned when a log line is not prefixed with a RFC3339 timestamp
+var ErrInvalidTimestamp = errors.New("Log line is not prefixed with a valid timestamp")
+
+type (
+	// Entry is a single log line read from a container or a service
+	Entry struct {
+		// Endpoint identifier
+		EndpointID portainer.EndpointID `json:"EndpointId" example:"1"`
+		// Type of the resource the line was read from (container or service)
+		ResourceType string `example:"container"`
+		// Identifier of the resource the line was read from
+		ResourceID string `json:"ResourceId" example:"c5e1c8e5b5a0"`
+		// Name of the resource the line was read from
+		ResourceName string `example:"web"`
+		// Time at which the line was written
+		Timestamp time.Time `example:"2021-03-01T10:00:00.000000000Z"`
+		// Stream the line was written on (stdout or stderr)
+		Stream string `example:"stdout"`
+		// Content of the line, without its timestamp
+		Line string `example:"GET / HTTP/1.1 200"`
+	}
+
+	// Query holds the criteria a log line must match to be part of the search results
+	Query struct {
+		// Lines written before this time are excluded, ignored when zero
+		Since time.Time
+		// Lines written after this time are excluded, ignored when zero
+		Until time.Time
+		// Lines not matching this pattern are excluded, ignored when nil
+		Pattern *regexp.Regexp
+	}
+
+	// AccessContext describes the user a search is run for, it is used to
+	// filter out the resources the user cannot access
+	AccessContext struct {
+		IsAdmin          bool
+		UserID           portainer.UserID
+		TeamIDs          []portainer.TeamID
+		ResourceControls []portainer.ResourceControl
+	}
+)
+
+// Matches returns true when the entry matches the time range and the pattern of the query
+func (query *Query) Matches(entry *Entry) bool {
+	if !query.Since.IsZero() && entry.Timestamp.Before(query.Since) {
+		return false
+	}
+
+	if !query.Until.IsZero() && entry.Timestamp.After(query.Until) {
+		return false
+	}
+
+	return query.Pattern == nil || query.Pattern.MatchString(entry.Line)
+}
+
+// StackName returns the name of the Compose or Swarm stack a resource is part of,
+// based on its labels. An empty string is returned for resources deployed outside of a stack.
+func StackName(labels map[string]string) string {
+	if labels[labelSwarmStackName] != "" {
+		return labels[labelSwarmStackName]
+	}
+
+	return labels[labelComposeStackName]
+}
+
+// CanAccess returns true when the user can read the logs of the specified resource.
+// The resource control is looked up the same way the Docker proxy does: first on the resource itself,
+// then on the service the container is part of and finally on the stack the resource was deployed with.
+// Resources without a resource control are only visible to administrators.
+func (access *AccessContext) CanAccess(endpointID portainer.EndpointID, resourceID string, resourceType portainer.ResourceControlType, labels map[string]string) bool {
+	if access.IsAdmin {
+		return true
+	}
+
+	resourceControl := findResourceControl(endpointID, resourceID, resourceType, labels, access.ResourceControls)
+
+	return authorization.UserCanAccessResource(access.UserID, access.TeamIDs, resourceControl)
+}
+
+func findResourceControl(endpointID portainer.EndpointID, resourceID string, resourceType portainer.ResourceControlType, labels map[string]string, resourceControls []portainer.ResourceControl) *portainer.ResourceControl {
+	resourceControl := authorization.GetResourceControlByResourceIDAndType(resourceID, resourceType, resourceControls)
+	if resourceControl != nil {
+		return resourceControl
+	}
+
+	if labels[labelSwarmServiceID] != "" {
+		resourceControl = authorization.GetResourceControlByResourceIDAndType(labels[labelSwarmServiceID], portainer.ServiceResourceControl, resourceControls)
+		if resourceControl != nil {
+			return resourceControl
+		}
+	}
+
+	stackName := StackName(labels)
+	if stackName != "" {
+		return authorization.GetResourceControlByResourceIDAndType(stackutils.ResourceControlID(endpointID, stackName), portainer.StackResourceControl, resourceControls)
+	}
+
+	return nil
+}
+
+// ParseLine splits a log line retrieved with timestamps enabled into its timestamp and its content
+func ParseLine(line string) (time.Time, string, error) {
+	idx := strings.IndexByte(line, ' ')
+	if idx == -1 {
+		idx = len(line)
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, line[:idx])
+	if err != nil {
+		return time.Time{}, "", ErrInvalidTimestamp
+	}
+
+	if idx == len(line) {
+		return timestamp, "", nil
+	}
+
+	return timestamp, line[idx+1:], nil
+}
+
+// Read reads the logs of a single resource, retrieved with timestamps enabled, and sends
+// the lines matching the query on the entries channel in the order they were written.
+// Logs of resources without a TTY are multiplexed and are demultiplexed into the stdout and stderr streams.
+// Read returns when the reader is exhausted or when the context is cancelled, the channel is not closed.
+func Read(ctx context.Context, reader io.Reader, tty bool, source Entry, query *Query, entries chan<- Entry) error {
+	emit := func(stream string, line string) error {
+		timestamp, content, err := ParseLine(line)
+		if err != nil {
+			return nil
+		}
+
+		entry := source
+		entry.Timestamp = timestamp
+		entry.Stream = stream
+		entry.Line = content
+
+		if !query.Matches(&entry) {
+			return nil
+		}
+
+		select {
+		case entries <- entry:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if tty {
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+		for scanner.Scan() {
+			err := emit(StreamStdout, strings.TrimSuffix(scanner.Text(), "\r"))
+			if err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	}
+
+	stdout := &lineWriter{stream: StreamStdout, emit: emit}
+	stderr := &lineWriter{stream: StreamStderr, emit: emit}
+
+	_, err := stdcopy.StdCopy(stdout, stderr, reader)
+	if err != nil {
+		return err
+	}
+
+	err = stdout.flush()
+	if err != nil {
+		return err
+	}
+
+	return stderr.flush()
+}
+
+// IsMultiplexed returns true when the logs read from the reader are multiplexed, which is the case of the
+// resources without a TTY. The header of the first frame is peeked, so the reader can then be passed to Read.
+func IsMultiplexed(reader *bufio.Reader) bool {
+	header, err := reader.Peek(multiplexedHeaderSize)
+	if err != nil {
+		return false
+	}
+
+	stream := stdcopy.StdType(header[0])
+	if stream != stdcopy.Stdin && stream != stdcopy.Stdout && stream != stdcopy.Stderr {
+		return false
+	}
+
+	return header[1] == 0 && header[2] == 0 && header[3] == 0
+}
+
+// lineWriter buffers the demultiplexed output of a stream and emits it line by line
+type lineWriter struct {
+	stream string
+	buffer []byte
+	emit   func(stream string, line string) error
+}
+
+func (writer *lineWriter) Write(data []byte) (int, error) {
+	writer.buffer = append(writer.buffer, data...)
+
+	for {
+		idx := bytes.IndexByte(writer.buffer, '\n')
+		if idx == -1 {
+			break
+		}
+
+		line := string(writer.buffer[:idx])
+		writer.buffer = writer.buffer[idx+1:]
+
+		err := writer.emit(writer.stream, line)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(data), nil
+}
+
+func (writer *lineWriter) flush() error {
+	if len(writer.buffer) == 0 {
+		return nil
+	}
+
+	line := string(writer.buffer)
+	writer.buffer = nil
+
+	return writer.emit(writer.stream, line)
+}
+
+// Collector keeps the earliest entries read from any number of resources, up to a limit.
+// The entries of the resources can be added in any order and concurrently.
+type Collector struct {
+	mu      sync.Mutex
+	limit   int
+	entries entryQueue
+}
+
+// NewCollector returns a collector keeping at most limit entries, all the entries are kept when limit is zero
+func NewCollector(limit int) *Collector {
+	return &Collector{limit: limit}
+}
+
+// Add adds an entry to the collector. It returns false when the collector is full and the entry is not earlier
+// than any of the kept entries: the entry is dropped and, as the lines of a resource are read in the order
+// they were written, the following lines of the same resource would be dropped as well.
+func (collector *Collector) Add(entry Entry) bool {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	if collector.limit <= 0 || collector.entries.Len() < collector.limit {
+		heap.Push(&collector.entries, entry)
+		return true
+	}
+
+	if !entry.Timestamp.Before(collector.entries[0].Timestamp) {
+		return false
+	}
+
+	collector.entries[0] = entry
+	heap.Fix(&collector.entries, 0)
+	return true
+}
+
+// Entries returns the kept entries in timestamp order
+func (collector *Collector) Entries() []Entry {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	entries := make([]Entry, len(collector.entries))
+	copy(entries, collector.entries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	return entries
+}
+
+// entryQueue is a max-heap of entries, ordered by timestamp, the latest entry being the first one
+type entryQueue []Entry
+
+func (queue entryQueue) Len() int { return len(queue) }
+
+func (queue entryQueue) Less(i, j int) bool {
+	return queue[j].Timestamp.Before(queue[i].Timestamp)
+}
+
+func (queue entryQueue) Swap(i, j int) { queue[i], queue[j] = queue[j], queue[i] }
+
+func (queue *entryQueue) Push(item interface{}) {
+	*queue = append(*queue, item.(Entry))
+}
+
+func (queue *entryQueue) Pop() interface{} {
+	old := *queue
+	item := old[len(old)-1]
+	*queue = old[:len(old)-1]
+	return item
+}
+
+// Merge reads the entries sent on the inputs, each input sending its entries in timestamp order, and passes them
+// to emit in timestamp order. An entry is passed as soon as it is final, once every input has sent a later entry
+// or is closed. Merge returns after limit entries, all the entries are passed when limit is zero, when an input
+// cannot be read because the context is cancelled or when emit returns an error.
+func Merge(ctx context.Context, inputs []<-chan Entry, limit int, emit func(entry Entry) error) error {
+	heads := make(mergeQueue, 0, len(inputs))
+
+	next := func(input int) error {
+		select {
+		case entry, ok := <-inputs[input]:
+			if ok {
+				heap.Push(&heads, mergeItem{entry: entry, input: input})
+			}
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	for input := range inputs {
+		err := next(input)
+		if err != nil {
+			return err
+		}
+	}
+
+	for count := 0; heads.Len() > 0 && (limit <= 0 || count < limit); count++ {
+		head := heap.Pop(&heads).(mergeItem)
+
+		err := emit(head.entry)
+		if err != nil {
+			return err
+		}
+
+		err = next(head.input)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mergeItem is the next entry of an input of Merge
+type mergeItem struct {
+	entry Entry
+	input int
+}
+
+// mergeQueue is a min-heap of the next entries of the inputs of Merge, ordered by timestamp then by input
+type mergeQueue []mergeItem
+
+func (queue mergeQueue) Len() int { return len(queue) }
+
+func (queue mergeQueue) Less(i, j int) bool {
+	if queue[i].entry.Timestamp.Equal(queue[j].entry.Timestamp) {
+		return queue[i].input < queue[j].input
+	}
+	return queue[i].entry.Timestamp.Before(queue[j].entry.Timestamp)
+}
+
+func (queue mergeQueue) Swap(i, j int) { queue[i], queue[j] = queue[j], queue[i] }
+
+func (queue *mergeQueue) Push(item interface{}) {
+	*queue = append(*queue, item.(mergeItem))
+}
+
+func (queue *mergeQueue) Pop() interface{} {
+	old := *queue
+	item := old[len(old)-1]
+	*queue = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,371 @@
+package logsearch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseLine(t *testing.T) {
+	tests := []struct {
+		name          string
+		line          string
+		wantTimestamp time.Time
+		wantContent   string
+		wantErr       bool
+	}{
+		{
+			name:          "line with content",
+			line:          "2021-03-01T10:00:00.123456789Z hello world",
+			wantTimestamp: time.Date(2021, 3, 1, 10, 0, 0, 123456789, time.UTC),
+			wantContent:   "hello world",
+		},
+		{
+			name:          "empty line",
+			line:          "2021-03-01T10:00:00Z",
+			wantTimestamp: time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC),
+			wantContent:   "",
+		},
+		{
+			name:    "line without timestamp",
+			line:    "hello world",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timestamp, content, err := ParseLine(tt.line)
+			if tt.wantErr {
+				assert.Equal(t, ErrInvalidTimestamp, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, tt.wantTimestamp.Equal(timestamp))
+			assert.Equal(t, tt.wantContent, content)
+		})
+	}
+}
+
+func Test_Query_Matches(t *testing.T) {
+	base := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		query Query
+		entry Entry
+		want  bool
+	}{
+		{
+			name:  "empty query",
+			query: Query{},
+			entry: Entry{Timestamp: base, Line: "anything"},
+			want:  true,
+		},
+		{
+			name:  "before since",
+			query: Query{Since: base},
+			entry: Entry{Timestamp: base.Add(-time.Second)},
+			want:  false,
+		},
+		{
+			name:  "after until",
+			query: Query{Until: base},
+			entry: Entry{Timestamp: base.Add(time.Second)},
+			want:  false,
+		},
+		{
+			name:  "within range and matching pattern",
+			query: Query{Since: base, Until: base.Add(time.Minute), Pattern: regexp.MustCompile("err(or)?")},
+			entry: Entry{Timestamp: base.Add(time.Second), Line: "an error occurred"},
+			want:  true,
+		},
+		{
+			name:  "not matching pattern",
+			query: Query{Pattern: regexp.MustCompile("^panic")},
+			entry: Entry{Timestamp: base, Line: "all good"},
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.query.Matches(&tt.entry))
+		})
+	}
+}
+
+func Test_AccessContext_CanAccess(t *testing.T) {
+	resourceControls := []portainer.ResourceControl{
+		{ResourceID: "container-1", Type: portainer.ContainerResourceControl, UserAccesses: []portainer.UserResourceAccess{{UserID: 2}}},
+		{ResourceID: "service-1", Type: portainer.ServiceResourceControl, TeamAccesses: []portainer.TeamResourceAccess{{TeamID: 5}}},
+		{ResourceID: "1_web", Type: portainer.StackResourceControl, Public: true},
+	}
+
+	tests := []struct {
+		name       string
+		access     AccessContext
+		resourceID string
+		labels     map[string]string
+		want       bool
+	}{
+		{
+			name:       "administrator can access resources without resource control",
+			access:     AccessContext{IsAdmin: true},
+			resourceID: "container-2",
+			want:       true,
+		},
+		{
+			name:       "user cannot access resources without resource control",
+			access:     AccessContext{UserID: 2},
+			resourceID: "container-2",
+			want:       false,
+		},
+		{
+			name:       "user with direct access",
+			access:     AccessContext{UserID: 2},
+			resourceID: "container-1",
+			want:       true,
+		},
+		{
+			name:       "user without direct access",
+			access:     AccessContext{UserID: 3},
+			resourceID: "container-1",
+			want:       false,
+		},
+		{
+			name:       "team access inherited from the service",
+			access:     AccessContext{UserID: 3, TeamIDs: []portainer.TeamID{5}},
+			resourceID: "container-3",
+			labels:     map[string]string{labelSwarmServiceID: "service-1"},
+			want:       true,
+		},
+		{
+			name:       "public access inherited from the compose stack",
+			access:     AccessContext{UserID: 3},
+			resourceID: "container-4",
+			labels:     map[string]string{labelComposeStackName: "web"},
+			want:       true,
+		},
+		{
+			name:       "stack resource control of another endpoint",
+			access:     AccessContext{UserID: 3},
+			resourceID: "container-4",
+			labels:     map[string]string{labelComposeStackName: "api"},
+			want:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.access.ResourceControls = resourceControls
+			got := tt.access.CanAccess(1, tt.resourceID, portainer.ContainerResourceControl, tt.labels)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_Read_Multiplexed(t *testing.T) {
+	var buffer bytes.Buffer
+	stdout := stdcopy.NewStdWriter(&buffer, stdcopy.Stdout)
+	stderr := stdcopy.NewStdWriter(&buffer, stdcopy.Stderr)
+
+	stdout.Write([]byte("2021-03-01T10:00:00Z starting\n"))
+	stderr.Write([]byte("2021-03-01T10:00:01Z error: disk full\n"))
+	stdout.Write([]byte("2021-03-01T10:00:02Z stopping"))
+
+	entries := make(chan Entry, 10)
+	source := Entry{EndpointID: 1, ResourceType: ResourceTypeContainer, ResourceID: "abc", ResourceName: "web"}
+
+	err := Read(context.Background(), &buffer, false, source, &Query{}, entries)
+	assert.NoError(t, err)
+	close(entries)
+
+	got := make([]Entry, 0)
+	for entry := range entries {
+		got = append(got, entry)
+	}
+
+	if assert.Len(t, got, 3) {
+		assert.Equal(t, StreamStdout, got[0].Stream)
+		assert.Equal(t, "starting", got[0].Line)
+		assert.Equal(t, StreamStderr, got[1].Stream)
+		assert.Equal(t, "error: disk full", got[1].Line)
+		assert.Equal(t, "stopping", got[2].Line)
+		assert.Equal(t, "web", got[2].ResourceName)
+	}
+}
+
+func Test_Read_TTYWithPattern(t *testing.T) {
+	reader := strings.NewReader("2021-03-01T10:00:00Z GET /\r\n2021-03-01T10:00:01Z POST /login\r\nnot a log line\n")
+
+	entries := make(chan Entry, 10)
+	query := &Query{Pattern: regexp.MustCompile("^POST")}
+
+	err := Read(context.Background(), reader, true, Entry{}, query, entries)
+	assert.NoError(t, err)
+	close(entries)
+
+	got := make([]string, 0)
+	for entry := range entries {
+		assert.Equal(t, StreamStdout, entry.Stream)
+		got = append(got, entry.Line)
+	}
+
+	assert.Equal(t, []string{"POST /login"}, got)
+}
+
+func Test_IsMultiplexed(t *testing.T) {
+	var multiplexed bytes.Buffer
+	stdcopy.NewStdWriter(&multiplexed, stdcopy.Stderr).Write([]byte("2021-03-01T10:00:00.000000000Z error\n"))
+
+	tests := []struct {
+		name string
+		logs []byte
+		want bool
+	}{
+		{name: "multiplexed logs", logs: multiplexed.Bytes(), want: true},
+		{name: "TTY logs", logs: []byte("2021-03-01T10:00:00.000000000Z GET /\r\n"), want: false},
+		{name: "no logs", logs: []byte{}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReader(bytes.NewReader(tt.logs))
+			assert.Equal(t, tt.want, IsMultiplexed(reader))
+
+			logs, err := ioutil.ReadAll(reader)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.logs, logs)
+		})
+	}
+}
+
+func Test_Collector(t *testing.T) {
+	base := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	sources := map[string][]int{
+		"a": {0, 3, 5},
+		"b": {1, 4},
+		"c": {2},
+		"d": {},
+	}
+
+	tests := []struct {
+		name    string
+		limit   int
+		want    []string
+		dropped []string
+	}{
+		{
+			name:    "all entries in timestamp order",
+			want:    []string{"a0", "b1", "c2", "a3", "b4", "a5"},
+			dropped: []string{},
+		},
+		{
+			name:    "limited number of entries",
+			limit:   4,
+			want:    []string{"a0", "b1", "c2", "a3"},
+			dropped: []string{"a5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := NewCollector(tt.limit)
+
+			dropped := make([]string, 0)
+			for _, name := range []string{"c", "b", "a", "d"} {
+				for _, offset := range sources[name] {
+					entry := Entry{ResourceName: name, Timestamp: base.Add(time.Duration(offset) * time.Second)}
+					if !collector.Add(entry) {
+						dropped = append(dropped, name+strconv.Itoa(offset))
+						break
+					}
+				}
+			}
+
+			got := make([]string, 0)
+			for _, entry := range collector.Entries() {
+				got = append(got, entry.ResourceName+strconv.Itoa(int(entry.Timestamp.Sub(base).Seconds())))
+			}
+
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.dropped, dropped)
+		})
+	}
+}
+
+func Test_Merge(t *testing.T) {
+	base := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	sources := []struct {
+		name    string
+		offsets []int
+	}{
+		{name: "a", offsets: []int{0, 3, 5}},
+		{name: "b", offsets: []int{1, 3}},
+		{name: "c", offsets: []int{2}},
+		{name: "d", offsets: []int{}},
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []string
+	}{
+		{name: "all entries in timestamp order", want: []string{"a0", "b1", "c2", "a3", "b3", "a5"}},
+		{name: "limited number of entries", limit: 4, want: []string{"a0", "b1", "c2", "a3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			inputs := make([]<-chan Entry, 0, len(sources))
+			for _, source := range sources {
+				entries := make(chan Entry)
+				inputs = append(inputs, entries)
+
+				go func(name string, offsets []int) {
+					defer close(entries)
+					for _, offset := range offsets {
+						select {
+						case entries <- Entry{ResourceName: name, Timestamp: base.Add(time.Duration(offset) * time.Second)}:
+						case <-ctx.Done():
+							return
+						}
+					}
+				}(source.name, source.offsets)
+			}
+
+			got := make([]string, 0)
+			err := Merge(ctx, inputs, tt.limit, func(entry Entry) error {
+				got = append(got, entry.ResourceName+strconv.Itoa(int(entry.Timestamp.Sub(base).Seconds())))
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_Merge_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := Merge(ctx, []<-chan Entry{make(chan Entry)}, 0, func(entry Entry) error { return nil })
+	assert.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// routeGroups are the route groups of the API, other paths are reported in the "other" group to bound the number of series
+var routeGroups = map[string]bool{
+	"auth": true, "backup": true, "custom_templates": true, "dockerhub": true, "edge": true,
+	"edge_enrollment_tokens": true, "edge_groups": true, "edge_jobs": true, "edge_stacks": true, "edge_templates": true,
+	"endpoint_groups": true, "endpoints": true, "jobs": true, "metrics": true, "motd": true, "registries": true,
+	"resource_controls": true, "restore": true, "roles": true, "settings": true, "stacks": true, "status": true,
+	"tags": true, "team_memberships": true, "teams": true, "templates": true, "upload": true, "users": true,
+	"webhooks": true, "websocket": true,
+}
+
+// endpointSubRoutes are the route groups of the requests proxied to or received from an endpoint
+var endpointSubRoutes = map[string]bool{
+	"docker": true, "kubernetes": true, "azure": true, "storidge": true, "edge": true,
+}
+
+// RouteGroup returns the route group of a request path, the requests proxied to or received from an endpoint
+// are grouped by the type of the route
+func RouteGroup(path string) string {
+	if !strings.HasPrefix(path, "/api/") {
+		return "static"
+	}
+
+	segments := strings.Split(strings.TrimPrefix(path, "/api/"), "/")
+	if !routeGroups[segments[0]] {
+		return "other"
+	}
+
+	if segments[0] == "endpoints" && len(segments) > 2 && endpointSubRoutes[segments[2]] {
+		if _, err := strconv.Atoi(segments[1]); err == nil {
+			return "endpoints/" + segments[2]
+		}
+	}
+
+	return segments[0]
+}
+
+// InstrumentHandler records the count and the latency of the HTTP requests served by the handler
+func InstrumentHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r)
+
+		group := RouteGroup(r.URL.Path)
+		HTTPRequests.Inc(group, r.Method, strconv.Itoa(recorder.status))
+		HTTPRequestDuration.Observe(time.Since(start).Seconds(), group)
+	})
+}
+
+// InstrumentProxy counts the requests served by the proxy of an endpoint. The proxies only serve the requests
+// of the users authorized on an existing endpoint, which bounds the number of series.
+func InstrumentProxy(endpointID int, proxyType string, next http.Handler) http.Handler {
+	endpointLabel := strconv.Itoa(endpointID)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ProxyRequests.Inc(endpointLabel, proxyType)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusRecorder records the status code of a response while keeping the streaming and websocket capabilities of the writer
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	if !recorder.wroteHeader {
+		recorder.status = status
+		recorder.wroteHeader = true
+	}
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Write(b []byte) (int, error) {
+	recorder.wroteHeader = true
+	return recorder.ResponseWriter.Write(b)
+}
+
+func (recorder *statusRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (recorder *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := recorder.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	recorder.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
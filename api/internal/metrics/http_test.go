@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RouteGroup(t *testing.T) {
+	tests := []struct {
+		path  string
+		group string
+	}{
+		{path: "/", group: "static"},
+		{path: "/main.js", group: "static"},
+		{path: "/api/status", group: "status"},
+		{path: "/api/edge_stacks/1/file", group: "edge_stacks"},
+		{path: "/api/endpoints", group: "endpoints"},
+		{path: "/api/endpoints/3/status", group: "endpoints"},
+		{path: "/api/endpoints/3/docker/containers/json", group: "endpoints/docker"},
+		{path: "/api/endpoints/12/kubernetes/api/v1/pods", group: "endpoints/kubernetes"},
+		{path: "/api/endpoints/3/edge/stacks/1", group: "endpoints/edge"},
+		{path: "/api/endpoints/abc/docker/info", group: "endpoints"},
+		{path: "/api/unknown-8f2d/info", group: "other"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.group, RouteGroup(test.path), test.path)
+	}
+}
+
+func Test_InstrumentHandler(t *testing.T) {
+	handler := InstrumentHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	for _, path := range []string{"/api/endpoints/7/docker/info", "/api/endpoints/7/docker/info", "/api/tags/missing"} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var output bytes.Buffer
+	assert.NoError(t, Default.Write(&output))
+	assert.Contains(t, output.String(), `portainer_http_requests_total{group="endpoints/docker",method="GET",code="200"} 2`)
+	assert.Contains(t, output.String(), `portainer_http_requests_total{group="tags",method="GET",code="404"} 1`)
+	assert.Contains(t, output.String(), `portainer_http_request_duration_seconds_count{group="endpoints/docker"} 2`)
+	assert.NotContains(t, output.String(), `portainer_proxy_requests_total{`)
+}
+
+func Test_InstrumentProxy(t *testing.T) {
+	handler := InstrumentProxy(9, "kubernetes", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/pods", nil))
+	}
+
+	var output bytes.Buffer
+	assert.NoError(t, Default.Write(&output))
+	assert.Contains(t, output.String(), `portainer_proxy_requests_total{endpoint_id="9",type="kubernetes"} 3`)
+}
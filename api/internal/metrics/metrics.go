@@ -0,0 +1,230 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the default upper bounds in seconds of the buckets of a histogram
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds a set of metrics and writes them in the Prometheus text exposition format
+type Registry struct {
+	mu      sync.Mutex
+	metrics []*metric
+}
+
+// NewRegistry creates a new instance of a registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// metric represents a metric family with a value for each combination of its label values
+type metric struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series represents the value of a metric for a combination of label values
+type series struct {
+	labelValues []string
+	value       float64
+	// cumulative count of the observations of a histogram for each bucket
+	bucketCounts []uint64
+	count        uint64
+}
+
+// Counter represents a metric whose value only increases
+type Counter struct{ *metric }
+
+// Gauge represents a metric whose value can go up and down
+type Gauge struct{ *metric }
+
+// Histogram represents a metric counting observations in buckets
+type Histogram struct{ *metric }
+
+func (registry *Registry) register(name, help, kind string, buckets []float64, labels []string) *metric {
+	m := &metric{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+
+	registry.mu.Lock()
+	registry.metrics = append(registry.metrics, m)
+	registry.mu.Unlock()
+
+	return m
+}
+
+// NewCounter registers a new counter with the specified label names
+func (registry *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{registry.register(name, help, "counter", nil, labels)}
+}
+
+// NewGauge registers a new gauge with the specified label names
+func (registry *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{registry.register(name, help, "gauge", nil, labels)}
+}
+
+// NewHistogram registers a new histogram with the specified bucket upper bounds and label names
+func (registry *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	sortedBuckets := append([]float64{}, buckets...)
+	sort.Float64s(sortedBuckets)
+	return &Histogram{registry.register(name, help, "histogram", sortedBuckets, labels)}
+}
+
+// Inc increments the counter by 1 for the label values
+func (counter *Counter) Inc(labelValues ...string) {
+	counter.Add(1, labelValues...)
+}
+
+// Add increases the counter by a positive value for the label values
+func (counter *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+	counter.update(labelValues, func(s *series) { s.value += value })
+}
+
+// Set sets the value of the gauge for the label values
+func (gauge *Gauge) Set(value float64, labelValues ...string) {
+	gauge.update(labelValues, func(s *series) { s.value = value })
+}
+
+// Observe adds an observation to the histogram for the label values
+func (histogram *Histogram) Observe(value float64, labelValues ...string) {
+	histogram.update(labelValues, func(s *series) {
+		for idx, upperBound := range histogram.buckets {
+			if value <= upperBound {
+				s.bucketCounts[idx]++
+			}
+		}
+		s.value += value
+		s.count++
+	})
+}
+
+func (m *metric) update(labelValues []string, apply func(s *series)) {
+	if len(labelValues) != len(m.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", m.name, len(m.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{
+			labelValues:  append([]string{}, labelValues...),
+			bucketCounts: make([]uint64, len(m.buckets)),
+		}
+		m.series[key] = s
+	}
+
+	apply(s)
+}
+
+// Write writes the metrics of the registry in the Prometheus text exposition format
+func (registry *Registry) Write(w io.Writer) error {
+	registry.mu.Lock()
+	metrics := append([]*metric{}, registry.metrics...)
+	registry.mu.Unlock()
+
+	writer := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(writer)
+	}
+
+	return writer.Flush()
+}
+
+func (m *metric) write(w *bufio.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", m.name, escapeHelp(m.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := m.series[key]
+		labels := formatLabels(m.labels, s.labelValues)
+
+		if m.kind != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", m.name, labels, formatValue(s.value))
+			continue
+		}
+
+		for idx, upperBound := range m.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, withLabel(labels, "le", formatValue(upperBound)), s.bucketCounts[idx])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, withLabel(labels, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", m.name, labels, formatValue(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", m.name, labels, s.count)
+	}
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(names))
+	for idx, name := range names {
+		pairs[idx] = fmt.Sprintf("%s=\"%s\"", name, escapeLabelValue(values[idx]))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func withLabel(labels, name, value string) string {
+	pair := fmt.Sprintf("%s=\"%s\"", name, value)
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
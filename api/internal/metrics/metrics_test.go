@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Registry_Write(t *testing.T) {
+	registry := NewRegistry()
+
+	counter := registry.NewCounter("test_requests_total", "Number of requests.", "method", "code")
+	counter.Inc("GET", "200")
+	counter.Add(2, "GET", "200")
+	counter.Inc("POST", "500")
+	counter.Add(-1, "POST", "500")
+
+	gauge := registry.NewGauge("test_endpoints", "Number of endpoints\nby status.", "status")
+	gauge.Set(3, "up")
+	gauge.Set(1, `do"wn`)
+
+	histogram := registry.NewHistogram("test_duration_seconds", "Duration.", []float64{1, 0.1})
+	histogram.Observe(0.05)
+	histogram.Observe(0.5)
+	histogram.Observe(2)
+
+	var output bytes.Buffer
+	err := registry.Write(&output)
+	assert.NoError(t, err)
+
+	assert.Equal(t, `# HELP test_requests_total Number of requests.
+# TYPE test_requests_total counter
+test_requests_total{method="GET",code="200"} 3
+test_requests_total{method="POST",code="500"} 1
+# HELP test_endpoints Number of endpoints\nby status.
+# TYPE test_endpoints gauge
+test_endpoints{status="do\"wn"} 1
+test_endpoints{status="up"} 3
+# HELP test_duration_seconds Duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="0.1"} 1
+test_duration_seconds_bucket{le="1"} 2
+test_duration_seconds_bucket{le="+Inf"} 3
+test_duration_seconds_sum 2.55
+test_duration_seconds_count 3
+`, output.String())
+}
+
+func Test_Metric_LabelValuesMismatch(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounter("test_total", "Test.", "method")
+
+	assert.Panics(t, func() { counter.Inc() })
+	assert.Panics(t, func() { counter.Inc("GET", "200") })
+}
@@ -0,0 +1,24 @@
+package metrics
+
+// Default is the registry of the metrics of the Portainer server
+var Default = NewRegistry()
+
+// databaseBuckets are the upper bounds in seconds of the buckets of the database transaction durations
+var databaseBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}
+
+var (
+	// HTTPRequests counts the HTTP requests by route group, method and status code
+	HTTPRequests = Default.NewCounter("portainer_http_requests_total", "Number of HTTP requests by route group, method and status code.", "group", "method", "code")
+	// HTTPRequestDuration observes the latency of the HTTP requests by route group
+	HTTPRequestDuration = Default.NewHistogram("portainer_http_request_duration_seconds", "Latency of the HTTP requests by route group.", DefaultBuckets, "group")
+	// ProxyRequests counts the requests proxied to each endpoint by proxy type
+	ProxyRequests = Default.NewCounter("portainer_proxy_requests_total", "Number of requests proxied to each endpoint by proxy type.", "endpoint_id", "type")
+	// SnapshotDuration observes the duration of the endpoint snapshots
+	SnapshotDuration = Default.NewHistogram("portainer_snapshot_duration_seconds", "Duration of the endpoint snapshots.", DefaultBuckets)
+	// SnapshotFailures counts the failed endpoint snapshots
+	SnapshotFailures = Default.NewCounter("portainer_snapshot_failures_total", "Number of failed endpoint snapshots.")
+	// DatabaseTransactionDuration observes the duration of the database transactions by type, view or update
+	DatabaseTransactionDuration = Default.NewHistogram("portainer_bolt_transaction_duration_seconds", "Duration of the database transactions by type.", databaseBuckets, "type")
+	// BackupJobs counts the backups by outcome, success or failure
+	BackupJobs = Default.NewCounter("portainer_backup_jobs_total", "Number of backups by outcome.", "outcome")
+)
@@ -0,0 +1,243 @@
+package notification
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/logging"
+)
+
+var logger = logging.Component("notification")
+
+const (
+	// queueSize is the number of events waiting to be dispatched after which new events are dropped
+	queueSize = 256
+	// retryBaseDelay is the delay before the second attempt to deliver an event, doubled for each following attempt
+	retryBaseDelay = 5 * time.Second
+	// requestTimeout is the timeout of the HTTP requests sent to the webhook channels
+	requestTimeout = 10 * time.Second
+	// smtpTimeout is the time given to the SMTP server of an email channel to accept a message
+	smtpTimeout = 30 * time.Second
+)
+
+type sender func(channel *portainer.NotificationChannel, event *portainer.NotificationEvent) error
+
+// Service sends notifications about platform events to the notification channels subscribed to them.
+// Events are dispatched in the background, each delivery being retried with an exponential backoff
+// and recorded in the delivery log.
+type Service struct {
+	dataStore      portainer.DataStore
+	shutdownCtx    context.Context
+	events         chan portainer.NotificationEvent
+	senders        map[portainer.NotificationChannelType]sender
+	retryBaseDelay time.Duration
+}
+
+// NewService creates a new instance of a service
+func NewService(dataStore portainer.DataStore, shutdownCtx context.Context) *Service {
+	client := &http.Client{Timeout: requestTimeout}
+
+	return &Service{
+		dataStore:   dataStore,
+		shutdownCtx: shutdownCtx,
+		events:      make(chan portainer.NotificationEvent, queueSize),
+		senders: map[portainer.NotificationChannelType]sender{
+			portainer.NotificationChannelWebhook: func(channel *portainer.NotificationChannel, event *portainer.NotificationEvent) error {
+				return sendWebhook(client, channel, event)
+			},
+			portainer.NotificationChannelSlack: func(channel *portainer.NotificationChannel, event *portainer.NotificationEvent) error {
+				return sendSlack(client, channel, event)
+			},
+			portainer.NotificationChannelEmail: sendEmail,
+		},
+		retryBaseDelay: retryBaseDelay,
+	}
+}
+
+// Start will start a background routine dispatching the events to the notification channels
+func (service *Service) Start() {
+	go func() {
+		for {
+			select {
+			case event := <-service.events:
+				err := service.dispatch(event)
+				if err != nil {
+					logger.WithField("event", event.Type).WithError(err).Error("unable to dispatch event")
+				}
+			case <-service.shutdownCtx.Done():
+				logger.Debug("shutting down notifications")
+				return
+			}
+		}
+	}()
+}
+
+// Notify queues an event to be sent to the notification channels subscribed to it.
+// The event is dropped when too many events are waiting to be dispatched.
+func (service *Service) Notify(event portainer.NotificationEvent) {
+	if event.Time == 0 {
+		event.Time = time.Now().Unix()
+	}
+
+	select {
+	case service.events <- event:
+	default:
+		logger.WithField("event", event.Type).Warn("notification queue is full, dropping event")
+	}
+}
+
+// Test sends a test event to a notification channel, without retrying nor recording the delivery
+func (service *Service) Test(channel *portainer.NotificationChannel) error {
+	event := portainer.NotificationEvent{
+		Type:    portainer.NotificationEventTest,
+		Time:    time.Now().Unix(),
+		Message: "Test notification sent by Portainer to the " + channel.Name + " channel",
+	}
+
+	return service.send(channel, &event)
+}
+
+// NewEndpointEvent returns an event related to an endpoint
+func NewEndpointEvent(eventType portainer.NotificationEventType, endpoint *portainer.Endpoint, message string) portainer.NotificationEvent {
+	return portainer.NotificationEvent{
+		Type:            eventType,
+		Time:            time.Now().Unix(),
+		EndpointID:      endpoint.ID,
+		EndpointName:    endpoint.Name,
+		EndpointGroupID: endpoint.GroupID,
+		Message:         message,
+	}
+}
+
+// Matches returns true when the channel is subscribed to the event
+func Matches(channel *portainer.NotificationChannel, event *portainer.NotificationEvent) bool {
+	if !channel.Enabled {
+		return false
+	}
+
+	if len(channel.EventTypes) > 0 {
+		subscribed := false
+		for _, eventType := range channel.EventTypes {
+			if eventType == event.Type {
+				subscribed = true
+				break
+			}
+		}
+
+		if !subscribed {
+			return false
+		}
+	}
+
+	if len(channel.EndpointGroupIDs) > 0 && event.EndpointID != 0 {
+		for _, groupID := range channel.EndpointGroupIDs {
+			if groupID == event.EndpointGroupID {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+func (service *Service) dispatch(event portainer.NotificationEvent) error {
+	if event.EndpointID != 0 && event.EndpointGroupID == 0 {
+		endpoint, err := service.dataStore.Endpoint().Endpoint(event.EndpointID)
+		if err == nil {
+			event.EndpointName = endpoint.Name
+			event.EndpointGroupID = endpoint.GroupID
+		}
+	}
+
+	channels, err := service.dataStore.NotificationChannel().NotificationChannels()
+	if err != nil {
+		return err
+	}
+
+	for idx := range channels {
+		channel := channels[idx]
+		if !Matches(&channel, &event) {
+			continue
+		}
+
+		go service.deliver(&channel, event)
+	}
+
+	return nil
+}
+
+// deliver sends an event to a channel, retrying up to NotificationMaxAttempts times, and records the delivery
+func (service *Service) deliver(channel *portainer.NotificationChannel, event portainer.NotificationEvent) {
+	delivery := &portainer.NotificationDelivery{
+		ChannelID: channel.ID,
+		Event:     event,
+		Status:    portainer.NotificationDeliveryPending,
+		Created:   time.Now().Unix(),
+	}
+
+	err := service.dataStore.NotificationDelivery().CreateNotificationDelivery(delivery)
+	if err != nil {
+		logger.WithField("channel", channel.Name).WithError(err).Error("unable to record notification delivery")
+		return
+	}
+
+	service.pruneDeliveries(delivery.ID)
+
+	delay := service.retryBaseDelay
+	for {
+		delivery.Attempts++
+		delivery.Updated = time.Now().Unix()
+
+		err = service.send(channel, &event)
+		if err == nil {
+			delivery.Status = portainer.NotificationDeliverySuccess
+			delivery.LastError = ""
+		} else {
+			delivery.LastError = err.Error()
+			if delivery.Attempts >= portainer.NotificationMaxAttempts {
+				delivery.Status = portainer.NotificationDeliveryFailed
+				logger.WithField("channel", channel.Name).WithField("event", event.Type).WithField("attempts", delivery.Attempts).WithError(err).Warn("unable to deliver notification")
+			}
+		}
+
+		err = service.dataStore.NotificationDelivery().UpdateNotificationDelivery(delivery.ID, delivery)
+		if err != nil {
+			logger.WithField("channel", channel.Name).WithError(err).Error("unable to record notification delivery")
+		}
+
+		if delivery.Status != portainer.NotificationDeliveryPending {
+			return
+		}
+
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-service.shutdownCtx.Done():
+			return
+		}
+	}
+}
+
+// pruneDeliveries keeps the NotificationDeliveryMaxCount most recent deliveries in the delivery log
+func (service *Service) pruneDeliveries(latest portainer.NotificationDeliveryID) {
+	if latest <= portainer.NotificationDeliveryMaxCount {
+		return
+	}
+
+	err := service.dataStore.NotificationDelivery().DeleteNotificationDeliveriesUpTo(latest - portainer.NotificationDeliveryMaxCount)
+	if err != nil {
+		logger.WithError(err).Error("unable to prune notification delivery log")
+	}
+}
+
+func (service *Service) send(channel *portainer.NotificationChannel, event *portainer.NotificationEvent) error {
+	send, ok := service.senders[channel.Type]
+	if !ok {
+		return errUnsupportedChannelType
+	}
+
+	return send(channel, event)
+}
@@ -0,0 +1,165 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Matches(t *testing.T) {
+	endpointEvent := &portainer.NotificationEvent{Type: portainer.NotificationEventEndpointDown, EndpointID: 1, EndpointGroupID: 2}
+	backupEvent := &portainer.NotificationEvent{Type: portainer.NotificationEventBackupFailed}
+
+	tests := []struct {
+		name     string
+		channel  portainer.NotificationChannel
+		event    *portainer.NotificationEvent
+		expected bool
+	}{
+		{name: "disabled channel", channel: portainer.NotificationChannel{}, event: endpointEvent, expected: false},
+		{name: "channel subscribed to all events", channel: portainer.NotificationChannel{Enabled: true}, event: endpointEvent, expected: true},
+		{
+			name:     "channel subscribed to the event type",
+			channel:  portainer.NotificationChannel{Enabled: true, EventTypes: []portainer.NotificationEventType{portainer.NotificationEventEndpointUp, portainer.NotificationEventEndpointDown}},
+			event:    endpointEvent,
+			expected: true,
+		},
+		{
+			name:     "channel subscribed to other event types",
+			channel:  portainer.NotificationChannel{Enabled: true, EventTypes: []portainer.NotificationEventType{portainer.NotificationEventEndpointUp}},
+			event:    endpointEvent,
+			expected: false,
+		},
+		{
+			name:     "channel subscribed to the endpoint group",
+			channel:  portainer.NotificationChannel{Enabled: true, EndpointGroupIDs: []portainer.EndpointGroupID{2}},
+			event:    endpointEvent,
+			expected: true,
+		},
+		{
+			name:     "channel subscribed to other endpoint groups",
+			channel:  portainer.NotificationChannel{Enabled: true, EndpointGroupIDs: []portainer.EndpointGroupID{1, 3}},
+			event:    endpointEvent,
+			expected: false,
+		},
+		{
+			name:     "event not related to an endpoint",
+			channel:  portainer.NotificationChannel{Enabled: true, EndpointGroupIDs: []portainer.EndpointGroupID{1}},
+			event:    backupEvent,
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Matches(&tt.channel, tt.event))
+		})
+	}
+}
+
+func Test_renderBody(t *testing.T) {
+	event := &portainer.NotificationEvent{Type: portainer.NotificationEventEndpointDown, Time: 1600000000, EndpointID: 1, EndpointName: "prod", Message: "Endpoint prod is down"}
+
+	body, err := renderBody(&portainer.NotificationChannel{}, event)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"Type":"endpoint.down","Time":1600000000,"EndpointId":1,"EndpointName":"prod","Message":"Endpoint prod is down"}`, string(body))
+
+	body, err = renderBody(&portainer.NotificationChannel{BodyTemplate: `{"summary": "{{ .Type }} on {{ .EndpointName }}"}`}, event)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"summary": "endpoint.down on prod"}`, string(body))
+
+	event.EndpointName = `prod "eu"`
+	event.Details = map[string]string{"error": "line 1\nline 2"}
+	body, err = renderBody(&portainer.NotificationChannel{BodyTemplate: `{"summary": "{{ .EndpointName }}", "error": "{{ .Details.error }}"}`}, event)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"summary": "prod \"eu\"", "error": "line 1\nline 2"}`, string(body))
+
+	_, err = renderBody(&portainer.NotificationChannel{BodyTemplate: `{"summary": {{ .EndpointName }}}`}, event)
+	assert.Error(t, err)
+
+	assert.Error(t, ValidateBodyTemplate("{{ .Type "))
+	assert.NoError(t, ValidateBodyTemplate("{{ .Message }}"))
+}
+
+func Test_subject(t *testing.T) {
+	event := &portainer.NotificationEvent{Type: portainer.NotificationEventEndpointDown, EndpointName: "prod\r\nBcc: attacker@example.com"}
+	assert.Equal(t, "[Portainer] endpoint.down: prod  Bcc: attacker@example.com", subject(event))
+}
+
+func Test_text(t *testing.T) {
+	event := &portainer.NotificationEvent{Message: "Backup failed", Details: map[string]string{"error": "disk full", "attempt": "1"}}
+	assert.Equal(t, "Backup failed\nattempt: 1\nerror: disk full", text(event))
+}
+
+func Test_sendSlack(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received = string(body)
+	}))
+	defer server.Close()
+
+	channel := &portainer.NotificationChannel{Type: portainer.NotificationChannelSlack, URL: server.URL}
+	err := sendSlack(server.Client(), channel, &portainer.NotificationEvent{Message: "Endpoint prod is down"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"text":"Endpoint prod is down"}`, received)
+}
+
+func Test_sendWebhook_UnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	channel := &portainer.NotificationChannel{Type: portainer.NotificationChannelWebhook, URL: server.URL}
+	err := sendWebhook(server.Client(), channel, &portainer.NotificationEvent{Message: "Backup failed"})
+	assert.Error(t, err)
+}
+
+func Test_deliver_RetriesUntilSuccess(t *testing.T) {
+	store := testhelpers.NewDatastore(testhelpers.WithNotificationDeliveries(nil))
+
+	attempts := 0
+	service := NewService(store, context.Background())
+	service.retryBaseDelay = time.Millisecond
+	service.senders = map[portainer.NotificationChannelType]sender{
+		portainer.NotificationChannelWebhook: func(channel *portainer.NotificationChannel, event *portainer.NotificationEvent) error {
+			attempts++
+			if attempts < 3 {
+				return errors.New("connection refused")
+			}
+			return nil
+		},
+	}
+
+	service.deliver(&portainer.NotificationChannel{ID: 1, Type: portainer.NotificationChannelWebhook}, portainer.NotificationEvent{Type: portainer.NotificationEventBackupFailed})
+
+	deliveries, _ := store.NotificationDelivery().NotificationDeliveries()
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, portainer.NotificationDeliverySuccess, deliveries[0].Status)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Empty(t, deliveries[0].LastError)
+}
+
+func Test_deliver_GivesUpAfterMaxAttempts(t *testing.T) {
+	store := testhelpers.NewDatastore(testhelpers.WithNotificationDeliveries(nil))
+
+	service := NewService(store, context.Background())
+	service.retryBaseDelay = time.Millisecond
+
+	service.deliver(&portainer.NotificationChannel{ID: 1, Type: portainer.NotificationChannelType(42)}, portainer.NotificationEvent{Type: portainer.NotificationEventBackupFailed})
+
+	deliveries, _ := store.NotificationDelivery().NotificationDeliveries()
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, portainer.NotificationDeliveryFailed, deliveries[0].Status)
+	assert.Equal(t, portainer.NotificationMaxAttempts, deliveries[0].Attempts)
+	assert.Equal(t, errUnsupportedChannelType.Error(), deliveries[0].LastError)
+}
@@ -0,0 +1,234 @@
+package notification
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+var (
+	errUnsupportedChannelType = errors.New("Unsupported notification channel type")
+	errInvalidBody            = errors.New("The body template did not render a valid JSON document")
+	errSMTPAuthUnsupported    = errors.New("The SMTP server does not support authentication")
+)
+
+// ValidateBodyTemplate returns an error when the body template of a webhook channel cannot be parsed
+func ValidateBodyTemplate(bodyTemplate string) error {
+	_, err := template.New("body").Parse(bodyTemplate)
+	return err
+}
+
+// renderBody returns the body sent to a webhook channel, the event encoded as JSON when the channel has no body template.
+// The strings of the event are escaped for JSON before rendering the template, and the rendered body must be a valid JSON document.
+func renderBody(channel *portainer.NotificationChannel, event *portainer.NotificationEvent) ([]byte, error) {
+	if channel.BodyTemplate == "" {
+		return json.Marshal(event)
+	}
+
+	tmpl, err := template.New("body").Parse(channel.BodyTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	err = tmpl.Execute(&body, escapeEvent(event))
+	if err != nil {
+		return nil, err
+	}
+
+	if !json.Valid(body.Bytes()) {
+		return nil, errInvalidBody
+	}
+
+	return body.Bytes(), nil
+}
+
+// escapeEvent returns a copy of the event whose strings are escaped to be inserted in a JSON string
+func escapeEvent(event *portainer.NotificationEvent) *portainer.NotificationEvent {
+	escaped := *event
+	escaped.Type = portainer.NotificationEventType(escapeJSONString(string(event.Type)))
+	escaped.EndpointName = escapeJSONString(event.EndpointName)
+	escaped.Message = escapeJSONString(event.Message)
+
+	if event.Details != nil {
+		escaped.Details = make(map[string]string, len(event.Details))
+		for key, value := range event.Details {
+			escaped.Details[key] = escapeJSONString(value)
+		}
+	}
+
+	return &escaped
+}
+
+func escapeJSONString(value string) string {
+	encoded, _ := json.Marshal(value)
+	return string(encoded[1 : len(encoded)-1])
+}
+
+func sendWebhook(client *http.Client, channel *portainer.NotificationChannel, event *portainer.NotificationEvent) error {
+	body, err := renderBody(channel, event)
+	if err != nil {
+		return err
+	}
+
+	return post(client, channel.URL, body)
+}
+
+// sendSlack sends the event to an incoming webhook accepting a JSON object with a text field,
+// which is supported by Slack, Microsoft Teams and Mattermost
+func sendSlack(client *http.Client, channel *portainer.NotificationChannel, event *portainer.NotificationEvent) error {
+	body, err := json.Marshal(map[string]string{"text": text(event)})
+	if err != nil {
+		return err
+	}
+
+	return post(client, channel.URL, body)
+}
+
+func post(client *http.Client, url string, body []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Unexpected response status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+func sendEmail(channel *portainer.NotificationChannel, event *portainer.NotificationEvent) error {
+	settings := channel.Email
+	if settings == nil {
+		return errors.New("Missing SMTP settings")
+	}
+
+	var auth smtp.Auth
+	if settings.Username != "" {
+		auth = smtp.PlainAuth("", settings.Username, settings.Password, settings.Host)
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", headerValue(settings.From))
+	fmt.Fprintf(&message, "To: %s\r\n", headerValue(strings.Join(settings.To, ", ")))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject(event)))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Unix(event.Time, 0).Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	message.WriteString(strings.ReplaceAll(text(event), "\n", "\r\n"))
+	message.WriteString("\r\n")
+
+	return sendMail(settings.Host, settings.Port, auth, settings.From, settings.To, message.Bytes())
+}
+
+// sendMail sends a message like smtp.SendMail does, giving up when the SMTP server does not answer within smtpTimeout
+func sendMail(host string, port int, auth smtp.Auth, from string, to []string, message []byte) error {
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	conn, err := dialer.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(smtpTimeout))
+	if err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: host})
+		if err != nil {
+			return err
+		}
+	}
+
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errSMTPAuthUnsupported
+		}
+
+		err = client.Auth(auth)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = client.Mail(from)
+	if err != nil {
+		return err
+	}
+
+	for _, recipient := range to {
+		err = client.Rcpt(recipient)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(message)
+	if err != nil {
+		return err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// headerValue removes the line breaks of a value, which would start a new header of the message
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
+
+func subject(event *portainer.NotificationEvent) string {
+	if event.EndpointName != "" {
+		return headerValue(fmt.Sprintf("[Portainer] %s: %s", event.Type, event.EndpointName))
+	}
+	return headerValue(fmt.Sprintf("[Portainer] %s", event.Type))
+}
+
+// text returns a plain text description of the event and its details
+func text(event *portainer.NotificationEvent) string {
+	lines := []string{event.Message}
+
+	keys := make([]string, 0, len(event.Details))
+	for key := range event.Details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		lines = append(lines, fmt.Sprintf("%s: %s", key, event.Details[key]))
+	}
+
+	return strings.Join(lines, "\n")
+}
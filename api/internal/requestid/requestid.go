@@ -0,0 +1,243 @@
+// Package requestid correlates the hops of an API request. Each request gets an identifier,
+// taken from its X-Request-ID header when valid or generated otherwise, which is returned in the
+// response headers and in the body of the error responses, forwarded to the Docker and Kubernetes
+// APIs and to the agents, and added to the log entries related to the request.
+package requestid
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/portainer/portainer/api/internal/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// Header is the header carrying the request identifier
+const Header = "X-Request-ID"
+
+// maxLength is the maximum length of a request identifier provided by a client
+const maxLength = 128
+
+// bodyField is the field of the JSON error responses carrying the request identifier
+const bodyField = "requestId"
+
+var logger = logging.Component("http")
+
+type contextKey struct{}
+
+// Tracer starts a span for each API request. It is used to export the API requests to a tracing
+// backend such as OpenTelemetry.
+type Tracer interface {
+	// StartSpan starts the span of a request and returns the context carrying the span
+	// and a function ending the span with the status code of the response
+	StartSpan(ctx context.Context, r *http.Request, requestID string) (context.Context, func(statusCode int))
+}
+
+// NewContext returns a copy of the context carrying the request identifier
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, requestID)
+}
+
+// FromContext returns the request identifier carried by the context, if any
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	requestID, _ := ctx.Value(contextKey{}).(string)
+	return requestID
+}
+
+// FromRequest returns the identifier of a request, if any
+func FromRequest(r *http.Request) string {
+	return FromContext(r.Context())
+}
+
+// SetHeader sets the request identifier carried by the context on the headers of an outgoing request
+func SetHeader(ctx context.Context, header http.Header) {
+	requestID := FromContext(ctx)
+	if requestID != "" {
+		header.Set(Header, requestID)
+	}
+}
+
+// Middleware assigns an identifier to each request. The identifier is stored in the request context,
+// set on the request headers so that it is forwarded by the reverse proxies, and set on the response headers.
+// It is also added to the JSON error responses and to the log entry of the failed requests.
+// A span is started for each request when a tracer is specified.
+func Middleware(next http.Handler, tracer Tracer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(Header)
+		if !isValid(requestID) {
+			requestID = generate()
+		}
+
+		r.Header.Set(Header, requestID)
+		w.Header().Set(Header, requestID)
+
+		ctx := NewContext(r.Context(), requestID)
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		if tracer != nil {
+			var end func(statusCode int)
+			ctx, end = tracer.StartSpan(ctx, r, requestID)
+			defer func() { end(recorder.status) }()
+		}
+
+		start := time.Now()
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+		message, details := recorder.writeErrorResponse(requestID)
+
+		entry := logger.WithContext(ctx).WithFields(logrus.Fields{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"status":      recorder.status,
+			"duration_ms": time.Since(start).Milliseconds(),
+		})
+
+		switch {
+		case message == "":
+			entry.Debug("request completed")
+		case recorder.status >= http.StatusInternalServerError:
+			entry.WithField("error", message).WithField("details", details).Error("request failed")
+		default:
+			entry.WithField("error", message).WithField("details", details).Info("request failed")
+		}
+	})
+}
+
+// isValid returns true when a request identifier provided by a client can be used as is
+func isValid(requestID string) bool {
+	if requestID == "" || len(requestID) > maxLength {
+		return false
+	}
+
+	for _, c := range requestID {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func generate() string {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return ""
+	}
+	return id.String()
+}
+
+// LogHook adds the identifier of the request carried by the context of the log entries
+type LogHook struct{}
+
+// Levels returns the levels the hook applies to
+func (hook LogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire adds the request identifier to the entry
+func (hook LogHook) Fire(entry *logrus.Entry) error {
+	requestID := FromContext(entry.Context)
+	if requestID != "" {
+		entry.Data[logging.FieldRequestID] = requestID
+	}
+	return nil
+}
+
+// statusRecorder records the status code of a response. The body of a JSON error response is held back
+// until the handler returns, so that the request identifier can be added to it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	errorBody   *bytes.Buffer
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	if !recorder.wroteHeader {
+		recorder.status = status
+		recorder.wroteHeader = true
+
+		if status >= http.StatusBadRequest && isJSON(recorder.Header()) {
+			recorder.errorBody = &bytes.Buffer{}
+			return
+		}
+	}
+
+	if recorder.errorBody == nil {
+		recorder.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (recorder *statusRecorder) Write(b []byte) (int, error) {
+	recorder.wroteHeader = true
+	if recorder.errorBody != nil {
+		return recorder.errorBody.Write(b)
+	}
+	return recorder.ResponseWriter.Write(b)
+}
+
+// writeErrorResponse writes the error response held back, with the request identifier added to it
+// when the body is a JSON object. It returns the message and the details of the error, if any.
+func (recorder *statusRecorder) writeErrorResponse(requestID string) (message, details string) {
+	if recorder.errorBody == nil {
+		return "", ""
+	}
+
+	body := recorder.errorBody.Bytes()
+	recorder.errorBody = nil
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err == nil && fields != nil {
+		json.Unmarshal(fields["message"], &message)
+		json.Unmarshal(fields["details"], &details)
+
+		if _, ok := fields[bodyField]; !ok {
+			fields[bodyField], _ = json.Marshal(requestID)
+			if rewritten, err := json.Marshal(fields); err == nil {
+				body = append(rewritten, '\n')
+			}
+		}
+	}
+
+	recorder.Header().Del("Content-Length")
+	recorder.ResponseWriter.WriteHeader(recorder.status)
+	if _, err := recorder.ResponseWriter.Write(body); err != nil {
+		logger.WithError(err).Debug("unable to write the error response")
+	}
+
+	return message, details
+}
+
+func (recorder *statusRecorder) Flush() {
+	if recorder.errorBody != nil {
+		return
+	}
+
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (recorder *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := recorder.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	recorder.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+func isJSON(header http.Header) bool {
+	return strings.HasPrefix(header.Get("Content-Type"), "application/json") && header.Get("Content-Encoding") == ""
+}
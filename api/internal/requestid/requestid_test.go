@@ -0,0 +1,158 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Middleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+		generated bool
+	}{
+		{name: "identifier provided by the client", requestID: "deploy-1234", generated: false},
+		{name: "no identifier provided", requestID: "", generated: true},
+		{name: "identifier with invalid characters", requestID: "deploy 1234", generated: true},
+		{name: "identifier too long", requestID: strings.Repeat("a", maxLength+1), generated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var contextID, headerID string
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contextID = FromRequest(r)
+				headerID = r.Header.Get(Header)
+			}), nil)
+
+			r := httptest.NewRequest(http.MethodGet, "/api/stacks", nil)
+			if tt.requestID != "" {
+				r.Header.Set(Header, tt.requestID)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			assert.NotEmpty(t, contextID)
+			assert.Equal(t, contextID, headerID)
+			assert.Equal(t, contextID, w.Header().Get(Header))
+			if tt.generated {
+				assert.NotEqual(t, tt.requestID, contextID)
+			} else {
+				assert.Equal(t, tt.requestID, contextID)
+			}
+		})
+	}
+}
+
+func Test_Middleware_ErrorResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		status      int
+		body        string
+		expected    string
+	}{
+		{
+			name:        "JSON error response",
+			contentType: "application/json",
+			status:      http.StatusNotFound,
+			body:        `{"message":"Unable to find a stack with the specified identifier inside the database","details":"Object not found inside the database"}` + "\n",
+			expected:    `{"details":"Object not found inside the database","message":"Unable to find a stack with the specified identifier inside the database","requestId":"deploy-1234"}` + "\n",
+		},
+		{
+			name:        "JSON error response already carrying an identifier",
+			contentType: "application/json",
+			status:      http.StatusBadGateway,
+			body:        `{"message":"agent error","requestId":"agent-1"}`,
+			expected:    `{"message":"agent error","requestId":"agent-1"}`,
+		},
+		{
+			name:        "plain text error response",
+			contentType: "text/plain",
+			status:      http.StatusNotFound,
+			body:        "404 page not found\n",
+			expected:    "404 page not found\n",
+		},
+		{
+			name:        "JSON success response",
+			contentType: "application/json",
+			status:      http.StatusOK,
+			body:        `{"message":"ok"}`,
+			expected:    `{"message":"ok"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}), nil)
+
+			r := httptest.NewRequest(http.MethodGet, "/api/stacks/1", nil)
+			r.Header.Set(Header, "deploy-1234")
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.expected, w.Body.String())
+		})
+	}
+}
+
+type stubTracer struct {
+	requestID  string
+	statusCode int
+}
+
+func (tracer *stubTracer) StartSpan(ctx context.Context, r *http.Request, requestID string) (context.Context, func(statusCode int)) {
+	tracer.requestID = requestID
+	return ctx, func(statusCode int) {
+		tracer.statusCode = statusCode
+	}
+}
+
+func Test_Middleware_Tracer(t *testing.T) {
+	tracer := &stubTracer{}
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}), tracer)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/stacks/1", nil)
+	r.Header.Set(Header, "deploy-1234")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, "deploy-1234", tracer.requestID)
+	assert.Equal(t, http.StatusNotFound, tracer.statusCode)
+}
+
+func Test_SetHeader(t *testing.T) {
+	header := http.Header{}
+	SetHeader(context.Background(), header)
+	assert.Empty(t, header.Get(Header))
+
+	SetHeader(NewContext(context.Background(), "deploy-1234"), header)
+	assert.Equal(t, "deploy-1234", header.Get(Header))
+}
+
+func Test_LogHook(t *testing.T) {
+	entry := logrus.NewEntry(logrus.New()).WithContext(NewContext(context.Background(), "deploy-1234"))
+
+	err := LogHook{}.Fire(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, "deploy-1234", entry.Data["request_id"])
+
+	entry = logrus.NewEntry(logrus.New())
+	err = LogHook{}.Fire(entry)
+	assert.NoError(t, err)
+	assert.NotContains(t, entry.Data, "request_id")
+}
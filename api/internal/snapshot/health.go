@@ -0,0 +1,76 @@
+package snapshot
+
+import (
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	// flappingWindow is the period over which the status changes of an endpoint are counted to detect flapping
+	flappingWindow = time.Hour
+	// flappingStatusChanges is the number of status changes within the flapping window after which an endpoint is flapping
+	flappingStatusChanges = 4
+)
+
+// FailureThreshold returns the number of consecutive failed snapshots after which an endpoint is marked as down
+func FailureThreshold(settings *portainer.Settings) int {
+	if settings.EndpointFailureThreshold <= 0 {
+		return portainer.DefaultEndpointFailureThreshold
+	}
+	return settings.EndpointFailureThreshold
+}
+
+// UpdateEndpointHealth records the outcome of a contact with the endpoint and updates its status.
+// The endpoint is marked as up on the first successful contact and as down once the number
+// of consecutive failed contacts reaches the failure threshold.
+func UpdateEndpointHealth(endpoint *portainer.Endpoint, contactError error, failureThreshold int, now time.Time) {
+	health := &endpoint.Health
+
+	if contactError == nil {
+		health.LastSuccessDate = now.Unix()
+		health.ConsecutiveFailures = 0
+		setEndpointStatus(endpoint, portainer.EndpointStatusUp, "", now)
+		return
+	}
+
+	health.LastError = contactError.Error()
+	health.LastErrorDate = now.Unix()
+	health.ConsecutiveFailures++
+
+	if health.ConsecutiveFailures >= failureThreshold {
+		setEndpointStatus(endpoint, portainer.EndpointStatusDown, health.LastError, now)
+	}
+}
+
+func setEndpointStatus(endpoint *portainer.Endpoint, status portainer.EndpointStatus, reason string, now time.Time) {
+	if endpoint.Status == status {
+		return
+	}
+
+	endpoint.Status = status
+
+	history := append(endpoint.Health.StatusHistory, portainer.EndpointStatusChange{
+		Status: status,
+		Date:   now.Unix(),
+		Reason: reason,
+	})
+	if len(history) > portainer.EndpointStatusHistoryMaxSize {
+		history = history[len(history)-portainer.EndpointStatusHistoryMaxSize:]
+	}
+	endpoint.Health.StatusHistory = history
+}
+
+// IsFlapping returns true when the status of the endpoint changed too often over the latest flapping window
+func IsFlapping(health *portainer.EndpointHealth, now time.Time) bool {
+	since := now.Add(-flappingWindow).Unix()
+
+	changes := 0
+	for _, change := range health.StatusHistory {
+		if change.Date >= since {
+			changes++
+		}
+	}
+
+	return changes >= flappingStatusChanges
+}
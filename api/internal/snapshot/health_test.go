@@ -0,0 +1,84 @@
+package snapshot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FailureThreshold(t *testing.T) {
+	assert.Equal(t, portainer.DefaultEndpointFailureThreshold, FailureThreshold(&portainer.Settings{}))
+	assert.Equal(t, 3, FailureThreshold(&portainer.Settings{EndpointFailureThreshold: 3}))
+}
+
+func Test_UpdateEndpointHealth(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	contactError := errors.New("connection refused")
+	endpoint := &portainer.Endpoint{Status: portainer.EndpointStatusUp}
+
+	UpdateEndpointHealth(endpoint, contactError, 2, now)
+	assert.Equal(t, portainer.EndpointStatusUp, endpoint.Status, "the endpoint stays up until the threshold is reached")
+	assert.Equal(t, 1, endpoint.Health.ConsecutiveFailures)
+	assert.Equal(t, "connection refused", endpoint.Health.LastError)
+	assert.Equal(t, now.Unix(), endpoint.Health.LastErrorDate)
+	assert.Empty(t, endpoint.Health.StatusHistory)
+
+	UpdateEndpointHealth(endpoint, contactError, 2, now.Add(time.Minute))
+	assert.Equal(t, portainer.EndpointStatusDown, endpoint.Status)
+	assert.Equal(t, []portainer.EndpointStatusChange{{Status: portainer.EndpointStatusDown, Date: now.Add(time.Minute).Unix(), Reason: "connection refused"}}, endpoint.Health.StatusHistory)
+
+	UpdateEndpointHealth(endpoint, contactError, 2, now.Add(2*time.Minute))
+	assert.Len(t, endpoint.Health.StatusHistory, 1, "a failure of a down endpoint is not a status change")
+
+	UpdateEndpointHealth(endpoint, nil, 2, now.Add(3*time.Minute))
+	assert.Equal(t, portainer.EndpointStatusUp, endpoint.Status)
+	assert.Equal(t, 0, endpoint.Health.ConsecutiveFailures)
+	assert.Equal(t, now.Add(3*time.Minute).Unix(), endpoint.Health.LastSuccessDate)
+	assert.Equal(t, "connection refused", endpoint.Health.LastError, "the last error is kept after a success")
+	assert.Equal(t, portainer.EndpointStatusChange{Status: portainer.EndpointStatusUp, Date: now.Add(3 * time.Minute).Unix()}, endpoint.Health.StatusHistory[1])
+}
+
+func Test_UpdateEndpointHealth_StatusHistoryIsBounded(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	endpoint := &portainer.Endpoint{Status: portainer.EndpointStatusUp}
+
+	for i := 0; i < portainer.EndpointStatusHistoryMaxSize+5; i++ {
+		UpdateEndpointHealth(endpoint, errors.New("timeout"), 1, now.Add(time.Duration(2*i)*time.Minute))
+		UpdateEndpointHealth(endpoint, nil, 1, now.Add(time.Duration(2*i+1)*time.Minute))
+	}
+
+	assert.Len(t, endpoint.Health.StatusHistory, portainer.EndpointStatusHistoryMaxSize)
+	assert.Equal(t, portainer.EndpointStatusUp, endpoint.Health.StatusHistory[portainer.EndpointStatusHistoryMaxSize-1].Status)
+}
+
+func Test_IsFlapping(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	changes := func(ages ...time.Duration) *portainer.EndpointHealth {
+		health := &portainer.EndpointHealth{}
+		for _, age := range ages {
+			health.StatusHistory = append(health.StatusHistory, portainer.EndpointStatusChange{Date: now.Add(-age).Unix()})
+		}
+		return health
+	}
+
+	tests := []struct {
+		name     string
+		health   *portainer.EndpointHealth
+		expected bool
+	}{
+		{name: "no status change", health: changes(), expected: false},
+		{name: "few status changes", health: changes(50*time.Minute, 10*time.Minute), expected: false},
+		{name: "many status changes within the window", health: changes(40*time.Minute, 30*time.Minute, 20*time.Minute, 10*time.Minute), expected: true},
+		{name: "many status changes outside of the window", health: changes(5*time.Hour, 4*time.Hour, 3*time.Hour, 2*time.Hour, time.Minute), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsFlapping(tt.health, now))
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package snapshot
+
+import (
+	"sort"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/logging"
+)
+
+// HistoryPolicy defines how long the snapshot history of an endpoint is kept
+// and when it is downsampled.
+type HistoryPolicy struct {
+	Retention          time.Duration
+	DownsampleAfter    time.Duration
+	DownsampleInterval time.Duration
+}
+
+// NewHistoryPolicy returns the snapshot history policy defined in the settings,
+// using the default value of each unset field.
+func NewHistoryPolicy(settings *portainer.Settings) (HistoryPolicy, error) {
+	var policy HistoryPolicy
+	var err error
+
+	policy.Retention, err = parseDurationOrDefault(settings.SnapshotHistoryRetention, portainer.DefaultSnapshotHistoryRetention)
+	if err != nil {
+		return policy, err
+	}
+
+	policy.DownsampleAfter, err = parseDurationOrDefault(settings.SnapshotHistoryDownsampleAfter, portainer.DefaultSnapshotHistoryDownsampleAfter)
+	if err != nil {
+		return policy, err
+	}
+
+	policy.DownsampleInterval, err = parseDurationOrDefault(settings.SnapshotHistoryDownsampleInterval, portainer.DefaultSnapshotHistoryDownsampleInterval)
+	if err != nil {
+		return policy, err
+	}
+
+	return policy, nil
+}
+
+func parseDurationOrDefault(value, defaultValue string) (time.Duration, error) {
+	if value == "" {
+		value = defaultValue
+	}
+	return time.ParseDuration(value)
+}
+
+// NewHistoryPoint returns a history point built from the latest snapshot of the endpoint,
+// or nil when the endpoint has no snapshot.
+func NewHistoryPoint(endpoint *portainer.Endpoint) *portainer.SnapshotHistoryPoint {
+	point := &portainer.SnapshotHistoryPoint{
+		EndpointID: endpoint.ID,
+		Samples:    1,
+	}
+
+	switch {
+	case len(endpoint.Snapshots) > 0:
+		snapshot := endpoint.Snapshots[len(endpoint.Snapshots)-1]
+		point.Time = snapshot.Time
+		point.RunningContainerCount = snapshot.RunningContainerCount
+		point.StoppedContainerCount = snapshot.StoppedContainerCount
+		point.HealthyContainerCount = snapshot.HealthyContainerCount
+		point.UnhealthyContainerCount = snapshot.UnhealthyContainerCount
+		point.ImageCount = snapshot.ImageCount
+		point.VolumeCount = snapshot.VolumeCount
+		point.ServiceCount = snapshot.ServiceCount
+		point.StackCount = snapshot.StackCount
+		point.NodeCount = snapshot.NodeCount
+		point.TotalCPU = int64(snapshot.TotalCPU)
+		point.TotalMemory = snapshot.TotalMemory
+	case len(endpoint.Kubernetes.Snapshots) > 0:
+		snapshot := endpoint.Kubernetes.Snapshots[len(endpoint.Kubernetes.Snapshots)-1]
+		point.Time = snapshot.Time
+		point.NodeCount = snapshot.NodeCount
+		point.TotalCPU = snapshot.TotalCPU
+		point.TotalMemory = snapshot.TotalMemory
+	default:
+		return nil
+	}
+
+	if point.Time == 0 {
+		point.Time = time.Now().Unix()
+	}
+
+	return point
+}
+
+// HistoryInterval returns the minimum time between two history points of an endpoint reporting its snapshots itself,
+// such as an Edge endpoint on each check-in: the snapshot interval of the endpoint when set, otherwise the snapshot
+// interval defined in the settings.
+func HistoryInterval(endpoint *portainer.Endpoint, settings *portainer.Settings) time.Duration {
+	for _, value := range []string{endpoint.SnapshotInterval, settings.SnapshotInterval} {
+		interval, err := time.ParseDuration(value)
+		if err == nil && interval > 0 {
+			return interval
+		}
+	}
+
+	return 0
+}
+
+// DownsampleHistory averages the points over intervals of the specified length (in seconds).
+// Each resulting point is timestamped with the start of its interval and its values are weighted
+// by the number of samples of the points it aggregates, so downsampling an already downsampled
+// history does not change it.
+func DownsampleHistory(points []portainer.SnapshotHistoryPoint, interval int64) []portainer.SnapshotHistoryPoint {
+	if interval <= 0 {
+		return points
+	}
+
+	type accumulator struct {
+		endpointID portainer.EndpointID
+		samples    int64
+		running    int64
+		stopped    int64
+		healthy    int64
+		unhealthy  int64
+		images     int64
+		volumes    int64
+		services   int64
+		stacks     int64
+		nodes      int64
+		cpu        int64
+		memory     int64
+	}
+
+	buckets := make(map[int64]*accumulator)
+	for _, point := range points {
+		start := point.Time - point.Time%interval
+
+		acc, ok := buckets[start]
+		if !ok {
+			acc = &accumulator{endpointID: point.EndpointID}
+			buckets[start] = acc
+		}
+
+		weight := int64(point.Samples)
+		if weight < 1 {
+			weight = 1
+		}
+
+		acc.samples += weight
+		acc.running += weight * int64(point.RunningContainerCount)
+		acc.stopped += weight * int64(point.StoppedContainerCount)
+		acc.healthy += weight * int64(point.HealthyContainerCount)
+		acc.unhealthy += weight * int64(point.UnhealthyContainerCount)
+		acc.images += weight * int64(point.ImageCount)
+		acc.volumes += weight * int64(point.VolumeCount)
+		acc.services += weight * int64(point.ServiceCount)
+		acc.stacks += weight * int64(point.StackCount)
+		acc.nodes += weight * int64(point.NodeCount)
+		acc.cpu += weight * point.TotalCPU
+		acc.memory += weight * point.TotalMemory
+	}
+
+	downsampled := make([]portainer.SnapshotHistoryPoint, 0, len(buckets))
+	for start, acc := range buckets {
+		average := func(total int64) int64 {
+			return (total + acc.samples/2) / acc.samples
+		}
+
+		downsampled = append(downsampled, portainer.SnapshotHistoryPoint{
+			EndpointID:              acc.endpointID,
+			Time:                    start,
+			Samples:                 int(acc.samples),
+			RunningContainerCount:   int(average(acc.running)),
+			StoppedContainerCount:   int(average(acc.stopped)),
+			HealthyContainerCount:   int(average(acc.healthy)),
+			UnhealthyContainerCount: int(average(acc.unhealthy)),
+			ImageCount:              int(average(acc.images)),
+			VolumeCount:             int(average(acc.volumes)),
+			ServiceCount:            int(average(acc.services)),
+			StackCount:              int(average(acc.stacks)),
+			NodeCount:               int(average(acc.nodes)),
+			TotalCPU:                average(acc.cpu),
+			TotalMemory:             average(acc.memory),
+		})
+	}
+
+	sort.Slice(downsampled, func(i, j int) bool {
+		return downsampled[i].Time < downsampled[j].Time
+	})
+
+	return downsampled
+}
+
+func (service *Service) recordHistory(endpoint *portainer.Endpoint) {
+	point := NewHistoryPoint(endpoint)
+	if point == nil {
+		return
+	}
+
+	err := service.dataStore.SnapshotHistory().CreateSnapshotHistoryPoint(point)
+	if err != nil {
+		logger.WithField(logging.FieldEndpointID, endpoint.ID).WithError(err).Error("unable to record snapshot history")
+	}
+}
+
+// compactHistory removes the points older than the retention period and downsamples
+// the points older than the downsampling age, for every endpoint.
+func (service *Service) compactHistory(endpoints []portainer.Endpoint, now time.Time) error {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	policy, err := NewHistoryPolicy(settings)
+	if err != nil {
+		return err
+	}
+
+	interval := int64(policy.DownsampleInterval.Seconds())
+	retentionCutoff := now.Add(-policy.Retention).Unix()
+	downsampleCutoff := now.Add(-policy.DownsampleAfter).Unix()
+	if interval > 0 {
+		// only downsample complete intervals
+		downsampleCutoff -= downsampleCutoff % interval
+	}
+
+	for _, endpoint := range endpoints {
+		err := service.dataStore.SnapshotHistory().DeleteSnapshotHistory(endpoint.ID, retentionCutoff)
+		if err != nil {
+			return err
+		}
+
+		if interval <= 0 || downsampleCutoff <= retentionCutoff {
+			continue
+		}
+
+		points, err := service.dataStore.SnapshotHistory().SnapshotHistory(endpoint.ID, retentionCutoff, downsampleCutoff)
+		if err != nil {
+			return err
+		}
+
+		downsampled := DownsampleHistory(points, interval)
+		if len(downsampled) == len(points) {
+			continue
+		}
+
+		err = service.dataStore.SnapshotHistory().ReplaceSnapshotHistory(endpoint.ID, retentionCutoff, downsampleCutoff, downsampled)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package snapshot
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewHistoryPolicy(t *testing.T) {
+	policy, err := NewHistoryPolicy(&portainer.Settings{})
+	assert.NoError(t, err)
+	assert.Equal(t, HistoryPolicy{Retention: 720 * time.Hour, DownsampleAfter: 24 * time.Hour, DownsampleInterval: time.Hour}, policy)
+
+	policy, err = NewHistoryPolicy(&portainer.Settings{SnapshotHistoryRetention: "168h", SnapshotHistoryDownsampleInterval: "15m"})
+	assert.NoError(t, err)
+	assert.Equal(t, HistoryPolicy{Retention: 168 * time.Hour, DownsampleAfter: 24 * time.Hour, DownsampleInterval: 15 * time.Minute}, policy)
+
+	_, err = NewHistoryPolicy(&portainer.Settings{SnapshotHistoryDownsampleAfter: "one day"})
+	assert.Error(t, err)
+}
+
+func Test_NewHistoryPoint(t *testing.T) {
+	endpoint := &portainer.Endpoint{ID: 1, Snapshots: []portainer.DockerSnapshot{{Time: 100, RunningContainerCount: 3, ImageCount: 7, TotalCPU: 4, TotalMemory: 2048}}}
+	assert.Equal(t, &portainer.SnapshotHistoryPoint{EndpointID: 1, Time: 100, Samples: 1, RunningContainerCount: 3, ImageCount: 7, TotalCPU: 4, TotalMemory: 2048}, NewHistoryPoint(endpoint))
+
+	endpoint = &portainer.Endpoint{ID: 2}
+	endpoint.Kubernetes.Snapshots = []portainer.KubernetesSnapshot{{Time: 200, NodeCount: 3, TotalCPU: 12, TotalMemory: 4096}}
+	assert.Equal(t, &portainer.SnapshotHistoryPoint{EndpointID: 2, Time: 200, Samples: 1, NodeCount: 3, TotalCPU: 12, TotalMemory: 4096}, NewHistoryPoint(endpoint))
+
+	assert.Nil(t, NewHistoryPoint(&portainer.Endpoint{ID: 3}))
+}
+
+func Test_HistoryInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		settings string
+		expected time.Duration
+	}{
+		{name: "endpoint interval", endpoint: "1m", settings: "5m", expected: time.Minute},
+		{name: "settings interval", endpoint: "", settings: "5m", expected: 5 * time.Minute},
+		{name: "invalid endpoint interval", endpoint: "often", settings: "5m", expected: 5 * time.Minute},
+		{name: "no interval", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, HistoryInterval(&portainer.Endpoint{SnapshotInterval: tt.endpoint}, &portainer.Settings{SnapshotInterval: tt.settings}))
+		})
+	}
+}
+
+func Test_DownsampleHistory(t *testing.T) {
+	tests := []struct {
+		name     string
+		points   []portainer.SnapshotHistoryPoint
+		expected []portainer.SnapshotHistoryPoint
+	}{
+		{
+			name:     "empty history",
+			points:   []portainer.SnapshotHistoryPoint{},
+			expected: []portainer.SnapshotHistoryPoint{},
+		},
+		{
+			name: "points are averaged per interval",
+			points: []portainer.SnapshotHistoryPoint{
+				{EndpointID: 1, Time: 3600, Samples: 1, RunningContainerCount: 2, TotalMemory: 100},
+				{EndpointID: 1, Time: 3900, Samples: 1, RunningContainerCount: 4, TotalMemory: 200},
+				{EndpointID: 1, Time: 7300, Samples: 1, RunningContainerCount: 5, TotalMemory: 300},
+			},
+			expected: []portainer.SnapshotHistoryPoint{
+				{EndpointID: 1, Time: 3600, Samples: 2, RunningContainerCount: 3, TotalMemory: 150},
+				{EndpointID: 1, Time: 7200, Samples: 1, RunningContainerCount: 5, TotalMemory: 300},
+			},
+		},
+		{
+			name: "downsampled points are weighted by their samples",
+			points: []portainer.SnapshotHistoryPoint{
+				{EndpointID: 1, Time: 3600, Samples: 3, ImageCount: 10},
+				{EndpointID: 1, Time: 4000, Samples: 1, ImageCount: 2},
+			},
+			expected: []portainer.SnapshotHistoryPoint{
+				{EndpointID: 1, Time: 3600, Samples: 4, ImageCount: 8},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			downsampled := DownsampleHistory(tt.points, 3600)
+			assert.Equal(t, tt.expected, downsampled)
+			assert.Equal(t, downsampled, DownsampleHistory(downsampled, 3600))
+		})
+	}
+}
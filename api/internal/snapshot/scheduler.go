@@ -0,0 +1,122 @@
+package snapshot
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	// scheduleTick is the resolution at which the scheduler looks for endpoints due for a snapshot
+	scheduleTick = 10 * time.Second
+	// maxBackoff is the longest delay between two snapshots of a down endpoint, unless its interval is longer
+	maxBackoff = time.Hour
+	// jitterRatio is the maximum share of the delay added at random to spread snapshots over time
+	jitterRatio = 10
+)
+
+type endpointSchedule struct {
+	lastRun  time.Time
+	jitter   time.Duration
+	failures int
+	inFlight bool
+}
+
+// scheduler keeps track of when each endpoint was last snapshotted and of its consecutive failures.
+// An endpoint is due once its snapshot interval, doubled for each consecutive failure and capped to maxBackoff,
+// plus a random jitter has elapsed since its last snapshot.
+type scheduler struct {
+	mu        sync.Mutex
+	endpoints map[portainer.EndpointID]*endpointSchedule
+	jitter    func(max time.Duration) time.Duration
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		endpoints: make(map[portainer.EndpointID]*endpointSchedule),
+		jitter:    randomJitter,
+	}
+}
+
+func randomJitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// backoff returns the delay before the next snapshot of an endpoint after the specified number of consecutive failures
+func backoff(interval time.Duration, failures int) time.Duration {
+	limit := maxBackoff
+	if interval > limit {
+		limit = interval
+	}
+
+	delay := interval
+	for i := 0; i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+
+	if delay > limit {
+		return limit
+	}
+	return delay
+}
+
+// begin marks the endpoint as being snapshotted and returns true when it is due for a snapshot at the specified time.
+// An endpoint seen for the first time is scheduled within the first part of its interval so that
+// the endpoints are not all snapshotted at once on startup.
+func (s *scheduler) begin(endpointID portainer.EndpointID, interval time.Duration, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, ok := s.endpoints[endpointID]
+	if !ok {
+		schedule = &endpointSchedule{
+			lastRun: now.Add(-interval),
+			jitter:  s.jitter(interval / jitterRatio),
+		}
+		s.endpoints[endpointID] = schedule
+	}
+
+	if schedule.inFlight || now.Before(schedule.lastRun.Add(backoff(interval, schedule.failures)+schedule.jitter)) {
+		return false
+	}
+
+	schedule.inFlight = true
+	return true
+}
+
+// complete records the outcome of the snapshot of an endpoint started at the specified time.
+func (s *scheduler) complete(endpointID portainer.EndpointID, interval time.Duration, success bool, start time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, ok := s.endpoints[endpointID]
+	if !ok {
+		return
+	}
+
+	schedule.inFlight = false
+	schedule.lastRun = start
+	if success {
+		schedule.failures = 0
+	} else {
+		schedule.failures++
+	}
+	schedule.jitter = s.jitter(backoff(interval, schedule.failures) / jitterRatio)
+}
+
+// prune forgets the endpoints that are not part of the specified set anymore.
+func (s *scheduler) prune(endpoints map[portainer.EndpointID]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for endpointID, schedule := range s.endpoints {
+		if !endpoints[endpointID] && !schedule.inFlight {
+			delete(s.endpoints, endpointID)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package snapshot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_backoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		failures int
+		expected time.Duration
+	}{
+		{name: "no failure", interval: 5 * time.Minute, failures: 0, expected: 5 * time.Minute},
+		{name: "doubled for each failure", interval: 5 * time.Minute, failures: 2, expected: 20 * time.Minute},
+		{name: "capped to the maximum backoff", interval: 5 * time.Minute, failures: 10, expected: time.Hour},
+		{name: "interval longer than the maximum backoff", interval: 2 * time.Hour, failures: 3, expected: 2 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, backoff(tt.interval, tt.failures))
+		})
+	}
+}
+
+func Test_scheduler(t *testing.T) {
+	s := newScheduler()
+	s.jitter = func(max time.Duration) time.Duration { return max }
+
+	interval := 10 * time.Minute
+	now := time.Unix(1600000000, 0)
+
+	// first snapshot is delayed by the jitter only
+	assert.False(t, s.begin(1, interval, now))
+	assert.True(t, s.begin(1, interval, now.Add(time.Minute)))
+	assert.False(t, s.begin(1, interval, now.Add(time.Hour)), "an endpoint being snapshotted is not scheduled again")
+
+	start := now.Add(time.Minute)
+	s.complete(1, interval, true, start)
+	assert.False(t, s.begin(1, interval, start.Add(10*time.Minute)))
+	assert.True(t, s.begin(1, interval, start.Add(11*time.Minute)))
+
+	start = start.Add(11 * time.Minute)
+	s.complete(1, interval, false, start)
+	assert.False(t, s.begin(1, interval, start.Add(21*time.Minute)), "a failing endpoint is backed off")
+	assert.True(t, s.begin(1, interval, start.Add(22*time.Minute)))
+
+	s.prune(map[portainer.EndpointID]bool{})
+	assert.Contains(t, s.endpoints, portainer.EndpointID(1), "an endpoint being snapshotted is kept")
+	s.complete(1, interval, true, start)
+	s.prune(map[portainer.EndpointID]bool{})
+	assert.NotContains(t, s.endpoints, portainer.EndpointID(1))
+}
+
+func Test_snapshotOptions(t *testing.T) {
+	concurrency, timeout, err := snapshotOptions(&portainer.Settings{})
+	assert.NoError(t, err)
+	assert.Equal(t, portainer.DefaultSnapshotConcurrency, concurrency)
+	assert.Equal(t, 30*time.Second, timeout)
+
+	concurrency, timeout, err = snapshotOptions(&portainer.Settings{SnapshotConcurrency: 50, SnapshotTimeout: "5s"})
+	assert.NoError(t, err)
+	assert.Equal(t, 50, concurrency)
+	assert.Equal(t, 5*time.Second, timeout)
+}
+
+// hungDockerSnapshotter completes its snapshots only once their context is done
+type hungDockerSnapshotter struct{}
+
+func (hungDockerSnapshotter) CreateSnapshot(ctx context.Context, endpoint *portainer.Endpoint) (*portainer.DockerSnapshot, error) {
+	<-ctx.Done()
+	return &portainer.DockerSnapshot{Time: time.Now().Unix()}, nil
+}
+
+func Test_snapshotEndpointWithTimeout_DropsLateSnapshots(t *testing.T) {
+	service := &Service{
+		dockerSnapshotter: hungDockerSnapshotter{},
+		shutdownCtx:       context.Background(),
+	}
+
+	endpoint := &portainer.Endpoint{ID: 1, Type: portainer.DockerEnvironment}
+	err := service.snapshotEndpointWithTimeout(endpoint, 10*time.Millisecond)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "snapshot did not complete within 10ms")
+	assert.Empty(t, endpoint.Snapshots)
+}
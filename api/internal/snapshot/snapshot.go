@@ -2,26 +2,38 @@ package snapshot
 
 import (
 	"context"
-	"log"
+	"fmt"
 	"time"
 
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/logging"
+	"github.com/portainer/portainer/api/internal/metrics"
+	"github.com/portainer/portainer/api/internal/notification"
+	"github.com/sirupsen/logrus"
 )
 
+var logger = logging.Component("snapshot")
+
 // Service repesents a service to manage endpoint snapshots.
 // It provides an interface to start background snapshots as well as
 // specific Docker/Kubernetes endpoint snapshot methods.
+// Background snapshots are run by a bounded number of concurrent workers,
+// each endpoint being snapshotted on its own schedule.
 type Service struct {
 	dataStore                 portainer.DataStore
 	refreshSignal             chan struct{}
 	snapshotIntervalInSeconds float64
 	dockerSnapshotter         portainer.DockerSnapshotter
 	kubernetesSnapshotter     portainer.KubernetesSnapshotter
+	notificationService       portainer.NotificationService
 	shutdownCtx               context.Context
+	scheduler                 *scheduler
+	workers                   chan struct{}
+	lastCompaction            time.Time
 }
 
 // NewService creates a new instance of a service
-func NewService(snapshotInterval string, dataStore portainer.DataStore, dockerSnapshotter portainer.DockerSnapshotter, kubernetesSnapshotter portainer.KubernetesSnapshotter, shutdownCtx context.Context) (*Service, error) {
+func NewService(snapshotInterval string, dataStore portainer.DataStore, dockerSnapshotter portainer.DockerSnapshotter, kubernetesSnapshotter portainer.KubernetesSnapshotter, notificationService portainer.NotificationService, shutdownCtx context.Context) (*Service, error) {
 	snapshotFrequency, err := time.ParseDuration(snapshotInterval)
 	if err != nil {
 		return nil, err
@@ -32,7 +44,9 @@ func NewService(snapshotInterval string, dataStore portainer.DataStore, dockerSn
 		snapshotIntervalInSeconds: snapshotFrequency.Seconds(),
 		dockerSnapshotter:         dockerSnapshotter,
 		kubernetesSnapshotter:     kubernetesSnapshotter,
+		notificationService:       notificationService,
 		shutdownCtx:               shutdownCtx,
+		scheduler:                 newScheduler(),
 	}, nil
 }
 
@@ -82,24 +96,49 @@ func SupportDirectSnapshot(endpoint *portainer.Endpoint) bool {
 }
 
 // SnapshotEndpoint will create a snapshot of the endpoint based on the endpoint type.
-// If the snapshot is a success, it will be associated to the endpoint.
+// If the snapshot is a success, it will be associated to the endpoint and recorded in its snapshot history.
 func (service *Service) SnapshotEndpoint(endpoint *portainer.Endpoint) error {
-	switch endpoint.Type {
-	case portainer.AzureEnvironment:
+	return service.snapshotEndpoint(service.shutdownCtx, endpoint)
+}
+
+// snapshotEndpoint snapshots the endpoint within the specified context. A snapshot completing after
+// the context is done is dropped, it is neither associated to the endpoint nor recorded in its history.
+func (service *Service) snapshotEndpoint(ctx context.Context, endpoint *portainer.Endpoint) error {
+	if endpoint.Type == portainer.AzureEnvironment {
 		return nil
+	}
+
+	start := time.Now()
+
+	var err error
+	switch endpoint.Type {
 	case portainer.KubernetesLocalEnvironment, portainer.AgentOnKubernetesEnvironment, portainer.EdgeAgentOnKubernetesEnvironment:
-		return service.snapshotKubernetesEndpoint(endpoint)
+		err = service.snapshotKubernetesEndpoint(ctx, endpoint)
+	default:
+		err = service.snapshotDockerEndpoint(ctx, endpoint)
+	}
+
+	metrics.SnapshotDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.SnapshotFailures.Inc()
+		return err
 	}
 
-	return service.snapshotDockerEndpoint(endpoint)
+	service.recordHistory(endpoint)
+
+	return nil
 }
 
-func (service *Service) snapshotKubernetesEndpoint(endpoint *portainer.Endpoint) error {
-	snapshot, err := service.kubernetesSnapshotter.CreateSnapshot(endpoint)
+func (service *Service) snapshotKubernetesEndpoint(ctx context.Context, endpoint *portainer.Endpoint) error {
+	snapshot, err := service.kubernetesSnapshotter.CreateSnapshot(ctx, endpoint)
 	if err != nil {
 		return err
 	}
 
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
 	if snapshot != nil {
 		endpoint.Kubernetes.Snapshots = []portainer.KubernetesSnapshot{*snapshot}
 	}
@@ -107,12 +146,16 @@ func (service *Service) snapshotKubernetesEndpoint(endpoint *portainer.Endpoint)
 	return nil
 }
 
-func (service *Service) snapshotDockerEndpoint(endpoint *portainer.Endpoint) error {
-	snapshot, err := service.dockerSnapshotter.CreateSnapshot(endpoint)
+func (service *Service) snapshotDockerEndpoint(ctx context.Context, endpoint *portainer.Endpoint) error {
+	snapshot, err := service.dockerSnapshotter.CreateSnapshot(ctx, endpoint)
 	if err != nil {
 		return err
 	}
 
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
 	if snapshot != nil {
 		endpoint.Snapshots = []portainer.DockerSnapshot{*snapshot}
 	}
@@ -121,26 +164,26 @@ func (service *Service) snapshotDockerEndpoint(endpoint *portainer.Endpoint) err
 }
 
 func (service *Service) startSnapshotLoop() error {
-	ticker := time.NewTicker(time.Duration(service.snapshotIntervalInSeconds) * time.Second)
+	ticker := time.NewTicker(scheduleTick)
 	go func() {
-		err := service.snapshotEndpoints()
+		err := service.scheduleSnapshots(time.Now())
 		if err != nil {
-			log.Printf("[ERROR] [internal,snapshot] [message: background schedule error (endpoint snapshot).] [error: %s]", err)
+			logger.WithError(err).Error("background schedule error (endpoint snapshot)")
 		}
 
 		for {
 			select {
 			case <-ticker.C:
-				err := service.snapshotEndpoints()
+				err := service.scheduleSnapshots(time.Now())
 				if err != nil {
-					log.Printf("[ERROR] [internal,snapshot] [message: background schedule error (endpoint snapshot).] [error: %s]", err)
+					logger.WithError(err).Error("background schedule error (endpoint snapshot)")
 				}
 			case <-service.shutdownCtx.Done():
-				log.Println("[DEBUG] [internal,snapshot] [message: shutting down snapshotting]")
+				logger.Debug("shutting down snapshotting")
 				ticker.Stop()
 				return
 			case <-service.refreshSignal:
-				log.Println("[DEBUG] [internal,snapshot] [message: shutting down snapshotting]")
+				logger.Debug("shutting down snapshotting")
 				ticker.Stop()
 				return
 			}
@@ -150,40 +193,152 @@ func (service *Service) startSnapshotLoop() error {
 	return nil
 }
 
-func (service *Service) snapshotEndpoints() error {
+// snapshotOptions returns the number of concurrent snapshots and the snapshot timeout defined in the settings
+func snapshotOptions(settings *portainer.Settings) (int, time.Duration, error) {
+	concurrency := settings.SnapshotConcurrency
+	if concurrency <= 0 {
+		concurrency = portainer.DefaultSnapshotConcurrency
+	}
+
+	timeout, err := parseDurationOrDefault(settings.SnapshotTimeout, portainer.DefaultSnapshotTimeout)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return concurrency, timeout, nil
+}
+
+// endpointInterval returns the snapshot interval of the endpoint, or the global snapshot interval when not set
+func (service *Service) endpointInterval(endpoint *portainer.Endpoint) time.Duration {
+	interval := time.Duration(service.snapshotIntervalInSeconds) * time.Second
+
+	if endpoint.SnapshotInterval != "" {
+		endpointInterval, err := time.ParseDuration(endpoint.SnapshotInterval)
+		if err == nil && endpointInterval > 0 {
+			interval = endpointInterval
+		}
+	}
+
+	return interval
+}
+
+// scheduleSnapshots starts the snapshot of every endpoint due at the specified time
+// and compacts the snapshot history once per global snapshot interval.
+func (service *Service) scheduleSnapshots(now time.Time) error {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	concurrency, timeout, err := snapshotOptions(settings)
+	if err != nil {
+		return err
+	}
+
+	failureThreshold := FailureThreshold(settings)
+
+	if cap(service.workers) != concurrency {
+		service.workers = make(chan struct{}, concurrency)
+	}
+
 	endpoints, err := service.dataStore.Endpoint().Endpoints()
 	if err != nil {
 		return err
 	}
 
+	known := make(map[portainer.EndpointID]bool)
 	for _, endpoint := range endpoints {
+		known[endpoint.ID] = true
+
 		if !SupportDirectSnapshot(&endpoint) {
 			continue
 		}
 
-		snapshotError := service.SnapshotEndpoint(&endpoint)
-
-		latestEndpointReference, err := service.dataStore.Endpoint().Endpoint(endpoint.ID)
-		if latestEndpointReference == nil {
-			log.Printf("background schedule error (endpoint snapshot). Endpoint not found inside the database anymore (endpoint=%s, URL=%s) (err=%s)\n", endpoint.Name, endpoint.URL, err)
+		interval := service.endpointInterval(&endpoint)
+		if !service.scheduler.begin(endpoint.ID, interval, now) {
 			continue
 		}
 
-		latestEndpointReference.Status = portainer.EndpointStatusUp
-		if snapshotError != nil {
-			log.Printf("background schedule error (endpoint snapshot). Unable to create snapshot (endpoint=%s, URL=%s) (err=%s)\n", endpoint.Name, endpoint.URL, snapshotError)
-			latestEndpointReference.Status = portainer.EndpointStatusDown
-		}
+		go service.runScheduledSnapshot(endpoint, interval, timeout, failureThreshold, service.workers)
+	}
+
+	service.scheduler.prune(known)
 
-		latestEndpointReference.Snapshots = endpoint.Snapshots
-		latestEndpointReference.Kubernetes.Snapshots = endpoint.Kubernetes.Snapshots
+	if now.Sub(service.lastCompaction).Seconds() >= service.snapshotIntervalInSeconds {
+		service.lastCompaction = now
 
-		err = service.dataStore.Endpoint().UpdateEndpoint(latestEndpointReference.ID, latestEndpointReference)
+		err = service.compactHistory(endpoints, now)
 		if err != nil {
-			log.Printf("background schedule error (endpoint snapshot). Unable to update endpoint (endpoint=%s, URL=%s) (err=%s)\n", endpoint.Name, endpoint.URL, err)
-			continue
+			logger.WithError(err).Error("unable to compact snapshot history")
 		}
 	}
 
 	return nil
 }
+
+// runScheduledSnapshot snapshots an endpoint once a worker is available and persists the result
+func (service *Service) runScheduledSnapshot(endpoint portainer.Endpoint, interval, timeout time.Duration, failureThreshold int, workers chan struct{}) {
+	workers <- struct{}{}
+	defer func() { <-workers }()
+
+	start := time.Now()
+	snapshotError := service.snapshotEndpointWithTimeout(&endpoint, timeout)
+	service.scheduler.complete(endpoint.ID, interval, snapshotError == nil, start)
+
+	endpointLogger := logger.WithFields(logrus.Fields{
+		logging.FieldEndpointID: endpoint.ID,
+		"endpoint":              endpoint.Name,
+		"url":                   endpoint.URL,
+	})
+
+	latestEndpointReference, err := service.dataStore.Endpoint().Endpoint(endpoint.ID)
+	if latestEndpointReference == nil {
+		endpointLogger.WithError(err).Error("background schedule error (endpoint snapshot). Endpoint not found inside the database anymore")
+		return
+	}
+
+	if snapshotError != nil {
+		endpointLogger.WithError(snapshotError).Error("background schedule error (endpoint snapshot). Unable to create snapshot")
+	}
+	previousStatus := latestEndpointReference.Status
+	UpdateEndpointHealth(latestEndpointReference, snapshotError, failureThreshold, time.Now())
+	service.notifyStatusChange(previousStatus, latestEndpointReference)
+
+	latestEndpointReference.Snapshots = endpoint.Snapshots
+	latestEndpointReference.Kubernetes.Snapshots = endpoint.Kubernetes.Snapshots
+
+	err = service.dataStore.Endpoint().UpdateEndpoint(latestEndpointReference.ID, latestEndpointReference)
+	if err != nil {
+		endpointLogger.WithError(err).Error("background schedule error (endpoint snapshot). Unable to update endpoint")
+	}
+}
+
+// notifyStatusChange sends an endpoint.down or endpoint.up event when the status of the endpoint changed
+func (service *Service) notifyStatusChange(previousStatus portainer.EndpointStatus, endpoint *portainer.Endpoint) {
+	if endpoint.Status == previousStatus {
+		return
+	}
+
+	switch endpoint.Status {
+	case portainer.EndpointStatusDown:
+		event := notification.NewEndpointEvent(portainer.NotificationEventEndpointDown, endpoint, fmt.Sprintf("Endpoint %s is down", endpoint.Name))
+		event.Details = map[string]string{"error": endpoint.Health.LastError}
+		service.notificationService.Notify(event)
+	case portainer.EndpointStatusUp:
+		service.notificationService.Notify(notification.NewEndpointEvent(portainer.NotificationEventEndpointUp, endpoint, fmt.Sprintf("Endpoint %s is up", endpoint.Name)))
+	}
+}
+
+// snapshotEndpointWithTimeout snapshots the endpoint and cancels the requests to the endpoint after the specified timeout,
+// so that a hung endpoint keeps its worker busy no longer than the timeout.
+func (service *Service) snapshotEndpointWithTimeout(endpoint *portainer.Endpoint, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(service.shutdownCtx, timeout)
+	defer cancel()
+
+	err := service.snapshotEndpoint(ctx, endpoint)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("snapshot did not complete within %s: %w", timeout, err)
+	}
+
+	return err
+}
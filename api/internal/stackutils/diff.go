@@ -0,0 +1,185 @@
+package stackutils
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContextLines is the number of unchanged lines displayed around each change
+	diffContextLines = 3
+	// diffMaxCells bounds the size of the longest common subsequence table, larger changes
+	// are reported as the removal of the previous lines followed by the addition of the current ones
+	diffMaxCells = 1 << 20
+)
+
+type diffOperation struct {
+	kind byte
+	line string
+}
+
+// Diff returns the unified diff between the previous and current content of a stack file.
+// An empty string is returned when both contents are identical.
+func Diff(previous, current string) string {
+	operations := diffLines(splitLines(previous), splitLines(current))
+
+	var builder strings.Builder
+	for start := 0; start < len(operations); {
+		if operations[start].kind == ' ' {
+			start++
+			continue
+		}
+
+		hunkStart := start - diffContextLines
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+
+		// extend the hunk while the next change is close enough to share its context
+		hunkEnd := start
+		for index := start; index < len(operations) && index <= hunkEnd+2*diffContextLines; index++ {
+			if operations[index].kind != ' ' {
+				hunkEnd = index
+			}
+		}
+		hunkEnd += diffContextLines + 1
+		if hunkEnd > len(operations) {
+			hunkEnd = len(operations)
+		}
+
+		writeHunk(&builder, operations, hunkStart, hunkEnd)
+		start = hunkEnd
+	}
+
+	return builder.String()
+}
+
+func writeHunk(builder *strings.Builder, operations []diffOperation, start, end int) {
+	previousLine, currentLine := 1, 1
+	for _, operation := range operations[:start] {
+		if operation.kind != '+' {
+			previousLine++
+		}
+		if operation.kind != '-' {
+			currentLine++
+		}
+	}
+
+	previousCount, currentCount := 0, 0
+	for _, operation := range operations[start:end] {
+		if operation.kind != '+' {
+			previousCount++
+		}
+		if operation.kind != '-' {
+			currentCount++
+		}
+	}
+
+	if previousCount == 0 {
+		previousLine--
+	}
+	if currentCount == 0 {
+		currentLine--
+	}
+
+	fmt.Fprintf(builder, "@@ -%d,%d +%d,%d @@\n", previousLine, previousCount, currentLine, currentCount)
+	for _, operation := range operations[start:end] {
+		builder.WriteByte(operation.kind)
+		builder.WriteString(operation.line)
+		builder.WriteByte('\n')
+	}
+}
+
+// diffLines computes the edit script between a and b. The lines shared at the start and
+// at the end of both contents are matched first and the remaining lines are compared
+// using their longest common subsequence.
+func diffLines(a, b []string) []diffOperation {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	operations := make([]diffOperation, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		operations = append(operations, diffOperation{' ', line})
+	}
+
+	operations = append(operations, diffChangedLines(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+
+	for _, line := range a[len(a)-suffix:] {
+		operations = append(operations, diffOperation{' ', line})
+	}
+
+	return operations
+}
+
+// diffChangedLines computes the shortest edit script between a and b using their longest common subsequence
+func diffChangedLines(a, b []string) []diffOperation {
+	operations := make([]diffOperation, 0, len(a)+len(b))
+
+	if (len(a)+1)*(len(b)+1) > diffMaxCells {
+		for _, line := range a {
+			operations = append(operations, diffOperation{'-', line})
+		}
+		for _, line := range b {
+			operations = append(operations, diffOperation{'+', line})
+		}
+		return operations
+	}
+
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			operations = append(operations, diffOperation{' ', a[i]})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			operations = append(operations, diffOperation{'-', a[i]})
+			i++
+		default:
+			operations = append(operations, diffOperation{'+', b[j]})
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		operations = append(operations, diffOperation{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		operations = append(operations, diffOperation{'+', b[j]})
+	}
+
+	return operations
+}
+
+func splitLines(content string) []string {
+	content = strings.TrimSuffix(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if content == "" {
+		return []string{}
+	}
+
+	return strings.Split(content, "\n")
+}
@@ -0,0 +1,63 @@
+package stackutils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Diff(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous string
+		current  string
+		expected string
+	}{
+		{
+			name:     "identical contents",
+			previous: "version: '3'\nservices:\n",
+			current:  "version: '3'\nservices:\n",
+			expected: "",
+		},
+		{
+			name:     "changed line",
+			previous: "services:\n  web:\n    image: nginx:1.19\n",
+			current:  "services:\n  web:\n    image: nginx:1.20\n",
+			expected: "@@ -1,3 +1,3 @@\n services:\n   web:\n-    image: nginx:1.19\n+    image: nginx:1.20\n",
+		},
+		{
+			name:     "initial revision",
+			previous: "",
+			current:  "a\nb\n",
+			expected: "@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:     "distant changes produce separate hunks",
+			previous: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			current:  "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			expected: "@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Diff(tt.previous, tt.current))
+		})
+	}
+}
+
+func Test_Diff_LargeChange(t *testing.T) {
+	previous, current := make([]string, 0, 2000), make([]string, 0, 2000)
+	for i := 0; i < 2000; i++ {
+		previous = append(previous, fmt.Sprintf("previous %d", i))
+		current = append(current, fmt.Sprintf("current %d", i))
+	}
+
+	diff := Diff("header\n"+strings.Join(previous, "\n"), "header\n"+strings.Join(current, "\n"))
+
+	assert.True(t, strings.HasPrefix(diff, "@@ -1,2001 +1,2001 @@\n header\n-previous 0\n"))
+	assert.Equal(t, 2000, strings.Count(diff, "\n-"))
+	assert.Equal(t, 2000, strings.Count(diff, "\n+"))
+}
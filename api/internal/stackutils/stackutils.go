@@ -10,3 +10,27 @@ import (
 func ResourceControlID(endpointID portainer.EndpointID, name string) string {
 	return fmt.Sprintf("%d_%s", endpointID, name)
 }
+
+// NewStackRevision returns a revision recording the stack file content, environment and git commit of the stack.
+// rollbackOf is the version of the revision being redeployed, 0 for a regular deployment.
+func NewStackRevision(stack *portainer.Stack, stackFileContent []byte, rollbackOf int) *portainer.StackRevision {
+	deployedBy := stack.UpdatedBy
+	if deployedBy == "" {
+		deployedBy = stack.CreatedBy
+	}
+
+	revision := &portainer.StackRevision{
+		StackID:          stack.ID,
+		StackFileContent: string(stackFileContent),
+		Env:              append([]portainer.Pair{}, stack.Env...),
+		RollbackOf:       rollbackOf,
+		DeployedBy:       deployedBy,
+	}
+
+	if stack.GitConfig != nil {
+		revision.GitReferenceName = stack.GitConfig.ReferenceName
+		revision.GitCommitID = stack.GitConfig.ConfigHash
+	}
+
+	return revision
+}
@@ -0,0 +1,493 @@
+package stackvalidation
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/cli/cli/compose/loader"
+	"github.com/docker/cli/cli/compose/schema"
+	"github.com/docker/cli/cli/compose/types"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/vulnerability"
+)
+
+type (
+	// Options describes the constraints a stack file is validated against
+	Options struct {
+		// Maximum supported version of the versioned compose syntax
+		MaxVersion string
+		// ComposeSpec is true when unversioned compose specification files are supported
+		ComposeSpec bool
+		// Swarm is true when the stack file is deployed as a Swarm stack
+		Swarm bool
+		// Endpoint security settings, only enforced when not nil
+		SecuritySettings *portainer.EndpointSecuritySettings
+		// Minimum severity of the known vulnerabilities preventing an image from being deployed,
+		// only enforced when not empty
+		VulnerabilityGateSeverity portainer.VulnerabilitySeverity
+		// Vulnerability reports of the scanned images, used by the vulnerability gate
+		VulnerabilityReports []portainer.ImageVulnerabilityReport
+		// Resources and services currently available on the endpoint,
+		// resources are not checked and no service changes are reported when nil
+		Endpoint *EndpointState
+	}
+
+	// EndpointState describes the resources available on an endpoint and the services already running for the stack
+	EndpointState struct {
+		// Image references available on the endpoint, such as nginx:latest
+		Images map[string]bool
+		// Network names available on the endpoint
+		Networks map[string]bool
+		// Secret names available on the endpoint, nil when secrets are not supported
+		Secrets map[string]bool
+		// Config names available on the endpoint, nil when configs are not supported
+		Configs map[string]bool
+		// Image of each service of the stack currently running on the endpoint, indexed by service name
+		Services map[string]string
+	}
+
+	// Report is the result of the validation of a stack file
+	Report struct {
+		// Valid is true when the stack file can be deployed
+		Valid bool `json:"Valid" example:"true"`
+		// Analyzed is false when the services of the stack file could not be analyzed, in which case
+		// the stack file is only validated by the stack manager on deployment
+		Analyzed bool `json:"Analyzed" example:"true"`
+		// Problems preventing the deployment of the stack file
+		Errors []string `json:"Errors"`
+		// Potential problems that do not prevent the deployment of the stack file
+		Warnings []string `json:"Warnings"`
+		// Services that would be created, changed or removed by the deployment
+		Services ServiceChanges `json:"Services"`
+	}
+
+	// ServiceChanges lists the services affected by a deployment, compared with what is running on the endpoint
+	ServiceChanges struct {
+		Created   []string `json:"Created" example:"web"`
+		Changed   []string `json:"Changed" example:"db"`
+		Removed   []string `json:"Removed" example:"cache"`
+		Unchanged []string `json:"Unchanged" example:"proxy"`
+	}
+)
+
+// Validate parses the stack file, resolves its variables from env and checks it against the options.
+// Every problem found is reported instead of stopping at the first one.
+func Validate(stackFileContent []byte, env []portainer.Pair, options Options) *Report {
+	report := &Report{
+		Errors:   make([]string, 0),
+		Warnings: make([]string, 0),
+		Services: ServiceChanges{
+			Created:   make([]string, 0),
+			Changed:   make([]string, 0),
+			Removed:   make([]string, 0),
+			Unchanged: make([]string, 0),
+		},
+	}
+
+	config, err := validateAndLoad(stackFileContent, env, options, report)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+
+	if config == nil {
+		// the deployment restrictions cannot be verified without analyzing the services
+		if options.SecuritySettings != nil || options.VulnerabilityGateSeverity != "" {
+			report.Errors = append(report.Errors, "the stack file could not be analyzed, the endpoint security settings and the vulnerability gate cannot be verified")
+		}
+
+		report.Valid = len(report.Errors) == 0
+		return report
+	}
+
+	report.Analyzed = true
+
+	for _, violation := range SecurityViolations(config, options.SecuritySettings) {
+		report.Errors = append(report.Errors, violation.Error())
+	}
+
+	if options.VulnerabilityGateSeverity != "" {
+		for _, violation := range VulnerabilityViolations(config, options.VulnerabilityReports, options.VulnerabilityGateSeverity) {
+			report.Errors = append(report.Errors, violation.Error())
+		}
+
+		for _, image := range UnscannedImages(config, options.VulnerabilityReports) {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("image %s has not been scanned for vulnerabilities yet, it will be scanned on deployment", image))
+		}
+	}
+
+	if options.Endpoint != nil {
+		checkResources(config, options, report)
+		compareServices(config, options.Endpoint, report)
+	}
+
+	report.Valid = len(report.Errors) == 0
+	return report
+}
+
+// SecurityViolations returns the features used by the stack services that are disabled for regular users by the
+// endpoint security settings. No violation is returned when the security settings are nil.
+func SecurityViolations(config *types.Config, securitySettings *portainer.EndpointSecuritySettings) []error {
+	violations := make([]error, 0)
+	if securitySettings == nil {
+		return violations
+	}
+
+	for _, service := range config.Services {
+		if !securitySettings.AllowBindMountsForRegularUsers {
+			for _, volume := range service.Volumes {
+				if volume.Type == "bind" {
+					violations = append(violations, errors.New("bind-mount disabled for non administrator users"))
+					break
+				}
+			}
+		}
+
+		if !securitySettings.AllowPrivilegedModeForRegularUsers && service.Privileged {
+			violations = append(violations, errors.New("privileged mode disabled for non administrator users"))
+		}
+
+		if !securitySettings.AllowHostNamespaceForRegularUsers && service.Pid == "host" {
+			violations = append(violations, errors.New("pid host disabled for non administrator users"))
+		}
+
+		if !securitySettings.AllowDeviceMappingForRegularUsers && len(service.Devices) > 0 {
+			violations = append(violations, errors.New("device mapping disabled for non administrator users"))
+		}
+
+		if !securitySettings.AllowSysctlSettingForRegularUsers && len(service.Sysctls) > 0 {
+			violations = append(violations, errors.New("sysctl setting disabled for non administrator users"))
+		}
+
+		if !securitySettings.AllowContainerCapabilitiesForRegularUsers && (len(service.CapAdd) > 0 || len(service.CapDrop) > 0) {
+			violations = append(violations, errors.New("container capabilities disabled for non administrator users"))
+		}
+	}
+
+	return violations
+}
+
+// VulnerabilityViolations returns the stack services using an image with known vulnerabilities whose severity is
+// equal to or higher than the specified severity. Images that were not scanned yet are not reported.
+func VulnerabilityViolations(config *types.Config, reports []portainer.ImageVulnerabilityReport, severity portainer.VulnerabilitySeverity) []error {
+	violations := make([]error, 0)
+
+	for _, service := range config.Services {
+		if service.Image == "" {
+			continue
+		}
+
+		for idx := range reports {
+			if !reportMatchesImage(&reports[idx], service.Image) {
+				continue
+			}
+
+			count := 0
+			for reportSeverity, severityCount := range reports[idx].Summary {
+				if vulnerability.AtLeast(reportSeverity, severity) {
+					count += severityCount
+				}
+			}
+
+			if count > 0 {
+				violations = append(violations, fmt.Errorf("service %s uses image %s with %d known vulnerabilities of severity %s or higher", service.Name, service.Image, count, severity))
+				break
+			}
+		}
+	}
+
+	return violations
+}
+
+// UnscannedImages returns the images used by the stack services which do not match any vulnerability report
+func UnscannedImages(config *types.Config, reports []portainer.ImageVulnerabilityReport) []string {
+	images := make([]string, 0)
+	found := make(map[string]bool)
+
+	for _, service := range config.Services {
+		if service.Image == "" || found[service.Image] {
+			continue
+		}
+		found[service.Image] = true
+
+		scanned := false
+		for idx := range reports {
+			if reportMatchesImage(&reports[idx], service.Image) {
+				scanned = true
+				break
+			}
+		}
+
+		if !scanned {
+			images = append(images, service.Image)
+		}
+	}
+
+	return images
+}
+
+// reportMatchesImage returns true when the image reference of a stack service, pinned by digest or not,
+// matches one of the references the scanned image is known as
+func reportMatchesImage(report *portainer.ImageVulnerabilityReport, image string) bool {
+	if strings.Contains(image, "@") {
+		return strings.HasSuffix(image, "@"+report.Digest)
+	}
+
+	image = normalizeImage(image)
+	for _, tag := range report.Tags {
+		if normalizeImage(tag) == image {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateAndLoad(stackFileContent []byte, env []portainer.Pair, options Options, report *Report) (*types.Config, error) {
+	configYAML, err := loader.ParseYAML(stackFileContent)
+	if err != nil {
+		return nil, err
+	}
+
+	_, versioned := configYAML["version"]
+	version := schema.Version(configYAML)
+
+	skipValidation := true
+	if options.Swarm || !options.ComposeSpec {
+		if !versioned {
+			return nil, errors.New("the stack file version is missing, unversioned compose specification files are not supported on this endpoint")
+		}
+
+		maxVersion := options.MaxVersion
+		if options.Swarm || maxVersion == "" {
+			maxVersion = portainer.ComposeSyntaxMaxVersion
+		}
+
+		if compareVersions(version, maxVersion) > 0 {
+			return nil, fmt.Errorf("the stack file version %s is not supported, the maximum supported version is %s", version, maxVersion)
+		}
+
+		if options.Swarm && !strings.HasPrefix(version, "3.") {
+			return nil, fmt.Errorf("the stack file version %s is not supported by Swarm stacks, version 3 is required", version)
+		}
+
+		skipValidation = !strings.HasPrefix(version, "3.")
+	}
+
+	environment := make(map[string]string)
+	for _, pair := range env {
+		environment[pair.Name] = pair.Value
+	}
+
+	missingVariables := make(map[string]bool)
+	config, err := loader.Load(types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{{Config: configYAML}},
+		Environment: environment,
+	}, func(loaderOptions *loader.Options) {
+		loaderOptions.SkipValidation = skipValidation
+		loaderOptions.Interpolate.LookupValue = func(key string) (string, bool) {
+			value, ok := environment[key]
+			if !ok {
+				missingVariables[key] = true
+			}
+			return value, ok
+		}
+	})
+	if err != nil && skipValidation {
+		// the loader only fully supports the version 3 syntax, other files are checked by the stack manager on deployment
+		report.Warnings = append(report.Warnings, fmt.Sprintf("unable to analyze the stack file, it will be validated on deployment: %s", err))
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, key := range sortedKeys(missingVariables) {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("the %s variable is not set, defaulting to a blank string", key))
+	}
+
+	return config, nil
+}
+
+func checkResources(config *types.Config, options Options, report *Report) {
+	state := options.Endpoint
+
+	for _, service := range config.Services {
+		if service.Image == "" {
+			if service.Build.Context == "" {
+				report.Errors = append(report.Errors, fmt.Sprintf("service %s has neither an image nor a build context", service.Name))
+			}
+		} else if !state.Images[normalizeImage(service.Image)] {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("image %s used by service %s is not available on the endpoint and will be pulled", service.Image, service.Name))
+		}
+
+		for networkName := range service.Networks {
+			if _, ok := config.Networks[networkName]; !ok && networkName != "default" {
+				report.Errors = append(report.Errors, fmt.Sprintf("service %s refers to undefined network %s", service.Name, networkName))
+			}
+		}
+
+		for _, secret := range service.Secrets {
+			if _, ok := config.Secrets[secret.Source]; !ok {
+				report.Errors = append(report.Errors, fmt.Sprintf("service %s refers to undefined secret %s", service.Name, secret.Source))
+			}
+		}
+
+		for _, serviceConfig := range service.Configs {
+			if _, ok := config.Configs[serviceConfig.Source]; !ok {
+				report.Errors = append(report.Errors, fmt.Sprintf("service %s refers to undefined config %s", service.Name, serviceConfig.Source))
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(config.Networks) {
+		network := config.Networks[key]
+		if network.External.External && !state.Networks[externalName(key, network.Name, network.External.Name)] {
+			report.Errors = append(report.Errors, fmt.Sprintf("external network %s not found on the endpoint", externalName(key, network.Name, network.External.Name)))
+		}
+	}
+
+	for _, key := range sortedKeys(config.Secrets) {
+		secret := config.Secrets[key]
+		if !secret.External.External {
+			continue
+		}
+
+		name := externalName(key, secret.Name, secret.External.Name)
+		if state.Secrets == nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("secret %s is external, external secrets are only available on Swarm endpoints", name))
+		} else if !state.Secrets[name] {
+			report.Errors = append(report.Errors, fmt.Sprintf("external secret %s not found on the endpoint", name))
+		}
+	}
+
+	for _, key := range sortedKeys(config.Configs) {
+		configObj := config.Configs[key]
+		if !configObj.External.External {
+			continue
+		}
+
+		name := externalName(key, configObj.Name, configObj.External.Name)
+		if state.Configs == nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("config %s is external, external configs are only available on Swarm endpoints", name))
+		} else if !state.Configs[name] {
+			report.Errors = append(report.Errors, fmt.Sprintf("external config %s not found on the endpoint", name))
+		}
+	}
+}
+
+func compareServices(config *types.Config, state *EndpointState, report *Report) {
+	services := make(map[string]bool)
+
+	for _, service := range config.Services {
+		services[service.Name] = true
+
+		runningImage, ok := state.Services[service.Name]
+		switch {
+		case !ok:
+			report.Services.Created = append(report.Services.Created, service.Name)
+		case !sameImage(service.Image, runningImage):
+			report.Services.Changed = append(report.Services.Changed, service.Name)
+		default:
+			report.Services.Unchanged = append(report.Services.Unchanged, service.Name)
+		}
+	}
+
+	for name := range state.Services {
+		if !services[name] {
+			report.Services.Removed = append(report.Services.Removed, name)
+		}
+	}
+
+	sort.Strings(report.Services.Created)
+	sort.Strings(report.Services.Changed)
+	sort.Strings(report.Services.Removed)
+	sort.Strings(report.Services.Unchanged)
+}
+
+// compareVersions compares two compose file versions such as 2.4 or 3.8
+func compareVersions(a, b string) int {
+	partsA := strings.Split(a, ".")
+	partsB := strings.Split(b, ".")
+
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var numberA, numberB int
+		if i < len(partsA) {
+			numberA, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			numberB, _ = strconv.Atoi(partsB[i])
+		}
+
+		if numberA != numberB {
+			if numberA < numberB {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// normalizeImage adds the latest tag to image references without tag nor digest
+func normalizeImage(image string) string {
+	if strings.Contains(image, "@") {
+		return image
+	}
+
+	name := image[strings.LastIndex(image, "/")+1:]
+	if !strings.Contains(name, ":") {
+		return image + ":latest"
+	}
+
+	return image
+}
+
+// sameImage compares the image of a stack service with the image of a running service.
+// The digest pinned by Swarm on running services is ignored when the stack service does not specify one.
+func sameImage(image, runningImage string) bool {
+	if !strings.Contains(image, "@") {
+		runningImage = strings.SplitN(runningImage, "@", 2)[0]
+	}
+
+	return normalizeImage(image) == normalizeImage(runningImage)
+}
+
+func externalName(key, name, externalName string) string {
+	if externalName != "" {
+		return externalName
+	}
+	if name != "" {
+		return name
+	}
+	return key
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := make([]string, 0)
+
+	switch values := m.(type) {
+	case map[string]bool:
+		for key := range values {
+			keys = append(keys, key)
+		}
+	case map[string]types.NetworkConfig:
+		for key := range values {
+			keys = append(keys, key)
+		}
+	case map[string]types.SecretConfig:
+		for key := range values {
+			keys = append(keys, key)
+		}
+	case map[string]types.ConfigObjConfig:
+		for key := range values {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
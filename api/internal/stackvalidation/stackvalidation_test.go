@@ -0,0 +1,252 @@
+package stackvalidation
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Validate_Version(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		maxVersion  string
+		composeSpec bool
+		swarm       bool
+		valid       bool
+	}{
+		{
+			name:       "supported version",
+			content:    "version: '3.8'\nservices:\n  web:\n    image: nginx\n",
+			maxVersion: "3.9",
+			valid:      true,
+		},
+		{
+			name:       "version above the maximum",
+			content:    "version: '3.9'\nservices:\n  web:\n    image: nginx\n",
+			maxVersion: "3.8",
+			valid:      false,
+		},
+		{
+			name:       "missing version without compose specification support",
+			content:    "services:\n  web:\n    image: nginx\n",
+			maxVersion: "3.9",
+			valid:      false,
+		},
+		{
+			name:        "missing version with compose specification support",
+			content:     "services:\n  web:\n    image: nginx\n",
+			maxVersion:  "3.9",
+			composeSpec: true,
+			valid:       true,
+		},
+		{
+			name:       "version 2 for a swarm stack",
+			content:    "version: '2.4'\nservices:\n  web:\n    image: nginx\n",
+			maxVersion: "3.9",
+			swarm:      true,
+			valid:      false,
+		},
+		{
+			name:       "schema violation",
+			content:    "version: '3.8'\nservices:\n  web:\n    image: nginx\n    unknown_key: true\n",
+			maxVersion: "3.9",
+			valid:      false,
+		},
+		{
+			name:       "invalid yaml",
+			content:    "version: '3.8'\nservices: [\n",
+			maxVersion: "3.9",
+			valid:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Validate([]byte(tt.content), nil, Options{MaxVersion: tt.maxVersion, ComposeSpec: tt.composeSpec, Swarm: tt.swarm})
+			assert.Equal(t, tt.valid, report.Valid, report.Errors)
+		})
+	}
+}
+
+func Test_Validate_ReportsMissingVariables(t *testing.T) {
+	content := "version: '3.8'\nservices:\n  web:\n    image: nginx:${TAG}\n    environment:\n      - DB=${DB_HOST}\n"
+	env := []portainer.Pair{{Name: "TAG", Value: "1.20"}}
+
+	report := Validate([]byte(content), env, Options{MaxVersion: "3.9", Endpoint: &EndpointState{Images: map[string]bool{"nginx:1.20": true}}})
+
+	assert.True(t, report.Valid)
+	assert.True(t, report.Analyzed)
+	assert.Equal(t, []string{"the DB_HOST variable is not set, defaulting to a blank string"}, report.Warnings)
+}
+
+func Test_Validate_ReportsAllSecurityViolations(t *testing.T) {
+	content := "version: '3.8'\nservices:\n  web:\n    image: nginx\n    privileged: true\n    volumes:\n      - /var/run/docker.sock:/var/run/docker.sock\n"
+
+	report := Validate([]byte(content), nil, Options{MaxVersion: "3.9", SecuritySettings: &portainer.EndpointSecuritySettings{}})
+
+	assert.False(t, report.Valid)
+	assert.ElementsMatch(t, []string{"bind-mount disabled for non administrator users", "privileged mode disabled for non administrator users"}, report.Errors)
+}
+
+func Test_Validate_VulnerabilityGate(t *testing.T) {
+	content := `version: '3.8'
+services:
+  web:
+    image: nginx
+  db:
+    image: postgres@sha256:bbb
+  cache:
+    image: redis:6
+  app:
+    image: myapp:dev
+`
+
+	reports := []portainer.ImageVulnerabilityReport{
+		{
+			Digest:  "sha256:aaa",
+			Tags:    []string{"nginx:latest"},
+			Summary: map[portainer.VulnerabilitySeverity]int{portainer.VulnerabilitySeverityCritical: 1, portainer.VulnerabilitySeverityHigh: 2},
+		},
+		{
+			Digest:  "sha256:bbb",
+			Tags:    []string{"postgres:13"},
+			Summary: map[portainer.VulnerabilitySeverity]int{portainer.VulnerabilitySeverityHigh: 1},
+		},
+		{
+			Digest:  "sha256:ccc",
+			Tags:    []string{"redis:6"},
+			Summary: map[portainer.VulnerabilitySeverity]int{portainer.VulnerabilitySeverityMedium: 4},
+		},
+	}
+
+	unscannedWarning := "image myapp:dev has not been scanned for vulnerabilities yet, it will be scanned on deployment"
+
+	tests := []struct {
+		name         string
+		severity     portainer.VulnerabilitySeverity
+		want         []string
+		wantWarnings []string
+	}{
+		{
+			name:         "gate disabled",
+			want:         []string{},
+			wantWarnings: []string{},
+		},
+		{
+			name:         "critical gate",
+			severity:     portainer.VulnerabilitySeverityCritical,
+			want:         []string{"service web uses image nginx with 1 known vulnerabilities of severity CRITICAL or higher"},
+			wantWarnings: []string{unscannedWarning},
+		},
+		{
+			name:     "high gate",
+			severity: portainer.VulnerabilitySeverityHigh,
+			want: []string{
+				"service web uses image nginx with 3 known vulnerabilities of severity HIGH or higher",
+				"service db uses image postgres@sha256:bbb with 1 known vulnerabilities of severity HIGH or higher",
+			},
+			wantWarnings: []string{unscannedWarning},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Validate([]byte(content), nil, Options{MaxVersion: "3.9", VulnerabilityGateSeverity: tt.severity, VulnerabilityReports: reports})
+
+			assert.Equal(t, len(tt.want) == 0, report.Valid)
+			assert.ElementsMatch(t, tt.want, report.Errors)
+			assert.Equal(t, tt.wantWarnings, report.Warnings)
+		})
+	}
+}
+
+func Test_Validate_ChecksEndpointResources(t *testing.T) {
+	content := `version: '3.8'
+services:
+  web:
+    image: nginx
+    networks:
+      - front
+      - missing
+    secrets:
+      - api_key
+networks:
+  front:
+    external: true
+secrets:
+  api_key:
+    external: true
+`
+	state := &EndpointState{
+		Images:   map[string]bool{},
+		Networks: map[string]bool{"front": true},
+		Secrets:  map[string]bool{},
+		Configs:  map[string]bool{},
+	}
+
+	report := Validate([]byte(content), nil, Options{MaxVersion: "3.9", Swarm: true, Endpoint: state})
+
+	assert.False(t, report.Valid)
+	assert.ElementsMatch(t, []string{"service web refers to undefined network missing", "external secret api_key not found on the endpoint"}, report.Errors)
+	assert.Equal(t, []string{"image nginx used by service web is not available on the endpoint and will be pulled"}, report.Warnings)
+}
+
+func Test_Validate_ReportsServiceChanges(t *testing.T) {
+	content := "version: '3.8'\nservices:\n  web:\n    image: nginx:1.20\n  db:\n    image: postgres\n  api:\n    image: api:2\n"
+	state := &EndpointState{
+		Images: map[string]bool{"nginx:1.20": true, "postgres:latest": true, "api:2": true},
+		Services: map[string]string{
+			"web":   "nginx:1.20@sha256:0123456789abcdef",
+			"db":    "postgres:12",
+			"cache": "redis",
+		},
+	}
+
+	report := Validate([]byte(content), nil, Options{MaxVersion: "3.9", Endpoint: state})
+
+	assert.True(t, report.Valid)
+	assert.Equal(t, ServiceChanges{
+		Created:   []string{"api"},
+		Changed:   []string{"db"},
+		Removed:   []string{"cache"},
+		Unchanged: []string{"web"},
+	}, report.Services)
+}
+
+func Test_Validate_UnanalyzedStackFile(t *testing.T) {
+	content := "version: '2.4'\nservices:\n  web:\n    image: nginx\n    privileged: true\n    ports:\n      - invalid\n"
+
+	tests := []struct {
+		name    string
+		options Options
+		valid   bool
+	}{
+		{
+			name:    "no deployment restriction",
+			options: Options{MaxVersion: "3.9"},
+			valid:   true,
+		},
+		{
+			name:    "security settings enforced",
+			options: Options{MaxVersion: "3.9", SecuritySettings: &portainer.EndpointSecuritySettings{}},
+			valid:   false,
+		},
+		{
+			name:    "vulnerability gate enabled",
+			options: Options{MaxVersion: "3.9", VulnerabilityGateSeverity: portainer.VulnerabilitySeverityHigh},
+			valid:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Validate([]byte(content), nil, tt.options)
+
+			assert.False(t, report.Analyzed)
+			assert.Equal(t, tt.valid, report.Valid, report.Errors)
+			assert.Len(t, report.Warnings, 1)
+		})
+	}
+}
@@ -4,59 +4,82 @@ import (
 	"io"
 
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/errors"
 )
 
 type datastore struct {
-	dockerHub        portainer.DockerHubService
-	customTemplate   portainer.CustomTemplateService
-	edgeGroup        portainer.EdgeGroupService
-	edgeJob          portainer.EdgeJobService
-	edgeStack        portainer.EdgeStackService
-	endpoint         portainer.EndpointService
-	endpointGroup    portainer.EndpointGroupService
-	endpointRelation portainer.EndpointRelationService
-	registry         portainer.RegistryService
-	resourceControl  portainer.ResourceControlService
-	role             portainer.RoleService
-	settings         portainer.SettingsService
-	stack            portainer.StackService
-	tag              portainer.TagService
-	teamMembership   portainer.TeamMembershipService
-	team             portainer.TeamService
-	tunnelServer     portainer.TunnelServerService
-	user             portainer.UserService
-	version          portainer.VersionService
-	webhook          portainer.WebhookService
-}
-
-func (d *datastore) BackupTo(io.Writer) error                            { return nil }
-func (d *datastore) Open() error                                         { return nil }
-func (d *datastore) Init() error                                         { return nil }
-func (d *datastore) Close() error                                        { return nil }
-func (d *datastore) CheckCurrentEdition() error                          { return nil }
-func (d *datastore) IsNew() bool                                         { return false }
-func (d *datastore) MigrateData(force bool) error                        { return nil }
-func (d *datastore) RollbackToCE() error                                 { return nil }
-func (d *datastore) DockerHub() portainer.DockerHubService               { return d.dockerHub }
-func (d *datastore) CustomTemplate() portainer.CustomTemplateService     { return d.customTemplate }
+	dockerHub            portainer.DockerHubService
+	customTemplate       portainer.CustomTemplateService
+	edgeEnrollmentToken  portainer.EdgeEnrollmentTokenService
+	edgeGroup            portainer.EdgeGroupService
+	edgeJob              portainer.EdgeJobService
+	edgeJobResult        portainer.EdgeJobResultService
+	edgeStack            portainer.EdgeStackService
+	endpoint             portainer.EndpointService
+	endpointGroup        portainer.EndpointGroupService
+	endpointRelation     portainer.EndpointRelationService
+	imageVulnerability   portainer.ImageVulnerabilityService
+	notificationChannel  portainer.NotificationChannelService
+	notificationDelivery portainer.NotificationDeliveryService
+	registry             portainer.RegistryService
+	resourceControl      portainer.ResourceControlService
+	role                 portainer.RoleService
+	settings             portainer.SettingsService
+	snapshotHistory      portainer.SnapshotHistoryService
+	stack                portainer.StackService
+	stackRevision        portainer.StackRevisionService
+	tag                  portainer.TagService
+	teamMembership       portainer.TeamMembershipService
+	team                 portainer.TeamService
+	tunnelServer         portainer.TunnelServerService
+	user                 portainer.UserService
+	version              portainer.VersionService
+	webhook              portainer.WebhookService
+}
+
+func (d *datastore) BackupTo(io.Writer) error                        { return nil }
+func (d *datastore) Open() error                                     { return nil }
+func (d *datastore) Init() error                                     { return nil }
+func (d *datastore) Close() error                                    { return nil }
+func (d *datastore) CheckCurrentEdition() error                      { return nil }
+func (d *datastore) IsNew() bool                                     { return false }
+func (d *datastore) MigrateData(force bool) error                    { return nil }
+func (d *datastore) RollbackToCE() error                             { return nil }
+func (d *datastore) DockerHub() portainer.DockerHubService           { return d.dockerHub }
+func (d *datastore) CustomTemplate() portainer.CustomTemplateService { return d.customTemplate }
+func (d *datastore) EdgeEnrollmentToken() portainer.EdgeEnrollmentTokenService {
+	return d.edgeEnrollmentToken
+}
 func (d *datastore) EdgeGroup() portainer.EdgeGroupService               { return d.edgeGroup }
 func (d *datastore) EdgeJob() portainer.EdgeJobService                   { return d.edgeJob }
+func (d *datastore) EdgeJobResult() portainer.EdgeJobResultService       { return d.edgeJobResult }
 func (d *datastore) EdgeStack() portainer.EdgeStackService               { return d.edgeStack }
 func (d *datastore) Endpoint() portainer.EndpointService                 { return d.endpoint }
 func (d *datastore) EndpointGroup() portainer.EndpointGroupService       { return d.endpointGroup }
 func (d *datastore) EndpointRelation() portainer.EndpointRelationService { return d.endpointRelation }
-func (d *datastore) Registry() portainer.RegistryService                 { return d.registry }
-func (d *datastore) ResourceControl() portainer.ResourceControlService   { return d.resourceControl }
-func (d *datastore) Role() portainer.RoleService                         { return d.role }
-func (d *datastore) Settings() portainer.SettingsService                 { return d.settings }
-func (d *datastore) Stack() portainer.StackService                       { return d.stack }
-func (d *datastore) Tag() portainer.TagService                           { return d.tag }
-func (d *datastore) TeamMembership() portainer.TeamMembershipService     { return d.teamMembership }
-func (d *datastore) Team() portainer.TeamService                         { return d.team }
-func (d *datastore) TunnelServer() portainer.TunnelServerService         { return d.tunnelServer }
-func (d *datastore) User() portainer.UserService                         { return d.user }
-func (d *datastore) Version() portainer.VersionService                   { return d.version }
-func (d *datastore) Webhook() portainer.WebhookService                   { return d.webhook }
+func (d *datastore) ImageVulnerability() portainer.ImageVulnerabilityService {
+	return d.imageVulnerability
+}
+func (d *datastore) NotificationChannel() portainer.NotificationChannelService {
+	return d.notificationChannel
+}
+func (d *datastore) NotificationDelivery() portainer.NotificationDeliveryService {
+	return d.notificationDelivery
+}
+func (d *datastore) Registry() portainer.RegistryService               { return d.registry }
+func (d *datastore) ResourceControl() portainer.ResourceControlService { return d.resourceControl }
+func (d *datastore) Role() portainer.RoleService                       { return d.role }
+func (d *datastore) Settings() portainer.SettingsService               { return d.settings }
+func (d *datastore) SnapshotHistory() portainer.SnapshotHistoryService { return d.snapshotHistory }
+func (d *datastore) Stack() portainer.StackService                     { return d.stack }
+func (d *datastore) StackRevision() portainer.StackRevisionService     { return d.stackRevision }
+func (d *datastore) Tag() portainer.TagService                         { return d.tag }
+func (d *datastore) TeamMembership() portainer.TeamMembershipService   { return d.teamMembership }
+func (d *datastore) Team() portainer.TeamService                       { return d.team }
+func (d *datastore) TunnelServer() portainer.TunnelServerService       { return d.tunnelServer }
+func (d *datastore) User() portainer.UserService                       { return d.user }
+func (d *datastore) Version() portainer.VersionService                 { return d.version }
+func (d *datastore) Webhook() portainer.WebhookService                 { return d.webhook }
 
 type datastoreOption = func(d *datastore)
 
@@ -112,3 +135,155 @@ func WithEdgeJobs(js []portainer.EdgeJob) datastoreOption {
 		d.edgeJob = &stubEdgeJobService{jobs: js}
 	}
 }
+
+type stubEdgeStackService struct {
+	edgeStacks map[portainer.EdgeStackID]portainer.EdgeStack
+}
+
+func (s *stubEdgeStackService) EdgeStacks() ([]portainer.EdgeStack, error) {
+	edgeStacks := make([]portainer.EdgeStack, 0, len(s.edgeStacks))
+	for _, edgeStack := range s.edgeStacks {
+		edgeStacks = append(edgeStacks, edgeStack)
+	}
+	return edgeStacks, nil
+}
+func (s *stubEdgeStackService) EdgeStack(ID portainer.EdgeStackID) (*portainer.EdgeStack, error) {
+	edgeStack, ok := s.edgeStacks[ID]
+	if !ok {
+		return nil, errors.ErrObjectNotFound
+	}
+	return &edgeStack, nil
+}
+func (s *stubEdgeStackService) CreateEdgeStack(edgeStack *portainer.EdgeStack) error {
+	s.edgeStacks[edgeStack.ID] = *edgeStack
+	return nil
+}
+func (s *stubEdgeStackService) UpdateEdgeStack(ID portainer.EdgeStackID, edgeStack *portainer.EdgeStack) error {
+	s.edgeStacks[ID] = *edgeStack
+	return nil
+}
+func (s *stubEdgeStackService) UpdateEdgeStackFunc(ID portainer.EdgeStackID, updateFunc func(edgeStack *portainer.EdgeStack)) error {
+	edgeStack, ok := s.edgeStacks[ID]
+	if !ok {
+		return errors.ErrObjectNotFound
+	}
+	updateFunc(&edgeStack)
+	s.edgeStacks[ID] = edgeStack
+	return nil
+}
+func (s *stubEdgeStackService) DeleteEdgeStack(ID portainer.EdgeStackID) error {
+	delete(s.edgeStacks, ID)
+	return nil
+}
+func (s *stubEdgeStackService) GetNextIdentifier() int { return len(s.edgeStacks) + 1 }
+
+// WithEdgeStacks option will instruct datastore to store and return the provided edge stacks
+func WithEdgeStacks(stacks []portainer.EdgeStack) datastoreOption {
+	return func(d *datastore) {
+		edgeStacks := make(map[portainer.EdgeStackID]portainer.EdgeStack)
+		for _, edgeStack := range stacks {
+			edgeStacks[edgeStack.ID] = edgeStack
+		}
+		d.edgeStack = &stubEdgeStackService{edgeStacks: edgeStacks}
+	}
+}
+
+type stubNotificationDeliveryService struct {
+	deliveries []portainer.NotificationDelivery
+}
+
+func (s *stubNotificationDeliveryService) NotificationDeliveries() ([]portainer.NotificationDelivery, error) {
+	return s.deliveries, nil
+}
+
+func (s *stubNotificationDeliveryService) CreateNotificationDelivery(delivery *portainer.NotificationDelivery) error {
+	delivery.ID = portainer.NotificationDeliveryID(len(s.deliveries) + 1)
+	s.deliveries = append(s.deliveries, *delivery)
+	return nil
+}
+
+func (s *stubNotificationDeliveryService) UpdateNotificationDelivery(ID portainer.NotificationDeliveryID, delivery *portainer.NotificationDelivery) error {
+	for idx := range s.deliveries {
+		if s.deliveries[idx].ID == ID {
+			s.deliveries[idx] = *delivery
+			return nil
+		}
+	}
+	return errors.ErrObjectNotFound
+}
+
+func (s *stubNotificationDeliveryService) DeleteNotificationDeliveriesUpTo(ID portainer.NotificationDeliveryID) error {
+	deliveries := s.deliveries[:0]
+	for _, delivery := range s.deliveries {
+		if delivery.ID > ID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	s.deliveries = deliveries
+	return nil
+}
+
+// WithNotificationDeliveries option will instruct datastore to use an in-memory notification delivery log
+func WithNotificationDeliveries(deliveries []portainer.NotificationDelivery) datastoreOption {
+	return func(d *datastore) {
+		d.notificationDelivery = &stubNotificationDeliveryService{deliveries: deliveries}
+	}
+}
+
+type stubImageVulnerabilityService struct {
+	reports map[string]portainer.ImageVulnerabilityReport
+}
+
+func (s *stubImageVulnerabilityService) ImageVulnerabilityReports() ([]portainer.ImageVulnerabilityReport, error) {
+	reports := make([]portainer.ImageVulnerabilityReport, 0, len(s.reports))
+	for _, report := range s.reports {
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func (s *stubImageVulnerabilityService) ImageVulnerabilityReport(digest string) (*portainer.ImageVulnerabilityReport, error) {
+	report, ok := s.reports[digest]
+	if !ok {
+		return nil, errors.ErrObjectNotFound
+	}
+	return &report, nil
+}
+
+func (s *stubImageVulnerabilityService) UpdateImageVulnerabilityReport(digest string, report *portainer.ImageVulnerabilityReport) error {
+	s.reports[digest] = *report
+	return nil
+}
+
+func (s *stubImageVulnerabilityService) DeleteImageVulnerabilityReport(digest string) error {
+	delete(s.reports, digest)
+	return nil
+}
+
+// WithImageVulnerabilityReports option will instruct datastore to use an in-memory store of image vulnerability reports
+func WithImageVulnerabilityReports(reports []portainer.ImageVulnerabilityReport) datastoreOption {
+	return func(d *datastore) {
+		reportsByDigest := make(map[string]portainer.ImageVulnerabilityReport)
+		for _, report := range reports {
+			reportsByDigest[report.Digest] = report
+		}
+		d.imageVulnerability = &stubImageVulnerabilityService{reports: reportsByDigest}
+	}
+}
+
+type stubSettingsService struct {
+	settings *portainer.Settings
+}
+
+func (s *stubSettingsService) Settings() (*portainer.Settings, error) { return s.settings, nil }
+func (s *stubSettingsService) UpdateSettings(settings *portainer.Settings) error {
+	s.settings = settings
+	return nil
+}
+
+// WithSettings option will instruct datastore to store and return the provided settings
+func WithSettings(settings *portainer.Settings) datastoreOption {
+	return func(d *datastore) {
+		d.settings = &stubSettingsService{settings: settings}
+	}
+}
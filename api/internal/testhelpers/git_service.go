@@ -10,3 +10,11 @@ func NewGitService() *gitService {
 func (service *gitService) CloneRepository(destination string, repositoryURL, referenceName string, username, password string) error {
 	return nil
 }
+
+func (service *gitService) CloneRepositoryAtCommit(destination string, repositoryURL, referenceName, commitID string, username, password string) error {
+	return nil
+}
+
+func (service *gitService) LatestCommitID(repositoryURL, referenceName, username, password string) (string, error) {
+	return "", nil
+}
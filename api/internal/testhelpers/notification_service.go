@@ -0,0 +1,10 @@
+package testhelpers
+
+import portainer "github.com/portainer/portainer/api"
+
+type NotificationService struct{}
+
+func (s NotificationService) Notify(event portainer.NotificationEvent) {}
+func (s NotificationService) Test(channel *portainer.NotificationChannel) error {
+	return nil
+}
@@ -1,6 +1,10 @@
 package testhelpers
 
-import portainer "github.com/portainer/portainer/api"
+import (
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+)
 
 type ReverseTunnelService struct{}
 
@@ -21,3 +25,10 @@ func (r ReverseTunnelService) GetTunnelDetails(endpointID portainer.EndpointID)
 func (r ReverseTunnelService) AddEdgeJob(endpointID portainer.EndpointID, edgeJob *portainer.EdgeJob) {
 }
 func (r ReverseTunnelService) RemoveEdgeJob(edgeJobID portainer.EdgeJobID) {}
+func (r ReverseTunnelService) TunnelStatistics() []portainer.TunnelStatistics {
+	return nil
+}
+func (r ReverseTunnelService) CloseTunnel(endpointID portainer.EndpointID) error { return nil }
+func (r ReverseTunnelService) TrackTunnelTraffic(endpointID portainer.EndpointID, next http.Handler) http.Handler {
+	return next
+}
@@ -0,0 +1,338 @@
+// Package tracing exports a span for each API request to an OpenTelemetry collector, using the
+// OTLP/HTTP protocol with the JSON encoding. The exporter is configured with the standard
+// OpenTelemetry environment variables and is disabled when no collector endpoint is defined.
+// The trace context is read from and forwarded with the W3C traceparent header, so that the spans
+// of the Docker and Kubernetes APIs and of the agents are attached to the trace of the request.
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/portainer/portainer/api/internal/logging"
+)
+
+const (
+	// TraceparentHeader is the W3C header carrying the trace context
+	TraceparentHeader = "traceparent"
+	// DefaultServiceName is the name of the service reported with the spans when none is configured
+	DefaultServiceName = "portainer"
+
+	// queueSize is the number of spans waiting to be exported after which new spans are dropped
+	queueSize = 2048
+	// batchSize is the maximum number of spans sent in one export request
+	batchSize = 256
+	// exportInterval is the maximum time a span waits before being exported
+	exportInterval = 5 * time.Second
+	// exportTimeout is the timeout of the export requests sent to the collector
+	exportTimeout = 10 * time.Second
+
+	// OTLP span kind and status codes
+	spanKindServer  = 2
+	statusCodeError = 2
+)
+
+// Environment variables used to configure the exporter
+const (
+	envTracesEndpoint = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	envEndpoint       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	envHeaders        = "OTEL_EXPORTER_OTLP_HEADERS"
+	envServiceName    = "OTEL_SERVICE_NAME"
+)
+
+var logger = logging.Component("tracing")
+
+type (
+	// Tracer starts a span for each API request and exports the ended spans in the background
+	Tracer struct {
+		endpoint    string
+		headers     map[string]string
+		serviceName string
+		client      *http.Client
+		shutdownCtx context.Context
+		spans       chan span
+	}
+
+	span struct {
+		traceID      string
+		spanID       string
+		parentSpanID string
+		name         string
+		start        time.Time
+		end          time.Time
+		attributes   []attribute
+		statusCode   int
+	}
+
+	attribute struct {
+		Key   string         `json:"key"`
+		Value attributeValue `json:"value"`
+	}
+
+	attributeValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+	}
+)
+
+// NewTracerFromEnvironment creates a tracer exporting the spans to the collector defined by the
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT environment variables.
+// It returns nil when none of them is defined.
+func NewTracerFromEnvironment(shutdownCtx context.Context) *Tracer {
+	endpoint := os.Getenv(envTracesEndpoint)
+	if endpoint == "" {
+		endpoint = os.Getenv(envEndpoint)
+		if endpoint == "" {
+			return nil
+		}
+		endpoint = strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+
+	serviceName := os.Getenv(envServiceName)
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+
+	return NewTracer(endpoint, serviceName, parseHeaders(os.Getenv(envHeaders)), shutdownCtx)
+}
+
+// NewTracer creates a tracer exporting the spans to an OTLP/HTTP traces endpoint
+func NewTracer(endpoint, serviceName string, headers map[string]string, shutdownCtx context.Context) *Tracer {
+	return &Tracer{
+		endpoint:    endpoint,
+		headers:     headers,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: exportTimeout},
+		shutdownCtx: shutdownCtx,
+		spans:       make(chan span, queueSize),
+	}
+}
+
+// Start starts a background routine exporting the ended spans. The spans waiting to be exported
+// are sent when the tracer is shut down.
+func (tracer *Tracer) Start() {
+	go func() {
+		ticker := time.NewTicker(exportInterval)
+		defer ticker.Stop()
+
+		batch := make([]span, 0, batchSize)
+		for {
+			select {
+			case s := <-tracer.spans:
+				batch = append(batch, s)
+				if len(batch) < batchSize {
+					continue
+				}
+			case <-ticker.C:
+			case <-tracer.shutdownCtx.Done():
+				batch = tracer.drain(batch)
+				tracer.export(batch)
+				logger.Debug("shutting down tracing")
+				return
+			}
+
+			tracer.export(batch)
+			batch = batch[:0]
+		}
+	}()
+}
+
+// StartSpan starts the span of an API request. The span is a child of the trace context sent by the client,
+// if any, and its context is set on the request headers so that it is forwarded by the reverse proxies.
+func (tracer *Tracer) StartSpan(ctx context.Context, r *http.Request, requestID string) (context.Context, func(statusCode int)) {
+	traceID, parentSpanID, ok := parseTraceparent(r.Header.Get(TraceparentHeader))
+	if !ok {
+		traceID = randomID(16)
+		parentSpanID = ""
+	}
+	spanID := randomID(8)
+
+	r.Header.Set(TraceparentHeader, fmt.Sprintf("00-%s-%s-01", traceID, spanID))
+
+	s := span{
+		traceID:      traceID,
+		spanID:       spanID,
+		parentSpanID: parentSpanID,
+		name:         "HTTP " + r.Method,
+		start:        time.Now(),
+		attributes: []attribute{
+			stringAttribute("http.method", r.Method),
+			stringAttribute("http.target", r.URL.Path),
+			stringAttribute("http.request_id", requestID),
+		},
+	}
+
+	return ctx, func(statusCode int) {
+		s.end = time.Now()
+		s.statusCode = statusCode
+		s.attributes = append(s.attributes, intAttribute("http.status_code", statusCode))
+
+		select {
+		case tracer.spans <- s:
+		default:
+			logger.Debug("tracing queue is full, dropping span")
+		}
+	}
+}
+
+// drain returns the batch with the spans still waiting to be exported appended to it
+func (tracer *Tracer) drain(batch []span) []span {
+	for {
+		select {
+		case s := <-tracer.spans:
+			batch = append(batch, s)
+		default:
+			return batch
+		}
+	}
+}
+
+func (tracer *Tracer) export(batch []span) {
+	for len(batch) > 0 {
+		size := len(batch)
+		if size > batchSize {
+			size = batchSize
+		}
+
+		err := tracer.send(batch[:size])
+		if err != nil {
+			logger.WithError(err).WithField("spans", size).Warn("unable to export spans")
+		}
+
+		batch = batch[size:]
+	}
+}
+
+func (tracer *Tracer) send(batch []span) error {
+	body, err := json.Marshal(tracer.exportRequest(batch))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, tracer.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range tracer.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := tracer.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("collector responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// exportRequest returns the OTLP ExportTraceServiceRequest carrying a batch of spans
+func (tracer *Tracer) exportRequest(batch []span) map[string]interface{} {
+	spans := make([]map[string]interface{}, 0, len(batch))
+	for _, s := range batch {
+		encoded := map[string]interface{}{
+			"traceId":           s.traceID,
+			"spanId":            s.spanID,
+			"name":              s.name,
+			"kind":              spanKindServer,
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        s.attributes,
+			"status":            map[string]interface{}{},
+		}
+
+		if s.parentSpanID != "" {
+			encoded["parentSpanId"] = s.parentSpanID
+		}
+
+		if s.statusCode >= http.StatusInternalServerError {
+			encoded["status"] = map[string]interface{}{"code": statusCodeError}
+		}
+
+		spans = append(spans, encoded)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": []attribute{stringAttribute("service.name", tracer.serviceName)},
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": DefaultServiceName},
+						"spans": spans,
+					},
+				},
+			},
+		},
+	}
+}
+
+// parseTraceparent returns the trace identifier and the parent span identifier of a W3C traceparent header
+func parseTraceparent(header string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(header, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+
+	if !isHex(parts[1]) || !isHex(parts[2]) || strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return "", "", false
+	}
+
+	return parts[1], parts[2], true
+}
+
+// parseHeaders parses the headers of the OTEL_EXPORTER_OTLP_HEADERS environment variable, a list of key=value pairs separated by commas
+func parseHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			continue
+		}
+		headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return headers
+}
+
+func isHex(value string) bool {
+	for _, c := range value {
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomID(size int) string {
+	id := make([]byte, size)
+	if _, err := rand.Read(id); err != nil {
+		return strings.Repeat("0", size*2-1) + "1"
+	}
+	return hex.EncodeToString(id)
+}
+
+func stringAttribute(key, value string) attribute {
+	return attribute{Key: key, Value: attributeValue{StringValue: &value}}
+}
+
+func intAttribute(key string, value int) attribute {
+	encoded := strconv.Itoa(value)
+	return attribute{Key: key, Value: attributeValue{IntValue: &encoded}}
+}
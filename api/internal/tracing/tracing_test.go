@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		traceID string
+		spanID  string
+		ok      bool
+	}{
+		{name: "valid header", header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", traceID: "4bf92f3577b34da6a3ce929d0e0e4736", spanID: "00f067aa0ba902b7", ok: true},
+		{name: "empty header", header: "", ok: false},
+		{name: "invalid version", header: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ok: false},
+		{name: "uppercase identifiers", header: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01", ok: false},
+		{name: "zero trace identifier", header: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", ok: false},
+		{name: "short span identifier", header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa-01", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traceID, spanID, ok := parseTraceparent(tt.header)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.traceID, traceID)
+			assert.Equal(t, tt.spanID, spanID)
+		})
+	}
+}
+
+func Test_parseHeaders(t *testing.T) {
+	headers := parseHeaders("Authorization=Bearer token, x-scope=portainer,invalid,=empty")
+	assert.Equal(t, map[string]string{"Authorization": "Bearer token", "x-scope": "portainer"}, headers)
+}
+
+func Test_Tracer(t *testing.T) {
+	exported := make(chan map[string]interface{}, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		exported <- body
+	}))
+	defer collector.Close()
+
+	shutdownCtx, shutdown := context.WithCancel(context.Background())
+	tracer := NewTracer(collector.URL+"/v1/traces", "portainer-test", map[string]string{"Authorization": "Bearer token"}, shutdownCtx)
+	tracer.Start()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/stacks", nil)
+	r.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	_, end := tracer.StartSpan(context.Background(), r, "deploy-1234")
+
+	traceID, spanID, ok := parseTraceparent(r.Header.Get(TraceparentHeader))
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+	assert.NotEqual(t, "00f067aa0ba902b7", spanID)
+
+	end(http.StatusInternalServerError)
+	shutdown()
+
+	select {
+	case body := <-exported:
+		encoded, err := json.Marshal(body)
+		assert.NoError(t, err)
+
+		for _, expected := range []string{
+			`"stringValue":"portainer-test"`,
+			`"traceId":"4bf92f3577b34da6a3ce929d0e0e4736"`,
+			`"parentSpanId":"00f067aa0ba902b7"`,
+			`"spanId":"` + spanID + `"`,
+			`"stringValue":"deploy-1234"`,
+			`"key":"http.status_code","value":{"intValue":"500"}`,
+			`"status":{"code":2}`,
+		} {
+			assert.True(t, strings.Contains(string(encoded), expected), expected)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("spans were not exported on shutdown")
+	}
+}
@@ -0,0 +1,104 @@
+package vulnerability
+
+import (
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// dockerHubDomain is the domain of the images whose reference does not specify a registry
+const dockerHubDomain = "docker.io"
+
+// loadRegistries returns the registries requiring authentication, including the DockerHub when credentials are defined
+func (service *Service) loadRegistries() ([]portainer.Registry, error) {
+	registries, err := service.dataStore.Registry().Registries()
+	if err != nil {
+		return nil, err
+	}
+
+	dockerHub, err := service.dataStore.DockerHub().DockerHub()
+	if err != nil {
+		return nil, err
+	}
+
+	authenticatedRegistries := make([]portainer.Registry, 0, len(registries)+1)
+	for _, registry := range registries {
+		if registry.Authentication {
+			authenticatedRegistries = append(authenticatedRegistries, registry)
+		}
+	}
+
+	if dockerHub.Authentication {
+		authenticatedRegistries = append(authenticatedRegistries, portainer.Registry{
+			Name:           "DockerHub",
+			URL:            dockerHubDomain,
+			Authentication: true,
+			Username:       dockerHub.Username,
+			Password:       dockerHub.Password,
+		})
+	}
+
+	return authenticatedRegistries, nil
+}
+
+// findRegistry returns the registry an image is pulled from, nil when none of the registries matches the image.
+// The registry with the longest matching URL is returned, as the URL of some registries include a namespace.
+func findRegistry(image string, registries []portainer.Registry) *portainer.Registry {
+	name := imageName(image)
+
+	var found *portainer.Registry
+	foundLength := 0
+	for idx := range registries {
+		url := registryURL(registries[idx].URL)
+		if url == "" || len(url) <= foundLength {
+			continue
+		}
+
+		if name == url || strings.HasPrefix(name, url+"/") {
+			found = &registries[idx]
+			foundLength = len(url)
+		}
+	}
+
+	return found
+}
+
+// imageName returns the name of an image, without tag nor digest, prefixed by the domain of its registry
+func imageName(image string) string {
+	if idx := strings.Index(image, "@"); idx != -1 {
+		image = image[:idx]
+	}
+	if idx := strings.LastIndex(image, ":"); idx > strings.LastIndex(image, "/") {
+		image = image[:idx]
+	}
+
+	idx := strings.Index(image, "/")
+	if idx == -1 || (!strings.ContainsAny(image[:idx], ".:") && image[:idx] != "localhost") {
+		return dockerHubDomain + "/" + image
+	}
+
+	return registryURL(image)
+}
+
+// registryURL returns the URL of a registry without scheme nor trailing slash, the DockerHub aliases being replaced by its domain
+func registryURL(url string) string {
+	url = strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://")
+	url = strings.TrimSuffix(url, "/")
+
+	for _, alias := range []string{"index.docker.io", "registry-1.docker.io", "registry.hub.docker.com"} {
+		if url == alias || strings.HasPrefix(url, alias+"/") {
+			return dockerHubDomain + url[len(alias):]
+		}
+	}
+
+	return url
+}
+
+// registryAuthority returns the host of a registry, as expected by the scanners
+func registryAuthority(registry *portainer.Registry) string {
+	authority := strings.SplitN(registryURL(registry.URL), "/", 2)[0]
+	if authority == dockerHubDomain {
+		return "index.docker.io"
+	}
+	return authority
+}
@@ -0,0 +1,234 @@
+package vulnerability
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	// ScannerTrivy is the name of the scanner running the Trivy command line
+	ScannerTrivy = "trivy"
+	// ScannerGrype is the name of the scanner running the Grype command line
+	ScannerGrype = "grype"
+)
+
+// commandRunner runs a command with additional environment variables and returns its standard output
+type commandRunner func(ctx context.Context, program string, env []string, args ...string) ([]byte, error)
+
+// cliScanner scans images with the Trivy or Grype command line. Images are pulled by the scanner
+// from their registry, they do not need to be available on the Portainer host. The credentials of the
+// registry are passed to the scanner through its environment variables.
+type cliScanner struct {
+	name      string
+	program   string
+	serverURL string
+	run       commandRunner
+}
+
+// NewScanner creates the scanner selected in the settings. The scanner binary is looked up
+// in the binary path first, then in the PATH.
+func NewScanner(settings *portainer.Settings, binaryPath string) (portainer.VulnerabilityScanner, error) {
+	switch settings.VulnerabilityScanner {
+	case ScannerTrivy, ScannerGrype:
+		return &cliScanner{
+			name:      settings.VulnerabilityScanner,
+			program:   programPath(binaryPath, settings.VulnerabilityScanner),
+			serverURL: settings.VulnerabilityScannerServerURL,
+			run:       runCommand,
+		}, nil
+	}
+
+	return nil, errors.New("Unsupported vulnerability scanner: " + settings.VulnerabilityScanner)
+}
+
+func programPath(binaryPath, name string) string {
+	if runtime.GOOS == "windows" {
+		name = name + ".exe"
+	}
+
+	program := path.Join(binaryPath, name)
+	if _, err := os.Stat(program); err == nil {
+		return program
+	}
+
+	return name
+}
+
+func runCommand(ctx context.Context, program string, env []string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, program, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			return nil, err
+		}
+		return nil, errors.New(message)
+	}
+
+	return output, nil
+}
+
+// Name returns the name of the scanner
+func (scanner *cliScanner) Name() string {
+	return scanner.name
+}
+
+// Scan runs the scanner against the image and returns the vulnerabilities found. The image is pulled
+// with the credentials of the registry when specified. The image reference is passed after the end of
+// the options, so that it cannot be interpreted as an option.
+func (scanner *cliScanner) Scan(ctx context.Context, image string, registry *portainer.Registry) ([]portainer.ImageVulnerability, error) {
+	if scanner.name == ScannerGrype {
+		output, err := scanner.run(ctx, scanner.program, grypeEnvironment(registry), "--output", "json", "--quiet", "--", "registry:"+image)
+		if err != nil {
+			return nil, err
+		}
+		return parseGrypeReport(output)
+	}
+
+	args := []string{"image", "--format", "json", "--quiet", "--no-progress"}
+	if scanner.serverURL != "" {
+		args = append(args, "--server", scanner.serverURL)
+	}
+	args = append(args, "--", image)
+
+	output, err := scanner.run(ctx, scanner.program, trivyEnvironment(registry), args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseTrivyReport(output)
+}
+
+func trivyEnvironment(registry *portainer.Registry) []string {
+	if registry == nil || !registry.Authentication {
+		return nil
+	}
+
+	return []string{
+		"TRIVY_USERNAME=" + registry.Username,
+		"TRIVY_PASSWORD=" + registry.Password,
+	}
+}
+
+func grypeEnvironment(registry *portainer.Registry) []string {
+	if registry == nil || !registry.Authentication {
+		return nil
+	}
+
+	return []string{
+		"GRYPE_REGISTRY_AUTH_AUTHORITY=" + registryAuthority(registry),
+		"GRYPE_REGISTRY_AUTH_USERNAME=" + registry.Username,
+		"GRYPE_REGISTRY_AUTH_PASSWORD=" + registry.Password,
+	}
+}
+
+type trivyResult struct {
+	Vulnerabilities []struct {
+		VulnerabilityID  string
+		PkgName          string
+		InstalledVersion string
+		FixedVersion     string
+		Severity         string
+		Title            string
+		PrimaryURL       string
+	}
+}
+
+// parseTrivyReport parses the JSON report of Trivy. Reports created before the
+// introduction of the schema version are a list of results instead of an object.
+func parseTrivyReport(output []byte) ([]portainer.ImageVulnerability, error) {
+	var results []trivyResult
+
+	output = bytes.TrimSpace(output)
+	if bytes.HasPrefix(output, []byte("[")) {
+		err := json.Unmarshal(output, &results)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var report struct {
+			Results []trivyResult
+		}
+		err := json.Unmarshal(output, &report)
+		if err != nil {
+			return nil, err
+		}
+		results = report.Results
+	}
+
+	vulnerabilities := make([]portainer.ImageVulnerability, 0)
+	for _, result := range results {
+		for _, vulnerability := range result.Vulnerabilities {
+			vulnerabilities = append(vulnerabilities, portainer.ImageVulnerability{
+				ID:               vulnerability.VulnerabilityID,
+				PackageName:      vulnerability.PkgName,
+				InstalledVersion: vulnerability.InstalledVersion,
+				FixedVersion:     vulnerability.FixedVersion,
+				Severity:         normalizeSeverity(vulnerability.Severity),
+				Title:            vulnerability.Title,
+				URL:              vulnerability.PrimaryURL,
+			})
+		}
+	}
+
+	return vulnerabilities, nil
+}
+
+// parseGrypeReport parses the JSON report of Grype
+func parseGrypeReport(output []byte) ([]portainer.ImageVulnerability, error) {
+	var report struct {
+		Matches []struct {
+			Vulnerability struct {
+				ID          string   `json:"id"`
+				Severity    string   `json:"severity"`
+				Description string   `json:"description"`
+				DataSource  string   `json:"dataSource"`
+				URLs        []string `json:"urls"`
+				Fix         struct {
+					Versions []string `json:"versions"`
+				} `json:"fix"`
+			} `json:"vulnerability"`
+			Artifact struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"artifact"`
+		} `json:"matches"`
+	}
+
+	err := json.Unmarshal(output, &report)
+	if err != nil {
+		return nil, err
+	}
+
+	vulnerabilities := make([]portainer.ImageVulnerability, 0, len(report.Matches))
+	for _, match := range report.Matches {
+		url := match.Vulnerability.DataSource
+		if url == "" && len(match.Vulnerability.URLs) > 0 {
+			url = match.Vulnerability.URLs[0]
+		}
+
+		vulnerabilities = append(vulnerabilities, portainer.ImageVulnerability{
+			ID:               match.Vulnerability.ID,
+			PackageName:      match.Artifact.Name,
+			InstalledVersion: match.Artifact.Version,
+			FixedVersion:     strings.Join(match.Vulnerability.Fix.Versions, ", "),
+			Severity:         normalizeSeverity(match.Vulnerability.Severity),
+			Title:            match.Vulnerability.Description,
+			URL:              url,
+		})
+	}
+
+	return vulnerabilities, nil
+}
@@ -0,0 +1,244 @@
+package vulnerability
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+const trivyReport = `{
+  "SchemaVersion": 2,
+  "ArtifactName": "nginx@sha256:abc",
+  "Results": [
+    {
+      "Target": "nginx (debian 10.9)",
+      "Vulnerabilities": [
+        {
+          "VulnerabilityID": "CVE-2021-3449",
+          "PkgName": "openssl",
+          "InstalledVersion": "1.1.1d-0",
+          "FixedVersion": "1.1.1k-1",
+          "Severity": "HIGH",
+          "Title": "openssl: NULL pointer dereference",
+          "PrimaryURL": "https://avd.aquasec.com/nvd/cve-2021-3449"
+        }
+      ]
+    },
+    {
+      "Target": "usr/local/bin/app",
+      "Vulnerabilities": null
+    }
+  ]
+}`
+
+const trivyLegacyReport = `[
+  {
+    "Target": "nginx (debian 10.9)",
+    "Vulnerabilities": [
+      {"VulnerabilityID": "CVE-2020-1971", "PkgName": "openssl", "Severity": "MEDIUM"}
+    ]
+  }
+]`
+
+const grypeReport = `{
+  "matches": [
+    {
+      "vulnerability": {
+        "id": "CVE-2021-3449",
+        "severity": "High",
+        "description": "openssl: NULL pointer dereference",
+        "dataSource": "https://nvd.nist.gov/vuln/detail/CVE-2021-3449",
+        "fix": {"versions": ["1.1.1k-1"], "state": "fixed"}
+      },
+      "artifact": {"name": "openssl", "version": "1.1.1d-0"}
+    },
+    {
+      "vulnerability": {
+        "id": "CVE-2019-1010022",
+        "severity": "Negligible",
+        "urls": ["https://security-tracker.debian.org/tracker/CVE-2019-1010022"],
+        "fix": {"versions": [], "state": "not-fixed"}
+      },
+      "artifact": {"name": "libc6", "version": "2.28-10"}
+    }
+  ]
+}`
+
+func Test_parseTrivyReport(t *testing.T) {
+	vulnerabilities, err := parseTrivyReport([]byte(trivyReport))
+	assert.NoError(t, err)
+	assert.Equal(t, []portainer.ImageVulnerability{
+		{
+			ID:               "CVE-2021-3449",
+			PackageName:      "openssl",
+			InstalledVersion: "1.1.1d-0",
+			FixedVersion:     "1.1.1k-1",
+			Severity:         portainer.VulnerabilitySeverityHigh,
+			Title:            "openssl: NULL pointer dereference",
+			URL:              "https://avd.aquasec.com/nvd/cve-2021-3449",
+		},
+	}, vulnerabilities)
+
+	vulnerabilities, err = parseTrivyReport([]byte(trivyLegacyReport))
+	assert.NoError(t, err)
+	if assert.Len(t, vulnerabilities, 1) {
+		assert.Equal(t, "CVE-2020-1971", vulnerabilities[0].ID)
+		assert.Equal(t, portainer.VulnerabilitySeverityMedium, vulnerabilities[0].Severity)
+	}
+
+	_, err = parseTrivyReport([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func Test_parseGrypeReport(t *testing.T) {
+	vulnerabilities, err := parseGrypeReport([]byte(grypeReport))
+	assert.NoError(t, err)
+	assert.Equal(t, []portainer.ImageVulnerability{
+		{
+			ID:               "CVE-2021-3449",
+			PackageName:      "openssl",
+			InstalledVersion: "1.1.1d-0",
+			FixedVersion:     "1.1.1k-1",
+			Severity:         portainer.VulnerabilitySeverityHigh,
+			Title:            "openssl: NULL pointer dereference",
+			URL:              "https://nvd.nist.gov/vuln/detail/CVE-2021-3449",
+		},
+		{
+			ID:               "CVE-2019-1010022",
+			PackageName:      "libc6",
+			InstalledVersion: "2.28-10",
+			Severity:         portainer.VulnerabilitySeverityLow,
+			URL:              "https://security-tracker.debian.org/tracker/CVE-2019-1010022",
+		},
+	}, vulnerabilities)
+}
+
+func Test_cliScanner_Scan(t *testing.T) {
+	registry := &portainer.Registry{URL: "registry.mydomain.tld:5000", Authentication: true, Username: "user", Password: "passwd"}
+
+	tests := []struct {
+		name     string
+		scanner  cliScanner
+		registry *portainer.Registry
+		output   string
+		wantArgs []string
+		wantEnv  []string
+	}{
+		{
+			name:     "trivy",
+			scanner:  cliScanner{name: ScannerTrivy, program: "trivy"},
+			output:   trivyReport,
+			wantArgs: []string{"image", "--format", "json", "--quiet", "--no-progress", "--", "nginx@sha256:abc"},
+		},
+		{
+			name:     "trivy with server",
+			scanner:  cliScanner{name: ScannerTrivy, program: "trivy", serverURL: "http://trivy:4954"},
+			output:   trivyReport,
+			wantArgs: []string{"image", "--format", "json", "--quiet", "--no-progress", "--server", "http://trivy:4954", "--", "nginx@sha256:abc"},
+		},
+		{
+			name:     "trivy with registry credentials",
+			scanner:  cliScanner{name: ScannerTrivy, program: "trivy"},
+			registry: registry,
+			output:   trivyReport,
+			wantArgs: []string{"image", "--format", "json", "--quiet", "--no-progress", "--", "nginx@sha256:abc"},
+			wantEnv:  []string{"TRIVY_USERNAME=user", "TRIVY_PASSWORD=passwd"},
+		},
+		{
+			name:     "grype",
+			scanner:  cliScanner{name: ScannerGrype, program: "grype"},
+			output:   grypeReport,
+			wantArgs: []string{"--output", "json", "--quiet", "--", "registry:nginx@sha256:abc"},
+		},
+		{
+			name:     "grype with registry credentials",
+			scanner:  cliScanner{name: ScannerGrype, program: "grype"},
+			registry: registry,
+			output:   grypeReport,
+			wantArgs: []string{"--output", "json", "--quiet", "--", "registry:nginx@sha256:abc"},
+			wantEnv:  []string{"GRYPE_REGISTRY_AUTH_AUTHORITY=registry.mydomain.tld:5000", "GRYPE_REGISTRY_AUTH_USERNAME=user", "GRYPE_REGISTRY_AUTH_PASSWORD=passwd"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotProgram string
+			var gotArgs, gotEnv []string
+
+			scanner := tt.scanner
+			scanner.run = func(ctx context.Context, program string, env []string, args ...string) ([]byte, error) {
+				gotProgram = program
+				gotEnv = env
+				gotArgs = args
+				return []byte(tt.output), nil
+			}
+
+			vulnerabilities, err := scanner.Scan(context.Background(), "nginx@sha256:abc", tt.registry)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, vulnerabilities)
+			assert.Equal(t, tt.scanner.program, gotProgram)
+			assert.Equal(t, tt.wantArgs, gotArgs)
+			assert.Equal(t, tt.wantEnv, gotEnv)
+		})
+	}
+}
+
+func Test_findRegistry(t *testing.T) {
+	registries := []portainer.Registry{
+		{Name: "mydomain", URL: "https://registry.mydomain.tld:5000/"},
+		{Name: "gitlab", URL: "registry.gitlab.com"},
+		{Name: "gitlab group", URL: "registry.gitlab.com/portainer"},
+		{Name: "DockerHub", URL: "docker.io"},
+	}
+
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "registry.mydomain.tld:5000/web:1.0", want: "mydomain"},
+		{image: "registry.mydomain.tld/web:1.0", want: ""},
+		{image: "registry.gitlab.com/portainer/agent@sha256:abc", want: "gitlab group"},
+		{image: "registry.gitlab.com/other/app", want: "gitlab"},
+		{image: "nginx:latest", want: "DockerHub"},
+		{image: "portainer/agent", want: "DockerHub"},
+		{image: "localhost:5000/app", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			registry := findRegistry(tt.image, registries)
+			if tt.want == "" {
+				assert.Nil(t, registry)
+				return
+			}
+			if assert.NotNil(t, registry) {
+				assert.Equal(t, tt.want, registry.Name)
+			}
+		})
+	}
+}
+
+func Test_cliScanner_Scan_CommandError(t *testing.T) {
+	scanner := cliScanner{
+		name:    ScannerTrivy,
+		program: "trivy",
+		run: func(ctx context.Context, program string, env []string, args ...string) ([]byte, error) {
+			return nil, errors.New("unable to pull image")
+		},
+	}
+
+	_, err := scanner.Scan(context.Background(), "nginx@sha256:abc", nil)
+	assert.EqualError(t, err, "unable to pull image")
+}
+
+func Test_NewScanner(t *testing.T) {
+	scanner, err := NewScanner(&portainer.Settings{VulnerabilityScanner: ScannerGrype}, "/nonexistent")
+	assert.NoError(t, err)
+	assert.Equal(t, ScannerGrype, scanner.Name())
+
+	_, err = NewScanner(&portainer.Settings{VulnerabilityScanner: "clair"}, "/nonexistent")
+	assert.Error(t, err)
+}
@@ -0,0 +1,383 @@
+package vulnerability
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/logging"
+)
+
+const (
+	scanTick      = time.Minute
+	retryInterval = time.Hour
+)
+
+var logger = logging.Component("vulnerability")
+
+var errScannerDisabled = errors.New("No vulnerability scanner is configured")
+
+var severityRanks = map[portainer.VulnerabilitySeverity]int{
+	portainer.VulnerabilitySeverityUnknown:  0,
+	portainer.VulnerabilitySeverityLow:      1,
+	portainer.VulnerabilitySeverityMedium:   2,
+	portainer.VulnerabilitySeverityHigh:     3,
+	portainer.VulnerabilitySeverityCritical: 4,
+}
+
+// ParseSeverity returns the severity matching the specified name, case insensitively
+func ParseSeverity(name string) (portainer.VulnerabilitySeverity, error) {
+	severity := portainer.VulnerabilitySeverity(strings.ToUpper(name))
+	if _, ok := severityRanks[severity]; !ok {
+		return "", errors.New("Invalid severity. Valid values are: UNKNOWN, LOW, MEDIUM, HIGH or CRITICAL")
+	}
+	return severity, nil
+}
+
+// AtLeast returns true when the severity is equal to or higher than the minimum severity
+func AtLeast(severity, minimum portainer.VulnerabilitySeverity) bool {
+	return severityRanks[severity] >= severityRanks[minimum]
+}
+
+// HasVulnerabilities returns true when the report contains at least one vulnerability
+// whose severity is equal to or higher than the minimum severity
+func HasVulnerabilities(report *portainer.ImageVulnerabilityReport, minimum portainer.VulnerabilitySeverity) bool {
+	for severity, count := range report.Summary {
+		if count > 0 && AtLeast(severity, minimum) {
+			return true
+		}
+	}
+	return false
+}
+
+// Summarize counts the vulnerabilities of each severity
+func Summarize(vulnerabilities []portainer.ImageVulnerability) map[portainer.VulnerabilitySeverity]int {
+	summary := make(map[portainer.VulnerabilitySeverity]int)
+	for severity := range severityRanks {
+		summary[severity] = 0
+	}
+
+	for _, vulnerability := range vulnerabilities {
+		summary[vulnerability.Severity]++
+	}
+
+	return summary
+}
+
+// normalizeSeverity maps the severity reported by a scanner to a known severity
+func normalizeSeverity(name string) portainer.VulnerabilitySeverity {
+	if strings.EqualFold(name, "negligible") {
+		return portainer.VulnerabilitySeverityLow
+	}
+
+	severity, err := ParseSeverity(name)
+	if err != nil {
+		return portainer.VulnerabilitySeverityUnknown
+	}
+	return severity
+}
+
+// Service runs the background job scanning the images found in the endpoint snapshots
+// for known vulnerabilities. Each image digest is scanned once per scan interval.
+type Service struct {
+	dataStore   portainer.DataStore
+	binaryPath  string
+	shutdownCtx context.Context
+	newScanner  func(settings *portainer.Settings, binaryPath string) (portainer.VulnerabilityScanner, error)
+}
+
+// NewService returns a new instance of Service
+func NewService(dataStore portainer.DataStore, binaryPath string, shutdownCtx context.Context) *Service {
+	return &Service{
+		dataStore:   dataStore,
+		binaryPath:  binaryPath,
+		shutdownCtx: shutdownCtx,
+		newScanner:  NewScanner,
+	}
+}
+
+// Start starts the background job. Images are only scanned when a scanner is selected in the settings.
+func (service *Service) Start() {
+	go func() {
+		ticker := time.NewTicker(scanTick)
+		defer ticker.Stop()
+
+		for {
+			err := service.scan()
+			if err != nil {
+				logger.WithError(err).Error("unable to scan images for vulnerabilities")
+			}
+
+			select {
+			case <-ticker.C:
+			case <-service.shutdownCtx.Done():
+				logger.Debug("shutting down vulnerability scanning")
+				return
+			}
+		}
+	}()
+}
+
+func (service *Service) scan() error {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	if settings.VulnerabilityScanner == "" {
+		return nil
+	}
+
+	scanner, err := service.newScanner(settings, service.binaryPath)
+	if err != nil {
+		return err
+	}
+
+	endpoints, err := service.dataStore.Endpoint().Endpoints()
+	if err != nil {
+		return err
+	}
+
+	registries, err := service.loadRegistries()
+	if err != nil {
+		return err
+	}
+
+	interval := durationSetting(settings.VulnerabilityScanInterval, portainer.DefaultVulnerabilityScanInterval)
+	timeout := durationSetting(settings.VulnerabilityScanTimeout, portainer.DefaultVulnerabilityScanTimeout)
+
+	return service.scanImages(scanner, endpoints, registries, interval, timeout, time.Now())
+}
+
+// durationSetting parses a duration of the settings, the default value is used when it is empty or invalid
+func durationSetting(value, defaultValue string) time.Duration {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		duration, _ = time.ParseDuration(defaultValue)
+	}
+	return duration
+}
+
+// ScanImage scans an image with the scanner selected in the settings and returns the vulnerabilities found,
+// without storing them. It is used to scan the images which are not found on the endpoints yet.
+// The scan is cancelled with the context or once the scan timeout of the settings is exceeded.
+func (service *Service) ScanImage(ctx context.Context, image string) ([]portainer.ImageVulnerability, error) {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.VulnerabilityScanner == "" {
+		return nil, errScannerDisabled
+	}
+
+	scanner, err := service.newScanner(settings, service.binaryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	registries, err := service.loadRegistries()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, durationSetting(settings.VulnerabilityScanTimeout, portainer.DefaultVulnerabilityScanTimeout))
+	defer cancel()
+
+	return scanner.Scan(ctx, image, findRegistry(image, registries))
+}
+
+// scanImages updates the reports of the images found on the endpoints, scans the images whose report
+// is missing or older than the interval and removes the reports of the images no longer found.
+func (service *Service) scanImages(scanner portainer.VulnerabilityScanner, endpoints []portainer.Endpoint, registries []portainer.Registry, interval, timeout time.Duration, now time.Time) error {
+	images := collectImages(endpoints)
+
+	reports, err := service.dataStore.ImageVulnerability().ImageVulnerabilityReports()
+	if err != nil {
+		return err
+	}
+
+	existingReports := make(map[string]portainer.ImageVulnerabilityReport)
+	for _, report := range reports {
+		if _, ok := images[report.Digest]; !ok {
+			err := service.dataStore.ImageVulnerability().DeleteImageVulnerabilityReport(report.Digest)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		existingReports[report.Digest] = report
+	}
+
+	digests := make([]string, 0, len(images))
+	for digest := range images {
+		digests = append(digests, digest)
+	}
+	sort.Strings(digests)
+
+	for _, digest := range digests {
+		if service.shutdownCtx.Err() != nil {
+			return nil
+		}
+
+		img := images[digest]
+		report, found := existingReports[digest]
+		previous := report
+
+		report.Digest = digest
+		report.References = img.references
+		report.Tags = img.tags
+		report.EndpointIDs = img.endpointIDs
+
+		if !found || needsScan(&report, scanner.Name(), interval, now) {
+			service.scanImage(scanner, &report, findRegistry(report.References[0], registries), timeout, now)
+		} else if reflect.DeepEqual(previous, report) {
+			continue
+		}
+
+		err := service.dataStore.ImageVulnerability().UpdateImageVulnerabilityReport(digest, &report)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func needsScan(report *portainer.ImageVulnerabilityReport, scannerName string, interval time.Duration, now time.Time) bool {
+	if report.Scanner != scannerName {
+		return true
+	}
+
+	if report.Error != "" && retryInterval < interval {
+		interval = retryInterval
+	}
+
+	return !time.Unix(report.ScannedAt, 0).Add(interval).After(now)
+}
+
+// scanImage scans the image with its first repository digest. The vulnerabilities of the previous
+// successful scan are kept when the scan fails.
+func (service *Service) scanImage(scanner portainer.VulnerabilityScanner, report *portainer.ImageVulnerabilityReport, registry *portainer.Registry, timeout time.Duration, now time.Time) {
+	ctx, cancel := context.WithTimeout(service.shutdownCtx, timeout)
+	defer cancel()
+
+	vulnerabilities, err := scanner.Scan(ctx, report.References[0], registry)
+
+	report.Scanner = scanner.Name()
+	report.ScannedAt = now.Unix()
+
+	if err != nil {
+		logger.WithField("image", report.References[0]).WithError(err).Warn("unable to scan image for vulnerabilities")
+		report.Error = err.Error()
+		if report.Vulnerabilities == nil {
+			report.Vulnerabilities = make([]portainer.ImageVulnerability, 0)
+			report.Summary = Summarize(nil)
+		}
+		return
+	}
+
+	report.Error = ""
+	report.Vulnerabilities = vulnerabilities
+	report.Summary = Summarize(vulnerabilities)
+}
+
+// image holds the references of an image digest found on the endpoints
+type image struct {
+	references  []string
+	tags        []string
+	endpointIDs []portainer.EndpointID
+}
+
+// imageSummary holds the fields of the images listed in the Docker snapshots
+type imageSummary struct {
+	RepoTags    []string
+	RepoDigests []string
+}
+
+// collectImages returns the images found in the latest snapshot of each Docker endpoint, indexed by digest.
+// Images without repository digest, such as images built on the endpoint, cannot be pulled by the scanner and are ignored.
+func collectImages(endpoints []portainer.Endpoint) map[string]*image {
+	images := make(map[string]*image)
+
+	for _, endpoint := range endpoints {
+		if len(endpoint.Snapshots) == 0 {
+			continue
+		}
+
+		snapshot := endpoint.Snapshots[len(endpoint.Snapshots)-1]
+		for _, summary := range snapshotImages(&snapshot) {
+			tags := make([]string, 0)
+			for _, tag := range summary.RepoTags {
+				if tag != "<none>:<none>" {
+					tags = append(tags, tag)
+				}
+			}
+
+			for _, reference := range summary.RepoDigests {
+				idx := strings.LastIndex(reference, "@")
+				if idx == -1 || strings.HasPrefix(reference, "<none>") {
+					continue
+				}
+
+				digest := reference[idx+1:]
+				if images[digest] == nil {
+					images[digest] = &image{}
+				}
+
+				img := images[digest]
+				img.references = appendUnique(img.references, reference)
+				for _, tag := range tags {
+					img.tags = appendUnique(img.tags, tag)
+				}
+				if len(img.endpointIDs) == 0 || img.endpointIDs[len(img.endpointIDs)-1] != endpoint.ID {
+					img.endpointIDs = append(img.endpointIDs, endpoint.ID)
+				}
+			}
+		}
+	}
+
+	for _, img := range images {
+		sort.Strings(img.references)
+		sort.Strings(img.tags)
+		if img.tags == nil {
+			img.tags = make([]string, 0)
+		}
+	}
+
+	return images
+}
+
+// snapshotImages decodes the images of a snapshot, which are stored as raw Docker API objects
+func snapshotImages(snapshot *portainer.DockerSnapshot) []imageSummary {
+	if snapshot.SnapshotRaw.Images == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(snapshot.SnapshotRaw.Images)
+	if err != nil {
+		return nil
+	}
+
+	var summaries []imageSummary
+	err = json.Unmarshal(data, &summaries)
+	if err != nil {
+		return nil
+	}
+
+	return summaries
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
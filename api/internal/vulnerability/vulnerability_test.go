@@ -0,0 +1,184 @@
+package vulnerability
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeScanner struct {
+	scanned         []string
+	err             error
+	vulnerabilities []portainer.ImageVulnerability
+}
+
+func (scanner *fakeScanner) Name() string { return ScannerTrivy }
+
+func (scanner *fakeScanner) Scan(ctx context.Context, image string, registry *portainer.Registry) ([]portainer.ImageVulnerability, error) {
+	scanner.scanned = append(scanner.scanned, image)
+	return scanner.vulnerabilities, scanner.err
+}
+
+func endpointWithImages(ID portainer.EndpointID, images interface{}) portainer.Endpoint {
+	return portainer.Endpoint{
+		ID: ID,
+		Snapshots: []portainer.DockerSnapshot{
+			{SnapshotRaw: portainer.DockerSnapshotRaw{Images: images}},
+		},
+	}
+}
+
+func Test_AtLeast(t *testing.T) {
+	tests := []struct {
+		severity portainer.VulnerabilitySeverity
+		minimum  portainer.VulnerabilitySeverity
+		want     bool
+	}{
+		{portainer.VulnerabilitySeverityCritical, portainer.VulnerabilitySeverityHigh, true},
+		{portainer.VulnerabilitySeverityHigh, portainer.VulnerabilitySeverityHigh, true},
+		{portainer.VulnerabilitySeverityMedium, portainer.VulnerabilitySeverityHigh, false},
+		{portainer.VulnerabilitySeverityUnknown, portainer.VulnerabilitySeverityLow, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.severity)+">="+string(tt.minimum), func(t *testing.T) {
+			assert.Equal(t, tt.want, AtLeast(tt.severity, tt.minimum))
+		})
+	}
+}
+
+func Test_ParseSeverity(t *testing.T) {
+	severity, err := ParseSeverity("critical")
+	assert.NoError(t, err)
+	assert.Equal(t, portainer.VulnerabilitySeverityCritical, severity)
+
+	_, err = ParseSeverity("severe")
+	assert.Error(t, err)
+
+	assert.Equal(t, portainer.VulnerabilitySeverityLow, normalizeSeverity("Negligible"))
+	assert.Equal(t, portainer.VulnerabilitySeverityUnknown, normalizeSeverity(""))
+}
+
+func Test_HasVulnerabilities(t *testing.T) {
+	report := &portainer.ImageVulnerabilityReport{
+		Summary: Summarize([]portainer.ImageVulnerability{
+			{Severity: portainer.VulnerabilitySeverityMedium},
+			{Severity: portainer.VulnerabilitySeverityHigh},
+		}),
+	}
+
+	assert.Equal(t, 1, report.Summary[portainer.VulnerabilitySeverityHigh])
+	assert.Equal(t, 0, report.Summary[portainer.VulnerabilitySeverityCritical])
+	assert.True(t, HasVulnerabilities(report, portainer.VulnerabilitySeverityHigh))
+	assert.False(t, HasVulnerabilities(report, portainer.VulnerabilitySeverityCritical))
+}
+
+func Test_collectImages(t *testing.T) {
+	endpoints := []portainer.Endpoint{
+		endpointWithImages(1, []interface{}{
+			map[string]interface{}{
+				"RepoTags":    []interface{}{"nginx:latest", "nginx:1.19"},
+				"RepoDigests": []interface{}{"nginx@sha256:aaa"},
+			},
+			map[string]interface{}{
+				"RepoTags":    []interface{}{"myapp:dev"},
+				"RepoDigests": []interface{}{},
+			},
+		}),
+		endpointWithImages(2, []map[string][]string{
+			{
+				"RepoTags":    {"registry.local/nginx:latest"},
+				"RepoDigests": {"registry.local/nginx@sha256:aaa"},
+			},
+			{
+				"RepoTags":    {"<none>:<none>"},
+				"RepoDigests": {"redis@sha256:bbb"},
+			},
+		}),
+		{ID: 3},
+	}
+
+	images := collectImages(endpoints)
+
+	assert.Len(t, images, 2)
+	if assert.Contains(t, images, "sha256:aaa") {
+		assert.Equal(t, []string{"nginx@sha256:aaa", "registry.local/nginx@sha256:aaa"}, images["sha256:aaa"].references)
+		assert.Equal(t, []string{"nginx:1.19", "nginx:latest", "registry.local/nginx:latest"}, images["sha256:aaa"].tags)
+		assert.Equal(t, []portainer.EndpointID{1, 2}, images["sha256:aaa"].endpointIDs)
+	}
+	if assert.Contains(t, images, "sha256:bbb") {
+		assert.Equal(t, []string{}, images["sha256:bbb"].tags)
+	}
+}
+
+func Test_scanImages(t *testing.T) {
+	now := time.Now()
+	interval := 24 * time.Hour
+
+	store := testhelpers.NewDatastore(testhelpers.WithImageVulnerabilityReports([]portainer.ImageVulnerabilityReport{
+		{Digest: "sha256:fresh", Scanner: ScannerTrivy, ScannedAt: now.Add(-time.Hour).Unix(), References: []string{"alpine@sha256:fresh"}},
+		{Digest: "sha256:stale", Scanner: ScannerTrivy, ScannedAt: now.Add(-48 * time.Hour).Unix()},
+		{Digest: "sha256:failed", Scanner: ScannerTrivy, ScannedAt: now.Add(-2 * time.Hour).Unix(), Error: "timeout"},
+		{Digest: "sha256:removed", Scanner: ScannerTrivy, ScannedAt: now.Unix()},
+	}))
+
+	endpoints := []portainer.Endpoint{
+		endpointWithImages(1, []map[string][]string{
+			{"RepoDigests": {"alpine@sha256:fresh"}},
+			{"RepoDigests": {"redis@sha256:stale"}},
+			{"RepoDigests": {"nginx@sha256:failed"}},
+			{"RepoDigests": {"postgres@sha256:new"}},
+		}),
+	}
+
+	scanner := &fakeScanner{vulnerabilities: []portainer.ImageVulnerability{{ID: "CVE-1", Severity: portainer.VulnerabilitySeverityCritical}}}
+	service := &Service{dataStore: store, shutdownCtx: context.Background()}
+
+	err := service.scanImages(scanner, endpoints, nil, interval, time.Minute, now)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"nginx@sha256:failed", "postgres@sha256:new", "redis@sha256:stale"}, scanner.scanned)
+
+	_, err = store.ImageVulnerability().ImageVulnerabilityReport("sha256:removed")
+	assert.Error(t, err)
+
+	report, err := store.ImageVulnerability().ImageVulnerabilityReport("sha256:new")
+	assert.NoError(t, err)
+	assert.Equal(t, []portainer.EndpointID{1}, report.EndpointIDs)
+	assert.Equal(t, now.Unix(), report.ScannedAt)
+	assert.Equal(t, 1, report.Summary[portainer.VulnerabilitySeverityCritical])
+
+	report, err = store.ImageVulnerability().ImageVulnerabilityReport("sha256:failed")
+	assert.NoError(t, err)
+	assert.Equal(t, "", report.Error)
+}
+
+func Test_scanImages_KeepsVulnerabilitiesOnFailure(t *testing.T) {
+	now := time.Now()
+	previous := []portainer.ImageVulnerability{{ID: "CVE-1", Severity: portainer.VulnerabilitySeverityHigh}}
+
+	store := testhelpers.NewDatastore(testhelpers.WithImageVulnerabilityReports([]portainer.ImageVulnerabilityReport{
+		{Digest: "sha256:aaa", Scanner: ScannerGrype, Vulnerabilities: previous, Summary: Summarize(previous)},
+	}))
+
+	endpoints := []portainer.Endpoint{
+		endpointWithImages(1, []map[string][]string{{"RepoDigests": {"nginx@sha256:aaa"}}}),
+	}
+
+	scanner := &fakeScanner{err: errors.New("registry unavailable")}
+	service := &Service{dataStore: store, shutdownCtx: context.Background()}
+
+	err := service.scanImages(scanner, endpoints, nil, time.Hour, time.Minute, now)
+	assert.NoError(t, err)
+
+	report, err := store.ImageVulnerability().ImageVulnerabilityReport("sha256:aaa")
+	assert.NoError(t, err)
+	assert.Equal(t, "registry unavailable", report.Error)
+	assert.Equal(t, ScannerTrivy, report.Scanner)
+	assert.Equal(t, previous, report.Vulnerabilities)
+}
@@ -0,0 +1,326 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/logging"
+	"github.com/portainer/portainer/api/kubernetes/cli"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+var logger = logging.Component("kubernetes")
+
+// listPageSize is the number of resources retrieved per request when listing the resources of a cluster
+const listPageSize = 500
+
+// Snapshotter represents a service used to create Kubernetes endpoint snapshots
+type Snapshotter struct {
+	clientFactory *cli.ClientFactory
+}
+
+// NewSnapshotter returns a new Snapshotter instance
+func NewSnapshotter(clientFactory *cli.ClientFactory) *Snapshotter {
+	return &Snapshotter{
+		clientFactory: clientFactory,
+	}
+}
+
+// CreateSnapshot creates a snapshot of a specific Kubernetes endpoint. The requests to the endpoint are
+// cancelled when the context is done.
+func (snapshotter *Snapshotter) CreateSnapshot(ctx context.Context, endpoint *portainer.Endpoint) (*portainer.KubernetesSnapshot, error) {
+	client, err := snapshotter.clientFactory.CreateClient(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot(ctx, client, endpoint)
+}
+
+// snapshotPart collects a part of the snapshot of a cluster
+type snapshotPart struct {
+	name    string
+	collect func(ctx context.Context, snapshot *portainer.KubernetesSnapshot, cli *kubernetes.Clientset) error
+}
+
+var snapshotParts = []snapshotPart{
+	{name: "version", collect: snapshotVersion},
+	{name: "nodes", collect: snapshotNodes},
+	{name: "namespaces", collect: snapshotNamespaces},
+	{name: "workloads", collect: snapshotWorkloads},
+	{name: "pods", collect: snapshotPods},
+	{name: "persistent volume claims", collect: snapshotPersistentVolumeClaims},
+	{name: "events", collect: snapshotWarningEvents},
+}
+
+func snapshot(ctx context.Context, cli *kubernetes.Clientset, endpoint *portainer.Endpoint) (*portainer.KubernetesSnapshot, error) {
+	res := cli.RESTClient().Get().AbsPath("/healthz").Context(ctx).Do()
+	if res.Error() != nil {
+		return nil, res.Error()
+	}
+
+	snapshot := &portainer.KubernetesSnapshot{}
+
+	for _, part := range snapshotParts {
+		err := part.collect(ctx, snapshot, cli)
+		if err != nil {
+			logger.WithContext(ctx).WithField(logging.FieldEndpointID, endpoint.ID).WithField("part", part.name).WithError(err).Warn("unable to snapshot a part of the cluster")
+			addSnapshotError(snapshot, part.name, err)
+		}
+	}
+
+	// the requests failing once the context is done leave the snapshot incomplete
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	snapshot.Time = time.Now().Unix()
+	return snapshot, nil
+}
+
+// addSnapshotError records the error of a part of the snapshot which could not be collected,
+// the counts of this part are left to zero
+func addSnapshotError(snapshot *portainer.KubernetesSnapshot, part string, err error) {
+	if snapshot.Errors == nil {
+		snapshot.Errors = make(map[string]string)
+	}
+	snapshot.Errors[part] = err.Error()
+}
+
+// listPage is a page of a list of resources
+type listPage interface {
+	runtime.Object
+	GetContinue() string
+}
+
+// list retrieves a list of resources page by page within the context of the snapshot and hands each page
+// to the visit function. The typed clients of this version of client-go do not accept a context.
+func list(ctx context.Context, client rest.Interface, resource string, options metav1.ListOptions, newPage func() listPage, visit func(page listPage)) error {
+	options.Limit = listPageSize
+
+	for {
+		page := newPage()
+		err := client.Get().
+			Context(ctx).
+			Resource(resource).
+			VersionedParams(&options, scheme.ParameterCodec).
+			Do().
+			Into(page)
+		if err != nil {
+			return err
+		}
+
+		visit(page)
+
+		options.Continue = page.GetContinue()
+		if options.Continue == "" {
+			return nil
+		}
+	}
+}
+
+func snapshotVersion(ctx context.Context, snapshot *portainer.KubernetesSnapshot, cli *kubernetes.Clientset) error {
+	body, err := cli.Discovery().RESTClient().Get().AbsPath("/version").Context(ctx).Do().Raw()
+	if err != nil {
+		return err
+	}
+
+	var versionInfo version.Info
+	err = json.Unmarshal(body, &versionInfo)
+	if err != nil {
+		return err
+	}
+
+	snapshot.KubernetesVersion = versionInfo.GitVersion
+	return nil
+}
+
+func snapshotNodes(ctx context.Context, snapshot *portainer.KubernetesSnapshot, cli *kubernetes.Clientset) error {
+	var nodeCount, readyNodeCount, notReadyNodeCount int
+	var totalCPUs, totalMemory int64
+
+	err := list(ctx, cli.CoreV1().RESTClient(), "nodes", metav1.ListOptions{}, func() listPage { return &corev1.NodeList{} }, func(page listPage) {
+		for _, node := range page.(*corev1.NodeList).Items {
+			nodeCount++
+			totalCPUs += node.Status.Capacity.Cpu().Value()
+			totalMemory += node.Status.Capacity.Memory().Value()
+
+			if isNodeReady(&node) {
+				readyNodeCount++
+			} else {
+				notReadyNodeCount++
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	snapshot.NodeCount = nodeCount
+	snapshot.ReadyNodeCount = readyNodeCount
+	snapshot.NotReadyNodeCount = notReadyNodeCount
+	snapshot.TotalCPU = totalCPUs
+	snapshot.TotalMemory = totalMemory
+	return nil
+}
+
+// isNodeReady returns true when the Ready condition of the node is true,
+// a node without a Ready condition is considered as not ready
+func isNodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func snapshotNamespaces(ctx context.Context, snapshot *portainer.KubernetesSnapshot, cli *kubernetes.Clientset) error {
+	namespaceCount := 0
+
+	err := list(ctx, cli.CoreV1().RESTClient(), "namespaces", metav1.ListOptions{}, func() listPage { return &corev1.NamespaceList{} }, func(page listPage) {
+		namespaceCount += len(page.(*corev1.NamespaceList).Items)
+	})
+	if err != nil {
+		return err
+	}
+
+	snapshot.NamespaceCount = namespaceCount
+	return nil
+}
+
+func snapshotWorkloads(ctx context.Context, snapshot *portainer.KubernetesSnapshot, cli *kubernetes.Clientset) error {
+	var deployments, statefulSets, daemonSets portainer.KubernetesWorkloadSnapshot
+
+	err := list(ctx, cli.AppsV1().RESTClient(), "deployments", metav1.ListOptions{}, func() listPage { return &appsv1.DeploymentList{} }, func(page listPage) {
+		for _, deployment := range page.(*appsv1.DeploymentList).Items {
+			addWorkload(&deployments, desiredReplicas(deployment.Spec.Replicas), int(deployment.Status.ReadyReplicas))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = list(ctx, cli.AppsV1().RESTClient(), "statefulsets", metav1.ListOptions{}, func() listPage { return &appsv1.StatefulSetList{} }, func(page listPage) {
+		for _, statefulSet := range page.(*appsv1.StatefulSetList).Items {
+			addWorkload(&statefulSets, desiredReplicas(statefulSet.Spec.Replicas), int(statefulSet.Status.ReadyReplicas))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = list(ctx, cli.AppsV1().RESTClient(), "daemonsets", metav1.ListOptions{}, func() listPage { return &appsv1.DaemonSetList{} }, func(page listPage) {
+		for _, daemonSet := range page.(*appsv1.DaemonSetList).Items {
+			addWorkload(&daemonSets, int(daemonSet.Status.DesiredNumberScheduled), int(daemonSet.Status.NumberReady))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	snapshot.Deployments = deployments
+	snapshot.StatefulSets = statefulSets
+	snapshot.DaemonSets = daemonSets
+	return nil
+}
+
+// desiredReplicas returns the number of replicas of a workload spec, which defaults to 1 when unset
+func desiredReplicas(replicas *int32) int {
+	if replicas == nil {
+		return 1
+	}
+	return int(*replicas)
+}
+
+func addWorkload(workloads *portainer.KubernetesWorkloadSnapshot, desired, ready int) {
+	workloads.Count++
+	workloads.DesiredReplicas += desired
+	workloads.ReadyReplicas += ready
+
+	if ready >= desired {
+		workloads.ReadyCount++
+	}
+}
+
+func snapshotPods(ctx context.Context, snapshot *portainer.KubernetesSnapshot, cli *kubernetes.Clientset) error {
+	var pods portainer.KubernetesPodSnapshot
+
+	err := list(ctx, cli.CoreV1().RESTClient(), "pods", metav1.ListOptions{}, func() listPage { return &corev1.PodList{} }, func(page listPage) {
+		for _, pod := range page.(*corev1.PodList).Items {
+			addPod(&pods, pod.Status.Phase)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	snapshot.Pods = pods
+	return nil
+}
+
+func addPod(pods *portainer.KubernetesPodSnapshot, phase corev1.PodPhase) {
+	switch phase {
+	case corev1.PodPending:
+		pods.Pending++
+	case corev1.PodRunning:
+		pods.Running++
+	case corev1.PodSucceeded:
+		pods.Succeeded++
+	case corev1.PodFailed:
+		pods.Failed++
+	default:
+		pods.Unknown++
+	}
+}
+
+func snapshotPersistentVolumeClaims(ctx context.Context, snapshot *portainer.KubernetesSnapshot, cli *kubernetes.Clientset) error {
+	var claimCount, boundClaimCount int
+	var boundStorageCapacity int64
+
+	err := list(ctx, cli.CoreV1().RESTClient(), "persistentvolumeclaims", metav1.ListOptions{}, func() listPage { return &corev1.PersistentVolumeClaimList{} }, func(page listPage) {
+		for _, claim := range page.(*corev1.PersistentVolumeClaimList).Items {
+			claimCount++
+			if claim.Status.Phase != corev1.ClaimBound {
+				continue
+			}
+
+			boundClaimCount++
+			if capacity, ok := claim.Status.Capacity[corev1.ResourceStorage]; ok {
+				boundStorageCapacity += capacity.Value()
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	snapshot.PersistentVolumeClaimCount = claimCount
+	snapshot.BoundPersistentVolumeClaimCount = boundClaimCount
+	snapshot.BoundStorageCapacity = boundStorageCapacity
+	return nil
+}
+
+func snapshotWarningEvents(ctx context.Context, snapshot *portainer.KubernetesSnapshot, cli *kubernetes.Clientset) error {
+	warningEventCount := 0
+
+	options := metav1.ListOptions{FieldSelector: "type=" + corev1.EventTypeWarning}
+	err := list(ctx, cli.CoreV1().RESTClient(), "events", options, func() listPage { return &corev1.EventList{} }, func(page listPage) {
+		warningEventCount += len(page.(*corev1.EventList).Items)
+	})
+	if err != nil {
+		return err
+	}
+
+	snapshot.WarningEventCount = warningEventCount
+	return nil
+}
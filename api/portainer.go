@@ -1,7 +1,9 @@
 package portainer
 
 import (
+	"context"
 	"io"
+	"net/http"
 	"time"
 
 	gittypes "github.com/portainer/portainer/api/git/types"
@@ -61,6 +63,8 @@ type (
 		SSLCert                   *string
 		SSLKey                    *string
 		SnapshotInterval          *string
+		LogLevel                  *string
+		LogFormat                 *string
 	}
 
 	// CustomTemplate represents a custom template
@@ -95,6 +99,39 @@ type (
 	// CustomTemplatePlatform represents a custom template platform
 	CustomTemplatePlatform int
 
+	// DeploymentJob represents an asynchronous stack deployment operation and the output it produced
+	DeploymentJob struct {
+		// DeploymentJob Identifier
+		ID DeploymentJobID `json:"Id" example:"1"`
+		// Type of the operation (1 - create, 2 - update, 3 - git redeploy, 4 - migrate)
+		Type DeploymentJobType `json:"Type" example:"1"`
+		// Stack identifier
+		StackID StackID `json:"StackId" example:"1"`
+		// Endpoint identifier where the stack is deployed
+		EndpointID EndpointID `json:"EndpointId" example:"1"`
+		// Status of the job (1 - pending, 2 - running, 3 - success, 4 - error)
+		Status DeploymentJobStatus `json:"Status" example:"2"`
+		// Output of the deployment, one entry per line
+		Output []string `json:"Output"`
+		// Error message when the deployment failed
+		Error string `json:"Error" example:"network not found"`
+		// Username of the user who started the job
+		CreatedBy string `json:"CreatedBy" example:"admin"`
+		// Job creation date
+		CreationDate int64 `json:"CreationDate" example:"1587399600"`
+		// Job completion date
+		EndDate int64 `json:"EndDate" example:"1587399600"`
+	}
+
+	// DeploymentJobID represents a deployment job identifier
+	DeploymentJobID int
+
+	// DeploymentJobStatus represents the status of a deployment job
+	DeploymentJobStatus int
+
+	// DeploymentJobType represents the stack operation executed by a deployment job
+	DeploymentJobType int
+
 	// DockerHub represents all the required information to connect and use the
 	// Docker Hub
 	DockerHub struct {
@@ -144,11 +181,66 @@ type (
 		TagIDs       []TagID      `json:"TagIds"`
 		Endpoints    []EndpointID `json:"Endpoints"`
 		PartialMatch bool         `json:"PartialMatch"`
+		// Selector expression matching the endpoints of a dynamic Edge group on their attributes, used instead of the tags when set
+		Selector string `json:"Selector" example:"tag == production and platform == docker and checkin_age < 1h"`
 	}
 
 	// EdgeGroupID represents an Edge group identifier
 	EdgeGroupID int
 
+	// EdgeEnrollmentToken represents a reusable token allowing Edge agents to register themselves as new endpoints
+	EdgeEnrollmentToken struct {
+		// EdgeEnrollmentToken Identifier
+		ID EdgeEnrollmentTokenID `json:"Id" example:"1"`
+		// Name of the token
+		Name string `json:"Name" example:"factory-golden-image"`
+		// Secret value presented by the Edge agents when they register, only returned when the token is created
+		Token string `json:"Token,omitempty" example:"2c0f7e2e-2f2d-4b8a-9a3f-6d1b3a3c9f5e"`
+		// SHA-256 hash of the secret value, used to find the token when an Edge agent registers
+		TokenHash string `json:"TokenHash,omitempty"`
+		// Last characters of the secret value, used to identify the token once its secret value is masked
+		TokenHint string `json:"TokenHint" example:"9f5e"`
+		// URL of the Portainer instance encoded in the Edge key of the registered endpoints
+		PortainerURL string `json:"PortainerURL" example:"https://portainer.mydomain.tld"`
+		// The date in unix time after which the token is rejected, 0 when the token does not expire
+		ExpiryDate int64 `json:"ExpiryDate" example:"1587399600"`
+		// Maximum number of endpoints registered with the token, 0 when unlimited
+		UsageLimit int `json:"UsageLimit" example:"100"`
+		// Number of endpoints registered with the token
+		UsageCount int `json:"UsageCount" example:"12"`
+		// Endpoint group of the registered endpoints
+		GroupID EndpointGroupID `json:"GroupId" example:"1"`
+		// List of tags associated to the registered endpoints
+		TagIDs []TagID `json:"TagIds"`
+		// List of static Edge groups the registered endpoints are added to
+		EdgeGroups []EdgeGroupID `json:"EdgeGroups"`
+		// The date in unix time when the token was created
+		Created int64 `json:"Created" example:"1587399600"`
+	}
+
+	// EdgeEnrollmentTokenID represents an Edge enrollment token identifier
+	EdgeEnrollmentTokenID int
+
+	// EdgeHeartbeat represents the agent version and host metrics reported by an Edge agent on check-in
+	EdgeHeartbeat struct {
+		// Version of the Edge agent
+		AgentVersion string `json:"AgentVersion" example:"2.4.0"`
+		// Uptime of the host in seconds
+		Uptime                int64 `json:"Uptime" example:"86400"`
+		RunningContainerCount int   `json:"RunningContainerCount" example:"5"`
+		StoppedContainerCount int   `json:"StoppedContainerCount" example:"1"`
+		// Used disk space of the host in bytes
+		DiskUsed int64 `json:"DiskUsed" example:"10737418240"`
+		// Total disk space of the host in bytes
+		DiskTotal int64 `json:"DiskTotal" example:"32212254720"`
+		// Used memory of the host in bytes
+		MemoryUsed int64 `json:"MemoryUsed" example:"1073741824"`
+		// Total memory of the host in bytes
+		MemoryTotal int64 `json:"MemoryTotal" example:"4294967296"`
+		// The date in unix time when the heartbeat was received
+		Time int64 `json:"Time" example:"1587399600"`
+	}
+
 	// EdgeJob represents a job that can run on Edge environments.
 	EdgeJob struct {
 		// EdgeJob Identifier
@@ -160,13 +252,56 @@ type (
 		ScriptPath     string                             `json:"ScriptPath"`
 		Recurring      bool                               `json:"Recurring"`
 		Version        int                                `json:"Version"`
+		// Number of times a failed run is retried on an endpoint
+		RetryCount int `json:"RetryCount" example:"3"`
+		// Number of minutes to wait before retrying a failed run
+		RetryInterval int `json:"RetryInterval" example:"5"`
+		// Number of runs whose results are kept, EdgeJobDefaultResultRetention when 0
+		ResultRetention int `json:"ResultRetention" example:"10"`
+		// Number of the last run of the job, shared by the results the endpoints report for the same run
+		Run int `json:"Run" example:"3"`
+		// Run started at each date scheduled by the cron expression, for the runs whose results are kept
+		ScheduledRuns map[int64]int `json:"ScheduledRuns"`
 	}
 
 	// EdgeJobEndpointMeta represents a meta data object for an Edge job and Endpoint relation
 	EdgeJobEndpointMeta struct {
 		LogsStatus  EdgeJobLogsStatus
 		CollectLogs bool
-	}
+		// Number of the last run of the job reported by the endpoint, 0 before the first run
+		Run int
+		// Number of attempts of the last run
+		Attempt int
+		// The date in unix time when the last run is retried, 0 when no retry is scheduled
+		RetryDate int64
+	}
+
+	// EdgeJobResult represents the result of a run of an Edge job on an endpoint
+	EdgeJobResult struct {
+		// EdgeJobResult Identifier
+		ID EdgeJobResultID `json:"Id" example:"1"`
+		// Identifier of the Edge job
+		EdgeJobID EdgeJobID `json:"EdgeJobId" example:"1"`
+		// Identifier of the endpoint the job ran on
+		EndpointID EndpointID `json:"EndpointId" example:"1"`
+		// Number of the run of the Edge job
+		Run int `json:"Run" example:"3"`
+		// Attempt of the run, 1 for the first attempt and above for the retries
+		Attempt int `json:"Attempt" example:"1"`
+		// The date in unix time the run was scheduled at, 0 when not reported by the endpoint
+		ScheduledDate int64 `json:"ScheduledDate" example:"1587399600"`
+		// Exit code of the script
+		ExitCode int `json:"ExitCode" example:"0"`
+		// Duration of the run in milliseconds
+		Duration int64 `json:"Duration" example:"1500"`
+		// Output of the script, truncated to its last EdgeJobResultMaxOutputSize bytes
+		Output string `json:"Output"`
+		// The date in unix time when the result was reported
+		Date int64 `json:"Date" example:"1587399600"`
+	}
+
+	// EdgeJobResultID represents an Edge job result identifier
+	EdgeJobResultID int
 
 	// EdgeJobID represents an Edge job identifier
 	EdgeJobID int
@@ -197,11 +332,116 @@ type (
 		EntryPoint   string                         `json:"EntryPoint"`
 		Version      int                            `json:"Version"`
 		Prune        bool                           `json:"Prune"`
+		Rollout      *EdgeStackRollout              `json:"Rollout"`
+		// Previous versions of the edge stack whose files are kept, from the oldest to the most recent one
+		PreviousVersions []int `json:"PreviousVersions"`
+		// Send the endpoints reporting an error back to the last version they deployed successfully
+		AutoRollback bool `json:"AutoRollback"`
+		// Version deployed on each endpoint
+		EndpointDeployments map[EndpointID]EdgeStackEndpointDeployment `json:"EndpointDeployments"`
+		// The git config of this edge stack, nil when the edge stack is not created from git
+		GitConfig *gittypes.RepoConfig `json:"GitConfig"`
+		// Automatic update of the edge stack when a new commit is pushed to its git repository
+		AutoUpdate *EdgeStackAutoUpdate `json:"AutoUpdate"`
+		// Variables of the stack file resolved for each endpoint when the stack file is retrieved by the agent
+		Variables []EdgeStackVariable `json:"Variables"`
+	}
+
+	// EdgeStackAutoUpdate represents how a git edge stack follows the new commits of its repository
+	EdgeStackAutoUpdate struct {
+		// Interval between two checks of the repository, empty to disable polling
+		Interval string `json:"Interval" example:"5m"`
+		// Token of the webhook triggering the update of the edge stack, empty to disable the webhook
+		Webhook string `json:"Webhook" example:"05de31a2-79fa-4644-9c12-faa67e5c49f0"`
+	}
+
+	// EdgeStackEndpointDeployment represents the versions of an edge stack deployed on an endpoint
+	EdgeStackEndpointDeployment struct {
+		// Version last reported by the endpoint
+		Version int `json:"Version" example:"3"`
+		// Status last reported by the endpoint
+		Status EdgeStackStatusType `json:"Status" example:"1"`
+		// Last version successfully deployed on the endpoint
+		LastOkVersion int `json:"LastOkVersion" example:"2"`
+		// Version that failed on the endpoint and triggered a rollback
+		FailedVersion int `json:"FailedVersion" example:"3"`
+		// Version the endpoint was sent back to after FailedVersion failed, 0 when no rollback happened
+		RollbackVersion int `json:"RollbackVersion" example:"2"`
+		// The date in unix time when the endpoint last reported its status
+		UpdateDate int64 `json:"UpdateDate" example:"1587399600"`
 	}
 
 	//EdgeStackID represents an edge stack id
 	EdgeStackID int
 
+	// EdgeStackRollout represents the staged rollout of a new version of an edge stack.
+	// The endpoints not reached by the rollout yet keep running the previous version.
+	EdgeStackRollout struct {
+		// Rollout strategy used to select the first batch of endpoints
+		Strategy EdgeStackRolloutStrategy `json:"Strategy" example:"1"`
+		// Percentage of the edge stack endpoints deployed in the first batch (canary strategy)
+		CanaryPercentage int `json:"CanaryPercentage" example:"10"`
+		// Endpoints deployed in the first batch (subset strategy)
+		CanaryEndpoints []EndpointID `json:"CanaryEndpoints"`
+		// Number of endpoints deployed in each batch following the first one, 0 to deploy all the remaining endpoints at once
+		BatchSize int `json:"BatchSize" example:"5"`
+		// Percentage of the deployed endpoints reporting an error above which the rollout is stopped
+		ErrorThreshold int `json:"ErrorThreshold" example:"20"`
+		// Time in seconds the endpoints of a batch have to report their status, 0 for the default of one hour.
+		// The endpoints not reporting in time count as failed
+		BatchTimeout int `json:"BatchTimeout" example:"3600"`
+		// The date in unix time when the current batch was deployed
+		BatchStartDate int64 `json:"BatchStartDate" example:"1587399600"`
+		// Current status of the rollout
+		Status EdgeStackRolloutStatus `json:"Status" example:"1"`
+		// Version of the edge stack deployed to the endpoints reached by the rollout
+		Version int `json:"Version" example:"3"`
+		// Version of the edge stack kept on the endpoints not reached by the rollout
+		PreviousVersion int `json:"PreviousVersion" example:"2"`
+		// Endpoints reached by the rollout, in deployment order
+		DeployedEndpoints []EndpointID `json:"DeployedEndpoints"`
+		// Number of batches deployed
+		Batches int `json:"Batches" example:"2"`
+		// Reason why the rollout was stopped
+		Error string `json:"Error"`
+		// The date in unix time when the rollout was started
+		StartDate int64 `json:"StartDate" example:"1587399600"`
+		// The date in unix time when the rollout status was last updated
+		UpdateDate int64 `json:"UpdateDate" example:"1587399600"`
+	}
+
+	// EdgeStackRolloutStatus represents the status of an edge stack rollout
+	EdgeStackRolloutStatus int
+
+	// EdgeStackRolloutStrategy represents the strategy used to select the first batch of an edge stack rollout
+	EdgeStackRolloutStrategy int
+
+	// EdgeStackVariable represents a variable of an edge stack file whose value can be set per endpoint, tag or edge group
+	EdgeStackVariable struct {
+		// Name of the variable, as used in the stack file
+		Name string `json:"Name" example:"SITE_ID"`
+		// Value used when no value is set for the endpoint
+		DefaultValue string `json:"DefaultValue" example:"default"`
+		// Whether a value must be resolved for each endpoint, either from Values or from DefaultValue
+		Required bool `json:"Required" example:"true"`
+		// Values set per endpoint, tag or edge group. The endpoint values take precedence over the tag values,
+		// which take precedence over the edge group values
+		Values []EdgeStackVariableValue `json:"Values"`
+	}
+
+	// EdgeStackVariableValue represents the value of an edge stack variable for an endpoint, a tag or an edge group
+	EdgeStackVariableValue struct {
+		// Scope of the value
+		Scope EdgeStackVariableScope `json:"Scope" example:"1"`
+		// Identifier of the endpoint, tag or edge group, depending on the scope
+		ScopeID int `json:"ScopeId" example:"1"`
+		// Value of the variable
+		Value string `json:"Value" example:"site-1"`
+	}
+
+	// EdgeStackVariableScope represents what an edge stack variable value is set for
+	EdgeStackVariableScope int
+
 	//EdgeStackStatus represents an edge stack status
 	EdgeStackStatus struct {
 		Type       EdgeStackStatusType `json:"Type"`
@@ -250,10 +490,22 @@ type (
 		Kubernetes KubernetesData `json:"Kubernetes" example:""`
 		// Maximum version of docker-compose
 		ComposeSyntaxMaxVersion string `json:"ComposeSyntaxMaxVersion" example:"3.8"`
+		// Whether unversioned compose specification files can be deployed
+		ComposeSpecSupported bool `json:"ComposeSpecSupported" example:"false"`
 		// Endpoint specific security settings
 		SecuritySettings EndpointSecuritySettings
 		// LastCheckInDate mark last check-in date on checkin
 		LastCheckInDate int64
+		// The interval in which snapshots of this endpoint are created, empty to use the interval defined in the settings
+		SnapshotInterval string `json:"SnapshotInterval" example:"1m"`
+		// Outcome of the latest contacts with the endpoint
+		Health EndpointHealth `json:"Health"`
+		// Whether the Edge endpoint registered itself with an enrollment token and waits for the approval of an administrator
+		EdgePending bool `json:"EdgePending" example:"false"`
+		// Identifier of the enrollment token the Edge endpoint registered itself with, 0 when created by an administrator
+		EdgeEnrollmentTokenID EdgeEnrollmentTokenID `json:"EdgeEnrollmentTokenId" example:"0"`
+		// Last heartbeat reported by the Edge agent on check-in
+		EdgeHeartbeat *EdgeHeartbeat `json:"EdgeHeartbeat,omitempty"`
 
 		// Deprecated fields
 		// Deprecated in DBVersion == 4
@@ -273,6 +525,30 @@ type (
 	// EndpointAuthorizations represents the authorizations associated to a set of endpoints
 	EndpointAuthorizations map[EndpointID]Authorizations
 
+	// EndpointHealth represents the outcome of the latest contacts with an endpoint
+	EndpointHealth struct {
+		// Error returned by the latest failed contact with the endpoint
+		LastError string `json:"LastError" example:"Cannot connect to the Docker daemon"`
+		// Unix timestamp of the latest failed contact with the endpoint
+		LastErrorDate int64 `json:"LastErrorDate" example:"1587399600"`
+		// Unix timestamp of the latest successful contact with the endpoint
+		LastSuccessDate int64 `json:"LastSuccessDate" example:"1587399600"`
+		// Number of failed contacts since the latest successful one
+		ConsecutiveFailures int `json:"ConsecutiveFailures" example:"0"`
+		// Latest status changes of the endpoint, ordered from the oldest to the most recent one
+		StatusHistory []EndpointStatusChange `json:"StatusHistory"`
+	}
+
+	// EndpointStatusChange represents a change of the status of an endpoint
+	EndpointStatusChange struct {
+		// The new status of the endpoint (1 - up, 2 - down)
+		Status EndpointStatus `json:"Status" example:"2"`
+		// Unix timestamp of the change
+		Date int64 `json:"Date" example:"1587399600"`
+		// Why the status changed
+		Reason string `json:"Reason" example:"Cannot connect to the Docker daemon"`
+	}
+
 	// EndpointExtension represents a deprecated form of Portainer extension
 	// TODO: legacy extension management
 	EndpointExtension struct {
@@ -389,6 +665,46 @@ type (
 		OrganisationName string `json:"OrganisationName"`
 	}
 
+	// ImageVulnerability represents a known vulnerability found in a package of an image
+	ImageVulnerability struct {
+		// Identifier of the vulnerability
+		ID string `json:"Id" example:"CVE-2021-3449"`
+		// Name of the vulnerable package
+		PackageName string `json:"PackageName" example:"openssl"`
+		// Version of the package installed in the image
+		InstalledVersion string `json:"InstalledVersion" example:"1.1.1j-r0"`
+		// Version of the package fixing the vulnerability, empty when no fix is available
+		FixedVersion string `json:"FixedVersion" example:"1.1.1k-r0"`
+		// Severity of the vulnerability
+		Severity VulnerabilitySeverity `json:"Severity" example:"HIGH" enums:"UNKNOWN,LOW,MEDIUM,HIGH,CRITICAL"`
+		// Short description of the vulnerability
+		Title string `json:"Title" example:"openssl: NULL pointer dereference in signature_algorithms processing"`
+		// Link to the details of the vulnerability
+		URL string `json:"URL" example:"https://avd.aquasec.com/nvd/cve-2021-3449"`
+	}
+
+	// ImageVulnerabilityReport represents the result of the vulnerability scan of an image digest
+	ImageVulnerabilityReport struct {
+		// Digest of the image
+		Digest string `json:"Digest" example:"sha256:75a55d33ecc73c2a242450a9f1cc858499d468f077ea942867e662c247b5e412"`
+		// Repository digests the image is known as, such as nginx@sha256:75a5...
+		References []string `json:"References"`
+		// Tags the image is known as on the endpoints, such as nginx:latest
+		Tags []string `json:"Tags"`
+		// Endpoints the image was found on during the latest scan
+		EndpointIDs []EndpointID `json:"EndpointIds"`
+		// Name of the scanner used to scan the image
+		Scanner string `json:"Scanner" example:"trivy"`
+		// Unix timestamp of the latest scan
+		ScannedAt int64 `json:"ScannedAt" example:"1587399600"`
+		// Error returned by the latest scan, the vulnerabilities of the previous successful scan are kept
+		Error string `json:"Error" example:""`
+		// Number of vulnerabilities found for each severity
+		Summary map[VulnerabilitySeverity]int `json:"Summary"`
+		// Vulnerabilities found in the image
+		Vulnerabilities []ImageVulnerability `json:"Vulnerabilities"`
+	}
+
 	// JobType represents a job type
 	JobType int
 
@@ -410,6 +726,46 @@ type (
 		NodeCount         int    `json:"NodeCount"`
 		TotalCPU          int64  `json:"TotalCPU"`
 		TotalMemory       int64  `json:"TotalMemory"`
+		// Number of nodes whose Ready condition is true
+		ReadyNodeCount int `json:"ReadyNodeCount" example:"3"`
+		// Number of nodes whose Ready condition is false or unknown
+		NotReadyNodeCount int                        `json:"NotReadyNodeCount" example:"0"`
+		NamespaceCount    int                        `json:"NamespaceCount" example:"5"`
+		Deployments       KubernetesWorkloadSnapshot `json:"Deployments"`
+		StatefulSets      KubernetesWorkloadSnapshot `json:"StatefulSets"`
+		DaemonSets        KubernetesWorkloadSnapshot `json:"DaemonSets"`
+		Pods              KubernetesPodSnapshot      `json:"Pods"`
+		// Number of persistent volume claims
+		PersistentVolumeClaimCount int `json:"PersistentVolumeClaimCount" example:"4"`
+		// Number of persistent volume claims bound to a volume
+		BoundPersistentVolumeClaimCount int `json:"BoundPersistentVolumeClaimCount" example:"3"`
+		// Storage capacity of the bound persistent volume claims (in bytes)
+		BoundStorageCapacity int64 `json:"BoundStorageCapacity" example:"32212254720"`
+		// Number of warning events reported by the cluster
+		WarningEventCount int `json:"WarningEventCount" example:"2"`
+		// Errors of the parts of the snapshot which could not be collected, by part
+		Errors map[string]string `json:"Errors,omitempty"`
+	}
+
+	// KubernetesWorkloadSnapshot represents the state of the workloads of a kind (deployments, statefulsets or daemonsets) in a Kubernetes snapshot
+	KubernetesWorkloadSnapshot struct {
+		// Number of workloads
+		Count int `json:"Count" example:"6"`
+		// Number of workloads whose replicas are all ready
+		ReadyCount int `json:"ReadyCount" example:"5"`
+		// Total number of replicas desired by the workloads
+		DesiredReplicas int `json:"DesiredReplicas" example:"12"`
+		// Total number of ready replicas of the workloads
+		ReadyReplicas int `json:"ReadyReplicas" example:"11"`
+	}
+
+	// KubernetesPodSnapshot represents the number of pods by phase in a Kubernetes snapshot
+	KubernetesPodSnapshot struct {
+		Pending   int `json:"Pending" example:"0"`
+		Running   int `json:"Running" example:"14"`
+		Succeeded int `json:"Succeeded" example:"2"`
+		Failed    int `json:"Failed" example:"1"`
+		Unknown   int `json:"Unknown" example:"0"`
 	}
 
 	// KubernetesConfiguration represents the configuration of a Kubernetes endpoint
@@ -473,6 +829,24 @@ type (
 		AutoCreateUsers bool `json:"AutoCreateUsers" example:"true"`
 	}
 
+	// LabelFilter represents a rule used to hide Docker resources based on their labels
+	LabelFilter struct {
+		// Pattern matched against the label name
+		Name string `json:"Name" example:"com.docker.compose.project"`
+		// Pattern matched against the label value. An empty value matches any value
+		Value string `json:"Value" example:"system-*"`
+		// How the Name and Value patterns are matched. Valid values are: 1 - exact, 2 - glob or 3 - regular expression
+		MatchType LabelFilterMatchType `json:"MatchType" example:"2" enums:"1,2,3"`
+		// List of endpoint group identifiers where the rule applies. The rule applies to every endpoint when empty
+		EndpointGroupIDs []EndpointGroupID `json:"EndpointGroupIds"`
+		// List of resource types hidden by the rule. Valid values are: 1 - container, 2 - service, 3 - volume or 4 - network.
+		// The rule applies to all of them when empty
+		ResourceTypes []ResourceControlType `json:"ResourceTypes"`
+	}
+
+	// LabelFilterMatchType represents the way a label filter pattern is matched
+	LabelFilterMatchType int
+
 	// LicenseInformation represents information about an extension license
 	LicenseInformation struct {
 		LicenseKey string `json:"LicenseKey,omitempty"`
@@ -484,6 +858,100 @@ type (
 	// MembershipRole represents the role of a user within a team
 	MembershipRole int
 
+	// NotificationChannel represents a destination notifications about platform events are sent to
+	NotificationChannel struct {
+		// Notification channel Identifier
+		ID NotificationChannelID `json:"Id" example:"1"`
+		// Notification channel name
+		Name string `json:"Name" example:"ops-team"`
+		// Notification channel type (1 - HTTP webhook, 2 - email, 3 - Slack/Teams compatible incoming webhook)
+		Type NotificationChannelType `json:"Type" example:"1"`
+		// Whether notifications are sent to this channel
+		Enabled bool `json:"Enabled" example:"true"`
+		// URL of the webhook, for webhook and Slack/Teams compatible channels
+		URL string `json:"URL,omitempty" example:"https://hooks.mydomain.tld/portainer"`
+		// Go template used to render the body of the webhook, the event is sent as JSON when empty
+		BodyTemplate string `json:"BodyTemplate,omitempty" example:"{\"text\": \"{{ .Message }}\"}"`
+		// SMTP settings, for email channels
+		Email *NotificationEmailSettings `json:"Email,omitempty"`
+		// Types of the events sent to this channel, all the events are sent when empty
+		EventTypes []NotificationEventType `json:"EventTypes"`
+		// Endpoint groups the events are sent for, the events of all the endpoint groups are sent when empty.
+		// Events not related to an endpoint are always sent
+		EndpointGroupIDs []EndpointGroupID `json:"EndpointGroupIds"`
+		// Unix timestamp of the creation of the channel
+		Created int64 `json:"Created" example:"1587399600"`
+	}
+
+	// NotificationChannelID represents a notification channel identifier
+	NotificationChannelID int
+
+	// NotificationChannelType represents the type of a notification channel
+	NotificationChannelType int
+
+	// NotificationDelivery represents an attempt to send an event to a notification channel
+	NotificationDelivery struct {
+		// Notification delivery Identifier
+		ID NotificationDeliveryID `json:"Id" example:"1"`
+		// Identifier of the channel the event is sent to
+		ChannelID NotificationChannelID `json:"ChannelId" example:"1"`
+		// The event sent to the channel
+		Event NotificationEvent `json:"Event"`
+		// Status of the delivery (1 - pending, 2 - delivered, 3 - failed)
+		Status NotificationDeliveryStatus `json:"Status" example:"2"`
+		// Number of attempts made to send the event
+		Attempts int `json:"Attempts" example:"1"`
+		// Error returned by the latest failed attempt
+		LastError string `json:"LastError" example:""`
+		// Unix timestamp of the creation of the delivery
+		Created int64 `json:"Created" example:"1587399600"`
+		// Unix timestamp of the latest attempt
+		Updated int64 `json:"Updated" example:"1587399600"`
+	}
+
+	// NotificationDeliveryID represents a notification delivery identifier
+	NotificationDeliveryID int
+
+	// NotificationDeliveryStatus represents the status of a notification delivery
+	NotificationDeliveryStatus int
+
+	// NotificationEmailSettings represents the SMTP settings of an email notification channel
+	NotificationEmailSettings struct {
+		// Host of the SMTP server
+		Host string `json:"Host" example:"smtp.mydomain.tld"`
+		// Port of the SMTP server
+		Port int `json:"Port" example:"587"`
+		// Username used to authenticate against the SMTP server, no authentication when empty
+		Username string `json:"Username" example:"portainer"`
+		// Password used to authenticate against the SMTP server
+		Password string `json:"Password,omitempty" example:"password"`
+		// Sender address
+		From string `json:"From" example:"portainer@mydomain.tld"`
+		// Recipient addresses
+		To []string `json:"To" example:"ops@mydomain.tld"`
+	}
+
+	// NotificationEvent represents a platform event notifications are sent for
+	NotificationEvent struct {
+		// Type of the event
+		Type NotificationEventType `json:"Type" example:"endpoint.down"`
+		// Unix timestamp of the event
+		Time int64 `json:"Time" example:"1587399600"`
+		// Identifier of the endpoint the event relates to, 0 when not related to an endpoint
+		EndpointID EndpointID `json:"EndpointId,omitempty" example:"1"`
+		// Name of the endpoint the event relates to
+		EndpointName string `json:"EndpointName,omitempty" example:"my-endpoint"`
+		// Identifier of the group of the endpoint the event relates to
+		EndpointGroupID EndpointGroupID `json:"EndpointGroupId,omitempty" example:"1"`
+		// Human readable description of the event
+		Message string `json:"Message" example:"Endpoint my-endpoint is down: Cannot connect to the Docker daemon"`
+		// Additional details of the event
+		Details map[string]string `json:"Details,omitempty"`
+	}
+
+	// NotificationEventType represents the type of a platform event
+	NotificationEventType string
+
 	// OAuthSettings represents the settings used to authorize with an authorization server
 	OAuthSettings struct {
 		ClientID             string `json:"ClientID"`
@@ -640,16 +1108,50 @@ type (
 		LogoURL string `json:"LogoURL" example:"https://mycompany.mydomain.tld/logo.png"`
 		// A list of label name & value that will be used to hide containers when querying containers
 		BlackListedLabels []Pair `json:"BlackListedLabels"`
+		// A list of label rules that will be used to hide containers, services, volumes and networks
+		LabelFilters []LabelFilter `json:"LabelFilters"`
 		// Active authentication method for the Portainer instance. Valid values are: 1 for internal, 2 for LDAP, or 3 for oauth
 		AuthenticationMethod AuthenticationMethod `json:"AuthenticationMethod" example:"1"`
 		LDAPSettings         LDAPSettings         `json:"LDAPSettings" example:""`
 		OAuthSettings        OAuthSettings        `json:"OAuthSettings" example:""`
 		// The interval in which endpoint snapshots are created
 		SnapshotInterval string `json:"SnapshotInterval" example:"5m"`
+		// The number of consecutive failed snapshots after which an endpoint is marked as down
+		EndpointFailureThreshold int `json:"EndpointFailureThreshold" example:"3"`
+		// The number of endpoint snapshots created concurrently
+		SnapshotConcurrency int `json:"SnapshotConcurrency" example:"10"`
+		// The duration after which the snapshot of an endpoint is abandoned
+		SnapshotTimeout string `json:"SnapshotTimeout" example:"30s"`
+		// How long the snapshot history of an endpoint is kept
+		SnapshotHistoryRetention string `json:"SnapshotHistoryRetention" example:"720h"`
+		// The age after which the snapshot history is downsampled
+		SnapshotHistoryDownsampleAfter string `json:"SnapshotHistoryDownsampleAfter" example:"24h"`
+		// The interval the snapshot history is averaged over once downsampled
+		SnapshotHistoryDownsampleInterval string `json:"SnapshotHistoryDownsampleInterval" example:"1h"`
 		// URL to the templates that will be displayed in the UI when navigating to App Templates
 		TemplatesURL string `json:"TemplatesURL" example:"https://raw.githubusercontent.com/portainer/templates/master/templates.json"`
 		// The default check in interval for edge agent (in seconds)
 		EdgeAgentCheckinInterval int `json:"EdgeAgentCheckinInterval" example:"5"`
+		// The number of check-ins an edge agent can miss before an alert is raised, 0 to disable the alert
+		EdgeAgentCheckinAlertThreshold int `json:"EdgeAgentCheckinAlertThreshold" example:"3"`
+		// The number of previous versions kept for each edge stack
+		EdgeStackMaxPreviousVersions int `json:"EdgeStackMaxPreviousVersions" example:"5"`
+		// Bearer token required to scrape the Prometheus metrics, metrics are disabled when empty
+		MetricsToken string `json:"MetricsToken" example:"c5f4d3a8b1e94f2c9a7d6e5b4c3a2f1e"`
+		// Level of the logs (DEBUG, INFO, WARN or ERROR), the level set by the --log-level flag is used when empty
+		LogLevel string `json:"LogLevel" example:"INFO"`
+		// Scanner used to scan the images of the endpoints for known vulnerabilities (trivy or grype), images are not scanned when empty
+		VulnerabilityScanner string `json:"VulnerabilityScanner" example:"trivy"`
+		// Address of the Trivy server the images are scanned with, the images are scanned locally when empty
+		VulnerabilityScannerServerURL string `json:"VulnerabilityScannerServerURL" example:"http://trivy:4954"`
+		// The interval after which an image is scanned again
+		VulnerabilityScanInterval string `json:"VulnerabilityScanInterval" example:"24h"`
+		// The maximum duration of the scan of an image
+		VulnerabilityScanTimeout string `json:"VulnerabilityScanTimeout" example:"10m"`
+		// Stacks using an image with a known vulnerability of this severity or higher cannot be deployed, the gate is disabled when empty
+		VulnerabilityGateSeverity VulnerabilitySeverity `json:"VulnerabilityGateSeverity" example:"CRITICAL"`
+		// Whether the vulnerability gate rejects the stacks using an image that could not be scanned
+		VulnerabilityGateRejectUnscanned bool `json:"VulnerabilityGateRejectUnscanned" example:"true"`
 		// Whether edge compute features are enabled
 		EnableEdgeComputeFeatures bool `json:"EnableEdgeComputeFeatures" example:""`
 		// The duration of a user session
@@ -672,6 +1174,27 @@ type (
 		AllowContainerCapabilitiesForRegularUsers bool `json:"AllowContainerCapabilitiesForRegularUsers"`
 	}
 
+	// SnapshotHistoryPoint represents the key figures of an endpoint snapshot at a point in time
+	SnapshotHistoryPoint struct {
+		// Endpoint identifier
+		EndpointID EndpointID `json:"EndpointId" example:"1"`
+		// Unix timestamp of the snapshot, or of the start of the interval once downsampled
+		Time int64 `json:"Time" example:"1587399600"`
+		// Number of snapshots aggregated in this point, greater than 1 once downsampled
+		Samples                 int   `json:"Samples" example:"1"`
+		RunningContainerCount   int   `json:"RunningContainerCount" example:"3"`
+		StoppedContainerCount   int   `json:"StoppedContainerCount" example:"1"`
+		HealthyContainerCount   int   `json:"HealthyContainerCount" example:"2"`
+		UnhealthyContainerCount int   `json:"UnhealthyContainerCount" example:"0"`
+		ImageCount              int   `json:"ImageCount" example:"12"`
+		VolumeCount             int   `json:"VolumeCount" example:"4"`
+		ServiceCount            int   `json:"ServiceCount" example:"0"`
+		StackCount              int   `json:"StackCount" example:"2"`
+		NodeCount               int   `json:"NodeCount" example:"1"`
+		TotalCPU                int64 `json:"TotalCPU" example:"4"`
+		TotalMemory             int64 `json:"TotalMemory" example:"8589934592"`
+	}
+
 	// SnapshotJob represents a scheduled job that can create endpoint snapshots
 	SnapshotJob struct{}
 
@@ -715,6 +1238,33 @@ type (
 	// StackID represents a stack identifier (it must be composed of Name + "_" + SwarmID to create a unique identifier)
 	StackID int
 
+	// StackRevision represents a deployed version of a stack
+	StackRevision struct {
+		// Stack revision Identifier
+		ID StackRevisionID `json:"Id" example:"1"`
+		// Identifier of the stack this revision belongs to
+		StackID StackID `json:"StackId" example:"1"`
+		// Revision number, incremented on each deployment of the stack
+		Version int `json:"Version" example:"3"`
+		// Content of the stack file at the time of the deployment
+		StackFileContent string `json:"StackFileContent" example:"version: 3\n services:\n web:\n image:nginx"`
+		// A list of environment variables used during the deployment
+		Env []Pair `json:"Env" example:""`
+		// Git reference used during the deployment, for git based stacks
+		GitReferenceName string `json:"GitReferenceName" example:"refs/heads/master"`
+		// Git commit hash deployed, for git based stacks
+		GitCommitID string `json:"GitCommitID" example:"0ea0c4aa2e57d44b4b7e8d6a6b3e9d8e8f1e2e3a"`
+		// Revision number this revision was rolled back from, 0 when it is not a rollback
+		RollbackOf int `json:"RollbackOf" example:"0"`
+		// The username which deployed this revision
+		DeployedBy string `json:"DeployedBy" example:"admin"`
+		// The date in unix time when this revision was deployed
+		DeploymentDate int64 `json:"DeploymentDate" example:"1587399600"`
+	}
+
+	// StackRevisionID represents a stack revision identifier
+	StackRevisionID int
+
 	// StackStatus represent a status for a stack
 	StackStatus int
 
@@ -927,6 +1477,28 @@ type (
 		Credentials  string
 	}
 
+	// TunnelStatistics represents the state and the traffic of a reverse tunnel connected to an Edge endpoint
+	TunnelStatistics struct {
+		// Identifier of the Edge endpoint
+		EndpointID EndpointID `json:"EndpointId" example:"1"`
+		// Status of the tunnel, one of IDLE, REQUIRED or ACTIVE
+		Status string `json:"Status" example:"ACTIVE"`
+		// Port of the tunnel on the tunnel server
+		Port int `json:"Port" example:"53641"`
+		// Number of seconds since the tunnel became active, 0 when it is not active
+		Uptime int64 `json:"Uptime" example:"120"`
+		// Number of bytes received from the Edge agent through the tunnel
+		BytesIn int64 `json:"BytesIn" example:"1048576"`
+		// Number of bytes sent to the Edge agent through the tunnel
+		BytesOut int64 `json:"BytesOut" example:"65536"`
+		// Number of requests currently proxied through the tunnel
+		OpenConnections int64 `json:"OpenConnections" example:"2"`
+		// Reason of the last status change
+		StatusReason string `json:"StatusReason" example:"Edge agent opened the tunnel"`
+		// The date in unix time of the last status change
+		StatusDate int64 `json:"StatusDate" example:"1587399600"`
+	}
+
 	// TunnelServerInfo represents information associated to the tunnel server
 	TunnelServerInfo struct {
 		PrivateKeySeed string `json:"PrivateKeySeed"`
@@ -963,6 +1535,9 @@ type (
 	// or a regular user
 	UserRole int
 
+	// VulnerabilitySeverity represents the severity of an image vulnerability
+	VulnerabilitySeverity string
+
 	// Webhook represents a url webhook that can be used to update a service
 	Webhook struct {
 		// Webhook Identifier
@@ -988,6 +1563,7 @@ type (
 	// ComposeStackManager represents a service to manage Compose stacks
 	ComposeStackManager interface {
 		ComposeSyntaxMaxVersion() string
+		ComposeSpecSupported() bool
 		NormalizeStackName(name string) string
 		Up(stack *Stack, endpoint *Endpoint) error
 		Down(stack *Stack, endpoint *Endpoint) error
@@ -1021,17 +1597,24 @@ type (
 
 		DockerHub() DockerHubService
 		CustomTemplate() CustomTemplateService
+		EdgeEnrollmentToken() EdgeEnrollmentTokenService
 		EdgeGroup() EdgeGroupService
 		EdgeJob() EdgeJobService
+		EdgeJobResult() EdgeJobResultService
 		EdgeStack() EdgeStackService
 		Endpoint() EndpointService
 		EndpointGroup() EndpointGroupService
 		EndpointRelation() EndpointRelationService
+		ImageVulnerability() ImageVulnerabilityService
+		NotificationChannel() NotificationChannelService
+		NotificationDelivery() NotificationDeliveryService
 		Registry() RegistryService
 		ResourceControl() ResourceControlService
 		Role() RoleService
 		Settings() SettingsService
+		SnapshotHistory() SnapshotHistoryService
 		Stack() StackService
+		StackRevision() StackRevisionService
 		Tag() TagService
 		TeamMembership() TeamMembershipService
 		Team() TeamService
@@ -1058,7 +1641,18 @@ type (
 
 	// DockerSnapshotter represents a service used to create Docker endpoint snapshots
 	DockerSnapshotter interface {
-		CreateSnapshot(endpoint *Endpoint) (*DockerSnapshot, error)
+		CreateSnapshot(ctx context.Context, endpoint *Endpoint) (*DockerSnapshot, error)
+	}
+
+	// EdgeEnrollmentTokenService represents a service to manage Edge enrollment tokens
+	EdgeEnrollmentTokenService interface {
+		EdgeEnrollmentTokens() ([]EdgeEnrollmentToken, error)
+		EdgeEnrollmentToken(ID EdgeEnrollmentTokenID) (*EdgeEnrollmentToken, error)
+		EdgeEnrollmentTokenByToken(token string) (*EdgeEnrollmentToken, error)
+		CreateEdgeEnrollmentToken(token *EdgeEnrollmentToken) error
+		UpdateEdgeEnrollmentToken(ID EdgeEnrollmentTokenID, token *EdgeEnrollmentToken) error
+		DeleteEdgeEnrollmentToken(ID EdgeEnrollmentTokenID) error
+		RegisterEndpoint(token string, register func(token *EdgeEnrollmentToken, endpointID EndpointID) (*Endpoint, error)) (*EdgeEnrollmentToken, *Endpoint, error)
 	}
 
 	// EdgeGroupService represents a service to manage Edge groups
@@ -1080,12 +1674,21 @@ type (
 		GetNextIdentifier() int
 	}
 
+	// EdgeJobResultService represents a service to manage Edge job results
+	EdgeJobResultService interface {
+		EdgeJobResults(edgeJobID EdgeJobID) ([]EdgeJobResult, error)
+		RecordEdgeJobResult(result *EdgeJobResult, update func(edgeJob *EdgeJob) error) (*EdgeJob, error)
+		DeleteEdgeJobResultsBefore(edgeJobID EdgeJobID, run int) error
+		DeleteEdgeJobResults(edgeJobID EdgeJobID) error
+	}
+
 	// EdgeStackService represents a service to manage Edge stacks
 	EdgeStackService interface {
 		EdgeStacks() ([]EdgeStack, error)
 		EdgeStack(ID EdgeStackID) (*EdgeStack, error)
 		CreateEdgeStack(edgeStack *EdgeStack) error
 		UpdateEdgeStack(ID EdgeStackID, edgeStack *EdgeStack) error
+		UpdateEdgeStackFunc(ID EdgeStackID, updateFunc func(edgeStack *EdgeStack)) error
 		DeleteEdgeStack(ID EdgeStackID) error
 		GetNextIdentifier() int
 	}
@@ -1152,6 +1755,16 @@ type (
 	// GitService represents a service for managing Git
 	GitService interface {
 		CloneRepository(destination string, repositoryURL, referenceName, username, password string) error
+		CloneRepositoryAtCommit(destination string, repositoryURL, referenceName, commitID, username, password string) error
+		LatestCommitID(repositoryURL, referenceName, username, password string) (string, error)
+	}
+
+	// ImageVulnerabilityService represents a service for managing the vulnerability reports of the images
+	ImageVulnerabilityService interface {
+		ImageVulnerabilityReports() ([]ImageVulnerabilityReport, error)
+		ImageVulnerabilityReport(digest string) (*ImageVulnerabilityReport, error)
+		UpdateImageVulnerabilityReport(digest string, report *ImageVulnerabilityReport) error
+		DeleteImageVulnerabilityReport(digest string) error
 	}
 
 	// JWTService represents a service for managing JWT tokens
@@ -1173,13 +1786,13 @@ type (
 
 	// KubernetesDeployer represents a service to deploy a manifest inside a Kubernetes endpoint
 	KubernetesDeployer interface {
-		Deploy(endpoint *Endpoint, data string, namespace string) (string, error)
+		Deploy(endpoint *Endpoint, data string, namespace string, requestID string) (string, error)
 		ConvertCompose(data string) ([]byte, error)
 	}
 
 	// KubernetesSnapshotter represents a service used to create Kubernetes endpoint snapshots
 	KubernetesSnapshotter interface {
-		CreateSnapshot(endpoint *Endpoint) (*KubernetesSnapshot, error)
+		CreateSnapshot(ctx context.Context, endpoint *Endpoint) (*KubernetesSnapshot, error)
 	}
 
 	// LDAPService represents a service used to authenticate users against a LDAP/AD
@@ -1189,6 +1802,29 @@ type (
 		GetUserGroups(username string, settings *LDAPSettings) ([]string, error)
 	}
 
+	// NotificationChannelService represents a service for managing notification channel data
+	NotificationChannelService interface {
+		NotificationChannels() ([]NotificationChannel, error)
+		NotificationChannel(ID NotificationChannelID) (*NotificationChannel, error)
+		CreateNotificationChannel(channel *NotificationChannel) error
+		UpdateNotificationChannel(ID NotificationChannelID, channel *NotificationChannel) error
+		DeleteNotificationChannel(ID NotificationChannelID) error
+	}
+
+	// NotificationDeliveryService represents a service for managing the notification delivery log
+	NotificationDeliveryService interface {
+		NotificationDeliveries() ([]NotificationDelivery, error)
+		CreateNotificationDelivery(delivery *NotificationDelivery) error
+		UpdateNotificationDelivery(ID NotificationDeliveryID, delivery *NotificationDelivery) error
+		DeleteNotificationDeliveriesUpTo(ID NotificationDeliveryID) error
+	}
+
+	// NotificationService represents a service used to send notifications about platform events
+	NotificationService interface {
+		Notify(event NotificationEvent)
+		Test(channel *NotificationChannel) error
+	}
+
 	// OAuthService represents a service used to authenticate users using OAuth
 	OAuthService interface {
 		Authenticate(code string, configuration *OAuthSettings) (string, *time.Time, error)
@@ -1224,6 +1860,9 @@ type (
 		GetTunnelDetails(endpointID EndpointID) *TunnelDetails
 		AddEdgeJob(endpointID EndpointID, edgeJob *EdgeJob)
 		RemoveEdgeJob(edgeJobID EdgeJobID)
+		TunnelStatistics() []TunnelStatistics
+		CloseTunnel(endpointID EndpointID) error
+		TrackTunnelTraffic(endpointID EndpointID, next http.Handler) http.Handler
 	}
 
 	// RoleService represents a service for managing user roles
@@ -1234,12 +1873,27 @@ type (
 		UpdateRole(ID RoleID, role *Role) error
 	}
 
+	// SecretService represents a service encrypting the secrets stored in the database
+	SecretService interface {
+		Encrypt(value string) (string, error)
+		Decrypt(value string) (string, error)
+	}
+
 	// SettingsService represents a service for managing application settings
 	SettingsService interface {
 		Settings() (*Settings, error)
 		UpdateSettings(settings *Settings) error
 	}
 
+	// SnapshotHistoryService represents a service for managing the snapshot history of the endpoints
+	SnapshotHistoryService interface {
+		SnapshotHistory(endpointID EndpointID, from, to int64) ([]SnapshotHistoryPoint, error)
+		CreateSnapshotHistoryPoint(point *SnapshotHistoryPoint) error
+		ReplaceSnapshotHistory(endpointID EndpointID, from, to int64, points []SnapshotHistoryPoint) error
+		DeleteSnapshotHistory(endpointID EndpointID, before int64) error
+		DeleteEndpointSnapshotHistory(endpointID EndpointID) error
+	}
+
 	// Server defines the interface to serve the API
 	Server interface {
 		Start() error
@@ -1251,6 +1905,7 @@ type (
 		StackByName(name string) (*Stack, error)
 		Stacks() ([]Stack, error)
 		CreateStack(stack *Stack) error
+		CreateStackWithUniqueName(stack *Stack) error
 		UpdateStack(ID StackID, stack *Stack) error
 		DeleteStack(ID StackID) error
 		GetNextIdentifier() int
@@ -1272,6 +1927,15 @@ type (
 		Remove(stack *Stack, endpoint *Endpoint) error
 	}
 
+	// StackRevisionService represents a service for managing stack revision data
+	StackRevisionService interface {
+		StackRevision(stackID StackID, version int) (*StackRevision, error)
+		LatestStackRevision(stackID StackID) (*StackRevision, error)
+		StackRevisions(stackID StackID) ([]StackRevision, error)
+		CreateStackRevision(revision *StackRevision) error
+		DeleteStackRevisions(stackID StackID) error
+	}
+
 	// TagService represents a service for managing tag data
 	TagService interface {
 		Tags() ([]Tag, error)
@@ -1330,6 +1994,18 @@ type (
 		StoreInstanceID(ID string) error
 	}
 
+	// VulnerabilityScanner represents a service used to scan images for known vulnerabilities
+	VulnerabilityScanner interface {
+		Name() string
+		Scan(ctx context.Context, image string, registry *Registry) ([]ImageVulnerability, error)
+	}
+
+	// VulnerabilityService represents a service scanning the images for known vulnerabilities
+	VulnerabilityService interface {
+		Start()
+		ScanImage(ctx context.Context, image string) ([]ImageVulnerability, error)
+	}
+
 	// WebhookService represents a service for managing webhook data.
 	WebhookService interface {
 		Webhooks() ([]Webhook, error)
@@ -1345,7 +2021,7 @@ const (
 	// APIVersion is the version number of the Portainer API
 	APIVersion = "2.6.0"
 	// DBVersion is the version number of the Portainer database
-	DBVersion = 30
+	DBVersion = 31
 	// ComposeSyntaxMaxVersion is a maximum supported version of the docker compose syntax
 	ComposeSyntaxMaxVersion = "3.9"
 	// AssetsServerURL represents the URL of the Portainer asset server
@@ -1373,10 +2049,40 @@ const (
 	PortainerAgentSignatureMessage = "Portainer-App"
 	// DefaultEdgeAgentCheckinIntervalInSeconds represents the default interval (in seconds) used by Edge agents to checkin with the Portainer instance
 	DefaultEdgeAgentCheckinIntervalInSeconds = 5
+	// DefaultEdgeStackMaxPreviousVersions represents the default number of previous versions kept for each edge stack
+	DefaultEdgeStackMaxPreviousVersions = 5
+	// EdgeJobDefaultResultRetention represents the default number of runs of an Edge job whose results are kept
+	EdgeJobDefaultResultRetention = 20
+	// EdgeJobResultMaxOutputSize represents the maximum size in bytes of the output kept for an Edge job result
+	EdgeJobResultMaxOutputSize = 16384
+	// EdgeHeartbeatMaxSize represents the maximum size in bytes of the heartbeat sent by an Edge agent on check-in
+	EdgeHeartbeatMaxSize = 65536
 	// DefaultTemplatesURL represents the URL to the official templates supported by Portainer
 	DefaultTemplatesURL = "https://raw.githubusercontent.com/portainer/templates/master/templates-2.0.json"
 	// DefaultUserSessionTimeout represents the default timeout after which the user session is cleared
 	DefaultUserSessionTimeout = "8h"
+	// NotificationDeliveryMaxCount represents the number of notification deliveries kept in the delivery log
+	NotificationDeliveryMaxCount = 1000
+	// NotificationMaxAttempts represents the number of attempts made to send an event to a notification channel
+	NotificationMaxAttempts = 5
+	// DefaultEndpointFailureThreshold represents the default number of consecutive failed snapshots after which an endpoint is marked as down
+	DefaultEndpointFailureThreshold = 1
+	// EndpointStatusHistoryMaxSize represents the number of status changes kept for each endpoint
+	EndpointStatusHistoryMaxSize = 20
+	// DefaultSnapshotConcurrency represents the default number of endpoint snapshots created concurrently
+	DefaultSnapshotConcurrency = 10
+	// DefaultSnapshotTimeout represents the default duration after which the snapshot of an endpoint is abandoned
+	DefaultSnapshotTimeout = "30s"
+	// DefaultSnapshotHistoryRetention represents the default duration the snapshot history of an endpoint is kept
+	DefaultSnapshotHistoryRetention = "720h"
+	// DefaultSnapshotHistoryDownsampleAfter represents the default age after which the snapshot history is downsampled
+	DefaultSnapshotHistoryDownsampleAfter = "24h"
+	// DefaultSnapshotHistoryDownsampleInterval represents the default interval the snapshot history is averaged over once downsampled
+	DefaultSnapshotHistoryDownsampleInterval = "1h"
+	// DefaultVulnerabilityScanInterval represents the default interval after which an image is scanned again
+	DefaultVulnerabilityScanInterval = "24h"
+	// DefaultVulnerabilityScanTimeout represents the default maximum duration of the scan of an image
+	DefaultVulnerabilityScanTimeout = "10m"
 )
 
 const (
@@ -1397,6 +2103,32 @@ const (
 	AgentPlatformKubernetes
 )
 
+const (
+	_ DeploymentJobStatus = iota
+	// DeploymentJobPending represents a job waiting to be executed
+	DeploymentJobPending
+	// DeploymentJobRunning represents a job being executed
+	DeploymentJobRunning
+	// DeploymentJobSuccess represents a job that completed successfully
+	DeploymentJobSuccess
+	// DeploymentJobError represents a job that failed
+	DeploymentJobError
+)
+
+const (
+	_ DeploymentJobType = iota
+	// DeploymentJobCreate represents the creation of a stack
+	DeploymentJobCreate
+	// DeploymentJobUpdate represents the update of a stack file or environment
+	DeploymentJobUpdate
+	// DeploymentJobGitRedeploy represents the redeployment of a stack from its git repository
+	DeploymentJobGitRedeploy
+	// DeploymentJobMigrate represents the migration of a stack to another endpoint
+	DeploymentJobMigrate
+	// DeploymentJobRollback represents the redeployment of a previous stack revision
+	DeploymentJobRollback
+)
+
 const (
 	_ EdgeJobLogsStatus = iota
 	// EdgeJobLogsStatusIdle represents an idle log collection job
@@ -1425,6 +2157,38 @@ const (
 	StatusAcknowledged
 )
 
+const (
+	_ EdgeStackRolloutStatus = iota
+	// EdgeStackRolloutRunning represents a rollout deploying batches of endpoints
+	EdgeStackRolloutRunning
+	// EdgeStackRolloutPaused represents a rollout paused by a user
+	EdgeStackRolloutPaused
+	// EdgeStackRolloutStopped represents a rollout stopped because the error threshold was exceeded
+	EdgeStackRolloutStopped
+	// EdgeStackRolloutCompleted represents a rollout that reached all the endpoints
+	EdgeStackRolloutCompleted
+	// EdgeStackRolloutAborted represents a rollout aborted by a user, all the endpoints are sent back to the previous version
+	EdgeStackRolloutAborted
+)
+
+const (
+	_ EdgeStackRolloutStrategy = iota
+	// EdgeStackRolloutCanary represents a rollout starting with a percentage of the endpoints
+	EdgeStackRolloutCanary
+	// EdgeStackRolloutSubset represents a rollout starting with a named subset of the endpoints
+	EdgeStackRolloutSubset
+)
+
+const (
+	_ EdgeStackVariableScope = iota
+	// EdgeStackVariableEndpointScope represents a value set for an endpoint
+	EdgeStackVariableEndpointScope
+	// EdgeStackVariableTagScope represents a value set for the endpoints with a tag
+	EdgeStackVariableTagScope
+	// EdgeStackVariableEdgeGroupScope represents a value set for the endpoints of an edge group
+	EdgeStackVariableEdgeGroupScope
+)
+
 const (
 	_ EndpointExtensionType = iota
 	// StoridgeEndpointExtension represents the Storidge extension
@@ -1463,6 +2227,16 @@ const (
 	SnapshotJobType = 2
 )
 
+const (
+	_ LabelFilterMatchType = iota
+	// LabelFilterMatchExact represents a label filter matching the exact name and value
+	LabelFilterMatchExact
+	// LabelFilterMatchGlob represents a label filter using glob patterns (* and ?)
+	LabelFilterMatchGlob
+	// LabelFilterMatchRegex represents a label filter using regular expressions
+	LabelFilterMatchRegex
+)
+
 const (
 	_ MembershipRole = iota
 	// TeamLeader represents a leader role inside a team
@@ -1538,6 +2312,8 @@ const (
 	_ StackStatus = iota
 	StackStatusActive
 	StackStatusInactive
+	// StackStatusDeploying is the status of a stack while its first deployment is running
+	StackStatusDeploying
 )
 
 const (
@@ -1575,6 +2351,60 @@ const (
 	ServiceWebhook
 )
 
+const (
+	_ NotificationChannelType = iota
+	// NotificationChannelWebhook represents a generic HTTP webhook with a templated body
+	NotificationChannelWebhook
+	// NotificationChannelEmail represents an SMTP email channel
+	NotificationChannelEmail
+	// NotificationChannelSlack represents a Slack or Microsoft Teams compatible incoming webhook
+	NotificationChannelSlack
+)
+
+const (
+	_ NotificationDeliveryStatus = iota
+	// NotificationDeliveryPending represents a delivery being attempted
+	NotificationDeliveryPending
+	// NotificationDeliverySuccess represents an event delivered to its channel
+	NotificationDeliverySuccess
+	// NotificationDeliveryFailed represents an event that could not be delivered after all the attempts
+	NotificationDeliveryFailed
+)
+
+const (
+	// NotificationEventEndpointDown is sent when an endpoint is marked as down
+	NotificationEventEndpointDown NotificationEventType = "endpoint.down"
+	// NotificationEventEndpointUp is sent when a down endpoint is marked as up again
+	NotificationEventEndpointUp NotificationEventType = "endpoint.up"
+	// NotificationEventEdgeStackDeployFailed is sent when an Edge endpoint reports a failed Edge stack deployment
+	NotificationEventEdgeStackDeployFailed NotificationEventType = "edgestack.deploy_failed"
+	// NotificationEventEdgeCheckinMissed is sent when an Edge endpoint missed more check-ins than the alert threshold
+	NotificationEventEdgeCheckinMissed NotificationEventType = "edge.checkin_missed"
+	// NotificationEventEdgeCheckinResumed is sent when an Edge endpoint checks in again after missing check-ins
+	NotificationEventEdgeCheckinResumed NotificationEventType = "edge.checkin_resumed"
+	// NotificationEventBackupFailed is sent when the creation of a backup fails
+	NotificationEventBackupFailed NotificationEventType = "backup.failed"
+	// NotificationEventSettingsUpdated is sent when an administrator updates the settings
+	NotificationEventSettingsUpdated NotificationEventType = "settings.updated"
+	// NotificationEventAdminAction is sent when a change is made through an endpoint restricted to administrators
+	NotificationEventAdminAction NotificationEventType = "audit.admin_action"
+	// NotificationEventTest is sent when testing a notification channel
+	NotificationEventTest NotificationEventType = "notification.test"
+)
+
+const (
+	// VulnerabilitySeverityUnknown represents a vulnerability whose severity is not known
+	VulnerabilitySeverityUnknown VulnerabilitySeverity = "UNKNOWN"
+	// VulnerabilitySeverityLow represents a low severity vulnerability
+	VulnerabilitySeverityLow VulnerabilitySeverity = "LOW"
+	// VulnerabilitySeverityMedium represents a medium severity vulnerability
+	VulnerabilitySeverityMedium VulnerabilitySeverity = "MEDIUM"
+	// VulnerabilitySeverityHigh represents a high severity vulnerability
+	VulnerabilitySeverityHigh VulnerabilitySeverity = "HIGH"
+	// VulnerabilitySeverityCritical represents a critical severity vulnerability
+	VulnerabilitySeverityCritical VulnerabilitySeverity = "CRITICAL"
+)
+
 const (
 	// EdgeAgentIdle represents an idle state for a tunnel connected to an Edge endpoint.
 	EdgeAgentIdle string = "IDLE"
@@ -10,15 +10,15 @@ angular.module('portainer.app').factory('Stack', [
       {
         get: { method: 'GET', params: { id: '@id' } },
         query: { method: 'GET', isArray: true },
-        create: { method: 'POST', ignoreLoadingBar: true },
-        update: { method: 'PUT', params: { id: '@id' }, ignoreLoadingBar: true },
+        create: { method: 'POST', params: { async: false }, ignoreLoadingBar: true },
+        update: { method: 'PUT', params: { id: '@id', async: false }, ignoreLoadingBar: true },
         associate: { method: 'PUT', params: { id: '@id', swarmId: '@swarmId', endpointId: '@endpointId', orphanedRunning: '@orphanedRunning', action: 'associate' } },
         remove: { method: 'DELETE', params: { id: '@id', external: '@external', endpointId: '@endpointId' } },
         getStackFile: { method: 'GET', params: { id: '@id', action: 'file' } },
-        migrate: { method: 'POST', params: { id: '@id', action: 'migrate', endpointId: '@endpointId' }, ignoreLoadingBar: true },
+        migrate: { method: 'POST', params: { id: '@id', action: 'migrate', endpointId: '@endpointId', async: false }, ignoreLoadingBar: true },
         start: { method: 'POST', params: { id: '@id', action: 'start' } },
         stop: { method: 'POST', params: { id: '@id', action: 'stop' } },
-        updateGit: { method: 'PUT', params: { action: 'git' } },
+        updateGit: { method: 'PUT', params: { action: 'git', async: false } },
       }
     );
   },
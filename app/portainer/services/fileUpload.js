@@ -73,7 +73,7 @@ angular.module('portainer.app').factory('FileUploadService', [
 
     service.createSwarmStack = function (stackName, swarmId, file, env, endpointId) {
       return Upload.upload({
-        url: 'api/stacks?method=file&type=1&endpointId=' + endpointId,
+        url: 'api/stacks?method=file&type=1&async=false&endpointId=' + endpointId,
         data: {
           file: file,
           Name: stackName,
@@ -86,7 +86,7 @@ angular.module('portainer.app').factory('FileUploadService', [
 
     service.createComposeStack = function (stackName, file, env, endpointId) {
       return Upload.upload({
-        url: 'api/stacks?method=file&type=2&endpointId=' + endpointId,
+        url: 'api/stacks?method=file&type=2&async=false&endpointId=' + endpointId,
         data: {
           file: file,
           Name: stackName,
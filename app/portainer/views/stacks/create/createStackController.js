@@ -227,6 +227,7 @@ angular
       try {
         const endpoint = EndpointProvider.currentEndpoint();
         $scope.composeSyntaxMaxVersion = endpoint.ComposeSyntaxMaxVersion;
+        $scope.composeSpecSupported = endpoint.ComposeSpecSupported;
       } catch (err) {
         Notifications.error('Failure', err, 'Unable to retrieve the ComposeSyntaxMaxVersion');
       }
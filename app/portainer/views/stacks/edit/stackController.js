@@ -455,6 +455,7 @@ angular.module('portainer.app').controller('StackController', [
       try {
         const endpoint = EndpointProvider.currentEndpoint();
         $scope.composeSyntaxMaxVersion = endpoint.ComposeSyntaxMaxVersion;
+        $scope.composeSpecSupported = endpoint.ComposeSpecSupported;
       } catch (err) {
         Notifications.error('Failure', err, 'Unable to retrieve the ComposeSyntaxMaxVersion');
       }